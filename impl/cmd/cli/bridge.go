@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/goccy/go-json"
+	"github.com/mr-tron/base58"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+var bridgePrivateKey string
+var bridgeMethod string
+
+func init() {
+	rootCmd.AddCommand(bridgeCmd)
+	bridgeCmd.AddCommand(bridgeImportCmd)
+	bridgeCmd.AddCommand(bridgeExportCmd)
+	bridgeImportCmd.Flags().StringVar(&bridgePrivateKey, "private-key", "", "base58 encoded ed25519 private key of the did, to sign the packet and write the relay API body")
+	bridgeExportCmd.Flags().StringVar(&bridgeMethod, "method", string(didsdk.KeyMethod), "did method to export to, key or jwk")
+}
+
+// bridgeImport is the did:dht Document imported from another DID method
+type bridgeImport struct {
+	DID      string          `json:"did"`
+	Document didsdk.Document `json:"document"`
+	// Packet is the base64url encoded DNS packet representing the document
+	Packet string `json:"packet"`
+	// Body is the base64url encoded body of a put to the relay API, when signed with the private key
+	Body string `json:"body,omitempty"`
+}
+
+var bridgeCmd = &cobra.Command{
+	Use:   "bridge",
+	Short: "Convert the identifiers of other DID methods to and from did:dht",
+	Long:  fmt.Sprintf(`Convert the identifiers of other DID methods to and from did:dht, bridging %s.`, bridgedMethods()),
+}
+
+var bridgeImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import a did:key or did:jwk into did:dht",
+	Long: `Import a did:key or did:jwk identifier of an ed25519 key, writing the did:dht Document whose identity key is the
+same key, and the DNS packet representing it. With the private key of the did, the packet is signed and the body of a
+put to the relay API written too.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		doc, err := did.ImportDID(args[0], did.CreateDIDDHTOpts{})
+		if err != nil {
+			logrus.WithError(err).Error("failed to import did")
+			return err
+		}
+		msg, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+		if err != nil {
+			logrus.WithError(err).Error("failed to convert did document to dns packet")
+			return err
+		}
+		packet, err := msg.Pack()
+		if err != nil {
+			logrus.WithError(err).Error("failed to pack dns packet")
+			return err
+		}
+		imported := bridgeImport{
+			DID:      doc.ID,
+			Document: *doc,
+			Packet:   base64.RawURLEncoding.EncodeToString(packet),
+		}
+
+		if bridgePrivateKey != "" {
+			privKey, err := base58.Decode(bridgePrivateKey)
+			if err != nil || len(privKey) != ed25519.PrivateKeySize {
+				return errors.New("invalid private key")
+			}
+			pubKey := ed25519.PrivateKey(privKey).Public().(ed25519.PublicKey)
+			if did.GetDIDDHTIdentifier(pubKey) != doc.ID {
+				return errors.New("private key is not the key of the did")
+			}
+			put, err := dht.CreatePKARRPublishRequest(privKey, *msg)
+			if err != nil {
+				logrus.WithError(err).Error("failed to create put request")
+				return err
+			}
+			var seq [8]byte
+			binary.BigEndian.PutUint64(seq[:], uint64(put.Seq))
+			body := append(append(put.Sig[:], seq[:]...), put.V.([]byte)...)
+			imported.Body = base64.RawURLEncoding.EncodeToString(body)
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(imported)
+	},
+}
+
+var bridgeExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a did:dht to did:key or did:jwk",
+	Long: `Export a did:dht identifier to the did:key or did:jwk identifier of its identity key. Only the identity key
+carries over; the other keys and services of the did:dht Document don't.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exported, err := did.ExportDID(did.DHT(args[0]), didsdk.Method(bridgeMethod))
+		if err != nil {
+			logrus.WithError(err).Error("failed to export did")
+			return err
+		}
+		fmt.Println(exported)
+		return nil
+	},
+}
+
+func bridgedMethods() string {
+	var methods []string
+	for _, method := range did.BridgedMethods() {
+		methods = append(methods, "did:"+string(method))
+	}
+	return strings.Join(methods, " and ")
+}
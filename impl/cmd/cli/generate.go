@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/generator"
+)
+
+const (
+	// formatRecords writes records in the format records are stored in, loadable with the gateway's --seed option
+	formatRecords = "records"
+	// formatRelay writes the ID of each record and its body for the relay API
+	formatRelay = "relay"
+)
+
+var generateOpts generator.Options
+var generateCount int
+var generateFormat string
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+	generateCmd.Flags().IntVarP(&generateCount, "count", "n", 100, "number of records to generate")
+	generateCmd.Flags().StringVar(&generateFormat, "format", formatRecords, "output format, records (loadable with --seed) or relay (id and relay API body)")
+	generateCmd.Flags().StringVar(&generateOpts.Seed, "key-seed", "", "derive keys from the seed to generate the same records on every run, random if empty")
+	generateCmd.Flags().StringVar((*string)(&generateOpts.Shape), "shape", string(generator.ShapeTXT), "shape of the dns packets, txt or did")
+	generateCmd.Flags().IntVar(&generateOpts.Size, "size", 0, fmt.Sprintf("pad each dns packet to this many bytes, up to %d", generator.MaxPacketSize))
+	generateCmd.Flags().IntVar(&generateOpts.TXTRecords, "txt-records", 1, "number of txt records of txt packets")
+	generateCmd.Flags().IntVar(&generateOpts.Services, "services", 1, "number of services of did packets")
+	generateCmd.Flags().Int64Var(&generateOpts.Seq, "seq", 1, "seq of the records")
+}
+
+// relayRecord is a generated record in the relay format
+type relayRecord struct {
+	ID string `json:"id"`
+	// Body is the base64url encoded body of a put to the relay API
+	Body string `json:"body"`
+}
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate signed records for testing",
+	Long: `Generate valid, signed records with dns packets of a configurable size and shape, writing one JSON record per
+line to stdout, for load, chaos, and interop testing.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if generateFormat != formatRecords && generateFormat != formatRelay {
+			return fmt.Errorf("unknown format: %s", generateFormat)
+		}
+		g, err := generator.New(generateOpts)
+		if err != nil {
+			logrus.WithError(err).Error("invalid generator options")
+			return err
+		}
+
+		out := bufio.NewWriter(os.Stdout)
+		defer out.Flush()
+		encoder := json.NewEncoder(out)
+		return g.Generate(generateCount, func(record generator.Record) error {
+			if generateFormat == formatRelay {
+				return encoder.Encode(relayRecord{
+					ID:   record.ID(),
+					Body: base64.RawURLEncoding.EncodeToString(record.Body()),
+				})
+			}
+			return encoder.Encode(record.StorageRecord())
+		})
+	},
+}
@@ -11,11 +11,11 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
-	"github.com/TBD54566975/did-dht-method/config"
-	"github.com/TBD54566975/did-dht-method/internal"
-	"github.com/TBD54566975/did-dht-method/internal/cli"
-	"github.com/TBD54566975/did-dht-method/internal/util"
-	"github.com/TBD54566975/did-dht-method/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal"
+	"github.com/TBD54566975/did-dht-method/impl/internal/cli"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
 )
 
 func init() {
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+// probeBodySizeLimit bounds the size of a record read from a gateway, the signature and seq and a 1000 byte packet
+const probeBodySizeLimit = 64 + 8 + 1000
+
+var (
+	probeNeighborhoods int
+	probeGateways      []string
+	probeTimeout       time.Duration
+	probeProxy         string
+)
+
+func init() {
+	rootCmd.AddCommand(probeCmd)
+	probeCmd.Flags().IntVar(&probeNeighborhoods, "neighborhoods", dht.DefaultProbeNeighborhoods, "number of traversals, each starting from other nodes, to find the nodes closest to the record")
+	probeCmd.Flags().StringArrayVar(&probeGateways, "gateway", nil, "base url of a gateway to resolve the record from, may be repeated")
+	probeCmd.Flags().DurationVar(&probeTimeout, "timeout", 2*time.Minute, "how long to probe for")
+	probeCmd.Flags().StringVar(&probeProxy, "proxy", "", "socks5:// or http(s):// url of a proxy, such as tor, to send the requests to gateways through")
+}
+
+// probeResult is where the record of a DID is present on the DHT and the gateways
+type probeResult struct {
+	DID      string           `json:"did"`
+	DHT      *dht.ProbeReport `json:"dht"`
+	Gateways []probedGateway  `json:"gateways,omitempty"`
+}
+
+// probedGateway is whether a gateway resolves the record, and at which seq
+type probedGateway struct {
+	URL     string `json:"url"`
+	Present bool   `json:"present"`
+	Seq     int64  `json:"seq,omitempty"`
+	// Stale is whether the gateway resolves an older seq than the latest found on the DHT
+	Stale         bool   `json:"stale,omitempty"`
+	LatencyMillis int64  `json:"latencyMillis"`
+	Error         string `json:"error,omitempty"`
+}
+
+var probeCmd = &cobra.Command{
+	Use:   "probe <did>",
+	Short: "Report where the record of a DID is replicated",
+	Long: `Probe the DHT for the record of a did:dht identifier, or a z-base-32 encoded key, from several neighborhoods,
+reporting which of the nodes closest to the record hold it, the seq each holds, and an estimate of how healthy its
+replication is. Gateways given are asked to resolve the record too. Records which sometimes don't resolve are
+typically held by few of the nodes closest to them, or only at an older seq.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := args[0]
+		if strings.HasPrefix(id, "did:") {
+			suffix, err := did.DHT(id).Suffix()
+			if err != nil {
+				logrus.WithError(err).Error("failed to parse did")
+				return err
+			}
+			id = suffix
+		}
+
+		client, err := util.NewProxiedHTTPClient(probeProxy)
+		if err != nil {
+			logrus.WithError(err).Error("invalid proxy")
+			return err
+		}
+		d, err := dht.NewDHT(config.GetDefaultBootstrapPeers())
+		if err != nil {
+			logrus.WithError(err).Error("failed to create dht")
+			return err
+		}
+		defer d.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		defer cancel()
+		var (
+			wg       sync.WaitGroup
+			gateways = make([]probedGateway, len(probeGateways))
+		)
+		for i, gateway := range probeGateways {
+			wg.Add(1)
+			go func(i int, gateway string) {
+				defer wg.Done()
+				gateways[i] = probeGateway(ctx, client, gateway, id)
+			}(i, gateway)
+		}
+		report, err := d.Probe(ctx, id, nil, probeNeighborhoods)
+		wg.Wait()
+		if err != nil {
+			logrus.WithError(err).Error("failed to probe dht")
+			return err
+		}
+		for i := range gateways {
+			gateways[i].Stale = gateways[i].Present && gateways[i].Seq < report.LatestSeq
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(probeResult{DID: did.Prefix + ":" + id, DHT: report, Gateways: gateways})
+	},
+}
+
+// probeGateway resolves the record of the z-base-32 encoded ID from the gateway's relay API
+func probeGateway(ctx context.Context, client *http.Client, gateway, id string) (probed probedGateway) {
+	gateway = strings.TrimSuffix(gateway, "/")
+	probed.URL = gateway
+	start := time.Now()
+	defer func() { probed.LatencyMillis = time.Since(start).Milliseconds() }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gateway+"/"+id, nil)
+	if err != nil {
+		probed.Error = err.Error()
+		return probed
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		probed.Error = err.Error()
+		return probed
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return probed
+	default:
+		probed.Error = fmt.Sprintf("unexpected status code: %d", resp.StatusCode)
+		return probed
+	}
+	// the body is the 64 byte signature, the 8 byte big-endian seq, and the packet
+	body, err := io.ReadAll(io.LimitReader(resp.Body, probeBodySizeLimit))
+	if err != nil {
+		probed.Error = err.Error()
+		return probed
+	}
+	if len(body) < 72 {
+		probed.Error = "record is too short"
+		return probed
+	}
+	probed.Present = true
+	probed.Seq = int64(binary.BigEndian.Uint64(body[64:72]))
+	return probed
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -14,12 +15,12 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
-	"github.com/TBD54566975/did-dht-method/config"
-	"github.com/TBD54566975/did-dht-method/pkg/server"
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/server"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
 )
 
-var commitHash string
-
 // main godoc
 //
 //	@title			The DID DHT Service
@@ -29,6 +30,11 @@ var commitHash string
 //	@contact.email	tbd-developer@squareup.com
 //	@license.name	Apache 2.0
 //	@license.url	http://www.apache.org/licenses/LICENSE-2.0.html
+//
+//	@securityDefinitions.apikey	AdminToken
+//	@in							header
+//	@name						Authorization
+//	@description				The admin token as a bearer token, e.g. "Bearer <token>"
 func main() {
 	logrus.SetFormatter(&logrus.JSONFormatter{
 		DisableTimestamp: false,
@@ -37,8 +43,8 @@ func main() {
 	logrus.SetReportCaller(true)
 
 	log := logrus.NewEntry(logrus.StandardLogger())
-	if commitHash != "" {
-		log = log.WithField("commit", commitHash)
+	if server.Commit != "" {
+		log = log.WithField("commit", server.Commit)
 	}
 	log.Info("starting up")
 
@@ -48,6 +54,10 @@ func main() {
 }
 
 func run() error {
+	seedPath := flag.String("seed", "", "path of a JSONL file of pre-signed records to load into storage on startup")
+	migrateKeys := flag.Bool("migrate-keys", false, "move stored records to hashed or plain keys, as configured by hash_keys, and exit")
+	flag.Parse()
+
 	// Load config
 	configPath := config.DefaultConfigPath
 	envConfigPath, present := os.LookupEnv(config.ConfigPath.String())
@@ -69,6 +79,13 @@ func run() error {
 			}
 		}(logFile)
 	}
+	if cfg.PrivacyConfig.Enabled {
+		util.RedactLogs()
+	}
+
+	if *migrateKeys {
+		return migrateStorageKeys(cfg)
+	}
 
 	// create a channel of buffer size 1 to handle shutdown.
 	// buffer's size is 1 in order to ignore any additional ctrl+c
@@ -82,11 +99,33 @@ func run() error {
 		return err
 	}
 
-	serverErrors := make(chan error, 1)
+	if *seedPath != "" {
+		result, err := s.SeedFile(context.Background(), *seedPath)
+		if err != nil {
+			return errors.Wrap(err, "loading seed records")
+		}
+		for _, seedErr := range result.Errors {
+			logrus.WithField("line", seedErr.Line).Warnf("invalid seed record: %s", seedErr.Error)
+		}
+	}
+
+	serverErrors := make(chan error, 3)
 	go func() {
 		logrus.WithField("listen_address", s.Addr).Info("starting listener")
 		serverErrors <- s.ListenAndServe()
 	}()
+	if s.DNSServer != nil {
+		go func() {
+			logrus.WithField("listen_address", s.DNSServer.Addr).Info("starting dns listener")
+			serverErrors <- s.DNSServer.ListenAndServe()
+		}()
+	}
+	if s.AdminServer != nil {
+		go func() {
+			logrus.WithField("listen_address", s.AdminServer.Addr).Info("starting admin listener")
+			serverErrors <- server.ListenAndServe(s.AdminServer)
+		}()
+	}
 
 	select {
 	case err = <-serverErrors:
@@ -97,17 +136,63 @@ func run() error {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 		defer cancel()
 
+		if s.DNSServer != nil {
+			if err = s.DNSServer.Shutdown(ctx); err != nil {
+				logrus.WithError(err).Error("failed to stop dns listener gracefully")
+			}
+		}
+		if s.AdminServer != nil {
+			if err = s.AdminServer.Shutdown(ctx); err != nil {
+				logrus.WithError(err).Error("failed to stop admin listener gracefully")
+			}
+		}
+
 		if err = s.Shutdown(ctx); err != nil {
 			if err = s.Close(); err != nil {
 				return err
 			}
 			return errors.Wrap(err, "main: failed to stop server gracefully")
 		}
+
+		if s.GeoIP != nil {
+			if err = s.GeoIP.Close(); err != nil {
+				logrus.WithError(err).Error("failed to close geoip database")
+			}
+		}
 	}
 
 	return nil
 }
 
+// migrateStorageKeys moves the stored records to their hashed keys if the config hashes keys, or back to their keys
+// otherwise, so hashing can be enabled or disabled for existing storage
+func migrateStorageKeys(cfg *config.Config) error {
+	// the storage is wrapped as it is to serve, but always hashing keys to find those of records stored under hashed
+	// keys, and without the secondary, which deletes aren't mirrored to so it is migrated with its own URI
+	migrateCfg := *cfg
+	migrateCfg.ServerConfig.SecondaryStorageURI = ""
+	migrateCfg.EncryptionConfig.HashKeys = true
+	db, err := storage.NewFromConfig(&migrateCfg)
+	if err != nil {
+		return errors.Wrap(err, "instantiating storage")
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			logrus.WithError(err).Error("failed to close storage")
+		}
+	}()
+	hashed, ok := storage.As[*storage.HashedKeys](db)
+	if !ok {
+		return errors.New("storage does not hash keys")
+	}
+	moved, err := storage.MigrateKeys(context.Background(), hashed, !cfg.EncryptionConfig.HashKeys)
+	if err != nil {
+		return errors.Wrapf(err, "migrating storage keys after moving %d record(s)", moved)
+	}
+	logrus.WithField("hash_keys", cfg.EncryptionConfig.HashKeys).Infof("migrated %d record(s)", moved)
+	return nil
+}
+
 // configureLogger configures the logger to logs to the given location and returns a file pointer to a logs
 // file that should be closed upon server shutdown
 func configureLogger(level, location string) *os.File {
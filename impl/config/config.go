@@ -22,25 +22,178 @@ const (
 	EnvironmentTest Environment = "test"
 	EnvironmentProd Environment = "prod"
 
+	// RoleAll runs both the resolver and publisher workloads
+	RoleAll Role = "all"
+	// RoleResolver only resolves records: it serves no publish API, doesn't write to storage, doesn't republish,
+	// and only gets from the DHT
+	RoleResolver Role = "resolver"
+	// RolePublisher only accepts and republishes records, serving no public resolution API
+	RolePublisher Role = "publisher"
+
+	// EvictionNone rejects new records once storage is full
+	EvictionNone EvictionPolicy = "none"
+	// EvictionLRU evicts the least recently resolved or published records once storage is full
+	EvictionLRU EvictionPolicy = "lru"
+
+	// CDCSinkKafka publishes record changes to a Kafka topic through a Kafka REST Proxy
+	CDCSinkKafka CDCSink = "kafka"
+	// CDCSinkNATS publishes record changes to a NATS subject
+	CDCSinkNATS CDCSink = "nats"
+	// CDCFormatJSON serializes record changes as JSON
+	CDCFormatJSON CDCFormat = "json"
+	// CDCFormatProtobuf serializes record changes as protobuf
+	CDCFormatProtobuf CDCFormat = "protobuf"
+
+	// ClientAuthNone doesn't require clients to present a certificate
+	ClientAuthNone ClientAuth = "none"
+	// ClientAuthRequire requires clients to present a certificate verified against the client CAs
+	ClientAuthRequire ClientAuth = "require"
+
+	// EndpointPolicyOff doesn't validate the service endpoints of published DID Documents
+	EndpointPolicyOff EndpointPolicy = "off"
+	// EndpointPolicyWarn logs the invalid service endpoints of published DID Documents, accepting the records
+	EndpointPolicyWarn EndpointPolicy = "warn"
+	// EndpointPolicyReject rejects the records of DID Documents with invalid service endpoints
+	EndpointPolicyReject EndpointPolicy = "reject"
+
+	// SeqUnitSeconds is for seqs which are unix timestamps in seconds
+	SeqUnitSeconds SeqUnit = "seconds"
+	// SeqUnitMilliseconds is for seqs which are unix timestamps in milliseconds
+	SeqUnitMilliseconds SeqUnit = "milliseconds"
+	// SeqUnitMicroseconds is for seqs which are unix timestamps in microseconds, as pkarr clients use
+	SeqUnitMicroseconds SeqUnit = "microseconds"
+
 	ConfigPath EnvironmentVariable = "CONFIG_PATH"
 	// BootstrapPeers A comma-separated list of bootstrap peers to connect to on startup.
 	BootstrapPeers EnvironmentVariable = "BOOTSTRAP_PEERS"
+	// EncryptionKeys A comma-separated list of base64url encoded storage encryption keys, replacing those of the config.
+	EncryptionKeys EnvironmentVariable = "STORAGE_ENCRYPTION_KEYS"
+	// KeyHashSecret A base64url encoded secret to hash the keys records are stored under with, replacing that of the config.
+	KeyHashSecret EnvironmentVariable = "STORAGE_KEY_HASH_SECRET"
 )
 
 type (
 	Environment         string
 	EnvironmentVariable string
+	Role                string
+	EvictionPolicy      string
+	CDCSink             string
+	CDCFormat           string
+	ClientAuth          string
+	EndpointPolicy      string
+	SeqUnit             string
 )
 
 func (e EnvironmentVariable) String() string {
 	return string(e)
 }
 
+// IsValid returns whether the role is known, treating an empty role as RoleAll
+func (r Role) IsValid() bool {
+	switch r {
+	case "", RoleAll, RoleResolver, RolePublisher:
+		return true
+	}
+	return false
+}
+
+// Resolves returns whether a process with the role serves the resolution APIs
+func (r Role) Resolves() bool {
+	return r != RolePublisher
+}
+
+// Publishes returns whether a process with the role accepts, stores, and republishes records
+func (r Role) Publishes() bool {
+	return r != RoleResolver
+}
+
+// IsValid returns whether the eviction policy is known, treating an empty policy as EvictionNone
+func (p EvictionPolicy) IsValid() bool {
+	switch p {
+	case "", EvictionNone, EvictionLRU:
+		return true
+	}
+	return false
+}
+
+// IsValid returns whether the sink is known, treating an empty sink as disabling change data capture
+func (s CDCSink) IsValid() bool {
+	switch s {
+	case "", CDCSinkKafka, CDCSinkNATS:
+		return true
+	}
+	return false
+}
+
+// IsValid returns whether the format is known, treating an empty format as CDCFormatJSON
+func (f CDCFormat) IsValid() bool {
+	switch f {
+	case "", CDCFormatJSON, CDCFormatProtobuf:
+		return true
+	}
+	return false
+}
+
+// IsValid returns whether the endpoint policy is known, treating an empty policy as EndpointPolicyOff
+func (p EndpointPolicy) IsValid() bool {
+	switch p {
+	case "", EndpointPolicyOff, EndpointPolicyWarn, EndpointPolicyReject:
+		return true
+	}
+	return false
+}
+
+// IsValid returns whether the client auth level is known, treating an empty level as ClientAuthNone
+func (a ClientAuth) IsValid() bool {
+	switch a {
+	case "", ClientAuthNone, ClientAuthRequire:
+		return true
+	}
+	return false
+}
+
+// IsValid returns whether the seq unit is known, treating an empty unit as SeqUnitSeconds
+func (u SeqUnit) IsValid() bool {
+	return u.PerSecond() > 0
+}
+
+// PerSecond returns how many seqs in the unit make a second, or 0 if the unit is unknown
+func (u SeqUnit) PerSecond() int64 {
+	switch u {
+	case "", SeqUnitSeconds:
+		return 1
+	case SeqUnitMilliseconds:
+		return 1_000
+	case SeqUnitMicroseconds:
+		return 1_000_000
+	}
+	return 0
+}
+
 type Config struct {
-	Log          LogConfig          `toml:"log"`
-	ServerConfig ServerConfig       `toml:"server"`
-	DHTConfig    DHTServiceConfig   `toml:"dht"`
-	PkarrConfig  PKARRServiceConfig `toml:"pkarr"`
+	Log                LogConfig          `toml:"log"`
+	ServerConfig       ServerConfig       `toml:"server"`
+	TLSConfig          TLSConfig          `toml:"tls"`
+	DHTConfig          DHTServiceConfig   `toml:"dht"`
+	PkarrConfig        PKARRServiceConfig `toml:"pkarr"`
+	IndexConfig        IndexConfig        `toml:"index"`
+	DIDWebConfig       DIDWebConfig       `toml:"did_web"`
+	DNSConfig          DNSConfig          `toml:"dns"`
+	ArchiveConfig      ArchiveConfig      `toml:"archive"`
+	HistoryConfig      HistoryConfig      `toml:"history"`
+	FeedConfig         FeedConfig         `toml:"feed"`
+	AttestationConfig  AttestationConfig  `toml:"attestation"`
+	AdminConfig        AdminConfig        `toml:"admin"`
+	GeoIPConfig        GeoIPConfig        `toml:"geoip"`
+	DocsConfig         DocsConfig         `toml:"docs"`
+	APIConfig          APIConfig          `toml:"api"`
+	EncryptionConfig   EncryptionConfig   `toml:"encryption"`
+	RetentionConfig    RetentionConfig    `toml:"retention"`
+	EquivocationConfig EquivocationConfig `toml:"equivocation"`
+	CDCConfig          CDCConfig          `toml:"cdc"`
+	SLAConfig          SLAConfig          `toml:"sla"`
+	PrivacyConfig      PrivacyConfig      `toml:"privacy"`
+	TenantConfig       TenantConfig       `toml:"tenants"`
 }
 
 type ServerConfig struct {
@@ -50,16 +203,401 @@ type ServerConfig struct {
 	BaseURL     string      `toml:"base_url"`
 	LogLocation string      `toml:"log_location"`
 	StorageURI  string      `toml:"storage_uri"`
+	// SecondaryStorageURI is the storage records are mirrored to asynchronously, and read from when the storage at
+	// StorageURI fails. There is no secondary storage if empty.
+	SecondaryStorageURI string `toml:"secondary_storage_uri"`
+	// CompressRecords compresses the values of stored records with zstd, flagging each compressed value so records
+	// written before are still read, and compresses the records stored before in the background on startup.
+	// Compressed records are read whether or not it is enabled.
+	CompressRecords bool `toml:"compress_records"`
+	// ReconcileCRON is the schedule the records of the secondary storage are reconciled with those of the storage on,
+	// repairing their drift. Reconciliation only runs on demand if empty.
+	ReconcileCRON string `toml:"reconcile_cron"`
+	// MaintenanceCRON is the schedule the space left behind by deleted records is reclaimed on: bolt is compacted,
+	// pausing reads and writes while it is, and postgres has the rows of deleted records deleted in batches.
+	// Maintenance only runs on demand if empty.
+	MaintenanceCRON string `toml:"maintenance_cron"`
+	// Role is the workload the process runs, one of all (the default), resolver, or publisher
+	Role Role `toml:"role"`
+	// SigningKey is the base64url encoded ed25519 seed identifying the gateway, which it signs attestations with.
+	// A new key is generated on startup if empty.
+	SigningKey string `toml:"signing_key"`
+	// Announce publishes the gateway's own did:dht record, the DID of SigningKey, whose service is the API at BaseURL,
+	// so clients can discover the gateway through the DHT. It is republished with the stored records, and requires a
+	// SigningKey, as a generated key would announce a new DID on each startup.
+	Announce bool `toml:"announce"`
+	// CanaryCRON is the schedule the gateway publishes a synthetic record under its own key on, salted so it doesn't
+	// replace the announced DID, and resolves it through the DHT, storage, and cache, catching silent DHT write
+	// failures. There is no canary if empty.
+	CanaryCRON string `toml:"canary_cron"`
+}
+
+type TLSConfig struct {
+	// CertFile and KeyFile are the PEM encoded certificate chain and private key the API is served over HTTPS with,
+	// which is served over plain HTTP if they are empty
+	CertFile string `toml:"cert_file"`
+	KeyFile  string `toml:"key_file"`
+	// ClientCAFile is a PEM bundle of the CAs client certificates are verified against. A certificate presented by a
+	// client is verified during the handshake, whether or not the endpoint requires one.
+	ClientCAFile string `toml:"client_ca_file"`
+	// PublishClientAuth and AdminClientAuth are whether publishing records and the admin API require a verified
+	// client certificate: none (the default) or require. The admin API requires its token either way.
+	PublishClientAuth ClientAuth `toml:"publish_client_auth"`
+	AdminClientAuth   ClientAuth `toml:"admin_client_auth"`
 }
 
 type DHTServiceConfig struct {
 	BootstrapPeers []string `toml:"bootstrap_peers"`
+	// BootstrapDNSSeeds are "host:port" names, each of whose addresses is also bootstrapped from
+	BootstrapDNSSeeds []string `toml:"bootstrap_dns_seeds"`
+	// NodesFile, if set, is the file the nodes the DHT knows are periodically saved to and bootstrapped from on
+	// startup, so the gateway can rejoin through the peers it knew without reaching well-known routers
+	NodesFile string `toml:"nodes_file"`
+	// ListenAddress is the UDP address the DHT listens on, such as the well-known address of a bootstrap node of a
+	// private network, or a random port if empty
+	ListenAddress string `toml:"listen_address"`
+	// Private runs the gateway in an isolated DHT network, such as for running did:dht internally. It must bootstrap
+	// from the network's own nodes, and never exchanges messages with nodes outside AllowedNetworks.
+	Private bool `toml:"private"`
+	// AllowedNetworks are the CIDRs of the nodes of a private network, the private address ranges if empty
+	AllowedNetworks []string `toml:"allowed_networks"`
+	// NetworkID, if set, identifies a private network, whose nodes refuse to answer nodes of other networks
+	NetworkID string `toml:"network_id"`
+	// MaxPutsPerSecond and MaxGetsPerSecond, if not zero, pace outbound DHT puts and gets with separate token
+	// buckets, so a gateway on a constrained network can cap its UDP traffic. Operations over budget wait their turn.
+	MaxPutsPerSecond float64 `toml:"max_puts_per_second"`
+	MaxGetsPerSecond float64 `toml:"max_gets_per_second"`
+	// PutBurst and GetBurst are the most puts and gets started at once after a quiet period, one second of budget if
+	// zero
+	PutBurst int `toml:"put_burst"`
+	GetBurst int `toml:"get_burst"`
+	// ReplicationFactor is the number of nodes closest to a record each put targets. When fewer of them store it, the
+	// record is put to the next closest nodes, up to twice as many in total. 8, the size of a routing table bucket,
+	// if zero.
+	ReplicationFactor int `toml:"replication_factor"`
+	// SeqSearchSeconds, if not zero, keeps each get searching this long after a record is found for a newer seq held
+	// by other nodes, rather than returning the first record found, trading resolution latency for freshness
+	SeqSearchSeconds int `toml:"seq_search_seconds"`
+	// GetMaxOutstanding is the most nodes each get queries at once, 15 if zero. GetTimeoutSeconds, if not zero, is the
+	// longest each get traverses the DHT for. Gets also stop shortly before the deadline of the request they serve,
+	// returning the best record found so far, flagged as partial, rather than nothing.
+	GetMaxOutstanding int `toml:"get_max_outstanding"`
+	GetTimeoutSeconds int `toml:"get_timeout_seconds"`
+	// GetHedgeMillis, if not zero, hedges gets: once a get has run for the 95th percentile latency of recent gets, a
+	// second get starts from other nodes, and the first to find the record wins, cutting long-tail resolution times.
+	// It is the delay used until enough gets have been timed. Gets searching for a newer seq aren't hedged.
+	GetHedgeMillis int `toml:"get_hedge_millis"`
+	// PeerBanThreshold, if not zero, is the number of queries in a row a peer may time out on, fail, or answer with a
+	// malformed value before its address is banned for PeerBanSeconds, 600 if zero. Banned peers are neither queried
+	// nor answered, so resolutions don't wait on unreliable nodes.
+	PeerBanThreshold int `toml:"peer_ban_threshold"`
+	PeerBanSeconds   int `toml:"peer_ban_seconds"`
+	// LogPeerReputation logs the failures of peers and their bans at the info level rather than debug
+	LogPeerReputation bool `toml:"log_peer_reputation"`
 }
 
 type PKARRServiceConfig struct {
-	RepublishCRON    string `toml:"republish_cron"`
+	// RepublishCRON is the schedule records are republished to the DHT on. If empty, this instance doesn't republish,
+	// which lets a fleet of stateless API instances leave republishing to a separate worker deployment.
+	RepublishCRON string `toml:"republish_cron"`
+	// RepublishSkipConfirmedSeconds, if not zero, skips republishing records unchanged since they were confirmed on
+	// the DHT within as many seconds, by a put stored by as many nodes as targeted or a complete get finding the record
+	// without conflict. It should stay well under the two hours DHT nodes keep records for.
+	RepublishSkipConfirmedSeconds int `toml:"republish_skip_confirmed_seconds"`
+	// RepublishJitterSeconds, if not zero, delays the start of each republish run by up to as many seconds at random,
+	// and RepublishSmearSeconds spreads its puts evenly, in random order, across as many seconds, so gateways on the
+	// same schedule holding the same records don't put them to the same DHT nodes in synchronized bursts. Their sum
+	// should stay well under the interval of the RepublishCRON.
+	RepublishJitterSeconds int `toml:"republish_jitter_seconds"`
+	RepublishSmearSeconds  int `toml:"republish_smear_seconds"`
+	// PublishJournal journals the put of each published record to the DHT before the publish is acknowledged, and
+	// replays the puts left unfinished on startup, so a crash between storing a record and putting it doesn't leave it
+	// off the DHT until it is next republished
+	PublishJournal bool `toml:"publish_journal"`
+	// CacheURI is the cache of resolved records: memory:// for a cache local to the instance, redis://<host>:<port>
+	// for a cache shared by all instances, or none:// to disable caching
+	CacheURI         string `toml:"cache_uri"`
 	CacheTTLSeconds  int    `toml:"cache_ttl_seconds"`
 	CacheSizeLimitMB int    `toml:"cache_size_limit_mb"`
+	// CacheShards is the number of shards of a cache local to the instance, each locked separately, which must be a
+	// power of two. CacheMaxEntrySize and CacheMaxEntriesInWindow are the size in bytes of the entries, and the number
+	// of entries cached within a TTL, its shards are initially sized for, and CacheCleanWindowSeconds the interval
+	// expired entries are evicted on, half the TTL if zero. GET /admin/cache reports the memory taken by each shard,
+	// to tune them to the records cached.
+	CacheShards             int `toml:"cache_shards"`
+	CacheMaxEntrySize       int `toml:"cache_max_entry_size"`
+	CacheMaxEntriesInWindow int `toml:"cache_max_entries_in_window"`
+	CacheCleanWindowSeconds int `toml:"cache_clean_window_seconds"`
+	// AdaptiveCacheTTL derives the TTL of each cached record from how often it is observed to change, between
+	// CacheMinTTLSeconds and CacheMaxTTLSeconds, starting from CacheTTLSeconds. Records which change often are
+	// resolved again sooner, and the TTL of records grows while they stay unchanged.
+	AdaptiveCacheTTL   bool `toml:"adaptive_cache_ttl"`
+	CacheMinTTLSeconds int  `toml:"cache_min_ttl_seconds"`
+	CacheMaxTTLSeconds int  `toml:"cache_max_ttl_seconds"`
+	// RecordCacheTTL caches each record for the smallest TTL of the DNS records in its packet, as its publisher
+	// intends, between CacheMinTTLSeconds and CacheMaxTTLSeconds, rather than for CacheTTLSeconds. With
+	// AdaptiveCacheTTL, a record is cached for the shorter of the two TTLs.
+	RecordCacheTTL bool `toml:"record_cache_ttl"`
+	// CacheCheckCRON is the schedule cached records are checked against storage on, evicting the records of denied
+	// IDs and records older than those stored. If empty, the cache is only checked on request.
+	CacheCheckCRON string `toml:"cache_check_cron"`
+	// AllowedKeys, if not empty, are the only z-base-32 encoded IDs records are accepted for
+	AllowedKeys []string `toml:"allowed_keys"`
+	// DeniedKeys are z-base-32 encoded IDs records are never accepted for
+	DeniedKeys []string `toml:"denied_keys"`
+	// FallbackGateways are the base URLs of other gateways records are resolved from, in order, when neither the DHT
+	// nor storage has them, e.g. https://diddht.tbddev.org
+	FallbackGateways       []string `toml:"fallback_gateways"`
+	FallbackTimeoutSeconds int      `toml:"fallback_timeout_seconds"`
+	// FallbackHedgeMillis, if not zero, is how long resolving a record from the fallback gateways, best first by
+	// latency and error rate, waits for a gateway before also trying the next, trading requests for tail latency.
+	// Gateways are tried in turn if zero.
+	FallbackHedgeMillis int `toml:"fallback_hedge_millis"`
+	// FallbackRegistryURL, if set, is a registry of gateways, a JSON object whose gateways each have the url of a
+	// gateway, which are added to the fallback gateways while they pass health checks
+	FallbackRegistryURL string `toml:"fallback_registry_url"`
+	// FallbackGatewayDIDs are the did:dht identifiers of gateways announcing themselves, whose endpoints are resolved
+	// through the DHT and added to the fallback gateways while they pass health checks
+	FallbackGatewayDIDs []string `toml:"fallback_gateway_dids"`
+	// FallbackDiscoveryCRON is the schedule the fallback gateways are discovered and health-checked on, keeping only
+	// the healthy ones. The fallback gateways aren't checked if empty.
+	FallbackDiscoveryCRON string `toml:"fallback_discovery_cron"`
+	// ColdStartGateways are the base URLs of peer gateways the records neither the DHT nor storage has are read
+	// through from, and stored, during the first ColdStartHours after startup, so a new deployment is useful before
+	// its storage fills up. They are resolved from with the timeout and hedging of the fallback gateways.
+	ColdStartGateways []string `toml:"cold_start_gateways"`
+	ColdStartHours    int      `toml:"cold_start_hours"`
+	// OutboundProxyURL, if set, is the socks5:// URL of a proxy, such as Tor, or the http(s):// URL of a proxy, which
+	// HTTP requests to other gateways go through: resolving records from the fallback gateways, discovering and
+	// health-checking them, and fetching crawl seed feeds. DHT traffic stays direct.
+	OutboundProxyURL string `toml:"outbound_proxy_url"`
+	// MaxFutureSeqSeconds, if not zero, rejects records whose seq, as a unix timestamp in the SeqUnit, is further in
+	// the future than this, tolerating clients with skewed clocks. A record with a seq far in the future can't be
+	// superseded until then, so this keeps a client's clock bug from bricking its key.
+	MaxFutureSeqSeconds int `toml:"max_future_seq_seconds"`
+	// SeqUnit is the unit of the unix timestamps clients use as seqs, which MaxFutureSeqSeconds checks them in:
+	// seconds, milliseconds, or microseconds
+	SeqUnit SeqUnit `toml:"seq_unit"`
+	// AssignSeq serves the seq the next record for an ID should use, for managed publishing flows which leave
+	// choosing seqs to the gateway
+	AssignSeq bool `toml:"assign_seq"`
+	// BatchGetLimit is the most IDs a single batch get request may resolve
+	BatchGetLimit int `toml:"batch_get_limit"`
+	// BatchGetConcurrency is the most records of a batch get request resolved at once
+	BatchGetConcurrency int `toml:"batch_get_concurrency"`
+	// MaxWaitSeconds caps how long a resolution may wait for a record which isn't found yet to be published
+	MaxWaitSeconds int `toml:"max_wait_seconds"`
+	// WaitRecheckSeconds is how often waiting resolutions recheck the DHT for records published elsewhere
+	WaitRecheckSeconds int `toml:"wait_recheck_seconds"`
+	// AdoptOnResolve stores records resolved from the DHT or fallback gateways, so they are republished like records
+	// published to the gateway, keeping the records its users resolve alive on the DHT
+	AdoptOnResolve bool `toml:"adopt_on_resolve"`
+	// AdoptMaxRecords, if not zero, stops adopting new records once storage holds this many records
+	AdoptMaxRecords int `toml:"adopt_max_records"`
+	// BurstMaxUpdates, if not zero, cools down a key which updates its records more than this many times within
+	// BurstWindowSeconds, rejecting its updates for BurstCooldownSeconds
+	BurstMaxUpdates      int `toml:"burst_max_updates"`
+	BurstWindowSeconds   int `toml:"burst_window_seconds"`
+	BurstCooldownSeconds int `toml:"burst_cooldown_seconds"`
+	// RequirePublishAuth only accepts records published by their DID's controller, or a delegate it authorized with
+	// a capability token, authenticated by an HTTP Message Signature over the request
+	RequirePublishAuth bool `toml:"require_publish_auth"`
+	// ServiceEndpointPolicy is how published DID Documents with service endpoints which aren't absolute URIs of one of
+	// the ServiceEndpointSchemes are treated: off (the default) accepts them, warn logs them, and reject rejects them
+	ServiceEndpointPolicy EndpointPolicy `toml:"service_endpoint_policy"`
+	// ServiceEndpointSchemes are the URI schemes service endpoints may have, https if empty
+	ServiceEndpointSchemes []string `toml:"service_endpoint_schemes"`
+	// LintPublishes lints the DID Documents of published records, returning the codes of its warnings, such as keys
+	// without a purpose, in the Lint-Warnings header of the publish response
+	LintPublishes bool `toml:"lint_publishes"`
+}
+
+type IndexConfig struct {
+	// Enabled indexes the DID Documents of stored records so they can be queried by their contents
+	Enabled bool `toml:"enabled"`
+}
+
+type DIDWebConfig struct {
+	// Enabled serves the DID Documents of did:dht records as did:web documents at /.well-known/did.json
+	Enabled bool `toml:"enabled"`
+	// Domains maps each domain (the Host of the request, including any port) to the z-base-32 encoded ID
+	// of the did:dht record it serves
+	Domains map[string]string `toml:"domains"`
+}
+
+type DNSConfig struct {
+	// Enabled answers DNS queries for stored records authoritatively over UDP and TCP
+	Enabled bool `toml:"enabled"`
+	// ListenAddress is the address the DNS listeners bind to
+	ListenAddress string `toml:"listen_address"`
+}
+
+type ArchiveConfig struct {
+	// Enabled periodically pins snapshots of all stored records, and a manifest of their CIDs, to an IPFS node
+	Enabled bool `toml:"enabled"`
+	// IPFSAPIURL is the URL of the IPFS node's HTTP RPC API
+	IPFSAPIURL string `toml:"ipfs_api_url"`
+	// ArchiveCRON is the schedule archives are taken on
+	ArchiveCRON string `toml:"archive_cron"`
+	// IPNSKey is the name of the node's key the latest manifest is published to IPNS under, or empty to not publish
+	IPNSKey string `toml:"ipns_key"`
+}
+
+type HistoryConfig struct {
+	// Enabled keeps a hash-chained log of all record updates, with a periodically signed Merkle root of the log
+	Enabled bool `toml:"enabled"`
+	// CheckpointCRON is the schedule the Merkle root of the log is signed on
+	CheckpointCRON string `toml:"checkpoint_cron"`
+}
+
+type FeedConfig struct {
+	// Enabled keeps an append-only feed of the records first seen or updated by the gateway, served at /feed for
+	// indexers to tail
+	Enabled bool `toml:"enabled"`
+}
+
+type AttestationConfig struct {
+	// Enabled signs an attestation that the gateway served a record, with the server's signing key, on each resolution
+	Enabled bool `toml:"enabled"`
+	// SignResponses wraps the responses to requests accepting application/jose in a JWS signed with the server's
+	// signing key, so clients behind untrusted proxies and CDNs can verify the whole response
+	SignResponses bool `toml:"sign_responses"`
+}
+
+type AdminConfig struct {
+	// Token is the bearer token required by the admin API, which is disabled if empty
+	Token string `toml:"token"`
+	// ListenAddress, if set, is the address the admin API is served on instead of with the public API, such as an
+	// address on a private network
+	ListenAddress string `toml:"listen_address"`
+	// Pprof serves the runtime profiles of the process at /admin/debug/pprof
+	Pprof bool `toml:"pprof"`
+}
+
+type GeoIPConfig struct {
+	// DatabasePath is the path of a MaxMind GeoIP2 or GeoLite2 Country (or City) database used to add the client's
+	// country to request logs and per-country request stats, which are disabled if empty
+	DatabasePath string `toml:"database_path"`
+}
+
+type DocsConfig struct {
+	// SwaggerUI serves a Swagger UI for the OpenAPI specification of the API at /swagger/index.html
+	SwaggerUI bool `toml:"swagger_ui"`
+}
+
+type APIConfig struct {
+	// LegacyRoutes serves the versioned API at its unversioned paths too, marked as deprecated
+	LegacyRoutes bool `toml:"legacy_routes"`
+	// LegacyDeprecationDate is the date (YYYY-MM-DD) the unversioned paths were deprecated on
+	LegacyDeprecationDate string `toml:"legacy_deprecation_date"`
+	// LegacySunsetDate is the date (YYYY-MM-DD) after which the unversioned paths may stop being served, if scheduled
+	LegacySunsetDate string `toml:"legacy_sunset_date"`
+}
+
+type EncryptionConfig struct {
+	// Keys are base64url encoded 32 byte AES-256 keys which record values are encrypted with at rest. Values are
+	// encrypted with the first key and decrypted with any of them, so keys are rotated by adding the new key first.
+	// Encryption is disabled if no keys are configured.
+	Keys []string `toml:"keys"`
+	// KeysFile is the path of a file of whitespace-separated keys, such as a mounted secret, which come before Keys
+	KeysFile string `toml:"keys_file"`
+	// HashKeys stores records under an HMAC of their key, keeping the key itself in the encrypted value, so storage
+	// doesn't reveal which keys the gateway holds records for. It requires encryption, and trades away lookups by
+	// key beyond exact matches: the document index, history log, and change feed, which are keyed by ID, must be
+	// disabled.
+	HashKeys bool `toml:"hash_keys"`
+	// KeyHashSecret is the base64url encoded secret, of at least 32 bytes, keys are hashed with. Changing it makes
+	// records stored under the previous secret unreadable.
+	KeyHashSecret string `toml:"key_hash_secret"`
+}
+
+type EquivocationConfig struct {
+	// WebhookURL, if set, is sent a POST of the JSON evidence of each key newly found to have signed different records
+	// with the same seq
+	WebhookURL string `toml:"webhook_url"`
+}
+
+type RetentionConfig struct {
+	// MaxRecords is the maximum number of records stored, unlimited if zero
+	MaxRecords int64 `toml:"max_records"`
+	// MaxBytes is the maximum approximate size of the stored records and their versions, unlimited if zero
+	MaxBytes int64 `toml:"max_bytes"`
+	// Eviction is the policy applied once storage is full: rejecting new records, or evicting stored ones
+	Eviction EvictionPolicy `toml:"eviction"`
+	// EvictToPercent is the percentage of the limits storage is reduced to when evicting, leaving room for new
+	// records before evicting again
+	EvictToPercent int `toml:"evict_to_percent"`
+	// KeepLabels are the labels operators attach to records to exempt them from eviction
+	KeepLabels []string `toml:"keep_labels"`
+	// MaxVersionsPerRecord is the maximum number of versions kept in the version history of each record, and
+	// MaxVersions of all records together, unlimited if zero. Beyond them, the versions with the lowest seqs are
+	// pruned, other than the current version of each record.
+	MaxVersionsPerRecord int   `toml:"max_versions_per_record"`
+	MaxVersions          int64 `toml:"max_versions"`
+	// PruneVersionsCRON is the schedule versions beyond the limits are pruned on
+	PruneVersionsCRON string `toml:"prune_versions_cron"`
+}
+
+type CDCConfig struct {
+	// Sink is the message broker each record created, updated, or deleted is published to: kafka, through a Kafka
+	// REST Proxy, or nats. Change data capture is disabled if empty.
+	Sink CDCSink `toml:"sink"`
+	// URL is the URL of the Kafka REST Proxy, or of the NATS server
+	URL string `toml:"url"`
+	// Topic is the Kafka topic, or the NATS subject the ID of the changed record is appended to as the last token
+	Topic string `toml:"topic"`
+	// Format is the serialization of the changes, json (the default) or protobuf
+	Format CDCFormat `toml:"format"`
+}
+
+type SLAConfig struct {
+	// DailyCRON and WeeklyCRON are the schedules, in UTC, summaries of the previous day's and week's resolution and
+	// publish success rates and latency are sent on, if a webhook or email recipients are configured
+	DailyCRON  string `toml:"daily_cron"`
+	WeeklyCRON string `toml:"weekly_cron"`
+	// WebhookURL, if set, is sent a POST of the JSON report of each summary
+	WebhookURL string `toml:"webhook_url"`
+	// SMTPURL is the smtp:// URL of the mail server summaries are emailed through, with the credentials to
+	// authenticate with, if any, as its user info
+	SMTPURL string `toml:"smtp_url"`
+	// EmailFrom is the sender, and EmailTo the recipients, of the emailed summaries
+	EmailFrom string   `toml:"email_from"`
+	EmailTo   []string `toml:"email_to"`
+}
+
+type PrivacyConfig struct {
+	// Enabled stops the gateway from keeping anything per DID about resolutions, only aggregates, and scrubs did:dht
+	// identifiers, record keys and values from its logs. It is incompatible with lru eviction, which tracks when each
+	// record was last resolved, and with adopting resolved records, which stores them.
+	Enabled bool `toml:"enabled"`
+}
+
+// TenantConfig attributes the records published with the API key of a tenant to it, and limits the records each
+// tenant stores. Beyond a soft limit publishes are accepted with a warning; beyond a hard limit they are rejected.
+// Limits are unlimited if zero.
+type TenantConfig struct {
+	// Tenants are the tenants publishing to the gateway, which is open to publishes without an API key if empty
+	Tenants []Tenant `toml:"tenant"`
+	// RequireAPIKey rejects publishes without the API key of a tenant
+	RequireAPIKey bool `toml:"require_api_key"`
+	// SoftMaxRecords, HardMaxRecords, SoftMaxBytes, and HardMaxBytes are the limits of tenants without their own, of
+	// the number of records each stores, and the size of their DNS packets
+	SoftMaxRecords int64 `toml:"soft_max_records"`
+	HardMaxRecords int64 `toml:"hard_max_records"`
+	SoftMaxBytes   int64 `toml:"soft_max_bytes"`
+	HardMaxBytes   int64 `toml:"hard_max_bytes"`
+}
+
+// Tenant is a tenant publishing records with its API key, with its own limits, if not zero
+type Tenant struct {
+	// Name identifies the tenant in its usage, up to 64 characters
+	Name   string `toml:"name"`
+	APIKey string `toml:"api_key"`
+	// SoftMaxRecords, HardMaxRecords, SoftMaxBytes, and HardMaxBytes override the limits of the TenantConfig
+	SoftMaxRecords int64 `toml:"soft_max_records"`
+	HardMaxRecords int64 `toml:"hard_max_records"`
+	SoftMaxBytes   int64 `toml:"soft_max_bytes"`
+	HardMaxBytes   int64 `toml:"hard_max_bytes"`
 }
 
 type LogConfig struct {
@@ -70,20 +608,84 @@ type LogConfig struct {
 func GetDefaultConfig() Config {
 	return Config{
 		ServerConfig: ServerConfig{
-			Environment: EnvironmentDev,
-			APIHost:     "0.0.0.0",
-			APIPort:     8305,
-			BaseURL:     "http://localhost:8305",
-			LogLocation: "log",
-			StorageURI:  "bolt://diddht.db",
+			Environment:   EnvironmentDev,
+			APIHost:       "0.0.0.0",
+			APIPort:       8305,
+			BaseURL:       "http://localhost:8305",
+			LogLocation:   "log",
+			StorageURI:    "bolt://diddht.db",
+			ReconcileCRON: "15 */6 * * *",
+			Role:          RoleAll,
+		},
+		TLSConfig: TLSConfig{
+			PublishClientAuth: ClientAuthNone,
+			AdminClientAuth:   ClientAuthNone,
 		},
 		DHTConfig: DHTServiceConfig{
-			BootstrapPeers: GetDefaultBootstrapPeers(),
+			BootstrapPeers:    GetDefaultBootstrapPeers(),
+			ReplicationFactor: 8,
+			GetMaxOutstanding: 15,
+			GetTimeoutSeconds: 10,
+			PeerBanThreshold:  10,
+			PeerBanSeconds:    600,
 		},
 		PkarrConfig: PKARRServiceConfig{
-			RepublishCRON:    "0 */2 * * *",
-			CacheTTLSeconds:  600,
-			CacheSizeLimitMB: 500,
+			RepublishCRON:           "0 */2 * * *",
+			CacheCheckCRON:          "30 * * * *",
+			CacheURI:                "memory://",
+			CacheTTLSeconds:         600,
+			CacheSizeLimitMB:        500,
+			CacheShards:             1024,
+			CacheMaxEntrySize:       1000,
+			CacheMaxEntriesInWindow: 600000,
+			CacheMinTTLSeconds:      60,
+			CacheMaxTTLSeconds:      21600,
+			FallbackTimeoutSeconds:  5,
+			FallbackHedgeMillis:     500,
+			FallbackDiscoveryCRON:   "*/15 * * * *",
+			ColdStartHours:          6,
+			SeqUnit:                 SeqUnitSeconds,
+			BatchGetLimit:           100,
+			BatchGetConcurrency:     10,
+			MaxWaitSeconds:          30,
+			WaitRecheckSeconds:      5,
+			BurstWindowSeconds:      60,
+			BurstCooldownSeconds:    600,
+			ServiceEndpointPolicy:   EndpointPolicyOff,
+			ServiceEndpointSchemes:  []string{"https"},
+			LintPublishes:           true,
+		},
+		DNSConfig: DNSConfig{
+			ListenAddress: "0.0.0.0:5353",
+		},
+		ArchiveConfig: ArchiveConfig{
+			IPFSAPIURL:  "http://localhost:5001",
+			ArchiveCRON: "0 0 * * *",
+			IPNSKey:     "self",
+		},
+		HistoryConfig: HistoryConfig{
+			CheckpointCRON: "*/10 * * * *",
+		},
+		DocsConfig: DocsConfig{
+			SwaggerUI: true,
+		},
+		APIConfig: APIConfig{
+			LegacyRoutes:          true,
+			LegacyDeprecationDate: "2026-10-16",
+		},
+		RetentionConfig: RetentionConfig{
+			Eviction:          EvictionNone,
+			EvictToPercent:    90,
+			KeepLabels:        []string{"pinned"},
+			PruneVersionsCRON: "40 * * * *",
+		},
+		CDCConfig: CDCConfig{
+			Topic:  "did-dht.records",
+			Format: CDCFormatJSON,
+		},
+		SLAConfig: SLAConfig{
+			DailyCRON:  "5 0 * * *",
+			WeeklyCRON: "10 0 * * 1",
 		},
 		Log: LogConfig{
 			Level: logrus.InfoLevel.String(),
@@ -113,7 +715,7 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 
-	if err = applyEnvVariables(cfg); err != nil {
+	if err = applyEnvVariables(&cfg); err != nil {
 		return nil, errors.Wrap(err, "apply env variables")
 	}
 	return &cfg, nil
@@ -139,7 +741,7 @@ func loadTOMLConfig(path string, cfg *Config) error {
 	return nil
 }
 
-func applyEnvVariables(cfg Config) error {
+func applyEnvVariables(cfg *Config) error {
 	if err := godotenv.Load(DefaultEnvPath); err != nil {
 		// The error indicates that the file or directory does not exist.
 		if os.IsNotExist(err) {
@@ -153,6 +755,14 @@ func applyEnvVariables(cfg Config) error {
 	if present {
 		cfg.DHTConfig.BootstrapPeers = strings.Split(bootstrapPeers, ",")
 	}
+	encryptionKeys, present := os.LookupEnv(EncryptionKeys.String())
+	if present {
+		cfg.EncryptionConfig.Keys = strings.Split(encryptionKeys, ",")
+	}
+	keyHashSecret, present := os.LookupEnv(KeyHashSecret.String())
+	if present {
+		cfg.EncryptionConfig.KeyHashSecret = keyHashSecret
+	}
 	return nil
 }
 
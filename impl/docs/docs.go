@@ -0,0 +1,11 @@
+// Package docs embeds the API specification generated from the handler annotations by `mage spec`
+package docs
+
+import (
+	_ "embed"
+)
+
+// SwaggerYAML is the Swagger 2.0 specification of the API
+//
+//go:embed swagger.yaml
+var SwaggerYAML []byte
@@ -7,7 +7,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 
-	"github.com/TBD54566975/did-dht-method/internal"
+	"github.com/TBD54566975/did-dht-method/impl/internal"
 )
 
 const (
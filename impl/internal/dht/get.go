@@ -5,14 +5,18 @@ import (
 	"crypto/sha1"
 	"errors"
 	"math"
+	"slices"
+	"time"
 
 	"github.com/anacrolix/log"
 	"github.com/anacrolix/torrent/bencode"
 
 	"github.com/anacrolix/dht/v2"
 	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/dht/v2/int160"
 	"github.com/anacrolix/dht/v2/krpc"
 	"github.com/anacrolix/dht/v2/traversal"
+	"github.com/anacrolix/dht/v2/types"
 )
 
 // Copied from https://github.com/anacrolix/dht/blob/master/exts/getput/getput.go and modified
@@ -23,16 +27,39 @@ type FullGetResult struct {
 	V       bencode.Bytes
 	Sig     [64]byte
 	Mutable bool
+	// Seqs are the distinct seqs of the values nodes held, in ascending order, if the get searched for the highest
+	Seqs []int64
+	// Partial is whether the context was done before the traversal finished, so the value is the best found so far
+	// and nodes not yet queried may hold a newer seq
+	Partial bool
 }
 
+// defaultAlpha is the most nodes a traversal queries at once by default
+const defaultAlpha = 15
+
+// nodeValue is a value found by a traversal, and the node which responded with it
+type nodeValue struct {
+	FullGetResult
+	from krpc.NodeAddr
+}
+
+// startGetTraversal starts finding the k nodes closest to the target, the traversal's default if zero, querying up
+// to alpha nodes at once, defaultAlpha if not positive, and telling the observer, if any, how each node responded.
+// The skip starting nodes closest to the target, which a traversal queries first, are left out, so the traversal
+// starts from other nodes than one which doesn't skip them. It fails, having stopped the traversal, if the server
+// knows no nodes to start from.
 func startGetTraversal(
-	target bep44.Target, s *dht.Server, seq *int64, salt []byte,
+	target bep44.Target, s *dht.Server, seq *int64, salt []byte, k, alpha, skip int, observer QueryObserver,
 ) (
-	vChan chan FullGetResult, op *traversal.Operation, err error,
+	vChan chan nodeValue, op *traversal.Operation, err error,
 ) {
-	vChan = make(chan FullGetResult)
+	if alpha <= 0 {
+		alpha = defaultAlpha
+	}
+	vChan = make(chan nodeValue)
 	op = traversal.Start(traversal.OperationInput{
-		Alpha:  15,
+		Alpha:  alpha,
+		K:      k,
 		Target: target,
 		DoQuery: func(ctx context.Context, addr krpc.NodeAddr) traversal.QueryResult {
 			logger := log.ContextLogger(ctx)
@@ -41,30 +68,33 @@ func startGetTraversal(
 			if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, dht.TransactionTimeout) {
 				logger.Levelf(log.Debug, "error querying %v: %v", addr, err)
 			}
+			malformed := false
 			if r := res.Reply.R; r != nil {
 				rv := r.V
 				bv := rv
 				if sha1.Sum(bv) == target {
 					select {
-					case vChan <- FullGetResult{
+					case vChan <- nodeValue{FullGetResult: FullGetResult{
 						V:   rv,
 						Sig: r.Sig,
-					}:
+					}, from: addr}:
 					case <-ctx.Done():
 					}
 				} else if sha1.Sum(append(r.K[:], salt...)) == target && bep44.Verify(r.K[:], salt, *r.Seq, bv, r.Sig[:]) {
 					select {
-					case vChan <- FullGetResult{
+					case vChan <- nodeValue{FullGetResult: FullGetResult{
 						Seq: *r.Seq,
 						V:   rv,
 						Sig: r.Sig,
-					}:
+					}, from: addr}:
 					case <-ctx.Done():
 					}
 				} else if rv != nil {
 					logger.Levelf(log.Debug, "get response item hash didn't match target: %q", rv)
+					malformed = true
 				}
 			}
+			observer.observe(addr, err, malformed)
 			tqr := res.TraversalQueryResult(addr)
 			// Filter replies from nodes that don't have a string token. This doesn't look prettier
 			// with generics. "The token value should be a short binary string." ¯\_(ツ)_/¯ (BEP 5).
@@ -77,17 +107,54 @@ func startGetTraversal(
 		NodeFilter: s.TraversalNodeFilter,
 	})
 	nodes, err := s.TraversalStartingNodes()
+	if err != nil {
+		op.Stop()
+		return
+	}
+	if skip > 0 {
+		nodes = skipClosestNodes(nodes, target, skip)
+	}
 	op.AddNodes(nodes)
 	return
 }
 
+// skipClosestNodes returns the nodes without the n closest to the target, or all of them if no others would be left.
+// Nodes of unknown ID are kept.
+func skipClosestNodes(nodes []types.AddrMaybeId, target bep44.Target, n int) []types.AddrMaybeId {
+	var known, unknown []types.AddrMaybeId
+	for _, node := range nodes {
+		if node.Id.Ok {
+			known = append(known, node)
+		} else {
+			unknown = append(unknown, node)
+		}
+	}
+	if len(known)+len(unknown) <= n {
+		return nodes
+	}
+	t := int160.FromByteArray(target)
+	slices.SortFunc(known, func(a, b types.AddrMaybeId) int {
+		return a.Id.Value.Distance(t).Cmp(b.Id.Value.Distance(t))
+	})
+	return append(known[min(n, len(known)):], unknown...)
+}
+
+// Get finds the value of the target, querying up to alpha nodes at once, and starting from other nodes than usual if
+// skipClosest is set, such as for a hedged get. If the context is done before the traversal stalls, the value with the
+// highest seq found so far is returned as Partial, or the context's error if none was.
 func Get(
-	ctx context.Context, target bep44.Target, s *dht.Server, seq *int64, salt []byte,
+	ctx context.Context, target bep44.Target, s *dht.Server, seq *int64, salt []byte, alpha int, skipClosest bool,
+	observer QueryObserver,
 ) (
 	ret FullGetResult, stats *traversal.Stats, err error,
 ) {
-	vChan, op, err := startGetTraversal(target, s, seq, salt)
+	skip := 0
+	if skipClosest {
+		skip = max(alpha, defaultAlpha)
+	}
+	vChan, op, err := startGetTraversal(target, s, seq, salt, 0, alpha, skip, observer)
 	if err != nil {
+		stats = new(traversal.Stats)
 		return
 	}
 	ret.Seq = math.MinInt64
@@ -102,17 +169,74 @@ receiveResults:
 		log.ContextLogger(ctx).Levelf(log.Debug, "received %#v", v)
 		gotValue = true
 		if !v.Mutable {
-			ret = v
+			ret = v.FullGetResult
 			break
 		}
 		if v.Seq >= ret.Seq {
-			ret = v
+			ret = v.FullGetResult
+		}
+		goto receiveResults
+	case <-ctx.Done():
+		if gotValue {
+			ret.Partial = true
+		} else {
+			err = ctx.Err()
+		}
+	}
+	op.Stop()
+	stats = op.Stats()
+	return
+}
+
+// GetHighestSeq is Get, modified to keep the traversal going for up to search after the first value is found rather
+// than returning it, since nodes further along may hold a newer seq. It returns the value with the highest seq found,
+// with the distinct seqs seen, of which there are several if nodes disagree on the latest value. Like Get, it queries
+// up to alpha nodes at once, and returns the value as Partial if the context is done first.
+func GetHighestSeq(
+	ctx context.Context, target bep44.Target, s *dht.Server, salt []byte, search time.Duration, alpha int,
+	observer QueryObserver,
+) (
+	ret FullGetResult, stats *traversal.Stats, err error,
+) {
+	vChan, op, err := startGetTraversal(target, s, nil, salt, 0, alpha, 0, observer)
+	if err != nil {
+		stats = new(traversal.Stats)
+		return
+	}
+	seen := make(map[int64]struct{})
+	var searched <-chan time.Time
+receiveResults:
+	select {
+	case <-op.Stalled():
+		if len(seen) == 0 {
+			err = errors.New("value not found")
 		}
+	case v := <-vChan:
+		log.ContextLogger(ctx).Levelf(log.Debug, "received %#v", v)
+		if len(seen) == 0 {
+			timer := time.NewTimer(search)
+			defer timer.Stop()
+			searched = timer.C
+			ret = v.FullGetResult
+		} else if v.Seq > ret.Seq {
+			ret = v.FullGetResult
+		}
+		seen[v.Seq] = struct{}{}
 		goto receiveResults
+	case <-searched:
 	case <-ctx.Done():
-		err = ctx.Err()
+		// the highest seq found so far is returned once the context is done
+		if len(seen) == 0 {
+			err = ctx.Err()
+		} else {
+			ret.Partial = true
+		}
 	}
 	op.Stop()
 	stats = op.Stats()
+	for seq := range seen {
+		ret.Seqs = append(ret.Seqs, seq)
+	}
+	slices.Sort(ret.Seqs)
 	return
 }
@@ -0,0 +1,44 @@
+package dht
+
+import (
+	"context"
+	"errors"
+
+	"github.com/anacrolix/dht/v2"
+	"github.com/anacrolix/dht/v2/krpc"
+)
+
+// QueryOutcome is how a node responded to a query
+type QueryOutcome int
+
+const (
+	// QueryResponded is a well-formed response
+	QueryResponded QueryOutcome = iota
+	// QueryTimedOut is a query the node never responded to
+	QueryTimedOut
+	// QueryFailed is a query the node responded to with an error, or which couldn't be sent to it
+	QueryFailed
+	// QueryMalformed is a response holding a value which doesn't match the target or its signature
+	QueryMalformed
+)
+
+// QueryObserver is told the outcome of each query made to a node, such as to keep the reputation of nodes
+type QueryObserver func(addr krpc.NodeAddr, outcome QueryOutcome)
+
+// observe tells the observer, if any, the outcome of the query. Queries stopped by their context say nothing about
+// the node, so aren't observed.
+func (o QueryObserver) observe(addr krpc.NodeAddr, err error, malformed bool) {
+	if o == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	switch {
+	case errors.Is(err, dht.TransactionTimeout):
+		o(addr, QueryTimedOut)
+	case err != nil:
+		o(addr, QueryFailed)
+	case malformed:
+		o(addr, QueryMalformed)
+	default:
+		o(addr, QueryResponded)
+	}
+}
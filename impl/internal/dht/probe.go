@@ -0,0 +1,59 @@
+package dht
+
+import (
+	"context"
+
+	"github.com/anacrolix/dht/v2"
+	"github.com/anacrolix/dht/v2/bep44"
+	k_nearest_nodes "github.com/anacrolix/dht/v2/k-nearest-nodes"
+	"github.com/anacrolix/dht/v2/krpc"
+	"github.com/anacrolix/dht/v2/traversal"
+)
+
+// ProbedNode is a node of a target's neighborhood, and the seq of the target's value it holds, if it holds one
+type ProbedNode struct {
+	ID    krpc.ID
+	Addr  krpc.NodeAddr
+	Holds bool
+	Seq   int64
+}
+
+// Probe finds the k nodes closest to the target, starting from the neighborhood-th group of defaultAlpha starting nodes
+// closest to it, so probes of distinct neighborhoods start from distinct nodes. It returns the nodes found, closest
+// first, with the seq of the value each holds, which is what a get would have resolved from each of them.
+func Probe(
+	ctx context.Context, target bep44.Target, s *dht.Server, salt []byte, k, neighborhood int, observer QueryObserver,
+) (
+	nodes []ProbedNode, stats *traversal.Stats, err error,
+) {
+	vChan, op, err := startGetTraversal(target, s, nil, salt, k, 0, neighborhood*defaultAlpha, observer)
+	if err != nil {
+		return
+	}
+	held := make(map[string]int64)
+receive:
+	for {
+		select {
+		case v := <-vChan:
+			if seq, ok := held[v.from.String()]; !ok || v.Seq > seq {
+				held[v.from.String()] = v.Seq
+			}
+		case <-op.Stalled():
+			break receive
+		case <-ctx.Done():
+			err = ctx.Err()
+			break receive
+		}
+	}
+	op.Stop()
+	stats = op.Stats()
+	if err != nil {
+		return
+	}
+	op.Closest().Range(func(elem k_nearest_nodes.Elem) {
+		addr := elem.Addr.ToNodeAddr()
+		seq, holds := held[addr.String()]
+		nodes = append(nodes, ProbedNode{ID: elem.ID, Addr: addr, Holds: holds, Seq: seq})
+	})
+	return
+}
@@ -0,0 +1,88 @@
+package dht
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/anacrolix/dht/v2"
+	"github.com/anacrolix/dht/v2/bep44"
+	k_nearest_nodes "github.com/anacrolix/dht/v2/k-nearest-nodes"
+	"github.com/anacrolix/dht/v2/traversal"
+	"github.com/anacrolix/log"
+)
+
+// Modified from getput.Put to target a configurable number of closest nodes, put to further nodes when fewer of them
+// store the value, and report how many did
+
+// PutResult is the number of nodes a value was put to, and how many of them stored it
+type PutResult struct {
+	Attempted int
+	Stored    int
+}
+
+// Put puts the value to the replication closest nodes to its target. The traversal finds up to candidates closest
+// nodes, and while fewer than replication nodes stored the value, it is put to the next closest of them. The observer,
+// if any, is told how each node responded.
+func Put(
+	ctx context.Context, s *dht.Server, put bep44.Put, replication, candidates int, observer QueryObserver,
+) (
+	ret PutResult, stats *traversal.Stats, err error,
+) {
+	// the seq of the values the nodes have doesn't matter, but the salt is needed to match their responses
+	vChan, op, err := startGetTraversal(put.Target(), s, nil, put.Salt, max(replication, candidates), 0, 0, observer)
+	if err != nil {
+		stats = new(traversal.Stats)
+		return
+	}
+drain:
+	select {
+	case <-vChan:
+		goto drain
+	case <-op.Stalled():
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	op.Stop()
+	stats = op.Stats()
+	if err != nil {
+		return
+	}
+
+	var closest []k_nearest_nodes.Elem
+	op.Closest().Range(func(elem k_nearest_nodes.Elem) {
+		closest = append(closest, elem)
+	})
+	for len(closest) > 0 && ret.Stored < replication && ctx.Err() == nil {
+		batch := closest[:min(replication-ret.Stored, len(closest))]
+		closest = closest[len(batch):]
+		ret.Attempted += len(batch)
+		ret.Stored += putAll(ctx, s, put, batch, observer)
+	}
+	return
+}
+
+// putAll puts the value to all the given nodes at once, returning the number which stored it
+func putAll(ctx context.Context, s *dht.Server, put bep44.Put, nodes []k_nearest_nodes.Elem, observer QueryObserver) int {
+	logger := log.ContextLogger(ctx)
+	var stored atomic.Int64
+	var wg sync.WaitGroup
+	for _, elem := range nodes {
+		wg.Add(1)
+		go func(elem k_nearest_nodes.Elem) {
+			defer wg.Done()
+			// This is enforced by startGetTraversal.
+			token := elem.Data.(string)
+			res := s.Put(ctx, dht.NewAddr(elem.Addr.UDP()), put, token, dht.QueryRateLimiting{})
+			err := res.ToError()
+			observer.observe(elem.Addr.ToNodeAddr(), err, false)
+			if err != nil {
+				logger.Levelf(log.Debug, "error putting to %v [token=%q]: %v", elem.Addr, token, err)
+				return
+			}
+			stored.Add(1)
+		}(elem)
+	}
+	wg.Wait()
+	return int(stored.Load())
+}
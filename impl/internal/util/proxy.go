@@ -0,0 +1,34 @@
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// NewProxiedHTTPClient returns an HTTP client sending its requests through the proxy at the given URL: a socks5://
+// proxy, such as Tor, which resolves the hosts requested itself, or an http(s):// proxy. It returns the default client
+// if the URL is empty.
+func NewProxiedHTTPClient(proxyURL string) (*http.Client, error) {
+	if proxyURL == "" {
+		return http.DefaultClient, nil
+	}
+	proxy, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	switch proxy.Scheme {
+	case "socks5h":
+		// the transport always leaves resolving hosts to socks5 proxies, but older versions don't know socks5h
+		proxy.Scheme = "socks5"
+	case "socks5", "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", proxy.Scheme)
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("proxy url has no host: %s", proxyURL)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+	return &http.Client{Transport: transport}, nil
+}
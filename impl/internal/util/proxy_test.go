@@ -0,0 +1,43 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProxiedHTTPClient(t *testing.T) {
+	client, err := NewProxiedHTTPClient("")
+	require.NoError(t, err)
+	assert.Equal(t, http.DefaultClient, client)
+
+	_, err = NewProxiedHTTPClient("ftp://127.0.0.1:21")
+	assert.Error(t, err)
+	_, err = NewProxiedHTTPClient("socks5://")
+	assert.Error(t, err)
+
+	client, err = NewProxiedHTTPClient("socks5h://127.0.0.1:9050")
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, "http://gateway.onion/health", nil)
+	require.NoError(t, err)
+	proxy, err := client.Transport.(*http.Transport).Proxy(req)
+	require.NoError(t, err)
+	assert.Equal(t, "socks5://127.0.0.1:9050", proxy.String())
+
+	t.Run("requests go through the proxy", func(t *testing.T) {
+		proxied := make(chan string, 1)
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied <- r.URL.String()
+		}))
+		defer proxy.Close()
+		client, err := NewProxiedHTTPClient(proxy.URL)
+		require.NoError(t, err)
+		resp, err := client.Get("http://gateway.invalid/v1/records")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, "http://gateway.invalid/v1/records", <-proxied)
+	})
+}
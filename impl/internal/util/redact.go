@@ -0,0 +1,62 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted replaces the identifiers scrubbed from logs
+const Redacted = "[redacted]"
+
+// identifierPattern matches did:dht identifiers and the z-base-32 encoded keys of records, 52 characters of the
+// z-base-32 alphabet
+var identifierPattern = regexp.MustCompile(`\b(did:dht:)?[ybndrfg8ejkmcpqxot1uwisza345h769]{52}\b`)
+
+// base64URLPattern matches the base64url encoded keys of records, 43 characters of the base64url alphabet, with the
+// salt the key of a salted record is stored under, and longer base64url encoded values such as those of records. The
+// character before it is matched instead of a word boundary, since '-' isn't a word character.
+var base64URLPattern = regexp.MustCompile(`(^|[^A-Za-z0-9_-])[A-Za-z0-9_-]{43,}(\.[A-Za-z0-9_-]+)?`)
+
+// Redact returns the string with the did:dht identifiers, z-base-32 and base64url encoded record keys, and base64url
+// encoded values in it replaced
+func Redact(s string) string {
+	s = identifierPattern.ReplaceAllString(s, Redacted)
+	return base64URLPattern.ReplaceAllString(s, "${1}"+Redacted)
+}
+
+// RedactLogs scrubs did:dht identifiers, record keys and values from the entries of the standard logger, unless
+// they are already
+func RedactLogs() {
+	if _, ok := logrus.StandardLogger().Formatter.(RedactingFormatter); ok {
+		return
+	}
+	logrus.SetFormatter(RedactingFormatter{Formatter: logrus.StandardLogger().Formatter})
+}
+
+// RedactingFormatter formats log entries with the wrapped formatter, after scrubbing did:dht identifiers, record keys
+// and values from their message and fields, so logs don't reveal which DIDs are resolved or published
+type RedactingFormatter struct {
+	logrus.Formatter
+}
+
+// Format redacts a copy of the entry, since the entry's fields may be shared with other entries, and formats it
+func (f RedactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	redacted := *entry
+	redacted.Message = Redact(entry.Message)
+	redacted.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		switch v := v.(type) {
+		case string:
+			redacted.Data[k] = Redact(v)
+		case error:
+			redacted.Data[k] = Redact(v.Error())
+		case fmt.Stringer:
+			redacted.Data[k] = Redact(v.String())
+		default:
+			redacted.Data[k] = v
+		}
+	}
+	return f.Formatter.Format(&redacted)
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	id := "yj47pezutnpw9pyudeeai8cx8z8d6wg35genrkoqf9k3rmfzy58o"
+	assert.Equal(t, "resolved pkarr record[[redacted]] from cache", Redact("resolved pkarr record["+id+"] from cache"))
+	assert.Equal(t, "GET /v1/[redacted]?salt=c2FsdA", Redact("GET /v1/"+id+"?salt=c2FsdA"))
+	assert.Equal(t, "[redacted]", Redact("did:dht:"+id))
+	// longer strings aren't partially redacted, but whole, as they may be base64url encoded values
+	assert.Equal(t, Redacted, Redact(id+"o"))
+
+	key := "9aNzjyRG7ruz-Onsq_CZZo9Gv76J6ZBD6aWHdEhS_Io"
+	assert.Equal(t, "failed to replay put of pkarr record[[redacted]]", Redact("failed to replay put of pkarr record["+key+"]"))
+	assert.Equal(t, "record [redacted] stored", Redact("record "+key+".c2FsdA stored"), "with the salt of a salted record")
+	assert.Equal(t, "value=[redacted]", Redact("value=AACEAAAAAAIAAAAAA19rMARfZGlkAAAQAAEAABwgADc2aWQ9MDt0PTA7az05YU56"))
+	assert.Equal(t, "record[[redacted]]", Redact("record[-"+key[1:]+"]"), "keys starting with '-' are redacted whole")
+
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	logger.SetFormatter(RedactingFormatter{Formatter: &logrus.JSONFormatter{}})
+	entry := logger.WithField("path", "/v1/"+id).WithError(errors.New("failed to resolve " + id))
+	entry.Infof("resolving %s", id)
+	assert.NotContains(t, out.String(), id)
+	require.Contains(t, out.String(), `"path":"/v1/[redacted]"`)
+	assert.Equal(t, "/v1/"+id, entry.Data["path"], "the entry's fields are left as they were")
+}
@@ -87,6 +87,17 @@ func runCITests(extraTestArgs ...string) error {
 	return err
 }
 
+// Mainline runs the opt-in tests against the live mainline DHT, which publish throwaway records from one node and
+// resolve them from another, logging how long they take to propagate. They need the network and take minutes.
+func Mainline() error {
+	args := []string{"test", "-v", "-count=1", "-timeout=15m", "-run=TestMainline", "./pkg/dht/"}
+	testEnv := map[string]string{
+		"DHT_MAINLINE_TEST": "1",
+	}
+	_, err := sh.Exec(testEnv, ColorizeTestStdout(), os.Stderr, Go, args...)
+	return err
+}
+
 func Deps() error {
 	return brewInstall("golangci-lint")
 }
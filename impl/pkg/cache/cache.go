@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Cache caches resolved records, keyed by their z-base-32 encoded ID, for a fixed TTL
+type Cache interface {
+	// Get returns the cached value for the key, or nil if it isn't cached
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set caches the value for the key, replacing any existing value
+	Set(ctx context.Context, key string, value []byte) error
+	// Delete evicts the value for the key, if any
+	Delete(ctx context.Context, key string) error
+}
+
+// NewCache returns the cache at the given URI, which is one of
+//   - memory:// (or empty) for a cache local to the instance
+//   - redis:// or rediss:// for a cache shared by all instances, so the API tier is stateless and can scale horizontally
+//   - none:// to disable caching
+//
+// The memory config is the sharding and lifecycle of a cache local to the instance.
+func NewCache(uri string, ttl time.Duration, memory MemoryConfig) (Cache, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "memory", "":
+		return NewMemoryWith(ttl, memory)
+	case "redis", "rediss":
+		return NewRedis(uri, ttl)
+	case "none":
+		return None{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported cache type %s (from uri %s)", u.Scheme, uri)
+	}
+}
+
+// None is a Cache which caches nothing
+type None struct{}
+
+func (None) Get(context.Context, string) ([]byte, error) {
+	return nil, nil
+}
+
+func (None) Set(context.Context, string, []byte) error {
+	return nil
+}
+
+func (None) Delete(context.Context, string) error {
+	return nil
+}
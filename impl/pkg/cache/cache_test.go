@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCache(t *testing.T) {
+	for uri, expected := range map[string]Cache{
+		"":          &Memory{},
+		"memory://": &Memory{},
+		"none://":   None{},
+	} {
+		c, err := NewCache(uri, time.Minute, MemoryConfig{SizeLimitMB: 1, MaxEntrySize: 1000})
+		require.NoError(t, err, uri)
+		assert.IsType(t, expected, c, uri)
+	}
+
+	_, err := NewCache("memcached://localhost:11211", time.Minute, MemoryConfig{})
+	assert.ErrorContains(t, err, "unsupported cache type")
+}
+
+func TestMemory(t *testing.T) {
+	c, err := NewMemory(time.Minute, 1, 1000)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	value, err := c.Get(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	require.NoError(t, c.Set(ctx, "alice", []byte("record")))
+	value, err = c.Get(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("record"), value)
+
+	require.NoError(t, c.Delete(ctx, "alice"))
+	value, err = c.Get(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	// deleting a missing entry is a no-op
+	assert.NoError(t, c.Delete(ctx, "alice"))
+}
+
+func TestMemoryInspector(t *testing.T) {
+	c, err := NewMemory(time.Minute, 1, 1000)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, "alice", []byte("record")))
+	require.NoError(t, c.Set(ctx, "bob", []byte("record")))
+	_, err = c.Get(ctx, "alice")
+	require.NoError(t, err)
+	_, err = c.Get(ctx, "carol")
+	require.NoError(t, err)
+
+	stats, err := c.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, int64(2), stats.Entries)
+	assert.NotZero(t, stats.Bytes)
+
+	var keys []string
+	require.NoError(t, c.Entries(ctx, func(entry Entry) bool {
+		assert.Equal(t, []byte("record"), entry.Value)
+		assert.Less(t, entry.Age, time.Minute)
+		keys = append(keys, entry.Key)
+		return true
+	}))
+	assert.ElementsMatch(t, []string{"alice", "bob"}, keys)
+
+	require.NoError(t, c.Flush(ctx))
+	stats, err = c.Stats(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Entries)
+	assert.Zero(t, stats.Evictions, "flushing isn't evicting")
+}
+
+func TestMemoryUsage(t *testing.T) {
+	_, err := NewMemoryWith(time.Minute, MemoryConfig{Shards: 3})
+	assert.Error(t, err, "shards must be a power of two")
+
+	c, err := NewMemoryWith(time.Minute, MemoryConfig{SizeLimitMB: 1, Shards: 4, MaxEntrySize: 100, MaxEntriesInWindow: 64})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	usage, err := c.Usage(ctx)
+	require.NoError(t, err)
+	assert.Len(t, usage.Shards, 4)
+	assert.Zero(t, usage.Entries)
+	assert.Equal(t, 100, usage.MaxEntrySize)
+	assert.Equal(t, int64(30), usage.CleanWindowSeconds)
+
+	require.NoError(t, c.Set(ctx, "alice", []byte("record")))
+	require.NoError(t, c.Set(ctx, "bob", []byte("a longer record")))
+	usage, err = c.Usage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Entries)
+	assert.Equal(t, int64(entrySize("alice", []byte("record"))+entrySize("bob", []byte("a longer record"))), usage.EstimatedBytes)
+	assert.Equal(t, entrySize("bob", []byte("a longer record")), usage.LargestEntryBytes)
+	assert.NotZero(t, usage.AllocatedBytes)
+
+	// the entries are attributed to the shards bigcache puts them in
+	var entries, bytes int64
+	for _, shard := range usage.Shards {
+		entries += shard.Entries
+		bytes += shard.EstimatedBytes
+	}
+	assert.Equal(t, usage.Entries, entries)
+	assert.Equal(t, usage.EstimatedBytes, bytes)
+	shard := usage.Shards[fnv64a{}.Sum64("alice")&3]
+	assert.GreaterOrEqual(t, shard.Entries, int64(1))
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Stats are the counters of a cache's efficiency
+type Stats struct {
+	// Hits and Misses count the lookups which found an entry, or didn't, since the instance started
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	// Evictions counts the entries removed for expiring or for lack of space, rather than deleted. For a shared cache
+	// it is that of the whole cache server.
+	Evictions int64 `json:"evictions"`
+	// Entries is the number of cached entries
+	Entries int64 `json:"entries"`
+	// Bytes is the memory allocated for entries, if known
+	Bytes int64 `json:"bytes,omitempty"`
+}
+
+// Entry is a cached entry
+type Entry struct {
+	Key   string
+	Value []byte
+	// Age is how long ago the entry was cached
+	Age time.Duration
+}
+
+// Inspector is implemented by caches which report their efficiency and can enumerate and flush their entries
+type Inspector interface {
+	// Stats returns the counters of the cache's efficiency
+	Stats(ctx context.Context) (*Stats, error)
+	// Entries calls fn for each cached entry, until it returns false
+	Entries(ctx context.Context, fn func(Entry) bool) error
+	// Flush evicts all entries
+	Flush(ctx context.Context) error
+}
+
+// Usage is the memory accounting of a cache local to the instance, for tuning its sharding to the size of its entries
+type Usage struct {
+	// Entries is the number of cached entries, and EstimatedBytes the memory they take with their headers
+	Entries        int64 `json:"entries"`
+	EstimatedBytes int64 `json:"estimatedBytes"`
+	// AllocatedBytes is the memory allocated for entries by all shards, which grows as they fill up
+	AllocatedBytes int64 `json:"allocatedBytes"`
+	// LargestEntryBytes is the estimated size of the largest entry
+	LargestEntryBytes int `json:"largestEntryBytes"`
+	// MaxEntrySize, MaxEntriesInWindow, and CleanWindowSeconds are the configuration in effect
+	MaxEntrySize       int   `json:"maxEntrySize"`
+	MaxEntriesInWindow int   `json:"maxEntriesInWindow"`
+	CleanWindowSeconds int64 `json:"cleanWindowSeconds"`
+	// Shards is the usage of each shard, in order
+	Shards []ShardUsage `json:"shards"`
+}
+
+// ShardUsage is the number of entries of a cache shard, and the memory they take
+type ShardUsage struct {
+	Entries        int64 `json:"entries"`
+	EstimatedBytes int64 `json:"estimatedBytes"`
+}
+
+// Accountant is implemented by caches local to the instance which account for the memory their entries take
+type Accountant interface {
+	// Usage returns the memory accounting of the cache, by shard
+	Usage(ctx context.Context) (*Usage, error)
+}
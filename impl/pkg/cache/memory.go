@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+)
+
+// entryHeaderSize is the size of the timestamp, hash, and key length bigcache stores each entry with
+const entryHeaderSize = 18
+
+// Memory is a Cache local to the instance
+type Memory struct {
+	cache     *bigcache.BigCache
+	evictions *atomic.Int64
+	shards    int
+	config    MemoryConfig
+}
+
+var (
+	_ Inspector  = (*Memory)(nil)
+	_ Accountant = (*Memory)(nil)
+)
+
+// MemoryConfig is the sharding and lifecycle of a Memory cache. Zero fields take the defaults of bigcache.
+type MemoryConfig struct {
+	// SizeLimitMB is the most memory the entries take, in MB, unlimited if zero
+	SizeLimitMB int
+	// Shards is the number of shards, each locked separately, which must be a power of two
+	Shards int
+	// MaxEntrySize is the size in bytes of the entries the shards are initially sized for
+	MaxEntrySize int
+	// MaxEntriesInWindow is the number of entries expected within a TTL, the shards are initially sized for
+	MaxEntriesInWindow int
+	// CleanWindow is the interval expired entries are evicted on, half the TTL if zero
+	CleanWindow time.Duration
+}
+
+// NewMemory returns a new instance of Memory holding up to sizeLimitMB of entries of up to maxEntrySize bytes
+func NewMemory(ttl time.Duration, sizeLimitMB, maxEntrySize int) (*Memory, error) {
+	return NewMemoryWith(ttl, MemoryConfig{SizeLimitMB: sizeLimitMB, MaxEntrySize: maxEntrySize})
+}
+
+// NewMemoryWith returns a new instance of Memory sharded and sized by the given config
+func NewMemoryWith(ttl time.Duration, config MemoryConfig) (*Memory, error) {
+	evictions := new(atomic.Int64)
+	cacheConfig := bigcache.DefaultConfig(ttl)
+	if config.Shards > 0 {
+		cacheConfig.Shards = config.Shards
+	}
+	if config.MaxEntrySize > 0 {
+		cacheConfig.MaxEntrySize = config.MaxEntrySize
+	}
+	if config.MaxEntriesInWindow > 0 {
+		cacheConfig.MaxEntriesInWindow = config.MaxEntriesInWindow
+	}
+	cacheConfig.HardMaxCacheSize = config.SizeLimitMB
+	cacheConfig.CleanWindow = ttl / 2
+	if config.CleanWindow > 0 {
+		cacheConfig.CleanWindow = config.CleanWindow
+	}
+	// the hasher is set, rather than left to the default, so entries can be attributed to their shards
+	cacheConfig.Hasher = fnv64a{}
+	cacheConfig.OnRemoveWithReason = func(string, []byte, bigcache.RemoveReason) {
+		evictions.Add(1)
+	}
+	cacheConfig = cacheConfig.OnRemoveFilterSet(bigcache.Expired, bigcache.NoSpace)
+	cache, err := bigcache.New(context.Background(), cacheConfig)
+	if err != nil {
+		return nil, err
+	}
+	config.MaxEntrySize = cacheConfig.MaxEntrySize
+	config.MaxEntriesInWindow = cacheConfig.MaxEntriesInWindow
+	config.CleanWindow = cacheConfig.CleanWindow
+	return &Memory{cache: cache, evictions: evictions, shards: cacheConfig.Shards, config: config}, nil
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	value, err := m.cache.Get(key)
+	if errors.Is(err, bigcache.ErrEntryNotFound) {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte) error {
+	return m.cache.Set(key, value)
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	if err := m.cache.Delete(key); err != nil && !errors.Is(err, bigcache.ErrEntryNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (m *Memory) Stats(context.Context) (*Stats, error) {
+	stats := m.cache.Stats()
+	return &Stats{
+		Hits:      stats.Hits,
+		Misses:    stats.Misses,
+		Evictions: m.evictions.Load(),
+		Entries:   int64(m.cache.Len()),
+		Bytes:     int64(m.cache.Capacity()),
+	}, nil
+}
+
+func (m *Memory) Entries(ctx context.Context, fn func(Entry) bool) error {
+	now := time.Now().Unix()
+	it := m.cache.Iterator()
+	for it.SetNext() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		info, err := it.Value()
+		if err != nil {
+			// the entry was evicted while iterating
+			continue
+		}
+		age := time.Duration(max(now-int64(info.Timestamp()), 0)) * time.Second
+		if !fn(Entry{Key: info.Key(), Value: info.Value(), Age: age}) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Flush(context.Context) error {
+	return m.cache.Reset()
+}
+
+func (m *Memory) Usage(ctx context.Context) (*Usage, error) {
+	usage := Usage{
+		Shards:             make([]ShardUsage, m.shards),
+		AllocatedBytes:     int64(m.cache.Capacity()),
+		MaxEntrySize:       m.config.MaxEntrySize,
+		MaxEntriesInWindow: m.config.MaxEntriesInWindow,
+		CleanWindowSeconds: int64(m.config.CleanWindow.Seconds()),
+	}
+	it := m.cache.Iterator()
+	for it.SetNext() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		info, err := it.Value()
+		if err != nil {
+			// the entry was evicted while iterating
+			continue
+		}
+		size := entrySize(info.Key(), info.Value())
+		shard := &usage.Shards[fnv64a{}.Sum64(info.Key())&uint64(m.shards-1)]
+		shard.Entries++
+		shard.EstimatedBytes += int64(size)
+		usage.Entries++
+		usage.EstimatedBytes += int64(size)
+		usage.LargestEntryBytes = max(usage.LargestEntryBytes, size)
+	}
+	return &usage, nil
+}
+
+// entrySize estimates the bytes bigcache takes to store an entry: its value, key, and headers, prefixed by their length
+func entrySize(key string, value []byte) int {
+	size := entryHeaderSize + len(key) + len(value)
+	var length [binary.MaxVarintLen64]byte
+	return size + binary.PutUvarint(length[:], uint64(size))
+}
+
+// fnv64a is the FNV-1a hash bigcache assigns keys to shards with by default
+type fnv64a struct{}
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+func (fnv64a) Sum64(key string) uint64 {
+	var hash uint64 = fnvOffset64
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= fnvPrime64
+	}
+	return hash
+}
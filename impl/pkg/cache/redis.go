@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisKeyPrefix namespaces the keys of the cache in a shared Redis instance
+	redisKeyPrefix = "did-dht:pkarr:"
+	// redisScanCount is the number of keys asked for by each SCAN over the cache's keys
+	redisScanCount = 1000
+)
+
+// Redis is a Cache shared by all instances using the same Redis
+type Redis struct {
+	client *redis.Client
+	ttl    time.Duration
+	// hits and misses are those of this instance, as others share the cache
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+var _ Inspector = (*Redis)(nil)
+
+// NewRedis returns a new instance of Redis connected to the Redis at the given URI
+func NewRedis(uri string, ttl time.Duration) (*Redis, error) {
+	opts, err := redis.ParseURL(uri)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	if err = client.Ping(context.Background()).Err(); err != nil {
+		return nil, err
+	}
+	return &Redis{client: client, ttl: ttl}, nil
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.client.Get(ctx, redisKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		r.misses.Add(1)
+		return nil, nil
+	}
+	if err == nil {
+		r.hits.Add(1)
+	}
+	return value, err
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte) error {
+	return r.client.Set(ctx, redisKeyPrefix+key, value, r.ttl).Err()
+}
+
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	return r.client.Del(ctx, redisKeyPrefix+key).Err()
+}
+
+func (r *Redis) Stats(ctx context.Context) (*Stats, error) {
+	info, err := r.client.Info(ctx, "stats").Result()
+	if err != nil {
+		return nil, err
+	}
+	stats := Stats{Hits: r.hits.Load(), Misses: r.misses.Load()}
+	for _, line := range strings.Split(info, "\r\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && (name == "expired_keys" || name == "evicted_keys") {
+			n, _ := strconv.ParseInt(value, 10, 64)
+			stats.Evictions += n
+		}
+	}
+	iter := r.client.Scan(ctx, 0, redisKeyPrefix+"*", redisScanCount).Iterator()
+	for iter.Next(ctx) {
+		stats.Entries++
+	}
+	if err = iter.Err(); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (r *Redis) Entries(ctx context.Context, fn func(Entry) bool) error {
+	iter := r.client.Scan(ctx, 0, redisKeyPrefix+"*", redisScanCount).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		value, err := r.client.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			// the entry expired while scanning
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var age time.Duration
+		if remaining, err := r.client.PTTL(ctx, key).Result(); err == nil && remaining > 0 {
+			age = max(r.ttl-remaining, 0)
+		}
+		if !fn(Entry{Key: strings.TrimPrefix(key, redisKeyPrefix), Value: value, Age: age}) {
+			return nil
+		}
+	}
+	return iter.Err()
+}
+
+// Flush deletes the cache's keys, leaving any other keys of the Redis instance
+func (r *Redis) Flush(ctx context.Context) error {
+	iter := r.client.Scan(ctx, 0, redisKeyPrefix+"*", redisScanCount).Iterator()
+	keys := make([]string, 0, redisScanCount)
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == redisScanCount {
+			if err := r.client.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		return r.client.Del(ctx, keys...).Err()
+	}
+	return nil
+}
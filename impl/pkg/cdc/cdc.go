@@ -0,0 +1,14 @@
+// Package cdc publishes changes to stored records to message brokers, for pipelines consuming DID changes in real
+// time. It speaks the brokers' wire protocols directly, so neither Kafka nor NATS client libraries are required.
+package cdc
+
+import "context"
+
+// Publisher delivers encoded change events to a message broker
+type Publisher interface {
+	// Publish delivers the payload, keyed by the ID of the changed record so changes to the same record keep their
+	// order, returning once the broker has accepted it
+	Publish(ctx context.Context, key string, payload []byte) error
+	// Close releases the publisher's connections
+	Close() error
+}
@@ -0,0 +1,80 @@
+package cdc
+
+import (
+	"encoding/base64"
+
+	"github.com/goccy/go-json"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// Op is the kind of change made to a stored record
+type Op string
+
+const (
+	// OpCreate is a record stored for the first time
+	OpCreate Op = "create"
+	// OpUpdate is a record replaced by one with a newer seq
+	OpUpdate Op = "update"
+	// OpDelete is a record evicted from storage, or taken down by denying its key
+	OpDelete Op = "delete"
+)
+
+// Event is a change made to a stored record
+type Event struct {
+	Op Op `json:"op"`
+	// ID is the z-base-32 encoded ID of the record's key
+	ID string `json:"id"`
+	// Record is the record created or updated, or the record deleted if known
+	Record *pkarr.Record `json:"record,omitempty"`
+	// Timestamp is the unix timestamp in seconds the change was made at
+	Timestamp int64 `json:"timestamp"`
+}
+
+// EncodeJSON encodes the event as JSON, with the record's fields base64url encoded as stored
+func EncodeJSON(event Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// EncodeProtobuf encodes the event as the Event message of event.proto, with the record's fields decoded to bytes
+func EncodeProtobuf(event Event) ([]byte, error) {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, string(event.Op))
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, event.ID)
+	if event.Record != nil {
+		record, err := encodeRecord(*event.Record)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, record)
+	}
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(event.Timestamp))
+	return b, nil
+}
+
+// encodeRecord encodes the record as the Record message of event.proto
+func encodeRecord(record pkarr.Record) ([]byte, error) {
+	var b []byte
+	for _, field := range []struct {
+		num   protowire.Number
+		value string
+	}{{1, record.V}, {2, record.K}, {3, record.Sig}, {5, record.Salt}} {
+		if field.value == "" {
+			continue
+		}
+		value, err := base64.RawURLEncoding.DecodeString(field.value)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, field.num, protowire.BytesType)
+		b = protowire.AppendBytes(b, value)
+	}
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(record.Seq))
+	return b, nil
+}
@@ -0,0 +1,70 @@
+package cdc
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func TestEncode(t *testing.T) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	event := Event{
+		Op:        OpUpdate,
+		ID:        "alice",
+		Record:    &pkarr.Record{V: b64([]byte("v")), K: b64([]byte("k")), Sig: b64([]byte("sig")), Seq: 2},
+		Timestamp: 100,
+	}
+
+	encoded, err := EncodeJSON(event)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"op":"update","id":"alice","record":{"v":"dg","k":"aw","sig":"c2ln","seq":2},"timestamp":100}`,
+		string(encoded))
+
+	encoded, err = EncodeProtobuf(event)
+	require.NoError(t, err)
+	fields := decodeFields(t, encoded)
+	assert.Equal(t, "update", string(fields[1].([]byte)))
+	assert.Equal(t, "alice", string(fields[2].([]byte)))
+	assert.Equal(t, uint64(100), fields[4])
+	record := decodeFields(t, fields[3].([]byte))
+	assert.Equal(t, map[protowire.Number]any{1: []byte("v"), 2: []byte("k"), 3: []byte("sig"), 4: uint64(2)}, record)
+
+	t.Run("deletes without a record", func(t *testing.T) {
+		encoded, err := EncodeProtobuf(Event{Op: OpDelete, ID: "alice", Timestamp: 100})
+		require.NoError(t, err)
+		assert.NotContains(t, decodeFields(t, encoded), protowire.Number(3))
+	})
+
+	t.Run("malformed record", func(t *testing.T) {
+		_, err := EncodeProtobuf(Event{Op: OpCreate, ID: "alice", Record: &pkarr.Record{V: "not base64!"}})
+		assert.Error(t, err)
+	})
+}
+
+// decodeFields decodes the bytes and varint fields of a protobuf message
+func decodeFields(t *testing.T, b []byte) map[protowire.Number]any {
+	fields := make(map[protowire.Number]any)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, n, 0)
+			fields[num], b = v, b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.GreaterOrEqual(t, n, 0)
+			fields[num], b = v, b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+	return fields
+}
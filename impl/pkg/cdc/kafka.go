@@ -0,0 +1,104 @@
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	kafkaContentType = "application/vnd.kafka.binary.v2+json"
+	kafkaAccept      = "application/vnd.kafka.v2+json"
+)
+
+// KafkaPublisher publishes to a Kafka topic through the v2 API of a Kafka REST Proxy
+// https://docs.confluent.io/platform/current/kafka-rest/api.html
+type KafkaPublisher struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewKafkaPublisher returns a new instance of KafkaPublisher for the topic, through the proxy at the given URL, e.g.
+// http://localhost:8082
+func NewKafkaPublisher(proxyURL, topic string) (*KafkaPublisher, error) {
+	if _, err := url.ParseRequestURI(proxyURL); err != nil {
+		return nil, fmt.Errorf("invalid kafka rest proxy url: %w", err)
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("kafka topic is required")
+	}
+	return &KafkaPublisher{
+		endpoint: fmt.Sprintf("%s/topics/%s", strings.TrimSuffix(proxyURL, "/"), url.PathEscape(topic)),
+		client:   http.DefaultClient,
+	}, nil
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type kafkaProduceRequest struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		Partition int    `json:"partition"`
+		Offset    int64  `json:"offset"`
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Publish produces the payload to the topic as a message keyed by the given key, so that messages of the same key
+// are written to the same partition
+func (p *KafkaPublisher) Publish(ctx context.Context, key string, payload []byte) error {
+	body, err := json.Marshal(kafkaProduceRequest{Records: []kafkaRecord{{
+		Key:   base64.StdEncoding.EncodeToString([]byte(key)),
+		Value: base64.StdEncoding.EncodeToString(payload),
+	}}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", kafkaAccept)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kafka rest proxy returned status %d: %s", resp.StatusCode, respBody)
+	}
+	var produced kafkaProduceResponse
+	if err = json.Unmarshal(respBody, &produced); err != nil {
+		return err
+	}
+	for _, offset := range produced.Offsets {
+		if offset.ErrorCode != nil || offset.Error != "" {
+			return fmt.Errorf("failed to produce to kafka: %s", offset.Error)
+		}
+	}
+	return nil
+}
+
+// Close is a no-op, as the proxy is called over HTTP
+func (p *KafkaPublisher) Close() error {
+	return nil
+}
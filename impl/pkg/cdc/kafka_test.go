@@ -0,0 +1,41 @@
+package cdc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaPublisher(t *testing.T) {
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/topics/did-dht.records", r.URL.Path)
+		assert.Equal(t, kafkaContentType, r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		// the key and value are base64 encoded
+		assert.JSONEq(t, `{"records":[{"key":"YWxpY2U=","value":"aGVsbG8="}]}`, string(body))
+		if fail {
+			_, _ = w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":50003,"error":"timed out"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"offsets":[{"partition":0,"offset":1,"error_code":null,"error":null}]}`))
+	}))
+	defer srv.Close()
+
+	_, err := NewKafkaPublisher(srv.URL, "")
+	assert.Error(t, err)
+	publisher, err := NewKafkaPublisher(srv.URL+"/", "did-dht.records")
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	require.NoError(t, publisher.Publish(context.Background(), "alice", []byte("hello")))
+	fail = true
+	assert.ErrorContains(t, publisher.Publish(context.Background(), "alice", []byte("hello")), "timed out")
+}
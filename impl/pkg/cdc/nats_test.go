@@ -0,0 +1,110 @@
+package cdc
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// message is a message received by fakeNATS
+type message struct {
+	subject string
+	payload string
+}
+
+// fakeNATS is a NATS server speaking enough of the protocol to receive published messages, rejecting those to
+// subjects ending with .denied
+func fakeNATS(t *testing.T, token string) (string, <-chan message) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	messages := make(chan message, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveNATS(conn, token, messages)
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func serveNATS(conn net.Conn, token string, messages chan<- message) {
+	defer conn.Close()
+	_, _ = conn.Write([]byte(`INFO {"server_id":"fake","max_payload":1048576}` + "\r\n"))
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONNECT":
+			if !strings.Contains(line, `"auth_token":"`+token+`"`) {
+				_, _ = conn.Write([]byte("-ERR 'Authorization Violation'\r\n"))
+				return
+			}
+		case "PING":
+			_, _ = conn.Write([]byte("PONG\r\n"))
+		case "PUB":
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			if _, err = io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			if strings.HasSuffix(fields[1], ".denied") {
+				_, _ = conn.Write([]byte("-ERR 'Permissions Violation for Publish to " + fields[1] + "'\r\n"))
+				continue
+			}
+			messages <- message{subject: fields[1], payload: string(payload[:size])}
+		}
+	}
+}
+
+func TestNATSPublisher(t *testing.T) {
+	addr, messages := fakeNATS(t, "secret")
+	ctx := context.Background()
+
+	_, err := NewNATSPublisher("http://"+addr, "did-dht.records")
+	assert.Error(t, err)
+	_, err = NewNATSPublisher("nats://"+addr, "did-dht.>")
+	assert.Error(t, err)
+
+	t.Run("publish", func(t *testing.T) {
+		publisher, err := NewNATSPublisher("nats://secret@"+addr, "did-dht.records")
+		require.NoError(t, err)
+		defer publisher.Close()
+
+		require.NoError(t, publisher.Publish(ctx, "alice", []byte("hello")))
+		require.NoError(t, publisher.Publish(ctx, "bob", []byte("hello\r\nworld")))
+		assert.Equal(t, message{subject: "did-dht.records.alice", payload: "hello"}, <-messages)
+		assert.Equal(t, message{subject: "did-dht.records.bob", payload: "hello\r\nworld"}, <-messages)
+
+		assert.ErrorContains(t, publisher.Publish(ctx, "denied", []byte("hello")), "Permissions Violation")
+		// the publisher reconnects after an error
+		require.NoError(t, publisher.Publish(ctx, "alice", []byte("again")))
+		assert.Equal(t, message{subject: "did-dht.records.alice", payload: "again"}, <-messages)
+
+		assert.Error(t, publisher.Publish(ctx, "not.a.token", []byte("hello")))
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		publisher, err := NewNATSPublisher("nats://wrong@"+addr, "did-dht.records")
+		require.NoError(t, err)
+		defer publisher.Close()
+		assert.ErrorContains(t, publisher.Publish(ctx, "alice", []byte("hello")), "Authorization Violation")
+	})
+}
@@ -0,0 +1,174 @@
+package dht
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"time"
+
+	"github.com/anacrolix/dht/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+const (
+	// bootstrapTimeout bounds how long discovering bootstrap peers may take
+	bootstrapTimeout = 30 * time.Second
+	// nodesSaveInterval is how often the nodes of the routing table are saved for the next run to bootstrap from
+	nodesSaveInterval = 5 * time.Minute
+)
+
+// BootstrapProvider discovers the "host:port" addresses of the nodes a DHT joins the network through, so deployments
+// in restricted networks can bring their own discovery
+type BootstrapProvider interface {
+	BootstrapPeers(ctx context.Context) ([]string, error)
+}
+
+// BootstrapFunc is a BootstrapProvider implemented by a function
+type BootstrapFunc func(ctx context.Context) ([]string, error)
+
+func (f BootstrapFunc) BootstrapPeers(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// StaticPeers bootstraps from a fixed list of "host:port" addresses
+type StaticPeers []string
+
+func (s StaticPeers) BootstrapPeers(context.Context) ([]string, error) {
+	return s, nil
+}
+
+// DNSSeeds bootstraps from every address the "host:port" seeds resolve to, so the set of bootstrap nodes can be
+// changed by updating DNS records rather than the config of each gateway
+type DNSSeeds []string
+
+func (d DNSSeeds) BootstrapPeers(ctx context.Context) ([]string, error) {
+	var peers []string
+	var errs []error
+	for _, seed := range d {
+		host, port, err := net.SplitHostPort(seed)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, addr := range addrs {
+			peers = append(peers, net.JoinHostPort(addr, port))
+		}
+	}
+	if len(peers) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return peers, nil
+}
+
+// KnownNodes bootstraps from the nodes saved to a file by a previous run, exchanged with peers while it was part of
+// the network, so a gateway can rejoin without reaching well-known routers. A missing file provides no peers.
+type KnownNodes string
+
+func (k KnownNodes) BootstrapPeers(context.Context) ([]string, error) {
+	nodes, err := dht.ReadNodesFromFile(string(k))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	peers := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		peers = append(peers, node.Addr.String())
+	}
+	return peers, nil
+}
+
+// MultiBootstrap bootstraps from the peers of all the given providers, failing only if all of them fail
+func MultiBootstrap(providers ...BootstrapProvider) BootstrapProvider {
+	return BootstrapFunc(func(ctx context.Context) ([]string, error) {
+		var peers []string
+		var errs []error
+		seen := make(map[string]struct{})
+		for _, provider := range providers {
+			provided, err := provider.BootstrapPeers(ctx)
+			if err != nil {
+				logrus.WithError(err).Warn("failed to discover dht bootstrap peers")
+				errs = append(errs, err)
+				continue
+			}
+			for _, peer := range provided {
+				if _, ok := seen[peer]; !ok {
+					seen[peer] = struct{}{}
+					peers = append(peers, peer)
+				}
+			}
+		}
+		if len(errs) == len(providers) && len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		return peers, nil
+	})
+}
+
+// BootstrapProviderFromConfig returns the provider of the static peers, DNS seeds, and known nodes of the config
+func BootstrapProviderFromConfig(cfg config.DHTServiceConfig) BootstrapProvider {
+	providers := []BootstrapProvider{StaticPeers(cfg.BootstrapPeers)}
+	if len(cfg.BootstrapDNSSeeds) > 0 {
+		providers = append(providers, DNSSeeds(cfg.BootstrapDNSSeeds))
+	}
+	if cfg.NodesFile != "" {
+		providers = append(providers, KnownNodes(cfg.NodesFile))
+	}
+	return MultiBootstrap(providers...)
+}
+
+// startingNodes adapts the provider to the starting nodes of a DHT server, skipping peers which don't resolve
+func startingNodes(provider BootstrapProvider) dht.StartingNodesGetter {
+	return func() ([]dht.Addr, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), bootstrapTimeout)
+		defer cancel()
+		peers, err := provider.BootstrapPeers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		addrs := make([]dht.Addr, 0, len(peers))
+		for _, peer := range peers {
+			addr, err := net.ResolveUDPAddr("udp", peer)
+			if err != nil {
+				logrus.WithError(err).Warnf("skipping unresolvable dht bootstrap peer[%s]", peer)
+				continue
+			}
+			addrs = append(addrs, dht.NewAddr(addr))
+		}
+		return addrs, nil
+	}
+}
+
+// saveNodes saves the nodes of the routing table to the nodes file every interval until stopped
+func (d *DHT) saveNodes(stop <-chan struct{}) {
+	ticker := time.NewTicker(nodesSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.writeNodes()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// writeNodes saves the nodes of the routing table to the nodes file, unless the table is empty
+func (d *DHT) writeNodes() {
+	nodes := d.Server.Nodes()
+	if len(nodes) == 0 {
+		return
+	}
+	if err := dht.WriteNodesToFile(nodes, d.nodesFile); err != nil {
+		logrus.WithError(err).Warnf("failed to save dht nodes to %s", d.nodesFile)
+	}
+}
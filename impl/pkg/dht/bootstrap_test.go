@@ -0,0 +1,62 @@
+package dht
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+func TestBootstrapProviders(t *testing.T) {
+	ctx := context.Background()
+	failing := BootstrapFunc(func(context.Context) ([]string, error) {
+		return nil, errors.New("unreachable")
+	})
+
+	t.Run("multiple providers are combined", func(t *testing.T) {
+		provider := MultiBootstrap(StaticPeers{"a:1", "b:2"}, failing, StaticPeers{"b:2", "c:3"})
+		peers, err := provider.BootstrapPeers(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a:1", "b:2", "c:3"}, peers)
+
+		_, err = MultiBootstrap(failing, failing).BootstrapPeers(ctx)
+		assert.Error(t, err)
+	})
+
+	t.Run("dns seeds", func(t *testing.T) {
+		peers, err := DNSSeeds{"localhost:6881"}.BootstrapPeers(ctx)
+		require.NoError(t, err)
+		assert.NotEmpty(t, peers)
+		for _, peer := range peers {
+			assert.Contains(t, []string{"127.0.0.1:6881", "[::1]:6881"}, peer)
+		}
+
+		_, err = DNSSeeds{"no port"}.BootstrapPeers(ctx)
+		assert.Error(t, err)
+	})
+
+	t.Run("missing known nodes file", func(t *testing.T) {
+		peers, err := KnownNodes(filepath.Join(t.TempDir(), "nodes")).BootstrapPeers(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, peers)
+	})
+
+	t.Run("unresolvable peers are skipped", func(t *testing.T) {
+		addrs, err := startingNodes(StaticPeers{"127.0.0.1:6881", "no port"})()
+		require.NoError(t, err)
+		require.Len(t, addrs, 1)
+		assert.Equal(t, "127.0.0.1:6881", addrs[0].String())
+	})
+
+	t.Run("config", func(t *testing.T) {
+		cfg := config.DHTServiceConfig{BootstrapPeers: []string{"a:1"}, NodesFile: filepath.Join(t.TempDir(), "nodes")}
+		peers, err := BootstrapProviderFromConfig(cfg).BootstrapPeers(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a:1"}, peers)
+	})
+}
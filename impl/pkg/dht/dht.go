@@ -2,42 +2,191 @@ package dht
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	errutil "github.com/TBD54566975/ssi-sdk/util"
 	"github.com/anacrolix/dht/v2"
 	"github.com/anacrolix/dht/v2/bep44"
 	"github.com/anacrolix/dht/v2/exts/getput"
+	"github.com/anacrolix/dht/v2/traversal"
 	"github.com/anacrolix/torrent/types/infohash"
+	"github.com/sirupsen/logrus"
 
-	dhtint "github.com/TBD54566975/did-dht-method/internal/dht"
-	"github.com/TBD54566975/did-dht-method/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	dhtint "github.com/TBD54566975/did-dht-method/impl/internal/dht"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+)
+
+// Client puts and gets BEP-44 values, implemented by DHT and by fakes of it for testing.
+type Client interface {
+	// Put puts the given BEP-44 value and returns its z32-encoded key.
+	Put(ctx context.Context, request bep44.Put) (string, error)
+	// GetFull returns the full BEP-44 result, including the signature, for the given z32-encoded key and optional salt.
+	GetFull(ctx context.Context, key string, salt []byte) (*FullGetResult, error)
+}
+
+// FullGetResult is a BEP-44 result including the signature data of the record.
+type FullGetResult = dhtint.FullGetResult
+
+var (
+	_ Client     = (*DHT)(nil)
+	_ Replicator = (*DHT)(nil)
+	_ PeerScorer = (*DHT)(nil)
 )
 
 // DHT is a wrapper around anacrolix/dht that implements the BEP-44 DHT protocol.
 type DHT struct {
 	*dht.Server
+
+	// replication is the number of closest nodes puts target
+	replication int
+	// seqSearch, if positive, is how long GetFull keeps searching for a newer seq after finding a record
+	seqSearch time.Duration
+	// traversalBudget bounds the traversal of each GetFull
+	traversalBudget TraversalBudget
+	// hedge, if set, hedges each GetFull which doesn't search for a newer seq
+	hedge *hedge
+	// reputation scores the peers queried, banning misbehaving ones
+	reputation *Reputation
+	// nodesFile is the file the nodes of the routing table are saved to, if any
+	nodesFile  string
+	stopSaving chan struct{}
+	closeOnce  sync.Once
 }
 
 // NewDHT returns a new instance of DHT with the given bootstrap peers.
 func NewDHT(bootstrapPeers []string) (*DHT, error) {
+	return NewDHTWithBootstrap(StaticPeers(bootstrapPeers))
+}
+
+// NewDHTFromConfig returns a new instance of DHT bootstrapped from the peers, DNS seeds, and known nodes of the config,
+// periodically saving the nodes it knows to the config's nodes file, if any, for the next run to bootstrap from.
+func NewDHTFromConfig(cfg config.DHTServiceConfig) (*DHT, error) {
+	network, err := NetworkFromConfig(cfg)
+	if err != nil {
+		return nil, errutil.LoggingErrorMsg(err, "invalid dht network")
+	}
+	d, err := NewDHTWithNetwork(BootstrapProviderFromConfig(cfg), network)
+	if err != nil {
+		return nil, err
+	}
+	d.SetReplication(cfg.ReplicationFactor)
+	d.SetSeqSearch(time.Duration(cfg.SeqSearchSeconds) * time.Second)
+	d.SetTraversalBudget(TraversalBudget{
+		MaxOutstanding: cfg.GetMaxOutstanding,
+		MaxDuration:    time.Duration(cfg.GetTimeoutSeconds) * time.Second,
+	})
+	d.SetHedgeDelay(time.Duration(cfg.GetHedgeMillis) * time.Millisecond)
+	d.reputation.SetBanPolicy(cfg.PeerBanThreshold, time.Duration(cfg.PeerBanSeconds)*time.Second)
+	if cfg.LogPeerReputation {
+		d.reputation.SetLogLevel(logrus.InfoLevel)
+	}
+	if cfg.NodesFile != "" {
+		d.nodesFile = cfg.NodesFile
+		d.stopSaving = make(chan struct{})
+		go d.saveNodes(d.stopSaving)
+	}
+	return d, nil
+}
+
+// NewDHTWithBootstrap returns a new instance of DHT joining the network through the peers the given provider discovers.
+func NewDHTWithBootstrap(provider BootstrapProvider) (*DHT, error) {
+	return NewDHTWithNetwork(provider, Network{})
+}
+
+// NewDHTWithNetwork returns a new instance of DHT joining the given network through the peers the given provider
+// discovers. It keeps the reputation of the peers it queries, but doesn't ban them unless a ban policy is set.
+func NewDHTWithNetwork(provider BootstrapProvider, network Network) (*DHT, error) {
 	c := dht.NewDefaultServerConfig()
-	c.StartingNodes = func() ([]dht.Addr, error) { return dht.ResolveHostPorts(bootstrapPeers) }
+	c.StartingNodes = startingNodes(provider)
+	if err := network.configure(c); err != nil {
+		return nil, errutil.LoggingErrorMsg(err, "failed to configure dht network")
+	}
+	reputation := NewReputation(0, defaultBanDuration)
+	if c.IPBlocklist != nil {
+		c.IPBlocklist = blocklists{c.IPBlocklist, reputation}
+	} else {
+		c.IPBlocklist = reputation
+	}
 	s, err := dht.NewServer(c)
 	if err != nil {
 		return nil, errutil.LoggingErrorMsg(err, "failed to create dht server")
 	}
-	return &DHT{Server: s}, nil
+	return &DHT{Server: s, replication: defaultReplication, reputation: reputation}, nil
+}
+
+// SetReplication sets the number of closest nodes puts target, the default of 8 if not positive.
+func (d *DHT) SetReplication(replication int) {
+	if replication <= 0 {
+		replication = defaultReplication
+	}
+	d.replication = replication
+}
+
+// SetSeqSearch sets how long GetFull keeps searching for a newer seq after finding a record, returning the first
+// record found if not positive.
+func (d *DHT) SetSeqSearch(search time.Duration) {
+	d.seqSearch = search
+}
+
+// SetTraversalBudget sets the budget bounding the traversal of each GetFull
+func (d *DHT) SetTraversalBudget(budget TraversalBudget) {
+	d.traversalBudget = budget
+}
+
+// SetHedgeDelay enables hedging gets: once a get has run for the 95th percentile latency of the latest gets, a second
+// get is started from other nodes, and the first to find the record wins. The given delay is used until enough gets
+// have been timed. Gets aren't hedged if it isn't positive, nor when they search for a newer seq.
+func (d *DHT) SetHedgeDelay(delay time.Duration) {
+	if delay <= 0 {
+		d.hedge = nil
+		return
+	}
+	d.hedge = &hedge{initial: delay}
+}
+
+// Reputation returns the reputation of the peers the DHT queries
+func (d *DHT) Reputation() *Reputation {
+	return d.reputation
+}
+
+// Close saves the known nodes, if they are saved, and closes the DHT server.
+func (d *DHT) Close() {
+	d.closeOnce.Do(func() {
+		if d.stopSaving != nil {
+			close(d.stopSaving)
+			d.writeNodes()
+		}
+		d.Server.Close()
+	})
 }
 
 // Put puts the given BEP-44 value into the DHT and returns its z32-encoded key.
 func (d *DHT) Put(ctx context.Context, request bep44.Put) (string, error) {
-	t, err := getput.Put(ctx, request.Target(), d.Server, nil, func(int64) bep44.Put {
-		return request
-	})
+	result, err := d.PutReplicated(ctx, request)
 	if err != nil {
-		return "", errutil.LoggingNewErrorf("failed to put key into dht, tried %d nodes, got %d responses", t.NumAddrsTried, t.NumResponses)
+		return "", err
+	}
+	return result.Key, nil
+}
+
+// PutReplicated puts the given BEP-44 value into the DHT, reporting how many of the closest nodes stored it. It
+// fails if no node stored the value.
+func (d *DHT) PutReplicated(ctx context.Context, request bep44.Put) (*PutResult, error) {
+	res, t, err := dhtint.Put(ctx, d.Server, request, d.replication, d.replication*putCandidateFactor, d.reputation.Observe)
+	if err != nil {
+		return nil, errutil.LoggingNewErrorf("failed to put key into dht, tried %d nodes, got %d responses", t.NumAddrsTried, t.NumResponses)
+	}
+	if res.Stored == 0 {
+		return nil, errutil.LoggingNewErrorf("failed to put key into dht, none of %d nodes stored it", res.Attempted)
 	}
-	return util.Z32Encode(request.K[:]), nil
+	return &PutResult{
+		Key:         util.Z32Encode(request.K[:]),
+		Replication: d.replication,
+		Stored:      res.Stored,
+		Attempted:   res.Attempted,
+	}, nil
 }
 
 // Get returns the BEP-44 result for the given key from the DHT.
@@ -48,6 +197,10 @@ func (d *DHT) Get(ctx context.Context, key string) (*getput.GetResult, error) {
 		return nil, errutil.LoggingErrorMsg(err, "failed to decode key")
 	}
 	res, t, err := getput.Get(ctx, infohash.HashBytes(z32Decoded), d.Server, nil, nil)
+	if err != nil && t == nil {
+		// the traversal didn't start, as the DHT knows no nodes
+		return nil, errutil.LoggingErrorMsgf(err, "failed to get key[%s] from dht", key)
+	}
 	if err != nil {
 		return nil, errutil.LoggingNewErrorf("failed to get key[%s] from dht; tried %d nodes, got %d responses", key, t.NumAddrsTried, t.NumResponses)
 	}
@@ -56,13 +209,50 @@ func (d *DHT) Get(ctx context.Context, key string) (*getput.GetResult, error) {
 
 // GetFull returns the full BEP-44 result for the given key from the DHT, using our modified
 // implementation of getput.Get. It should ONLY be used when it's needed to get the signature
-// data for a record.
-func (d *DHT) GetFull(ctx context.Context, key string) (*dhtint.FullGetResult, error) {
+// data for a record. If a seq search is set, it returns the record with the highest seq found
+// within it, as GetHighestSeq does. The traversal is bounded by the traversal budget and the context's
+// deadline, returning the best record found so far as Partial once either runs out.
+func (d *DHT) GetFull(ctx context.Context, key string, salt []byte) (*FullGetResult, error) {
+	ctx, cancel := d.traversalBudget.context(ctx)
+	defer cancel()
+	if d.seqSearch > 0 {
+		res, err := d.GetHighestSeq(ctx, key, salt, d.seqSearch)
+		if err == nil && len(res.Seqs) > 1 {
+			logrus.Debugf("found seqs %v for key[%s] in dht, returning the highest", res.Seqs, key)
+		}
+		return res, err
+	}
+	z32Decoded, err := util.Z32Decode(key)
+	if err != nil {
+		return nil, errutil.LoggingErrorMsg(err, "failed to decode key")
+	}
+	// the target of a salted value is the hash of the key and the salt
+	target := infohash.HashBytes(append(z32Decoded, salt...))
+	var res FullGetResult
+	var t *traversal.Stats
+	if d.hedge != nil {
+		res, t, err = d.hedgedGet(ctx, key, target, salt)
+	} else {
+		res, t, err = dhtint.Get(ctx, target, d.Server, nil, salt, d.traversalBudget.MaxOutstanding, false, d.reputation.Observe)
+	}
+	if err != nil {
+		return nil, errutil.LoggingNewErrorf("failed to get key[%s] from dht; tried %d nodes, got %d responses", key, t.NumAddrsTried, t.NumResponses)
+	}
+	if res.Partial {
+		logrus.Debugf("traversal budget of key[%s] ran out after %d nodes, returning the record found so far", key, t.NumAddrsTried)
+	}
+	return &res, nil
+}
+
+// GetHighestSeq returns the full BEP-44 result with the highest seq found for the given key and optional salt, with
+// the distinct seqs nodes held. Rather than returning the first record found, it keeps searching for up to search
+// afterward, since nodes further along may hold a newer seq.
+func (d *DHT) GetHighestSeq(ctx context.Context, key string, salt []byte, search time.Duration) (*FullGetResult, error) {
 	z32Decoded, err := util.Z32Decode(key)
 	if err != nil {
 		return nil, errutil.LoggingErrorMsg(err, "failed to decode key")
 	}
-	res, t, err := dhtint.Get(ctx, infohash.HashBytes(z32Decoded), d.Server, nil, nil)
+	res, t, err := dhtint.GetHighestSeq(ctx, infohash.HashBytes(append(z32Decoded, salt...)), d.Server, salt, search, d.traversalBudget.MaxOutstanding, d.reputation.Observe)
 	if err != nil {
 		return nil, errutil.LoggingNewErrorf("failed to get key[%s] from dht; tried %d nodes, got %d responses", key, t.NumAddrsTried, t.NumResponses)
 	}
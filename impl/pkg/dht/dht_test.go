@@ -11,8 +11,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/TBD54566975/did-dht-method/config"
-	"github.com/TBD54566975/did-dht-method/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
 )
 
 func TestGetPutDHT(t *testing.T) {
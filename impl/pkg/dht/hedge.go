@@ -0,0 +1,106 @@
+package dht
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/dht/v2/traversal"
+	"github.com/sirupsen/logrus"
+
+	dhtint "github.com/TBD54566975/did-dht-method/impl/internal/dht"
+)
+
+const (
+	// hedgeSamples is the number of the latest gets whose latency the hedge delay is derived from
+	hedgeSamples = 256
+	// hedgeMinSamples is the number of gets timed before the hedge delay is derived from their latency rather than
+	// the configured delay
+	hedgeMinSamples = 20
+	// hedgePercentile is the percentile of the latency of gets the hedge delay is, so about one get in twenty is hedged
+	hedgePercentile = 0.95
+)
+
+// hedge derives the delay after which a get is hedged from the latency of the latest gets which found a record
+type hedge struct {
+	// initial is the delay until hedgeMinSamples gets have been timed
+	initial time.Duration
+
+	mu      sync.Mutex
+	samples []time.Duration
+	// next is the index of the sample the next latency replaces, once there are hedgeSamples
+	next int
+}
+
+// delay returns how long a get runs before it is hedged: the 95th percentile latency of the latest gets, or the
+// initial delay until enough gets have been timed
+func (h *hedge) delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) < hedgeMinSamples {
+		return h.initial
+	}
+	sorted := slices.Clone(h.samples)
+	slices.Sort(sorted)
+	return sorted[int(float64(len(sorted)-1)*hedgePercentile)]
+}
+
+// observe records the latency of a get which found a record
+func (h *hedge) observe(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) < hedgeSamples {
+		h.samples = append(h.samples, latency)
+		return
+	}
+	h.samples[h.next] = latency
+	h.next = (h.next + 1) % hedgeSamples
+}
+
+// getOutcome is the outcome of one of the gets of a hedged get
+type getOutcome struct {
+	res    FullGetResult
+	stats  *traversal.Stats
+	err    error
+	hedged bool
+}
+
+// hedgedGet gets the value of the target, and if the get hasn't returned by the hedge delay, starts a second get from
+// other nodes than the first, returning whichever finds the value first. It only fails once both gets have.
+func (d *DHT) hedgedGet(ctx context.Context, key string, target bep44.Target, salt []byte) (FullGetResult, *traversal.Stats, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	outcomes := make(chan getOutcome, 2)
+	get := func(hedged bool) {
+		res, stats, err := dhtint.Get(ctx, target, d.Server, nil, salt, d.traversalBudget.MaxOutstanding, hedged, d.reputation.Observe)
+		outcomes <- getOutcome{res: res, stats: stats, err: err, hedged: hedged}
+	}
+
+	start := time.Now()
+	go get(false)
+	timer := time.NewTimer(d.hedge.delay())
+	defer timer.Stop()
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			logrus.Debugf("hedging get of key[%s] after %s", key, time.Since(start))
+			pending++
+			go get(true)
+		case outcome := <-outcomes:
+			pending--
+			if outcome.err != nil && pending > 0 {
+				continue
+			}
+			if outcome.err == nil && !outcome.res.Partial {
+				d.hedge.observe(time.Since(start))
+				if outcome.hedged {
+					logrus.Debugf("hedged get of key[%s] found the record first", key)
+				}
+			}
+			return outcome.res, outcome.stats, outcome.err
+		}
+	}
+}
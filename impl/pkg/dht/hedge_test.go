@@ -0,0 +1,38 @@
+package dht
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedgeDelay(t *testing.T) {
+	h := hedge{initial: 500 * time.Millisecond}
+	for i := 1; i < hedgeMinSamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	// too few gets have been timed
+	assert.Equal(t, 500*time.Millisecond, h.delay())
+
+	for i := hedgeMinSamples; i <= 100; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, 95*time.Millisecond, h.delay())
+
+	t.Run("only the latest gets are kept", func(t *testing.T) {
+		for i := 0; i < hedgeSamples; i++ {
+			h.observe(time.Second)
+		}
+		assert.Len(t, h.samples, hedgeSamples)
+		assert.Equal(t, time.Second, h.delay())
+	})
+}
+
+func TestSetHedgeDelay(t *testing.T) {
+	d := new(DHT)
+	d.SetHedgeDelay(time.Second)
+	assert.NotNil(t, d.hedge)
+	d.SetHedgeDelay(0)
+	assert.Nil(t, d.hedge)
+}
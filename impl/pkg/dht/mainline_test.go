@@ -0,0 +1,146 @@
+package dht_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"os"
+	"testing"
+	"time"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+const (
+	// mainlineEnv opts into the tests against the live mainline DHT, which put real throwaway records to it
+	mainlineEnv = "DHT_MAINLINE_TEST"
+	// mainlinePropagationTimeout bounds how long a record put by one node may take to resolve from another
+	mainlinePropagationTimeout = 2 * time.Minute
+	// mainlinePollInterval is the pause between gets while waiting for a record to propagate
+	mainlinePollInterval = 2 * time.Second
+)
+
+// TestMainline publishes throwaway records to the live mainline DHT from one node and resolves them from a second,
+// independent node, logging how long they took to propagate. It catches regressions in talking to real nodes which
+// the in-process fakes can't, so it needs the network and takes minutes, and only runs with DHT_MAINLINE_TEST set.
+func TestMainline(t *testing.T) {
+	if os.Getenv(mainlineEnv) == "" {
+		t.Skipf("set %s to run against the live mainline dht", mainlineEnv)
+	}
+	publisher := newMainlineNode(t)
+	resolver := newMainlineNode(t)
+
+	t.Run("did document", func(t *testing.T) {
+		key, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{})
+		require.NoError(t, err)
+		put := signedPut(t, key, nil, 0, packDocument(t, *doc))
+		t.Cleanup(func() { retract(t, publisher, key, nil, put.Seq) })
+
+		got := publishAndResolve(t, publisher, resolver, put)
+		var msg dns.Msg
+		require.NoError(t, msg.Unpack(value(t, got)))
+		resolved, _, err := did.DHT(doc.ID).FromDNSPacket(&msg)
+		require.NoError(t, err)
+		assert.Equal(t, doc.ID, resolved.ID)
+
+		t.Run("updates propagate", func(t *testing.T) {
+			doc.Services = []didsdk.Service{{
+				ID:              "test",
+				Type:            "LinkedDomains",
+				ServiceEndpoint: []string{"https://example.com"},
+			}}
+			put = signedPut(t, key, nil, put.Seq, packDocument(t, *doc))
+			got := publishAndResolve(t, publisher, resolver, put)
+			require.NoError(t, msg.Unpack(value(t, got)))
+			resolved, _, err := did.DHT(doc.ID).FromDNSPacket(&msg)
+			require.NoError(t, err)
+			assert.Len(t, resolved.Services, 1)
+		})
+	})
+
+	t.Run("salted record", func(t *testing.T) {
+		_, key, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		salt := []byte("did-dht-mainline-test")
+		put := signedPut(t, key, salt, 0, []byte("hello mainline"))
+		t.Cleanup(func() { retract(t, publisher, key, salt, put.Seq) })
+
+		got := publishAndResolve(t, publisher, resolver, put)
+		assert.Equal(t, put.V, value(t, got))
+	})
+}
+
+// newMainlineNode starts a DHT node of its own, bootstrapped from the default mainline bootstrap peers
+func newMainlineNode(t *testing.T) *dht.DHT {
+	d, err := dht.NewDHT(config.GetDefaultBootstrapPeers())
+	require.NoError(t, err)
+	t.Cleanup(d.Close)
+	return d
+}
+
+// signedPut signs the value with a seq newer than prev
+func signedPut(t *testing.T, key ed25519.PrivateKey, salt []byte, prev int64, v []byte) bep44.Put {
+	put := bep44.Put{V: v, K: (*[32]byte)(key.Public().(ed25519.PublicKey)), Salt: salt, Seq: dht.NextSeq(prev)}
+	put.Sign(key)
+	return put
+}
+
+// packDocument packs the DID Document as the DNS packet it is published as
+func packDocument(t *testing.T, doc didsdk.Document) []byte {
+	msg, err := did.DHT(doc.ID).ToDNSPacket(doc, nil)
+	require.NoError(t, err)
+	packed, err := msg.Pack()
+	require.NoError(t, err)
+	return packed
+}
+
+// publishAndResolve puts the value from the publisher, then gets it from the resolver until it has at least the seq
+// put, failing if it hasn't propagated within the timeout. The resolved value's signature is verified.
+func publishAndResolve(t *testing.T, publisher, resolver *dht.DHT, put bep44.Put) *dht.FullGetResult {
+	ctx := context.Background()
+	start := time.Now()
+	result, err := publisher.PutReplicated(ctx, put)
+	require.NoError(t, err)
+	t.Logf("put seq %d to %d node(s), stored by %d of %d targeted, in %s",
+		put.Seq, result.Attempted, result.Stored, result.Replication, time.Since(start))
+
+	id := util.Z32Encode(put.K[:])
+	for {
+		got, err := resolver.GetFull(ctx, id, put.Salt)
+		if err == nil && got.Seq >= put.Seq {
+			t.Logf("resolved seq %d from a second node %s after publishing", got.Seq, time.Since(start))
+			assert.True(t, bep44.Verify(put.K[:], put.Salt, got.Seq, got.V, got.Sig[:]), "resolved signature is invalid")
+			return got
+		}
+		if time.Since(start) > mainlinePropagationTimeout {
+			t.Fatalf("seq %d didn't propagate to a second node within %s (last error: %v)", put.Seq, mainlinePropagationTimeout, err)
+		}
+		time.Sleep(mainlinePollInterval)
+	}
+}
+
+// value returns the value of the resolved record
+func value(t *testing.T, got *dht.FullGetResult) []byte {
+	var v []byte
+	require.NoError(t, bencode.Unmarshal(got.V, &v))
+	return v
+}
+
+// retract replaces the throwaway record with an empty DNS packet, as records can't be deleted from the DHT. Not being
+// republished, the replacement expires from the nodes storing it within hours.
+func retract(t *testing.T, publisher *dht.DHT, key ed25519.PrivateKey, salt []byte, prev int64) {
+	empty, err := new(dns.Msg).Pack()
+	require.NoError(t, err)
+	if _, err = publisher.PutReplicated(context.Background(), signedPut(t, key, salt, prev, empty)); err != nil {
+		t.Logf("failed to retract throwaway record: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/anacrolix/dht/v2"
+	"github.com/anacrolix/dht/v2/krpc"
+	"github.com/anacrolix/torrent/iplist"
+	"golang.org/x/time/rate"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+// networkIDPrefixLength is the number of leading bytes of node IDs which identify the network of a node
+const networkIDPrefixLength = 4
+
+// privateNetworks are the address ranges a private network is restricted to if no others are configured: loopback,
+// private IPv4, and unique local IPv6 addresses
+var privateNetworks = []string{"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7"}
+
+// Network configures the network a DHT joins, which is the public Mainline DHT unless it is isolated by AllowedNetworks
+// or ID
+type Network struct {
+	// ListenAddress is the UDP address the DHT listens on, such as the well-known address of a bootstrap node of a
+	// private network, or a random port on all interfaces if empty
+	ListenAddress string
+	// AllowedNetworks, if not empty, are the only address ranges of nodes the DHT exchanges messages with, so it
+	// never mixes nodes of the public DHT into its routing table
+	AllowedNetworks []*net.IPNet
+	// ID, if set, prefixes the node ID of the DHT with a hash of it, and the DHT refuses to answer queries from nodes
+	// without the same prefix, so separate private networks sharing address ranges don't serve each other
+	ID string
+	// SendLimiter, if set, limits the rate the DHT sends messages at, rather than the limit shared by every DHT of
+	// the process, under which the replies of a DHT are dropped while others of the process are busy
+	SendLimiter *rate.Limiter
+}
+
+// NetworkFromConfig returns the network described by the config. A private network must bootstrap from its own
+// nodes rather than the public routers, and is restricted to private address ranges unless others are configured.
+func NetworkFromConfig(cfg config.DHTServiceConfig) (Network, error) {
+	network := Network{ListenAddress: cfg.ListenAddress}
+	if !cfg.Private {
+		return network, nil
+	}
+	if len(cfg.BootstrapPeers) == 0 && len(cfg.BootstrapDNSSeeds) == 0 && cfg.NodesFile == "" {
+		return network, fmt.Errorf("a private dht network requires bootstrap peers, dns seeds, or a nodes file")
+	}
+	for _, peer := range cfg.BootstrapPeers {
+		if slices.Contains(config.GetDefaultBootstrapPeers(), peer) {
+			return network, fmt.Errorf("a private dht network must not bootstrap from public router[%s]", peer)
+		}
+	}
+	allowed := cfg.AllowedNetworks
+	if len(allowed) == 0 {
+		allowed = privateNetworks
+	}
+	for _, cidr := range allowed {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return network, fmt.Errorf("invalid allowed network[%s]: %w", cidr, err)
+		}
+		network.AllowedNetworks = append(network.AllowedNetworks, ipNet)
+	}
+	network.ID = cfg.NetworkID
+	return network, nil
+}
+
+// configure applies the network to the config of a DHT server
+func (n Network) configure(c *dht.ServerConfig) error {
+	if n.ListenAddress != "" {
+		conn, err := net.ListenPacket("udp", n.ListenAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", n.ListenAddress, err)
+		}
+		c.Conn = conn
+	}
+	if n.SendLimiter != nil {
+		c.SendLimiter = n.SendLimiter
+	}
+	if len(n.AllowedNetworks) > 0 {
+		c.IPBlocklist = outsideNetworks(n.AllowedNetworks)
+	}
+	if n.ID != "" {
+		prefix := networkIDPrefix(n.ID)
+		c.NodeId = dht.RandomNodeID()
+		copy(c.NodeId[:], prefix)
+		// node IDs of a network can't also be secured to their IP, so the security extension is disabled
+		c.NoSecurity = true
+		c.OnQuery = func(query *krpc.Msg, _ net.Addr) bool {
+			sender := query.SenderID()
+			return sender != nil && bytes.HasPrefix(sender[:], prefix)
+		}
+	}
+	return nil
+}
+
+// networkIDPrefix returns the node ID prefix of the nodes of the network with the given ID
+func networkIDPrefix(id string) []byte {
+	hash := sha1.Sum([]byte(id))
+	return hash[:networkIDPrefixLength]
+}
+
+// outsideNetworks is an iplist.Ranger blocking every address outside its networks
+type outsideNetworks []*net.IPNet
+
+func (o outsideNetworks) Lookup(ip net.IP) (iplist.Range, bool) {
+	for _, ipNet := range o {
+		if ipNet.Contains(ip) {
+			return iplist.Range{}, false
+		}
+	}
+	return iplist.Range{First: ip, Last: ip, Description: "outside the private dht network"}, true
+}
+
+func (o outsideNetworks) NumRanges() int {
+	return len(o)
+}
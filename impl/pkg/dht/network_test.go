@@ -0,0 +1,103 @@
+package dht
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+)
+
+func TestNetworkFromConfig(t *testing.T) {
+	network, err := NetworkFromConfig(config.DHTServiceConfig{BootstrapPeers: config.GetDefaultBootstrapPeers()})
+	require.NoError(t, err)
+	assert.Empty(t, network.AllowedNetworks)
+
+	_, err = NetworkFromConfig(config.DHTServiceConfig{Private: true})
+	assert.Error(t, err, "private networks require their own bootstrap nodes")
+	_, err = NetworkFromConfig(config.DHTServiceConfig{Private: true, BootstrapPeers: config.GetDefaultBootstrapPeers()})
+	assert.Error(t, err, "private networks can't bootstrap from public routers")
+	_, err = NetworkFromConfig(config.DHTServiceConfig{Private: true, BootstrapPeers: []string{"10.0.0.1:6881"}, AllowedNetworks: []string{"10.0.0.0"}})
+	assert.Error(t, err)
+
+	network, err = NetworkFromConfig(config.DHTServiceConfig{Private: true, BootstrapPeers: []string{"10.0.0.1:6881"}, NetworkID: "internal"})
+	require.NoError(t, err)
+	assert.Len(t, network.AllowedNetworks, len(privateNetworks))
+	assert.Equal(t, "internal", network.ID)
+
+	blocklist := outsideNetworks(network.AllowedNetworks)
+	_, blocked := blocklist.Lookup(net.ParseIP("10.1.2.3"))
+	assert.False(t, blocked)
+	_, blocked = blocklist.Lookup(net.ParseIP("8.8.8.8"))
+	assert.True(t, blocked)
+}
+
+func TestPrivateNetwork(t *testing.T) {
+	_, loopback, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	network := Network{ListenAddress: "127.0.0.1:0", AllowedNetworks: []*net.IPNet{loopback}, ID: "internal"}
+
+	// the first node bootstraps the other two, and none of them reach the public DHT
+	first, err := NewDHTWithNetwork(StaticPeers{}, network)
+	require.NoError(t, err)
+	defer first.Close()
+	bootstrap := StaticPeers{first.Addr().String()}
+	second, err := NewDHTWithNetwork(bootstrap, network)
+	require.NoError(t, err)
+	defer second.Close()
+	third, err := NewDHTWithNetwork(bootstrap, network)
+	require.NoError(t, err)
+	defer third.Close()
+
+	pubKey, privKey, err := util.GenerateKeypair()
+	require.NoError(t, err)
+	put := bep44.Put{V: []byte("hello private dht"), K: (*[32]byte)(pubKey), Seq: time.Now().Unix()}
+	put.Sign(privKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	id, err := second.Put(ctx, put)
+	require.NoError(t, err)
+
+	got, err := third.GetFull(ctx, id, nil)
+	require.NoError(t, err)
+	assert.Equal(t, put.Seq, got.Seq)
+	assert.Equal(t, put.Sig, got.Sig)
+
+	t.Run("nodes knowing no other nodes fail", func(t *testing.T) {
+		lonely, err := NewDHTWithNetwork(StaticPeers{}, network)
+		require.NoError(t, err)
+		defer lonely.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err = lonely.PutReplicated(ctx, put)
+		assert.Error(t, err)
+		_, err = lonely.GetFull(ctx, id, nil)
+		assert.Error(t, err)
+		_, err = lonely.Get(ctx, id)
+		assert.Error(t, err)
+		lonely.SetSeqSearch(time.Second)
+		_, err = lonely.GetFull(ctx, id, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("nodes of other networks are refused", func(t *testing.T) {
+		other := network
+		other.ID = "other"
+		outsider, err := NewDHTWithNetwork(bootstrap, other)
+		require.NoError(t, err)
+		defer outsider.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err = outsider.GetFull(ctx, id, nil)
+		assert.Error(t, err)
+	})
+}
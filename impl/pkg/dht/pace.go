@@ -0,0 +1,153 @@
+package dht
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"golang.org/x/time/rate"
+)
+
+// Budget is the rate of outbound operations of one kind a Paced client allows, as a token bucket refilled at
+// PerSecond tokens a second and holding up to Burst tokens. A zero PerSecond is unlimited.
+type Budget struct {
+	PerSecond float64
+	// Burst is the most operations started at once after a quiet period, one second of budget if zero
+	Burst int
+}
+
+func (b Budget) limiter() *rate.Limiter {
+	if b.PerSecond <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	burst := b.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(b.PerSecond))
+	}
+	return rate.NewLimiter(rate.Limit(b.PerSecond), burst)
+}
+
+// Paced is a Client which paces outbound puts and gets with separate budgets, so a gateway on a constrained network
+// can cap the UDP traffic of its DHT operations. Operations over budget wait for a token, or fail once their context
+// is done.
+type Paced struct {
+	client Client
+	puts   *pacer
+	gets   *pacer
+}
+
+var (
+	_ Client     = (*Paced)(nil)
+	_ Replicator = (*Paced)(nil)
+)
+
+// NewPaced returns a Client pacing the puts and gets of the given client with the given budgets
+func NewPaced(client Client, puts, gets Budget) *Paced {
+	return &Paced{
+		client: client,
+		puts:   &pacer{limiter: puts.limiter()},
+		gets:   &pacer{limiter: gets.limiter()},
+	}
+}
+
+// Put puts the given BEP-44 value once the put budget allows it
+func (p *Paced) Put(ctx context.Context, request bep44.Put) (string, error) {
+	if err := p.puts.wait(ctx); err != nil {
+		return "", err
+	}
+	return p.client.Put(ctx, request)
+}
+
+// PutReplicated puts the given BEP-44 value once the put budget allows it, reporting its replication if the paced
+// client supports it
+func (p *Paced) PutReplicated(ctx context.Context, request bep44.Put) (*PutResult, error) {
+	if err := p.puts.wait(ctx); err != nil {
+		return nil, err
+	}
+	return PutReplicated(ctx, p.client, request)
+}
+
+// GetFull gets the full BEP-44 result for the given key and optional salt once the get budget allows it
+func (p *Paced) GetFull(ctx context.Context, key string, salt []byte) (*FullGetResult, error) {
+	if err := p.gets.wait(ctx); err != nil {
+		return nil, err
+	}
+	return p.client.GetFull(ctx, key, salt)
+}
+
+// PaceStats is the utilization of the outbound DHT budgets
+type PaceStats struct {
+	Puts BudgetStats `json:"puts"`
+	Gets BudgetStats `json:"gets"`
+}
+
+// BudgetStats is the utilization of the budget of one kind of outbound DHT operation
+type BudgetStats struct {
+	// PerSecond is the budgeted rate of operations, unlimited if zero
+	PerSecond float64 `json:"perSecond"`
+	Burst     int     `json:"burst"`
+	// Utilization is the fraction of the burst currently spent, from 0 for a full bucket to 1 for an empty one
+	Utilization float64 `json:"utilization"`
+	// Waiting is the number of operations currently waiting for a token
+	Waiting int64 `json:"waiting"`
+	// Total is the number of operations started since startup
+	Total int64 `json:"total"`
+	// Delayed is the number of operations which had to wait for a token since startup
+	Delayed int64 `json:"delayed"`
+}
+
+// Stats returns the current utilization of the put and get budgets
+func (p *Paced) Stats() PaceStats {
+	return PaceStats{Puts: p.puts.stats(), Gets: p.gets.stats()}
+}
+
+// pacer is the token bucket of one kind of operation, counting the operations it paces
+type pacer struct {
+	limiter *rate.Limiter
+	waiting atomic.Int64
+	total   atomic.Int64
+	delayed atomic.Int64
+}
+
+// wait blocks until a token is available, or returns the context's error once it is done
+func (p *pacer) wait(ctx context.Context) error {
+	reservation := p.limiter.Reserve()
+	if !reservation.OK() {
+		return errors.New("dht operation exceeds its budget")
+	}
+	delay := reservation.Delay()
+	if delay > 0 {
+		p.delayed.Add(1)
+		p.waiting.Add(1)
+		defer p.waiting.Add(-1)
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			reservation.Cancel()
+			return ctx.Err()
+		}
+	}
+	p.total.Add(1)
+	return nil
+}
+
+func (p *pacer) stats() BudgetStats {
+	stats := BudgetStats{
+		Waiting: p.waiting.Load(),
+		Total:   p.total.Load(),
+		Delayed: p.delayed.Load(),
+	}
+	if p.limiter.Limit() == rate.Inf {
+		return stats
+	}
+	stats.PerSecond = float64(p.limiter.Limit())
+	stats.Burst = p.limiter.Burst()
+	// tokens are negative while operations are waiting for them
+	stats.Utilization = math.Min(1, math.Max(0, 1-p.limiter.Tokens()/float64(stats.Burst)))
+	return stats
+}
@@ -0,0 +1,75 @@
+package dht
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nopClient succeeds every put and get immediately
+type nopClient struct{}
+
+func (nopClient) Put(context.Context, bep44.Put) (string, error) {
+	return "", nil
+}
+
+func (nopClient) GetFull(context.Context, string, []byte) (*FullGetResult, error) {
+	return new(FullGetResult), nil
+}
+
+func TestPaced(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unlimited", func(t *testing.T) {
+		paced := NewPaced(nopClient{}, Budget{}, Budget{})
+		for i := 0; i < 100; i++ {
+			_, err := paced.GetFull(ctx, "", nil)
+			require.NoError(t, err)
+		}
+		stats := paced.Stats()
+		assert.Equal(t, BudgetStats{Total: 100}, stats.Gets)
+		assert.Equal(t, BudgetStats{}, stats.Puts)
+	})
+
+	t.Run("budgets are separate", func(t *testing.T) {
+		paced := NewPaced(nopClient{}, Budget{PerSecond: 10, Burst: 2}, Budget{PerSecond: 1, Burst: 1})
+		_, err := paced.GetFull(ctx, "", nil)
+		require.NoError(t, err)
+		assert.InDelta(t, 1.0, paced.Stats().Gets.Utilization, 0.01)
+
+		// the get budget is spent, but puts still have theirs
+		start := time.Now()
+		for i := 0; i < 2; i++ {
+			_, err = paced.Put(ctx, bep44.Put{})
+			require.NoError(t, err)
+		}
+		assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+		// a third put waits for a token
+		_, err = paced.Put(ctx, bep44.Put{})
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+		stats := paced.Stats().Puts
+		assert.Equal(t, 10.0, stats.PerSecond)
+		assert.Equal(t, 2, stats.Burst)
+		assert.Equal(t, int64(3), stats.Total)
+		assert.Equal(t, int64(1), stats.Delayed)
+	})
+
+	t.Run("waiting stops with the context", func(t *testing.T) {
+		paced := NewPaced(nopClient{}, Budget{}, Budget{PerSecond: 0.1, Burst: 1})
+		_, err := paced.GetFull(ctx, "", nil)
+		require.NoError(t, err)
+
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = paced.GetFull(timeout, "", nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Zero(t, paced.Stats().Gets.Waiting)
+		assert.Equal(t, int64(1), paced.Stats().Gets.Total)
+	})
+}
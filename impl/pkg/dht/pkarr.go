@@ -48,6 +48,15 @@ func CreatePKARRPublishRequest(privateKey ed25519.PrivateKey, msg dns.Msg) (*bep
 	return put, nil
 }
 
+// NextSeq returns the seq of a record replacing one with the given seq: the current unix timestamp in seconds, or
+// prev+1 if the clock is behind prev, so seqs keep increasing on clients whose clocks are skewed or were adjusted.
+func NextSeq(prev int64) int64 {
+	if now := time.Now().Unix(); now > prev {
+		return now
+	}
+	return prev + 1
+}
+
 // ParsePKARRGetResponse parses the response from a get request.
 // The response is expected to be a slice of DNS resource records.
 func ParsePKARRGetResponse(response getput.GetResult) (*dns.Msg, error) {
@@ -3,6 +3,7 @@ package dht
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/TBD54566975/ssi-sdk/crypto"
 	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
@@ -11,9 +12,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/TBD54566975/did-dht-method/config"
-	"github.com/TBD54566975/did-dht-method/internal/did"
-	"github.com/TBD54566975/did-dht-method/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
 )
 
 func TestGetPutPKARRDHT(t *testing.T) {
@@ -123,3 +124,13 @@ func TestGetPutDIDDHT(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, gotDoc)
 }
+
+func TestNextSeq(t *testing.T) {
+	now := time.Now().Unix()
+	assert.GreaterOrEqual(t, NextSeq(0), now)
+	assert.GreaterOrEqual(t, NextSeq(now-60), now)
+
+	// a previous seq ahead of the clock is still superseded
+	future := now + 3600
+	assert.Equal(t, future+1, NextSeq(future))
+}
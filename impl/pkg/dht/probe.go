@@ -0,0 +1,161 @@
+package dht
+
+import (
+	"context"
+	"encoding/hex"
+	"slices"
+	"sync"
+
+	errutil "github.com/TBD54566975/ssi-sdk/util"
+	"github.com/anacrolix/dht/v2/int160"
+	"github.com/anacrolix/torrent/types/infohash"
+	"github.com/sirupsen/logrus"
+
+	dhtint "github.com/TBD54566975/did-dht-method/impl/internal/dht"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+)
+
+// DefaultProbeNeighborhoods is the number of neighborhoods a probe starts its traversals from by default
+const DefaultProbeNeighborhoods = 3
+
+// Replication health of a probed record
+const (
+	// ProbeHealthy is a record at least half of the closest nodes hold the latest seq of
+	ProbeHealthy = "healthy"
+	// ProbeDegraded is a record fewer than half of the closest nodes hold the latest seq of, which resolves from some
+	// neighborhoods but not others
+	ProbeDegraded = "degraded"
+	// ProbeMissing is a record no probed node holds
+	ProbeMissing = "missing"
+)
+
+// ProbedNode is a node of a record's neighborhood, and the seq of the record it holds, if it holds it
+type ProbedNode struct {
+	ID    string `json:"id"`
+	Addr  string `json:"addr"`
+	Holds bool   `json:"holds"`
+	Seq   int64  `json:"seq,omitempty"`
+	// Closest is whether the node is one of the replication factor of nodes closest to the record, which puts target
+	Closest bool `json:"closest"`
+}
+
+// ProbeReport is where a record is replicated on the DHT
+type ProbeReport struct {
+	// Key is the z32-encoded key of the record
+	Key string `json:"key"`
+	// Neighborhoods is the number of traversals, each starting from other nodes, the nodes were found by
+	Neighborhoods int `json:"neighborhoods"`
+	// Nodes are the nodes closest to the record found by any of the traversals, closest first
+	Nodes []ProbedNode `json:"nodes"`
+	// Replication is the number of closest nodes puts target
+	Replication int `json:"replication"`
+	// Holders is the number of nodes holding the record, of which Current hold the latest seq found and Stale an
+	// older one
+	Holders   int   `json:"holders"`
+	LatestSeq int64 `json:"latestSeq,omitempty"`
+	Current   int   `json:"current"`
+	Stale     int   `json:"stale"`
+	// ClosestCurrent is the number of the closest nodes holding the latest seq, which Health is estimated from
+	ClosestCurrent int    `json:"closestCurrent"`
+	Health         string `json:"health"`
+}
+
+// Probe finds the nodes closest to the record of the given key and optional salt from the given number of
+// neighborhoods, DefaultProbeNeighborhoods if not positive, each traversal starting from other nodes than the others,
+// and reports which of them hold the record, at which seq, and how healthy its replication is. A record which
+// resolves through some nodes but not others is typically held by few of the nodes closest to it.
+func (d *DHT) Probe(ctx context.Context, key string, salt []byte, neighborhoods int) (*ProbeReport, error) {
+	if neighborhoods <= 0 {
+		neighborhoods = DefaultProbeNeighborhoods
+	}
+	z32Decoded, err := util.Z32Decode(key)
+	if err != nil {
+		return nil, errutil.LoggingErrorMsg(err, "failed to decode key")
+	}
+	target := infohash.HashBytes(append(z32Decoded, salt...))
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		found    = make(map[string]dhtint.ProbedNode)
+		failures int
+	)
+	for i := 0; i < neighborhoods; i++ {
+		wg.Add(1)
+		go func(neighborhood int) {
+			defer wg.Done()
+			nodes, _, err := dhtint.Probe(ctx, target, d.Server, salt, d.replication*putCandidateFactor, neighborhood, d.reputation.Observe)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures++
+				logrus.WithError(err).Warnf("failed to probe neighborhood %d of key[%s]", neighborhood, key)
+				return
+			}
+			for _, node := range nodes {
+				addr := node.Addr.String()
+				if prev, ok := found[addr]; ok && (!node.Holds || prev.Holds && prev.Seq >= node.Seq) {
+					continue
+				}
+				found[addr] = node
+			}
+		}(i)
+	}
+	wg.Wait()
+	if failures == neighborhoods {
+		return nil, errutil.LoggingNewErrorf("failed to probe key[%s] from any neighborhood", key)
+	}
+
+	nodes := make([]dhtint.ProbedNode, 0, len(found))
+	for _, node := range found {
+		nodes = append(nodes, node)
+	}
+	t := int160.FromByteArray(target)
+	slices.SortFunc(nodes, func(a, b dhtint.ProbedNode) int {
+		return int160.FromByteArray(a.ID).Distance(t).Cmp(int160.FromByteArray(b.ID).Distance(t))
+	})
+	report := newProbeReport(key, nodes, d.replication)
+	report.Neighborhoods = neighborhoods
+	return report, nil
+}
+
+// newProbeReport reports the replication of the record of the key held by the given nodes, closest first
+func newProbeReport(key string, nodes []dhtint.ProbedNode, replication int) *ProbeReport {
+	report := ProbeReport{Key: key, Nodes: make([]ProbedNode, 0, len(nodes)), Replication: replication}
+	for _, node := range nodes {
+		if node.Holds {
+			report.Holders++
+			report.LatestSeq = max(report.LatestSeq, node.Seq)
+		}
+	}
+	for i, node := range nodes {
+		closest := i < replication
+		report.Nodes = append(report.Nodes, ProbedNode{
+			ID:      hex.EncodeToString(node.ID[:]),
+			Addr:    node.Addr.String(),
+			Holds:   node.Holds,
+			Seq:     node.Seq,
+			Closest: closest,
+		})
+		if !node.Holds {
+			continue
+		}
+		if node.Seq < report.LatestSeq {
+			report.Stale++
+			continue
+		}
+		report.Current++
+		if closest {
+			report.ClosestCurrent++
+		}
+	}
+	switch {
+	case report.Holders == 0:
+		report.Health = ProbeMissing
+	case report.ClosestCurrent*2 >= replication:
+		report.Health = ProbeHealthy
+	default:
+		report.Health = ProbeDegraded
+	}
+	return &report
+}
@@ -0,0 +1,46 @@
+package dht
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	dhtint "github.com/TBD54566975/did-dht-method/impl/internal/dht"
+)
+
+func TestNewProbeReport(t *testing.T) {
+	nodes := func(seqs ...int64) []dhtint.ProbedNode {
+		probed := make([]dhtint.ProbedNode, len(seqs))
+		for i, seq := range seqs {
+			// a seq of zero stands for a node which doesn't hold the record
+			probed[i] = dhtint.ProbedNode{ID: [20]byte{byte(i)}, Holds: seq > 0, Seq: seq}
+		}
+		return probed
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		report := newProbeReport("key", nodes(0, 0, 0, 0), 4)
+		assert.Equal(t, ProbeMissing, report.Health)
+		assert.Zero(t, report.Holders)
+		assert.Len(t, report.Nodes, 4)
+	})
+
+	t.Run("healthy", func(t *testing.T) {
+		report := newProbeReport("key", nodes(2, 0, 2, 1, 0, 2), 4)
+		assert.Equal(t, ProbeHealthy, report.Health)
+		assert.Equal(t, int64(2), report.LatestSeq)
+		assert.Equal(t, 4, report.Holders)
+		assert.Equal(t, 3, report.Current)
+		assert.Equal(t, 1, report.Stale)
+		assert.Equal(t, 2, report.ClosestCurrent)
+		assert.True(t, report.Nodes[3].Closest)
+		assert.False(t, report.Nodes[4].Closest)
+	})
+
+	t.Run("held at the latest seq by too few of the closest nodes", func(t *testing.T) {
+		report := newProbeReport("key", nodes(1, 1, 2, 0, 2, 2), 4)
+		assert.Equal(t, ProbeDegraded, report.Health)
+		assert.Equal(t, 1, report.ClosestCurrent)
+		assert.Equal(t, 2, report.Stale)
+	})
+}
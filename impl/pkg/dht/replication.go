@@ -0,0 +1,49 @@
+package dht
+
+import (
+	"context"
+
+	"github.com/anacrolix/dht/v2/bep44"
+)
+
+const (
+	// defaultReplication is the number of closest nodes puts target by default, the size of a routing table bucket
+	defaultReplication = 8
+	// putCandidateFactor is how many times the replication factor of closest nodes are found for a put, so nodes
+	// which don't store it can be replaced by further ones
+	putCandidateFactor = 2
+)
+
+// PutResult is how widely a put value was replicated
+type PutResult struct {
+	// Key is the z32-encoded key of the value
+	Key string `json:"key"`
+	// Replication is the number of closest nodes the put targeted, unknown if zero
+	Replication int `json:"replication"`
+	// Stored is the number of nodes which acknowledged storing the value
+	Stored int `json:"stored"`
+	// Attempted is the number of nodes the value was put to, including further nodes put to after others failed
+	Attempted int `json:"attempted"`
+}
+
+// Replicated returns whether as many nodes as targeted stored the value
+func (r PutResult) Replicated() bool {
+	return r.Stored >= r.Replication
+}
+
+// Replicator is a Client which reports how widely the values it puts are replicated
+type Replicator interface {
+	PutReplicated(ctx context.Context, request bep44.Put) (*PutResult, error)
+}
+
+// PutReplicated puts the given BEP-44 value with the client, reporting its replication if the client supports it
+func PutReplicated(ctx context.Context, client Client, request bep44.Put) (*PutResult, error) {
+	if replicator, ok := client.(Replicator); ok {
+		return replicator.PutReplicated(ctx, request)
+	}
+	key, err := client.Put(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &PutResult{Key: key}, nil
+}
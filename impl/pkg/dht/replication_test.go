@@ -0,0 +1,21 @@
+package dht
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutReplicated(t *testing.T) {
+	result, err := PutReplicated(context.Background(), nopClient{}, bep44.Put{})
+	require.NoError(t, err)
+	assert.Zero(t, result.Replication)
+	assert.True(t, result.Replicated(), "clients which don't report replication aren't under-replicated")
+
+	result, err = NewPaced(nopClient{}, Budget{}, Budget{}).PutReplicated(context.Background(), bep44.Put{})
+	require.NoError(t, err)
+	assert.True(t, result.Replicated())
+}
@@ -0,0 +1,259 @@
+package dht
+
+import (
+	"cmp"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/dht/v2/krpc"
+	"github.com/anacrolix/torrent/iplist"
+	"github.com/sirupsen/logrus"
+
+	dhtint "github.com/TBD54566975/did-dht-method/impl/internal/dht"
+)
+
+const (
+	// defaultBanDuration is how long misbehaving peers are banned for by default
+	defaultBanDuration = 10 * time.Minute
+	// maxTrackedPeers bounds the number of peers a Reputation keeps scores for, since a gateway on the open DHT
+	// queries an unbounded number of them
+	maxTrackedPeers = 10000
+)
+
+// PeerScorer is a Client which keeps the reputation of the peers it queries
+type PeerScorer interface {
+	Reputation() *Reputation
+}
+
+// Reputation scores the peers a DHT queries by how they respond, temporarily banning the addresses of peers which
+// time out, fail, or send malformed responses too many times in a row. Banned addresses are neither queried nor
+// answered until their ban expires, which keeps resolutions on the open DHT from waiting on unreliable nodes.
+type Reputation struct {
+	mu sync.Mutex
+	// threshold is the number of consecutive failures which bans a peer, never if zero
+	threshold int
+	ban       time.Duration
+	// level is the level failures and bans are logged at
+	level logrus.Level
+	peers map[string]*peerReputation
+	stats ReputationStats
+}
+
+// peerReputation is the score of the peers at one address
+type peerReputation struct {
+	PeerReputation
+	bannedUntil time.Time
+}
+
+// PeerReputation is how the peers at an address have responded to queries
+type PeerReputation struct {
+	// Addr is the IP address of the peers
+	Addr      string `json:"addr"`
+	Responses int64  `json:"responses"`
+	Timeouts  int64  `json:"timeouts"`
+	Failures  int64  `json:"failures"`
+	Malformed int64  `json:"malformed"`
+	// Score is the number of consecutive queries the peers didn't respond to properly
+	Score int `json:"score"`
+	// BannedUntil is the unix timestamp in seconds the ban of the address expires at, if banned
+	BannedUntil int64 `json:"bannedUntil,omitempty"`
+}
+
+// ReputationStats counts the outcomes of queries and the bans of misbehaving peers since startup
+type ReputationStats struct {
+	// Threshold is the number of consecutive failures which bans a peer, never if zero
+	Threshold  int   `json:"threshold"`
+	BanSeconds int64 `json:"banSeconds"`
+	Responses  int64 `json:"responses"`
+	Timeouts   int64 `json:"timeouts"`
+	Failures   int64 `json:"failures"`
+	Malformed  int64 `json:"malformed"`
+	// Bans is the number of bans since startup, including bans made by an operator
+	Bans int64 `json:"bans"`
+	// Banned is the number of addresses currently banned
+	Banned int `json:"banned"`
+	// Peers are the addresses which are banned or failed their last query, ordered by address
+	Peers []PeerReputation `json:"peers"`
+}
+
+// NewReputation returns a Reputation banning peers for the given duration after threshold consecutive failures, or
+// never banning them automatically if threshold is zero
+func NewReputation(threshold int, ban time.Duration) *Reputation {
+	r := &Reputation{peers: make(map[string]*peerReputation), level: logrus.DebugLevel}
+	r.SetBanPolicy(threshold, ban)
+	return r
+}
+
+// SetBanPolicy bans peers for the given duration, 10 minutes if not positive, after threshold consecutive failures,
+// or never automatically if threshold is zero
+func (r *Reputation) SetBanPolicy(threshold int, ban time.Duration) {
+	if ban <= 0 {
+		ban = defaultBanDuration
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.threshold = max(threshold, 0)
+	r.ban = ban
+}
+
+// SetLogLevel sets the level failures and bans are logged at, debug by default
+func (r *Reputation) SetLogLevel(level logrus.Level) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.level = level
+}
+
+// Observe scores the peer at the address by the outcome of a query to it
+func (r *Reputation) Observe(addr krpc.NodeAddr, outcome dhtint.QueryOutcome) {
+	ip := addr.IP.String()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	peer := r.peer(ip)
+	switch outcome {
+	case dhtint.QueryResponded:
+		peer.Responses++
+		r.stats.Responses++
+		peer.Score = 0
+		return
+	case dhtint.QueryTimedOut:
+		peer.Timeouts++
+		r.stats.Timeouts++
+	case dhtint.QueryFailed:
+		peer.Failures++
+		r.stats.Failures++
+	case dhtint.QueryMalformed:
+		peer.Malformed++
+		r.stats.Malformed++
+	}
+	peer.Score++
+	fields := logrus.Fields{
+		"addr":      ip,
+		"score":     peer.Score,
+		"timeouts":  peer.Timeouts,
+		"failures":  peer.Failures,
+		"malformed": peer.Malformed,
+	}
+	if r.threshold == 0 || peer.Score < r.threshold || r.banned(peer, time.Now()) {
+		logrus.WithFields(fields).Log(r.level, "dht peer failed to respond properly")
+		return
+	}
+	r.banPeer(peer, r.ban)
+	logrus.WithFields(fields).Log(min(r.level, logrus.InfoLevel), "banned misbehaving dht peer")
+}
+
+// Ban bans the given IP address for the given duration, the ban duration of the policy if not positive
+func (r *Reputation) Ban(ip net.IP, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if duration <= 0 {
+		duration = r.ban
+	}
+	r.banPeer(r.peer(ip.String()), duration)
+}
+
+// Unban lifts the ban of the given IP address, if any, and resets its score
+func (r *Reputation) Unban(ip net.IP) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if peer, ok := r.peers[ip.String()]; ok {
+		peer.bannedUntil = time.Time{}
+		peer.Score = 0
+	}
+}
+
+// Stats returns the outcomes of queries and the bans since startup, with the addresses which are banned or failed
+// their last query
+func (r *Reputation) Stats() ReputationStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.stats
+	stats.Threshold = r.threshold
+	stats.BanSeconds = int64(r.ban / time.Second)
+	stats.Peers = []PeerReputation{}
+	now := time.Now()
+	for _, peer := range r.peers {
+		banned := r.banned(peer, now)
+		if !banned && peer.Score == 0 {
+			continue
+		}
+		reputation := peer.PeerReputation
+		if banned {
+			stats.Banned++
+			reputation.BannedUntil = peer.bannedUntil.Unix()
+		}
+		stats.Peers = append(stats.Peers, reputation)
+	}
+	slices.SortFunc(stats.Peers, func(a, b PeerReputation) int {
+		return cmp.Compare(a.Addr, b.Addr)
+	})
+	return stats
+}
+
+// Lookup blocks the addresses of banned peers, making Reputation the iplist.Ranger of a DHT server's blocklist
+func (r *Reputation) Lookup(ip net.IP) (iplist.Range, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	peer, ok := r.peers[ip.String()]
+	if !ok || !r.banned(peer, time.Now()) {
+		return iplist.Range{}, false
+	}
+	return iplist.Range{First: ip, Last: ip, Description: "banned misbehaving dht peer"}, true
+}
+
+func (r *Reputation) NumRanges() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.peers)
+}
+
+// peer returns the score of the address, making room for it by forgetting a peer in good standing if too many are
+// tracked
+func (r *Reputation) peer(ip string) *peerReputation {
+	if peer, ok := r.peers[ip]; ok {
+		return peer
+	}
+	if len(r.peers) >= maxTrackedPeers {
+		now := time.Now()
+		for addr, peer := range r.peers {
+			if !r.banned(peer, now) {
+				delete(r.peers, addr)
+				break
+			}
+		}
+	}
+	peer := &peerReputation{PeerReputation: PeerReputation{Addr: ip}}
+	r.peers[ip] = peer
+	return peer
+}
+
+func (r *Reputation) banPeer(peer *peerReputation, duration time.Duration) {
+	peer.bannedUntil = time.Now().Add(duration)
+	peer.Score = 0
+	r.stats.Bans++
+}
+
+func (r *Reputation) banned(peer *peerReputation, now time.Time) bool {
+	return now.Before(peer.bannedUntil)
+}
+
+// blocklists is an iplist.Ranger blocking the addresses any of its lists block
+type blocklists []iplist.Ranger
+
+func (b blocklists) Lookup(ip net.IP) (iplist.Range, bool) {
+	for _, list := range b {
+		if r, blocked := list.Lookup(ip); blocked {
+			return r, true
+		}
+	}
+	return iplist.Range{}, false
+}
+
+func (b blocklists) NumRanges() int {
+	var n int
+	for _, list := range b {
+		n += list.NumRanges()
+	}
+	return n
+}
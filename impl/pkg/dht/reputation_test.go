@@ -0,0 +1,89 @@
+package dht
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/krpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	dhtint "github.com/TBD54566975/did-dht-method/impl/internal/dht"
+)
+
+func TestReputation(t *testing.T) {
+	peer := krpc.NodeAddr{IP: net.ParseIP("10.0.0.1"), Port: 6881}
+
+	t.Run("consecutive failures ban a peer", func(t *testing.T) {
+		reputation := NewReputation(3, time.Minute)
+		reputation.Observe(peer, dhtint.QueryTimedOut)
+		reputation.Observe(peer, dhtint.QueryMalformed)
+		// a proper response resets the score
+		reputation.Observe(peer, dhtint.QueryResponded)
+		reputation.Observe(peer, dhtint.QueryTimedOut)
+		reputation.Observe(peer, dhtint.QueryFailed)
+		_, blocked := reputation.Lookup(peer.IP)
+		assert.False(t, blocked)
+
+		reputation.Observe(peer, dhtint.QueryTimedOut)
+		_, blocked = reputation.Lookup(peer.IP)
+		assert.True(t, blocked)
+		_, blocked = reputation.Lookup(net.ParseIP("10.0.0.2"))
+		assert.False(t, blocked)
+
+		stats := reputation.Stats()
+		assert.Equal(t, int64(1), stats.Bans)
+		assert.Equal(t, 1, stats.Banned)
+		assert.Equal(t, int64(3), stats.Timeouts)
+		assert.Equal(t, int64(1), stats.Malformed)
+		require.Len(t, stats.Peers, 1)
+		assert.Equal(t, "10.0.0.1", stats.Peers[0].Addr)
+		assert.NotZero(t, stats.Peers[0].BannedUntil)
+
+		reputation.Unban(peer.IP)
+		_, blocked = reputation.Lookup(peer.IP)
+		assert.False(t, blocked)
+		assert.Empty(t, reputation.Stats().Peers)
+	})
+
+	t.Run("no threshold never bans automatically", func(t *testing.T) {
+		reputation := NewReputation(0, time.Minute)
+		for i := 0; i < 100; i++ {
+			reputation.Observe(peer, dhtint.QueryTimedOut)
+		}
+		_, blocked := reputation.Lookup(peer.IP)
+		assert.False(t, blocked)
+		stats := reputation.Stats()
+		require.Len(t, stats.Peers, 1)
+		assert.Equal(t, 100, stats.Peers[0].Score)
+
+		reputation.Ban(peer.IP, 0)
+		_, blocked = reputation.Lookup(peer.IP)
+		assert.True(t, blocked)
+	})
+
+	t.Run("bans expire", func(t *testing.T) {
+		reputation := NewReputation(1, time.Minute)
+		reputation.Ban(peer.IP, 10*time.Millisecond)
+		_, blocked := reputation.Lookup(peer.IP)
+		assert.True(t, blocked)
+		time.Sleep(20 * time.Millisecond)
+		_, blocked = reputation.Lookup(peer.IP)
+		assert.False(t, blocked)
+	})
+
+	t.Run("blocklists are combined", func(t *testing.T) {
+		reputation := NewReputation(1, time.Minute)
+		reputation.Observe(peer, dhtint.QueryFailed)
+		_, private, err := net.ParseCIDR("10.0.0.0/8")
+		require.NoError(t, err)
+		list := blocklists{outsideNetworks{private}, reputation}
+		_, blocked := list.Lookup(net.ParseIP("8.8.8.8"))
+		assert.True(t, blocked)
+		_, blocked = list.Lookup(peer.IP)
+		assert.True(t, blocked)
+		_, blocked = list.Lookup(net.ParseIP("10.0.0.2"))
+		assert.False(t, blocked)
+	})
+}
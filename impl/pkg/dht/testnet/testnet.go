@@ -0,0 +1,87 @@
+// Package testnet runs networks of in-process DHT nodes on the loopback interface, wired together and isolated from
+// the public Mainline DHT, for integration tests of putting, getting, and republishing records.
+package testnet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/anacrolix/dht/v2/krpc"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+)
+
+// Network is a network of in-process DHT nodes, each of which knows every other node from the start
+type Network struct {
+	Nodes []*dht.DHT
+}
+
+// New starts a network of the given number of nodes, listening on random loopback ports. Every node is added to the
+// routing table of every other node, so puts and gets don't depend on bootstrapping. Nodes only exchange messages
+// over loopback, and the network has its own network ID, so concurrent networks don't serve each other. The network
+// is stopped when the test completes.
+func New(t testing.TB, size int) *Network {
+	require.Positive(t, size)
+	_, loopback, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	id := make([]byte, 8)
+	_, err = rand.Read(id)
+	require.NoError(t, err)
+	network := dht.Network{
+		ListenAddress:   "127.0.0.1:0",
+		AllowedNetworks: []*net.IPNet{loopback},
+		ID:              hex.EncodeToString(id),
+	}
+
+	n := &Network{Nodes: make([]*dht.DHT, 0, size)}
+	t.Cleanup(n.Close)
+	for i := 0; i < size; i++ {
+		// each node limits its own sends, rather than sharing the default limiter of the process with the other nodes,
+		// which drops their replies as soon as a few puts and gets are made at once
+		network.SendLimiter = rate.NewLimiter(rate.Inf, 0)
+		// nodes bootstrap from the other nodes, should their routing tables empty
+		node, err := dht.NewDHTWithNetwork(dht.BootstrapFunc(n.peers), network)
+		require.NoError(t, err)
+		n.Nodes = append(n.Nodes, node)
+	}
+	for _, node := range n.Nodes {
+		for _, other := range n.Nodes {
+			if node == other {
+				continue
+			}
+			info := krpc.NodeInfo{ID: other.ID(), Addr: krpc.NodeAddr{IP: net.IPv4(127, 0, 0, 1), Port: other.Addr().(*net.UDPAddr).Port}}
+			require.NoError(t, node.AddNode(info))
+		}
+	}
+	return n
+}
+
+// Node returns the i-th node of the network
+func (n *Network) Node(i int) *dht.DHT {
+	return n.Nodes[i]
+}
+
+// Addrs returns the "host:port" addresses of the nodes of the network, such as to bootstrap other DHTs from
+func (n *Network) Addrs() []string {
+	addrs := make([]string, 0, len(n.Nodes))
+	for _, node := range n.Nodes {
+		addrs = append(addrs, node.Addr().String())
+	}
+	return addrs
+}
+
+func (n *Network) peers(context.Context) ([]string, error) {
+	return n.Addrs(), nil
+}
+
+// Close stops every node of the network
+func (n *Network) Close() {
+	for _, node := range n.Nodes {
+		node.Close()
+	}
+}
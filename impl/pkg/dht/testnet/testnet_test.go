@@ -0,0 +1,107 @@
+package testnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+)
+
+func TestNetwork(t *testing.T) {
+	network := New(t, 3)
+	require.Len(t, network.Addrs(), 3)
+
+	pubKey, privKey, err := util.GenerateKeypair()
+	require.NoError(t, err)
+	put := bep44.Put{V: []byte("hello testnet"), K: (*[32]byte)(pubKey), Seq: 1}
+	put.Sign(privKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	id, err := network.Node(0).Put(ctx, put)
+	require.NoError(t, err)
+	for _, node := range network.Nodes {
+		got, err := node.GetFull(ctx, id, nil)
+		require.NoError(t, err)
+		assert.Equal(t, put.Seq, got.Seq)
+	}
+
+	t.Run("updates replace older values", func(t *testing.T) {
+		updated := bep44.Put{V: []byte("hello again"), K: put.K, Seq: 2}
+		updated.Sign(privKey)
+		_, err := network.Node(1).Put(ctx, updated)
+		require.NoError(t, err)
+		for _, node := range network.Nodes {
+			got, err := node.GetFull(ctx, id, nil)
+			require.NoError(t, err)
+			assert.Equal(t, updated.Seq, got.Seq)
+			assert.Equal(t, updated.Sig, got.Sig)
+		}
+	})
+
+	t.Run("replication", func(t *testing.T) {
+		pubKey, privKey, err := util.GenerateKeypair()
+		require.NoError(t, err)
+		node := network.Node(0)
+		defer node.SetReplication(0)
+
+		// the two other nodes are all the network has to replicate to
+		node.SetReplication(2)
+		put := bep44.Put{V: []byte("hello replicas"), K: (*[32]byte)(pubKey), Seq: 1}
+		put.Sign(privKey)
+		result, err := node.PutReplicated(ctx, put)
+		require.NoError(t, err)
+		assert.Equal(t, util.Z32Encode(pubKey), result.Key)
+		assert.Equal(t, 2, result.Stored)
+		assert.True(t, result.Replicated())
+
+		// a put targets no more nodes than the network has, however many are asked for
+		node.SetReplication(4)
+		put.Seq++
+		put.Sign(privKey)
+		result, err = node.PutReplicated(ctx, put)
+		require.NoError(t, err)
+		assert.Equal(t, 4, result.Replication)
+		assert.GreaterOrEqual(t, result.Stored, 1)
+		assert.LessOrEqual(t, result.Stored, 2)
+		assert.Equal(t, 2, result.Attempted)
+		assert.False(t, result.Replicated())
+	})
+
+	t.Run("highest seq", func(t *testing.T) {
+		pubKey, privKey, err := util.GenerateKeypair()
+		require.NoError(t, err)
+		node := network.Node(2)
+		defer node.SetReplication(0)
+
+		// both other nodes store the first seq, but only one of them the second
+		first := bep44.Put{V: []byte("first"), K: (*[32]byte)(pubKey), Seq: 1}
+		first.Sign(privKey)
+		id, err := node.Put(ctx, first)
+		require.NoError(t, err)
+		node.SetReplication(1)
+		second := bep44.Put{V: []byte("second"), K: first.K, Seq: 2}
+		second.Sign(privKey)
+		_, err = node.Put(ctx, second)
+		require.NoError(t, err)
+
+		got, err := node.GetHighestSeq(ctx, id, nil, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, second.Seq, got.Seq)
+		assert.Equal(t, second.Sig, got.Sig)
+		assert.Equal(t, []int64{1, 2}, got.Seqs)
+	})
+
+	t.Run("networks are isolated", func(t *testing.T) {
+		other := New(t, 2)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err := other.Node(0).GetFull(ctx, id, nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,37 @@
+package dht
+
+import (
+	"context"
+	"time"
+)
+
+// getDeadlineMargin is how long before the deadline of a get's context its traversal stops, at most half the time
+// left, leaving the caller time to verify the record and respond with it
+const getDeadlineMargin = 500 * time.Millisecond
+
+// TraversalBudget bounds the traversal of a get, so a get returns the best record found so far, rather than nothing,
+// when the request it serves is about to time out.
+type TraversalBudget struct {
+	// MaxOutstanding is the most nodes queried at once, 15 if not positive
+	MaxOutstanding int
+	// MaxDuration, if positive, is the longest a traversal runs
+	MaxDuration time.Duration
+}
+
+// context returns the context of a traversal within the budget, which is done by the max duration, or shortly before
+// the given context's deadline, whichever is sooner
+func (b TraversalBudget) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	limit := b.MaxDuration
+	if deadline, ok := ctx.Deadline(); ok {
+		left := time.Until(deadline)
+		left -= min(getDeadlineMargin, left/2)
+		if limit <= 0 || left < limit {
+			limit = left
+		}
+	}
+	if limit <= 0 {
+		// there is no limit, or the deadline has passed and the context is done already
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, limit)
+}
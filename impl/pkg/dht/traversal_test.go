@@ -0,0 +1,56 @@
+package dht
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraversalBudgetContext(t *testing.T) {
+	remaining := func(ctx context.Context) time.Duration {
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+		return time.Until(deadline)
+	}
+
+	t.Run("unbounded", func(t *testing.T) {
+		ctx, cancel := TraversalBudget{}.context(context.Background())
+		defer cancel()
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+	})
+
+	t.Run("max duration", func(t *testing.T) {
+		ctx, cancel := TraversalBudget{MaxDuration: 5 * time.Second}.context(context.Background())
+		defer cancel()
+		assert.InDelta(t, 5*time.Second, remaining(ctx), float64(100*time.Millisecond))
+	})
+
+	t.Run("request deadline", func(t *testing.T) {
+		request, cancelRequest := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancelRequest()
+		ctx, cancel := TraversalBudget{MaxDuration: 5 * time.Second}.context(request)
+		defer cancel()
+		// the traversal stops short of the request's deadline
+		assert.InDelta(t, 3*time.Second-getDeadlineMargin, remaining(ctx), float64(100*time.Millisecond))
+	})
+
+	t.Run("short request deadline", func(t *testing.T) {
+		request, cancelRequest := context.WithTimeout(context.Background(), 400*time.Millisecond)
+		defer cancelRequest()
+		ctx, cancel := TraversalBudget{}.context(request)
+		defer cancel()
+		assert.InDelta(t, 200*time.Millisecond, remaining(ctx), float64(50*time.Millisecond))
+	})
+
+	t.Run("passed deadline", func(t *testing.T) {
+		request, cancelRequest := context.WithTimeout(context.Background(), -time.Second)
+		defer cancelRequest()
+		ctx, cancel := TraversalBudget{MaxDuration: 5 * time.Second}.context(request)
+		defer cancel()
+		assert.Error(t, ctx.Err())
+	})
+}
@@ -0,0 +1,177 @@
+package did
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/TBD54566975/ssi-sdk/did/jwk"
+	"github.com/TBD54566975/ssi-sdk/did/key"
+	"github.com/tv42/zbase32"
+)
+
+var (
+	// ErrUnsupportedMethod is returned for identifiers of DID methods without a registered Bridge
+	ErrUnsupportedMethod = errors.New("unsupported did method")
+	// ErrNotIdentityKey is returned for identifiers whose key can't be a did:dht identity key, which must be an
+	// ed25519 signing key
+	ErrNotIdentityKey = errors.New("key is not an ed25519 signing key")
+)
+
+// Bridge converts the identifiers of another DID method to and from did:dht, for methods whose key can be a did:dht
+// identity key. It eases migrating existing identities onto the DHT: the holder keeps their key, and signs the
+// did:dht record with it.
+type Bridge interface {
+	// Method is the DID method bridged
+	Method() did.Method
+	// IdentityKey returns the ed25519 public key of the identifier, or ErrNotIdentityKey if it has another key
+	IdentityKey(identifier string) (ed25519.PublicKey, error)
+	// Identifier returns the identifier of the method for the ed25519 public key
+	Identifier(pubKey ed25519.PublicKey) (string, error)
+}
+
+var (
+	bridgesMu sync.RWMutex
+	bridges   = map[did.Method]Bridge{
+		did.KeyMethod: KeyBridge{},
+		did.JWKMethod: JWKBridge{},
+	}
+)
+
+// RegisterBridge registers the bridge for its DID method, replacing any bridge registered for the method before
+func RegisterBridge(bridge Bridge) {
+	bridgesMu.Lock()
+	defer bridgesMu.Unlock()
+	bridges[bridge.Method()] = bridge
+}
+
+// BridgedMethods returns the DID methods with a registered Bridge, in alphabetical order
+func BridgedMethods() []did.Method {
+	bridgesMu.RLock()
+	defer bridgesMu.RUnlock()
+	methods := make([]did.Method, 0, len(bridges))
+	for method := range bridges {
+		methods = append(methods, method)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i] < methods[j] })
+	return methods
+}
+
+func bridgeFor(method did.Method) (Bridge, error) {
+	bridgesMu.RLock()
+	defer bridgesMu.RUnlock()
+	bridge, ok := bridges[method]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMethod, method)
+	}
+	return bridge, nil
+}
+
+// ImportDID returns the did:dht Document of the identifier of a bridged DID method, whose identity key is the key of
+// the identifier, and which lists the identifier as an alternative identifier (alsoKnownAs)
+func ImportDID(identifier string, opts CreateDIDDHTOpts) (*did.Document, error) {
+	parts := strings.SplitN(identifier, ":", 3)
+	if len(parts) != 3 || parts[0] != "did" {
+		return nil, fmt.Errorf("invalid did: %s", identifier)
+	}
+	bridge, err := bridgeFor(did.Method(parts[1]))
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := bridge.IdentityKey(identifier)
+	if err != nil {
+		return nil, err
+	}
+	opts.AlsoKnownAs = append(opts.AlsoKnownAs, identifier)
+	return CreateDIDDHTDID(pubKey, opts)
+}
+
+// ExportDID returns the identifier of the bridged DID method for the identity key of the did:dht identifier. Only the
+// identity key carries over; the other keys and services of the did:dht Document don't.
+func ExportDID(id DHT, method did.Method) (string, error) {
+	bridge, err := bridgeFor(method)
+	if err != nil {
+		return "", err
+	}
+	suffix, err := id.Suffix()
+	if err != nil {
+		return "", err
+	}
+	pubKey, err := zbase32.DecodeString(suffix)
+	if err != nil || len(pubKey) != ed25519.PublicKeySize {
+		return "", fmt.Errorf("invalid did:dht identifier: %s", id)
+	}
+	return bridge.Identifier(pubKey)
+}
+
+// KeyBridge bridges did:key identifiers of ed25519 keys, https://w3c-ccg.github.io/did-method-key/
+type KeyBridge struct{}
+
+func (KeyBridge) Method() did.Method {
+	return did.KeyMethod
+}
+
+func (KeyBridge) IdentityKey(identifier string) (ed25519.PublicKey, error) {
+	pubKey, keyType, err := key.DIDKey(identifier).Decode()
+	if err != nil {
+		return nil, err
+	}
+	if keyType != crypto.Ed25519 || len(pubKey) != ed25519.PublicKeySize {
+		return nil, ErrNotIdentityKey
+	}
+	return pubKey, nil
+}
+
+func (KeyBridge) Identifier(pubKey ed25519.PublicKey) (string, error) {
+	didKey, err := key.CreateDIDKey(crypto.Ed25519, pubKey)
+	if err != nil {
+		return "", err
+	}
+	return didKey.String(), nil
+}
+
+// JWKBridge bridges did:jwk identifiers of ed25519 signing keys, https://github.com/quartzjer/did-jwk/blob/main/spec.md
+type JWKBridge struct{}
+
+func (JWKBridge) Method() did.Method {
+	return did.JWKMethod
+}
+
+func (JWKBridge) IdentityKey(identifier string) (ed25519.PublicKey, error) {
+	doc, err := jwk.JWK(identifier).Expand()
+	if err != nil {
+		return nil, err
+	}
+	publicKeyJWK := doc.VerificationMethod[0].PublicKeyJWK
+	// keys only for encryption can't sign records
+	if publicKeyJWK.Use == "enc" {
+		return nil, ErrNotIdentityKey
+	}
+	pubKey, err := publicKeyJWK.ToPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := pubKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, ErrNotIdentityKey
+	}
+	return edKey, nil
+}
+
+func (JWKBridge) Identifier(pubKey ed25519.PublicKey) (string, error) {
+	publicKeyJWK, err := jwx.PublicKeyToPublicKeyJWK(nil, pubKey)
+	if err != nil {
+		return "", err
+	}
+	didJWK, err := jwk.CreateDIDJWK(*publicKeyJWK)
+	if err != nil {
+		return "", err
+	}
+	return didJWK.String(), nil
+}
@@ -0,0 +1,61 @@
+package did
+
+import (
+	"testing"
+
+	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/TBD54566975/ssi-sdk/did/jwk"
+	"github.com/TBD54566975/ssi-sdk/did/key"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBridge(t *testing.T) {
+	assert.Equal(t, []did.Method{did.JWKMethod, did.KeyMethod}, BridgedMethods())
+
+	for _, method := range []did.Method{did.KeyMethod, did.JWKMethod} {
+		t.Run(string(method), func(t *testing.T) {
+			var identifier string
+			if method == did.KeyMethod {
+				_, didKey, err := key.GenerateDIDKey(crypto.Ed25519)
+				require.NoError(t, err)
+				identifier = didKey.String()
+			} else {
+				_, didJWK, err := jwk.GenerateDIDJWK(crypto.Ed25519)
+				require.NoError(t, err)
+				identifier = didJWK.String()
+			}
+
+			doc, err := ImportDID(identifier, CreateDIDDHTOpts{})
+			require.NoError(t, err)
+			assert.True(t, DHT(doc.ID).IsValid())
+			assert.Equal(t, identifier, doc.AlsoKnownAs)
+			_, err = DHT(doc.ID).ToDNSPacket(*doc, nil)
+			require.NoError(t, err)
+
+			exported, err := ExportDID(DHT(doc.ID), method)
+			require.NoError(t, err)
+			assert.Equal(t, identifier, exported, "the identity key round trips")
+		})
+	}
+
+	t.Run("keys which can't be identity keys", func(t *testing.T) {
+		_, didKey, err := key.GenerateDIDKey(crypto.SECP256k1)
+		require.NoError(t, err)
+		_, err = ImportDID(didKey.String(), CreateDIDDHTOpts{})
+		assert.ErrorIs(t, err, ErrNotIdentityKey)
+
+		_, didJWK, err := jwk.GenerateDIDJWK(crypto.P256)
+		require.NoError(t, err)
+		_, err = ImportDID(didJWK.String(), CreateDIDDHTOpts{})
+		assert.ErrorIs(t, err, ErrNotIdentityKey)
+	})
+
+	t.Run("unsupported methods", func(t *testing.T) {
+		_, err := ImportDID("did:web:example.com", CreateDIDDHTOpts{})
+		assert.ErrorIs(t, err, ErrUnsupportedMethod)
+		_, err = ExportDID("did:dht:cyuoqaf7itop8ohww4yn5ojg13qaq83r9zihgqntc5i9zwrfdfoo", did.WebMethod)
+		assert.ErrorIs(t, err, ErrUnsupportedMethod)
+	})
+}
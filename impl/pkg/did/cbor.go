@@ -0,0 +1,48 @@
+package did
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/fxamacker/cbor/v2"
+)
+
+var (
+	// cborEncoding encodes CBOR deterministically, with map keys sorted, so a document is always encoded the same way
+	cborEncoding, _ = cbor.CoreDetEncOptions().EncMode()
+	// cborDecoding decodes CBOR maps with string keys, as JSON objects have
+	cborDecoding, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any(nil))}.DecMode()
+)
+
+// ToCBOR returns the document in the application/did+cbor representation, which is smaller and cheaper to parse than
+// JSON for constrained clients. The document is encoded from its JSON representation, so both have the same
+// properties, with the same names.
+func ToCBOR(doc did.Document) ([]byte, error) {
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err = json.Unmarshal(docJSON, &value); err != nil {
+		return nil, err
+	}
+	return cborEncoding.Marshal(value)
+}
+
+// FromCBOR decodes a document in the application/did+cbor representation
+func FromCBOR(data []byte) (*did.Document, error) {
+	var value any
+	if err := cborDecoding.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	docJSON, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var doc did.Document
+	if err = json.Unmarshal(docJSON, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
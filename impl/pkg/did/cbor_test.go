@@ -0,0 +1,40 @@
+package did
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCBOR(t *testing.T) {
+	_, doc, err := GenerateDIDDHT(CreateDIDDHTOpts{
+		Services: []did.Service{{
+			ID:              "hub",
+			Type:            "MessagingService",
+			ServiceEndpoint: "https://example.com/hub/",
+		}},
+	})
+	require.NoError(t, err)
+
+	encoded, err := ToCBOR(*doc)
+	require.NoError(t, err)
+	docJSON, err := json.Marshal(doc)
+	require.NoError(t, err)
+	assert.Less(t, len(encoded), len(docJSON))
+
+	again, err := ToCBOR(*doc)
+	require.NoError(t, err)
+	assert.Equal(t, encoded, again, "documents are encoded deterministically")
+
+	decoded, err := FromCBOR(encoded)
+	require.NoError(t, err)
+	decodedJSON, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(docJSON), string(decodedJSON))
+
+	_, err = FromCBOR([]byte("not cbor"))
+	assert.Error(t, err)
+}
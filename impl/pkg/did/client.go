@@ -14,6 +14,9 @@ import (
 	"github.com/pkg/errors"
 )
 
+// gatewayAPIPrefix is the path of the version of the Gateway API the client speaks
+const gatewayAPIPrefix = "/v1"
+
 // GatewayClient is the client for the Gateway API
 type GatewayClient struct {
 	gatewayURL string
@@ -41,7 +44,7 @@ func (c *GatewayClient) GetDIDDocument(id string) (*did.Document, []TypeIndex, e
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to get suffix")
 	}
-	resp, err := http.Get(c.gatewayURL + "/" + suffix)
+	resp, err := c.client.Get(c.gatewayURL + gatewayAPIPrefix + "/" + suffix)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to get did document")
 	}
@@ -60,8 +63,9 @@ func (c *GatewayClient) GetDIDDocument(id string) (*did.Document, []TypeIndex, e
 	return d.FromDNSPacket(msg)
 }
 
+// GetMessage gets the DNS packet of the record with the given z-base-32 encoded id from a did:dht Gateway
 func (c *GatewayClient) GetMessage(id string) (*dns.Msg, error) {
-	resp, err := http.Get(c.gatewayURL + "/" + id)
+	resp, err := c.client.Get(c.gatewayURL + gatewayAPIPrefix + "/" + id)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get did document")
 	}
@@ -96,7 +100,7 @@ func (c *GatewayClient) PutDocument(id string, put bep44.Put) error {
 	binary.BigEndian.PutUint64(seqBuf[:], uint64(put.Seq))
 	reqBytes := append(put.Sig[:], append(seqBuf[:], put.V.([]byte)...)...)
 
-	req, err := http.NewRequest(http.MethodPut, c.gatewayURL+"/"+suffix, bytes.NewReader(reqBytes))
+	req, err := http.NewRequest(http.MethodPut, c.gatewayURL+gatewayAPIPrefix+"/"+suffix, bytes.NewReader(reqBytes))
 	if err != nil {
 		return errors.Wrap(err, "could not construct http put request")
 	}
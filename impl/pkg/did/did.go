@@ -462,6 +462,14 @@ func parseServiceData(serviceEndpoint any) string {
 
 // FromDNSPacket converts a DNS packet to a DID DHT Document
 func (d DHT) FromDNSPacket(msg *dns.Msg) (*did.Document, []TypeIndex, error) {
+	return d.FromDNSPacketFields(msg)
+}
+
+// FromDNSPacketFields converts a DNS packet to a DID DHT Document with only the given fields, along with its id,
+// skipping the decoding of the records of the others, such as the keys of verification methods not requested.
+// The whole document is decoded if no fields are given.
+func (d DHT) FromDNSPacketFields(msg *dns.Msg, fields ...DocumentField) (*did.Document, []TypeIndex, error) {
+	want := newFieldSet(fields)
 	doc := did.Document{
 		ID: d.String(),
 	}
@@ -471,15 +479,21 @@ func (d DHT) FromDNSPacket(msg *dns.Msg) (*did.Document, []TypeIndex, error) {
 	for _, rr := range msg.Answer {
 		switch record := rr.(type) {
 		case *dns.TXT:
-			if strings.HasPrefix(record.Hdr.Name, "_cnt") {
+			if strings.HasPrefix(record.Hdr.Name, "_cnt") && want(FieldController) {
 				doc.Controller = strings.Split(record.Txt[0], ",")
 			}
-			if strings.HasPrefix(record.Hdr.Name, "_aka") {
+			if strings.HasPrefix(record.Hdr.Name, "_aka") && want(FieldAlsoKnownAs) {
 				doc.AlsoKnownAs = strings.Split(record.Txt[0], ",")
 			}
 			if strings.HasPrefix(record.Hdr.Name, "_k") {
 				data := parseTxtData(strings.Join(record.Txt, ","))
 				vmID := data["id"]
+
+				// add to key lookup (e.g.  "k1" -> "key1"), which verification relationships refer to keys by
+				keyLookup[strings.Split(record.Hdr.Name, ".")[0][1:]] = vmID
+				if !want(FieldVerificationMethod) {
+					continue
+				}
 				keyType := keyTypeLookUp(data["t"])
 				keyBase64URL := data["k"]
 
@@ -504,10 +518,7 @@ func (d DHT) FromDNSPacket(msg *dns.Msg) (*did.Document, []TypeIndex, error) {
 					PublicKeyJWK: pubKeyJWK,
 				}
 				doc.VerificationMethod = append(doc.VerificationMethod, vm)
-
-				// add to key lookup (e.g.  "k1" -> "key1")
-				keyLookup[strings.Split(record.Hdr.Name, ".")[0][1:]] = vmID
-			} else if strings.HasPrefix(record.Hdr.Name, "_s") {
+			} else if strings.HasPrefix(record.Hdr.Name, "_s") && want(FieldService) {
 				data := parseTxtData(strings.Join(record.Txt, ","))
 				sID := data["id"]
 				serviceType := data["t"]
@@ -566,22 +577,37 @@ func (d DHT) FromDNSPacket(msg *dns.Msg) (*did.Document, []TypeIndex, error) {
 
 					switch key {
 					case "auth":
+						if !want(FieldAuthentication) {
+							continue
+						}
 						for _, valueItem := range valueItems {
 							doc.Authentication = append(doc.Authentication, doc.ID+"#"+keyLookup[valueItem])
 						}
 					case "asm":
+						if !want(FieldAssertionMethod) {
+							continue
+						}
 						for _, valueItem := range valueItems {
 							doc.AssertionMethod = append(doc.AssertionMethod, doc.ID+"#"+keyLookup[valueItem])
 						}
 					case "agm":
+						if !want(FieldKeyAgreement) {
+							continue
+						}
 						for _, valueItem := range valueItems {
 							doc.KeyAgreement = append(doc.KeyAgreement, doc.ID+"#"+keyLookup[valueItem])
 						}
 					case "inv":
+						if !want(FieldCapabilityInvocation) {
+							continue
+						}
 						for _, valueItem := range valueItems {
 							doc.CapabilityInvocation = append(doc.CapabilityInvocation, doc.ID+"#"+keyLookup[valueItem])
 						}
 					case "del":
+						if !want(FieldCapabilityDelegation) {
+							continue
+						}
 						for _, valueItem := range valueItems {
 							doc.CapabilityDelegation = append(doc.CapabilityDelegation, doc.ID+"#"+keyLookup[valueItem])
 						}
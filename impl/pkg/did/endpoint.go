@@ -0,0 +1,75 @@
+package did
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/TBD54566975/ssi-sdk/did"
+)
+
+// ErrInvalidServiceEndpoint is returned for service endpoints which aren't absolute URIs of an allowed scheme
+var ErrInvalidServiceEndpoint = errors.New("invalid service endpoint")
+
+// authoritySchemes are the schemes whose URIs must have a host
+var authoritySchemes = []string{"http", "https", "ws", "wss"}
+
+// ServiceEndpoints returns the endpoints of the service, whose endpoint may be a single URI or a set of URIs
+func ServiceEndpoints(service did.Service) []string {
+	switch se := service.ServiceEndpoint.(type) {
+	case string:
+		return []string{se}
+	case []string:
+		return se
+	case []any:
+		endpoints := make([]string, 0, len(se))
+		for _, v := range se {
+			endpoints = append(endpoints, fmt.Sprintf("%v", v))
+		}
+		return endpoints
+	}
+	return nil
+}
+
+// ValidateServiceEndpoint returns ErrInvalidServiceEndpoint unless the endpoint is an absolute URI with one of the
+// given schemes, which are compared case-insensitively. URIs of schemes with an authority, such as https, must have
+// a host.
+func ValidateServiceEndpoint(endpoint string, schemes []string) error {
+	if endpoint == "" || strings.ContainsFunc(endpoint, unicode.IsSpace) {
+		return fmt.Errorf("%w: %q is not a uri", ErrInvalidServiceEndpoint, endpoint)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" {
+		return fmt.Errorf("%w: %q is not an absolute uri", ErrInvalidServiceEndpoint, endpoint)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if !slices.ContainsFunc(schemes, func(allowed string) bool { return strings.EqualFold(allowed, scheme) }) {
+		return fmt.Errorf("%w: scheme of %q is not allowed", ErrInvalidServiceEndpoint, endpoint)
+	}
+	if slices.Contains(authoritySchemes, scheme) && u.Host == "" {
+		return fmt.Errorf("%w: %q has no host", ErrInvalidServiceEndpoint, endpoint)
+	}
+	return nil
+}
+
+// ValidateServiceEndpoints validates the endpoints of each service of the document with ValidateServiceEndpoint,
+// returning the errors of all invalid endpoints
+func ValidateServiceEndpoints(doc did.Document, schemes []string) error {
+	var errs []error
+	for _, service := range doc.Services {
+		endpoints := ServiceEndpoints(service)
+		if len(endpoints) == 0 {
+			errs = append(errs, fmt.Errorf("service[%s]: %w: none", service.ID, ErrInvalidServiceEndpoint))
+			continue
+		}
+		for _, endpoint := range endpoints {
+			if err := ValidateServiceEndpoint(endpoint, schemes); err != nil {
+				errs = append(errs, fmt.Errorf("service[%s]: %w", service.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package did
+
+import (
+	"testing"
+
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateServiceEndpoint(t *testing.T) {
+	schemes := []string{"https", "urn"}
+	tests := []struct {
+		endpoint string
+		valid    bool
+	}{
+		{"https://example.com/dwn", true},
+		{"HTTPS://example.com", true},
+		{"urn:uuid:6e8bc430-9c3a-11d9-9669-0800200c9a66", true},
+		{"http://example.com", false},
+		{"https:///dwn", false},
+		{"example.com/dwn", false},
+		{"https://example.com/a b", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		t.Run(test.endpoint, func(t *testing.T) {
+			err := ValidateServiceEndpoint(test.endpoint, schemes)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidServiceEndpoint)
+			}
+		})
+	}
+}
+
+func TestValidateServiceEndpoints(t *testing.T) {
+	doc := did.Document{
+		Services: []did.Service{
+			{ID: "dwn", Type: "DecentralizedWebNode", ServiceEndpoint: []string{"https://example.com/dwn", "ftp://example.com"}},
+			{ID: "status", Type: "CredentialStatusService", ServiceEndpoint: "https://example.com/status"},
+			{ID: "empty", Type: "CredentialStatusService"},
+		},
+	}
+	err := ValidateServiceEndpoints(doc, []string{"https"})
+	require.ErrorIs(t, err, ErrInvalidServiceEndpoint)
+	assert.Contains(t, err.Error(), "service[dwn]")
+	assert.Contains(t, err.Error(), "service[empty]")
+	assert.NotContains(t, err.Error(), "service[status]")
+
+	doc.Services = doc.Services[1:2]
+	assert.NoError(t, ValidateServiceEndpoints(doc, []string{"https"}))
+}
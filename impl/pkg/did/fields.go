@@ -0,0 +1,73 @@
+package did
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DocumentField is a property of a DID Document, by its JSON name, which can be decoded from a DNS packet on its own
+type DocumentField string
+
+const (
+	FieldController           DocumentField = "controller"
+	FieldAlsoKnownAs          DocumentField = "alsoKnownAs"
+	FieldVerificationMethod   DocumentField = "verificationMethod"
+	FieldAuthentication       DocumentField = "authentication"
+	FieldAssertionMethod      DocumentField = "assertionMethod"
+	FieldKeyAgreement         DocumentField = "keyAgreement"
+	FieldCapabilityInvocation DocumentField = "capabilityInvocation"
+	FieldCapabilityDelegation DocumentField = "capabilityDelegation"
+	FieldService              DocumentField = "service"
+)
+
+// DocumentFields are the properties of a DID Document which can be selected, in the order of the document
+var DocumentFields = []DocumentField{
+	FieldController,
+	FieldAlsoKnownAs,
+	FieldVerificationMethod,
+	FieldAuthentication,
+	FieldAssertionMethod,
+	FieldKeyAgreement,
+	FieldCapabilityInvocation,
+	FieldCapabilityDelegation,
+	FieldService,
+}
+
+// ParseDocumentFields parses a comma separated list of DID Document properties, e.g. verificationMethod,service,
+// returning an error for properties which can't be selected
+func ParseDocumentFields(list string) ([]DocumentField, error) {
+	var fields []DocumentField
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		field := DocumentField(name)
+		if !field.IsValid() {
+			return nil, fmt.Errorf("unknown document field: %s", name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// IsValid returns whether the field is a property of a DID Document which can be selected
+func (f DocumentField) IsValid() bool {
+	return slices.Contains(DocumentFields, f)
+}
+
+// newFieldSet returns whether each field is among the given fields, or true for every field if none are given
+func newFieldSet(fields []DocumentField) func(DocumentField) bool {
+	if len(fields) == 0 {
+		return func(DocumentField) bool { return true }
+	}
+	set := make(map[DocumentField]struct{}, len(fields))
+	for _, field := range fields {
+		set[field] = struct{}{}
+	}
+	return func(field DocumentField) bool {
+		_, ok := set[field]
+		return ok
+	}
+}
@@ -0,0 +1,69 @@
+package did
+
+import (
+	"testing"
+
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDocumentFields(t *testing.T) {
+	fields, err := ParseDocumentFields("verificationMethod, service,")
+	require.NoError(t, err)
+	assert.Equal(t, []DocumentField{FieldVerificationMethod, FieldService}, fields)
+
+	fields, err = ParseDocumentFields("")
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+
+	_, err = ParseDocumentFields("verificationMethod,publicKey")
+	assert.ErrorContains(t, err, "publicKey")
+}
+
+func TestFromDNSPacketFields(t *testing.T) {
+	_, doc, err := GenerateDIDDHT(CreateDIDDHTOpts{
+		Controller:  []string{"did:example:controller"},
+		AlsoKnownAs: []string{"did:example:aka"},
+		Services: []did.Service{{
+			ID:              "hub",
+			Type:            "MessagingService",
+			ServiceEndpoint: "https://example.com/hub/",
+		}},
+	})
+	require.NoError(t, err)
+	didID := DHT(doc.ID)
+	packet, err := didID.ToDNSPacket(*doc, nil)
+	require.NoError(t, err)
+
+	t.Run("all fields", func(t *testing.T) {
+		decoded, _, err := didID.FromDNSPacketFields(packet)
+		require.NoError(t, err)
+		whole, _, err := didID.FromDNSPacket(packet)
+		require.NoError(t, err)
+		assert.Equal(t, whole, decoded)
+		assert.Len(t, decoded.VerificationMethod, 1)
+		assert.NotNil(t, decoded.AlsoKnownAs)
+	})
+
+	t.Run("services only", func(t *testing.T) {
+		decoded, _, err := didID.FromDNSPacketFields(packet, FieldService)
+		require.NoError(t, err)
+		assert.Equal(t, doc.ID, decoded.ID)
+		assert.Equal(t, doc.Services, decoded.Services)
+		assert.Empty(t, decoded.VerificationMethod)
+		assert.Empty(t, decoded.Authentication)
+		assert.Nil(t, decoded.Controller)
+		assert.Nil(t, decoded.AlsoKnownAs)
+	})
+
+	t.Run("relationships without their keys", func(t *testing.T) {
+		decoded, _, err := didID.FromDNSPacketFields(packet, FieldAuthentication, FieldAssertionMethod)
+		require.NoError(t, err)
+		assert.Empty(t, decoded.VerificationMethod)
+		assert.Equal(t, doc.Authentication, decoded.Authentication)
+		assert.Equal(t, doc.AssertionMethod, decoded.AssertionMethod)
+		assert.Empty(t, decoded.CapabilityInvocation)
+		assert.Empty(t, decoded.Services)
+	})
+}
@@ -0,0 +1,60 @@
+package did
+
+import (
+	"slices"
+
+	"github.com/TBD54566975/ssi-sdk/cryptosuite"
+	"github.com/TBD54566975/ssi-sdk/did"
+)
+
+const (
+	// DIDContext is the JSON-LD context of the DID Core vocabulary, the first context of every DID Document
+	// produced as JSON-LD
+	DIDContext = "https://www.w3.org/ns/did/v1"
+	// JWKContext is the JSON-LD context defining the JsonWebKey verification method type and publicKeyJwk
+	JWKContext = "https://w3id.org/security/jwk/v1"
+	// JWS2020Context is the JSON-LD context defining the JsonWebKey2020 verification method type
+	JWS2020Context = "https://w3id.org/security/suites/jws-2020/v1"
+)
+
+// verificationMethodContexts are the JSON-LD contexts defining the verification method types of documents
+var verificationMethodContexts = map[cryptosuite.LDKeyType]string{
+	JSONWebKeyType:                 JWKContext,
+	cryptosuite.JSONWebKey2020Type: JWS2020Context,
+}
+
+// ToJSONLD returns the document as JSON-LD, with the @context the DID Core specification requires of the
+// application/did+ld+json representation: the DID Core context first, then the contexts defining the types of its
+// verification methods, then any contexts the document already had. The document itself is left unchanged.
+func ToJSONLD(doc did.Document) did.Document {
+	contexts := []any{DIDContext}
+	add := func(context any) {
+		if !slices.Contains(contexts, context) {
+			contexts = append(contexts, context)
+		}
+	}
+	for _, vm := range doc.VerificationMethod {
+		if context, ok := verificationMethodContexts[vm.Type]; ok {
+			add(context)
+		}
+	}
+	switch existing := doc.Context.(type) {
+	case string:
+		add(existing)
+	case []string:
+		for _, context := range existing {
+			add(context)
+		}
+	case []any:
+		for _, context := range existing {
+			// embedded contexts are objects, which aren't comparable, so they are kept as they are
+			if _, ok := context.(string); !ok {
+				contexts = append(contexts, context)
+				continue
+			}
+			add(context)
+		}
+	}
+	doc.Context = contexts
+	return doc
+}
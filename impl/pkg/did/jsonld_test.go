@@ -0,0 +1,32 @@
+package did
+
+import (
+	"testing"
+
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToJSONLD(t *testing.T) {
+	_, doc, err := GenerateDIDDHT(CreateDIDDHTOpts{})
+	require.NoError(t, err)
+
+	ld := ToJSONLD(*doc)
+	assert.Equal(t, []any{DIDContext, JWKContext}, ld.Context)
+	assert.Nil(t, doc.Context, "the document is left unchanged")
+	assert.Equal(t, doc.VerificationMethod, ld.VerificationMethod)
+
+	t.Run("existing contexts", func(t *testing.T) {
+		embedded := map[string]any{"@vocab": "https://example.com/vocab#"}
+		withContext := did.Document{
+			ID:      doc.ID,
+			Context: []any{"https://example.com/context/v1", DIDContext, embedded},
+		}
+		ld := ToJSONLD(withContext)
+		assert.Equal(t, []any{DIDContext, "https://example.com/context/v1", embedded}, ld.Context)
+
+		withContext.Context = DIDContext
+		assert.Equal(t, []any{DIDContext}, ToJSONLD(withContext).Context)
+	})
+}
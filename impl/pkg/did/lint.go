@@ -0,0 +1,143 @@
+package did
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/miekg/dns"
+)
+
+const (
+	// LintOversizedServiceEndpoint warns of a service endpoint taking up a large part of the 1000 bytes of a record
+	LintOversizedServiceEndpoint = "oversized_service_endpoint"
+	// LintMissingKeyPurpose warns of a verification method no verification relationship references, which no
+	// verifier can use
+	LintMissingKeyPurpose = "missing_key_purpose"
+	// LintDuplicateKeyPurpose warns of a verification relationship referencing the same verification method twice
+	LintDuplicateKeyPurpose = "duplicate_key_purpose"
+	// LintIdentityKeyNotFirst warns of a document whose first verification method isn't the identity key, #0
+	LintIdentityKeyNotFirst = "identity_key_not_first"
+	// LintNonCanonicalEncoding warns of a DNS packet larger than the canonical encoding of its document, such as one
+	// whose names aren't compressed or whose records are out of order
+	LintNonCanonicalEncoding = "non_canonical_encoding"
+
+	// lintEndpointLength is the length beyond which a service endpoint is oversized
+	lintEndpointLength = 200
+	// lintWastedBytes is the number of bytes a DNS packet may exceed its canonical encoding by without a warning
+	lintWastedBytes = 16
+)
+
+// LintWarning is a finding of the lint pass over a DID Document, which, unlike a validation error, doesn't make the
+// document invalid, but points out how to make it smaller or more useful
+type LintWarning struct {
+	// Code is the machine-readable kind of the warning, e.g. missing_key_purpose
+	Code string `json:"code"`
+	// Path is the id of the verification method or service, or the relationship, the warning is about, if any
+	Path string `json:"path,omitempty"`
+	// Message is a human-readable description of the warning and how to address it
+	Message string `json:"message"`
+}
+
+// Lint returns the warnings of the document: oversized service endpoints, verification methods without a purpose,
+// relationships referencing a method twice, and an identity key which isn't the first verification method
+func Lint(doc did.Document) []LintWarning {
+	var warnings []LintWarning
+	if len(doc.VerificationMethod) > 0 && fragment(doc.VerificationMethod[0].ID) != "0" {
+		warnings = append(warnings, LintWarning{
+			Code:    LintIdentityKeyNotFirst,
+			Path:    doc.VerificationMethod[0].ID,
+			Message: "the identity key, #0, should be the first verification method, as it is encoded as k0",
+		})
+	}
+
+	relationships := []struct {
+		name string
+		set  []did.VerificationMethodSet
+	}{
+		{"authentication", doc.Authentication},
+		{"assertionMethod", doc.AssertionMethod},
+		{"keyAgreement", doc.KeyAgreement},
+		{"capabilityInvocation", doc.CapabilityInvocation},
+		{"capabilityDelegation", doc.CapabilityDelegation},
+	}
+	purposes := make(map[string]bool)
+	for _, relationship := range relationships {
+		seen := make(map[string]bool)
+		for _, ref := range relationship.set {
+			id, ok := ref.(string)
+			if !ok {
+				continue
+			}
+			if seen[fragment(id)] {
+				warnings = append(warnings, LintWarning{
+					Code:    LintDuplicateKeyPurpose,
+					Path:    relationship.name,
+					Message: fmt.Sprintf("%s references %s more than once, list it once", relationship.name, id),
+				})
+			}
+			seen[fragment(id)] = true
+			purposes[fragment(id)] = true
+		}
+	}
+	for _, vm := range doc.VerificationMethod {
+		if !purposes[fragment(vm.ID)] {
+			warnings = append(warnings, LintWarning{
+				Code:    LintMissingKeyPurpose,
+				Path:    vm.ID,
+				Message: "no verification relationship references the key, add it to one, such as authentication, or remove it",
+			})
+		}
+	}
+
+	for _, service := range doc.Services {
+		for _, endpoint := range ServiceEndpoints(service) {
+			if len(endpoint) > lintEndpointLength {
+				warnings = append(warnings, LintWarning{
+					Code: LintOversizedServiceEndpoint,
+					Path: service.ID,
+					Message: fmt.Sprintf("endpoint is %d bytes, more than %d, of the 1000 bytes of the record; shorten it",
+						len(endpoint), lintEndpointLength),
+				})
+			}
+		}
+	}
+	return warnings
+}
+
+// LintDNSPacket returns the warnings of the DID Document the packet encodes, as Lint does, and whether the packet is
+// larger than the canonical encoding of the document, with its names compressed
+func (d DHT) LintDNSPacket(packet []byte) ([]LintWarning, error) {
+	msg := new(dns.Msg)
+	if err := msg.Unpack(packet); err != nil {
+		return nil, err
+	}
+	doc, types, err := d.FromDNSPacket(msg)
+	if err != nil {
+		return nil, err
+	}
+	warnings := Lint(*doc)
+
+	canonical, err := d.ToDNSPacket(*doc, types)
+	if err != nil {
+		return nil, err
+	}
+	canonical.Compress = true
+	packed, err := canonical.Pack()
+	if err != nil {
+		return nil, err
+	}
+	if wasted := len(packet) - len(packed); wasted > lintWastedBytes {
+		warnings = append(warnings, LintWarning{
+			Code: LintNonCanonicalEncoding,
+			Message: fmt.Sprintf("packet is %d bytes, %d more than the canonical encoding of the document; order the "+
+				"records as the method specifies and compress their names", len(packet), wasted),
+		})
+	}
+	return warnings, nil
+}
+
+// fragment returns the fragment of a DID URL, or the id itself if it has none
+func fragment(id string) string {
+	return id[strings.LastIndex(id, "#")+1:]
+}
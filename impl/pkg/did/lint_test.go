@@ -0,0 +1,65 @@
+package did
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	var services []did.Service
+	for _, id := range []string{"dwn", "vcs", "hub", "status"} {
+		services = append(services, did.Service{ID: id, Type: "LinkedDomains", ServiceEndpoint: "https://example.com/" + id})
+	}
+	_, doc, err := GenerateDIDDHT(CreateDIDDHTOpts{Services: services})
+	require.NoError(t, err)
+	assert.Empty(t, Lint(*doc))
+
+	t.Run("warnings", func(t *testing.T) {
+		linted := *doc
+		unused := doc.VerificationMethod[0]
+		unused.ID = doc.ID + "#unused"
+		linted.VerificationMethod = append([]did.VerificationMethod{unused}, doc.VerificationMethod...)
+		linted.Authentication = append(linted.Authentication, doc.ID+"#0")
+		linted.Services = []did.Service{{
+			ID:              doc.ID + "#long",
+			Type:            "LinkedDomains",
+			ServiceEndpoint: "https://example.com/" + strings.Repeat("a", 200),
+		}}
+
+		var codes []string
+		for _, warning := range Lint(linted) {
+			codes = append(codes, warning.Code)
+		}
+		assert.ElementsMatch(t, []string{
+			LintIdentityKeyNotFirst, LintDuplicateKeyPurpose, LintMissingKeyPurpose, LintOversizedServiceEndpoint,
+		}, codes)
+	})
+
+	t.Run("packets", func(t *testing.T) {
+		d := DHT(doc.ID)
+		msg, err := d.ToDNSPacket(*doc, nil)
+		require.NoError(t, err)
+
+		msg.Compress = true
+		canonical, err := msg.Pack()
+		require.NoError(t, err)
+		warnings, err := d.LintDNSPacket(canonical)
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+
+		msg.Compress = false
+		uncompressed, err := msg.Pack()
+		require.NoError(t, err)
+		warnings, err = d.LintDNSPacket(uncompressed)
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, LintNonCanonicalEncoding, warnings[0].Code)
+
+		_, err = d.LintDNSPacket([]byte{1, 2, 3})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,275 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TBD54566975/ssi-sdk/util"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	intutil "github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/server"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+// shutdownTimeout bounds how long in-flight requests are given to complete once the gateway is stopped
+const shutdownTimeout = 5 * time.Second
+
+// Mux is a router the gateway's HTTP API can be mounted onto, such as an *http.ServeMux
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// Option configures a Gateway
+type Option func(*Gateway)
+
+// WithConfig configures the gateway with the given config instead of the default config
+func WithConfig(cfg *config.Config) Option {
+	return func(g *Gateway) {
+		g.cfg = cfg
+	}
+}
+
+// WithStorage stores records in the given storage instead of the one at the configured storage URI
+func WithStorage(db storage.Storage) Option {
+	return func(g *Gateway) {
+		g.db = db
+	}
+}
+
+// WithDHT gets and puts records using the given DHT, or a fake of it, instead of one bootstrapped from the configured peers
+func WithDHT(d dht.Client) Option {
+	return func(g *Gateway) {
+		g.dht = d
+	}
+}
+
+// WithBootstrapProvider bootstraps the DHT from the peers the given provider discovers instead of those of the config,
+// such as to discover peers in a restricted network. The DHT still joins the network of the config. It is ignored if
+// WithDHT is used.
+func WithBootstrapProvider(provider dht.BootstrapProvider) Option {
+	return func(g *Gateway) {
+		g.bootstrap = provider
+	}
+}
+
+// WithCache caches records in the given cache instead of the one at the configured cache URI
+func WithCache(c cache.Cache) Option {
+	return func(g *Gateway) {
+		g.cache = c
+	}
+}
+
+// WithMux mounts the gateway's HTTP API onto the given mux under the given path prefix, e.g. "/did-dht", instead
+// of serving it on its own listener
+func WithMux(mux Mux, prefix string) Option {
+	return func(g *Gateway) {
+		g.mux = mux
+		g.prefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// WithEvents subscribes to the events of the gateway's Pkarr service once it is created on Start, before the gateway
+// serves any request, such as to react to records being published and resolved
+func WithEvents(subscribe func(events *service.Events)) Option {
+	return func(g *Gateway) {
+		g.subscribe = subscribe
+	}
+}
+
+// Gateway is a did:dht gateway which can be embedded in another Go process
+type Gateway struct {
+	cfg    *config.Config
+	db     storage.Storage
+	dht    dht.Client
+	cache  cache.Cache
+	mux    Mux
+	prefix string
+	// bootstrap discovers the peers the DHT is bootstrapped from, if the gateway creates its DHT
+	bootstrap dht.BootstrapProvider
+	// subscribe subscribes to the events of the service, if set
+	subscribe func(events *service.Events)
+
+	// ownsDB is set if the gateway created its storage, and so closes it when stopped
+	ownsDB bool
+	// ownedDHT is the DHT the gateway created, if any, which it closes when stopped
+	ownedDHT *dht.DHT
+	svc      *service.PkarrService
+	server   *server.Server
+
+	// errs receives the first error of the listeners, after which the gateway stops
+	errs chan error
+	stop sync.Once
+	done chan struct{}
+	err  error
+}
+
+// New returns a new Gateway configured by the given options. Anything not set by an option is created from
+// the config on Start.
+func New(opts ...Option) *Gateway {
+	g := &Gateway{
+		errs: make(chan error, 3),
+		done: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.cfg == nil {
+		cfg := config.GetDefaultConfig()
+		g.cfg = &cfg
+	}
+	return g
+}
+
+// Start creates the gateway's Pkarr service and starts serving its API, either on the given mux or on the listeners
+// of the config. It returns once the gateway is serving, which it does until the context is done or a listener fails;
+// use Wait to block until then.
+func (g *Gateway) Start(ctx context.Context) error {
+	var err error
+	// the gateway logs with the standard logger, so its entries are scrubbed wherever it is embedded
+	if g.cfg.PrivacyConfig.Enabled {
+		intutil.RedactLogs()
+	}
+	if g.db == nil {
+		if g.db, err = storage.NewFromConfig(g.cfg); err != nil {
+			return util.LoggingErrorMsg(err, "could not instantiate storage")
+		}
+		g.ownsDB = true
+	}
+	if g.dht == nil {
+		if g.bootstrap != nil {
+			var network dht.Network
+			if network, err = dht.NetworkFromConfig(g.cfg.DHTConfig); err != nil {
+				return util.LoggingErrorMsg(err, "invalid dht network")
+			}
+			g.ownedDHT, err = dht.NewDHTWithNetwork(g.bootstrap, network)
+		} else {
+			g.ownedDHT, err = dht.NewDHTFromConfig(g.cfg.DHTConfig)
+		}
+		if err != nil {
+			return util.LoggingErrorMsg(err, "failed to instantiate dht")
+		}
+		g.dht = g.ownedDHT
+	}
+	if g.cache == nil {
+		if g.cache, err = service.NewRecordCache(g.cfg); err != nil {
+			return util.LoggingErrorMsg(err, "failed to instantiate cache")
+		}
+	}
+	if g.svc, err = service.NewPkarrServiceWith(g.cfg, g.db, g.dht, g.cache); err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate pkarr service")
+	}
+	if g.subscribe != nil {
+		g.subscribe(g.svc.Events())
+	}
+	if g.server, err = server.NewServerWithService(g.cfg, nil, g.svc); err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate server")
+	}
+
+	if g.mux != nil {
+		g.mux.Handle(g.prefix+"/", http.StripPrefix(g.prefix, g.server.Handler))
+		logrus.WithField("prefix", g.prefix+"/").Info("mounted gateway api")
+	} else {
+		go func() {
+			logrus.WithField("listen_address", g.server.Addr).Info("starting listener")
+			if err := g.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				g.errs <- err
+			}
+		}()
+	}
+	if g.server.DNSServer != nil {
+		go func() {
+			logrus.WithField("listen_address", g.server.DNSServer.Addr).Info("starting dns listener")
+			g.errs <- g.server.DNSServer.ListenAndServe()
+		}()
+	}
+	if g.server.AdminServer != nil {
+		go func() {
+			logrus.WithField("listen_address", g.server.AdminServer.Addr).Info("starting admin listener")
+			if err := server.ListenAndServe(g.server.AdminServer); !errors.Is(err, http.ErrServerClosed) {
+				g.errs <- err
+			}
+		}()
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			g.shutdown(nil)
+		case err := <-g.errs:
+			g.shutdown(err)
+		}
+	}()
+	return nil
+}
+
+// Wait blocks until the gateway has stopped, returning the error of the listener which failed, if any
+func (g *Gateway) Wait() error {
+	<-g.done
+	return g.err
+}
+
+// Service returns the gateway's Pkarr service, e.g. to register publish interceptors. It is nil until Start.
+func (g *Gateway) Service() *service.PkarrService {
+	return g.svc
+}
+
+// Handler returns the gateway's HTTP API, e.g. to mount it onto a router which isn't a Mux. It is nil until Start.
+func (g *Gateway) Handler() http.Handler {
+	if g.server == nil {
+		return nil
+	}
+	return g.server.Handler
+}
+
+// shutdown stops the listeners and the service's background work, and closes the storage the gateway created,
+// recording the cause of the shutdown
+func (g *Gateway) shutdown(cause error) {
+	g.stop.Do(func() {
+		defer close(g.done)
+		g.err = cause
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if g.server.DNSServer != nil {
+			if err := g.server.DNSServer.Shutdown(ctx); err != nil {
+				logrus.WithError(err).Error("failed to stop dns listener gracefully")
+			}
+		}
+		if g.server.AdminServer != nil {
+			if err := g.server.AdminServer.Shutdown(ctx); err != nil {
+				logrus.WithError(err).Error("failed to stop admin listener gracefully")
+			}
+		}
+		if g.mux == nil {
+			if err := g.server.Shutdown(ctx); err != nil {
+				logrus.WithError(err).Error("failed to stop listener gracefully")
+			}
+		}
+		// the service's scheduled jobs and background work stop before the storage and DHT they use are closed
+		if err := g.svc.Close(ctx); err != nil {
+			logrus.WithError(err).Error("failed to stop pkarr service gracefully")
+		}
+		if g.server.GeoIP != nil {
+			if err := g.server.GeoIP.Close(); err != nil {
+				logrus.WithError(err).Error("failed to close geoip database")
+			}
+		}
+		if g.ownsDB {
+			if err := g.db.Close(); err != nil {
+				logrus.WithError(err).Error("failed to close storage")
+			}
+		}
+		if g.ownedDHT != nil {
+			g.ownedDHT.Close()
+		}
+	})
+}
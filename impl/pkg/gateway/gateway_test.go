@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestGatewayMountedOnMux(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.ServerConfig.Environment = config.EnvironmentTest
+	db, err := storage.NewStorage("bolt://gateway.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	ctx, cancel := context.WithCancel(context.Background())
+	gw := New(WithConfig(&cfg), WithStorage(db), WithMux(mux, "/did-dht/"))
+	require.NoError(t, gw.Start(ctx))
+	require.NotNil(t, gw.Service())
+
+	req := httptest.NewRequest(http.MethodGet, "/did-dht/health", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	cancel()
+	assert.NoError(t, gw.Wait())
+}
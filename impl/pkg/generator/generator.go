@@ -0,0 +1,243 @@
+// Package generator generates valid, signed Pkarr records en masse, such as for load, chaos, and interop testing
+package generator
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/miekg/dns"
+
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	diddht "github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// MaxPacketSize is the largest DNS packet a Pkarr record can hold
+const MaxPacketSize = 1000
+
+// Shape is the kind of DNS packet generated
+type Shape string
+
+const (
+	// ShapeTXT packets only have TXT resource records
+	ShapeTXT Shape = "txt"
+	// ShapeDID packets represent a DID Document, with Services services
+	ShapeDID Shape = "did"
+)
+
+// padName is the name of the TXT resource record which pads packets to their size
+const padName = "_pad."
+
+// Options shape the generated records
+type Options struct {
+	// Seed derives the keys of the records from the seed and their index, so the same records are generated on every
+	// run. Keys are random if empty.
+	Seed string
+	// Shape is the kind of DNS packet, ShapeTXT by default
+	Shape Shape
+	// Size pads each smaller packet to this many bytes, up to MaxPacketSize. Packets fewer than 16 bytes smaller,
+	// too close to fit a padding resource record, are left as is.
+	Size int
+	// TXTRecords is the number of TXT resource records of ShapeTXT packets, besides padding
+	TXTRecords int
+	// Services is the number of services of the DID Documents of ShapeDID packets
+	Services int
+	// Seq is the seq of the records, 1 by default
+	Seq int64
+}
+
+// Generator generates signed records
+type Generator struct {
+	opts Options
+}
+
+// New returns a generator of records with the given options
+func New(opts Options) (*Generator, error) {
+	if opts.Shape == "" {
+		opts.Shape = ShapeTXT
+	}
+	if opts.Shape != ShapeTXT && opts.Shape != ShapeDID {
+		return nil, fmt.Errorf("unknown shape: %s", opts.Shape)
+	}
+	if opts.Size < 0 || opts.Size > MaxPacketSize {
+		return nil, fmt.Errorf("size must be between 0 and %d", MaxPacketSize)
+	}
+	if opts.TXTRecords < 0 || opts.Services < 0 {
+		return nil, fmt.Errorf("txt records and services must not be negative")
+	}
+	if opts.Seq == 0 {
+		opts.Seq = 1
+	}
+	return &Generator{opts: opts}, nil
+}
+
+// Record is a generated record, signed by its key
+type Record struct {
+	Key ed25519.PrivateKey
+	Put bep44.Put
+}
+
+// ID returns the z-base-32 encoded ID of the record
+func (r Record) ID() string {
+	return util.Z32Encode(r.Key.Public().(ed25519.PublicKey))
+}
+
+// Request returns the record as a request to the Pkarr service
+func (r Record) Request() service.PublishPkarrRequest {
+	return service.PublishPkarrRequest{
+		V:   r.Put.V.([]byte),
+		K:   *r.Put.K,
+		Sig: r.Put.Sig,
+		Seq: r.Put.Seq,
+	}
+}
+
+// Body returns the record as the body of a request to the relay API: 64 bytes sig, 8 bytes u64 big-endian seq,
+// and the v
+func (r Record) Body() []byte {
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], uint64(r.Put.Seq))
+	body := append(append([]byte{}, r.Put.Sig[:]...), seq[:]...)
+	return append(body, r.Put.V.([]byte)...)
+}
+
+// StorageRecord returns the record in the format records are stored in, as loaded from seed data
+func (r Record) StorageRecord() pkarr.Record {
+	encoding := base64.RawURLEncoding
+	return pkarr.Record{
+		V:   encoding.EncodeToString(r.Put.V.([]byte)),
+		K:   encoding.EncodeToString(r.Put.K[:]),
+		Sig: encoding.EncodeToString(r.Put.Sig[:]),
+		Seq: r.Put.Seq,
+	}
+}
+
+// Key returns the key of the i-th record
+func (g *Generator) Key(i int) (ed25519.PrivateKey, error) {
+	if g.opts.Seed == "" {
+		_, key, err := ed25519.GenerateKey(nil)
+		return key, err
+	}
+	seed := sha256.Sum256([]byte(g.opts.Seed + ":" + strconv.Itoa(i)))
+	return ed25519.NewKeyFromSeed(seed[:]), nil
+}
+
+// Record returns the i-th record
+func (g *Generator) Record(i int) (*Record, error) {
+	key, err := g.Key(i)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := g.Packet(key)
+	if err != nil {
+		return nil, err
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+	put := bep44.Put{
+		V:   packed,
+		K:   (*[32]byte)(key.Public().(ed25519.PublicKey)),
+		Seq: g.opts.Seq,
+	}
+	put.Sign(key)
+	return &Record{Key: key, Put: put}, nil
+}
+
+// Generate calls fn with each of n records in order, stopping at the first error
+func (g *Generator) Generate(n int, fn func(Record) error) error {
+	for i := 0; i < n; i++ {
+		record, err := g.Record(i)
+		if err != nil {
+			return err
+		}
+		if err = fn(*record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Packet returns the DNS packet of the record of the given key, in the shape of the options and padded to their size
+func (g *Generator) Packet(key ed25519.PrivateKey) (*dns.Msg, error) {
+	var msg *dns.Msg
+	switch g.opts.Shape {
+	case ShapeDID:
+		var err error
+		if msg, err = g.didPacket(key); err != nil {
+			return nil, err
+		}
+	default:
+		msg = &dns.Msg{MsgHdr: dns.MsgHdr{Response: true, Authoritative: true}}
+		for i := 0; i < g.opts.TXTRecords; i++ {
+			msg.Answer = append(msg.Answer, txt(fmt.Sprintf("_r%d.", i), fmt.Sprintf("value %d", i)))
+		}
+	}
+	if err := pad(msg, g.opts.Size); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// didPacket returns the packet of a DID Document of the key with the configured number of services
+func (g *Generator) didPacket(key ed25519.PrivateKey) (*dns.Msg, error) {
+	opts := diddht.CreateDIDDHTOpts{}
+	for i := 0; i < g.opts.Services; i++ {
+		opts.Services = append(opts.Services, did.Service{
+			ID:              fmt.Sprintf("s%d", i),
+			Type:            "SyntheticService",
+			ServiceEndpoint: fmt.Sprintf("https://example.com/%d", i),
+		})
+	}
+	doc, err := diddht.CreateDIDDHTDID(key.Public().(ed25519.PublicKey), opts)
+	if err != nil {
+		return nil, err
+	}
+	return diddht.DHT(doc.ID).ToDNSPacket(*doc, nil)
+}
+
+// pad adds a TXT resource record to the packet so it packs to exactly size bytes, if it is smaller than that
+func pad(msg *dns.Msg, size int) error {
+	packed, err := msg.Pack()
+	if err != nil {
+		return err
+	}
+	if len(packed) > MaxPacketSize {
+		return fmt.Errorf("packet of %d bytes is larger than %d bytes", len(packed), MaxPacketSize)
+	}
+	// the uncompressed name, then 2 bytes type, 2 bytes class, 4 bytes ttl, and 2 bytes rdata length
+	remaining := size - len(packed) - (len(padName) + 1) - 10
+	if remaining < 0 {
+		return nil
+	}
+	var values []string
+	for remaining > 0 {
+		// each character-string is a length byte and up to 255 bytes
+		n := min(255, remaining-1)
+		values = append(values, strings.Repeat("x", n))
+		remaining -= n + 1
+	}
+	msg.Answer = append(msg.Answer, txt(padName, values...))
+	return nil
+}
+
+func txt(name string, values ...string) *dns.TXT {
+	return &dns.TXT{
+		Hdr: dns.RR_Header{
+			Name:   name,
+			Rrtype: dns.TypeTXT,
+			Class:  dns.ClassINET,
+			Ttl:    7200,
+		},
+		Txt: values,
+	}
+}
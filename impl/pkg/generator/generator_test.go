@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	diddht "github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+func TestGenerator(t *testing.T) {
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := New(Options{Shape: "svg"})
+		assert.Error(t, err)
+		_, err = New(Options{Size: MaxPacketSize + 1})
+		assert.Error(t, err)
+		_, err = New(Options{TXTRecords: -1})
+		assert.Error(t, err)
+	})
+
+	t.Run("records are signed and padded to size", func(t *testing.T) {
+		for _, size := range []int{0, 100, 500, MaxPacketSize} {
+			g, err := New(Options{Size: size, TXTRecords: 2})
+			require.NoError(t, err)
+			record, err := g.Record(0)
+			require.NoError(t, err)
+
+			v := record.Put.V.([]byte)
+			if size > 0 {
+				assert.Len(t, v, size)
+			}
+			bv, err := bencode.Marshal(v)
+			require.NoError(t, err)
+			assert.True(t, bep44.Verify(record.Put.K[:], nil, 1, bv, record.Put.Sig[:]))
+
+			var msg dns.Msg
+			require.NoError(t, msg.Unpack(v))
+			assert.GreaterOrEqual(t, len(msg.Answer), 2)
+		}
+	})
+
+	t.Run("seeded records are deterministic", func(t *testing.T) {
+		g, err := New(Options{Seed: "load", Seq: 7})
+		require.NoError(t, err)
+		var ids []string
+		var records []Record
+		require.NoError(t, g.Generate(3, func(record Record) error {
+			ids = append(ids, record.ID())
+			records = append(records, record)
+			assert.Equal(t, int64(7), record.Request().Seq)
+			assert.Len(t, record.Body(), 72+len(record.Put.V.([]byte)))
+			return nil
+		}))
+		assert.Len(t, ids, 3)
+		assert.NotEqual(t, ids[0], ids[1])
+
+		again, err := g.Record(1)
+		require.NoError(t, err)
+		assert.Equal(t, ids[1], again.ID())
+		assert.Equal(t, records[1].StorageRecord(), again.StorageRecord())
+	})
+
+	t.Run("did documents", func(t *testing.T) {
+		g, err := New(Options{Shape: ShapeDID, Services: 3, Size: 600})
+		require.NoError(t, err)
+		record, err := g.Record(0)
+		require.NoError(t, err)
+		assert.Len(t, record.Put.V.([]byte), 600)
+
+		var msg dns.Msg
+		require.NoError(t, msg.Unpack(record.Put.V.([]byte)))
+		doc, _, err := diddht.DHT("did:dht:" + record.ID()).FromDNSPacket(&msg)
+		require.NoError(t, err)
+		assert.Len(t, doc.Services, 3)
+	})
+
+	t.Run("packets larger than a record", func(t *testing.T) {
+		g, err := New(Options{TXTRecords: 100})
+		require.NoError(t, err)
+		_, err = g.Record(0)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,235 @@
+// Package httpsig signs and verifies requests with HTTP Message Signatures (https://www.rfc-editor.org/rfc/rfc9421)
+// made by ed25519 keys, so the owner of a DID can authenticate requests with the key its identifier is made of.
+package httpsig
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureInputHeader = "Signature-Input"
+	SignatureHeader      = "Signature"
+	ContentDigestHeader  = "Content-Digest"
+
+	// Algorithm is the only algorithm signatures are made with
+	Algorithm = "ed25519"
+	// Label is the label of signatures made by Sign
+	Label = "sig1"
+	// MaxAge bounds how long after, or before, its creation a signature is accepted
+	MaxAge = 5 * time.Minute
+)
+
+var (
+	// ErrMissingSignature is returned by Verify for requests with no signature by the key
+	ErrMissingSignature = errors.New("request is not signed by the key")
+	// ErrInvalidSignature is returned by Verify for requests whose signature doesn't verify
+	ErrInvalidSignature = errors.New("invalid request signature")
+
+	// requiredComponents are the components every signature must cover, binding it to one request to one gateway
+	requiredComponents = []string{"@method", "@authority", "@path"}
+)
+
+// Sign signs the request with the key, identified by keyID, covering its method, authority, path, and query, and
+// for requests with a body, its Content-Digest, which is set. The request's body is read and replaced.
+func Sign(req *http.Request, keyID string, key ed25519.PrivateKey, created time.Time) error {
+	components := append([]string{}, requiredComponents...)
+	if req.URL.RawQuery != "" {
+		components = append(components, "@query")
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := readBody(req)
+		if err != nil {
+			return err
+		}
+		if len(body) > 0 {
+			req.Header.Set(ContentDigestHeader, contentDigest(body))
+			components = append(components, strings.ToLower(ContentDigestHeader))
+		}
+	}
+	params := []param{
+		{key: "created", value: created.Unix()},
+		{key: "keyid", value: keyID},
+		{key: "alg", value: Algorithm},
+	}
+	signatureParams := serializeInnerList(components, params)
+	base, err := signatureBase(req, components, signatureParams)
+	if err != nil {
+		return err
+	}
+	signature := ed25519.Sign(key, []byte(base))
+	req.Header.Set(SignatureInputHeader, Label+"="+signatureParams)
+	req.Header.Set(SignatureHeader, Label+"=:"+base64.StdEncoding.EncodeToString(signature)+":")
+	return nil
+}
+
+// Verify verifies the request carries a signature by the key identified by keyID, created within MaxAge of now,
+// which covers at least its method, authority, and path, its query if it has one, and for requests with a body, a
+// matching Content-Digest.
+// The request's body is read and replaced.
+func Verify(req *http.Request, keyID string, key ed25519.PublicKey, now time.Time) error {
+	inputs, err := parseDictionary(strings.Join(req.Header.Values(SignatureInputHeader), ", "))
+	if err != nil {
+		return fmt.Errorf("%w: malformed %s: %s", ErrInvalidSignature, SignatureInputHeader, err)
+	}
+	var input *member
+	for i := range inputs {
+		if inputs[i].list != nil && paramValue(inputs[i].params, "keyid") == keyID {
+			input = &inputs[i]
+			break
+		}
+	}
+	if input == nil {
+		return ErrMissingSignature
+	}
+	signatures, err := parseDictionary(strings.Join(req.Header.Values(SignatureHeader), ", "))
+	if err != nil {
+		return fmt.Errorf("%w: malformed %s: %s", ErrInvalidSignature, SignatureHeader, err)
+	}
+	var signature []byte
+	for _, s := range signatures {
+		if s.key == input.key {
+			signature, _ = s.value.([]byte)
+		}
+	}
+	if signature == nil {
+		return fmt.Errorf("%w: no signature labeled %s", ErrInvalidSignature, input.key)
+	}
+
+	if alg, ok := paramValue(input.params, "alg").(string); ok && alg != Algorithm {
+		return fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidSignature, alg)
+	}
+	created, ok := paramValue(input.params, "created").(int64)
+	if !ok {
+		return fmt.Errorf("%w: no created timestamp", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(created, 0)); age > MaxAge || age < -MaxAge {
+		return fmt.Errorf("%w: created outside of %s of now", ErrInvalidSignature, MaxAge)
+	}
+	if expires, ok := paramValue(input.params, "expires").(int64); ok && !now.Before(time.Unix(expires, 0)) {
+		return fmt.Errorf("%w: expired", ErrInvalidSignature)
+	}
+	required := append([]string{}, requiredComponents...)
+	if req.URL.RawQuery != "" {
+		required = append(required, "@query")
+	}
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	if len(body) > 0 {
+		if req.Header.Get(ContentDigestHeader) != contentDigest(body) {
+			return fmt.Errorf("%w: %s doesn't match the body", ErrInvalidSignature, ContentDigestHeader)
+		}
+		required = append(required, strings.ToLower(ContentDigestHeader))
+	}
+	for _, component := range required {
+		if !contains(input.list, component) {
+			return fmt.Errorf("%w: %s isn't covered", ErrInvalidSignature, component)
+		}
+	}
+
+	base, err := signatureBase(req, input.list, serializeInnerList(input.list, input.params))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, err)
+	}
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, []byte(base), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signatureBase returns the signature base of the request's components, ending with the signature parameters
+func signatureBase(req *http.Request, components []string, signatureParams string) (string, error) {
+	var b strings.Builder
+	seen := make(map[string]struct{}, len(components))
+	for _, component := range components {
+		if _, ok := seen[component]; ok {
+			return "", fmt.Errorf("%s is covered more than once", component)
+		}
+		seen[component] = struct{}{}
+		value, err := componentValue(req, component)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(serializeString(component) + ": " + value + "\n")
+	}
+	b.WriteString(`"@signature-params": ` + signatureParams)
+	return b.String(), nil
+}
+
+// componentValue returns the value of the derived component or header field of the request
+func componentValue(req *http.Request, component string) (string, error) {
+	switch component {
+	case "@method":
+		return req.Method, nil
+	case "@authority":
+		return strings.ToLower(req.Host), nil
+	case "@path":
+		if path := req.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + req.URL.RawQuery, nil
+	}
+	if strings.HasPrefix(component, "@") {
+		return "", fmt.Errorf("unsupported component %s", component)
+	}
+	values := req.Header.Values(component)
+	if component == "host" && len(values) == 0 && req.Host != "" {
+		values = []string{req.Host}
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("covered header %s is missing", component)
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(values, ", "), nil
+}
+
+// readBody reads the request's body, replacing it so it can be read again
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// contentDigest returns the Content-Digest (https://www.rfc-editor.org/rfc/rfc9530) of the body
+func contentDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+func paramValue(params []param, key string) any {
+	for _, p := range params {
+		if p.key == key {
+			return p.value
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
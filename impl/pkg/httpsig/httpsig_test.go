@@ -0,0 +1,94 @@
+package httpsig
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	now := time.Now()
+
+	t.Run("signed request verifies", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "https://gateway.example/v1/abc?x=1", bytes.NewReader([]byte("hello")))
+		require.NoError(t, Sign(req, "abc", privKey, now))
+		assert.Contains(t, req.Header.Get(SignatureInputHeader), `sig1=("@method" "@authority" "@path" "@query" "content-digest");created=`)
+		require.NoError(t, Verify(req, "abc", pubKey, now.Add(time.Minute)))
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(body), "the body can still be read")
+	})
+
+	t.Run("unsigned request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "https://gateway.example/v1/abc", nil)
+		assert.ErrorIs(t, Verify(req, "abc", pubKey, now), ErrMissingSignature)
+		require.NoError(t, Sign(req, "other", privKey, now))
+		assert.ErrorIs(t, Verify(req, "abc", pubKey, now), ErrMissingSignature)
+	})
+
+	tests := []struct {
+		name   string
+		modify func(req *http.Request)
+		now    time.Time
+		key    ed25519.PublicKey
+	}{
+		{name: "another method", modify: func(req *http.Request) { req.Method = http.MethodPut }},
+		{name: "another path", modify: func(req *http.Request) { req.URL.Path = "/v1/abd" }},
+		{name: "query added", modify: func(req *http.Request) { req.URL.RawQuery = "salt=c2FsdA" }},
+		{name: "another gateway", modify: func(req *http.Request) { req.Host = "other.example" }},
+		{name: "another body", modify: func(req *http.Request) { req.Body = io.NopCloser(bytes.NewReader([]byte("bye"))) }},
+		{name: "too old", now: now.Add(MaxAge + time.Second)},
+		{name: "from the future", now: now.Add(-MaxAge - time.Second)},
+		{name: "another key", key: func() ed25519.PublicKey { k, _, _ := ed25519.GenerateKey(nil); return k }()},
+		{name: "uncovered path", modify: func(req *http.Request) {
+			req.Header.Set(SignatureInputHeader, `sig1=("@method" "@authority");created=1;keyid="abc";alg="ed25519"`)
+		}},
+		{name: "another algorithm", modify: func(req *http.Request) {
+			req.Header.Set(SignatureInputHeader, `sig1=("@method" "@authority" "@path");keyid="abc";alg="rsa-pss-sha512"`)
+		}},
+		{name: "malformed", modify: func(req *http.Request) { req.Header.Set(SignatureHeader, "sig1=:not base64") }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "https://gateway.example/v1/abc", bytes.NewReader([]byte("hello")))
+			require.NoError(t, Sign(req, "abc", privKey, now))
+			if test.modify != nil {
+				test.modify(req)
+			}
+			verifyAt, key := now, pubKey
+			if !test.now.IsZero() {
+				verifyAt = test.now
+			}
+			if test.key != nil {
+				key = test.key
+			}
+			assert.ErrorIs(t, Verify(req, "abc", key, verifyAt), ErrInvalidSignature)
+		})
+	}
+}
+
+func TestParseDictionary(t *testing.T) {
+	members, err := parseDictionary(`sig1=("@method" "@path");created=1618884473;keyid="test-key-ed25519", sig2=:AQID:;a=?0;b=tok`)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, "sig1", members[0].key)
+	assert.Equal(t, []string{"@method", "@path"}, members[0].list)
+	assert.Equal(t, `("@method" "@path");created=1618884473;keyid="test-key-ed25519"`,
+		serializeInnerList(members[0].list, members[0].params))
+	assert.Equal(t, []byte{1, 2, 3}, members[1].value)
+	assert.Equal(t, []param{{key: "a", value: false}, {key: "b", value: token("tok")}}, members[1].params)
+
+	for _, malformed := range []string{`sig1=("@method"`, `sig1=("a";x=1)`, `sig1="unterminated`, `Sig1=?1`, `sig1=?1,`} {
+		_, err = parseDictionary(malformed)
+		assert.Error(t, err, malformed)
+	}
+}
@@ -0,0 +1,255 @@
+package httpsig
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The subset of Structured Field Values (https://www.rfc-editor.org/rfc/rfc8941) the Signature-Input and Signature
+// fields are made of: dictionaries whose members are inner lists of strings, or byte sequences, with parameters.
+
+// param is a parameter of an item or inner list, whose value is an int64, string, token, []byte, or bool
+type param struct {
+	key   string
+	value any
+}
+
+// token is a bare token, distinguished from a string when serialized
+type token string
+
+// member is a member of a dictionary, either an inner list or an item
+type member struct {
+	key string
+	// list is set for inner lists, and value for items
+	list   []string
+	value  any
+	params []param
+}
+
+// parseDictionary parses a dictionary, e.g. sig1=("@method" "@path");created=1;keyid="abc"
+func parseDictionary(s string) ([]member, error) {
+	p := parser{s: s}
+	var members []member
+	p.skipSP()
+	for !p.done() {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		m := member{key: key, value: true}
+		if p.peek() == '=' {
+			p.i++
+			if p.peek() == '(' {
+				m.list, err = p.innerList()
+			} else {
+				m.value, err = p.bareItem()
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if m.params, err = p.params(); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+		p.skipOWS()
+		if p.done() {
+			break
+		}
+		if p.peek() != ',' {
+			return nil, fmt.Errorf("expected ',' at %d", p.i)
+		}
+		p.i++
+		p.skipOWS()
+		if p.done() {
+			return nil, errors.New("trailing ','")
+		}
+	}
+	return members, nil
+}
+
+type parser struct {
+	s string
+	i int
+}
+
+func (p *parser) done() bool {
+	return p.i >= len(p.s)
+}
+
+func (p *parser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+func (p *parser) skipSP() {
+	for p.peek() == ' ' {
+		p.i++
+	}
+}
+
+func (p *parser) skipOWS() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.i++
+	}
+}
+
+func (p *parser) key() (string, error) {
+	start := p.i
+	if c := p.peek(); !(c >= 'a' && c <= 'z') && c != '*' {
+		return "", fmt.Errorf("expected key at %d", p.i)
+	}
+	for c := p.peek(); (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || (c != 0 && strings.IndexByte("_-.*", c) >= 0); c = p.peek() {
+		p.i++
+	}
+	return p.s[start:p.i], nil
+}
+
+// innerList parses an inner list of strings, e.g. ("@method" "content-digest")
+func (p *parser) innerList() ([]string, error) {
+	p.i++
+	list := []string{}
+	for {
+		p.skipSP()
+		if p.peek() == ')' {
+			p.i++
+			return list, nil
+		}
+		value, err := p.bareItem()
+		if err != nil {
+			return nil, err
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string in inner list at %d", p.i)
+		}
+		// parameters of components, such as ;sf or ;key, aren't supported
+		if p.peek() == ';' {
+			return nil, fmt.Errorf("unsupported parameters of component %q", s)
+		}
+		list = append(list, s)
+		if c := p.peek(); c != ' ' && c != ')' {
+			return nil, fmt.Errorf("expected ' ' or ')' at %d", p.i)
+		}
+	}
+}
+
+func (p *parser) params() ([]param, error) {
+	var params []param
+	for p.peek() == ';' {
+		p.i++
+		p.skipSP()
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		var value any = true
+		if p.peek() == '=' {
+			p.i++
+			if value, err = p.bareItem(); err != nil {
+				return nil, err
+			}
+		}
+		params = append(params, param{key: key, value: value})
+	}
+	return params, nil
+}
+
+func (p *parser) bareItem() (any, error) {
+	c := p.peek()
+	switch {
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.i
+		p.i++
+		for c := p.peek(); c >= '0' && c <= '9'; c = p.peek() {
+			p.i++
+		}
+		return strconv.ParseInt(p.s[start:p.i], 10, 64)
+	case c == '"':
+		var b strings.Builder
+		for p.i++; !p.done(); p.i++ {
+			switch c := p.s[p.i]; c {
+			case '\\':
+				p.i++
+				if c := p.peek(); c != '"' && c != '\\' {
+					return nil, fmt.Errorf("invalid escape at %d", p.i)
+				}
+				b.WriteByte(p.s[p.i])
+			case '"':
+				p.i++
+				return b.String(), nil
+			default:
+				if c < 0x20 || c > 0x7e {
+					return nil, fmt.Errorf("invalid string character at %d", p.i)
+				}
+				b.WriteByte(c)
+			}
+		}
+		return nil, errors.New("unterminated string")
+	case c == ':':
+		end := strings.IndexByte(p.s[p.i+1:], ':')
+		if end < 0 {
+			return nil, errors.New("unterminated byte sequence")
+		}
+		value, err := base64.StdEncoding.DecodeString(p.s[p.i+1 : p.i+1+end])
+		if err != nil {
+			return nil, err
+		}
+		p.i += end + 2
+		return value, nil
+	case c == '?':
+		if p.i+1 >= len(p.s) || (p.s[p.i+1] != '0' && p.s[p.i+1] != '1') {
+			return nil, fmt.Errorf("invalid boolean at %d", p.i)
+		}
+		p.i += 2
+		return p.s[p.i-1] == '1', nil
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '*':
+		start := p.i
+		for c := p.peek(); c > ' ' && c < 0x7f && strings.IndexByte(`"(),;<=>?@[\]{}`, c) < 0; c = p.peek() {
+			p.i++
+		}
+		return token(p.s[start:p.i]), nil
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", c, p.i)
+}
+
+// serializeInnerList serializes an inner list of strings with parameters, as covered by a signature
+func serializeInnerList(list []string, params []param) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, s := range list {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(serializeString(s))
+	}
+	b.WriteByte(')')
+	for _, p := range params {
+		b.WriteByte(';')
+		b.WriteString(p.key)
+		switch v := p.value.(type) {
+		case bool:
+			if !v {
+				b.WriteString("=?0")
+			}
+		case int64:
+			b.WriteString("=" + strconv.FormatInt(v, 10))
+		case string:
+			b.WriteString("=" + serializeString(v))
+		case token:
+			b.WriteString("=" + string(v))
+		case []byte:
+			b.WriteString("=:" + base64.StdEncoding.EncodeToString(v) + ":")
+		}
+	}
+	return b.String()
+}
+
+func serializeString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,97 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// Client is a minimal client for the HTTP RPC API of an IPFS node, such as Kubo
+// https://docs.ipfs.tech/reference/kubo/rpc/
+type Client struct {
+	apiURL string
+	client *http.Client
+}
+
+// NewClient returns a new instance of Client for the node's API at the given URL, e.g. http://localhost:5001
+func NewClient(apiURL string) *Client {
+	return &Client{
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		client: http.DefaultClient,
+	}
+}
+
+type addResponse struct {
+	Hash string `json:"Hash"`
+}
+
+// Add adds and pins the given data to the node, returning its CID
+func (c *Client) Add(ctx context.Context, data []byte) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "file")
+	if err != nil {
+		return "", err
+	}
+	if _, err = part.Write(data); err != nil {
+		return "", err
+	}
+	if err = writer.Close(); err != nil {
+		return "", err
+	}
+
+	params := url.Values{"pin": {"true"}, "cid-version": {"1"}}
+	var resp addResponse
+	if err = c.call(ctx, "add", params, writer.FormDataContentType(), &body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Hash, nil
+}
+
+type publishResponse struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// Publish publishes the given CID to IPNS under the node's key with the given name, returning the IPNS name
+func (c *Client) Publish(ctx context.Context, cid, key string) (string, error) {
+	params := url.Values{"arg": {"/ipfs/" + cid}, "key": {key}}
+	var resp publishResponse
+	if err := c.call(ctx, "name/publish", params, "", nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.Name, nil
+}
+
+// call makes a request to the given RPC API command, all of which are POST requests, and decodes the JSON response
+func (c *Client) call(ctx context.Context, command string, params url.Values, contentType string, body io.Reader, resp any) error {
+	endpoint := fmt.Sprintf("%s/api/v0/%s?%s", c.apiURL, command, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("ipfs %s failed with status %d: %s", command, res.StatusCode, string(resBytes))
+	}
+	return json.Unmarshal(resBytes, resp)
+}
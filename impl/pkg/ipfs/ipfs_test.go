@@ -0,0 +1,48 @@
+package ipfs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		switch r.URL.Path {
+		case "/api/v0/add":
+			assert.Equal(t, "true", r.URL.Query().Get("pin"))
+			file, _, err := r.FormFile("file")
+			require.NoError(t, err)
+			data, err := io.ReadAll(file)
+			require.NoError(t, err)
+			assert.Equal(t, "hello", string(data))
+			_, _ = w.Write([]byte(`{"Name":"file","Hash":"bafyhello","Size":"5"}`))
+		case "/api/v0/name/publish":
+			assert.Equal(t, "/ipfs/bafyhello", r.URL.Query().Get("arg"))
+			assert.Equal(t, "self", r.URL.Query().Get("key"))
+			_, _ = w.Write([]byte(`{"Name":"k51name","Value":"/ipfs/bafyhello"}`))
+		default:
+			http.Error(w, `{"Message":"unknown command"}`, http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL + "/")
+
+	cid, err := client.Add(context.Background(), []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "bafyhello", cid)
+
+	name, err := client.Publish(context.Background(), cid, "self")
+	require.NoError(t, err)
+	assert.Equal(t, "k51name", name)
+
+	err = client.call(context.Background(), "unknown", nil, "", nil, nil)
+	assert.ErrorContains(t, err, "status 500")
+}
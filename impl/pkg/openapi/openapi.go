@@ -0,0 +1,229 @@
+package openapi
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Version is the version of the OpenAPI specification documents are converted to
+	Version = "3.0.3"
+
+	// ProblemSchema is the schema of error responses, which are served as application/problem+json
+	ProblemSchema = "pkg_server.Problem"
+
+	defaultMediaType = "application/json"
+	problemMediaType = "application/problem+json"
+)
+
+// FromSwagger converts a Swagger 2.0 specification, such as the one generated from the handler annotations by swag,
+// to an OpenAPI 3 document according to https://spec.openapis.org/oas/v3.0.3
+func FromSwagger(swaggerYAML []byte) (map[string]any, error) {
+	var v2 map[string]any
+	if err := yaml.Unmarshal(swaggerYAML, &v2); err != nil {
+		return nil, err
+	}
+	if v2["swagger"] != "2.0" {
+		return nil, fmt.Errorf("unsupported swagger version: %v", v2["swagger"])
+	}
+	v2 = rewriteRefs(v2).(map[string]any)
+
+	v3 := map[string]any{
+		"openapi": Version,
+		"info":    v2["info"],
+		"paths":   map[string]any{},
+	}
+	if tags, ok := v2["tags"]; ok {
+		v3["tags"] = tags
+	}
+	if security, ok := v2["security"]; ok {
+		v3["security"] = security
+	}
+	if host, ok := v2["host"].(string); ok {
+		v3["servers"] = []any{map[string]any{"url": "https://" + host + stringValue(v2["basePath"])}}
+	}
+
+	components := map[string]any{}
+	if definitions, ok := v2["definitions"].(map[string]any); ok {
+		components["schemas"] = definitions
+	}
+	if securityDefinitions, ok := v2["securityDefinitions"].(map[string]any); ok {
+		components["securitySchemes"] = securityDefinitions
+	}
+	v3["components"] = components
+
+	consumes := stringsValue(v2["consumes"])
+	produces := stringsValue(v2["produces"])
+	paths, _ := v2["paths"].(map[string]any)
+	for path, item := range paths {
+		operations, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		v3Operations := map[string]any{}
+		for method, op := range operations {
+			operation, ok := op.(map[string]any)
+			if !ok {
+				continue
+			}
+			v3Operations[method] = convertOperation(operation, consumes, produces)
+		}
+		v3["paths"].(map[string]any)[path] = v3Operations
+	}
+	return v3, nil
+}
+
+// convertOperation converts a Swagger 2.0 operation, moving the body parameter to the request body and response
+// schemas to their content, keyed by media type
+func convertOperation(operation map[string]any, consumes, produces []string) map[string]any {
+	if opConsumes := stringsValue(operation["consumes"]); len(opConsumes) > 0 {
+		consumes = opConsumes
+	}
+	if opProduces := stringsValue(operation["produces"]); len(opProduces) > 0 {
+		produces = opProduces
+	}
+	if len(consumes) == 0 {
+		consumes = []string{defaultMediaType}
+	}
+	if len(produces) == 0 {
+		produces = []string{defaultMediaType}
+	}
+
+	v3 := map[string]any{}
+	for key, value := range operation {
+		switch key {
+		case "consumes", "produces", "parameters", "responses":
+		default:
+			v3[key] = value
+		}
+	}
+
+	var parameters []any
+	params, _ := operation["parameters"].([]any)
+	for _, p := range params {
+		param, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		if param["in"] == "body" {
+			requestBody := map[string]any{"content": content(consumes, param["schema"])}
+			if description, ok := param["description"]; ok {
+				requestBody["description"] = description
+			}
+			if required, ok := param["required"]; ok {
+				requestBody["required"] = required
+			}
+			v3["requestBody"] = requestBody
+			continue
+		}
+		parameters = append(parameters, convertParameter(param))
+	}
+	if len(parameters) > 0 {
+		v3["parameters"] = parameters
+	}
+
+	responses := map[string]any{}
+	v2Responses, _ := operation["responses"].(map[string]any)
+	for status, r := range v2Responses {
+		response, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+		v3Response := map[string]any{"description": response["description"]}
+		if schema, ok := response["schema"]; ok {
+			mediaTypes := produces
+			if isProblem(schema) {
+				mediaTypes = []string{problemMediaType}
+			}
+			v3Response["content"] = content(mediaTypes, schema)
+		}
+		if headers, ok := response["headers"].(map[string]any); ok {
+			v3Headers := map[string]any{}
+			for name, h := range headers {
+				header, _ := h.(map[string]any)
+				v3Header := map[string]any{"schema": schemaOf(header)}
+				if description, ok := header["description"]; ok {
+					v3Header["description"] = description
+				}
+				v3Headers[name] = v3Header
+			}
+			v3Response["headers"] = v3Headers
+		}
+		responses[status] = v3Response
+	}
+	v3["responses"] = responses
+	return v3
+}
+
+// convertParameter converts a non-body Swagger 2.0 parameter, moving its type to its schema
+func convertParameter(param map[string]any) map[string]any {
+	v3 := map[string]any{}
+	for _, key := range []string{"name", "in", "description", "required"} {
+		if value, ok := param[key]; ok {
+			v3[key] = value
+		}
+	}
+	v3["schema"] = schemaOf(param)
+	return v3
+}
+
+// schemaOf returns the schema of a Swagger 2.0 parameter or header, which are declared inline
+func schemaOf(value map[string]any) map[string]any {
+	schema := map[string]any{}
+	for _, key := range []string{"type", "format", "items", "enum", "default", "minimum", "maximum"} {
+		if v, ok := value[key]; ok {
+			schema[key] = v
+		}
+	}
+	return schema
+}
+
+func content(mediaTypes []string, schema any) map[string]any {
+	c := map[string]any{}
+	for _, mediaType := range mediaTypes {
+		c[mediaType] = map[string]any{"schema": schema}
+	}
+	return c
+}
+
+func isProblem(schema any) bool {
+	s, ok := schema.(map[string]any)
+	return ok && s["$ref"] == "#/components/schemas/"+ProblemSchema
+}
+
+// rewriteRefs points references to definitions at the components of the OpenAPI 3 document
+func rewriteRefs(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				v[key] = strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+				continue
+			}
+			v[key] = rewriteRefs(child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = rewriteRefs(child)
+		}
+	}
+	return value
+}
+
+func stringValue(value any) string {
+	s, _ := value.(string)
+	return s
+}
+
+func stringsValue(value any) []string {
+	values, _ := value.([]any)
+	var strs []string
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
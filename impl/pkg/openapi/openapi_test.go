@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/docs"
+)
+
+func TestFromSwagger(t *testing.T) {
+	spec, err := FromSwagger(docs.SwaggerYAML)
+	require.NoError(t, err)
+	assert.Equal(t, Version, spec["openapi"])
+
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+	require.Contains(t, schemas, ProblemSchema)
+	require.Contains(t, spec["components"].(map[string]any)["securitySchemes"], "AdminToken")
+
+	// all references resolve to components
+	var refs []string
+	collectRefs(spec, &refs)
+	require.NotEmpty(t, refs)
+	for _, ref := range refs {
+		name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+		require.True(t, ok, ref)
+		assert.Contains(t, schemas, name)
+	}
+
+	put := spec["paths"].(map[string]any)["/v1/{id}"].(map[string]any)["put"].(map[string]any)
+	requestBody := put["requestBody"].(map[string]any)
+	assert.Equal(t, true, requestBody["required"])
+	assert.Contains(t, requestBody["content"], "application/octet-stream")
+
+	params := put["parameters"].([]any)
+	require.Len(t, params, 3)
+	for _, param := range params {
+		assert.Equal(t, map[string]any{"type": "string"}, param.(map[string]any)["schema"])
+	}
+
+	badRequest := put["responses"].(map[string]any)["400"].(map[string]any)
+	assert.Contains(t, badRequest["content"], problemMediaType)
+
+	get := spec["paths"].(map[string]any)["/v1/{id}"].(map[string]any)["get"].(map[string]any)
+	ok := get["responses"].(map[string]any)["200"].(map[string]any)
+	assert.Contains(t, ok["headers"], "Gateway-Attestation")
+}
+
+func TestFromSwaggerUnsupportedVersion(t *testing.T) {
+	_, err := FromSwagger([]byte(`openapi: 3.0.3`))
+	assert.ErrorContains(t, err, "unsupported swagger version")
+}
+
+func collectRefs(value any, refs *[]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				*refs = append(*refs, ref)
+				continue
+			}
+			collectRefs(child, refs)
+		}
+	case []any:
+		for _, child := range v {
+			collectRefs(child, refs)
+		}
+	}
+}
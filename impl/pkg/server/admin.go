@@ -0,0 +1,22 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth is middleware which requires the admin token as a bearer token in the Authorization header
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			LoggingRespondErrMsg(c, "invalid admin token", http.StatusUnauthorized)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
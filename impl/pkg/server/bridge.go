@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+// MethodParam is the query parameter of the DID method to export a did:dht identifier to, e.g. key or jwk
+const MethodParam string = "method"
+
+// BridgeRouter is the router for converting the identifiers of other DID methods to and from did:dht
+type BridgeRouter struct{}
+
+// NewBridgeRouter returns a new instance of the Bridge router
+func NewBridgeRouter() (*BridgeRouter, error) {
+	return &BridgeRouter{}, nil
+}
+
+// ImportDIDRequest is the request to convert the identifier of another DID method to did:dht
+type ImportDIDRequest struct {
+	// DID is a did:key or did:jwk identifier of an ed25519 key
+	DID string `json:"did" validate:"required"`
+}
+
+// ImportDIDResponse is the did:dht Document converted from the identifier of another DID method
+type ImportDIDResponse struct {
+	// DID is the did:dht identifier, whose identity key is the key of the imported identifier
+	DID      string          `json:"did"`
+	Document didsdk.Document `json:"document"`
+	// Packet is the base64url encoded DNS packet representing the document, the v of the record to sign with the key
+	// and publish
+	Packet string `json:"packet"`
+}
+
+// ExportDIDResponse is the identifier of another DID method converted from a did:dht identifier
+type ExportDIDResponse struct {
+	DID string `json:"did"`
+}
+
+// ImportDID godoc
+//
+//	@Summary		Import a DID into did:dht
+//	@Description	Convert a did:key or did:jwk identifier of an ed25519 key into a did:dht Document whose identity key
+//	@Description	is the same key, listing the identifier as alsoKnownAs, along with the DNS packet representing it.
+//	@Description	The holder of the key signs the packet and publishes it to move the identity onto the DHT.
+//	@Tags			Bridge
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		ImportDIDRequest	true	"DID to import"
+//	@Success		200		{object}	ImportDIDResponse
+//	@Failure		400		{object}	Problem	"Bad request, unsupported DID method, or a key which can't be an identity key"
+//	@Router			/v1/bridge/import [post]
+func (r *BridgeRouter) ImportDID(c *gin.Context) {
+	var request ImportDIDRequest
+	if err := Decode(c.Request, &request); err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid import request", http.StatusBadRequest)
+		return
+	}
+	doc, err := did.ImportDID(request.DID, did.CreateDIDDHTOpts{})
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to import did", http.StatusBadRequest)
+		return
+	}
+	msg, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to convert did document to dns packet", http.StatusInternalServerError)
+		return
+	}
+	packet, err := msg.Pack()
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to pack dns packet", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, ImportDIDResponse{
+		DID:      doc.ID,
+		Document: *doc,
+		Packet:   base64.RawURLEncoding.EncodeToString(packet),
+	}, http.StatusOK)
+}
+
+// ExportDID godoc
+//
+//	@Summary		Export a did:dht identifier to another DID method
+//	@Description	Convert a did:dht identifier into the did:key or did:jwk identifier of its identity key. Only the
+//	@Description	identity key carries over; the other keys and services of the did:dht Document don't.
+//	@Tags			Bridge
+//	@Produce		json
+//	@Param			id		path		string	true	"did:dht identifier, or its z-base-32 encoded suffix"
+//	@Param			method	query		string	true	"DID method to export to, key or jwk"
+//	@Success		200		{object}	ExportDIDResponse
+//	@Failure		400		{object}	Problem	"Bad request or unsupported DID method"
+//	@Router			/v1/bridge/export/{id} [get]
+func (r *BridgeRouter) ExportDID(c *gin.Context) {
+	id := GetParam(c, IDParam)
+	if id == nil || *id == "" {
+		LoggingRespondErrMsg(c, "missing id param", http.StatusBadRequest)
+		return
+	}
+	method := GetQueryValue(c, MethodParam)
+	if method == nil || *method == "" {
+		LoggingRespondErrMsg(c, "missing method param", http.StatusBadRequest)
+		return
+	}
+	identifier := did.DHT(did.Prefix + ":" + strings.TrimPrefix(*id, did.Prefix+":"))
+	exported, err := did.ExportDID(identifier, didsdk.Method(*method))
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to export did", http.StatusBadRequest)
+		return
+	}
+	Respond(c, ExportDIDResponse{DID: exported}, http.StatusOK)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// CacheRouter is the router for inspecting and maintaining the record cache
+type CacheRouter struct {
+	service *service.PkarrService
+}
+
+// NewCacheRouter returns a new instance of the Cache router
+func NewCacheRouter(service *service.PkarrService) (*CacheRouter, error) {
+	return &CacheRouter{service: service}, nil
+}
+
+// GetCacheStats godoc
+//
+//	@Summary		Get record cache efficiency
+//	@Description	Get the hits, misses, and evictions of the record cache, the number and ages of its entries, and the
+//	@Description	outcome of the last consistency check, for sizing the cache from data
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	service.CacheStats
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/cache [get]
+func (r *CacheRouter) GetCacheStats(c *gin.Context) {
+	stats, err := r.service.GetCacheStats(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to get cache stats", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, stats, http.StatusOK)
+}
+
+// FlushCache godoc
+//
+//	@Summary		Flush the record cache
+//	@Description	Evict every cached record, so each is next resolved from the DHT or storage
+//	@Tags			Admin
+//	@Security		AdminToken
+//	@Success		204
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/cache [delete]
+func (r *CacheRouter) FlushCache(c *gin.Context) {
+	if err := r.service.FlushCache(c); err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to flush cache", http.StatusInternalServerError)
+		return
+	}
+	ResponseStatus(c, http.StatusNoContent)
+}
+
+// CheckCache godoc
+//
+//	@Summary		Check the record cache against storage
+//	@Description	Check the cached records against storage now, evicting the records of denied IDs, records older than
+//	@Description	the stored record, and entries which can't be decoded
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	service.CacheCheck
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/cache/check [post]
+func (r *CacheRouter) CheckCache(c *gin.Context) {
+	check, err := r.service.CheckCache(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to check cache", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, check, http.StatusOK)
+}
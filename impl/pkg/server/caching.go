@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+const (
+	CacheControlHeader string = "Cache-Control"
+	// AgeHeader is the response header of the seconds since the gateway resolved the record it serves from its cache
+	AgeHeader          string = "Age"
+	LastModifiedHeader string = "Last-Modified"
+
+	// immutableMaxAge is the max-age of responses which never change, a year
+	immutableMaxAge = 365 * 24 * time.Hour
+)
+
+// setCacheHeaders sets the headers HTTP caches in front of the gateway, such as CDNs, serve a resolved record by. A
+// record resolved as the latest is fresh for as long as the gateway caches it, and its Age is the time since the
+// gateway cached it, so caches don't serve it for longer than the gateway would. Other resolutions, such as those
+// depending on a consistency token, must be revalidated.
+func setCacheHeaders(c *gin.Context, resp service.GetPkarrResponse) {
+	setLastModified(c, resp.Seq)
+	if resp.Freshness == nil {
+		c.Header(CacheControlHeader, "no-cache")
+		return
+	}
+	c.Header(CacheControlHeader, fmt.Sprintf("public, max-age=%d", int64(resp.Freshness.TTL.Seconds())))
+	if age := time.Since(resp.Freshness.Cached); age > 0 {
+		c.Header(AgeHeader, fmt.Sprintf("%d", int64(age.Seconds())))
+	}
+}
+
+// setImmutableCacheHeaders sets the headers of a response which never changes, such as a version of a record, which
+// caches may serve without revalidating
+func setImmutableCacheHeaders(c *gin.Context, seq int64) {
+	setLastModified(c, seq)
+	c.Header(CacheControlHeader, fmt.Sprintf("public, max-age=%d, immutable", int64(immutableMaxAge.Seconds())))
+}
+
+// setLastModified sets the Last-Modified header to the time the record was signed, its seq as a unix timestamp in
+// seconds as the spec recommends, unless the seq can't be one
+func setLastModified(c *gin.Context, seq int64) {
+	if seq <= 0 || seq > time.Now().Unix() {
+		return
+	}
+	c.Header(LastModifiedHeader, time.Unix(seq, 0).UTC().Format(http.TimeFormat))
+}
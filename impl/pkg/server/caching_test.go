@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+func TestCacheHeaders(t *testing.T) {
+	seq := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+	t.Run("latest record", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		setCacheHeaders(c, service.GetPkarrResponse{
+			Seq:       seq,
+			Freshness: &service.Freshness{Cached: time.Now().Add(-time.Minute), TTL: 10 * time.Minute},
+		})
+		assert.Equal(t, "public, max-age=600", w.Header().Get(CacheControlHeader))
+		assert.Equal(t, "60", w.Header().Get(AgeHeader))
+		assert.Equal(t, "Wed, 01 May 2024 00:00:00 GMT", w.Header().Get(LastModifiedHeader))
+	})
+
+	t.Run("revalidated record", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		setCacheHeaders(c, service.GetPkarrResponse{Seq: 1 << 40})
+		assert.Equal(t, "no-cache", w.Header().Get(CacheControlHeader))
+		assert.Empty(t, w.Header().Get(AgeHeader))
+		// seqs which aren't timestamps have no modification time
+		assert.Empty(t, w.Header().Get(LastModifiedHeader))
+	})
+
+	t.Run("version", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		setImmutableCacheHeaders(c, seq)
+		assert.Equal(t, "public, max-age=31536000, immutable", w.Header().Get(CacheControlHeader))
+		assert.Equal(t, "Wed, 01 May 2024 00:00:00 GMT", w.Header().Get(LastModifiedHeader))
+	})
+}
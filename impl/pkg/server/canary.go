@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// CanaryRouter is the router for the canary record verifying the gateway end to end
+type CanaryRouter struct {
+	service *service.PkarrService
+}
+
+// NewCanaryRouter returns a new instance of the Canary router
+func NewCanaryRouter(service *service.PkarrService) (*CanaryRouter, error) {
+	return &CanaryRouter{service: service}, nil
+}
+
+// GetCanaryStatus godoc
+//
+//	@Summary		Get canary status
+//	@Description	Get the counts of the canary's round trips since the gateway started, with the outcome of the last
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	service.CanaryStatus
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		404	{object}	Problem	"Canary is disabled"
+//	@Router			/admin/canary [get]
+func (r *CanaryRouter) GetCanaryStatus(c *gin.Context) {
+	status, err := r.service.GetCanaryStatus()
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to get canary status", http.StatusNotFound)
+		return
+	}
+	Respond(c, status, http.StatusOK)
+}
+
+// StartCanary godoc
+//
+//	@Summary		Run the canary
+//	@Description	Start a round trip of the canary now: publish a new canary record under the gateway's own key, wait
+//	@Description	for it to be found in the DHT, and resolve it through the cache and storage. The round trip runs in
+//	@Description	the background for up to two minutes; poll the canary status for its outcome.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		202	{object}	service.CanaryStatus
+//	@Failure		400	{object}	Problem	"The canary is already running"
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		404	{object}	Problem	"Canary is disabled"
+//	@Router			/admin/canary [post]
+func (r *CanaryRouter) StartCanary(c *gin.Context) {
+	status, err := r.service.StartCanary()
+	if err != nil {
+		if errors.Is(err, service.ErrCanaryDisabled) {
+			LoggingRespondErrWithMsg(c, err, "failed to run canary", http.StatusNotFound)
+			return
+		}
+		LoggingRespondErrWithMsg(c, err, "failed to run canary", http.StatusBadRequest)
+		return
+	}
+	Respond(c, status, http.StatusAccepted)
+}
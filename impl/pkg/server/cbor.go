@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// CBORMediaType is the media type of CBOR, which clients accept to have records resolved as a RecordEnvelope
+const CBORMediaType string = "application/cbor"
+
+// cborEncoding encodes CBOR deterministically, so the same record is always encoded the same way
+var cborEncoding, _ = cbor.CoreDetEncOptions().EncMode()
+
+// RecordEnvelope is a resolved Pkarr record encoded as CBOR, for constrained clients which would rather decode a map
+// than slice the sig, seq, and v out of the raw bytes
+type RecordEnvelope struct {
+	// Sig is the 64 bytes ed25519 signature of the record
+	Sig []byte `cbor:"sig"`
+	// Seq is the sequence number of the record
+	Seq int64 `cbor:"seq"`
+	// V is the DNS packet of the record, as published
+	V []byte `cbor:"v"`
+}
+
+// acceptsCBOR returns whether the request accepts records as a RecordEnvelope
+func acceptsCBOR(c *gin.Context) bool {
+	return acceptsMediaType(c.GetHeader("Accept"), CBORMediaType)
+}
+
+// respondRecordEnvelope responds with the record encoded as a RecordEnvelope
+func respondRecordEnvelope(c *gin.Context, resp service.GetPkarrResponse) {
+	envelope, err := cborEncoding.Marshal(RecordEnvelope{Sig: resp.Sig[:], Seq: resp.Seq, V: resp.V})
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to encode record envelope", http.StatusInternalServerError)
+		return
+	}
+	c.Data(http.StatusOK, CBORMediaType, envelope)
+}
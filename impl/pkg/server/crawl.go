@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// CrawlRouter is the router for importing the records of known identifiers from the DHT
+type CrawlRouter struct {
+	service *service.PkarrService
+}
+
+// NewCrawlRouter returns a new instance of the Crawl router
+func NewCrawlRouter(service *service.PkarrService) (*CrawlRouter, error) {
+	return &CrawlRouter{service: service}, nil
+}
+
+// Crawl godoc
+//
+//	@Summary		Import records from the DHT
+//	@Description	Start importing the records of the given identifiers, and of those listed by the feed at feedUrl,
+//	@Description	from the DHT. Each record is verified and stored like a published record unless the same or a newer
+//	@Description	seq is already stored. The crawl runs in the background; poll its status for progress.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			request	body		service.CrawlRequest	true	"Identifiers to import"
+//	@Success		202		{object}	service.CrawlStatus
+//	@Failure		400		{object}	Problem	"Bad request, or a crawl is already running"
+//	@Failure		401		{object}	Problem	"Unauthorized"
+//	@Router			/admin/crawl [post]
+func (r *CrawlRouter) Crawl(c *gin.Context) {
+	var request service.CrawlRequest
+	if err := Decode(c.Request, &request); err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid crawl request", http.StatusBadRequest)
+		return
+	}
+	status, err := r.service.Crawl(c, request)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to start crawl", http.StatusBadRequest)
+		return
+	}
+	Respond(c, status, http.StatusAccepted)
+}
+
+// GetCrawlStatus godoc
+//
+//	@Summary		Get the crawl status
+//	@Description	Get the progress of the running crawl, or the outcome of the last one
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	service.CrawlStatus
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Router			/admin/crawl [get]
+func (r *CrawlRouter) GetCrawlStatus(c *gin.Context) {
+	Respond(c, r.service.CrawlStatus(), http.StatusOK)
+}
+
+// StopCrawl godoc
+//
+//	@Summary		Stop the crawl
+//	@Description	Stop the running crawl, if any, keeping the records it imported
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	service.CrawlStatus
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Router			/admin/crawl [delete]
+func (r *CrawlRouter) StopCrawl(c *gin.Context) {
+	Respond(c, r.service.StopCrawl(), http.StatusOK)
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deadline is middleware bounding the context of each request by the given timeout, such as the server's write
+// timeout, after which the response would be dropped anyway. DHT gets stop shortly before the deadline, responding
+// with the best record found so far rather than timing out. Streamed listings aren't bounded, as they are written
+// until the listing ends or the client goes away.
+func Deadline(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if acceptsNDJSON(c) {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
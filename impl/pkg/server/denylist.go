@@ -0,0 +1,125 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// DenylistRouter is the router for managing the keys the gateway refuses to store, serve, or republish
+type DenylistRouter struct {
+	service *service.PkarrService
+}
+
+// NewDenylistRouter returns a new instance of the Denylist router
+func NewDenylistRouter(service *service.PkarrService) (*DenylistRouter, error) {
+	return &DenylistRouter{service: service}, nil
+}
+
+// ListDenylistResponse is the list of denied keys
+type ListDenylistResponse struct {
+	Entries []pkarr.DenylistEntry `json:"entries"`
+}
+
+// DenyKeyRequest is the request to add a key to the denylist
+type DenyKeyRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+// ListDenylist godoc
+//
+//	@Summary		List the denylist
+//	@Description	List the keys the gateway refuses to store, serve, or republish records for
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	ListDenylistResponse
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/denylist [get]
+func (r *DenylistRouter) ListDenylist(c *gin.Context) {
+	entries, err := r.service.ListDeniedKeys(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to list denylist", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []pkarr.DenylistEntry{}
+	}
+	Respond(c, ListDenylistResponse{Entries: entries}, http.StatusOK)
+}
+
+// DenyKey godoc
+//
+//	@Summary		Add a key to the denylist
+//	@Description	Take down the record of a key, refusing to store, serve, or republish records for it
+//	@Tags			Admin
+//	@Accept			json
+//	@Security		AdminToken
+//	@Param			id		path	string			true	"ID of the key to deny"
+//	@Param			request	body	DenyKeyRequest	true	"Reason for the takedown"
+//	@Success		200
+//	@Failure		400	{object}	Problem	"Bad request"
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/denylist/{id} [put]
+func (r *DenylistRouter) DenyKey(c *gin.Context) {
+	id := getKeyParam(c)
+	if id == nil {
+		return
+	}
+	var request DenyKeyRequest
+	if err := Decode(c.Request, &request); err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid deny key request", http.StatusBadRequest)
+		return
+	}
+	if err := r.service.DenyKey(c, *id, request.Reason); err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to deny key", http.StatusInternalServerError)
+		return
+	}
+	ResponseStatus(c, http.StatusOK)
+}
+
+// AllowKey godoc
+//
+//	@Summary		Remove a key from the denylist
+//	@Description	Remove a key from the denylist, accepting and serving its records again
+//	@Tags			Admin
+//	@Security		AdminToken
+//	@Param			id	path	string	true	"ID of the key to allow"
+//	@Success		200
+//	@Failure		400	{object}	Problem	"Bad request"
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/denylist/{id} [delete]
+func (r *DenylistRouter) AllowKey(c *gin.Context) {
+	id := getKeyParam(c)
+	if id == nil {
+		return
+	}
+	if err := r.service.AllowKey(c, *id); err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to allow key", http.StatusInternalServerError)
+		return
+	}
+	ResponseStatus(c, http.StatusOK)
+}
+
+// getKeyParam reads the id path param as a z-base-32 encoded ed25519 public key, responding with an error if invalid
+func getKeyParam(c *gin.Context) *string {
+	id := GetParam(c, IDParam)
+	if id == nil || *id == "" {
+		LoggingRespondErrMsg(c, "missing id param", http.StatusBadRequest)
+		return nil
+	}
+	key, err := util.Z32Decode(*id)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		LoggingRespondError(c, errMalformedID, http.StatusBadRequest)
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+func TestAdminAuth(t *testing.T) {
+	handler := gin.New()
+	handler.GET("/admin", AdminAuth("secret"), func(c *gin.Context) {
+		ResponseStatus(c, http.StatusOK)
+	})
+
+	for header, status := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"secret":        http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"Bearer secret": http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, testServerURL+"/admin", nil)
+		req.Header.Set("Authorization", header)
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code, header)
+	}
+}
+
+func TestDenylistRouter(t *testing.T) {
+	pkarrSvc := testPKARRService(t)
+	pkarrRouter, err := NewPkarrRouter(pkarrSvc)
+	require.NoError(t, err)
+	denylistRouter, err := NewDenylistRouter(pkarrSvc)
+	require.NoError(t, err)
+
+	didID, reqData := generateDIDPutRequest(t)
+	suffix, err := did.DHT(didID).Suffix()
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("%s/%s", testServerURL, suffix), bytes.NewReader(reqData))
+	pkarrRouter.PutRecord(newRequestContextWithParams(w, req, map[string]string{IDParam: suffix}))
+	require.True(t, is2xxResponse(w.Code))
+
+	t.Run("deny key", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("%s/admin/denylist/%s", testServerURL, suffix), strings.NewReader(`{"reason":"abuse"}`))
+		denylistRouter.DenyKey(newRequestContextWithParams(w, req, map[string]string{IDParam: suffix}))
+		assert.True(t, is2xxResponse(w.Code))
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, testServerURL+"/admin/denylist", nil)
+		denylistRouter.ListDenylist(newRequestContext(w, req))
+		assert.True(t, is2xxResponse(w.Code))
+
+		var resp ListDenylistResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Len(t, resp.Entries, 1)
+		assert.Equal(t, suffix, resp.Entries[0].ID)
+		assert.Equal(t, "abuse", resp.Entries[0].Reason)
+	})
+
+	t.Run("denied record is not served or stored", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s", testServerURL, suffix), nil)
+		pkarrRouter.GetRecord(newRequestContextWithParams(w, req, map[string]string{IDParam: suffix}))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, fmt.Sprintf("%s/%s", testServerURL, suffix), bytes.NewReader(reqData))
+		pkarrRouter.PutRecord(newRequestContextWithParams(w, req, map[string]string{IDParam: suffix}))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("allow key", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("%s/admin/denylist/%s", testServerURL, suffix), nil)
+		denylistRouter.AllowKey(newRequestContextWithParams(w, req, map[string]string{IDParam: suffix}))
+		assert.True(t, is2xxResponse(w.Code))
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, fmt.Sprintf("%s/%s", testServerURL, suffix), bytes.NewReader(reqData))
+		pkarrRouter.PutRecord(newRequestContextWithParams(w, req, map[string]string{IDParam: suffix}))
+		assert.True(t, is2xxResponse(w.Code))
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, testServerURL+"/admin/denylist/bad", nil)
+		denylistRouter.AllowKey(newRequestContextWithParams(w, req, map[string]string{IDParam: "bad"}))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/miekg/dns"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+const (
+	// DNSParam is the base64url encoded DNS query of a DNS-over-HTTPS GET request
+	DNSParam string = "dns"
+
+	dnsMessageContentType = "application/dns-message"
+)
+
+// DNSRouter is the router for resolving records as DNS responses over HTTPS
+type DNSRouter struct {
+	service *service.PkarrService
+}
+
+// NewDNSRouter returns a new instance of the DNS router
+func NewDNSRouter(service *service.PkarrService) (*DNSRouter, error) {
+	return &DNSRouter{service: service}, nil
+}
+
+// GetDNSQuery godoc
+//
+//	@Summary		Resolve a DNS query over HTTPS
+//	@Description	Resolve a DNS query for `<z32>.did.` names from the stored packet's resource records according to RFC 8484
+//	@Tags			DNS
+//	@Produce		application/dns-message
+//	@Param			dns	query		string	true	"base64url encoded DNS query"
+//	@Success		200	{array}		byte	"DNS response"
+//	@Failure		400	{object}	Problem	"Bad request"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/dns-query [get]
+func (r *DNSRouter) GetDNSQuery(c *gin.Context) {
+	query := GetQueryValue(c, DNSParam)
+	if query == nil {
+		LoggingRespondErrMsg(c, "missing dns query param", http.StatusBadRequest)
+		return
+	}
+	msg, err := base64.RawURLEncoding.DecodeString(*query)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to decode dns query", http.StatusBadRequest)
+		return
+	}
+	r.resolve(c, msg)
+}
+
+// PostDNSQuery godoc
+//
+//	@Summary		Resolve a DNS query over HTTPS
+//	@Description	Resolve a DNS query for `<z32>.did.` names from the stored packet's resource records according to RFC 8484
+//	@Tags			DNS
+//	@Accept			application/dns-message
+//	@Produce		application/dns-message
+//	@Param			request	body		[]byte	true	"DNS query"
+//	@Success		200		{array}		byte	"DNS response"
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		415		{object}	Problem	"Unsupported media type"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/dns-query [post]
+func (r *DNSRouter) PostDNSQuery(c *gin.Context) {
+	if c.ContentType() != dnsMessageContentType {
+		LoggingRespondErrMsg(c, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	msg, err := io.ReadAll(io.LimitReader(c.Request.Body, dns.MaxMsgSize))
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to read dns query", http.StatusBadRequest)
+		return
+	}
+	r.resolve(c, msg)
+}
+
+func (r *DNSRouter) resolve(c *gin.Context, msg []byte) {
+	query := new(dns.Msg)
+	if err := query.Unpack(msg); err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to unpack dns query", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := r.service.ResolveDNS(c, query)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to resolve dns query", http.StatusInternalServerError)
+		return
+	}
+	res, err := resp.Pack()
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to pack dns response", http.StatusInternalServerError)
+		return
+	}
+
+	// responses are cacheable for as long as the shortest lived record according to RFC 8484 section 5.1
+	if len(resp.Answer) > 0 {
+		ttl := resp.Answer[0].Header().Ttl
+		for _, rr := range resp.Answer[1:] {
+			ttl = min(ttl, rr.Header().Ttl)
+		}
+		c.Header("Cache-Control", fmt.Sprintf("max-age=%d", ttl))
+	}
+	c.Data(http.StatusOK, dnsMessageContentType, res)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+const dnsQueryTimeout = 5 * time.Second
+
+// DNSServer answers DNS queries for stored records authoritatively over UDP and TCP, e.g. `_did.<z32>.` queries
+type DNSServer struct {
+	Addr string
+
+	udp *dns.Server
+	tcp *dns.Server
+}
+
+// NewDNSServer returns a new instance of DNSServer listening on the given address
+func NewDNSServer(addr string, service *service.PkarrService) *DNSServer {
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, query *dns.Msg) {
+		ctx, cancel := context.WithTimeout(context.Background(), dnsQueryTimeout)
+		defer cancel()
+
+		resp, err := service.ResolveDNS(ctx, query)
+		if err != nil {
+			logrus.WithError(err).Error("failed to resolve dns query")
+			resp = new(dns.Msg)
+			resp.SetRcode(query, dns.RcodeServerFailure)
+		}
+		// limit udp responses to the size the client supports, setting the truncated bit so it retries over tcp
+		if w.LocalAddr().Network() == "udp" {
+			size := dns.MinMsgSize
+			if opt := query.IsEdns0(); opt != nil {
+				size = int(opt.UDPSize())
+			}
+			resp.Truncate(size)
+		}
+		if err = w.WriteMsg(resp); err != nil {
+			logrus.WithError(err).Error("failed to write dns response")
+		}
+	})
+	return &DNSServer{
+		Addr: addr,
+		udp:  &dns.Server{Addr: addr, Net: "udp", Handler: handler},
+		tcp:  &dns.Server{Addr: addr, Net: "tcp", Handler: handler},
+	}
+}
+
+// ListenAndServe starts the UDP and TCP listeners, returning when either of them stops
+func (s *DNSServer) ListenAndServe() error {
+	errs := make(chan error, 2)
+	go func() {
+		errs <- errors.Wrap(s.udp.ListenAndServe(), "udp listener")
+	}()
+	go func() {
+		errs <- errors.Wrap(s.tcp.ListenAndServe(), "tcp listener")
+	}()
+	return <-errs
+}
+
+// Shutdown gracefully stops the UDP and TCP listeners
+func (s *DNSServer) Shutdown(ctx context.Context) error {
+	udpErr := s.udp.ShutdownContext(ctx)
+	tcpErr := s.tcp.ShutdownContext(ctx)
+	if udpErr != nil {
+		return udpErr
+	}
+	return tcpErr
+}
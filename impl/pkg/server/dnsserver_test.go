@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+// localDHT is a DHT which accepts every put and finds nothing, so records are only resolved from storage
+type localDHT struct{}
+
+func (localDHT) Put(context.Context, bep44.Put) (string, error) {
+	return "", nil
+}
+
+func (localDHT) GetFull(context.Context, string, []byte) (*dht.FullGetResult, error) {
+	return nil, errors.New("not found")
+}
+
+func TestDNSServer(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "dns.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	pkarrService, err := service.NewPkarrServiceWith(&cfg, db, localDHT{}, cache.None{})
+	require.NoError(t, err)
+
+	// enough services for the answer to the whole packet not to fit in 512 bytes
+	var services []didsdk.Service
+	for i := 0; i < 8; i++ {
+		services = append(services, didsdk.Service{
+			ID:              fmt.Sprintf("s%d", i),
+			Type:            "LinkedDomains",
+			ServiceEndpoint: []string{fmt.Sprintf("https://service-%d.example.com/endpoint", i)},
+		})
+	}
+	sk, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{Services: services})
+	require.NoError(t, err)
+	packet, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+	require.NoError(t, err)
+	put, err := dht.CreatePKARRPublishRequest(sk, *packet)
+	require.NoError(t, err)
+	suffix, err := did.DHT(doc.ID).Suffix()
+	require.NoError(t, err)
+	require.NoError(t, pkarrService.PublishPkarr(context.Background(), suffix, service.PublishPkarrRequest{
+		V:   put.V.([]byte),
+		K:   *put.K,
+		Sig: put.Sig,
+		Seq: put.Seq,
+	}))
+
+	udpAddr, tcpAddr := serveDNS(t, NewDNSServer("", pkarrService))
+
+	t.Run("udp query", func(t *testing.T) {
+		resp := exchangeDNS(t, "udp", udpAddr, new(dns.Msg).SetQuestion("_did."+suffix+".did.", dns.TypeTXT))
+		assert.Equal(t, dns.RcodeSuccess, resp.Rcode)
+		assert.True(t, resp.Authoritative)
+		require.Len(t, resp.Answer, 1)
+		assert.Equal(t, "_did."+suffix+".did.", resp.Answer[0].Header().Name)
+	})
+
+	t.Run("tcp query", func(t *testing.T) {
+		resp := exchangeDNS(t, "tcp", tcpAddr, new(dns.Msg).SetQuestion("_did."+suffix+".did.", dns.TypeTXT))
+		assert.Equal(t, dns.RcodeSuccess, resp.Rcode)
+		require.Len(t, resp.Answer, 1)
+		assert.Equal(t, "_did."+suffix+".did.", resp.Answer[0].Header().Name)
+	})
+
+	t.Run("unknown names are nxdomain", func(t *testing.T) {
+		_, unknown, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{})
+		require.NoError(t, err)
+		unknownSuffix, err := did.DHT(unknown.ID).Suffix()
+		require.NoError(t, err)
+		for _, name := range []string{"_did." + unknownSuffix + ".did.", "_did.example.com.", "_x." + suffix + ".did."} {
+			resp := exchangeDNS(t, "udp", udpAddr, new(dns.Msg).SetQuestion(name, dns.TypeTXT))
+			assert.Equal(t, dns.RcodeNameError, resp.Rcode, name)
+			assert.Empty(t, resp.Answer, name)
+		}
+	})
+
+	t.Run("queries of more than one question are rejected", func(t *testing.T) {
+		query := new(dns.Msg).SetQuestion("_did."+suffix+".did.", dns.TypeTXT)
+		query.Question = append(query.Question, query.Question[0])
+		resp := exchangeDNS(t, "udp", udpAddr, query)
+		assert.Equal(t, dns.RcodeFormatError, resp.Rcode)
+	})
+
+	t.Run("oversized udp responses are truncated", func(t *testing.T) {
+		full := exchangeDNS(t, "tcp", tcpAddr, new(dns.Msg).SetQuestion(suffix+".did.", dns.TypeANY))
+		assert.False(t, full.Truncated)
+		assert.Len(t, full.Answer, len(packet.Answer))
+		require.Greater(t, full.Len(), dns.MinMsgSize)
+
+		truncated := exchangeDNS(t, "udp", udpAddr, new(dns.Msg).SetQuestion(suffix+".did.", dns.TypeANY))
+		assert.True(t, truncated.Truncated)
+		assert.Less(t, len(truncated.Answer), len(full.Answer))
+		// the response was truncated to fit with name compression
+		truncated.Compress = true
+		assert.LessOrEqual(t, truncated.Len(), dns.MinMsgSize)
+
+		// clients advertising a larger buffer with EDNS0 get the whole answer over udp
+		query := new(dns.Msg).SetQuestion(suffix+".did.", dns.TypeANY)
+		query.SetEdns0(4096, false)
+		resp := exchangeDNS(t, "udp", udpAddr, query)
+		assert.False(t, resp.Truncated)
+		assert.Len(t, resp.Answer, len(full.Answer))
+	})
+}
+
+// serveDNS serves the DNS server on local listeners until the test ends, returning their UDP and TCP addresses
+func serveDNS(t *testing.T, s *DNSServer) (string, string) {
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s.udp.PacketConn, s.tcp.Listener = packetConn, listener
+	go func() { _ = s.udp.ActivateAndServe() }()
+	go func() { _ = s.tcp.ActivateAndServe() }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = s.Shutdown(ctx)
+	})
+	return packetConn.LocalAddr().String(), listener.Addr().String()
+}
+
+// exchangeDNS sends the query over the network, returning the response
+func exchangeDNS(t *testing.T, network, addr string, query *dns.Msg) *dns.Msg {
+	client := dns.Client{Net: network, Timeout: 5 * time.Second}
+	resp, _, err := client.Exchange(query, addr)
+	require.NoError(t, err)
+	return resp
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// DrainRouter is the router for draining the gateway ahead of a restart
+type DrainRouter struct {
+	service *service.PkarrService
+}
+
+// NewDrainRouter returns a new instance of the Drain router
+func NewDrainRouter(service *service.PkarrService) (*DrainRouter, error) {
+	return &DrainRouter{service: service}, nil
+}
+
+// Drain godoc
+//
+//	@Summary		Drain the gateway
+//	@Description	Flip readiness to not ready and stop accepting new publishes, while in-flight DHT puts and republishing
+//	@Description	finish. Poll the drain status until it reports drained, after which the gateway is safe to terminate.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	service.DrainStatus
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Router			/admin/drain [post]
+func (r *DrainRouter) Drain(c *gin.Context) {
+	Respond(c, r.service.Drain(), http.StatusOK)
+}
+
+// GetDrainStatus godoc
+//
+//	@Summary		Get the drain status
+//	@Description	Get the progress of draining the gateway, which is safe to terminate once drained
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	service.DrainStatus
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Router			/admin/drain [get]
+func (r *DrainRouter) GetDrainStatus(c *gin.Context) {
+	Respond(c, r.service.DrainStatus(), http.StatusOK)
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// EquivocationRouter is the router for the evidence of keys signing different records with the same seq
+type EquivocationRouter struct {
+	service *service.PkarrService
+}
+
+// NewEquivocationRouter returns a new instance of the Equivocation router
+func NewEquivocationRouter(service *service.PkarrService) (*EquivocationRouter, error) {
+	return &EquivocationRouter{service: service}, nil
+}
+
+// ListEquivocations godoc
+//
+//	@Summary		List equivocations
+//	@Description	List the evidence of keys found signing different records with the same seq, when published to the
+//	@Description	gateway or resolved from the DHT, with the number detected since startup
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	service.EquivocationReport
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/equivocations [get]
+func (r *EquivocationRouter) ListEquivocations(c *gin.Context) {
+	report, err := r.service.ListEquivocations(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to list equivocations", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, report, http.StatusOK)
+}
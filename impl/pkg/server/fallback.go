@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// FallbackRouter is the router for the pool of gateways records are resolved from when neither the DHT nor storage
+// has them
+type FallbackRouter struct {
+	service *service.PkarrService
+}
+
+// NewFallbackRouter returns a new instance of the Fallback router
+func NewFallbackRouter(service *service.PkarrService) (*FallbackRouter, error) {
+	return &FallbackRouter{service: service}, nil
+}
+
+// ListFallbackGatewaysResponse is the list of fallback gateways
+type ListFallbackGatewaysResponse struct {
+	Gateways []service.FallbackGateway `json:"gateways"`
+}
+
+// ListFallbackGateways godoc
+//
+//	@Summary		List fallback gateways
+//	@Description	List the gateways found by the last discovery of fallback gateways, from the config, the registry,
+//	@Description	and the DHT, with the outcome of their health checks
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	ListFallbackGatewaysResponse
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		404	{object}	Problem	"No fallback gateways"
+//	@Router			/admin/fallback [get]
+func (r *FallbackRouter) ListFallbackGateways(c *gin.Context) {
+	gateways, err := r.service.GetFallbackGateways()
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to list fallback gateways", http.StatusNotFound)
+		return
+	}
+	Respond(c, ListFallbackGatewaysResponse{Gateways: gateways}, http.StatusOK)
+}
+
+// DiscoverFallbackGateways godoc
+//
+//	@Summary		Discover fallback gateways
+//	@Description	Discover and health-check the fallback gateways now, replacing the pool with the healthy ones
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	ListFallbackGatewaysResponse
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		404	{object}	Problem	"No fallback gateways"
+//	@Router			/admin/fallback [post]
+func (r *FallbackRouter) DiscoverFallbackGateways(c *gin.Context) {
+	gateways, err := r.service.DiscoverGateways(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to discover fallback gateways", http.StatusNotFound)
+		return
+	}
+	Respond(c, ListFallbackGatewaysResponse{Gateways: gateways}, http.StatusOK)
+}
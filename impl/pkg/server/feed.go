@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	// SinceParam is the query parameter of the cursor after which to list feed entries
+	SinceParam string = "since"
+)
+
+// FeedRouter is the router for the change feed of the records first seen or updated by the gateway
+type FeedRouter struct {
+	service *service.PkarrService
+}
+
+// NewFeedRouter returns a new instance of the Feed router
+func NewFeedRouter(service *service.PkarrService) (*FeedRouter, error) {
+	return &FeedRouter{service: service}, nil
+}
+
+// ListFeed godoc
+//
+//	@Summary		List the change feed
+//	@Description	List the (id, seq, timestamp) of the records first seen or updated by the gateway, in the order it saw
+//	@Description	them. Pass the returned cursor as since to tail the feed. Requests accepting application/x-ndjson
+//	@Description	are streamed every entry after since, one per line, up to limit if given.
+//	@Tags			Feed
+//	@Produce		json
+//	@Produce		x-ndjson
+//	@Param			since	query		int	false	"Cursor after which to list entries, defaults to 0 for the start of the feed"
+//	@Param			limit	query		int	false	"Maximum number of entries to return"
+//	@Success		200		{object}	service.FeedPage
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/v1/feed [get]
+func (r *FeedRouter) ListFeed(c *gin.Context) {
+	var since int64
+	if value := GetQueryValue(c, SinceParam); value != nil {
+		s, err := strconv.ParseInt(*value, 10, 64)
+		if err != nil || s < 0 {
+			LoggingRespondErrMsg(c, "invalid since param", http.StatusBadRequest)
+			return
+		}
+		since = s
+	}
+	var limit int
+	if value := GetQueryValue(c, LimitParam); value != nil {
+		l, err := strconv.Atoi(*value)
+		if err != nil || l <= 0 {
+			LoggingRespondErrMsg(c, "invalid limit param", http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+
+	if acceptsNDJSON(c) {
+		var streamed int
+		streamNDJSON(c, func(ctx context.Context) ([]pkarr.FeedEntry, bool, error) {
+			page, err := r.service.ListFeed(ctx, since, streamPageLimit(limit, streamed))
+			if err != nil {
+				return nil, false, err
+			}
+			// a page can be empty but still move the cursor, past entries of denied keys
+			more := page.Cursor != since
+			since, streamed = page.Cursor, streamed+len(page.Entries)
+			return page.Entries, more && (limit <= 0 || streamed < limit), nil
+		})
+		return
+	}
+
+	page, err := r.service.ListFeed(c, since, limit)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to list feed", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, page, http.StatusOK)
+}
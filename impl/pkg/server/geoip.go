@@ -0,0 +1,130 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oschwald/geoip2-golang"
+	"github.com/sirupsen/logrus"
+	ginlogrus "github.com/toorop/gin-logrus"
+)
+
+const (
+	// CountryKey is the key of the ISO 3166-1 alpha-2 code of the client's country in the gin context,
+	// for use by later middleware such as regional rate limits
+	CountryKey string = "country"
+
+	// unknownCountry is recorded for clients whose country can't be determined, e.g. private addresses
+	unknownCountry string = "unknown"
+)
+
+// GeoIP enriches request logs with the country of the client, looked up in a MaxMind GeoIP2 or GeoLite2
+// database, and aggregates request counts per country
+type GeoIP struct {
+	db     *geoip2.Reader
+	lookup func(ip net.IP) string
+
+	mu     sync.Mutex
+	counts map[string]int64
+
+	// loggers holds a request logger per country, so each log entry carries the country field
+	loggers sync.Map
+}
+
+// NewGeoIP returns a new instance of GeoIP reading the MaxMind database at the given path
+func NewGeoIP(path string) (*GeoIP, error) {
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	geo := newGeoIP(func(ip net.IP) string {
+		record, err := db.Country(ip)
+		if err != nil {
+			logrus.WithError(err).Debugf("failed to look up country of ip[%s]", ip)
+			return ""
+		}
+		return record.Country.IsoCode
+	})
+	geo.db = db
+	return geo, nil
+}
+
+func newGeoIP(lookup func(ip net.IP) string) *GeoIP {
+	return &GeoIP{
+		lookup: lookup,
+		counts: make(map[string]int64),
+	}
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country of the given IP address
+func (g *GeoIP) Country(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return unknownCountry
+	}
+	if country := g.lookup(parsed); country != "" {
+		return country
+	}
+	return unknownCountry
+}
+
+// Logger is request logging middleware which adds the country of the client to each log entry and counts it
+func (g *GeoIP) Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		country := g.Country(c.ClientIP())
+		c.Set(CountryKey, country)
+		g.count(country)
+
+		logger, ok := g.loggers.Load(country)
+		if !ok {
+			logger, _ = g.loggers.LoadOrStore(country, ginlogrus.Logger(logrus.WithField(CountryKey, country)))
+		}
+		logger.(gin.HandlerFunc)(c)
+	}
+}
+
+func (g *GeoIP) count(country string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.counts[country]++
+}
+
+// Counts returns the number of requests seen per country since startup
+func (g *GeoIP) Counts() map[string]int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	counts := make(map[string]int64, len(g.counts))
+	for country, count := range g.counts {
+		counts[country] = count
+	}
+	return counts
+}
+
+// Close closes the underlying database
+func (g *GeoIP) Close() error {
+	if g.db == nil {
+		return nil
+	}
+	return g.db.Close()
+}
+
+type CountryStatsResponse struct {
+	// Countries maps ISO 3166-1 alpha-2 country codes to the number of requests from them
+	Countries map[string]int64 `json:"countries"`
+}
+
+// GetCountryStats godoc
+//
+//	@Summary		Get request counts per country
+//	@Description	Get the number of requests seen per client country since startup
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	CountryStatsResponse
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Router			/admin/stats/countries [get]
+func (g *GeoIP) GetCountryStats(c *gin.Context) {
+	Respond(c, CountryStatsResponse{Countries: g.Counts()}, http.StatusOK)
+}
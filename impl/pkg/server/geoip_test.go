@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeoIP(t *testing.T) {
+	geoIP := newGeoIP(func(ip net.IP) string {
+		if ip.Equal(net.ParseIP("81.2.69.142")) {
+			return "GB"
+		}
+		return ""
+	})
+
+	handler := gin.New()
+	handler.Use(geoIP.Logger())
+	handler.GET("/country", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(CountryKey))
+	})
+	handler.GET("/stats", geoIP.GetCountryStats)
+
+	request := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("/country", "81.2.69.142:1234")
+	assert.Equal(t, "GB", w.Body.String())
+
+	w = request("/country", "10.0.0.1:1234")
+	assert.Equal(t, unknownCountry, w.Body.String())
+
+	w = request("/stats", "81.2.69.142:1234")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp CountryStatsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, map[string]int64{"GB": 2, unknownCountry: 1}, resp.Countries)
+}
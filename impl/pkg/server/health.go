@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
 )
 
 type GetHealthCheckResponse struct {
@@ -12,7 +14,9 @@ type GetHealthCheckResponse struct {
 }
 
 const (
-	HealthOK string = "OK"
+	HealthOK       string = "OK"
+	HealthDraining string = "DRAINING"
+	HealthFailing  string = "FAILING"
 )
 
 // Health godoc
@@ -28,3 +32,45 @@ func Health(c *gin.Context) {
 	status := GetHealthCheckResponse{Status: HealthOK}
 	Respond(c, status, http.StatusOK)
 }
+
+// Readiness godoc
+//
+//	@Summary		Readiness Check
+//	@Description	Readiness responds with a 200 OK while the gateway takes traffic, and a 503 once it is draining
+//	@Tags			Health
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	GetHealthCheckResponse
+//	@Failure		503	{object}	GetHealthCheckResponse
+//	@Router			/ready [get]
+func Readiness(service *service.PkarrService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !service.Ready() {
+			Respond(c, GetHealthCheckResponse{Status: HealthDraining}, http.StatusServiceUnavailable)
+			return
+		}
+		Respond(c, GetHealthCheckResponse{Status: HealthOK}, http.StatusOK)
+	}
+}
+
+// CanaryHealth godoc
+//
+//	@Summary		Canary Health Check
+//	@Description	CanaryHealth responds with a 200 OK while the last round trip of the canary record succeeded, or
+//	@Description	none has finished yet, and a 503 once it failed, such as when DHT writes fail silently
+//	@Tags			Health
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	GetHealthCheckResponse
+//	@Failure		503	{object}	GetHealthCheckResponse
+//	@Router			/health/canary [get]
+func CanaryHealth(service *service.PkarrService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := service.GetCanaryStatus()
+		if err == nil && !status.Healthy() {
+			Respond(c, GetHealthCheckResponse{Status: HealthFailing}, http.StatusServiceUnavailable)
+			return
+		}
+		Respond(c, GetHealthCheckResponse{Status: HealthOK}, http.StatusOK)
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// HistoryRouter is the router for the History API, which exposes the log of record updates witnessed by the gateway
+type HistoryRouter struct {
+	service *service.PkarrService
+}
+
+// NewHistoryRouter returns a new instance of the History router
+func NewHistoryRouter(service *service.PkarrService) (*HistoryRouter, error) {
+	return &HistoryRouter{service: service}, nil
+}
+
+// ListHistoryResponse is a page of the history log
+type ListHistoryResponse struct {
+	Entries []pkarr.HistoryEntry `json:"entries"`
+}
+
+// ListHistory godoc
+//
+//	@Summary		List the history log
+//	@Description	List the hash-chained log of record updates witnessed by the gateway, ordered by index. Requests
+//	@Description	accepting application/x-ndjson are streamed every entry from the index on, one per line, up to limit if
+//	@Description	given.
+//	@Tags			History
+//	@Produce		json
+//	@Produce		x-ndjson
+//	@Param			from	query		int	false	"Index of the first entry to return, defaults to 0"
+//	@Param			limit	query		int	false	"Maximum number of entries to return"
+//	@Success		200		{object}	ListHistoryResponse
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/v1/history [get]
+func (r *HistoryRouter) ListHistory(c *gin.Context) {
+	var from int64
+	if value := GetQueryValue(c, FromParam); value != nil {
+		f, err := strconv.ParseInt(*value, 10, 64)
+		if err != nil || f < 0 {
+			LoggingRespondErrMsg(c, "invalid from param", http.StatusBadRequest)
+			return
+		}
+		from = f
+	}
+	var limit int
+	if value := GetQueryValue(c, LimitParam); value != nil {
+		l, err := strconv.Atoi(*value)
+		if err != nil || l <= 0 {
+			LoggingRespondErrMsg(c, "invalid limit param", http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+
+	if acceptsNDJSON(c) {
+		var streamed int
+		streamNDJSON(c, func(ctx context.Context) ([]pkarr.HistoryEntry, bool, error) {
+			entries, err := r.service.ListHistory(ctx, from, streamPageLimit(limit, streamed))
+			if err != nil {
+				return nil, false, err
+			}
+			from, streamed = from+int64(len(entries)), streamed+len(entries)
+			return entries, len(entries) > 0 && (limit <= 0 || streamed < limit), nil
+		})
+		return
+	}
+
+	entries, err := r.service.ListHistory(c, from, limit)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to list history", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []pkarr.HistoryEntry{}
+	}
+	Respond(c, ListHistoryResponse{Entries: entries}, http.StatusOK)
+}
+
+// GetHistoryCheckpoint godoc
+//
+//	@Summary		Get the latest checkpoint of the history log
+//	@Description	Get the latest signed Merkle root of the history log, computed according to RFC 6962
+//	@Tags			History
+//	@Produce		json
+//	@Success		200	{object}	service.HistoryCheckpoint
+//	@Failure		404	{object}	Problem	"Not found"
+//	@Router			/v1/history/checkpoint [get]
+func (r *HistoryRouter) GetHistoryCheckpoint(c *gin.Context) {
+	checkpoint := r.service.GetHistoryCheckpoint()
+	if checkpoint == nil {
+		LoggingRespondErrMsg(c, "no history checkpoint yet", http.StatusNotFound)
+		return
+	}
+	Respond(c, checkpoint, http.StatusOK)
+}
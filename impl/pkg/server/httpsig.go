@@ -0,0 +1,75 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/httpsig"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/ucan"
+)
+
+const (
+	// AbilityPublish is the capability to publish the records of a DID, AbilityDelete to delete them from the gateway,
+	// and AbilityTransfer to export them for a transfer to another gateway, which its controller may delegate with a
+	// capability token
+	AbilityPublish  = "pkarr/publish"
+	AbilityDelete   = "pkarr/delete"
+	AbilityTransfer = "pkarr/transfer"
+)
+
+// KeyOwnerAuth is middleware which requires the request to carry an HTTP Message Signature by the ed25519 key of the
+// z-base-32 encoded ID in its path, identified by that ID as its keyid, so only the owner of a DID can make it.
+// Alternatively, the request may be signed by a delegate of the owner, identified by its own z-base-32 encoded ID as
+// the keyid, presenting a capability token as a bearer token which delegates it the ability from the DID.
+func KeyOwnerAuth(ability string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := GetParam(c, IDParam)
+		if id == nil || *id == "" {
+			LoggingRespondErrMsg(c, "missing id param", http.StatusBadRequest)
+			c.Abort()
+			return
+		}
+		key, err := util.Z32Decode(*id)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			LoggingRespondError(c, errMalformedID, http.StatusBadRequest)
+			c.Abort()
+			return
+		}
+		now := time.Now()
+		err = httpsig.Verify(c.Request, *id, key, now)
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && errors.Is(err, httpsig.ErrMissingSignature) {
+			err = verifyDelegate(c.Request, token, ucan.Capability{With: did.Prefix + ":" + *id, Can: ability}, now)
+		}
+		if err != nil {
+			LoggingRespondErrWithMsg(c, err, "request must be signed by the key of the id or its delegate", http.StatusUnauthorized)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// verifyDelegate verifies the capability token delegates the capability to its audience, and that the request is
+// signed by the audience's key
+func verifyDelegate(req *http.Request, token string, capability ucan.Capability, now time.Time) error {
+	t, err := ucan.Parse(token)
+	if err != nil {
+		return err
+	}
+	if err = t.Allows(capability, now); err != nil {
+		return err
+	}
+	delegateKey, err := ucan.PublicKey(t.Audience)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ucan.ErrInvalidToken, err)
+	}
+	return httpsig.Verify(req, strings.TrimPrefix(t.Audience, did.Prefix+":"), delegateKey, now)
+}
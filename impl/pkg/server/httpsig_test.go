@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/httpsig"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/ucan"
+)
+
+func TestDeleteRecord(t *testing.T) {
+	pkarrSvc := testPKARRService(t)
+	pkarrRouter, err := NewPkarrRouter(pkarrSvc)
+	require.NoError(t, err)
+	handler := gin.New()
+	handler.PUT("/:id", pkarrRouter.PutRecord)
+	handler.DELETE("/:id", KeyOwnerAuth(AbilityDelete), pkarrRouter.DeleteRecord)
+
+	sk, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{})
+	require.NoError(t, err)
+	packet, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+	require.NoError(t, err)
+	bep44Put, err := dht.CreatePKARRPublishRequest(sk, *packet)
+	require.NoError(t, err)
+	var seqBuf [8]byte
+	binary.BigEndian.PutUint64(seqBuf[:], uint64(bep44Put.Seq))
+	suffix, err := did.DHT(doc.ID).Suffix()
+	require.NoError(t, err)
+
+	put := func() {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("%s/%s", testServerURL, suffix),
+			bytes.NewReader(append(bep44Put.Sig[:], append(seqBuf[:], bep44Put.V.([]byte)...)...)))
+		handler.ServeHTTP(w, req)
+		require.True(t, is2xxResponse(w.Code))
+	}
+	put()
+
+	deleteRequest := func(key ed25519.PrivateKey) *http.Request {
+		req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("%s/%s", testServerURL, suffix), nil)
+		if key != nil {
+			require.NoError(t, httpsig.Sign(req, suffix, key, time.Now()))
+		}
+		return req
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, deleteRequest(nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		_, other, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, deleteRequest(other))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("signed by a delegate", func(t *testing.T) {
+		delegatePub, delegateKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		delegate := util.Z32Encode(delegatePub)
+		delegation := func(can string) string {
+			token, err := ucan.Issue(sk, ucan.Payload{
+				Issuer:       doc.ID,
+				Audience:     did.Prefix + ":" + delegate,
+				Expiry:       time.Now().Add(time.Hour).Unix(),
+				Attenuations: []ucan.Capability{{With: doc.ID, Can: can}},
+			})
+			require.NoError(t, err)
+			return token
+		}
+
+		for _, test := range []struct {
+			token  string
+			status int
+		}{
+			{token: "", status: http.StatusUnauthorized},
+			{token: delegation(AbilityPublish), status: http.StatusUnauthorized},
+			{token: delegation("pkarr/*") + "x", status: http.StatusUnauthorized},
+			{token: delegation("pkarr/*"), status: http.StatusNoContent},
+		} {
+			req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("%s/%s", testServerURL, suffix), nil)
+			require.NoError(t, httpsig.Sign(req, delegate, delegateKey, time.Now()))
+			req.Header.Set("Authorization", "Bearer "+test.token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, test.status, w.Code)
+		}
+	})
+
+	t.Run("replayed with a query added", func(t *testing.T) {
+		req := deleteRequest(sk)
+		req.URL.RawQuery = "salt=c2FsdA"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("signed by the owner", func(t *testing.T) {
+		put()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, deleteRequest(sk))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, deleteRequest(sk))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	CursorParam string = "cursor"
+	LimitParam  string = "limit"
+	DomainParam string = "domain"
+	PrefixParam string = "prefix"
+)
+
+// indexQueryParams maps the supported query parameters to the document field they filter on
+var indexQueryParams = map[string]pkarr.IndexField{
+	"serviceType":            pkarr.ServiceTypeField,
+	"serviceEndpoint":        pkarr.ServiceEndpointField,
+	"verificationMethodType": pkarr.VerificationMethodTypeField,
+	"type":                   pkarr.TypeField,
+}
+
+// IndexRouter is the router for the Index API, which queries DIDs by the contents of their documents
+type IndexRouter struct {
+	service *service.PkarrService
+}
+
+// NewIndexRouter returns a new instance of the Index router
+func NewIndexRouter(service *service.PkarrService) (*IndexRouter, error) {
+	return &IndexRouter{service: service}, nil
+}
+
+// QueryIndexResponse is the response to a query of the document index
+type QueryIndexResponse struct {
+	DIDs []string `json:"dids"`
+	// Next is the cursor for the next page of results, absent on the last page
+	Next string `json:"next,omitempty"`
+}
+
+// QueryIndex godoc
+//
+//	@Summary		Query DIDs by the contents of their documents
+//	@Description	Query the DIDs whose documents have the given value. Exactly one query parameter must be provided.
+//	@Description	Requests accepting application/x-ndjson are streamed every DID after cursor, one per line, up to
+//	@Description	limit if given.
+//	@Tags			Index
+//	@Produce		json
+//	@Produce		x-ndjson
+//	@Param			serviceType				query		string	false	"Type of a service, e.g. DecentralizedWebNode"
+//	@Param			serviceEndpoint			query		string	false	"Endpoint of a service"
+//	@Param			verificationMethodType	query		string	false	"Type of a verification method"
+//	@Param			type					query		int		false	"Type index of the DID"
+//	@Param			cursor					query		string	false	"Cursor returned with the previous page of results"
+//	@Param			limit					query		int		false	"Maximum number of results to return, up to 1000"
+//	@Success		200						{object}	QueryIndexResponse
+//	@Failure		400						{object}	Problem	"Bad request"
+//	@Failure		500						{object}	Problem	"Internal server error"
+//	@Router			/v1/index [get]
+func (r *IndexRouter) QueryIndex(c *gin.Context) {
+	var query pkarr.DocumentQuery
+	for param, paramField := range indexQueryParams {
+		if got := GetQueryValue(c, param); got != nil {
+			if query.Field != "" {
+				LoggingRespondErrMsg(c, "only one query parameter may be provided", http.StatusBadRequest)
+				return
+			}
+			query.Field, query.Value = paramField, *got
+		}
+	}
+	if query.Field == "" {
+		LoggingRespondErrMsg(c, "missing query parameter", http.StatusBadRequest)
+		return
+	}
+	r.respondQuery(c, query)
+}
+
+// QueryEndpoints godoc
+//
+//	@Summary		Query DIDs by their service endpoints
+//	@Description	Query the DIDs with a service endpoint on the given domain or starting with the given URL prefix.
+//	@Description	Exactly one of domain or prefix must be provided.
+//	@Description	Requests accepting application/x-ndjson are streamed every DID after cursor, one per line, up to
+//	@Description	limit if given.
+//	@Tags			Index
+//	@Produce		json
+//	@Produce		x-ndjson
+//	@Param			domain	query		string	false	"Domain of a service endpoint, e.g. example.com"
+//	@Param			prefix	query		string	false	"URL prefix of a service endpoint, e.g. https://example.com/dwn"
+//	@Param			cursor	query		string	false	"Cursor returned with the previous page of results"
+//	@Param			limit	query		int		false	"Maximum number of results to return, up to 1000"
+//	@Success		200		{object}	QueryIndexResponse
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/v1/index/endpoints [get]
+func (r *IndexRouter) QueryEndpoints(c *gin.Context) {
+	domain := GetQueryValue(c, DomainParam)
+	prefix := GetQueryValue(c, PrefixParam)
+	var query pkarr.DocumentQuery
+	switch {
+	case domain != nil && prefix != nil:
+		LoggingRespondErrMsg(c, "only one of domain or prefix may be provided", http.StatusBadRequest)
+		return
+	case domain != nil:
+		query = pkarr.DocumentQuery{Field: pkarr.ServiceDomainField, Value: *domain}
+	case prefix != nil:
+		query = pkarr.DocumentQuery{Field: pkarr.ServiceEndpointField, Value: *prefix, Prefix: true}
+	default:
+		LoggingRespondErrMsg(c, "missing domain or prefix param", http.StatusBadRequest)
+		return
+	}
+	r.respondQuery(c, query)
+}
+
+// respondQuery applies the pagination params of the request to the query and responds with its results
+func (r *IndexRouter) respondQuery(c *gin.Context, query pkarr.DocumentQuery) {
+	if cursor := GetQueryValue(c, CursorParam); cursor != nil {
+		query.After = *cursor
+	}
+	if limit := GetQueryValue(c, LimitParam); limit != nil {
+		l, err := strconv.Atoi(*limit)
+		if err != nil || l <= 0 {
+			LoggingRespondErrMsg(c, "invalid limit param", http.StatusBadRequest)
+			return
+		}
+		query.Limit = l
+	}
+
+	if acceptsNDJSON(c) {
+		limit, streamed := query.Limit, 0
+		streamNDJSON(c, func(ctx context.Context) ([]string, bool, error) {
+			query.Limit = streamPageLimit(limit, streamed)
+			result, err := r.service.QueryDocuments(ctx, query)
+			if err != nil {
+				return nil, false, err
+			}
+			query.After, streamed = result.Next, streamed+len(result.DIDs)
+			return result.DIDs, result.Next != "" && (limit <= 0 || streamed < limit), nil
+		})
+		return
+	}
+
+	result, err := r.service.QueryDocuments(c, query)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to query index", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, QueryIndexResponse{DIDs: result.DIDs, Next: result.Next}, http.StatusOK)
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+// Version, Commit, and BuildDate describe the build of the gateway, and are set at link time, e.g. with
+// -ldflags="-X github.com/TBD54566975/did-dht-method/impl/pkg/server.Commit=<hash>". Unset values fall back to the
+// module version and VCS revision and time embedded by the go toolchain, if any.
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+const (
+	// NetworkMainline is the network of a gateway joining the public BitTorrent Mainline DHT
+	NetworkMainline string = "mainline"
+	// NetworkPrivate is the network of a gateway running in an isolated DHT network
+	NetworkPrivate string = "private"
+)
+
+// GetInfoResponse describes the build and configuration of the gateway, without any secrets
+type GetInfoResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"buildDate,omitempty"`
+	// Role is the workload the gateway runs: all, resolver, or publisher
+	Role config.Role `json:"role"`
+	// Network is the DHT network the gateway joins, mainline or private, and NetworkID the ID of a private network
+	Network   string `json:"network"`
+	NetworkID string `json:"networkId,omitempty"`
+	// Storage and Cache are the schemes of the storage and cache URIs, e.g. postgres and redis
+	Storage string `json:"storage"`
+	Cache   string `json:"cache"`
+	// Features are the optional features of the gateway, and whether each is enabled
+	Features map[string]bool `json:"features"`
+	// DID is the did:dht the gateway announces itself with, if it does
+	DID string `json:"did,omitempty"`
+}
+
+// NewInfo returns the description of the build of the gateway and the given configuration
+func NewInfo(cfg *config.Config) GetInfoResponse {
+	info := GetInfoResponse{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		Role:      cfg.ServerConfig.Role,
+		Network:   NetworkMainline,
+		Storage:   uriScheme(cfg.ServerConfig.StorageURI),
+		Cache:     uriScheme(cfg.PkarrConfig.CacheURI),
+		Features: map[string]bool{
+			"adaptiveCacheTTL":   cfg.PkarrConfig.AdaptiveCacheTTL,
+			"admin":              cfg.AdminConfig.Token != "",
+			"announce":           cfg.ServerConfig.Announce,
+			"adoptOnResolve":     cfg.PkarrConfig.AdoptOnResolve,
+			"archive":            cfg.ArchiveConfig.Enabled,
+			"assignSeq":          cfg.PkarrConfig.AssignSeq,
+			"attestation":        cfg.AttestationConfig.Enabled,
+			"canary":             cfg.ServerConfig.CanaryCRON != "" && cfg.ServerConfig.Role.Publishes(),
+			"cdc":                cfg.CDCConfig.Sink != "",
+			"coldStart":          len(cfg.PkarrConfig.ColdStartGateways) > 0 && cfg.PkarrConfig.ColdStartHours > 0,
+			"compression":        cfg.ServerConfig.CompressRecords,
+			"didWeb":             cfg.DIDWebConfig.Enabled,
+			"dns":                cfg.DNSConfig.Enabled,
+			"encryption":         len(cfg.EncryptionConfig.Keys) > 0 || cfg.EncryptionConfig.KeysFile != "",
+			"failover":           cfg.ServerConfig.SecondaryStorageURI != "",
+			"fallbackDiscovery":  cfg.PkarrConfig.FallbackRegistryURL != "" || len(cfg.PkarrConfig.FallbackGatewayDIDs) > 0,
+			"feed":               cfg.FeedConfig.Enabled,
+			"hashKeys":           cfg.EncryptionConfig.HashKeys,
+			"history":            cfg.HistoryConfig.Enabled,
+			"index":              cfg.IndexConfig.Enabled,
+			"legacyRoutes":       cfg.APIConfig.LegacyRoutes,
+			"lintPublishes":      cfg.PkarrConfig.LintPublishes,
+			"maintenance":        cfg.ServerConfig.MaintenanceCRON != "",
+			"maxClockDrift":      cfg.PkarrConfig.MaxFutureSeqSeconds > 0,
+			"privacy":            cfg.PrivacyConfig.Enabled,
+			"publishJournal":     cfg.PkarrConfig.PublishJournal,
+			"recordCacheTTL":     cfg.PkarrConfig.RecordCacheTTL,
+			"republish":          cfg.PkarrConfig.RepublishCRON != "",
+			"republishJitter":    cfg.PkarrConfig.RepublishJitterSeconds > 0 || cfg.PkarrConfig.RepublishSmearSeconds > 0,
+			"requirePublishAuth": cfg.PkarrConfig.RequirePublishAuth,
+			"signResponses":      cfg.AttestationConfig.SignResponses,
+			"skipConfirmed":      cfg.PkarrConfig.RepublishSkipConfirmedSeconds > 0,
+			"slaSummaries":       cfg.SLAConfig.WebhookURL != "" || (cfg.SLAConfig.SMTPURL != "" && len(cfg.SLAConfig.EmailTo) > 0),
+			"swaggerUI":          cfg.DocsConfig.SwaggerUI,
+			"tenants":            len(cfg.TenantConfig.Tenants) > 0,
+			"tls":                cfg.TLSConfig.CertFile != "",
+			"versionPruning":     cfg.RetentionConfig.MaxVersionsPerRecord > 0 || cfg.RetentionConfig.MaxVersions > 0,
+		},
+	}
+	if cfg.DHTConfig.Private {
+		info.Network = NetworkPrivate
+		info.NetworkID = cfg.DHTConfig.NetworkID
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
+
+// LogInfo logs the build and configuration of the gateway as a structured startup banner
+func LogInfo(info GetInfoResponse) {
+	fields := logrus.Fields{
+		"version":   info.Version,
+		"commit":    info.Commit,
+		"buildDate": info.BuildDate,
+		"role":      info.Role,
+		"network":   info.Network,
+		"storage":   info.Storage,
+		"cache":     info.Cache,
+	}
+	if info.NetworkID != "" {
+		fields["networkId"] = info.NetworkID
+	}
+	if info.DID != "" {
+		fields["did"] = info.DID
+	}
+	var enabled []string
+	for feature, on := range info.Features {
+		if on {
+			enabled = append(enabled, feature)
+		}
+	}
+	sort.Strings(enabled)
+	fields["features"] = enabled
+	logrus.WithFields(fields).Info("did:dht gateway")
+}
+
+// Info godoc
+//
+//	@Summary		Gateway info
+//	@Description	Info describes the build of the gateway, its role, DHT network, storage and cache backends, and
+//	@Description	which optional features are enabled, for directories of gateways and for debugging deployments
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	GetInfoResponse
+//	@Router			/info [get]
+func Info(info GetInfoResponse) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		Respond(c, info, http.StatusOK)
+	}
+}
+
+// uriScheme returns the scheme of the URI, leaving out any credentials or addresses it contains
+func uriScheme(uri string) string {
+	scheme, _, _ := strings.Cut(uri, "://")
+	return scheme
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+func TestNewInfo(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.ServerConfig.StorageURI = "postgres://user:secret@db:5432/diddht"
+	cfg.DHTConfig.Private = true
+	cfg.DHTConfig.NetworkID = "internal"
+	cfg.IndexConfig.Enabled = true
+
+	info := NewInfo(&cfg)
+	assert.NotEmpty(t, info.Version)
+	assert.Equal(t, config.RoleAll, info.Role)
+	assert.Equal(t, NetworkPrivate, info.Network)
+	assert.Equal(t, "internal", info.NetworkID)
+	assert.Equal(t, "postgres", info.Storage, "credentials aren't reported")
+	assert.Equal(t, "memory", info.Cache)
+	assert.True(t, info.Features["index"])
+	assert.False(t, info.Features["dns"])
+	assert.Contains(t, info.Features, "attestation")
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// LabelParam is the query param filtering labeled records by a label
+const LabelParam string = "label"
+
+// LabelsRouter is the router for the private labels operators attach to records
+type LabelsRouter struct {
+	service *service.PkarrService
+}
+
+// NewLabelsRouter returns a new instance of the Labels router
+func NewLabelsRouter(service *service.PkarrService) (*LabelsRouter, error) {
+	return &LabelsRouter{service: service}, nil
+}
+
+// ListLabelsResponse is the list of labeled records
+type ListLabelsResponse struct {
+	Records []service.LabeledRecord `json:"records"`
+}
+
+// RecordLabelsResponse is the labels of a record
+type RecordLabelsResponse struct {
+	Labels []string `json:"labels"`
+}
+
+// LabelRecordRequest is the request to replace the labels of a record
+type LabelRecordRequest struct {
+	Labels []string `json:"labels"`
+}
+
+// ListLabels godoc
+//
+//	@Summary		List labeled records
+//	@Description	List the records operators attached labels to, or only those with the given label
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			label	query		string	false	"Label the records have, such as pinned"
+//	@Success		200		{object}	ListLabelsResponse
+//	@Failure		401		{object}	Problem	"Unauthorized"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/admin/labels [get]
+func (r *LabelsRouter) ListLabels(c *gin.Context) {
+	var label string
+	if value := GetQueryValue(c, LabelParam); value != nil {
+		label = *value
+	}
+	records, err := r.service.ListLabeledRecords(c, label)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to list labeled records", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, ListLabelsResponse{Records: records}, http.StatusOK)
+}
+
+// GetRecordLabels godoc
+//
+//	@Summary		Get the labels of a record
+//	@Description	Get the labels operators attached to the record of an ID and optional salt
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			id		path		string	true	"ID of the record"
+//	@Param			salt	query		string	false	"base64url encoded salt of the record, up to 64 bytes"
+//	@Success		200		{object}	RecordLabelsResponse
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		401		{object}	Problem	"Unauthorized"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/admin/labels/{id} [get]
+func (r *LabelsRouter) GetRecordLabels(c *gin.Context) {
+	id := getKeyParam(c)
+	if id == nil {
+		return
+	}
+	salt, err := getSalt(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid salt", http.StatusBadRequest)
+		return
+	}
+	labels, err := r.service.GetRecordLabels(*id, salt)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to get record labels", http.StatusInternalServerError)
+		return
+	}
+	if labels == nil {
+		labels = []string{}
+	}
+	Respond(c, RecordLabelsResponse{Labels: labels}, http.StatusOK)
+}
+
+// LabelRecord godoc
+//
+//	@Summary		Label a record
+//	@Description	Replace the labels of the record of an ID and optional salt, which needn't be stored. Labels are 1
+//	@Description	to 64 letters, digits, and the separators ':', '.', '_', '/', and '-', such as customer:acme.
+//	@Tags			Admin
+//	@Accept			json
+//	@Security		AdminToken
+//	@Param			id		path	string				true	"ID of the record"
+//	@Param			salt	query	string				false	"base64url encoded salt of the record, up to 64 bytes"
+//	@Param			request	body	LabelRecordRequest	true	"Labels of the record"
+//	@Success		200
+//	@Failure		400	{object}	Problem	"Bad request"
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/labels/{id} [put]
+func (r *LabelsRouter) LabelRecord(c *gin.Context) {
+	id := getKeyParam(c)
+	if id == nil {
+		return
+	}
+	salt, err := getSalt(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid salt", http.StatusBadRequest)
+		return
+	}
+	var request LabelRecordRequest
+	if err = Decode(c.Request, &request); err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid label record request", http.StatusBadRequest)
+		return
+	}
+	if err = r.service.LabelRecord(c, *id, salt, request.Labels); err != nil {
+		if errors.Is(err, service.ErrInvalidLabel) {
+			LoggingRespondErrWithMsg(c, err, "invalid label record request", http.StatusBadRequest)
+			return
+		}
+		LoggingRespondErrWithMsg(c, err, "failed to label record", http.StatusInternalServerError)
+		return
+	}
+	ResponseStatus(c, http.StatusOK)
+}
+
+// UnlabelRecord godoc
+//
+//	@Summary		Remove the labels of a record
+//	@Description	Remove all labels of the record of an ID and optional salt
+//	@Tags			Admin
+//	@Security		AdminToken
+//	@Param			id		path	string	true	"ID of the record"
+//	@Param			salt	query	string	false	"base64url encoded salt of the record, up to 64 bytes"
+//	@Success		200
+//	@Failure		400	{object}	Problem	"Bad request"
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/labels/{id} [delete]
+func (r *LabelsRouter) UnlabelRecord(c *gin.Context) {
+	id := getKeyParam(c)
+	if id == nil {
+		return
+	}
+	salt, err := getSalt(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid salt", http.StatusBadRequest)
+		return
+	}
+	if err = r.service.LabelRecord(c, *id, salt, nil); err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to remove record labels", http.StatusInternalServerError)
+		return
+	}
+	ResponseStatus(c, http.StatusOK)
+}
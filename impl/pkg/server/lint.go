@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+// ValidateRecordResponse is the lint warnings of a valid record's DID Document
+type ValidateRecordResponse struct {
+	Warnings []did.LintWarning `json:"warnings"`
+}
+
+// ValidateRecord godoc
+//
+//	@Summary		Validate a Pkarr record without publishing it
+//	@Description	Validate a record like a published record, without storing or publishing it, and lint its DID
+//	@Description	Document: warnings point out oversized service endpoints, keys without a purpose, relationships
+//	@Description	listing a key twice, and packets larger than their canonical encoding, none of which make the
+//	@Description	record invalid. Records which aren't DID Documents have no warnings.
+//	@Tags			Pkarr
+//	@Accept			octet-stream
+//	@Produce		json
+//	@Param			id		path		string	true	"ID of the record to validate"
+//	@Param			salt	query		string	false	"base64url encoded salt of the record, up to 64 bytes"
+//	@Param			request	body		[]byte	true	"64 bytes sig, 8 bytes u64 big-endian seq, 0-1000 bytes of v."
+//	@Success		200		{object}	ValidateRecordResponse
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		413		{object}	Problem	"Packet too large"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/v1/{id}/validate [post]
+func (r *PkarrRouter) ValidateRecord(c *gin.Context) {
+	id, request := getPutRequest(c)
+	if request == nil {
+		return
+	}
+	warnings, err := r.service.ValidatePkarr(*id, *request)
+	if err != nil {
+		if status, ok := publishErrorStatus(err); ok {
+			LoggingRespondErrWithMsg(c, err, "invalid pkarr record", status)
+			return
+		}
+		LoggingRespondErrWithMsg(c, err, "failed to validate pkarr record", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, ValidateRecordResponse{Warnings: warnings}, http.StatusOK)
+}
+
+// lintCodes returns the distinct codes of the warnings, comma separated, in the order they were found
+func lintCodes(warnings []did.LintWarning) string {
+	var codes []string
+	for _, warning := range warnings {
+		if !slices.Contains(codes, warning.Code) {
+			codes = append(codes, warning.Code)
+		}
+	}
+	return strings.Join(codes, ",")
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// MaintenanceRouter is the router for reclaiming the space left behind by deleted records
+type MaintenanceRouter struct {
+	service *service.PkarrService
+}
+
+// NewMaintenanceRouter returns a new instance of the Maintenance router
+func NewMaintenanceRouter(service *service.PkarrService) (*MaintenanceRouter, error) {
+	return &MaintenanceRouter{service: service}, nil
+}
+
+// GetMaintenanceStats godoc
+//
+//	@Summary		Get storage maintenance stats
+//	@Description	Get the counts of the storage maintenance run since the gateway started, with the outcome of the last
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	service.MaintenanceStats
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		404	{object}	Problem	"Storage needs no maintenance"
+//	@Router			/admin/maintenance [get]
+func (r *MaintenanceRouter) GetMaintenanceStats(c *gin.Context) {
+	stats, err := r.service.GetMaintenanceStats()
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to get maintenance stats", http.StatusNotFound)
+		return
+	}
+	Respond(c, stats, http.StatusOK)
+}
+
+// MaintainStorage godoc
+//
+//	@Summary		Maintain storage
+//	@Description	Reclaim the space left behind by deleted records now. Bolt is compacted into a fresh file, pausing
+//	@Description	reads and writes while it is, and postgres has the rows of deleted records deleted in batches.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	pkarr.Maintenance
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		404	{object}	Problem	"Storage needs no maintenance"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/maintenance [post]
+func (r *MaintenanceRouter) MaintainStorage(c *gin.Context) {
+	maintenance, err := r.service.MaintainStorage(c)
+	if err != nil {
+		if errors.Is(err, service.ErrMaintenanceUnsupported) {
+			LoggingRespondErrWithMsg(c, err, "failed to maintain storage", http.StatusNotFound)
+			return
+		}
+		LoggingRespondErrWithMsg(c, err, "failed to maintain storage", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, maintenance, http.StatusOK)
+}
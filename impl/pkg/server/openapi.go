@@ -0,0 +1,29 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/docs"
+)
+
+// GetSwaggerSpec serves the Swagger 2.0 specification generated from the handler annotations
+func GetSwaggerSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/yaml", docs.SwaggerYAML)
+}
+
+// GetOpenAPISpec godoc
+//
+//	@Summary		Get the OpenAPI specification
+//	@Description	Get the OpenAPI 3 specification of the API, converted from the specification generated from the
+//	@Description	handler annotations
+//	@Tags			Docs
+//	@Produce		json
+//	@Success		200	{object}	map[string]any
+//	@Router			/openapi.json [get]
+func GetOpenAPISpec(spec map[string]any) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		Respond(c, spec, http.StatusOK)
+	}
+}
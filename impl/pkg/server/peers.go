@@ -0,0 +1,136 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+const (
+	// AddrParam is the path parameter of the IP address of DHT peers
+	AddrParam string = "addr"
+
+	// DurationParam is the query parameter of how long to ban DHT peers for, e.g. 1h
+	DurationParam string = "duration"
+)
+
+// PeerRouter is the router for the reputation of the DHT peers the gateway queries
+type PeerRouter struct {
+	service *service.PkarrService
+}
+
+// NewPeerRouter returns a new instance of the Peer router
+func NewPeerRouter(service *service.PkarrService) (*PeerRouter, error) {
+	return &PeerRouter{service: service}, nil
+}
+
+// ListPeers godoc
+//
+//	@Summary		List DHT peer reputation
+//	@Description	List the outcomes of DHT queries and the bans of misbehaving peers since startup, with the addresses
+//	@Description	which are banned or failed their last query
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	dht.ReputationStats
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/peers [get]
+func (r *PeerRouter) ListPeers(c *gin.Context) {
+	stats, err := r.service.GetPeerReputation()
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to list dht peers", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, stats, http.StatusOK)
+}
+
+// BanPeer godoc
+//
+//	@Summary		Ban a DHT peer
+//	@Description	Ban the DHT peers at an IP address, neither querying nor answering them until the ban expires
+//	@Tags			Admin
+//	@Security		AdminToken
+//	@Param			addr		path	string	true	"IP address of the peers to ban"
+//	@Param			duration	query	string	false	"How long to ban the peers for, e.g. 1h, the configured ban duration if not set"
+//	@Success		200
+//	@Failure		400	{object}	Problem	"Bad request"
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/peers/{addr} [put]
+func (r *PeerRouter) BanPeer(c *gin.Context) {
+	ip := getAddrParam(c)
+	if ip == nil {
+		return
+	}
+	duration, err := getDuration(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid duration", http.StatusBadRequest)
+		return
+	}
+	if err = r.service.BanPeer(ip, duration); err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to ban dht peer", http.StatusInternalServerError)
+		return
+	}
+	ResponseStatus(c, http.StatusOK)
+}
+
+// UnbanPeer godoc
+//
+//	@Summary		Unban a DHT peer
+//	@Description	Lift the ban of the DHT peers at an IP address and reset their score
+//	@Tags			Admin
+//	@Security		AdminToken
+//	@Param			addr	path	string	true	"IP address of the peers to unban"
+//	@Success		200
+//	@Failure		400	{object}	Problem	"Bad request"
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/peers/{addr} [delete]
+func (r *PeerRouter) UnbanPeer(c *gin.Context) {
+	ip := getAddrParam(c)
+	if ip == nil {
+		return
+	}
+	if err := r.service.UnbanPeer(ip); err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to unban dht peer", http.StatusInternalServerError)
+		return
+	}
+	ResponseStatus(c, http.StatusOK)
+}
+
+// getAddrParam reads the addr path param as an IP address, responding with an error if invalid
+func getAddrParam(c *gin.Context) net.IP {
+	addr := GetParam(c, AddrParam)
+	if addr == nil || *addr == "" {
+		LoggingRespondErrMsg(c, "missing addr param", http.StatusBadRequest)
+		return nil
+	}
+	ip := net.ParseIP(*addr)
+	if ip == nil {
+		LoggingRespondErrMsg(c, "addr param is not an ip address", http.StatusBadRequest)
+		return nil
+	}
+	return ip
+}
+
+// getDuration reads the optional duration query param, such as 1h
+func getDuration(c *gin.Context) (time.Duration, error) {
+	param := GetQueryValue(c, DurationParam)
+	if param == nil {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(*param)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, errors.New("duration must be positive")
+	}
+	return duration, nil
+}
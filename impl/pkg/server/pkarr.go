@@ -2,14 +2,57 @@ package server
 
 import (
 	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/binary"
+	"errors"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 
-	"github.com/TBD54566975/did-dht-method/internal/util"
-	"github.com/TBD54566975/did-dht-method/pkg/service"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+const (
+	// AttestationHeader is the response header carrying the gateway's signed attestation that it served a record
+	AttestationHeader string = "Gateway-Attestation"
+
+	// ConsistencyTokenHeader is the response header of the opaque token identifying the seq of a published or resolved
+	// record, and the request header clients send it back in to resolve a record at least that new through any replica
+	ConsistencyTokenHeader string = "Consistency-Token"
+
+	// ResolutionConflictHeader is the response header set to true if DHT nodes held records of different seqs for the
+	// resolved ID, and ResolutionSeqsHeader is the comma separated list of those seqs, in ascending order. They are only
+	// set when the gateway searches the DHT for the highest seq.
+	ResolutionConflictHeader string = "Resolution-Conflict"
+	ResolutionSeqsHeader     string = "Resolution-Seqs"
+
+	// LintWarningsHeader is the response header of the comma separated codes of the lint warnings of a published DID
+	// Document, such as missing_key_purpose, whose details the validate endpoint returns
+	LintWarningsHeader string = "Lint-Warnings"
+
+	// ResolutionPartialHeader is the response header set to true if the DHT traversal resolving the record was cut
+	// short by its budget or the request's deadline, so the record is the best found so far and may be stale
+	ResolutionPartialHeader string = "Resolution-Partial"
+
+	// RetryAfterHeader is the response header of the seconds until a key cooling down may update its records again, or
+	// until a replica behind a consistency token should be asked again
+	RetryAfterHeader string = "Retry-After"
+
+	// SaltParam is the query parameter of the base64url encoded BEP-44 salt of a record, for keys with multiple records
+	SaltParam string = "salt"
+
+	// WaitParam is the query parameter of how long to wait for a record which isn't found to be published, e.g. 30s
+	WaitParam string = "wait"
+
+	// VersionTimeParam is the query parameter of the RFC 3339 time to resolve the version of a record current at,
+	// e.g. 2024-05-01T00:00:00Z
+	VersionTimeParam string = "versionTime"
 )
 
 // PkarrRouter is the router for the Pkarr API
@@ -29,12 +72,28 @@ func NewPkarrRouter(service *service.PkarrService) (*PkarrRouter, error) {
 //	@Tags			Pkarr
 //	@Accept			octet-stream
 //	@Produce		octet-stream
-//	@Param			id	path		string	true	"ID to get"
-//	@Success		200	{array}		byte	"64 bytes sig, 8 bytes u64 big-endian seq, 0-1000 bytes of v."
-//	@Failure		400	{string}	string	"Bad request"
-//	@Failure		404	{string}	string	"Not found"
-//	@Failure		500	{string}	string	"Internal server error"
-//	@Router			/{id} [get]
+//	@Produce		application/cbor
+//	@Param			id		path		string	true	"ID to get"
+//	@Param			salt	query		string	false	"base64url encoded salt of the record, up to 64 bytes"
+//	@Param			wait	query		string	false	"Duration to wait for the record to be published if not found, e.g. 30s"
+//	@Param			versionTime			query	string	false	"RFC 3339 time to resolve the stored version of the record current at, e.g. 2024-05-01T00:00:00Z"
+//	@Param			Consistency-Token	header	string	false	"Token returned by an earlier publish or resolution, to resolve a record at least that new"
+//	@Success		200		{array}		byte	"64 bytes sig, 8 bytes u64 big-endian seq, 0-1000 bytes of v, or a RecordEnvelope if accepting application/cbor."
+//	@Header			200		{string}	Consistency-Token	"Token identifying the seq of the record"
+//	@Header			200		{string}	Gateway-Attestation	"Signed attestation that the gateway served the record, if enabled"
+//	@Header			200		{boolean}	Resolution-Conflict	"Whether DHT nodes held records of different seqs, if searched"
+//	@Header			200		{boolean}	Resolution-Partial	"True if the DHT traversal was cut short, so the record may be stale"
+//	@Header			200		{string}	Resolution-Seqs		"Comma separated seqs DHT nodes held, if searched"
+//	@Header			200		{string}	Cache-Control		"How long caches may serve the record, as long as the gateway caches it"
+//	@Header			200		{integer}	Age					"Seconds since the gateway cached the record"
+//	@Header			200		{string}	Last-Modified		"Time of the record's seq, as a unix timestamp"
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		404		{object}	Problem	"Not found"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Failure		503		{object}	Problem	"Neither storage nor the DHT hold a record as new as the consistency token"
+//	@Header			503		{integer}	Retry-After	"Seconds until the gateway should be asked again"
+//	@Router			/v1/{id} [get]
+//	@Router			/v1/records/{id} [get]
 func (r *PkarrRouter) GetRecord(c *gin.Context) {
 	id := GetParam(c, IDParam)
 	if id == nil || *id == "" {
@@ -42,16 +101,72 @@ func (r *PkarrRouter) GetRecord(c *gin.Context) {
 		return
 	}
 
-	resp, err := r.service.GetPkarr(c, *id)
+	salt, err := getSalt(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid salt param", http.StatusBadRequest)
+		return
+	}
+
+	wait, err := getWait(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid wait param", http.StatusBadRequest)
+		return
+	}
+
+	versionTime, err := getVersionTime(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid versionTime param", http.StatusBadRequest)
+		return
+	}
+
+	var resp *service.GetPkarrResponse
+	if versionTime != nil {
+		resp, err = r.service.GetSaltedPkarrAt(c, *id, salt, *versionTime)
+	} else if token := c.GetHeader(ConsistencyTokenHeader); token != "" {
+		var seq int64
+		if seq, err = service.ParseConsistencyToken(token, *id, salt); err != nil {
+			LoggingRespondErrWithMsg(c, err, "invalid consistency token header", http.StatusBadRequest)
+			return
+		}
+		resp, err = r.service.GetSaltedPkarrAtLeast(c, *id, salt, seq)
+		if errors.Is(err, service.ErrNotConsistent) {
+			c.Header(RetryAfterHeader, "1")
+			LoggingRespondErrWithMsg(c, err, "pkarr record not yet replicated", http.StatusServiceUnavailable)
+			return
+		}
+	} else {
+		resp, err = r.service.WaitForSaltedPkarr(c, *id, salt, wait)
+	}
 	if err != nil {
 		LoggingRespondErrWithMsg(c, err, "failed to get pkarr record", http.StatusInternalServerError)
 		return
 	}
 	if resp == nil {
+		// the record may be published any moment
+		c.Header(CacheControlHeader, "no-store")
 		LoggingRespondErrMsg(c, "pkarr record not found", http.StatusNotFound)
 		return
 	}
 
+	attestation, err := r.service.AttestPkarr(*id, *resp)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to attest pkarr record", http.StatusInternalServerError)
+		return
+	}
+	if attestation != "" {
+		c.Header(AttestationHeader, attestation)
+	}
+	c.Header(ConsistencyTokenHeader, service.ConsistencyToken(*id, salt, resp.Seq))
+	if resp.Metadata != nil {
+		setResolutionHeaders(c, *resp.Metadata)
+	}
+	setCacheHeaders(c, *resp)
+	c.Writer.Header().Add("Vary", "Accept")
+	if acceptsCBOR(c) {
+		respondRecordEnvelope(c, *resp)
+		return
+	}
+
 	// Convert int64 to uint64 since binary.PutUint64 expects a uint64 value
 	// according to https://github.com/Nuhvi/pkarr/blob/main/design/relays.md#get
 	var seqBuf [8]byte
@@ -68,38 +183,210 @@ func (r *PkarrRouter) GetRecord(c *gin.Context) {
 //	@Tags			Pkarr
 //	@Accept			octet-stream
 //	@Param			id		path	string	true	"ID of the record to put"
+//	@Param			salt	query	string	false	"base64url encoded salt of the record, up to 64 bytes"
+//	@Param			API-Key	header	string	false	"API key of the tenant publishing the record, if tenants are configured"
 //	@Param			request	body	[]byte	true	"64 bytes sig, 8 bytes u64 big-endian seq, 0-1000 bytes of v."
 //	@Success		200
-//	@Failure		400	{string}	string	"Bad request"
-//	@Failure		500	{string}	string	"Internal server error"
-//	@Router			/{id} [put]
+//	@Header			200	{string}	Consistency-Token	"Token to send with later resolutions, through any replica, to resolve a record at least this new"
+//	@Header			200	{string}	Lint-Warnings		"Comma separated codes of the lint warnings of the record's DID Document, if any"
+//	@Header			200	{string}	Quota-Warning		"Comma separated soft quotas, records or bytes, the tenant of the API key is beyond, if any"
+//	@Failure		400	{object}	Problem	"Bad request"
+//	@Failure		401	{object}	Problem	"Verified client certificate, signature by the key of the ID or its delegate, or API key, required"
+//	@Failure		403	{object}	Problem	"Rejected by policy, or beyond a hard quota of the tenant of the API key"
+//	@Failure		409	{object}	Problem	"Stale seq"
+//	@Failure		413	{object}	Problem	"Packet too large"
+//	@Failure		429	{object}	Problem	"Key is cooling down after updating its records too often"
+//	@Header			429		{integer}	Retry-After	"Seconds until the cool-down ends"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Failure		503	{object}	Problem	"Gateway is draining"
+//	@Failure		507	{object}	Problem	"Storage is full"
+//	@Router			/v1/{id} [put]
 func (r *PkarrRouter) PutRecord(c *gin.Context) {
+	id, request := getPutRequest(c)
+	if request == nil {
+		return
+	}
+	quotaWarnings, err := r.service.PublishTenantPkarr(c, c.GetString(TenantKey), *id, *request)
+	if err != nil {
+		var coolDown *service.CoolDownError
+		if errors.As(err, &coolDown) {
+			c.Header(RetryAfterHeader, strconv.FormatInt(max(int64(time.Until(coolDown.Until).Seconds()), 1), 10))
+			LoggingRespondErrWithMsg(c, err, "pkarr record updated too often", http.StatusTooManyRequests)
+			return
+		}
+		var rejected *service.PublishRejectedError
+		if errors.As(err, &rejected) {
+			LoggingRespondErrWithMsg(c, err, "pkarr record rejected", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, service.ErrQuotaExceeded) {
+			LoggingRespondErrWithMsg(c, err, "pkarr record rejected", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, service.ErrDraining) {
+			LoggingRespondErrWithMsg(c, err, "not accepting pkarr records", http.StatusServiceUnavailable)
+			return
+		}
+		if errors.Is(err, service.ErrStorageFull) {
+			LoggingRespondErrWithMsg(c, err, "not accepting new pkarr records", http.StatusInsufficientStorage)
+			return
+		}
+		if status, ok := publishErrorStatus(err); ok {
+			LoggingRespondErrWithMsg(c, err, "invalid pkarr record", status)
+			return
+		}
+		LoggingRespondErrWithMsg(c, err, "failed to publish pkarr record", http.StatusInternalServerError)
+		return
+	}
+
+	if warnings := r.service.LintPkarr(*id, request.V); len(warnings) > 0 {
+		c.Header(LintWarningsHeader, lintCodes(warnings))
+	}
+	if len(quotaWarnings) > 0 {
+		c.Header(QuotaWarningHeader, strings.Join(quotaWarnings, ","))
+	}
+	c.Header(ConsistencyTokenHeader, service.ConsistencyToken(*id, request.Salt, request.Seq))
+	ResponseStatus(c, http.StatusOK)
+}
+
+// DeleteRecord godoc
+//
+//	@Summary		Delete a Pkarr record from the gateway
+//	@Description	Delete the stored record of an ID, with all of its versions, so the gateway stops republishing it.
+//	@Description	The record remains on the DHT until it expires there. The request must carry an HTTP Message
+//	@Description	Signature (RFC 9421) by the ed25519 key of the ID, with the ID as its keyid, covering @method,
+//	@Description	@authority, and @path, created within 5 minutes, or by the key of a delegate presenting a capability
+//	@Description	token as a bearer token which delegates it pkarr/delete from the DID.
+//	@Tags			Pkarr
+//	@Param			id		path	string	true	"ID of the record to delete"
+//	@Param			salt	query	string	false	"base64url encoded salt of the record, up to 64 bytes"
+//	@Success		204
+//	@Failure		400	{object}	Problem	"Bad request"
+//	@Failure		401	{object}	Problem	"Missing or invalid signature by the key of the ID or its delegate"
+//	@Failure		404	{object}	Problem	"Not found"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/v1/{id} [delete]
+func (r *PkarrRouter) DeleteRecord(c *gin.Context) {
 	id := GetParam(c, IDParam)
 	if id == nil || *id == "" {
 		LoggingRespondErrMsg(c, "missing id param", http.StatusBadRequest)
 		return
 	}
-	key, err := util.Z32Decode(*id)
+	salt, err := getSalt(c)
 	if err != nil {
-		LoggingRespondErrWithMsg(c, err, "failed to read id", http.StatusInternalServerError)
+		LoggingRespondErrWithMsg(c, err, "invalid salt param", http.StatusBadRequest)
 		return
 	}
-	if len(key) != ed25519.PublicKeySize {
-		LoggingRespondErrMsg(c, "invalid z32 encoded ed25519 public key", http.StatusBadRequest)
+	deleted, err := r.service.DeletePkarr(c, *id, salt)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to delete pkarr record", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		LoggingRespondErrMsg(c, "pkarr record not found", http.StatusNotFound)
 		return
 	}
+	ResponseStatus(c, http.StatusNoContent)
+}
+
+// getSalt returns the decoded salt query parameter, nil if not set
+func getSalt(c *gin.Context) ([]byte, error) {
+	salt := GetQueryValue(c, SaltParam)
+	if salt == nil {
+		return nil, nil
+	}
+	return base64.RawURLEncoding.DecodeString(*salt)
+}
+
+// getWait reads the optional wait query param as a duration, such as 30s
+func getWait(c *gin.Context) (time.Duration, error) {
+	wait := GetQueryValue(c, WaitParam)
+	if wait == nil {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(*wait)
+	if err != nil {
+		return 0, err
+	}
+	if duration < 0 {
+		return 0, errors.New("wait must not be negative")
+	}
+	return duration, nil
+}
+
+// getVersionTime returns the time of the version to resolve, or nil to resolve the latest version
+func getVersionTime(c *gin.Context) (*time.Time, error) {
+	versionTime := GetQueryValue(c, VersionTimeParam)
+	if versionTime == nil {
+		return nil, nil
+	}
+	at, err := time.Parse(time.RFC3339, *versionTime)
+	if err != nil {
+		return nil, err
+	}
+	return &at, nil
+}
+
+type GetNextSeqResponse struct {
+	// Seq is the current unix timestamp in seconds, or the stored record's seq plus one if that is newer
+	Seq int64 `json:"seq"`
+}
+
+// GetNextSeq godoc
+//
+//	@Summary		Get the seq to publish the next record for an ID with
+//	@Description	Get the seq the next record for an ID should use, newer than the stored record's, for managed publishing flows
+//	@Tags			Pkarr
+//	@Produce		json
+//	@Param			id	path		string	true	"ID to get the next seq for"
+//	@Success		200	{object}	GetNextSeqResponse
+//	@Failure		400	{object}	Problem	"Bad request"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/v1/{id}/seq [get]
+func (r *PkarrRouter) GetNextSeq(c *gin.Context) {
+	id := GetParam(c, IDParam)
+	if id == nil || *id == "" {
+		LoggingRespondErrMsg(c, "missing id param", http.StatusBadRequest)
+		return
+	}
+	if key, err := util.Z32Decode(*id); err != nil || len(key) != ed25519.PublicKeySize {
+		LoggingRespondError(c, errMalformedID, http.StatusBadRequest)
+		return
+	}
+
+	seq, err := r.service.NextSeq(c, *id)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to get next seq", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, GetNextSeqResponse{Seq: seq}, http.StatusOK)
+}
+
+// getPutRequest returns the ID and the publish request of a request with the body of a relay's PUT, or nil after
+// responding with an error if the request is invalid
+func getPutRequest(c *gin.Context) (*string, *service.PublishPkarrRequest) {
+	id := GetParam(c, IDParam)
+	if id == nil || *id == "" {
+		LoggingRespondErrMsg(c, "missing id param", http.StatusBadRequest)
+		return nil, nil
+	}
+	key, err := util.Z32Decode(*id)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		LoggingRespondError(c, errMalformedID, http.StatusBadRequest)
+		return nil, nil
+	}
 
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		LoggingRespondErrWithMsg(c, err, "failed to read body", http.StatusInternalServerError)
-		return
+		return nil, nil
 	}
 	defer c.Request.Body.Close()
 
 	// 64 byte signature and 8 byte sequence number
 	if len(body) <= 72 {
 		LoggingRespondErrMsg(c, "invalid request body", http.StatusBadRequest)
-		return
+		return nil, nil
 	}
 
 	// transform the request into a service request by extracting the fields
@@ -109,16 +396,49 @@ func (r *PkarrRouter) PutRecord(c *gin.Context) {
 	bytes := body[:64]
 	sigBytes := [64]byte(bytes)
 	seq := int64(binary.BigEndian.Uint64(body[64:72]))
-	request := service.PublishPkarrRequest{
-		V:   vBytes,
-		K:   keyBytes,
-		Sig: sigBytes,
-		Seq: seq,
+	salt, err := getSalt(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid salt param", http.StatusBadRequest)
+		return nil, nil
 	}
-	if err = r.service.PublishPkarr(c, *id, request); err != nil {
-		LoggingRespondErrWithMsg(c, err, "failed to publish pkarr record", http.StatusInternalServerError)
-		return
+	return id, &service.PublishPkarrRequest{
+		V:    vBytes,
+		K:    keyBytes,
+		Sig:  sigBytes,
+		Seq:  seq,
+		Salt: salt,
 	}
+}
 
-	ResponseStatus(c, http.StatusOK)
+// publishErrorStatus returns the status code of a publish request rejected as invalid, and false for other errors
+func publishErrorStatus(err error) (int, bool) {
+	var validationErrs validator.ValidationErrors
+	var keyMismatch *service.KeyMismatchError
+	switch {
+	case errors.Is(err, service.ErrInvalidSignature), errors.Is(err, service.ErrSeqInFuture),
+		errors.As(err, &validationErrs), errors.As(err, &keyMismatch):
+		return http.StatusBadRequest, true
+	case errors.Is(err, service.ErrStaleSeq):
+		return http.StatusConflict, true
+	case errors.Is(err, service.ErrPacketTooLarge):
+		return http.StatusRequestEntityTooLarge, true
+	}
+	return 0, false
+}
+
+// setResolutionHeaders sets the response headers describing which seqs DHT nodes held for the resolved record, and
+// whether the resolution was cut short
+func setResolutionHeaders(c *gin.Context, metadata service.ResolutionMetadata) {
+	if metadata.Partial {
+		c.Header(ResolutionPartialHeader, "true")
+	}
+	if len(metadata.Seqs) == 0 {
+		return
+	}
+	seqs := make([]string, 0, len(metadata.Seqs))
+	for _, seq := range metadata.Seqs {
+		seqs = append(seqs, strconv.FormatInt(seq, 10))
+	}
+	c.Header(ResolutionConflictHeader, strconv.FormatBool(metadata.Conflict))
+	c.Header(ResolutionSeqsHeader, strings.Join(seqs, ","))
 }
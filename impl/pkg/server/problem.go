@@ -0,0 +1,149 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// ProblemContentType is the media type of error responses
+const ProblemContentType = "application/problem+json"
+
+// ErrorCode is a machine-readable code identifying the kind of error in a Problem
+type ErrorCode string
+
+const (
+	CodeInvalidRequest       ErrorCode = "invalid_request"
+	CodeInvalidSignature     ErrorCode = "invalid_signature"
+	CodeStaleSeq             ErrorCode = "stale_seq"
+	CodeSeqInFuture          ErrorCode = "seq_in_future"
+	CodePacketTooLarge       ErrorCode = "packet_too_large"
+	CodeMalformedDID         ErrorCode = "malformed_did"
+	CodeKeyMismatch          ErrorCode = "key_mismatch"
+	CodeUnauthorized         ErrorCode = "unauthorized"
+	CodeForbidden            ErrorCode = "forbidden"
+	CodeNotFound             ErrorCode = "not_found"
+	CodeUnsupportedMediaType ErrorCode = "unsupported_media_type"
+	CodeUnavailable          ErrorCode = "unavailable"
+	CodeStorageFull          ErrorCode = "storage_full"
+	CodeCoolingDown          ErrorCode = "cooling_down"
+	CodeQuotaExceeded        ErrorCode = "quota_exceeded"
+	CodeNotConsistent        ErrorCode = "not_consistent"
+	CodeInternal             ErrorCode = "internal_error"
+)
+
+// errMalformedID is returned for IDs which aren't z-base-32 encoded ed25519 public keys
+var errMalformedID = errors.New("invalid z32 encoded ed25519 public key")
+
+// Problem is an error response according to https://www.rfc-editor.org/rfc/rfc7807
+type Problem struct {
+	// Type is always about:blank, the Code identifies the kind of error instead
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Instance is the path of the request
+	Instance string    `json:"instance,omitempty"`
+	Code     ErrorCode `json:"code"`
+	// Errors are the field-level details of the error, if any
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single field of a request is invalid
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// NewProblem returns the Problem describing the error, for a response with the given status code
+func NewProblem(err error, statusCode int) Problem {
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: err.Error(),
+		Code:   statusCode2ErrorCode(statusCode),
+	}
+
+	var validationErrs validator.ValidationErrors
+	var keyMismatch *service.KeyMismatchError
+	switch {
+	case errors.Is(err, service.ErrInvalidSignature):
+		problem.Code = CodeInvalidSignature
+		problem.Errors = []FieldError{{Field: "sig", Reason: "does not verify"}}
+	case errors.Is(err, service.ErrStaleSeq):
+		problem.Code = CodeStaleSeq
+		problem.Errors = []FieldError{{Field: "seq", Reason: "older than the stored record"}}
+	case errors.Is(err, service.ErrSeqInFuture):
+		problem.Code = CodeSeqInFuture
+		problem.Errors = []FieldError{{Field: "seq", Reason: "too far in the future"}}
+	case errors.Is(err, service.ErrPacketTooLarge):
+		problem.Code = CodePacketTooLarge
+		problem.Errors = []FieldError{{Field: "v", Reason: "exceeds 1000 bytes"}}
+	case errors.Is(err, errMalformedID):
+		problem.Code = CodeMalformedDID
+		problem.Errors = []FieldError{{Field: IDParam, Reason: "not a z-base-32 encoded ed25519 public key"}}
+	case errors.Is(err, service.ErrQuotaExceeded):
+		problem.Code = CodeQuotaExceeded
+	case errors.Is(err, service.ErrNotConsistent):
+		problem.Code = CodeNotConsistent
+	case errors.Is(err, service.ErrInvalidConsistencyToken):
+		problem.Errors = []FieldError{{Field: ConsistencyTokenHeader, Reason: "malformed or issued for another record"}}
+	case errors.Is(err, service.ErrBatchTooLarge):
+		problem.Errors = []FieldError{{Field: "ids", Reason: "exceeds the batch limit"}}
+	case errors.As(err, &keyMismatch):
+		problem.Code = CodeKeyMismatch
+		problem.Errors = []FieldError{{Field: IDParam, Reason: "not the z-base-32 encoding of k"}}
+	case errors.As(err, &validationErrs):
+		problem.Code = CodeInvalidRequest
+		for _, fieldErr := range validationErrs {
+			problem.Errors = append(problem.Errors, FieldError{Field: fieldErr.Field(), Reason: fieldErr.Tag()})
+		}
+	}
+	return problem
+}
+
+func statusCode2ErrorCode(statusCode int) ErrorCode {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeStaleSeq
+	case http.StatusRequestEntityTooLarge:
+		return CodePacketTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMediaType
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusInsufficientStorage:
+		return CodeStorageFull
+	case http.StatusTooManyRequests:
+		return CodeCoolingDown
+	}
+	if statusCode >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
+
+// RespondProblem sends the error to the client as an RFC 7807 problem, or as a JSON string to clients following the
+// draft Gateway API of the spec
+func RespondProblem(c *gin.Context, err error, statusCode int) {
+	problem := NewProblem(err, statusCode)
+	if NegotiatedSpecVersion(c) == SpecVersionGatewayDraft {
+		c.PureJSON(statusCode, problem.Detail)
+		return
+	}
+	problem.Instance = c.Request.URL.Path
+	// gin keeps an already set content type when rendering JSON
+	c.Header("Content-Type", ProblemContentType)
+	c.PureJSON(statusCode, problem)
+}
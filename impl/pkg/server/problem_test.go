@@ -0,0 +1,80 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+func TestNewProblem(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   ErrorCode
+		field  string
+	}{
+		{err: errors.New("missing id param"), status: http.StatusBadRequest, code: CodeInvalidRequest},
+		{err: errors.New("not found"), status: http.StatusNotFound, code: CodeNotFound},
+		{err: errors.New("boom"), status: http.StatusInternalServerError, code: CodeInternal},
+		{err: pkgerrors.Wrap(service.ErrInvalidSignature, "invalid pkarr record"), status: http.StatusBadRequest, code: CodeInvalidSignature, field: "sig"},
+		{err: pkgerrors.Wrap(service.ErrStaleSeq, "invalid pkarr record"), status: http.StatusConflict, code: CodeStaleSeq, field: "seq"},
+		{err: pkgerrors.Wrap(service.ErrSeqInFuture, "invalid pkarr record"), status: http.StatusBadRequest, code: CodeSeqInFuture, field: "seq"},
+		{err: pkgerrors.Wrap(service.ErrPacketTooLarge, "invalid pkarr record"), status: http.StatusRequestEntityTooLarge, code: CodePacketTooLarge, field: "v"},
+		{err: errMalformedID, status: http.StatusBadRequest, code: CodeMalformedDID, field: IDParam},
+		{err: pkgerrors.Wrap(&service.KeyMismatchError{ID: "not-z32"}, "invalid pkarr record"), status: http.StatusBadRequest, code: CodeKeyMismatch, field: IDParam},
+		{err: pkgerrors.Wrap(&service.CoolDownError{ID: "alice"}, "pkarr record updated too often"), status: http.StatusTooManyRequests, code: CodeCoolingDown},
+		{err: pkgerrors.Wrap(service.ErrQuotaExceeded, "pkarr record rejected"), status: http.StatusForbidden, code: CodeQuotaExceeded},
+		{err: pkgerrors.Wrap(service.ErrNotConsistent, "pkarr record not yet replicated"), status: http.StatusServiceUnavailable, code: CodeNotConsistent},
+		{err: pkgerrors.Wrap(service.ErrInvalidConsistencyToken, "invalid consistency token header"), status: http.StatusBadRequest, code: CodeInvalidRequest, field: ConsistencyTokenHeader},
+	}
+	for _, test := range tests {
+		problem := NewProblem(test.err, test.status)
+		assert.Equal(t, test.code, problem.Code, test.err.Error())
+		assert.Equal(t, test.status, problem.Status)
+		assert.Equal(t, http.StatusText(test.status), problem.Title)
+		assert.Equal(t, test.err.Error(), problem.Detail)
+		if test.field != "" {
+			require.Len(t, problem.Errors, 1)
+			assert.Equal(t, test.field, problem.Errors[0].Field)
+		}
+	}
+}
+
+func TestNewProblemValidationErrors(t *testing.T) {
+	err := validate.Struct(DenyKeyRequest{})
+	require.Error(t, err)
+
+	problem := NewProblem(err, http.StatusBadRequest)
+	assert.Equal(t, CodeInvalidRequest, problem.Code)
+	assert.Equal(t, []FieldError{{Field: "reason", Reason: "required"}}, problem.Errors)
+}
+
+func TestRespondProblem(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, testServerURL+"/history", nil)
+
+	LoggingRespondErrMsg(c, "invalid from param", http.StatusBadRequest)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+	var problem Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, Problem{
+		Type:     "about:blank",
+		Title:    "Bad Request",
+		Status:   http.StatusBadRequest,
+		Detail:   "invalid from param",
+		Instance: "/history",
+		Code:     CodeInvalidRequest,
+	}, problem)
+}
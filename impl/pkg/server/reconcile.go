@@ -0,0 +1,71 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// ReconcileRouter is the router for reconciling the records of the primary and secondary storage
+type ReconcileRouter struct {
+	service *service.PkarrService
+}
+
+// NewReconcileRouter returns a new instance of the Reconcile router
+func NewReconcileRouter(service *service.PkarrService) (*ReconcileRouter, error) {
+	return &ReconcileRouter{service: service}, nil
+}
+
+// GetLastReconciliation godoc
+//
+//	@Summary		Get the last storage reconciliation
+//	@Description	Get the drift found between the primary and secondary storage by the last reconciliation, and its
+//	@Description	repair
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	storage.Reconciliation
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		404	{object}	Problem	"No secondary storage, or no reconciliation has run"
+//	@Router			/admin/reconcile [get]
+func (r *ReconcileRouter) GetLastReconciliation(c *gin.Context) {
+	reconciliation, err := r.service.GetLastReconciliation()
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to get last reconciliation", http.StatusNotFound)
+		return
+	}
+	if reconciliation == nil {
+		LoggingRespondErrMsg(c, "no reconciliation has run", http.StatusNotFound)
+		return
+	}
+	Respond(c, reconciliation, http.StatusOK)
+}
+
+// ReconcileStorage godoc
+//
+//	@Summary		Reconcile the primary and secondary storage
+//	@Description	Compare the records of the primary and secondary storage now, copying records missing from or behind
+//	@Description	in either from the other, and deleting records the primary doesn't have from the secondary
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	storage.Reconciliation
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		404	{object}	Problem	"No secondary storage"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/reconcile [post]
+func (r *ReconcileRouter) ReconcileStorage(c *gin.Context) {
+	reconciliation, err := r.service.ReconcileStorage(c)
+	if err != nil {
+		if errors.Is(err, service.ErrNoSecondaryStorage) {
+			LoggingRespondErrWithMsg(c, err, "failed to reconcile storage", http.StatusNotFound)
+			return
+		}
+		LoggingRespondErrWithMsg(c, err, "failed to reconcile storage", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, reconciliation, http.StatusOK)
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+const (
+	FromParam string = "from"
+	ToParam   string = "to"
+	// SeqParam is the path param of the seq of a version of a record
+	SeqParam string = "seq"
+
+	// ActionParam is the path param of the custom method of a collection, e.g. :batchGet in /records:batchGet
+	ActionParam string = "action"
+	// batchGetAction is the custom method resolving many records at once
+	batchGetAction string = ":batchGet"
+)
+
+// RecordsRouter is the router for the Records API, which exposes the history of the records the gateway stores
+type RecordsRouter struct {
+	service *service.PkarrService
+}
+
+// NewRecordsRouter returns a new instance of the Records router
+func NewRecordsRouter(service *service.PkarrService) (*RecordsRouter, error) {
+	return &RecordsRouter{service: service}, nil
+}
+
+// GetRecordDiff godoc
+//
+//	@Summary		Diff two versions of a record
+//	@Description	Diff the DNS resource records and DID Document properties of two stored versions of a record
+//	@Tags			Records
+//	@Produce		json
+//	@Param			id		path		string	true	"ID of the record"
+//	@Param			from	query		int		true	"Seq of the version to diff from"
+//	@Param			to		query		int		true	"Seq of the version to diff to"
+//	@Success		200		{object}	service.PkarrRecordDiff
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		404		{object}	Problem	"Not found"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/v1/records/{id}/diff [get]
+func (r *RecordsRouter) GetRecordDiff(c *gin.Context) {
+	id := getKeyParam(c)
+	if id == nil {
+		return
+	}
+	from, err := getSeqQueryValue(c, FromParam)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid from param", http.StatusBadRequest)
+		return
+	}
+	to, err := getSeqQueryValue(c, ToParam)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid to param", http.StatusBadRequest)
+		return
+	}
+
+	diff, err := r.service.DiffPkarr(c, *id, from, to)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to diff pkarr record", http.StatusInternalServerError)
+		return
+	}
+	if diff == nil {
+		LoggingRespondErrMsg(c, "pkarr record version not found", http.StatusNotFound)
+		return
+	}
+	Respond(c, diff, http.StatusOK)
+}
+
+// GetRecordVersion godoc
+//
+//	@Summary		Get a version of a record
+//	@Description	Get the stored version of a record with the given seq, in the format of the relay API. A version
+//	@Description	doesn't change once stored, so it is served as immutable, for CDNs in front of the gateway to cache.
+//	@Tags			Records
+//	@Produce		octet-stream
+//	@Param			id		path		string	true	"ID of the record"
+//	@Param			seq		path		int		true	"Seq of the version"
+//	@Param			salt	query		string	false	"base64url encoded salt of the record, up to 64 bytes"
+//	@Success		200		{array}		byte	"64 bytes sig, 8 bytes u64 big-endian seq, 0-1000 bytes of v."
+//	@Header			200		{string}	Cache-Control	"public, max-age=31536000, immutable"
+//	@Header			200		{string}	Last-Modified	"Time of the record's seq, as a unix timestamp"
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		404		{object}	Problem	"Not found"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/v1/records/{id}/versions/{seq} [get]
+func (r *RecordsRouter) GetRecordVersion(c *gin.Context) {
+	id := GetParam(c, IDParam)
+	if id == nil || *id == "" {
+		LoggingRespondErrMsg(c, "missing id param", http.StatusBadRequest)
+		return
+	}
+	seq, err := strconv.ParseInt(c.Param(SeqParam), 10, 64)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid seq param", http.StatusBadRequest)
+		return
+	}
+	salt, err := getSalt(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid salt param", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := r.service.GetSaltedPkarrVersion(c, *id, salt, seq)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to get pkarr record version", http.StatusInternalServerError)
+		return
+	}
+	if resp == nil {
+		// the version may be stored later, such as once replicated
+		c.Header(CacheControlHeader, "no-store")
+		LoggingRespondErrMsg(c, "pkarr record version not found", http.StatusNotFound)
+		return
+	}
+	setImmutableCacheHeaders(c, resp.Seq)
+
+	var seqBuf [8]byte
+	binary.BigEndian.PutUint64(seqBuf[:], uint64(resp.Seq))
+	RespondBytes(c, append(append(resp.Sig[:], seqBuf[:]...), resp.V...), http.StatusOK)
+}
+
+// BatchGetRecordsRequest is the request to resolve the records of many IDs at once
+type BatchGetRecordsRequest struct {
+	// IDs are the z-base-32 encoded IDs to resolve
+	IDs []string `json:"ids" validate:"required,min=1"`
+}
+
+// BatchGetRecordsResponse is the result of resolving each ID of a batch, in the order of the request
+type BatchGetRecordsResponse struct {
+	Results []BatchGetRecordResult `json:"results"`
+}
+
+// BatchGetRecordResult is the result of resolving one ID of a batch: either its record or an error
+type BatchGetRecordResult struct {
+	ID string `json:"id"`
+	// Record is the base64url encoded record as served by the relay API: 64 bytes sig, 8 bytes u64 big-endian seq,
+	// and 0-1000 bytes of v
+	Record string `json:"record,omitempty"`
+	// Attestation is the gateway's signed attestation that it served the record, if enabled
+	Attestation string `json:"attestation,omitempty"`
+	// Metadata describes the seqs DHT nodes held for the record, if the gateway searched for the highest seq
+	Metadata *service.ResolutionMetadata `json:"metadata,omitempty"`
+	// Error is why the record could not be resolved, such as it not being found
+	Error *Problem `json:"error,omitempty"`
+}
+
+// BatchGetRecords godoc
+//
+//	@Summary		Resolve many records
+//	@Description	Resolve the records of many IDs at once, up to the configured limit, with a result or an error for
+//	@Description	each ID in the order of the request
+//	@Tags			Records
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		BatchGetRecordsRequest	true	"IDs to resolve"
+//	@Success		200		{object}	BatchGetRecordsResponse
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		404		{object}	Problem	"Not found"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/v1/records:batchGet [post]
+func (r *RecordsRouter) BatchGetRecords(c *gin.Context) {
+	// gin has no literal colons in paths, so custom methods are a param following the collection
+	if action := GetParam(c, ActionParam); action == nil || *action != batchGetAction {
+		LoggingRespondErrMsg(c, "not found", http.StatusNotFound)
+		return
+	}
+
+	var request BatchGetRecordsRequest
+	if err := Decode(c.Request, &request); err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid batch get request", http.StatusBadRequest)
+		return
+	}
+
+	results := make([]BatchGetRecordResult, len(request.IDs))
+	var valid []string
+	for i, id := range request.IDs {
+		results[i].ID = id
+		if key, err := util.Z32Decode(id); err != nil || len(key) != ed25519.PublicKeySize {
+			problem := NewProblem(errMalformedID, http.StatusBadRequest)
+			results[i].Error = &problem
+			continue
+		}
+		valid = append(valid, id)
+	}
+
+	resolved, err := r.service.BatchGetPkarr(c, valid)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to resolve batch", batchGetErrorStatus(err))
+		return
+	}
+	for i, j := 0, 0; i < len(results); i++ {
+		if results[i].Error != nil {
+			continue
+		}
+		results[i].Record, results[i].Attestation, results[i].Error = r.batchGetResult(results[i].ID, resolved[j])
+		if results[i].Error == nil {
+			results[i].Metadata = resolved[j].Record.Metadata
+		}
+		j++
+	}
+	Respond(c, BatchGetRecordsResponse{Results: results}, http.StatusOK)
+}
+
+// batchGetResult encodes a resolved record like the relay API, or the problem resolving it
+func (r *RecordsRouter) batchGetResult(id string, result service.BatchGetResult) (string, string, *Problem) {
+	if result.Err != nil {
+		problem := NewProblem(result.Err, http.StatusInternalServerError)
+		return "", "", &problem
+	}
+	if result.Record == nil {
+		problem := NewProblem(errors.New("pkarr record not found"), http.StatusNotFound)
+		return "", "", &problem
+	}
+	attestation, err := r.service.AttestPkarr(id, *result.Record)
+	if err != nil {
+		problem := NewProblem(err, http.StatusInternalServerError)
+		return "", "", &problem
+	}
+	var seqBuf [8]byte
+	binary.BigEndian.PutUint64(seqBuf[:], uint64(result.Record.Seq))
+	record := append(append(result.Record.Sig[:], seqBuf[:]...), result.Record.V...)
+	return base64.RawURLEncoding.EncodeToString(record), attestation, nil
+}
+
+// batchGetErrorStatus returns the status code of the response to a batch the service failed to resolve
+func batchGetErrorStatus(err error) int {
+	if errors.Is(err, service.ErrBatchTooLarge) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// getSeqQueryValue reads a required sequence number from the query string
+func getSeqQueryValue(c *gin.Context, param string) (int64, error) {
+	value := GetQueryValue(c, param)
+	if value == nil {
+		return 0, errors.New("missing value")
+	}
+	return strconv.ParseInt(*value, 10, 64)
+}
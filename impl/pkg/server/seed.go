@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// seedBodyLimit bounds the size of seed data accepted by the seed API
+const seedBodyLimit = 32 << 20
+
+// SeedRouter is the router for loading seed records, such as fixtures for local development and demos
+type SeedRouter struct {
+	service *service.PkarrService
+}
+
+// NewSeedRouter returns a new instance of the Seed router
+func NewSeedRouter(service *service.PkarrService) (*SeedRouter, error) {
+	return &SeedRouter{service: service}, nil
+}
+
+// Seed godoc
+//
+//	@Summary		Load seed records
+//	@Description	Load pre-signed records into storage from JSONL, one record per line with a seq and base64url
+//	@Description	encoded v, k, sig, and optional salt. Each record is validated like a published record; invalid records
+//	@Description	are reported by line without failing the others. Records are not put to the DHT until republished.
+//	@Tags			Admin
+//	@Accept			plain
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			request	body		string	true	"JSONL seed records"
+//	@Success		200		{object}	service.SeedResult
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		401		{object}	Problem	"Unauthorized"
+//	@Router			/admin/seed [post]
+func (r *SeedRouter) Seed(c *gin.Context) {
+	result, err := r.service.Seed(c, http.MaxBytesReader(c.Writer, c.Request.Body, seedBodyLimit))
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to read seed records", http.StatusBadRequest)
+		return
+	}
+	Respond(c, result, http.StatusOK)
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// SeqResetRouter is the router for the emergency reset of records whose seq was accidentally set astronomically high
+type SeqResetRouter struct {
+	service *service.PkarrService
+}
+
+// NewSeqResetRouter returns a new instance of the SeqReset router
+func NewSeqResetRouter(service *service.PkarrService) (*SeqResetRouter, error) {
+	return &SeqResetRouter{service: service}, nil
+}
+
+// ListSeqResetsResponse is the list of seq resets
+type ListSeqResetsResponse struct {
+	Resets []service.SeqReset `json:"resets"`
+}
+
+// OpenSeqResetRequest is the request to open the seq reset of a record
+type OpenSeqResetRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+// ConsentSeqResetRequest is the consent of a key's owner to the seq reset of its record
+type ConsentSeqResetRequest struct {
+	// Consent is the compact JWS of the reset's consent signed with the key of the ID
+	Consent string `json:"consent" validate:"required"`
+}
+
+// ListSeqResets godoc
+//
+//	@Summary		List seq resets
+//	@Description	List the emergency seq resets in progress, and the executed resets whose seq is still suppressed
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	ListSeqResetsResponse
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/seq-resets [get]
+func (r *SeqResetRouter) ListSeqResets(c *gin.Context) {
+	resets, err := r.service.ListSeqResets()
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to list seq resets", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, ListSeqResetsResponse{Resets: resets}, http.StatusOK)
+}
+
+// OpenSeqReset godoc
+//
+//	@Summary		Open the seq reset of a record
+//	@Description	Open the emergency reset of the stored record of an ID and optional salt whose seq was accidentally
+//	@Description	set astronomically high, which no record can supersede. The key's owner consents to the reset by
+//	@Description	signing its consent as a compact JWS, with the did-dht-seq-reset+json content type, which is then
+//	@Description	submitted to the consent step. The reset expires unless consented to and executed within an hour.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			id		path		string				true	"ID of the record"
+//	@Param			salt	query		string				false	"base64url encoded salt of the record, up to 64 bytes"
+//	@Param			request	body		OpenSeqResetRequest	true	"Reason for the reset"
+//	@Success		201		{object}	service.SeqReset
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		401		{object}	Problem	"Unauthorized"
+//	@Failure		404		{object}	Problem	"No record stored"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/admin/seq-resets/{id} [post]
+func (r *SeqResetRouter) OpenSeqReset(c *gin.Context) {
+	id := getKeyParam(c)
+	if id == nil {
+		return
+	}
+	salt, err := getSalt(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid salt", http.StatusBadRequest)
+		return
+	}
+	var request OpenSeqResetRequest
+	if err = Decode(c.Request, &request); err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid open seq reset request", http.StatusBadRequest)
+		return
+	}
+	reset, err := r.service.OpenSeqReset(c, *id, salt, request.Reason)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to open seq reset", http.StatusInternalServerError)
+		return
+	}
+	if reset == nil {
+		LoggingRespondErrMsg(c, "pkarr record not found", http.StatusNotFound)
+		return
+	}
+	Respond(c, reset, http.StatusCreated)
+}
+
+// ConsentSeqReset godoc
+//
+//	@Summary		Consent to the seq reset of a record
+//	@Description	Submit the consent of the key's owner to the seq reset of the record of an ID and optional salt: the
+//	@Description	compact JWS of the reset's consent, signed with the key of the ID.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			id		path		string					true	"ID of the record"
+//	@Param			salt	query		string					false	"base64url encoded salt of the record, up to 64 bytes"
+//	@Param			request	body		ConsentSeqResetRequest	true	"Consent of the key's owner"
+//	@Success		200		{object}	service.SeqReset
+//	@Failure		400		{object}	Problem	"Bad request, or the reset isn't awaiting consent"
+//	@Failure		401		{object}	Problem	"Unauthorized"
+//	@Failure		403		{object}	Problem	"Consent not signed by the key of the ID for this reset"
+//	@Failure		404		{object}	Problem	"Seq reset not found"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/admin/seq-resets/{id}/consent [put]
+func (r *SeqResetRouter) ConsentSeqReset(c *gin.Context) {
+	id := getKeyParam(c)
+	if id == nil {
+		return
+	}
+	salt, err := getSalt(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid salt", http.StatusBadRequest)
+		return
+	}
+	var request ConsentSeqResetRequest
+	if err = Decode(c.Request, &request); err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid seq reset consent request", http.StatusBadRequest)
+		return
+	}
+	reset, err := r.service.ConsentSeqReset(*id, salt, request.Consent)
+	if err != nil {
+		respondSeqResetErr(c, err, "failed to consent to seq reset")
+		return
+	}
+	Respond(c, reset, http.StatusOK)
+}
+
+// ExecuteSeqReset godoc
+//
+//	@Summary		Execute the seq reset of a record
+//	@Description	Execute the consented seq reset of the record of an ID and optional salt: the record is purged from
+//	@Description	storage, with all of its versions, and from the cache, and its seq, and any newer, is suppressed for
+//	@Description	a day, so it is neither stored, republished, nor resolved from the DHT while it lingers there. The
+//	@Description	owner may then publish a record with a lower seq.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			id		path		string	true	"ID of the record"
+//	@Param			salt	query		string	false	"base64url encoded salt of the record, up to 64 bytes"
+//	@Success		200		{object}	service.SeqReset
+//	@Failure		400		{object}	Problem	"Bad request, or the reset isn't consented to"
+//	@Failure		401		{object}	Problem	"Unauthorized"
+//	@Failure		404		{object}	Problem	"Seq reset not found"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/admin/seq-resets/{id}/execute [post]
+func (r *SeqResetRouter) ExecuteSeqReset(c *gin.Context) {
+	id := getKeyParam(c)
+	if id == nil {
+		return
+	}
+	salt, err := getSalt(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid salt", http.StatusBadRequest)
+		return
+	}
+	reset, err := r.service.ExecuteSeqReset(c, *id, salt)
+	if err != nil {
+		respondSeqResetErr(c, err, "failed to execute seq reset")
+		return
+	}
+	Respond(c, reset, http.StatusOK)
+}
+
+// respondSeqResetErr responds with the status of an error taking a step of a seq reset
+func respondSeqResetErr(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, service.ErrSeqResetNotFound):
+		LoggingRespondErrWithMsg(c, err, msg, http.StatusNotFound)
+	case errors.Is(err, service.ErrSeqResetState):
+		LoggingRespondErrWithMsg(c, err, msg, http.StatusBadRequest)
+	case errors.Is(err, service.ErrInvalidConsent):
+		LoggingRespondErrWithMsg(c, err, msg, http.StatusForbidden)
+	default:
+		LoggingRespondErrWithMsg(c, err, msg, http.StatusInternalServerError)
+	}
+}
@@ -1,8 +1,10 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"time"
 
@@ -14,9 +16,11 @@ import (
 	ginlogrus "github.com/toorop/gin-logrus"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
-	"github.com/TBD54566975/did-dht-method/config"
-	"github.com/TBD54566975/did-dht-method/pkg/service"
-	"github.com/TBD54566975/did-dht-method/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/docs"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/openapi"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
 )
 
 const (
@@ -31,32 +35,123 @@ type Server struct {
 
 	cfg *config.Config
 	svc *service.PkarrService
+
+	// DNSServer is set if the authoritative DNS listeners are enabled
+	DNSServer *DNSServer
+	// AdminServer is set if the admin API is served on its own listener rather than with the public API
+	AdminServer *http.Server
+	// GeoIP is set if requests are enriched with the client's country
+	GeoIP *GeoIP
 }
 
 // NewServer returns a new instance of Server with the given db and host.
 func NewServer(cfg *config.Config, shutdown chan os.Signal) (*Server, error) {
-	// set up server prerequisites
-	handler := setupHandler(cfg.ServerConfig.Environment)
-
-	db, err := storage.NewStorage(cfg.ServerConfig.StorageURI)
+	db, err := storage.NewFromConfig(cfg)
 	if err != nil {
-		return nil, util.LoggingErrorMsg(err, "failed to instantiate storage")
+		return nil, util.LoggingErrorMsg(err, "could not instantiate storage")
 	}
 
 	pkarrService, err := service.NewPkarrService(cfg, db)
 	if err != nil {
 		return nil, util.LoggingErrorMsg(err, "could not instantiate pkarr service")
 	}
+	return NewServerWithService(cfg, shutdown, pkarrService)
+}
+
+// NewServerWithService returns a new instance of Server serving the given Pkarr service
+func NewServerWithService(cfg *config.Config, shutdown chan os.Signal, pkarrService *service.PkarrService) (*Server, error) {
+	// set up server prerequisites
+	var geoIP *GeoIP
+	if cfg.GeoIPConfig.DatabasePath != "" {
+		var err error
+		if geoIP, err = NewGeoIP(cfg.GeoIPConfig.DatabasePath); err != nil {
+			return nil, util.LoggingErrorMsg(err, "failed to open geoip database")
+		}
+	}
+	tlsConfig, err := NewTLSConfig(cfg.TLSConfig)
+	if err != nil {
+		return nil, util.LoggingErrorMsg(err, "invalid tls config")
+	}
+	info := NewInfo(cfg)
+	if cfg.ServerConfig.Announce {
+		info.DID = pkarrService.GatewayDID()
+	}
+	LogInfo(info)
+	// resolutions waiting for a record to be published may take up to the max wait before responding
+	writeTimeout := time.Second*15 + time.Duration(cfg.PkarrConfig.MaxWaitSeconds)*time.Second
+	handler := setupHandler(cfg.ServerConfig.Environment, geoIP, info.Version)
+	handler.Use(Deadline(writeTimeout))
+	if cfg.AttestationConfig.SignResponses {
+		handler.Use(SignedResponses(pkarrService))
+	}
 
 	handler.GET("/health", Health)
+	handler.GET("/ready", Readiness(pkarrService))
+	if cfg.ServerConfig.CanaryCRON != "" && cfg.ServerConfig.Role.Publishes() {
+		handler.GET("/health/canary", CanaryHealth(pkarrService))
+	}
+	handler.GET("/info", Info(info))
+	handler.GET("/spec", Spec(info.Version))
 
-	// set up swagger
-	handler.StaticFile("swagger.yaml", "./docs/swagger.yaml")
-	handler.GET("/swagger/*any", ginswagger.WrapHandler(swaggerfiles.Handler, ginswagger.URL("/swagger.yaml")))
+	// set up the api specs, and swagger ui if enabled
+	spec, err := openapi.FromSwagger(docs.SwaggerYAML)
+	if err != nil {
+		return nil, util.LoggingErrorMsg(err, "could not convert swagger spec to openapi")
+	}
+	handler.GET("/swagger.yaml", GetSwaggerSpec)
+	handler.GET("/openapi.json", GetOpenAPISpec(spec))
+	if cfg.DocsConfig.SwaggerUI {
+		handler.GET("/swagger/*any", ginswagger.WrapHandler(swaggerfiles.Handler, ginswagger.URL("/openapi.json")))
+	}
 
-	// root relay API
-	if err = PkarrAPI(&handler.RouterGroup, pkarrService); err != nil {
-		return nil, util.LoggingErrorMsg(err, "could not setup pkarr API")
+	// versioned API, which is also served at its legacy unversioned paths marked as deprecated
+	role := cfg.ServerConfig.Role
+	logrus.WithField("role", role).Info("configuring server for role")
+	if err = V1API(handler.Group("/"+APIVersionV1), cfg, pkarrService); err != nil {
+		return nil, util.LoggingErrorMsg(err, "could not setup v1 API")
+	}
+	if cfg.APIConfig.LegacyRoutes {
+		deprecation, err := legacyDeprecation(cfg.APIConfig)
+		if err != nil {
+			return nil, util.LoggingErrorMsg(err, "invalid legacy routes deprecation")
+		}
+		if err = V1API(handler.Group("", Deprecated(deprecation)), cfg, pkarrService); err != nil {
+			return nil, util.LoggingErrorMsg(err, "could not setup legacy API")
+		}
+	}
+	if role.Resolves() {
+		if err = DNSAPI(handler.Group("/dns-query"), pkarrService); err != nil {
+			return nil, util.LoggingErrorMsg(err, "could not setup dns API")
+		}
+	}
+	if cfg.DIDWebConfig.Enabled && role.Resolves() {
+		if err = DIDWebAPI(&handler.RouterGroup, pkarrService); err != nil {
+			return nil, util.LoggingErrorMsg(err, "could not setup did:web API")
+		}
+	}
+	// the admin API is served on its own listener if configured, keeping it off the public interface
+	var adminServer *http.Server
+	if cfg.AdminConfig.Token != "" {
+		adminHandler := handler
+		if cfg.AdminConfig.ListenAddress != "" {
+			adminHandler = setupHandler(cfg.ServerConfig.Environment, nil, info.Version)
+			adminHandler.GET("/health", Health)
+			adminServer = &http.Server{
+				Addr:              cfg.AdminConfig.ListenAddress,
+				Handler:           adminHandler,
+				ReadTimeout:       time.Second * 15,
+				ReadHeaderTimeout: time.Second * 15,
+				TLSConfig:         tlsConfig,
+			}
+		}
+		admin := adminHandler.Group("/admin", ClientCertAuth(cfg.TLSConfig.AdminClientAuth), AdminAuth(cfg.AdminConfig.Token))
+		if err = AdminAPI(admin, cfg, pkarrService, geoIP); err != nil {
+			return nil, util.LoggingErrorMsg(err, "could not setup admin API")
+		}
+	}
+	var dnsServer *DNSServer
+	if cfg.DNSConfig.Enabled && role.Resolves() {
+		dnsServer = NewDNSServer(cfg.DNSConfig.ListenAddress, pkarrService)
 	}
 	return &Server{
 		Server: &http.Server{
@@ -64,22 +159,47 @@ func NewServer(cfg *config.Config, shutdown chan os.Signal) (*Server, error) {
 			Handler:           handler,
 			ReadTimeout:       time.Second * 15,
 			ReadHeaderTimeout: time.Second * 15,
-			WriteTimeout:      time.Second * 15,
+			WriteTimeout:      writeTimeout,
+			TLSConfig:         tlsConfig,
 		},
-		cfg:      cfg,
-		svc:      pkarrService,
-		handler:  handler,
-		shutdown: shutdown,
+		cfg:         cfg,
+		svc:         pkarrService,
+		handler:     handler,
+		shutdown:    shutdown,
+		DNSServer:   dnsServer,
+		AdminServer: adminServer,
+		GeoIP:       geoIP,
 	}, nil
 }
 
-func setupHandler(env config.Environment) *gin.Engine {
+// ListenAndServe serves the API over HTTPS if TLS is configured, or over plain HTTP otherwise
+func (s *Server) ListenAndServe() error {
+	return ListenAndServe(s.Server)
+}
+
+// RegisterPublishInterceptor registers an interceptor to enforce a custom policy on published records
+func (s *Server) RegisterPublishInterceptor(interceptor service.PublishInterceptor) {
+	s.svc.RegisterPublishInterceptor(interceptor)
+}
+
+// SeedFile loads the pre-signed records of the JSONL file at the given path into storage, such as fixtures for local
+// development
+func (s *Server) SeedFile(ctx context.Context, path string) (*service.SeedResult, error) {
+	return s.svc.SeedFile(ctx, path)
+}
+
+func setupHandler(env config.Environment, geoIP *GeoIP, version string) *gin.Engine {
+	logger := ginlogrus.Logger(logrus.StandardLogger())
+	if geoIP != nil {
+		logger = geoIP.Logger()
+	}
 	middlewares := gin.HandlersChain{
 		gin.Recovery(),
-		ginlogrus.Logger(logrus.StandardLogger()),
+		logger,
 		gin.ErrorLogger(),
 		otelgin.Middleware(config.ServiceName),
 		CORS(),
+		SpecNegotiation(version),
 	}
 	logrus.WithField("environment", env).Info("configuring server for environment")
 	switch env {
@@ -91,19 +211,463 @@ func setupHandler(env config.Environment) *gin.Engine {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	handler := gin.New()
+	// handlers pass the gin context to the service, which should be done with the request's context
+	handler.ContextWithFallback = true
 	handler.Use(middlewares...)
 	return handler
 }
 
-// PkarrAPI sets up the relay API routes according to https://github.com/Nuhvi/pkarr/blob/main/design/relays.md
-func PkarrAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+// V1API sets up the routes of version 1 of the API
+func V1API(rg *gin.RouterGroup, cfg *config.Config, service *service.PkarrService) error {
+	if err := PkarrAPI(rg, service, cfg); err != nil {
+		return util.LoggingErrorMsg(err, "could not setup pkarr API")
+	}
+	if cfg.PkarrConfig.AssignSeq && cfg.ServerConfig.Role.Publishes() {
+		if err := SeqAPI(rg, service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup seq API")
+		}
+	}
+	if cfg.ServerConfig.Role.Publishes() {
+		if err := TransferAPI(rg, service, cfg); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup transfer API")
+		}
+	}
+	if len(cfg.TenantConfig.Tenants) > 0 && cfg.ServerConfig.Role.Publishes() {
+		if err := TenantAPI(rg, service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup tenant API")
+		}
+	}
+	if err := RecordsAPI(rg.Group("/records"), service); err != nil {
+		return util.LoggingErrorMsg(err, "could not setup records API")
+	}
+	if cfg.ServerConfig.Role.Resolves() {
+		if err := BatchGetAPI(rg, service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup batch get API")
+		}
+	}
+	if cfg.IndexConfig.Enabled {
+		if err := IndexAPI(rg.Group("/index"), service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup index API")
+		}
+	}
+	if cfg.HistoryConfig.Enabled {
+		if err := HistoryAPI(rg.Group("/history"), service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup history API")
+		}
+	}
+	if cfg.FeedConfig.Enabled {
+		if err := FeedAPI(rg.Group("/feed"), service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup feed API")
+		}
+	}
+	if err := BridgeAPI(rg.Group("/bridge")); err != nil {
+		return util.LoggingErrorMsg(err, "could not setup bridge API")
+	}
+	return nil
+}
+
+// PkarrAPI sets up the relay API routes according to https://github.com/Nuhvi/pkarr/blob/main/design/relays.md,
+// limited to the routes of the configured role
+func PkarrAPI(rg *gin.RouterGroup, service *service.PkarrService, cfg *config.Config) error {
 	relayRouter, err := NewPkarrRouter(service)
 	if err != nil {
 		return util.LoggingErrorMsg(err, "could not instantiate relay router")
 	}
 
-	rg.PUT("/:id", relayRouter.PutRecord)
-	rg.GET("/:id", relayRouter.GetRecord)
+	role := cfg.ServerConfig.Role
+	if role.Publishes() {
+		publish := []gin.HandlerFunc{ClientCertAuth(cfg.TLSConfig.PublishClientAuth)}
+		if cfg.PkarrConfig.RequirePublishAuth {
+			publish = append(publish, KeyOwnerAuth(AbilityPublish))
+		}
+		if len(cfg.TenantConfig.Tenants) > 0 {
+			publish = append(publish, TenantAuth(service, cfg.TenantConfig.RequireAPIKey))
+		}
+		rg.PUT("/:id", append(publish, relayRouter.PutRecord)...)
+		rg.DELETE("/:id", KeyOwnerAuth(AbilityDelete), relayRouter.DeleteRecord)
+		rg.POST("/:id/validate", relayRouter.ValidateRecord)
+	}
+	if role.Resolves() {
+		rg.GET("/:id", relayRouter.GetRecord)
+		rg.GET("/records/:id", relayRouter.GetRecord)
+	}
+	return nil
+}
+
+// SeqAPI sets up the route serving the seq the next record for an ID should use
+func SeqAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	relayRouter, err := NewPkarrRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate relay router")
+	}
+
+	rg.GET("/:id/seq", relayRouter.GetNextSeq)
+	return nil
+}
+
+// TransferAPI sets up the routes for exporting records to, and importing records from, other gateways at the request
+// of their owners
+func TransferAPI(rg *gin.RouterGroup, service *service.PkarrService, cfg *config.Config) error {
+	transferRouter, err := NewTransferRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate transfer router")
+	}
+
+	rg.GET("/:id/transfer", KeyOwnerAuth(AbilityTransfer), transferRouter.ExportTransfer)
+	// imports are publishes, authenticated like them but for the signature of the key, which signs the bundle
+	publish := []gin.HandlerFunc{ClientCertAuth(cfg.TLSConfig.PublishClientAuth)}
+	if len(cfg.TenantConfig.Tenants) > 0 {
+		publish = append(publish, TenantAuth(service, cfg.TenantConfig.RequireAPIKey))
+	}
+	rg.POST("/transfers", append(publish, transferRouter.ImportTransfer)...)
+	return nil
+}
+
+// TenantAPI sets up the route reporting the usage of the tenant of the request's API key
+func TenantAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	tenantRouter, err := NewTenantRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate tenant router")
+	}
+
+	rg.GET("/usage", TenantAuth(service, true), tenantRouter.GetUsage)
+	return nil
+}
+
+// RecordsAPI sets up the routes for inspecting the history of stored records
+func RecordsAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	recordsRouter, err := NewRecordsRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate records router")
+	}
+
+	rg.GET("/:id/diff", recordsRouter.GetRecordDiff)
+	rg.GET("/:id/versions/:seq", recordsRouter.GetRecordVersion)
+	return nil
+}
+
+// BatchGetAPI sets up the route resolving many records at once, as the batchGet custom method of the records collection
+func BatchGetAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	recordsRouter, err := NewRecordsRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate records router")
+	}
+
+	rg.POST("/records:"+ActionParam, recordsRouter.BatchGetRecords)
+	return nil
+}
+
+// DNSAPI sets up the DNS-over-HTTPS routes according to https://www.rfc-editor.org/rfc/rfc8484
+func DNSAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	dnsRouter, err := NewDNSRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate dns router")
+	}
+
+	rg.GET("", dnsRouter.GetDNSQuery)
+	rg.POST("", dnsRouter.PostDNSQuery)
+	return nil
+}
+
+// IndexAPI sets up the routes for querying the document index
+func IndexAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	indexRouter, err := NewIndexRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate index router")
+	}
+
+	rg.GET("", indexRouter.QueryIndex)
+	rg.GET("/endpoints", indexRouter.QueryEndpoints)
+	return nil
+}
+
+// HistoryAPI sets up the routes for auditing the log of record updates
+func HistoryAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	historyRouter, err := NewHistoryRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate history router")
+	}
+
+	rg.GET("", historyRouter.ListHistory)
+	rg.GET("/checkpoint", historyRouter.GetHistoryCheckpoint)
+	return nil
+}
+
+// BridgeAPI sets up the routes for converting the identifiers of other DID methods to and from did:dht
+func BridgeAPI(rg *gin.RouterGroup) error {
+	bridgeRouter, err := NewBridgeRouter()
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate bridge router")
+	}
+
+	rg.POST("/import", bridgeRouter.ImportDID)
+	rg.GET("/export/:id", bridgeRouter.ExportDID)
+	return nil
+}
+
+// FeedAPI sets up the change feed route
+func FeedAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	feedRouter, err := NewFeedRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate feed router")
+	}
+
+	rg.GET("", feedRouter.ListFeed)
+	return nil
+}
+
+// AdminAPI sets up the admin routes, limited to those of the configured role, and the runtime profiles if enabled
+func AdminAPI(admin *gin.RouterGroup, cfg *config.Config, service *service.PkarrService, geoIP *GeoIP) error {
+	if cfg.ServerConfig.Role.Publishes() {
+		if err := DenylistAPI(admin.Group("/denylist"), service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup denylist API")
+		}
+		if err := SeedAPI(admin.Group("/seed"), service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup seed API")
+		}
+		if err := CrawlAPI(admin.Group("/crawl"), service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup crawl API")
+		}
+		if err := ReconcileAPI(admin.Group("/reconcile"), service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup reconcile API")
+		}
+		if err := LabelsAPI(admin.Group("/labels"), service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup labels API")
+		}
+		if err := CanaryAPI(admin.Group("/canary"), service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup canary API")
+		}
+		if err := SeqResetAPI(admin.Group("/seq-resets"), service); err != nil {
+			return util.LoggingErrorMsg(err, "could not setup seq reset API")
+		}
+	}
+	if err := DrainAPI(admin.Group("/drain"), service); err != nil {
+		return util.LoggingErrorMsg(err, "could not setup drain API")
+	}
+	if err := MaintenanceAPI(admin.Group("/maintenance"), service); err != nil {
+		return util.LoggingErrorMsg(err, "could not setup maintenance API")
+	}
+	if err := StatsAPI(admin.Group("/stats"), service); err != nil {
+		return util.LoggingErrorMsg(err, "could not setup stats API")
+	}
+	if err := CacheAPI(admin.Group("/cache"), service); err != nil {
+		return util.LoggingErrorMsg(err, "could not setup cache API")
+	}
+	if err := PeerAPI(admin.Group("/peers"), service); err != nil {
+		return util.LoggingErrorMsg(err, "could not setup peer API")
+	}
+	if err := EquivocationAPI(admin.Group("/equivocations"), service); err != nil {
+		return util.LoggingErrorMsg(err, "could not setup equivocation API")
+	}
+	if err := FallbackAPI(admin.Group("/fallback"), service); err != nil {
+		return util.LoggingErrorMsg(err, "could not setup fallback API")
+	}
+	if geoIP != nil {
+		admin.GET("/stats/countries", geoIP.GetCountryStats)
+	}
+	if cfg.AdminConfig.Pprof {
+		PprofAPI(admin.Group("/debug/pprof"))
+	}
+	return nil
+}
+
+// PprofAPI sets up the routes serving the runtime profiles of the process, see net/http/pprof
+func PprofAPI(rg *gin.RouterGroup) {
+	rg.GET("/", gin.WrapF(pprof.Index))
+	rg.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	rg.GET("/profile", gin.WrapF(pprof.Profile))
+	rg.GET("/symbol", gin.WrapF(pprof.Symbol))
+	rg.POST("/symbol", gin.WrapF(pprof.Symbol))
+	rg.GET("/trace", gin.WrapF(pprof.Trace))
+	rg.GET("/:profile", func(c *gin.Context) {
+		pprof.Handler(c.Param("profile")).ServeHTTP(c.Writer, c.Request)
+	})
+}
+
+// DenylistAPI sets up the admin routes for managing the denylist
+func DenylistAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	denylistRouter, err := NewDenylistRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate denylist router")
+	}
+
+	rg.GET("", denylistRouter.ListDenylist)
+	rg.PUT("/:id", denylistRouter.DenyKey)
+	rg.DELETE("/:id", denylistRouter.AllowKey)
+	return nil
+}
+
+// SeedAPI sets up the admin route for loading seed records
+func SeedAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	seedRouter, err := NewSeedRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate seed router")
+	}
+
+	rg.POST("", seedRouter.Seed)
+	return nil
+}
+
+// CrawlAPI sets up the admin routes for importing records from the DHT
+func CrawlAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	crawlRouter, err := NewCrawlRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate crawl router")
+	}
+
+	rg.GET("", crawlRouter.GetCrawlStatus)
+	rg.POST("", crawlRouter.Crawl)
+	rg.DELETE("", crawlRouter.StopCrawl)
+	return nil
+}
+
+// DrainAPI sets up the admin routes for draining the gateway ahead of termination
+func DrainAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	drainRouter, err := NewDrainRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate drain router")
+	}
+
+	rg.GET("", drainRouter.GetDrainStatus)
+	rg.POST("", drainRouter.Drain)
+	return nil
+}
+
+// StatsAPI sets up the gateway stats routes
+func StatsAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	statsRouter, err := NewStatsRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate stats router")
+	}
+
+	rg.GET("/storage", statsRouter.GetStorageStats)
+	rg.GET("/encryption", statsRouter.GetEncryptionStats)
+	rg.GET("/dht", statsRouter.GetDHTStats)
+	rg.GET("/sla", statsRouter.GetSLAReport)
+	return nil
+}
+
+// CacheAPI sets up the admin routes for inspecting and maintaining the record cache
+func CacheAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	cacheRouter, err := NewCacheRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate cache router")
+	}
+
+	rg.GET("", cacheRouter.GetCacheStats)
+	rg.DELETE("", cacheRouter.FlushCache)
+	rg.POST("/check", cacheRouter.CheckCache)
+	return nil
+}
+
+// LabelsAPI sets up the admin routes for the private labels operators attach to records
+func LabelsAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	labelsRouter, err := NewLabelsRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate labels router")
+	}
+
+	rg.GET("", labelsRouter.ListLabels)
+	rg.GET("/:id", labelsRouter.GetRecordLabels)
+	rg.PUT("/:id", labelsRouter.LabelRecord)
+	rg.DELETE("/:id", labelsRouter.UnlabelRecord)
+	return nil
+}
+
+// ReconcileAPI sets up the admin routes for reconciling the records of the primary and secondary storage
+func ReconcileAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	reconcileRouter, err := NewReconcileRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate reconcile router")
+	}
+
+	rg.GET("", reconcileRouter.GetLastReconciliation)
+	rg.POST("", reconcileRouter.ReconcileStorage)
+	return nil
+}
+
+// CanaryAPI sets up the admin routes for the canary record verifying the gateway end to end
+func CanaryAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	canaryRouter, err := NewCanaryRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate canary router")
+	}
+
+	rg.GET("", canaryRouter.GetCanaryStatus)
+	rg.POST("", canaryRouter.StartCanary)
+	return nil
+}
+
+// SeqResetAPI sets up the admin routes for the emergency reset of records whose seq was set astronomically high
+func SeqResetAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	seqResetRouter, err := NewSeqResetRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate seq reset router")
+	}
+
+	rg.GET("", seqResetRouter.ListSeqResets)
+	rg.POST("/:id", seqResetRouter.OpenSeqReset)
+	rg.PUT("/:id/consent", seqResetRouter.ConsentSeqReset)
+	rg.POST("/:id/execute", seqResetRouter.ExecuteSeqReset)
+	return nil
+}
+
+// MaintenanceAPI sets up the admin routes for reclaiming the space left behind by deleted records
+func MaintenanceAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	maintenanceRouter, err := NewMaintenanceRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate maintenance router")
+	}
+
+	rg.GET("", maintenanceRouter.GetMaintenanceStats)
+	rg.POST("", maintenanceRouter.MaintainStorage)
+	return nil
+}
+
+// FallbackAPI sets up the admin routes for the pool of fallback gateways
+func FallbackAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	fallbackRouter, err := NewFallbackRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate fallback router")
+	}
+
+	rg.GET("", fallbackRouter.ListFallbackGateways)
+	rg.POST("", fallbackRouter.DiscoverFallbackGateways)
+	return nil
+}
+
+// PeerAPI sets up the admin routes for the reputation and bans of DHT peers
+func PeerAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	peerRouter, err := NewPeerRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate peer router")
+	}
+
+	rg.GET("", peerRouter.ListPeers)
+	rg.PUT("/:addr", peerRouter.BanPeer)
+	rg.DELETE("/:addr", peerRouter.UnbanPeer)
+	return nil
+}
+
+// EquivocationAPI sets up the admin route listing the evidence of keys signing different records with the same seq
+func EquivocationAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	equivocationRouter, err := NewEquivocationRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate equivocation router")
+	}
+
+	rg.GET("", equivocationRouter.ListEquivocations)
+	return nil
+}
+
+// DIDWebAPI sets up the did:web bridge routes according to https://w3c-ccg.github.io/did-method-web/
+func DIDWebAPI(rg *gin.RouterGroup, service *service.PkarrService) error {
+	didWebRouter, err := NewDIDWebRouter(service)
+	if err != nil {
+		return util.LoggingErrorMsg(err, "could not instantiate did:web router")
+	}
+
+	rg.GET("/.well-known/did.json", didWebRouter.GetDIDWebDocument)
 	return nil
 }
 
@@ -2,26 +2,29 @@ package server
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/fxamacker/cbor/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/TBD54566975/did-dht-method/config"
-	"github.com/TBD54566975/did-dht-method/internal/did"
-	"github.com/TBD54566975/did-dht-method/pkg/dht"
-	"github.com/TBD54566975/did-dht-method/pkg/service"
-	"github.com/TBD54566975/did-dht-method/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
 )
 
 func TestPKARRRouter(t *testing.T) {
 	pkarrSvc := testPKARRService(t)
-	pkarrRouter, err := NewPkarrRouter(&pkarrSvc)
+	pkarrRouter, err := NewPkarrRouter(pkarrSvc)
 	require.NoError(t, err)
 	require.NotEmpty(t, pkarrRouter)
 
@@ -61,10 +64,62 @@ func TestPKARRRouter(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotEmpty(t, resp)
 		assert.Equal(t, reqData, resp)
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s", testServerURL, suffix), nil)
+		req.Header.Set("Accept", CBORMediaType)
+		c = newRequestContextWithParams(w, req, map[string]string{IDParam: suffix})
+
+		pkarrRouter.GetRecord(c)
+		assert.True(t, is2xxResponse(w.Code))
+		assert.Equal(t, CBORMediaType, w.Header().Get("Content-Type"))
+
+		var envelope RecordEnvelope
+		require.NoError(t, cbor.Unmarshal(w.Body.Bytes(), &envelope))
+		assert.Equal(t, reqData[:64], envelope.Sig)
+		assert.Equal(t, int64(binary.BigEndian.Uint64(reqData[64:72])), envelope.Seq)
+		assert.Equal(t, reqData[72:], envelope.V)
+	})
+
+	t.Run("test batch get records", func(t *testing.T) {
+		didID, reqData := generateDIDPutRequest(t)
+		suffix, err := did.DHT(didID).Suffix()
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("%s/%s", testServerURL, suffix), bytes.NewReader(reqData))
+		pkarrRouter.PutRecord(newRequestContextWithParams(w, req, map[string]string{IDParam: suffix}))
+		require.True(t, is2xxResponse(w.Code))
+
+		recordsRouter, err := NewRecordsRouter(pkarrSvc)
+		require.NoError(t, err)
+		body, err := json.Marshal(BatchGetRecordsRequest{IDs: []string{suffix, "malformed", suffix}})
+		require.NoError(t, err)
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, testServerURL+"/records:batchGet", bytes.NewReader(body))
+		recordsRouter.BatchGetRecords(newRequestContextWithParams(w, req, map[string]string{ActionParam: ":batchGet"}))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp BatchGetRecordsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Len(t, resp.Results, 3)
+		for _, i := range []int{0, 2} {
+			assert.Equal(t, suffix, resp.Results[i].ID)
+			assert.Nil(t, resp.Results[i].Error)
+			assert.Equal(t, base64.RawURLEncoding.EncodeToString(reqData), resp.Results[i].Record)
+		}
+		require.NotNil(t, resp.Results[1].Error)
+		assert.Equal(t, CodeMalformedDID, resp.Results[1].Error.Code)
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, testServerURL+"/records:batchDelete", bytes.NewReader(body))
+		recordsRouter.BatchGetRecords(newRequestContextWithParams(w, req, map[string]string{ActionParam: ":batchDelete"}))
+		assert.Equal(t, http.StatusNotFound, w.Code)
 	})
 }
 
-func testPKARRService(t *testing.T) service.PkarrService {
+func testPKARRService(t *testing.T) *service.PkarrService {
 	defaultConfig := config.GetDefaultConfig()
 	db, err := storage.NewStorage(defaultConfig.ServerConfig.StorageURI)
 	require.NoError(t, err)
@@ -72,7 +127,7 @@ func testPKARRService(t *testing.T) service.PkarrService {
 	pkarrService, err := service.NewPkarrService(&defaultConfig, db)
 	require.NoError(t, err)
 	require.NotEmpty(t, pkarrService)
-	return *pkarrService
+	return pkarrService
 }
 
 func generateDIDPutRequest(t *testing.T) (string, []byte) {
@@ -10,8 +10,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
-	"github.com/TBD54566975/did-dht-method/config"
+	"github.com/TBD54566975/did-dht-method/impl/config"
 )
 
 const (
@@ -45,6 +46,41 @@ func TestHealthCheckAPI(t *testing.T) {
 	assert.Equal(t, HealthOK, resp.Status)
 }
 
+func TestAdminListener(t *testing.T) {
+	pkarrSvc := testPKARRService(t)
+	cfg := config.GetDefaultConfig()
+	cfg.AdminConfig.Token = "secret"
+	cfg.AdminConfig.Pprof = true
+	get := func(handler http.Handler, path string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, testServerURL+path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("served with the public api", func(t *testing.T) {
+		server, err := NewServerWithService(&cfg, nil, pkarrSvc)
+		require.NoError(t, err)
+		assert.Nil(t, server.AdminServer)
+		assert.Equal(t, http.StatusOK, get(server.Handler, "/admin/stats/dht"))
+	})
+
+	t.Run("served on its own listener", func(t *testing.T) {
+		cfg := cfg
+		cfg.AdminConfig.ListenAddress = "127.0.0.1:8306"
+		server, err := NewServerWithService(&cfg, nil, pkarrSvc)
+		require.NoError(t, err)
+		require.NotNil(t, server.AdminServer)
+		assert.Equal(t, "127.0.0.1:8306", server.AdminServer.Addr)
+		assert.Equal(t, http.StatusNotFound, get(server.Handler, "/admin/stats/dht"))
+		assert.Equal(t, http.StatusOK, get(server.AdminServer.Handler, "/admin/stats/dht"))
+		assert.Equal(t, http.StatusOK, get(server.AdminServer.Handler, "/admin/debug/pprof/"))
+		assert.Equal(t, http.StatusOK, get(server.AdminServer.Handler, "/admin/debug/pprof/goroutine"))
+		assert.Equal(t, http.StatusOK, get(server.AdminServer.Handler, "/health"))
+	})
+}
+
 // Is2xxResponse returns true if the given status code is a 2xx response
 func is2xxResponse(statusCode int) bool {
 	return statusCode/100 == 2
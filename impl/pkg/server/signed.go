@@ -0,0 +1,140 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+const (
+	// JOSEMediaType is the media type of a compact JWS, which clients accept to have responses signed by the gateway
+	JOSEMediaType string = "application/jose"
+	// SignedResponseContentType is the content type (cty) of the payload of a signed response, a SignedResponse
+	SignedResponseContentType string = "did-dht-response+json"
+)
+
+// SignedResponse is the payload of a response signed by the gateway, binding the status, headers, and body of the
+// response to the request it answers
+type SignedResponse struct {
+	Method string `json:"method"`
+	// URI is the request URI, its path and query
+	URI     string            `json:"uri"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is the base64url encoded body of the response
+	Body string `json:"body,omitempty"`
+	// Timestamp is the unix time in seconds the response was signed at
+	Timestamp int64 `json:"timestamp"`
+}
+
+// SignedResponses is middleware which wraps the responses to requests accepting application/jose in a compact JWS
+// signed with the gateway's key, whose payload is a SignedResponse. Clients fetching through untrusted proxies or
+// CDNs can verify the whole response, not just the signature of a record. The status of the response is kept.
+func SignedResponses(svc *service.PkarrService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept")
+		if !acceptsMediaType(c.GetHeader("Accept"), JOSEMediaType) {
+			c.Next()
+			return
+		}
+
+		writer := c.Writer
+		buffered := &bufferedWriter{ResponseWriter: writer, status: writer.Status()}
+		c.Writer = buffered
+		c.Next()
+		c.Writer = writer
+
+		signed := SignedResponse{
+			Method:    c.Request.Method,
+			URI:       c.Request.URL.RequestURI(),
+			Status:    buffered.status,
+			Headers:   make(map[string]string),
+			Timestamp: time.Now().Unix(),
+		}
+		for name, values := range writer.Header() {
+			if name == "Content-Length" {
+				continue
+			}
+			signed.Headers[name] = strings.Join(values, ", ")
+		}
+		if buffered.body.Len() > 0 {
+			signed.Body = base64.RawURLEncoding.EncodeToString(buffered.body.Bytes())
+		}
+		payload, err := json.Marshal(signed)
+		if err != nil {
+			LoggingRespondErrWithMsg(c, err, "failed to marshal signed response", http.StatusInternalServerError)
+			return
+		}
+		jws, err := svc.SignJWS(payload, SignedResponseContentType)
+		if err != nil {
+			LoggingRespondErrWithMsg(c, err, "failed to sign response", http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", JOSEMediaType)
+		c.Data(buffered.status, JOSEMediaType, []byte(jws))
+	}
+}
+
+// acceptsMediaType returns whether the Accept header lists the media type
+func acceptsMediaType(accept, mediaType string) bool {
+	for _, accepted := range strings.Split(accept, ",") {
+		accepted, _, _ = strings.Cut(accepted, ";")
+		if strings.EqualFold(strings.TrimSpace(accepted), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds the response written by the handlers instead of sending it, so it can be signed
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	body    bytes.Buffer
+	written bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// Flush is a no-op, as the response is only sent once signed
+func (w *bufferedWriter) Flush() {}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+func TestSignedResponses(t *testing.T) {
+	svc := testPKARRService(t)
+	handler := gin.New()
+	handler.Use(SignedResponses(svc))
+	handler.GET("/records/:id", func(c *gin.Context) {
+		c.Header("Consistency-Token", "token")
+		Respond(c, gin.H{"id": c.Param("id")}, http.StatusCreated)
+	})
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/records/alice?wait=1s", nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("not accepting jose", func(t *testing.T) {
+		w := get("application/json")
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.JSONEq(t, `{"id":"alice"}`, w.Body.String())
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	})
+
+	t.Run("accepting jose", func(t *testing.T) {
+		w := get("application/json;q=0.5, application/jose")
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, JOSEMediaType, w.Header().Get("Content-Type"))
+
+		// the gateway's key is pinned by clients, and taken from the header here
+		compact := w.Body.String()
+		headerBytes, err := base64.RawURLEncoding.DecodeString(strings.Split(compact, ".")[0])
+		require.NoError(t, err)
+		var header struct {
+			Cty string `json:"cty"`
+			JWK struct {
+				X string `json:"x"`
+			} `json:"jwk"`
+		}
+		require.NoError(t, json.Unmarshal(headerBytes, &header))
+		assert.Equal(t, SignedResponseContentType, header.Cty)
+		publicKey, err := base64.RawURLEncoding.DecodeString(header.JWK.X)
+		require.NoError(t, err)
+
+		payload, err := service.VerifyJWS(compact, ed25519.PublicKey(publicKey), SignedResponseContentType)
+		require.NoError(t, err)
+		var signed SignedResponse
+		require.NoError(t, json.Unmarshal(payload, &signed))
+		assert.Equal(t, http.MethodGet, signed.Method)
+		assert.Equal(t, "/records/alice?wait=1s", signed.URI)
+		assert.Equal(t, http.StatusCreated, signed.Status)
+		assert.Equal(t, "token", signed.Headers["Consistency-Token"])
+		body, err := base64.RawURLEncoding.DecodeString(signed.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"alice"}`, string(body))
+	})
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// SpecVersionHeader is the response header of the version of the DID DHT spec the gateway follows for the request,
+	// and the request header clients hint the version they implement with
+	SpecVersionHeader string = "DID-DHT-Spec-Version"
+	// RelayVersionHeader is the response header of the version of the Pkarr relay protocol the gateway implements
+	RelayVersionHeader string = "Pkarr-Relay-Version"
+	// GatewayVersionHeader is the response header of the semantic version of the gateway's build
+	GatewayVersionHeader string = "Gateway-Version"
+
+	// SpecVersion is the latest version of the DID DHT spec the gateway implements, https://did-dht.com
+	SpecVersion string = "1.0"
+	// SpecVersionGatewayDraft is the version of the draft Gateway API of the spec, whose errors are JSON strings
+	// rather than RFC 7807 problems
+	SpecVersionGatewayDraft string = "0.1"
+	// RelayVersion is the version of the Pkarr relay protocol the gateway implements, the binary sig, seq, and v
+	// encoding of https://github.com/Nuhvi/pkarr/blob/main/design/relays.md, which isn't versioned upstream
+	RelayVersion string = "1"
+
+	// SpecVersionKey is the key of the negotiated version of the DID DHT spec in the gin context
+	SpecVersionKey string = "specVersion"
+)
+
+// SupportedSpecVersions are the versions of the DID DHT spec the gateway can follow, in ascending order
+var SupportedSpecVersions = []string{SpecVersionGatewayDraft, SpecVersion}
+
+// errUnsupportedSpecVersion is returned for spec version hints older than any supported version, or malformed
+var errUnsupportedSpecVersion = errors.New("unsupported did:dht spec version")
+
+// GetSpecResponse describes the versions of the specs the gateway implements
+type GetSpecResponse struct {
+	// GatewayVersion is the semantic version of the gateway's build
+	GatewayVersion string `json:"gatewayVersion"`
+	// SpecVersion is the latest version of the DID DHT spec implemented, and SupportedSpecVersions those clients may
+	// hint with the DID-DHT-Spec-Version header
+	SpecVersion           string   `json:"specVersion"`
+	SupportedSpecVersions []string `json:"supportedSpecVersions"`
+	SpecURL               string   `json:"specUrl"`
+	// RelayVersion is the version of the Pkarr relay protocol implemented
+	RelayVersion string `json:"relayVersion"`
+	RelayURL     string `json:"relayUrl"`
+}
+
+// SpecNegotiation is middleware which declares the versions of the specs the gateway implements on each response,
+// following the version of the DID DHT spec hinted by the client: the newest supported version not newer than the
+// hint, or the latest without one. Hints older than any supported version are rejected.
+func SpecNegotiation(gatewayVersion string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(GatewayVersionHeader, gatewayVersion)
+		c.Header(RelayVersionHeader, RelayVersion)
+		c.Writer.Header().Add("Vary", SpecVersionHeader)
+		version := SpecVersion
+		if hint := c.GetHeader(SpecVersionHeader); hint != "" {
+			negotiated, ok := negotiateSpecVersion(hint)
+			if !ok {
+				c.Header(SpecVersionHeader, SpecVersion)
+				LoggingRespondError(c, errUnsupportedSpecVersion, http.StatusBadRequest)
+				c.Abort()
+				return
+			}
+			version = negotiated
+		}
+		c.Set(SpecVersionKey, version)
+		c.Header(SpecVersionHeader, version)
+		c.Next()
+	}
+}
+
+// NegotiatedSpecVersion returns the version of the DID DHT spec followed for the request
+func NegotiatedSpecVersion(c *gin.Context) string {
+	if version := c.GetString(SpecVersionKey); version != "" {
+		return version
+	}
+	return SpecVersion
+}
+
+// Spec godoc
+//
+//	@Summary		Spec versions
+//	@Description	Spec describes the versions of the DID DHT spec and Pkarr relay protocol the gateway implements.
+//	@Description	Clients may hint the version of the DID DHT spec they implement with the DID-DHT-Spec-Version header,
+//	@Description	and the gateway follows the newest supported version not newer than the hint, which is returned in
+//	@Description	the same header. Clients hinting 0.1 get errors as JSON strings, as in the draft Gateway API.
+//	@Tags			Health
+//	@Produce		json
+//	@Param			DID-DHT-Spec-Version	header		string	false	"Version of the DID DHT spec the client implements"
+//	@Success		200						{object}	GetSpecResponse
+//	@Header			200						{string}	DID-DHT-Spec-Version	"Version of the DID DHT spec followed"
+//	@Header			200						{string}	Pkarr-Relay-Version		"Version of the Pkarr relay protocol implemented"
+//	@Header			200						{string}	Gateway-Version			"Semantic version of the gateway"
+//	@Failure		400						{object}	Problem					"Unsupported spec version"
+//	@Router			/spec [get]
+func Spec(gatewayVersion string) gin.HandlerFunc {
+	spec := GetSpecResponse{
+		GatewayVersion:        gatewayVersion,
+		SpecVersion:           SpecVersion,
+		SupportedSpecVersions: SupportedSpecVersions,
+		SpecURL:               "https://did-dht.com",
+		RelayVersion:          RelayVersion,
+		RelayURL:              "https://github.com/Nuhvi/pkarr/blob/main/design/relays.md",
+	}
+	return func(c *gin.Context) {
+		Respond(c, spec, http.StatusOK)
+	}
+}
+
+// negotiateSpecVersion returns the newest supported spec version not newer than the hinted version
+func negotiateSpecVersion(hint string) (string, bool) {
+	hinted, ok := parseSpecVersion(hint)
+	if !ok {
+		return "", false
+	}
+	negotiated := ""
+	for _, version := range SupportedSpecVersions {
+		supported, _ := parseSpecVersion(version)
+		if supported[0] > hinted[0] || (supported[0] == hinted[0] && supported[1] > hinted[1]) {
+			break
+		}
+		negotiated = version
+	}
+	return negotiated, negotiated != ""
+}
+
+// parseSpecVersion parses a major.minor version, the minor version defaulting to zero
+func parseSpecVersion(version string) ([2]int, bool) {
+	majorStr, minorStr, hasMinor := strings.Cut(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil || major < 0 {
+		return [2]int{}, false
+	}
+	minor := 0
+	if hasMinor {
+		// patch versions are ignored
+		minorStr, _, _ = strings.Cut(minorStr, ".")
+		if minor, err = strconv.Atoi(minorStr); err != nil || minor < 0 {
+			return [2]int{}, false
+		}
+	}
+	return [2]int{major, minor}, true
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecNegotiation(t *testing.T) {
+	handler := gin.New()
+	handler.Use(SpecNegotiation("1.2.3"))
+	handler.GET("/spec", Spec("1.2.3"))
+	handler.GET("/fail", func(c *gin.Context) {
+		LoggingRespondError(c, errors.New("boom"), http.StatusBadRequest)
+	})
+	get := func(path, hint string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if hint != "" {
+			req.Header.Set(SpecVersionHeader, hint)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/spec", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, SpecVersion, w.Header().Get(SpecVersionHeader))
+	assert.Equal(t, RelayVersion, w.Header().Get(RelayVersionHeader))
+	assert.Equal(t, "1.2.3", w.Header().Get(GatewayVersionHeader))
+	var spec GetSpecResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, SupportedSpecVersions, spec.SupportedSpecVersions)
+
+	tests := []struct {
+		hint       string
+		negotiated string
+	}{
+		{hint: "1.0", negotiated: SpecVersion},
+		{hint: "v1", negotiated: SpecVersion},
+		{hint: "2.3.1", negotiated: SpecVersion},
+		{hint: "0.5", negotiated: SpecVersionGatewayDraft},
+		{hint: "0.0", negotiated: ""},
+		{hint: "latest", negotiated: ""},
+	}
+	for _, test := range tests {
+		w = get("/spec", test.hint)
+		if test.negotiated == "" {
+			assert.Equal(t, http.StatusBadRequest, w.Code, test.hint)
+			continue
+		}
+		assert.Equal(t, http.StatusOK, w.Code, test.hint)
+		assert.Equal(t, test.negotiated, w.Header().Get(SpecVersionHeader), test.hint)
+	}
+
+	t.Run("errors follow the negotiated version", func(t *testing.T) {
+		w := get("/fail", "")
+		assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+		w = get("/fail", SpecVersionGatewayDraft)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var detail string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+		assert.Equal(t, "boom", detail)
+	})
+}
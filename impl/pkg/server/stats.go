@@ -0,0 +1,129 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+const (
+	// PeriodParam is the query param of the period of an SLA report, daily or weekly
+	PeriodParam string = "period"
+	// DateParam is the query param of a date (YYYY-MM-DD) in the period of an SLA report
+	DateParam string = "date"
+)
+
+// StatsRouter is the router for the gateway's operational stats
+type StatsRouter struct {
+	service *service.PkarrService
+}
+
+// NewStatsRouter returns a new instance of the Stats router
+func NewStatsRouter(service *service.PkarrService) (*StatsRouter, error) {
+	return &StatsRouter{service: service}, nil
+}
+
+// GetStorageStats godoc
+//
+//	@Summary		Get storage occupancy
+//	@Description	Get the number and approximate size of the stored records, relative to the retention budget
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	service.StorageStats
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/stats/storage [get]
+func (r *StatsRouter) GetStorageStats(c *gin.Context) {
+	stats, err := r.service.GetStorageStats(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to get storage stats", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, stats, http.StatusOK)
+}
+
+// GetEncryptionStats godoc
+//
+//	@Summary		Get encryption key usage
+//	@Description	Get the number of stored values of records and their versions encrypted with each key, by its ID,
+//	@Description	to tell when a rotated out key is no longer used and can be removed
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	pkarr.EncryptionKeyUsage
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Failure		404	{object}	Problem	"Storage is not encrypted"
+//	@Failure		500	{object}	Problem	"Internal server error"
+//	@Router			/admin/stats/encryption [get]
+func (r *StatsRouter) GetEncryptionStats(c *gin.Context) {
+	usage, err := r.service.GetEncryptionKeyUsage(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to get encryption key usage", http.StatusInternalServerError)
+		return
+	}
+	if usage == nil {
+		LoggingRespondErrMsg(c, "storage is not encrypted", http.StatusNotFound)
+		return
+	}
+	Respond(c, usage, http.StatusOK)
+}
+
+// GetDHTStats godoc
+//
+//	@Summary		Get outbound DHT utilization
+//	@Description	Get the utilization of the outbound DHT put and get budgets
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	dht.PaceStats
+//	@Failure		401	{object}	Problem	"Unauthorized"
+//	@Router			/admin/stats/dht [get]
+func (r *StatsRouter) GetDHTStats(c *gin.Context) {
+	Respond(c, r.service.GetDHTStats(), http.StatusOK)
+}
+
+// GetSLAReport godoc
+//
+//	@Summary		Get an SLA report
+//	@Description	Get the success rate and latency percentiles of the resolutions and publishes of a UTC day, or of a
+//	@Description	week starting on Monday. The stats are kept in memory for two weeks, so periods the gateway didn't
+//	@Description	run all of are reported as incomplete.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			period	query		string	false	"daily (the default) or weekly"
+//	@Param			date	query		string	false	"Date (YYYY-MM-DD) in the period, defaults to today"
+//	@Success		200		{object}	service.SLAReport
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		401		{object}	Problem	"Unauthorized"
+//	@Router			/admin/stats/sla [get]
+func (r *StatsRouter) GetSLAReport(c *gin.Context) {
+	period := service.SLAPeriodDaily
+	if value := GetQueryValue(c, PeriodParam); value != nil {
+		period = *value
+	}
+	at := time.Now()
+	if value := GetQueryValue(c, DateParam); value != nil {
+		date, err := time.Parse(time.DateOnly, *value)
+		if err != nil {
+			LoggingRespondErrMsg(c, "invalid date param", http.StatusBadRequest)
+			return
+		}
+		at = date
+	}
+	report, err := r.service.GetSLAReport(period, at)
+	if errors.Is(err, service.ErrUnknownSLAPeriod) {
+		LoggingRespondErrWithMsg(c, err, "invalid period param", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to get sla report", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, report, http.StatusOK)
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// NDJSONMediaType is the media type of newline delimited JSON, which clients accept to have listings streamed one
+	// item per line rather than paginated
+	NDJSONMediaType string = "application/x-ndjson"
+
+	// streamHeartbeat is how long a stream may go without writing before an empty line is written, keeping the
+	// connection alive through proxies which time out idle connections
+	streamHeartbeat = 15 * time.Second
+	// streamPageSize is the number of items requested of each page of a stream, the most the listings return at once
+	streamPageSize = 1000
+	// streamWriteTimeout bounds each write of a stream, in place of the server's write timeout, which would otherwise
+	// cut long streams short
+	streamWriteTimeout = 30 * time.Second
+)
+
+// streamError is the last line of a stream which failed after its first items were written
+type streamError struct {
+	Error string `json:"error"`
+}
+
+// streamPageLimit returns the number of items to request of the next page of a stream limited to limit items, if
+// positive, of which streamed have been streamed
+func streamPageLimit(limit, streamed int) int {
+	if limit <= 0 {
+		return streamPageSize
+	}
+	return min(streamPageSize, limit-streamed)
+}
+
+// acceptsNDJSON returns whether the request accepts listings streamed as newline delimited JSON
+func acceptsNDJSON(c *gin.Context) bool {
+	return acceptsMediaType(c.GetHeader("Accept"), NDJSONMediaType)
+}
+
+// streamNDJSON responds with the items of each page returned by next, one JSON value per line, until next reports
+// there are no more, so listings of any size are written without buffering them. An empty line is written as a
+// heartbeat while the next page is loading for long. If next fails, the stream ends with a streamError line.
+func streamNDJSON[T any](c *gin.Context, next func(ctx context.Context) (items []T, more bool, err error)) {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	pages := make(chan []T)
+	failed := make(chan error, 1)
+	go func() {
+		defer close(pages)
+		for {
+			items, more, err := next(ctx)
+			if err != nil {
+				failed <- err
+				return
+			}
+			if len(items) > 0 {
+				select {
+				case pages <- items:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if !more {
+				return
+			}
+		}
+	}()
+
+	controller := http.NewResponseController(c.Writer)
+	extendDeadline := func() {
+		if err := controller.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+			logrus.WithError(err).Debug("failed to extend write deadline of stream")
+		}
+	}
+	extendDeadline()
+	c.Header("Content-Type", NDJSONMediaType)
+	c.Header(CacheControlHeader, "no-store")
+	// ask proxies such as nginx to pass the stream through rather than buffering it
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	w := bufio.NewWriter(c.Writer)
+	encoder := json.NewEncoder(w)
+	flush := func() bool {
+		if err := w.Flush(); err != nil {
+			logrus.WithError(err).Debug("stream closed by client")
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	}
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case items, ok := <-pages:
+			if !ok {
+				select {
+				case err := <-failed:
+					logrus.WithError(err).Error("failed to stream listing")
+					_ = encoder.Encode(streamError{Error: err.Error()})
+				default:
+				}
+				flush()
+				return
+			}
+			extendDeadline()
+			for _, item := range items {
+				if err := encoder.Encode(item); err != nil {
+					logrus.WithError(err).Error("failed to encode streamed item")
+					return
+				}
+			}
+			if !flush() {
+				return
+			}
+			heartbeat.Reset(streamHeartbeat)
+		case <-heartbeat.C:
+			extendDeadline()
+			if err := w.WriteByte('\n'); err != nil || !flush() {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamNDJSON(t *testing.T) {
+	stream := func(pages [][]int, err error) *httptest.ResponseRecorder {
+		handler := gin.New()
+		handler.GET("/items", func(c *gin.Context) {
+			streamNDJSON(c, func(context.Context) ([]int, bool, error) {
+				if len(pages) == 0 {
+					return nil, false, err
+				}
+				page := pages[0]
+				pages = pages[1:]
+				return page, len(pages) > 0 || err != nil, nil
+			})
+		})
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set("Accept", NDJSONMediaType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("streams every page", func(t *testing.T) {
+		w := stream([][]int{{1, 2}, {}, {3}}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, NDJSONMediaType, w.Header().Get("Content-Type"))
+		assert.Equal(t, "1\n2\n3\n", w.Body.String())
+	})
+
+	t.Run("ends with the error", func(t *testing.T) {
+		w := stream([][]int{{1}}, errors.New("storage is down"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1\n{\"error\":\"storage is down\"}\n", w.Body.String())
+	})
+}
+
+func TestStreamPageLimit(t *testing.T) {
+	assert.Equal(t, streamPageSize, streamPageLimit(0, 5000))
+	assert.Equal(t, streamPageSize, streamPageLimit(5000, 1000))
+	assert.Equal(t, 250, streamPageLimit(1250, 1000))
+}
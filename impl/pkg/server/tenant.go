@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+const (
+	// APIKeyHeader is the request header of the API key identifying the tenant publishing a record or asking for its
+	// usage
+	APIKeyHeader string = "API-Key"
+
+	// QuotaWarningHeader is the response header of the comma separated soft quotas, records or bytes, the tenant
+	// publishing a record is beyond
+	QuotaWarningHeader string = "Quota-Warning"
+
+	// TenantKey is the key of the name of the tenant identified by the request's API key in the gin context
+	TenantKey string = "tenant"
+)
+
+// TenantAuth is middleware which identifies the tenant of the request by its API key, rejecting unknown keys, and
+// requests without a key if required
+func TenantAuth(service *service.PkarrService, require bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader(APIKeyHeader)
+		if apiKey == "" {
+			if require {
+				LoggingRespondErrMsg(c, "api key required", http.StatusUnauthorized)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		tenant, ok := service.Tenant(apiKey)
+		if !ok {
+			LoggingRespondErrMsg(c, "unknown api key", http.StatusUnauthorized)
+			c.Abort()
+			return
+		}
+		c.Set(TenantKey, tenant)
+		c.Next()
+	}
+}
+
+// TenantRouter is the router for the tenant API, which reports the storage used by tenants
+type TenantRouter struct {
+	service *service.PkarrService
+}
+
+// NewTenantRouter returns a new instance of the tenant router
+func NewTenantRouter(service *service.PkarrService) (*TenantRouter, error) {
+	return &TenantRouter{service: service}, nil
+}
+
+// GetUsage godoc
+//
+//	@Summary		Get the usage of a tenant
+//	@Description	Get the number of records stored by the tenant of the API key, and the size of their DNS packets,
+//	@Description	relative to its quotas. Publishes beyond a hard quota are rejected; those beyond a soft quota are
+//	@Description	accepted with a Quota-Warning header.
+//	@Tags			Tenants
+//	@Produce		json
+//	@Param			API-Key	header		string	true	"API key of the tenant"
+//	@Success		200		{object}	service.TenantUsage
+//	@Failure		401		{object}	Problem	"Missing or unknown API key"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/v1/usage [get]
+func (r *TenantRouter) GetUsage(c *gin.Context) {
+	usage, err := r.service.GetTenantUsage(c, c.GetString(TenantKey))
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to get tenant usage", http.StatusInternalServerError)
+		return
+	}
+	Respond(c, usage, http.StatusOK)
+}
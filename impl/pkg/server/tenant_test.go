@@ -0,0 +1,90 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestTenantPublish(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.TenantConfig = config.TenantConfig{
+		Tenants:        []config.Tenant{{Name: "acme", APIKey: "acme-key", SoftMaxRecords: 1}},
+		RequireAPIKey:  true,
+		HardMaxRecords: 2,
+	}
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "tenants.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	pkarrService, err := service.NewPkarrServiceWith(&cfg, db, localDHT{}, cache.None{})
+	require.NoError(t, err)
+	handler := gin.New()
+	require.NoError(t, PkarrAPI(&handler.RouterGroup, pkarrService, &cfg))
+	require.NoError(t, TenantAPI(&handler.RouterGroup, pkarrService))
+
+	publish := func(apiKey string) *httptest.ResponseRecorder {
+		sk, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{})
+		require.NoError(t, err)
+		packet, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+		require.NoError(t, err)
+		put, err := dht.CreatePKARRPublishRequest(sk, *packet)
+		require.NoError(t, err)
+		suffix, err := did.DHT(doc.ID).Suffix()
+		require.NoError(t, err)
+		var seqBuf [8]byte
+		binary.BigEndian.PutUint64(seqBuf[:], uint64(put.Seq))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("%s/%s", testServerURL, suffix),
+			bytes.NewReader(append(put.Sig[:], append(seqBuf[:], put.V.([]byte)...)...)))
+		if apiKey != "" {
+			req.Header.Set(APIKeyHeader, apiKey)
+		}
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("publishes without a known api key are rejected", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, publish("").Code)
+		assert.Equal(t, http.StatusUnauthorized, publish("unknown-key").Code)
+	})
+
+	t.Run("publishes count against the tenant of the api key", func(t *testing.T) {
+		w := publish("acme-key")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(QuotaWarningHeader))
+
+		w = publish("acme-key")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, service.QuotaRecords, w.Header().Get(QuotaWarningHeader))
+
+		assert.Equal(t, http.StatusForbidden, publish("acme-key").Code, "beyond the hard quota")
+
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, testServerURL+"/usage", nil)
+		req.Header.Set(APIKeyHeader, "acme-key")
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var usage service.TenantUsage
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&usage))
+		assert.Equal(t, "acme", usage.Tenant)
+		assert.Equal(t, int64(2), usage.Records)
+	})
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+// NewTLSConfig returns the TLS config the listeners serve HTTPS with, verifying the certificates clients present
+// against the client CAs if configured, or nil to serve plain HTTP
+func NewTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	if !cfg.PublishClientAuth.IsValid() {
+		return nil, fmt.Errorf("unknown publish client auth: %s", cfg.PublishClientAuth)
+	}
+	if !cfg.AdminClientAuth.IsValid() {
+		return nil, fmt.Errorf("unknown admin client auth: %s", cfg.AdminClientAuth)
+	}
+	requiresClientCerts := cfg.PublishClientAuth == config.ClientAuthRequire || cfg.AdminClientAuth == config.ClientAuthRequire
+	if cfg.CertFile == "" && cfg.KeyFile == "" {
+		if requiresClientCerts || cfg.ClientCAFile != "" {
+			return nil, errors.New("client certificates require a cert_file and key_file to serve https with")
+		}
+		return nil, nil
+	}
+	if requiresClientCerts && cfg.ClientCAFile == "" {
+		return nil, errors.New("requiring client certificates requires a client_ca_file to verify them against")
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tls certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.ClientCAFile != "" {
+		bundle, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no certificates found in client ca file: %s", cfg.ClientCAFile)
+		}
+		// certificates are only required by some endpoints, which check for a verified chain
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+// ClientCertAuth is middleware which requires the client to have presented a certificate verified against the client
+// CAs, or does nothing for ClientAuthNone
+func ClientCertAuth(level config.ClientAuth) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if level != config.ClientAuthRequire {
+			c.Next()
+			return
+		}
+		if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+			LoggingRespondErrMsg(c, "verified client certificate required", http.StatusUnauthorized)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ListenAndServe serves the listener over HTTPS if it has a TLS config, with its certificates loaded, or over plain
+// HTTP otherwise
+func ListenAndServe(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
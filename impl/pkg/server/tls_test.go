@@ -0,0 +1,142 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+// testCert is a certificate and its key, signed by its parent or self-signed
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write writes the PEM encoded certificate and key to the directory, returning their paths
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func TestClientCertAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil, x509.ExtKeyUsageAny)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCert(t, "gateway", ca, x509.ExtKeyUsageServerAuth).write(t, dir, "gateway")
+	client := newTestCert(t, "publisher", ca, x509.ExtKeyUsageClientAuth)
+	stranger := newTestCert(t, "stranger", nil, x509.ExtKeyUsageClientAuth)
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := NewTLSConfig(config.TLSConfig{PublishClientAuth: "sometimes"})
+		assert.Error(t, err)
+		_, err = NewTLSConfig(config.TLSConfig{PublishClientAuth: config.ClientAuthRequire})
+		assert.Error(t, err)
+		_, err = NewTLSConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, AdminClientAuth: config.ClientAuthRequire})
+		assert.Error(t, err)
+		tlsConfig, err := NewTLSConfig(config.TLSConfig{})
+		assert.NoError(t, err)
+		assert.Nil(t, tlsConfig)
+	})
+
+	tlsConfig, err := NewTLSConfig(config.TLSConfig{
+		CertFile:          certFile,
+		KeyFile:           keyFile,
+		ClientCAFile:      caFile,
+		PublishClientAuth: config.ClientAuthRequire,
+	})
+	require.NoError(t, err)
+	handler := gin.New()
+	handler.PUT("/publish", ClientCertAuth(config.ClientAuthRequire), func(c *gin.Context) {
+		ResponseStatus(c, http.StatusOK)
+	})
+	handler.GET("/resolve", ClientCertAuth(config.ClientAuthNone), func(c *gin.Context) {
+		ResponseStatus(c, http.StatusOK)
+	})
+	srv := httptest.NewUnstartedServer(handler)
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	request := func(method, path string, cert *testCert) (int, error) {
+		clientConfig := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+		if cert != nil {
+			clientConfig.Certificates = []tls.Certificate{{Certificate: [][]byte{cert.der}, PrivateKey: cert.key}}
+		}
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	status, err := request(http.MethodPut, "/publish", client)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	status, err = request(http.MethodPut, "/publish", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, err = request(http.MethodGet, "/resolve", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	// certificates which aren't issued by the client CAs are never verified
+	status, err = request(http.MethodPut, "/publish", stranger)
+	if err == nil {
+		assert.Equal(t, http.StatusUnauthorized, status)
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// TransferRouter is the router for moving records between gateways at the request of their owners
+type TransferRouter struct {
+	service *service.PkarrService
+}
+
+// NewTransferRouter returns a new instance of the Transfer router
+func NewTransferRouter(service *service.PkarrService) (*TransferRouter, error) {
+	return &TransferRouter{service: service}, nil
+}
+
+// ImportTransferRequest is the request to import a transfer bundle exported from another gateway
+type ImportTransferRequest struct {
+	// Bundle is the compact JWS of the transfer bundle signed with the key of its ID
+	Bundle string `json:"bundle" validate:"required"`
+}
+
+// ExportTransfer godoc
+//
+//	@Summary		Export a record for a transfer to another gateway
+//	@Description	Export the stored record of an ID and optional salt, with the versions of it the gateway retains, as a
+//	@Description	transfer bundle. The owner signs the bundle as a compact JWS with the did-dht-transfer+json content
+//	@Description	type, and imports it to another gateway within a day. The request must carry an HTTP Message
+//	@Description	Signature (RFC 9421) by the ed25519 key of the ID, or by a delegate presenting a capability token
+//	@Description	which delegates it pkarr/transfer from the DID.
+//	@Tags			Pkarr
+//	@Produce		json
+//	@Param			id		path		string	true	"ID of the record"
+//	@Param			salt	query		string	false	"base64url encoded salt of the record, up to 64 bytes"
+//	@Success		200		{object}	service.TransferBundle
+//	@Failure		400		{object}	Problem	"Bad request"
+//	@Failure		401		{object}	Problem	"Missing or invalid signature by the key of the ID or its delegate"
+//	@Failure		404		{object}	Problem	"No record stored"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/v1/{id}/transfer [get]
+func (r *TransferRouter) ExportTransfer(c *gin.Context) {
+	id := getKeyParam(c)
+	if id == nil {
+		return
+	}
+	salt, err := getSalt(c)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid salt", http.StatusBadRequest)
+		return
+	}
+	bundle, err := r.service.ExportTransfer(c, *id, salt)
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to export pkarr record", http.StatusInternalServerError)
+		return
+	}
+	if bundle == nil {
+		LoggingRespondErrMsg(c, "pkarr record not found", http.StatusNotFound)
+		return
+	}
+	Respond(c, bundle, http.StatusOK)
+}
+
+// ImportTransfer godoc
+//
+//	@Summary		Import a record transferred from another gateway
+//	@Description	Import a transfer bundle exported from another gateway and signed by the key of its ID. Its versions
+//	@Description	are stored in ascending order of seq, each validated like a published record, then its current record
+//	@Description	is published like a PUT, counted against the quotas of the tenant of the API key, and the gateway
+//	@Description	republishes the record from then on. Versions older than a record the gateway already stores are
+//	@Description	skipped.
+//	@Tags			Pkarr
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		ImportTransferRequest	true	"Signed transfer bundle"
+//	@Success		201		{object}	service.TransferResult
+//	@Header			201		{string}	Quota-Warning	"Comma separated soft quotas, records or bytes, the tenant of the API key is beyond, if any"
+//	@Failure		400		{object}	Problem	"Invalid, expired, or unsigned bundle, or an invalid record"
+//	@Failure		401		{object}	Problem	"Verified client certificate, or API key, required"
+//	@Failure		403		{object}	Problem	"Record rejected by the gateway's policy, or beyond a hard quota of the tenant of the API key"
+//	@Failure		409		{object}	Problem	"The gateway stores a newer record"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Failure		503		{object}	Problem	"Gateway is draining"
+//	@Failure		507		{object}	Problem	"Not accepting new records"
+//	@Router			/v1/transfers [post]
+func (r *TransferRouter) ImportTransfer(c *gin.Context) {
+	var request ImportTransferRequest
+	if err := Decode(c.Request, &request); err != nil {
+		LoggingRespondErrWithMsg(c, err, "invalid import transfer request", http.StatusBadRequest)
+		return
+	}
+	result, quotaWarnings, err := r.service.ImportTransfer(c, c.GetString(TenantKey), request.Bundle)
+	if err != nil {
+		var rejected *service.PublishRejectedError
+		switch {
+		case errors.Is(err, service.ErrInvalidTransfer):
+			LoggingRespondErrWithMsg(c, err, "invalid transfer bundle", http.StatusBadRequest)
+		case errors.As(err, &rejected), errors.Is(err, service.ErrQuotaExceeded):
+			LoggingRespondErrWithMsg(c, err, "pkarr record rejected", http.StatusForbidden)
+		case errors.Is(err, service.ErrDraining):
+			LoggingRespondErrWithMsg(c, err, "not accepting pkarr records", http.StatusServiceUnavailable)
+		case errors.Is(err, service.ErrStorageFull):
+			LoggingRespondErrWithMsg(c, err, "not accepting new pkarr records", http.StatusInsufficientStorage)
+		default:
+			if status, ok := publishErrorStatus(err); ok {
+				LoggingRespondErrWithMsg(c, err, "invalid pkarr record", status)
+				return
+			}
+			LoggingRespondErrWithMsg(c, err, "failed to import pkarr record", http.StatusInternalServerError)
+		}
+		return
+	}
+	if len(quotaWarnings) > 0 {
+		c.Header(QuotaWarningHeader, strings.Join(quotaWarnings, ","))
+	}
+	Respond(c, result, http.StatusCreated)
+}
@@ -82,10 +82,10 @@ func RespondBytes(c *gin.Context, data []byte, statusCode int) {
 	c.Data(statusCode, "application/octet-stream", data)
 }
 
-// LoggingRespondError sends an error response back to the client as a safe error
+// LoggingRespondError sends an error response back to the client as a safe error, in the form of an RFC 7807 problem
 func LoggingRespondError(c *gin.Context, err error, statusCode int) {
 	logrus.WithError(err).Error()
-	Respond(c, err, statusCode)
+	RespondProblem(c, err, statusCode)
 }
 
 // LoggingRespondErrMsg sends an error response back to the client as a safe error from a msg
@@ -131,7 +131,23 @@ func CORS() gin.HandlerFunc {
 			http.MethodPatch,
 			http.MethodDelete,
 		},
-		AllowHeaders:     []string{"*"},
+		AllowHeaders: []string{"*"},
+		ExposeHeaders: []string{
+			AttestationHeader,
+			ConsistencyTokenHeader,
+			LintWarningsHeader,
+			QuotaWarningHeader,
+			SpecVersionHeader,
+			RelayVersionHeader,
+			GatewayVersionHeader,
+			ResolutionConflictHeader,
+			ResolutionSeqsHeader,
+			ResolutionPartialHeader,
+			AgeHeader,
+			DeprecationHeader,
+			SunsetHeader,
+			LinkHeader,
+		},
 		AllowCredentials: false,
 	})
 }
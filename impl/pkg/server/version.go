@@ -0,0 +1,62 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+const (
+	// APIVersionV1 is the path prefix of version 1 of the API
+	APIVersionV1 string = "v1"
+
+	DeprecationHeader string = "Deprecation"
+	SunsetHeader      string = "Sunset"
+	LinkHeader        string = "Link"
+)
+
+// Deprecation describes the deprecation of a set of routes
+type Deprecation struct {
+	// Date is when the routes were deprecated
+	Date time.Time
+	// Sunset is when the routes may stop being served, if scheduled
+	Sunset time.Time
+	// SuccessorPrefix is prepended to the path of a request to link to its replacement, if any
+	SuccessorPrefix string
+}
+
+// Deprecated is middleware marking the routes it's applied to as deprecated, with the Deprecation header according
+// to https://www.rfc-editor.org/rfc/rfc9745, the Sunset header according to https://www.rfc-editor.org/rfc/rfc8594,
+// and a link to the successor version of the route
+func Deprecated(deprecation Deprecation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(DeprecationHeader, fmt.Sprintf("@%d", deprecation.Date.Unix()))
+		if !deprecation.Sunset.IsZero() {
+			c.Header(SunsetHeader, deprecation.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if deprecation.SuccessorPrefix != "" {
+			c.Header(LinkHeader, fmt.Sprintf(`<%s%s>; rel="successor-version"`, deprecation.SuccessorPrefix, c.Request.URL.Path))
+		}
+		c.Next()
+	}
+}
+
+// legacyDeprecation returns the deprecation of the unversioned routes, which are succeeded by the v1 routes
+func legacyDeprecation(cfg config.APIConfig) (Deprecation, error) {
+	deprecation := Deprecation{SuccessorPrefix: "/" + APIVersionV1}
+	date, err := time.Parse(time.DateOnly, cfg.LegacyDeprecationDate)
+	if err != nil {
+		return deprecation, err
+	}
+	deprecation.Date = date
+	if cfg.LegacySunsetDate != "" {
+		if deprecation.Sunset, err = time.Parse(time.DateOnly, cfg.LegacySunsetDate); err != nil {
+			return deprecation, err
+		}
+	}
+	return deprecation, nil
+}
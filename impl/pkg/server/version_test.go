@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+func TestDeprecated(t *testing.T) {
+	deprecation, err := legacyDeprecation(config.APIConfig{
+		LegacyDeprecationDate: "2026-10-16",
+		LegacySunsetDate:      "2027-04-16",
+	})
+	require.NoError(t, err)
+
+	handler := gin.New()
+	handler.GET("/v1/records", func(c *gin.Context) { ResponseStatus(c, http.StatusOK) })
+	handler.Group("", Deprecated(deprecation)).GET("/records", func(c *gin.Context) { ResponseStatus(c, http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/records", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "@1792108800", w.Header().Get(DeprecationHeader))
+	assert.Equal(t, "Fri, 16 Apr 2027 00:00:00 GMT", w.Header().Get(SunsetHeader))
+	assert.Equal(t, `</v1/records>; rel="successor-version"`, w.Header().Get(LinkHeader))
+
+	// versioned routes aren't deprecated
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/records", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(DeprecationHeader))
+}
+
+func TestLegacyDeprecation(t *testing.T) {
+	deprecation, err := legacyDeprecation(config.APIConfig{LegacyDeprecationDate: "2026-10-16"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), deprecation.Date)
+	assert.True(t, deprecation.Sunset.IsZero())
+
+	_, err = legacyDeprecation(config.APIConfig{})
+	assert.Error(t, err)
+}
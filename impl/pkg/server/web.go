@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net"
+	"net/http"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/gin-gonic/gin"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+const (
+	// FieldsParam is the query parameter of the comma separated properties of a DID Document to return
+	FieldsParam string = "fields"
+
+	// DIDJSONMediaType is the media type of the JSON representation of a DID Document
+	DIDJSONMediaType string = "application/did+json"
+	// DIDLDJSONMediaType is the media type of the JSON-LD representation of a DID Document
+	DIDLDJSONMediaType string = "application/did+ld+json"
+	// DIDCBORMediaType is the media type of the CBOR representation of a DID Document
+	DIDCBORMediaType string = "application/did+cbor"
+)
+
+// DIDWebRouter is the router for the did:web bridge, which serves did:dht documents as did:web documents
+type DIDWebRouter struct {
+	service *service.PkarrService
+}
+
+// NewDIDWebRouter returns a new instance of the did:web router
+func NewDIDWebRouter(service *service.PkarrService) (*DIDWebRouter, error) {
+	return &DIDWebRouter{service: service}, nil
+}
+
+// GetDIDWebDocument godoc
+//
+//	@Summary		Get the did:web document of the requested domain
+//	@Description	Get the DID Document of the did:dht record mapped to the requested domain, as a did:web document.
+//	@Description	Constrained clients may select the properties they need with fields, e.g.
+//	@Description	fields=verificationMethod,service, which are the only ones decoded from the record, along with the id.
+//	@Description	Consumers requiring JSON-LD processing may accept application/did+ld+json, for the document with the
+//	@Description	@context of the DID Core vocabulary and of its verification method types. Constrained clients may
+//	@Description	accept application/did+cbor, for the document encoded as CBOR.
+//	@Tags			DIDWeb
+//	@Produce		json
+//	@Produce		application/did+json
+//	@Produce		application/did+ld+json
+//	@Produce		application/did+cbor
+//	@Param			fields	query		string	false	"Comma separated properties of the document to return, all if empty"
+//	@Success		200		{object}	did.Document
+//	@Failure		400		{object}	Problem	"Unknown field"
+//	@Failure		404		{object}	Problem	"Not found"
+//	@Failure		500		{object}	Problem	"Internal server error"
+//	@Router			/.well-known/did.json [get]
+func (r *DIDWebRouter) GetDIDWebDocument(c *gin.Context) {
+	var fields []did.DocumentField
+	if list := GetQueryValue(c, FieldsParam); list != nil {
+		var err error
+		if fields, err = did.ParseDocumentFields(*list); err != nil {
+			LoggingRespondErrWithMsg(c, err, "invalid fields param", http.StatusBadRequest)
+			return
+		}
+	}
+	domain := c.Request.Host
+	doc, err := r.service.GetDIDWebDocument(c, domain, fields...)
+	if err == nil && doc == nil {
+		// fall back to the domain without its port, if any
+		if host, _, splitErr := net.SplitHostPort(domain); splitErr == nil {
+			doc, err = r.service.GetDIDWebDocument(c, host, fields...)
+		}
+	}
+	if err != nil {
+		LoggingRespondErrWithMsg(c, err, "failed to get did:web document", http.StatusInternalServerError)
+		return
+	}
+	if doc == nil {
+		LoggingRespondErrMsg(c, "did:web document not found", http.StatusNotFound)
+		return
+	}
+	respondDocument(c, *doc)
+}
+
+// respondDocument responds with the DID Document in the representation negotiated by the Accept header: CBOR for
+// application/did+cbor, JSON-LD for application/did+ld+json, plain JSON otherwise
+func respondDocument(c *gin.Context, doc didsdk.Document) {
+	c.Writer.Header().Add("Vary", "Accept")
+	accept := c.GetHeader("Accept")
+	switch {
+	case acceptsMediaType(accept, DIDCBORMediaType):
+		docCBOR, err := did.ToCBOR(doc)
+		if err != nil {
+			LoggingRespondErrWithMsg(c, err, "failed to encode did:web document", http.StatusInternalServerError)
+			return
+		}
+		c.Data(http.StatusOK, DIDCBORMediaType, docCBOR)
+	case acceptsMediaType(accept, DIDLDJSONMediaType):
+		// gin keeps an already set content type when rendering JSON
+		c.Header("Content-Type", DIDLDJSONMediaType)
+		Respond(c, did.ToJSONLD(doc), http.StatusOK)
+	case acceptsMediaType(accept, DIDJSONMediaType):
+		c.Header("Content-Type", DIDJSONMediaType)
+		Respond(c, doc, http.StatusOK)
+	default:
+		Respond(c, doc, http.StatusOK)
+	}
+}
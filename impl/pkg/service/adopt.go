@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	intutil "github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+// adopter persists records resolved from the DHT or fallback gateways, adding them to the republished records so the
+// gateway keeps records its users resolve alive on the DHT
+type adopter struct {
+	// counter counts the stored records, if the storage supports it
+	counter storage.RecordCounter
+	// maxRecords stops adoption once the storage holds this many records, if not zero
+	maxRecords int64
+}
+
+// adopt stores the record resolved for the given z-base-32 encoded ID and salt, unless the storage is full. Records
+// go through the publish interceptors like published records, so the allow and deny lists apply to adoption too.
+func (s *PkarrService) adopt(ctx context.Context, id string, salt []byte, resp GetPkarrResponse) {
+	if s.adopter == nil {
+		return
+	}
+	k, err := intutil.Z32Decode(id)
+	if err != nil || len(k) != 32 {
+		return
+	}
+	storageKey, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return
+	}
+	current, err := s.db.ReadRecord(ctx, storageKey)
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to read pkarr record[%s] for adoption", id)
+		return
+	}
+	if current != nil && current.Seq >= resp.Seq {
+		return
+	}
+	// records already stored are updated regardless of the limit, which only limits adopting new records
+	if current == nil && s.adopter.maxRecords > 0 {
+		count, err := s.adopter.counter.CountRecords(ctx)
+		if err != nil {
+			logrus.WithError(err).Warn("failed to count records for adoption")
+			return
+		}
+		if count >= s.adopter.maxRecords {
+			logrus.Debugf("not adopting pkarr record[%s], storage holds the maximum of %d records", id, count)
+			return
+		}
+	}
+	request := PublishPkarrRequest{
+		V:    resp.V,
+		K:    [32]byte(k),
+		Sig:  resp.Sig,
+		Seq:  resp.Seq,
+		Salt: salt,
+	}
+	if err = s.storePkarr(ctx, id, request); err != nil {
+		if !errors.Is(err, ErrStaleSeq) && !errors.Is(err, ErrStorageFull) {
+			logrus.WithError(err).Warnf("failed to adopt pkarr record[%s]", id)
+		}
+		return
+	}
+	logrus.Debugf("adopted pkarr record[%s]", id)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestAdoptOnResolve(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	put := bep44.Put{V: []byte("hello pkarr"), K: (*[32]byte)(pubKey), Seq: 1}
+	put.Sign(privKey)
+	v, err := bencode.Marshal(put.V)
+	require.NoError(t, err)
+	d := staticDHT{result: dht.FullGetResult{Seq: put.Seq, V: v, Sig: put.Sig, Mutable: true}}
+
+	newService := func(t *testing.T, adopt bool, maxRecords int) (*PkarrService, storage.Storage) {
+		cfg := config.GetDefaultConfig()
+		cfg.PkarrConfig.RepublishCRON = ""
+		cfg.PkarrConfig.AdoptOnResolve = adopt
+		cfg.PkarrConfig.AdoptMaxRecords = maxRecords
+		db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "adopt.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		svc, err := NewPkarrServiceWith(&cfg, db, d, cache.None{})
+		require.NoError(t, err)
+		return svc, db
+	}
+
+	t.Run("adopts resolved record", func(t *testing.T) {
+		svc, db := newService(t, true, 0)
+		got, err := svc.GetPkarr(context.Background(), id)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+
+		key, err := recordKey(id)
+		require.NoError(t, err)
+		stored, err := db.ReadRecord(context.Background(), key)
+		require.NoError(t, err)
+		require.NotNil(t, stored)
+		assert.Equal(t, put.Seq, stored.Seq)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		svc, db := newService(t, false, 0)
+		_, err := svc.GetPkarr(context.Background(), id)
+		require.NoError(t, err)
+
+		key, err := recordKey(id)
+		require.NoError(t, err)
+		stored, err := db.ReadRecord(context.Background(), key)
+		require.NoError(t, err)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("storage full", func(t *testing.T) {
+		svc, db := newService(t, true, 1)
+		otherPub, otherPriv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		other := bep44.Put{V: []byte("other"), K: (*[32]byte)(otherPub), Seq: 1}
+		other.Sign(otherPriv)
+		request := PublishPkarrRequest{V: other.V.([]byte), K: *other.K, Sig: other.Sig, Seq: other.Seq}
+		require.NoError(t, svc.storePkarr(context.Background(), util.Z32Encode(otherPub), request))
+
+		got, err := svc.GetPkarr(context.Background(), id)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+
+		key, err := recordKey(id)
+		require.NoError(t, err)
+		stored, err := db.ReadRecord(context.Background(), key)
+		require.NoError(t, err)
+		assert.Nil(t, stored)
+	})
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+const (
+	// GatewayServiceID and GatewayServiceType are the ID and type of the service of the gateway's own DID Document,
+	// whose endpoint is the base URL of its API
+	GatewayServiceID   = "gateway"
+	GatewayServiceType = "DIDDHTGateway"
+)
+
+// GatewayDID returns the did:dht identifier of the gateway's signing key, the DID it announces itself with
+func (s *PkarrService) GatewayDID() string {
+	return did.GetDIDDHTIdentifier(s.key.Public().(ed25519.PublicKey))
+}
+
+// GatewayDocument returns the gateway's own DID Document, whose identity key is its signing key and whose service is
+// its API at the configured base URL
+func (s *PkarrService) GatewayDocument() (*didsdk.Document, error) {
+	return did.CreateDIDDHTDID(s.key.Public().(ed25519.PublicKey), did.CreateDIDDHTOpts{
+		Services: []didsdk.Service{{
+			ID:              GatewayServiceID,
+			Type:            GatewayServiceType,
+			ServiceEndpoint: []string{s.cfg.ServerConfig.BaseURL},
+		}},
+	})
+}
+
+// announce publishes the gateway's own DID Document through the publish pipeline, so clients can discover the
+// gateway through the DHT, unless the stored record already is the document. From then on the record is republished
+// with the other stored records.
+func (s *PkarrService) announce(ctx context.Context) error {
+	doc, err := s.GatewayDocument()
+	if err != nil {
+		return err
+	}
+	msg, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+	if err != nil {
+		return err
+	}
+	v, err := msg.Pack()
+	if err != nil {
+		return err
+	}
+	id, err := did.DHT(doc.ID).Suffix()
+	if err != nil {
+		return err
+	}
+	key, err := recordKey(id)
+	if err != nil {
+		return err
+	}
+	current, err := s.db.ReadRecord(ctx, key)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.V == base64.RawURLEncoding.EncodeToString(v) {
+		logrus.WithField("did", doc.ID).Info("gateway is already announced")
+		return nil
+	}
+
+	var prev int64
+	if current != nil {
+		prev = current.Seq
+	}
+	put := bep44.Put{V: v, K: (*[32]byte)(s.key.Public().(ed25519.PublicKey)), Seq: dht.NextSeq(prev)}
+	put.Sign(s.key)
+	if err = s.PublishPkarr(ctx, id, PublishPkarrRequest{V: v, K: *put.K, Sig: put.Sig, Seq: put.Seq}); err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"did":      doc.ID,
+		"endpoint": s.cfg.ServerConfig.BaseURL,
+	}).Info("announced gateway")
+	return nil
+}
+
+// announceGateway announces the gateway, logging whether it failed
+func (s *PkarrService) announceGateway() {
+	if err := s.announce(context.Background()); err != nil {
+		logrus.WithError(err).Error("failed to announce gateway")
+	}
+}
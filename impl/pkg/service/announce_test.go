@@ -0,0 +1,68 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestAnnounce(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.ServerConfig.BaseURL = "https://gateway.example.com"
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "announce.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("requires a signing key", func(t *testing.T) {
+		cfg := cfg
+		cfg.ServerConfig.Announce = true
+		_, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+		assert.Error(t, err)
+	})
+
+	cfg.ServerConfig.SigningKey = base64.RawURLEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, svc.announce(ctx))
+
+	id, err := did.DHT(svc.GatewayDID()).Suffix()
+	require.NoError(t, err)
+	resolve := func() *GetPkarrResponse {
+		resp, err := svc.GetPkarr(ctx, id)
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		return resp
+	}
+	announced := resolve()
+	var msg dns.Msg
+	require.NoError(t, msg.Unpack(announced.V))
+	doc, _, err := did.DHT(svc.GatewayDID()).FromDNSPacket(&msg)
+	require.NoError(t, err)
+	require.Len(t, doc.Services, 1)
+	assert.Equal(t, GatewayServiceType, doc.Services[0].Type)
+	assert.Equal(t, "https://gateway.example.com", doc.Services[0].ServiceEndpoint)
+
+	t.Run("isn't announced again unless it changed", func(t *testing.T) {
+		require.NoError(t, svc.announce(ctx))
+		assert.Equal(t, announced.Seq, resolve().Seq)
+
+		svc.cfg.ServerConfig.BaseURL = "https://moved.example.com"
+		require.NoError(t, svc.announce(ctx))
+		moved := resolve()
+		assert.Greater(t, moved.Seq, announced.Seq)
+		assert.NotEqual(t, announced.V, moved.V)
+	})
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// ArchiveManifest lists the CIDs of the signed record snapshots pinned in an archive
+type ArchiveManifest struct {
+	Timestamp int64            `json:"timestamp"`
+	Records   []ArchivedRecord `json:"records"`
+}
+
+// ArchivedRecord is the CID of a pinned snapshot of a record, a JSON encoded pkarr.Record
+type ArchivedRecord struct {
+	ID  string `json:"id"`
+	Seq int64  `json:"seq"`
+	CID string `json:"cid"`
+}
+
+// archive pins a snapshot of every stored record to IPFS, then pins a manifest of their CIDs and publishes it to IPNS.
+// Snapshots are content addressed, so records which have not changed since the last archive are not duplicated.
+func (s *PkarrService) archive() {
+	ctx := context.Background()
+	records, err := s.db.ListRecords(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("failed to list record(s) for archiving")
+		return
+	}
+
+	manifest := ArchiveManifest{Timestamp: time.Now().Unix()}
+	for _, record := range records {
+		archived, err := s.archiveRecord(ctx, record)
+		if err != nil {
+			logrus.WithError(err).Errorf("failed to archive record[%s]", record.K)
+			continue
+		}
+		if archived == nil {
+			continue
+		}
+		manifest.Records = append(manifest.Records, *archived)
+	}
+
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		logrus.WithError(err).Error("failed to marshal archive manifest")
+		return
+	}
+	cid, err := s.ipfs.Add(ctx, manifestBytes)
+	if err != nil {
+		logrus.WithError(err).Error("failed to pin archive manifest")
+		return
+	}
+	logrus.Infof("Archived %d out of %d record(s) to manifest[%s]", len(manifest.Records), len(records), cid)
+
+	if s.cfg.ArchiveConfig.IPNSKey == "" {
+		return
+	}
+	name, err := s.ipfs.Publish(ctx, cid, s.cfg.ArchiveConfig.IPNSKey)
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to publish archive manifest[%s] to ipns", cid)
+		return
+	}
+	logrus.Infof("Published archive manifest[%s] to ipns name[%s]", cid, name)
+}
+
+// archiveRecord pins a snapshot of the record, returning nil if the record is denied
+func (s *PkarrService) archiveRecord(ctx context.Context, record pkarr.Record) (*ArchivedRecord, error) {
+	id, err := recordID(record.K)
+	if err != nil {
+		return nil, err
+	}
+	if s.isDenied(id) {
+		return nil, nil
+	}
+	snapshot, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	cid, err := s.ipfs.Add(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &ArchivedRecord{ID: id, Seq: record.Seq, CID: cid}, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/ipfs"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func TestArchive(t *testing.T) {
+	var mu sync.Mutex
+	var added [][]byte
+	var published string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v0/add":
+			file, _, err := r.FormFile("file")
+			require.NoError(t, err)
+			data, err := io.ReadAll(file)
+			require.NoError(t, err)
+			added = append(added, data)
+			_, _ = fmt.Fprintf(w, `{"Hash":"cid%d"}`, len(added))
+		case "/api/v0/name/publish":
+			published = r.URL.Query().Get("arg")
+			_, _ = w.Write([]byte(`{"Name":"k51name","Value":"` + published + `"}`))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.GetDefaultConfig()
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "archive.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// a record with a z-base-32 encoded id of "yyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyy"
+	record := pkarr.Record{
+		V:   "dg",
+		K:   "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+		Sig: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+		Seq: 1,
+	}
+	require.NoError(t, db.WriteRecord(context.Background(), record))
+
+	svc := PkarrService{cfg: &cfg, db: db, ipfs: ipfs.NewClient(srv.URL)}
+	svc.archive()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, added, 2)
+
+	var snapshot pkarr.Record
+	require.NoError(t, json.Unmarshal(added[0], &snapshot))
+	assert.Equal(t, record, snapshot)
+
+	var manifest ArchiveManifest
+	require.NoError(t, json.Unmarshal(added[1], &manifest))
+	require.Len(t, manifest.Records, 1)
+	assert.Equal(t, ArchivedRecord{ID: "yyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyy", Seq: 1, CID: "cid1"}, manifest.Records[0])
+	assert.Equal(t, "/ipfs/cid2", published)
+}
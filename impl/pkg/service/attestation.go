@@ -0,0 +1,87 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// Attestation is a statement by the gateway that it served a record at a point in time
+type Attestation struct {
+	// Gateway is the base64url encoded ed25519 public key of the gateway
+	Gateway string `json:"gateway"`
+	ID      string `json:"id"`
+	Seq     int64  `json:"seq"`
+	// Hash is the hex encoded SHA-256 hash of the served payload: the record's signature, seq, and value
+	Hash      string `json:"hash"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// AttestPkarr returns a signed attestation that the gateway served the record with the given z-base-32 encoded ID,
+// in the compact form `<base64url encoded JSON attestation>.<base64url encoded signature>`. The attestation is
+// empty if attestations are disabled.
+func (s *PkarrService) AttestPkarr(id string, record GetPkarrResponse) (string, error) {
+	if !s.cfg.AttestationConfig.Enabled {
+		return "", nil
+	}
+	attestation := Attestation{
+		Gateway:   base64.RawURLEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey)),
+		ID:        id,
+		Seq:       record.Seq,
+		Hash:      hex.EncodeToString(hashPayload(record.Sig, record.Seq, record.V)),
+		Timestamp: time.Now().Unix(),
+	}
+	attestationBytes, err := json.Marshal(attestation)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(attestationBytes)
+	signature := ed25519.Sign(s.key, []byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyAttestation verifies the signature of a compact attestation against the gateway's public key
+func VerifyAttestation(compact string, publicKey ed25519.PublicKey) (*Attestation, error) {
+	payload, signature, ok := strings.Cut(compact, ".")
+	if !ok {
+		return nil, errors.New("attestation is not in compact form")
+	}
+	signatureBytes, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(publicKey, []byte(payload), signatureBytes) {
+		return nil, errors.New("attestation signature is invalid")
+	}
+	attestationBytes, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
+	}
+	var attestation Attestation
+	if err = json.Unmarshal(attestationBytes, &attestation); err != nil {
+		return nil, err
+	}
+	if attestation.Gateway != base64.RawURLEncoding.EncodeToString(publicKey) {
+		return nil, errors.New("attestation is from another gateway")
+	}
+	return &attestation, nil
+}
+
+// hashPayload returns the SHA-256 hash of a record's payload as served by the relay API: its signature,
+// big-endian seq, and value
+func hashPayload(sig [64]byte, seq int64, v []byte) []byte {
+	var seqBuf [8]byte
+	binary.BigEndian.PutUint64(seqBuf[:], uint64(seq))
+	hash := sha256.New()
+	hash.Write(sig[:])
+	hash.Write(seqBuf[:])
+	hash.Write(v)
+	return hash.Sum(nil)
+}
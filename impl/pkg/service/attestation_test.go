@@ -0,0 +1,49 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+func TestAttestPkarr(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	key, err := signingKey("")
+	require.NoError(t, err)
+	svc := PkarrService{cfg: &cfg, key: key}
+	record := GetPkarrResponse{V: []byte("v"), Seq: 1}
+
+	t.Run("disabled", func(t *testing.T) {
+		attestation, err := svc.AttestPkarr("alice", record)
+		assert.NoError(t, err)
+		assert.Empty(t, attestation)
+	})
+
+	cfg.AttestationConfig.Enabled = true
+
+	t.Run("verifies", func(t *testing.T) {
+		compact, err := svc.AttestPkarr("alice", record)
+		require.NoError(t, err)
+
+		attestation, err := VerifyAttestation(compact, key.Public().(ed25519.PublicKey))
+		require.NoError(t, err)
+		assert.Equal(t, "alice", attestation.ID)
+		assert.Equal(t, int64(1), attestation.Seq)
+		assert.Equal(t, hex.EncodeToString(hashPayload(record.Sig, record.Seq, record.V)), attestation.Hash)
+	})
+
+	t.Run("other gateway", func(t *testing.T) {
+		compact, err := svc.AttestPkarr("alice", record)
+		require.NoError(t, err)
+
+		otherKey, err := signingKey("")
+		require.NoError(t, err)
+		_, err = VerifyAttestation(compact, otherKey.Public().(ed25519.PublicKey))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBatchTooLarge is returned for batches of more IDs than the configured limit
+var ErrBatchTooLarge = errors.New("too many ids in batch")
+
+// BatchGetResult is the outcome of resolving one ID of a batch
+type BatchGetResult struct {
+	// Record is the resolved record, or nil if there is none
+	Record *GetPkarrResponse
+	// Err is the error resolving the record, if any
+	Err error
+}
+
+// BatchGetPkarr resolves the records of the given z-base-32 encoded IDs, returning a result for each ID in order.
+// Records are resolved like GetPkarr, sharing its cache, with at most the configured number in flight at once;
+// an ID repeated in the batch is resolved once.
+func (s *PkarrService) BatchGetPkarr(ctx context.Context, ids []string) ([]BatchGetResult, error) {
+	if limit := s.cfg.PkarrConfig.BatchGetLimit; limit > 0 && len(ids) > limit {
+		return nil, ErrBatchTooLarge
+	}
+	concurrency := s.cfg.PkarrConfig.BatchGetConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	unique := make(map[string]*BatchGetResult, len(ids))
+	for _, id := range ids {
+		unique[id] = new(BatchGetResult)
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for id, result := range unique {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id string, result *BatchGetResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result.Record, result.Err = s.GetPkarr(ctx, id)
+		}(id, result)
+	}
+	wg.Wait()
+
+	results := make([]BatchGetResult, 0, len(ids))
+	for _, id := range ids {
+		results = append(results, *unique[id])
+	}
+	return results, nil
+}
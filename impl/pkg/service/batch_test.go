@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+// countingDHT has no records, and counts the gets for them
+type countingDHT struct {
+	gets atomic.Int32
+}
+
+func (d *countingDHT) Put(context.Context, bep44.Put) (string, error) {
+	return "", nil
+}
+
+func (d *countingDHT) GetFull(context.Context, string, []byte) (*dht.FullGetResult, error) {
+	d.gets.Add(1)
+	return nil, errors.New("not found")
+}
+
+func TestBatchGetPkarr(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.PkarrConfig.BatchGetLimit = 4
+	cfg.PkarrConfig.BatchGetConcurrency = 2
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "batch.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	d := new(countingDHT)
+	svc, err := NewPkarrServiceWith(&cfg, db, d, cache.None{})
+	require.NoError(t, err)
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	put := bep44.Put{V: []byte("hello pkarr"), K: (*[32]byte)(pubKey), Seq: 1}
+	put.Sign(privKey)
+	id := util.Z32Encode(pubKey)
+	require.NoError(t, svc.storePkarr(context.Background(), id, PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}))
+
+	unknownKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	unknown := util.Z32Encode(unknownKey)
+
+	results, err := svc.BatchGetPkarr(context.Background(), []string{id, unknown, id})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, i := range []int{0, 2} {
+		assert.NoError(t, results[i].Err)
+		require.NotNil(t, results[i].Record)
+		assert.Equal(t, put.V, results[i].Record.V)
+	}
+	assert.NoError(t, results[1].Err)
+	assert.Nil(t, results[1].Record)
+	// repeated ids are resolved once
+	assert.Equal(t, int32(2), d.gets.Load())
+
+	_, err = svc.BatchGetPkarr(context.Background(), []string{id, id, id, id, id})
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+}
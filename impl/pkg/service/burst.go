@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultBurstWindow is the window updates are counted in by default
+	defaultBurstWindow = time.Minute
+	// defaultBurstCooldown is how long a bursting key is cooled down for by default
+	defaultBurstCooldown = 10 * time.Minute
+	// maxTrackedKeys bounds the number of keys bursts are counted for
+	maxTrackedKeys = 100_000
+)
+
+// CoolDownError is returned for updates to the records of a key cooling down after updating them too often
+type CoolDownError struct {
+	ID string
+	// Until is the time the cool-down ends at
+	Until time.Time
+}
+
+func (e *CoolDownError) Error() string {
+	return fmt.Sprintf("record[%s] is cooling down after too many updates, retry after %s", e.ID,
+		e.Until.UTC().Format(time.RFC3339))
+}
+
+// bursts detects keys updating their records pathologically often, such as hundreds of seq bumps a minute, and cools
+// them down by rejecting their updates for a while. Each accepted update is put to the DHT and republished, so this
+// keeps a single key from flooding the DHT neighbors of the gateway.
+type bursts struct {
+	// maxUpdates is the number of updates a key may make within the window
+	maxUpdates int
+	window     time.Duration
+	cooldown   time.Duration
+
+	mu   sync.Mutex
+	keys map[string]*keyBurst
+}
+
+// keyBurst counts the updates of one key in its current window
+type keyBurst struct {
+	lastSeq     int64
+	windowStart time.Time
+	updates     int
+	coolUntil   time.Time
+}
+
+// newBursts returns bursts cooling down keys which make more than maxUpdates updates within the window for the
+// cool-down, a minute and 10 minutes respectively if not positive
+func newBursts(maxUpdates int, window, cooldown time.Duration) *bursts {
+	if window <= 0 {
+		window = defaultBurstWindow
+	}
+	if cooldown <= 0 {
+		cooldown = defaultBurstCooldown
+	}
+	return &bursts{maxUpdates: maxUpdates, window: window, cooldown: cooldown, keys: make(map[string]*keyBurst)}
+}
+
+// InterceptPublish rejects updates to the records of the z-base-32 encoded ID with a CoolDownError while the key is
+// cooling down, and cools it down once it bursts. Publishing the seq last published isn't an update.
+func (b *bursts) InterceptPublish(_ context.Context, id string, request PublishPkarrRequest) error {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := b.key(id, now)
+	if now.Before(key.coolUntil) {
+		return &CoolDownError{ID: id, Until: key.coolUntil}
+	}
+	if key.updates > 0 && request.Seq == key.lastSeq {
+		return nil
+	}
+	if now.Sub(key.windowStart) >= b.window {
+		key.windowStart, key.updates = now, 0
+	}
+	key.lastSeq = request.Seq
+	key.updates++
+	if key.updates <= b.maxUpdates {
+		return nil
+	}
+	key.coolUntil, key.updates = now.Add(b.cooldown), 0
+	logrus.WithFields(logrus.Fields{
+		"id":      id,
+		"updates": b.maxUpdates + 1,
+		"window":  b.window.String(),
+		"until":   key.coolUntil.Unix(),
+	}).Warn("cooling down key updating its records too often")
+	return &CoolDownError{ID: id, Until: key.coolUntil}
+}
+
+// key returns the updates of the ID, making room for it by forgetting keys which are neither cooling down nor
+// within a window if too many are tracked
+func (b *bursts) key(id string, now time.Time) *keyBurst {
+	if key, ok := b.keys[id]; ok {
+		return key
+	}
+	if len(b.keys) >= maxTrackedKeys {
+		for tracked, key := range b.keys {
+			if now.After(key.coolUntil) && now.Sub(key.windowStart) >= b.window {
+				delete(b.keys, tracked)
+			}
+		}
+	}
+	key := &keyBurst{windowStart: now}
+	b.keys[id] = key
+	return key
+}
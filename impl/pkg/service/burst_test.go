@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBursts(t *testing.T) {
+	ctx := context.Background()
+	update := func(seq int64) PublishPkarrRequest {
+		return PublishPkarrRequest{Seq: seq}
+	}
+
+	t.Run("cools down bursting keys", func(t *testing.T) {
+		b := newBursts(3, time.Minute, time.Hour)
+		for seq := int64(1); seq <= 3; seq++ {
+			require.NoError(t, b.InterceptPublish(ctx, "alice", update(seq)))
+			// republishing the same seq isn't an update
+			require.NoError(t, b.InterceptPublish(ctx, "alice", update(seq)))
+		}
+		var coolDown *CoolDownError
+		require.ErrorAs(t, b.InterceptPublish(ctx, "alice", update(4)), &coolDown)
+		assert.Equal(t, "alice", coolDown.ID)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), coolDown.Until, time.Minute)
+
+		// every update is rejected while cooling down, and other keys are unaffected
+		assert.ErrorAs(t, b.InterceptPublish(ctx, "alice", update(3)), &coolDown)
+		assert.NoError(t, b.InterceptPublish(ctx, "bob", update(1)))
+	})
+
+	t.Run("updates are counted per window", func(t *testing.T) {
+		b := newBursts(2, 20*time.Millisecond, time.Hour)
+		require.NoError(t, b.InterceptPublish(ctx, "alice", update(1)))
+		require.NoError(t, b.InterceptPublish(ctx, "alice", update(2)))
+		time.Sleep(30 * time.Millisecond)
+		require.NoError(t, b.InterceptPublish(ctx, "alice", update(3)))
+		require.NoError(t, b.InterceptPublish(ctx, "alice", update(4)))
+	})
+
+	t.Run("cool-downs expire", func(t *testing.T) {
+		b := newBursts(1, time.Minute, 20*time.Millisecond)
+		require.NoError(t, b.InterceptPublish(ctx, "alice", update(1)))
+		var coolDown *CoolDownError
+		require.ErrorAs(t, b.InterceptPublish(ctx, "alice", update(2)), &coolDown)
+		time.Sleep(30 * time.Millisecond)
+		assert.NoError(t, b.InterceptPublish(ctx, "alice", update(2)))
+	})
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+)
+
+var (
+	errCacheInspectionUnsupported = errors.New("cache does not support inspection")
+	// errUnsignedCacheEntry is the corruption of a cached entry which decodes, but holds no signed record
+	errUnsignedCacheEntry = errors.New("cached entry holds no signed record")
+)
+
+// cacheAgeBuckets are the upper bounds of the ages cached entries are counted by, the last bucket holding the rest
+var cacheAgeBuckets = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour}
+
+// CacheStats is the efficiency of the record cache, for sizing it from data
+type CacheStats struct {
+	cache.Stats
+	// HitRatio is the share of lookups which found a cached record, zero before any lookup
+	HitRatio float64 `json:"hitRatio"`
+	// SizeLimitMB is the configured size limit of a cache local to the instance
+	SizeLimitMB int `json:"sizeLimitMb"`
+	// Ages is the number of cached entries by age
+	Ages []CacheAgeBucket `json:"ages"`
+	// LastCheck is the outcome of the last consistency check against storage, if any
+	LastCheck *CacheCheck `json:"lastCheck,omitempty"`
+	// Corrupted is the number of entries found corrupted by lookups since startup, which were evicted and resolved
+	// from the DHT or storage instead
+	Corrupted int64 `json:"corrupted"`
+	// Memory is the memory accounting of a cache local to the instance, by shard
+	Memory *cache.Usage `json:"memory,omitempty"`
+}
+
+// CacheAgeBucket is the number of cached entries cached less than MaxAgeSeconds ago, and at least as long as the
+// previous bucket's MaxAgeSeconds. The last bucket has no MaxAgeSeconds.
+type CacheAgeBucket struct {
+	MaxAgeSeconds int64 `json:"maxAgeSeconds,omitempty"`
+	Entries       int64 `json:"entries"`
+}
+
+// CacheCheck is the outcome of checking the cached entries against storage, evicting those which are orphaned: the
+// records of denied IDs, records older than the stored record, and entries which can't be decoded
+type CacheCheck struct {
+	// Started and Finished are the unix timestamps in seconds the check started and finished at
+	Started  int64 `json:"started"`
+	Finished int64 `json:"finished"`
+	// Checked is the number of entries checked
+	Checked int `json:"checked"`
+	// Denied, Stale, and Malformed count the entries evicted for each reason
+	Denied    int `json:"denied"`
+	Stale     int `json:"stale"`
+	Malformed int `json:"malformed"`
+}
+
+// cacheChecks serializes consistency checks, keeping the outcome of the last one, and counts the corrupted entries
+// found by lookups
+type cacheChecks struct {
+	mu        sync.Mutex
+	last      *CacheCheck
+	corrupted atomic.Int64
+}
+
+// GetCacheStats returns the efficiency of the record cache, and the distribution of the ages of its entries
+func (s *PkarrService) GetCacheStats(ctx context.Context) (*CacheStats, error) {
+	inspector, ok := s.cache.(cache.Inspector)
+	if !ok {
+		return nil, errCacheInspectionUnsupported
+	}
+	stats, err := inspector.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ages := make([]CacheAgeBucket, len(cacheAgeBuckets)+1)
+	for i, bound := range cacheAgeBuckets {
+		ages[i].MaxAgeSeconds = int64(bound.Seconds())
+	}
+	err = inspector.Entries(ctx, func(entry cache.Entry) bool {
+		i := 0
+		for i < len(cacheAgeBuckets) && entry.Age >= cacheAgeBuckets[i] {
+			i++
+		}
+		ages[i].Entries++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := CacheStats{Stats: *stats, SizeLimitMB: s.cfg.PkarrConfig.CacheSizeLimitMB, Ages: ages}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		result.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	s.cacheChecks.mu.Lock()
+	result.LastCheck = s.cacheChecks.last
+	s.cacheChecks.mu.Unlock()
+	result.Corrupted = s.cacheChecks.corrupted.Load()
+	if accountant, ok := s.cache.(cache.Accountant); ok {
+		if result.Memory, err = accountant.Usage(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+// FlushCache evicts every cached record, so each is next resolved from the DHT or storage
+func (s *PkarrService) FlushCache(ctx context.Context) error {
+	inspector, ok := s.cache.(cache.Inspector)
+	if !ok {
+		return errCacheInspectionUnsupported
+	}
+	if err := inspector.Flush(ctx); err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{"audit": "cache", "action": "flush"}).Info("record cache flushed")
+	return nil
+}
+
+// CheckCache checks the cached entries against storage, evicting those which are orphaned: the records of denied
+// IDs, which another instance sharing the cache may have cached, records older than the stored record, and entries
+// which can't be decoded. Records resolved from the DHT aren't necessarily stored, so they are kept.
+func (s *PkarrService) CheckCache(ctx context.Context) (*CacheCheck, error) {
+	inspector, ok := s.cache.(cache.Inspector)
+	if !ok {
+		return nil, errCacheInspectionUnsupported
+	}
+	s.cacheChecks.mu.Lock()
+	defer s.cacheChecks.mu.Unlock()
+
+	check := CacheCheck{Started: time.Now().Unix()}
+	var denied, stale, malformed []string
+	err := inspector.Entries(ctx, func(entry cache.Entry) bool {
+		check.Checked++
+		var cached cachedRecord
+		if err := json.Unmarshal(entry.Value, &cached); err != nil || cached.Sig == ([64]byte{}) {
+			malformed = append(malformed, entry.Key)
+			return true
+		}
+		id, encodedSalt, _ := strings.Cut(entry.Key, ".")
+		if s.isDenied(id) {
+			denied = append(denied, entry.Key)
+			return true
+		}
+		salt, err := base64.RawURLEncoding.DecodeString(encodedSalt)
+		if err != nil {
+			malformed = append(malformed, entry.Key)
+			return true
+		}
+		key, err := saltedRecordKey(id, salt)
+		if err != nil {
+			malformed = append(malformed, entry.Key)
+			return true
+		}
+		record, err := s.db.ReadRecord(ctx, key)
+		if err != nil {
+			logrus.WithError(err).Warnf("failed to read pkarr record[%s] to check the cache", entry.Key)
+			return true
+		}
+		if record != nil && record.Seq > cached.Seq {
+			stale = append(stale, entry.Key)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	// entries are evicted once enumerated, as a local cache can't be modified while iterating
+	for _, keys := range [][]string{denied, stale, malformed} {
+		for _, key := range keys {
+			if err = s.cache.Delete(ctx, key); err != nil {
+				logrus.WithError(err).Warnf("failed to evict record[%s] from cache", key)
+			}
+		}
+	}
+	check.Denied, check.Stale, check.Malformed = len(denied), len(stale), len(malformed)
+	check.Finished = time.Now().Unix()
+	s.cacheChecks.last = &check
+	logrus.Infof("checked %d cached record(s): evicted %d denied, %d stale, %d malformed",
+		check.Checked, check.Denied, check.Stale, check.Malformed)
+	return &check, nil
+}
+
+// checkCache runs a scheduled consistency check of the cache
+func (s *PkarrService) checkCache() {
+	if _, err := s.CheckCache(context.Background()); err != nil {
+		logrus.WithError(err).Error("failed to check the record cache")
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestCheckCache(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.PkarrConfig.CacheCheckCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("cache without inspection", func(t *testing.T) {
+		svc, err := NewPkarrServiceWith(&cfg, db, staticDHT{}, cache.None{})
+		require.NoError(t, err)
+		_, err = svc.GetCacheStats(context.Background())
+		assert.ErrorIs(t, err, errCacheInspectionUnsupported)
+	})
+
+	memory, err := cache.NewMemory(time.Minute, 1, 10000)
+	require.NoError(t, err)
+	svc, err := NewPkarrServiceWith(&cfg, db, staticDHT{}, memory)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	newID := func() (string, func(int64) PublishPkarrRequest) {
+		pubKey, privKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		return util.Z32Encode(pubKey), func(seq int64) PublishPkarrRequest {
+			put := bep44.Put{V: []byte("hello cache"), K: (*[32]byte)(pubKey), Seq: seq}
+			put.Sign(privKey)
+			return PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+		}
+	}
+	cacheResponse := func(id string, request PublishPkarrRequest) {
+		resp := GetPkarrResponse{V: request.V, Seq: request.Seq, Sig: request.Sig}
+		_, err := svc.cacheRecord(ctx, cacheKey(id, nil), resp, nil)
+		require.NoError(t, err)
+	}
+
+	// a record cached before a newer one was stored, by another instance sharing the cache
+	staleID, staleRequest := newID()
+	require.NoError(t, svc.storePkarr(ctx, staleID, staleRequest(2)))
+	cacheResponse(staleID, staleRequest(1))
+	// a record only resolved from the dht
+	dhtID, dhtRequest := newID()
+	cacheResponse(dhtID, dhtRequest(1))
+	// a record of a denied id
+	deniedID, deniedRequest := newID()
+	require.NoError(t, svc.DenyKey(ctx, deniedID, "spam"))
+	defer func() { _ = svc.AllowKey(ctx, deniedID) }()
+	cacheResponse(deniedID, deniedRequest(1))
+	require.NoError(t, memory.Set(ctx, "malformed", []byte("not json")))
+
+	_, err = svc.GetSaltedPkarr(ctx, dhtID, nil)
+	require.NoError(t, err)
+	stats, err := svc.GetCacheStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), stats.Entries)
+	assert.NotZero(t, stats.Hits)
+	assert.InDelta(t, float64(stats.Hits)/float64(stats.Hits+stats.Misses), stats.HitRatio, 1e-9)
+	require.Len(t, stats.Ages, len(cacheAgeBuckets)+1)
+	assert.Equal(t, int64(4), stats.Ages[0].Entries)
+	assert.Nil(t, stats.LastCheck)
+	require.NotNil(t, stats.Memory)
+	assert.Equal(t, int64(4), stats.Memory.Entries)
+
+	check, err := svc.CheckCache(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, check.Checked)
+	assert.Equal(t, 1, check.Stale)
+	assert.Equal(t, 1, check.Denied)
+	assert.Equal(t, 1, check.Malformed)
+	cached, err := svc.getCachedRecord(ctx, dhtID)
+	require.NoError(t, err)
+	assert.NotNil(t, cached, "records resolved from the dht are kept")
+
+	stats, err = svc.GetCacheStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Entries)
+	assert.Equal(t, check, stats.LastCheck)
+
+	require.NoError(t, svc.FlushCache(ctx))
+	stats, err = svc.GetCacheStats(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Entries)
+}
+
+func TestCorruptedCacheEntry(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.PkarrConfig.CacheCheckCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "corrupted.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	memory, err := cache.NewMemory(time.Minute, 1, 10000)
+	require.NoError(t, err)
+	svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), memory)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	put := bep44.Put{V: []byte("hello corruption"), K: (*[32]byte)(pubKey), Seq: 1}
+	put.Sign(privKey)
+	require.NoError(t, svc.storePkarr(ctx, id, PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}))
+
+	for i, corrupted := range []string{"not json", "{}"} {
+		require.NoError(t, memory.Set(ctx, cacheKey(id, nil), []byte(corrupted)))
+
+		// the record is resolved from storage, as the dht doesn't have it
+		got, err := svc.GetSaltedPkarr(ctx, id, nil)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, put.Sig, got.Sig)
+
+		stats, err := svc.GetCacheStats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(i+1), stats.Corrupted)
+		cached, err := svc.getCachedRecord(ctx, cacheKey(id, nil))
+		require.NoError(t, err)
+		require.NotNil(t, cached, "the corrupted entry is replaced")
+		assert.Equal(t, put.Sig, cached.Sig)
+	}
+}
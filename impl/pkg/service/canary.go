@@ -0,0 +1,266 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+
+	intutil "github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+)
+
+const (
+	// CanaryStagePublish, CanaryStageDHT, and CanaryStageResolve are the stages of the canary's round trip: publishing
+	// the record to storage and the DHT, finding it in the DHT, and resolving it through the cache and storage
+	CanaryStagePublish = "publish"
+	CanaryStageDHT     = "dht"
+	CanaryStageResolve = "resolve"
+)
+
+// canarySalt distinguishes the canary record from the gateway's announced DID, which is published under the same key
+var canarySalt = []byte("canary")
+
+var (
+	// ErrCanaryDisabled is returned for running the canary, or its status, when it isn't scheduled
+	ErrCanaryDisabled = errors.New("canary is disabled")
+	// ErrCanaryRunning is returned for running the canary while a round trip is already running
+	ErrCanaryRunning = errors.New("canary is already running")
+)
+
+// CanaryResult is the outcome of one round trip of the canary record
+type CanaryResult struct {
+	Started time.Time `json:"started"`
+	Seq     int64     `json:"seq"`
+	Healthy bool      `json:"healthy"`
+	// Stage is the stage the round trip failed at, and Error why, if it failed
+	Stage string `json:"stage,omitempty"`
+	Error string `json:"error,omitempty"`
+	// PublishMillis is how long publishing took, DHTMillis how long after publishing the record was found in the DHT,
+	// and ResolveMillis how long resolving it took
+	PublishMillis int64 `json:"publishMillis"`
+	DHTMillis     int64 `json:"dhtMillis"`
+	ResolveMillis int64 `json:"resolveMillis"`
+}
+
+// CanaryStatus counts the canary's round trips since the gateway started
+type CanaryStatus struct {
+	// Running is whether a round trip is running
+	Running  bool  `json:"running"`
+	Runs     int64 `json:"runs"`
+	Failures int64 `json:"failures"`
+	// ConsecutiveFailures is the number of round trips which failed since the last which succeeded
+	ConsecutiveFailures int64 `json:"consecutiveFailures"`
+	// Last is the outcome of the last round trip, if any
+	Last *CanaryResult `json:"last,omitempty"`
+}
+
+// Healthy returns whether the last round trip succeeded, or none has finished yet
+func (c CanaryStatus) Healthy() bool {
+	return c.Last == nil || c.Last.Healthy
+}
+
+// canary keeps one round trip of the canary record running at a time, counting them
+type canary struct {
+	// timeout bounds each round trip, and pollInterval is how often the DHT is checked for the record until it is found
+	timeout      time.Duration
+	pollInterval time.Duration
+
+	mu     sync.Mutex
+	status CanaryStatus
+}
+
+func newCanary() *canary {
+	return &canary{timeout: 2 * time.Minute, pollInterval: 5 * time.Second}
+}
+
+// begin marks a round trip running, returning false if one already is
+func (c *canary) begin() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status.Running {
+		return false
+	}
+	c.status.Running = true
+	return true
+}
+
+// finish counts the round trip, alerting if it failed
+func (c *canary) finish(result CanaryResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := &c.status
+	status.Running = false
+	status.Runs++
+	status.Last = &result
+	if result.Healthy {
+		status.ConsecutiveFailures = 0
+		logrus.WithFields(logrus.Fields{
+			"seq":           result.Seq,
+			"publishMillis": result.PublishMillis,
+			"dhtMillis":     result.DHTMillis,
+			"resolveMillis": result.ResolveMillis,
+		}).Info("canary round trip succeeded")
+		return
+	}
+	status.Failures++
+	status.ConsecutiveFailures++
+	logrus.WithFields(logrus.Fields{
+		"alert":               "canary",
+		"seq":                 result.Seq,
+		"stage":               result.Stage,
+		"error":               result.Error,
+		"consecutiveFailures": status.ConsecutiveFailures,
+	}).Warn("canary round trip failed")
+}
+
+// RunCanary publishes a synthetic record under the gateway's own key, salted so it doesn't replace the gateway's
+// announced DID, then waits for it to be found in the DHT and resolves it through the cache and storage, catching
+// writes which fail silently. The canary's publishes and resolutions aren't counted in SLA reports.
+func (s *PkarrService) RunCanary(ctx context.Context) (*CanaryResult, error) {
+	if s.canary == nil {
+		return nil, ErrCanaryDisabled
+	}
+	if !s.canary.begin() {
+		return nil, ErrCanaryRunning
+	}
+	result := s.canaryRoundTrip(ctx)
+	return &result, nil
+}
+
+// StartCanary starts a round trip of the canary in the background, returning the status to poll for its outcome
+func (s *PkarrService) StartCanary() (*CanaryStatus, error) {
+	if s.canary == nil {
+		return nil, ErrCanaryDisabled
+	}
+	if !s.canary.begin() {
+		return nil, ErrCanaryRunning
+	}
+	s.goBackground(func() { s.canaryRoundTrip(context.Background()) })
+	return s.GetCanaryStatus()
+}
+
+// GetCanaryStatus returns the counts of the canary's round trips since the gateway started, with the last outcome
+func (s *PkarrService) GetCanaryStatus() (*CanaryStatus, error) {
+	if s.canary == nil {
+		return nil, ErrCanaryDisabled
+	}
+	s.canary.mu.Lock()
+	defer s.canary.mu.Unlock()
+	status := s.canary.status
+	return &status, nil
+}
+
+// canaryRoundTrip runs a round trip of the canary which has begun, bounded by the canary's timeout, and counts it
+func (s *PkarrService) canaryRoundTrip(ctx context.Context) CanaryResult {
+	ctx, cancel := context.WithTimeout(ctx, s.canary.timeout)
+	defer cancel()
+	result := s.roundTrip(ctx)
+	s.canary.finish(result)
+	return result
+}
+
+// roundTrip publishes, finds, and resolves a new canary record, returning the stage it failed at, if any
+func (s *PkarrService) roundTrip(ctx context.Context) (result CanaryResult) {
+	result.Started = time.Now()
+	fail := func(stage string, err error) CanaryResult {
+		result.Stage, result.Error = stage, err.Error()
+		return result
+	}
+
+	pubKey := s.key.Public().(ed25519.PublicKey)
+	id, request, err := s.canaryRecord(ctx, pubKey)
+	if err != nil {
+		return fail(CanaryStagePublish, err)
+	}
+	result.Seq = request.Seq
+	if err = s.publishPkarr(ctx, id, *request); err != nil {
+		return fail(CanaryStagePublish, err)
+	}
+	published := time.Now()
+	result.PublishMillis = published.Sub(result.Started).Milliseconds()
+
+	// the record is put to the DHT asynchronously, so the DHT is polled until it has the record
+	ticker := time.NewTicker(s.canary.pollInterval)
+	defer ticker.Stop()
+	for {
+		got, err := s.getFromDHT(ctx, id, canarySalt)
+		if err == nil && got.Seq == request.Seq && bytes.Equal(got.V, request.V) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				err = fmt.Errorf("dht has seq %d instead", got.Seq)
+			}
+			return fail(CanaryStageDHT, fmt.Errorf("record not found in the dht: %w", err))
+		case <-ticker.C:
+		}
+	}
+	resolving := time.Now()
+	result.DHTMillis = resolving.Sub(published).Milliseconds()
+
+	resp, err := s.getSaltedPkarr(ctx, id, canarySalt)
+	if err != nil {
+		return fail(CanaryStageResolve, err)
+	}
+	if resp == nil || resp.Seq != request.Seq || !bytes.Equal(resp.V, request.V) {
+		return fail(CanaryStageResolve, errors.New("resolved a different record than published"))
+	}
+	result.ResolveMillis = time.Since(resolving).Milliseconds()
+	result.Healthy = true
+	return result
+}
+
+// canaryRecord returns a new canary record, a TXT record of a random nonce signed by the given key of the gateway,
+// with a seq newer than the stored record's
+func (s *PkarrService) canaryRecord(ctx context.Context, pubKey ed25519.PublicKey) (string, *PublishPkarrRequest, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	msg := dns.Msg{
+		MsgHdr: dns.MsgHdr{Response: true, Authoritative: true},
+		Answer: []dns.RR{&dns.TXT{
+			Hdr: dns.RR_Header{Name: "_canary.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+			Txt: []string{hex.EncodeToString(nonce)},
+		}},
+	}
+	v, err := msg.Pack()
+	if err != nil {
+		return "", nil, err
+	}
+
+	id := intutil.Z32Encode(pubKey)
+	key, err := saltedRecordKey(id, canarySalt)
+	if err != nil {
+		return "", nil, err
+	}
+	current, err := s.db.ReadRecord(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+	var prev int64
+	if current != nil {
+		prev = current.Seq
+	}
+	put := bep44.Put{V: v, K: (*[32]byte)(pubKey), Salt: canarySalt, Seq: dht.NextSeq(prev)}
+	put.Sign(s.key)
+	return id, &PublishPkarrRequest{V: v, K: *put.K, Sig: put.Sig, Seq: put.Seq, Salt: canarySalt}, nil
+}
+
+// runCanary runs a scheduled round trip of the canary
+func (s *PkarrService) runCanary() {
+	if _, err := s.RunCanary(context.Background()); err != nil {
+		logrus.WithError(err).Error("failed to run canary")
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+// memoryDHT keeps the last record put, acknowledging but dropping puts while dropping is set
+type memoryDHT struct {
+	dropping atomic.Bool
+
+	mu   sync.Mutex
+	last *dht.FullGetResult
+}
+
+func (d *memoryDHT) Put(_ context.Context, put bep44.Put) (string, error) {
+	if d.dropping.Load() {
+		return "", nil
+	}
+	v, err := bencode.Marshal(put.V)
+	if err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.last = &dht.FullGetResult{Seq: put.Seq, V: v, Sig: put.Sig, Mutable: true}
+	return "", nil
+}
+
+func (d *memoryDHT) GetFull(context.Context, string, []byte) (*dht.FullGetResult, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.last == nil {
+		return nil, errors.New("not found")
+	}
+	last := *d.last
+	return &last, nil
+}
+
+func TestCanary(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "canary.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("disabled", func(t *testing.T) {
+		svc, err := NewPkarrServiceWith(&cfg, db, new(memoryDHT), cache.None{})
+		require.NoError(t, err)
+		_, err = svc.RunCanary(context.Background())
+		assert.ErrorIs(t, err, ErrCanaryDisabled)
+		_, err = svc.GetCanaryStatus()
+		assert.ErrorIs(t, err, ErrCanaryDisabled)
+	})
+
+	cfg.ServerConfig.CanaryCRON = "0 0 1 1 *"
+	d := new(memoryDHT)
+	svc, err := NewPkarrServiceWith(&cfg, db, d, cache.None{})
+	require.NoError(t, err)
+	svc.canary.timeout = time.Second
+	svc.canary.pollInterval = 10 * time.Millisecond
+	ctx := context.Background()
+
+	result, err := svc.RunCanary(ctx)
+	require.NoError(t, err)
+	assert.True(t, result.Healthy, result.Error)
+	assert.NotZero(t, result.Seq)
+	status, err := svc.GetCanaryStatus()
+	require.NoError(t, err)
+	assert.True(t, status.Healthy())
+	assert.Equal(t, int64(1), status.Runs)
+	assert.False(t, status.Running)
+
+	// the canary is salted, leaving the unsalted record of the gateway's key alone
+	resp, err := svc.GetPkarr(ctx, util.Z32Encode(svc.key.Public().(ed25519.PublicKey)))
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+
+	t.Run("silent dht write failures", func(t *testing.T) {
+		d.dropping.Store(true)
+		defer d.dropping.Store(false)
+		result, err := svc.RunCanary(ctx)
+		require.NoError(t, err)
+		assert.False(t, result.Healthy)
+		assert.Equal(t, CanaryStageDHT, result.Stage)
+
+		status, err := svc.GetCanaryStatus()
+		require.NoError(t, err)
+		assert.False(t, status.Healthy())
+		assert.Equal(t, int64(2), status.Runs)
+		assert.Equal(t, int64(1), status.Failures)
+		assert.Equal(t, int64(1), status.ConsecutiveFailures)
+	})
+
+	t.Run("one round trip at a time", func(t *testing.T) {
+		require.True(t, svc.canary.begin())
+		_, err := svc.RunCanary(ctx)
+		assert.ErrorIs(t, err, ErrCanaryRunning)
+		_, err = svc.StartCanary()
+		assert.ErrorIs(t, err, ErrCanaryRunning)
+		svc.canary.finish(CanaryResult{Healthy: true})
+
+		status, err := svc.GetCanaryStatus()
+		require.NoError(t, err)
+		assert.True(t, status.Healthy())
+		assert.Zero(t, status.ConsecutiveFailures)
+	})
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cdc"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	// cdcQueueSize bounds the changes waiting to be published, beyond which changes are dropped rather than
+	// holding up publishing records while the broker is unavailable
+	cdcQueueSize = 10000
+	// cdcPublishTimeout bounds how long publishing one change may take
+	cdcPublishTimeout = 10 * time.Second
+	// cdcAttempts is the number of times publishing a change is attempted
+	cdcAttempts = 3
+)
+
+// changes publishes each change to a stored record to a message broker, in the order the changes were made
+type changes struct {
+	publisher cdc.Publisher
+	encode    func(cdc.Event) ([]byte, error)
+	queue     chan cdc.Event
+	dropped   atomic.Int64
+	// done is closed once the queued changes are published after closing the queue
+	done chan struct{}
+}
+
+// newChanges returns the change data capture described by the config, starting its publisher, or nil if disabled
+func newChanges(cfg config.CDCConfig) (*changes, error) {
+	if !cfg.Sink.IsValid() {
+		return nil, fmt.Errorf("unknown cdc sink: %s", cfg.Sink)
+	}
+	if !cfg.Format.IsValid() {
+		return nil, fmt.Errorf("unknown cdc format: %s", cfg.Format)
+	}
+	var publisher cdc.Publisher
+	var err error
+	switch cfg.Sink {
+	case "":
+		return nil, nil
+	case config.CDCSinkKafka:
+		publisher, err = cdc.NewKafkaPublisher(cfg.URL, cfg.Topic)
+	case config.CDCSinkNATS:
+		publisher, err = cdc.NewNATSPublisher(cfg.URL, cfg.Topic)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return startChanges(publisher, cfg.Format), nil
+}
+
+// startChanges returns changes published by the publisher in the format, starting to publish them
+func startChanges(publisher cdc.Publisher, format config.CDCFormat) *changes {
+	c := &changes{
+		publisher: publisher,
+		encode:    cdc.EncodeJSON,
+		queue:     make(chan cdc.Event, cdcQueueSize),
+		done:      make(chan struct{}),
+	}
+	if format == config.CDCFormatProtobuf {
+		c.encode = cdc.EncodeProtobuf
+	}
+	go c.run()
+	return c
+}
+
+// emitChange emits the change to the record of the given z-base-32 encoded ID
+func (s *PkarrService) emitChange(op cdc.Op, id string, record *pkarr.Record) {
+	s.events.change.emit(cdc.Event{Op: op, ID: id, Record: record, Timestamp: time.Now().Unix()})
+}
+
+// queueChange queues the change for publishing, if change data capture is enabled. It subscribes to the changes
+// emitted rather than queueing them on a goroutine of its own, keeping their order.
+func (s *PkarrService) queueChange(event cdc.Event) {
+	if s.changes == nil {
+		return
+	}
+	select {
+	case s.changes.queue <- event:
+	default:
+		dropped := s.changes.dropped.Add(1)
+		logrus.Warnf("cdc queue is full, dropped %s of record[%s] (%d dropped since startup)", event.Op, event.ID, dropped)
+	}
+}
+
+// run publishes the queued changes one at a time, keeping their order
+func (c *changes) run() {
+	defer close(c.done)
+	for event := range c.queue {
+		payload, err := c.encode(event)
+		if err != nil {
+			logrus.WithError(err).Errorf("failed to encode cdc %s of record[%s]", event.Op, event.ID)
+			continue
+		}
+		for attempt := 1; attempt <= cdcAttempts; attempt++ {
+			ctx, cancel := context.WithTimeout(context.Background(), cdcPublishTimeout)
+			err = c.publisher.Publish(ctx, event.ID, payload)
+			cancel()
+			if err == nil {
+				break
+			}
+			if attempt < cdcAttempts {
+				time.Sleep(time.Duration(attempt) * time.Second)
+			}
+		}
+		if err != nil {
+			dropped := c.dropped.Add(1)
+			logrus.WithError(err).Errorf("failed to publish cdc %s of record[%s] (%d dropped since startup)", event.Op, event.ID, dropped)
+		}
+	}
+}
+
+// close publishes the queued changes, or as many as it can until the context ends, and closes the publisher. No change
+// may be queued once closing.
+func (c *changes) close(ctx context.Context) error {
+	close(c.queue)
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		logrus.Warnf("stopped publishing cdc changes with %d queued", len(c.queue))
+	}
+	return c.publisher.Close()
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cdc"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+// recordingPublisher records the keys of the payloads published to it
+type recordingPublisher struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, key string, _ []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append(p.keys, key)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	return nil
+}
+
+func (p *recordingPublisher) published() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.keys)
+}
+
+func TestChanges(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	request := func(v string, seq int64) PublishPkarrRequest {
+		put := bep44.Put{V: []byte(v), K: (*[32]byte)(pubKey), Seq: seq}
+		put.Sign(privKey)
+		return PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+	}
+
+	t.Run("unknown sink", func(t *testing.T) {
+		_, err := newChanges(config.CDCConfig{Sink: "kinesis"})
+		assert.Error(t, err)
+		changes, err := newChanges(config.CDCConfig{})
+		assert.NoError(t, err)
+		assert.Nil(t, changes)
+	})
+
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "cdc.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, staticDHT{}, cache.None{})
+	require.NoError(t, err)
+	// changes are queued in the order they are made
+	svc.changes = &changes{queue: make(chan cdc.Event, 10)}
+
+	ctx := context.Background()
+	require.NoError(t, svc.storePkarr(ctx, id, request("first", 1)))
+	// republishing the same seq isn't a change
+	require.NoError(t, svc.storePkarr(ctx, id, request("first", 1)))
+	require.NoError(t, svc.storePkarr(ctx, id, request("second", 2)))
+	require.NoError(t, svc.DenyKey(ctx, id, "phishing"))
+	close(svc.changes.queue)
+
+	var ops []cdc.Op
+	for event := range svc.changes.queue {
+		assert.Equal(t, id, event.ID)
+		ops = append(ops, event.Op)
+	}
+	assert.Equal(t, []cdc.Op{cdc.OpCreate, cdc.OpUpdate, cdc.OpDelete}, ops)
+
+	t.Run("publishes queued changes", func(t *testing.T) {
+		publisher := new(recordingPublisher)
+		svc.changes = startChanges(publisher, config.CDCFormatProtobuf)
+		require.NoError(t, svc.AllowKey(ctx, id))
+		require.NoError(t, svc.storePkarr(ctx, id, request("third", 3)))
+		assert.Eventually(t, func() bool { return publisher.published() == 1 }, time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	dhtint "github.com/TBD54566975/did-dht-method/impl/internal/dht"
+)
+
+// lifecycle tracks the scheduled jobs and background goroutines the service starts, so Close can stop them
+type lifecycle struct {
+	mu         sync.Mutex
+	closed     bool
+	schedulers []*dhtint.Scheduler
+	background sync.WaitGroup
+	// unsubscribe stops the service's own subscriptions to its events
+	unsubscribe []func()
+	// stopChanges stops change data capture once the work which could emit changes has stopped
+	stopChanges sync.Once
+}
+
+// schedule runs the job on the cron schedule until the service is closed
+func (s *PkarrService) schedule(cron string, job func()) error {
+	scheduler := dhtint.NewScheduler()
+	if err := scheduler.Schedule(cron, job); err != nil {
+		return err
+	}
+	s.lifecycle.mu.Lock()
+	defer s.lifecycle.mu.Unlock()
+	if s.lifecycle.closed {
+		scheduler.Stop()
+		return nil
+	}
+	s.lifecycle.schedulers = append(s.lifecycle.schedulers, &scheduler)
+	return nil
+}
+
+// goBackground runs the work on a goroutine of its own, which Close waits for, unless the service is closed
+func (s *PkarrService) goBackground(work func()) {
+	s.lifecycle.mu.Lock()
+	defer s.lifecycle.mu.Unlock()
+	if s.lifecycle.closed {
+		return
+	}
+	s.lifecycle.background.Add(1)
+	go func() {
+		defer s.lifecycle.background.Done()
+		work()
+	}()
+}
+
+// Close stops the service's scheduled jobs, such as republishing, history checkpoints, and pruning, and its background
+// work. It drains the service first, so long-running work stops early, then waits for in-flight work and DHT puts to
+// finish, or the context to end, before stopping change data capture. The storage, DHT, and cache the service was
+// created with are left open for their owner to close. It is idempotent.
+func (s *PkarrService) Close(ctx context.Context) error {
+	s.Drain()
+	s.StopCrawl()
+
+	s.lifecycle.mu.Lock()
+	s.lifecycle.closed = true
+	schedulers := s.lifecycle.schedulers
+	s.lifecycle.schedulers = nil
+	s.lifecycle.mu.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		// stopping a scheduler waits for its running job
+		for _, scheduler := range schedulers {
+			scheduler.Stop()
+		}
+		s.lifecycle.background.Wait()
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for !s.drain.status().Drained {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var err error
+	s.lifecycle.stopChanges.Do(func() {
+		for _, unsubscribe := range s.lifecycle.unsubscribe {
+			unsubscribe()
+		}
+		if s.changes != nil {
+			err = s.changes.close(ctx)
+		}
+	})
+	return err
+}
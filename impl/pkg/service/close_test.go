@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestClose(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = "0 0 * * *"
+	cfg.RetentionConfig.MaxVersionsPerRecord = 2
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "close.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, staticDHT{}, cache.None{})
+	require.NoError(t, err)
+	assert.Len(t, svc.lifecycle.schedulers, 2, "republishing and version pruning")
+
+	publisher := new(recordingPublisher)
+	svc.changes = startChanges(publisher, config.CDCFormatJSON)
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	put := bep44.Put{V: []byte("hello pkarr"), K: (*[32]byte)(pubKey), Seq: 1}
+	put.Sign(privKey)
+	require.NoError(t, svc.PublishPkarr(context.Background(), util.Z32Encode(pubKey), PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}))
+
+	release := make(chan struct{})
+	svc.goBackground(func() { <-release })
+
+	// closing waits for background work
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, svc.Close(ctx), context.DeadlineExceeded)
+	assert.False(t, svc.Ready(), "closing drains the service")
+	assert.Empty(t, svc.lifecycle.schedulers)
+
+	close(release)
+	require.NoError(t, svc.Close(context.Background()))
+	assert.Equal(t, 1, publisher.published(), "queued changes are published before closing")
+
+	// no work is started once closed
+	svc.goBackground(func() { t.Error("background work started after closing") })
+	require.NoError(t, svc.schedule("0 0 * * *", func() {}))
+	assert.Empty(t, svc.lifecycle.schedulers)
+	require.NoError(t, svc.Close(context.Background()), "closing again is a no-op")
+}
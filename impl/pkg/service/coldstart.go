@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	intutil "github.com/TBD54566975/did-dht-method/impl/internal/util"
+)
+
+// coldStart reads the records missing from storage through from peer gateways during the first hours after the
+// gateway starts, storing them, so a new deployment is useful before its storage fills up
+type coldStart struct {
+	// peers resolves records from the peer gateways
+	peers *fallback
+	// until is when the gateway stops reading records through
+	until time.Time
+}
+
+// active returns whether records are still read through from the peer gateways
+func (c *coldStart) active(now time.Time) bool {
+	return c != nil && now.Before(c.until)
+}
+
+// readThrough resolves the record for the given z-base-32 encoded ID from the peer gateways while the gateway is
+// cold, storing it like a published record so it is resolved from storage, and republished, from then on. It returns
+// nil once the gateway is warm, or if no peer has the record.
+func (s *PkarrService) readThrough(ctx context.Context, id string) *GetPkarrResponse {
+	if !s.coldStart.active(time.Now()) {
+		return nil
+	}
+	resp := s.coldStart.peers.resolve(ctx, id)
+	if resp == nil {
+		logrus.Debugf("pkarr record[%s] not found on any peer gateway", id)
+		return nil
+	}
+	if s.seqSuppressed(id, nil, resp.Seq) {
+		logrus.Debugf("pkarr record[%s] from peer gateways has a reset seq", id)
+		return nil
+	}
+	// the peers only return records signed by the key of the ID
+	k, err := intutil.Z32Decode(id)
+	if err != nil || len(k) != 32 {
+		return nil
+	}
+	request := PublishPkarrRequest{V: resp.V, K: [32]byte(k), Sig: resp.Sig, Seq: resp.Seq}
+	if err = s.storePkarr(ctx, id, request); err != nil {
+		if !errors.Is(err, ErrStaleSeq) {
+			logrus.WithError(err).Warnf("failed to store pkarr record[%s] read through from peer gateways", id)
+		}
+		// the record is served all the same, as it would be from the fallback gateways
+		if _, err = s.cacheRecord(ctx, cacheKey(id, nil), *resp, nil); err != nil {
+			logrus.WithError(err).Errorf("failed to set pkarr record[%s] in cache", id)
+		}
+		return resp
+	}
+	logrus.Debugf("read pkarr record[%s] through from peer gateways", id)
+	return resp
+}
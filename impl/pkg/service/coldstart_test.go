@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestColdStart(t *testing.T) {
+	bodies := make(map[string][]byte)
+	newRecord := func() string {
+		pubKey, privKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		put := bep44.Put{V: []byte("hello cold start"), K: (*[32]byte)(pubKey), Seq: 1}
+		put.Sign(privKey)
+		var seq [8]byte
+		binary.BigEndian.PutUint64(seq[:], uint64(put.Seq))
+		id := util.Z32Encode(pubKey)
+		bodies[id] = append(append(put.Sig[:], seq[:]...), put.V.([]byte)...)
+		return id
+	}
+	warmID, coldID := newRecord(), newRecord()
+	var requests atomic.Int32
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		body, ok := bodies[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer peer.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.PkarrConfig.ColdStartGateways = []string{peer.URL}
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "coldstart.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// the dht doesn't have the record, so it is read through from the peer and stored
+	got, err := svc.GetPkarr(ctx, coldID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, int64(1), got.Seq)
+	key, err := saltedRecordKey(coldID, nil)
+	require.NoError(t, err)
+	stored, err := db.ReadRecord(ctx, key)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, int64(1), stored.Seq)
+
+	// once stored, it is resolved from storage
+	_, err = svc.GetPkarr(ctx, coldID)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+
+	// records no peer has aren't found
+	got, err = svc.GetPkarr(ctx, util.Z32Encode(make([]byte, 32)))
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	assert.Equal(t, int32(2), requests.Load())
+
+	// once warm, the peers aren't asked
+	svc.coldStart.until = time.Now().Add(-time.Second)
+	got, err = svc.GetPkarr(ctx, warmID)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	assert.Equal(t, int32(2), requests.Load())
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+// compressStored compresses the stored records written before compression was enabled in the background, stopping
+// early if the gateway drains
+func (s *PkarrService) compressStored(compressed *storage.Compressed) {
+	if !s.drain.begin() {
+		return
+	}
+	defer s.drain.done()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.drain.stopping():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	n, err := storage.CompressRecords(ctx, compressed)
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to compress stored pkarr records after compressing %d", n)
+		return
+	}
+	if n > 0 {
+		logrus.Infof("compressed %d stored pkarr record(s)", n)
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// confirmations tracks when the seq of each record was last confirmed on the DHT, by a put stored by as many nodes as
+// targeted, or by a complete get finding it without conflict, so republishing can skip records which were
+// confirmed recently and haven't changed since
+type confirmations struct {
+	// window is how recently a record must have been confirmed to skip republishing it
+	window time.Duration
+
+	mu        sync.Mutex
+	confirmed map[string]confirmation
+}
+
+// confirmation is the seq of a record confirmed on the DHT, and when
+type confirmation struct {
+	seq int64
+	at  time.Time
+}
+
+func newConfirmations(window time.Duration) *confirmations {
+	return &confirmations{window: window, confirmed: make(map[string]confirmation)}
+}
+
+// confirm records that the seq of the record stored under the given key was found on the DHT at the given time,
+// unless a newer seq already was
+func (c *confirmations) confirm(key string, seq int64, at time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, ok := c.confirmed[key]; ok && current.seq > seq {
+		return
+	}
+	c.confirmed[key] = confirmation{seq: seq, at: at}
+}
+
+// recent returns whether the seq of the record stored under the given key was confirmed within the window before
+// the given time
+func (c *confirmations) recent(key string, seq int64, now time.Time) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	current, ok := c.confirmed[key]
+	return ok && current.seq == seq && now.Sub(current.at) < c.window
+}
+
+// forget forgets the confirmation of the record stored under the given key, such as once it is purged
+func (c *confirmations) forget(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.confirmed, key)
+}
+
+// prune forgets the confirmations older than the window before the given time, which no longer skip republishing
+func (c *confirmations) prune(now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, current := range c.confirmed {
+		if now.Sub(current.at) >= c.window {
+			delete(c.confirmed, key)
+		}
+	}
+}
+
+// confirmOnDHT records that the seq of the record for the given z-base-32 encoded ID and salt was found on the DHT
+func (s *PkarrService) confirmOnDHT(id string, salt []byte, seq int64) {
+	if s.confirmations == nil {
+		return
+	}
+	key, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return
+	}
+	s.confirmations.confirm(key, seq, time.Now())
+}
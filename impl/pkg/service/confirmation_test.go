@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestConfirmations(t *testing.T) {
+	c := newConfirmations(time.Minute)
+	now := time.Now()
+	c.confirm("key", 2, now)
+	assert.True(t, c.recent("key", 2, now.Add(30*time.Second)))
+	assert.False(t, c.recent("key", 2, now.Add(time.Minute)), "the confirmation is outside the window")
+	assert.False(t, c.recent("key", 3, now), "a newer record wasn't confirmed")
+	assert.False(t, c.recent("other", 2, now))
+
+	// an older seq found on the DHT doesn't replace the confirmation of a newer one
+	c.confirm("key", 1, now.Add(time.Second))
+	assert.True(t, c.recent("key", 2, now))
+
+	c.prune(now.Add(time.Minute))
+	assert.Empty(t, c.confirmed)
+
+	var disabled *confirmations
+	disabled.confirm("key", 2, now)
+	assert.False(t, disabled.recent("key", 2, now))
+}
+
+func TestRepublishSkipsConfirmed(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.PkarrConfig.RepublishSkipConfirmedSeconds = 600
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "confirmation.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	d := new(flakyDHT)
+	svc, err := NewPkarrServiceWith(&cfg, db, d, cache.None{})
+	require.NoError(t, err)
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	store := func(seq int64) {
+		put := bep44.Put{V: []byte("hello pkarr"), K: (*[32]byte)(pubKey), Seq: seq}
+		put.Sign(privKey)
+		require.NoError(t, svc.storePkarr(context.Background(), id, PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}))
+	}
+	events := make(chan RepublishCompleteEvent, 3)
+	defer svc.Events().OnRepublishComplete(func(e RepublishCompleteEvent) { events <- e })()
+	republish := func() RepublishCompleteEvent {
+		svc.republish()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("republishing didn't complete")
+			return RepublishCompleteEvent{}
+		}
+	}
+
+	store(1)
+	e := republish()
+	assert.Equal(t, 1, e.Republished)
+	assert.Equal(t, int32(1), d.puts.Load())
+
+	e = republish()
+	assert.Equal(t, 1, e.Skipped, "the record was confirmed by the last republish")
+	assert.Zero(t, e.Republished)
+	assert.Equal(t, int32(1), d.puts.Load())
+
+	// a changed record is republished regardless
+	store(2)
+	e = republish()
+	assert.Equal(t, 1, e.Republished)
+	assert.Equal(t, int32(2), d.puts.Load())
+
+	t.Run("failed puts aren't confirmed", func(t *testing.T) {
+		store(3)
+		d.failing.Store(true)
+		e := republish()
+		assert.Equal(t, 1, e.Failed)
+		d.failing.Store(false)
+		e = republish()
+		assert.Equal(t, 1, e.Republished)
+	})
+
+	t.Run("denied records aren't counted as republished", func(t *testing.T) {
+		store(4)
+		require.NoError(t, svc.DenyKey(context.Background(), id, "spam"))
+		puts := d.puts.Load()
+		e := republish()
+		assert.Equal(t, 1, e.Denied)
+		assert.Zero(t, e.Republished)
+		assert.Zero(t, e.Skipped)
+		assert.Equal(t, puts, d.puts.Load())
+	})
+}
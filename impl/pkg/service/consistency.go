@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrInvalidConsistencyToken is returned for consistency tokens which are malformed or issued for another record
+	ErrInvalidConsistencyToken = errors.New("invalid consistency token")
+	// ErrNotConsistent is returned when neither the gateway's storage nor the DHT hold a record at least as new as
+	// the one a consistency token was issued for, e.g. while a replica catches up with a write made through another
+	ErrNotConsistent = errors.New("record is older than the consistency token")
+)
+
+// ConsistencyToken returns the opaque token identifying the given seq of the record for the z-base-32 encoded ID and
+// optional salt. Clients send it with later resolutions, through any replica, to read a record at least that new.
+func ConsistencyToken(id string, salt []byte, seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cacheKey(id, salt) + ":" + strconv.FormatInt(seq, 10)))
+}
+
+// ParseConsistencyToken returns the seq of the consistency token, which must have been issued for the record of the
+// z-base-32 encoded ID and optional salt
+func ParseConsistencyToken(token, id string, salt []byte) (int64, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidConsistencyToken
+	}
+	key, seqStr, ok := strings.Cut(string(decoded), ":")
+	if !ok || key != cacheKey(id, salt) {
+		return 0, ErrInvalidConsistencyToken
+	}
+	seq, err := strconv.ParseInt(seqStr, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, ErrInvalidConsistencyToken
+	}
+	return seq, nil
+}
+
+// GetSaltedPkarrAtLeast resolves the record for the given z-base-32 encoded ID and optional salt, only returning a
+// record with at least the given seq, for read-your-writes consistency across replicas. The cache is bypassed if it
+// holds an older record, then the gateway's storage and the DHT are checked in turn. ErrNotConsistent is returned if
+// neither holds a record that new.
+func (s *PkarrService) GetSaltedPkarrAtLeast(ctx context.Context, id string, salt []byte, seq int64) (*GetPkarrResponse, error) {
+	if s.isDenied(id) {
+		logrus.Debugf("refusing to resolve denied pkarr record[%s]", id)
+		return nil, nil
+	}
+
+	key := cacheKey(id, salt)
+	cached, err := s.getCachedRecord(ctx, key)
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to get pkarr record[%s] from cache", key)
+	} else if cached != nil && cached.Seq >= seq {
+		logrus.Debugf("resolved pkarr record[%s] from cache", key)
+		s.markResolved(id, salt)
+		s.emitResolve(id, salt, &cached.GetPkarrResponse, ResolveSourceCache)
+		return &cached.GetPkarrResponse, nil
+	}
+
+	storageKey, err := saltedRecordKey(id, salt)
+	if err != nil {
+		logrus.WithError(err).Debugf("pkarr record[%s] has an invalid id", key)
+		return nil, nil
+	}
+	record, err := s.db.ReadRecord(ctx, storageKey)
+	if err != nil {
+		return nil, err
+	}
+	var resp *GetPkarrResponse
+	source := ResolveSourceStorage
+	if record != nil && record.Seq >= seq {
+		logrus.Debugf("resolved pkarr record[%s] from storage", key)
+		if resp, err = fromPkarrRecord(*record); err != nil {
+			return nil, err
+		}
+	} else {
+		// storage may lag behind the replica the record was published through, so fall back to the DHT
+		resp, err = s.getFromDHT(ctx, id, salt)
+		if err != nil || resp.Seq < seq {
+			logrus.WithError(err).Debugf("pkarr record[%s] hasn't reached seq %d", key, seq)
+			return nil, ErrNotConsistent
+		}
+		s.checkResolvedEquivocation(ctx, id, salt, *resp)
+		s.adopt(ctx, id, salt, *resp)
+		source = ResolveSourceDHT
+	}
+
+	if _, err = s.cacheRecord(ctx, key, *resp, cached); err != nil {
+		logrus.WithError(err).Errorf("failed to set pkarr record[%s] in cache", key)
+	}
+	s.markResolved(id, salt)
+	s.emitResolve(id, salt, resp, source)
+	return resp, nil
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestConsistencyToken(t *testing.T) {
+	token := ConsistencyToken("alice", []byte("salt"), 42)
+	seq, err := ParseConsistencyToken(token, "alice", []byte("salt"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), seq)
+
+	_, err = ParseConsistencyToken(token, "alice", nil)
+	assert.ErrorIs(t, err, ErrInvalidConsistencyToken, "tokens are bound to the salt")
+	_, err = ParseConsistencyToken(token, "bob", []byte("salt"))
+	assert.ErrorIs(t, err, ErrInvalidConsistencyToken, "tokens are bound to the id")
+	_, err = ParseConsistencyToken("not a token", "alice", nil)
+	assert.ErrorIs(t, err, ErrInvalidConsistencyToken)
+}
+
+func TestGetSaltedPkarrAtLeast(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "consistency.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	newReplica := func(d dht.Client) *PkarrService {
+		memory, err := cache.NewMemory(time.Minute, 1, 10000)
+		require.NoError(t, err)
+		svc, err := NewPkarrServiceWith(&cfg, db, d, memory)
+		require.NoError(t, err)
+		return svc
+	}
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	sign := func(seq int64) bep44.Put {
+		put := bep44.Put{V: []byte("hello consistency"), K: (*[32]byte)(pubKey), Seq: seq}
+		put.Sign(privKey)
+		return put
+	}
+	publish := func(svc *PkarrService, seq int64) {
+		put := sign(seq)
+		request := PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+		require.NoError(t, svc.storePkarr(context.Background(), id, request))
+	}
+
+	ctx := context.Background()
+	writer, reader := newReplica(new(countingDHT)), newReplica(new(countingDHT))
+	publish(writer, 1)
+	got, err := reader.GetSaltedPkarr(ctx, id, nil)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, int64(1), got.Seq)
+
+	t.Run("stale caches are bypassed", func(t *testing.T) {
+		publish(writer, 2)
+		got, err := reader.GetSaltedPkarr(ctx, id, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), got.Seq, "the reader serves its cached record")
+
+		got, err = reader.GetSaltedPkarrAtLeast(ctx, id, nil, 2)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, int64(2), got.Seq)
+		got, err = reader.GetSaltedPkarr(ctx, id, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), got.Seq, "the newer record replaces the cached one")
+	})
+
+	t.Run("records newer than storage are resolved from the dht", func(t *testing.T) {
+		put := sign(3)
+		v, err := bencode.Marshal(put.V)
+		require.NoError(t, err)
+		replica := newReplica(staticDHT{result: dht.FullGetResult{Seq: put.Seq, V: v, Sig: put.Sig, Mutable: true}})
+		got, err := replica.GetSaltedPkarrAtLeast(ctx, id, nil, 3)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, int64(3), got.Seq)
+	})
+
+	t.Run("replicas behind the token", func(t *testing.T) {
+		_, err := reader.GetSaltedPkarrAtLeast(ctx, id, nil, 4)
+		assert.ErrorIs(t, err, ErrNotConsistent)
+	})
+}
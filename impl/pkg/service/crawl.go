@@ -0,0 +1,303 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	intutil "github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+const (
+	// crawlWorkers is the number of identifiers resolved at once by a crawl, whose gets are also paced by the DHT's
+	// get budget
+	crawlWorkers = 8
+	// crawlGetTimeout bounds how long the DHT is searched for the record of one identifier
+	crawlGetTimeout = 30 * time.Second
+	// crawlFeedTimeout bounds how long fetching the feed of identifiers to crawl may take
+	crawlFeedTimeout = 30 * time.Second
+	// crawlMaxIDs bounds the number of identifiers one crawl imports the records of
+	crawlMaxIDs = 100000
+	// crawlErrorLimit bounds the number of errors a crawl reports
+	crawlErrorLimit = 100
+)
+
+var (
+	// ErrCrawlRunning is returned for a crawl started while another is running
+	ErrCrawlRunning = errors.New("a crawl is already running")
+	// ErrNothingToCrawl is returned for a crawl with no identifiers
+	ErrNothingToCrawl = errors.New("crawl requires identifiers or a feed of them")
+
+	errMalformedCrawlID = errors.New("not a z-base-32 encoded ed25519 public key")
+)
+
+// CrawlRequest is the identifiers a crawl imports the records of from the DHT
+type CrawlRequest struct {
+	// IDs are z-base-32 encoded IDs or did:dht identifiers
+	IDs []string `json:"ids,omitempty"`
+	// FeedURL is the URL of a list of z-base-32 encoded IDs or did:dht identifiers, one per line, which are crawled
+	// along with IDs. Blank lines and lines starting with # are ignored.
+	FeedURL string `json:"feedUrl,omitempty"`
+}
+
+// CrawlStatus is the progress of the last crawl
+type CrawlStatus struct {
+	// Running is true while the crawl resolves identifiers
+	Running bool `json:"running"`
+	// Stopped is true if the crawl was stopped before resolving all identifiers
+	Stopped bool `json:"stopped,omitempty"`
+	// Started and Finished are the unix timestamps in seconds the crawl started and finished at
+	Started  int64 `json:"started,omitempty"`
+	Finished int64 `json:"finished,omitempty"`
+	// Total is the number of identifiers to crawl, and Crawled the number resolved so far
+	Total   int `json:"total"`
+	Crawled int `json:"crawled"`
+	// Imported is the number of records stored, new or newer than the stored record
+	Imported int `json:"imported"`
+	// Unchanged is the number of records already stored with the same or a newer seq
+	Unchanged int `json:"unchanged"`
+	// Unresolved is the number of identifiers whose record couldn't be found on the DHT
+	Unresolved int `json:"unresolved"`
+	// Failed is the number of records which didn't verify, were rejected, or failed to store
+	Failed int `json:"failed"`
+	// Errors are the first errors of the crawl
+	Errors []CrawlError `json:"errors,omitempty"`
+}
+
+// CrawlError is an identifier whose record failed to import
+type CrawlError struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// crawler runs one crawl at a time, keeping the status of the last one
+type crawler struct {
+	// client fetches seed feeds
+	client *http.Client
+
+	mu     sync.Mutex
+	status CrawlStatus
+	cancel context.CancelFunc
+}
+
+// crawlOutcome is how importing the record of one identifier went
+type crawlOutcome int
+
+const (
+	crawlImported crawlOutcome = iota
+	crawlUnchanged
+	crawlUnresolved
+	crawlFailed
+)
+
+// Crawl starts importing the records of the requested identifiers from the DHT, bootstrapping the gateway with the
+// records of an existing ecosystem. Each record is verified against its key and stored like a published record,
+// unless a record with the same or a newer seq is already stored. The crawl runs in the background, its progress
+// reported by CrawlStatus.
+func (s *PkarrService) Crawl(ctx context.Context, request CrawlRequest) (*CrawlStatus, error) {
+	if !s.cfg.ServerConfig.Role.Publishes() {
+		return nil, ErrReadOnly
+	}
+	ids := request.IDs
+	if request.FeedURL != "" {
+		feed, err := s.crawler.fetchFeed(ctx, request.FeedURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch crawl feed: %w", err)
+		}
+		ids = append(ids, feed...)
+	}
+	ids = uniqueCrawlIDs(ids)
+	if len(ids) == 0 {
+		return nil, ErrNothingToCrawl
+	}
+	if len(ids) > crawlMaxIDs {
+		return nil, fmt.Errorf("crawl exceeds the limit of %d identifiers", crawlMaxIDs)
+	}
+
+	s.crawler.mu.Lock()
+	defer s.crawler.mu.Unlock()
+	if s.crawler.status.Running {
+		return nil, ErrCrawlRunning
+	}
+	crawlCtx, cancel := context.WithCancel(context.Background())
+	s.crawler.cancel = cancel
+	s.crawler.status = CrawlStatus{Running: true, Started: time.Now().Unix(), Total: len(ids)}
+	status := s.crawler.status
+	logrus.Infof("crawling the dht for the records of %d identifier(s)", len(ids))
+	s.goBackground(func() { s.crawl(crawlCtx, ids) })
+	return &status, nil
+}
+
+// CrawlStatus returns the progress of the running crawl, or the outcome of the last one
+func (s *PkarrService) CrawlStatus() CrawlStatus {
+	s.crawler.mu.Lock()
+	defer s.crawler.mu.Unlock()
+	status := s.crawler.status
+	status.Errors = append([]CrawlError(nil), status.Errors...)
+	return status
+}
+
+// StopCrawl stops the running crawl, if any, keeping the records it imported
+func (s *PkarrService) StopCrawl() CrawlStatus {
+	s.crawler.mu.Lock()
+	if s.crawler.status.Running {
+		s.crawler.status.Stopped = true
+		s.crawler.cancel()
+	}
+	s.crawler.mu.Unlock()
+	return s.CrawlStatus()
+}
+
+// crawl imports the records of the identifiers with a pool of workers until done or stopped
+func (s *PkarrService) crawl(ctx context.Context, ids []string) {
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < crawlWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range work {
+				outcome, err := s.crawlID(ctx, id)
+				// identifiers cut short by stopping the crawl aren't counted
+				if ctx.Err() != nil {
+					continue
+				}
+				s.crawler.record(id, outcome, err)
+			}
+		}()
+	}
+feed:
+	for _, id := range ids {
+		select {
+		case work <- id:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	s.crawler.mu.Lock()
+	defer s.crawler.mu.Unlock()
+	s.crawler.cancel()
+	s.crawler.status.Running = false
+	s.crawler.status.Finished = time.Now().Unix()
+	status := s.crawler.status
+	logrus.Infof("crawled %d of %d identifier(s): imported %d, unchanged %d, unresolved %d, failed %d",
+		status.Crawled, status.Total, status.Imported, status.Unchanged, status.Unresolved, status.Failed)
+}
+
+// crawlID imports the record of the z-base-32 encoded ID from the DHT
+func (s *PkarrService) crawlID(ctx context.Context, id string) (crawlOutcome, error) {
+	k, err := intutil.Z32Decode(id)
+	if err != nil || len(k) != 32 {
+		return crawlFailed, errMalformedCrawlID
+	}
+	getCtx, cancel := context.WithTimeout(ctx, crawlGetTimeout)
+	defer cancel()
+	resp, err := s.getFromDHT(getCtx, id, nil)
+	if errors.Is(err, ErrInvalidSignature) {
+		return crawlFailed, err
+	}
+	if err != nil {
+		return crawlUnresolved, nil
+	}
+	storageKey, err := saltedRecordKey(id, nil)
+	if err != nil {
+		return crawlFailed, err
+	}
+	current, err := s.db.ReadRecord(ctx, storageKey)
+	if err != nil {
+		return crawlFailed, err
+	}
+	if current != nil && current.Seq >= resp.Seq {
+		return crawlUnchanged, nil
+	}
+	request := PublishPkarrRequest{V: resp.V, K: [32]byte(k), Sig: resp.Sig, Seq: resp.Seq}
+	if err = request.isValid(); err != nil {
+		return crawlFailed, err
+	}
+	if err = s.storePkarr(ctx, id, request); err != nil {
+		if errors.Is(err, ErrStaleSeq) {
+			return crawlUnchanged, nil
+		}
+		return crawlFailed, err
+	}
+	return crawlImported, nil
+}
+
+// record counts the outcome of crawling the ID
+func (c *crawler) record(id string, outcome crawlOutcome, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.Crawled++
+	switch outcome {
+	case crawlImported:
+		c.status.Imported++
+	case crawlUnchanged:
+		c.status.Unchanged++
+	case crawlUnresolved:
+		c.status.Unresolved++
+	case crawlFailed:
+		c.status.Failed++
+		logrus.WithError(err).Debugf("failed to import pkarr record[%s] from dht", id)
+		if len(c.status.Errors) < crawlErrorLimit {
+			c.status.Errors = append(c.status.Errors, CrawlError{ID: id, Error: err.Error()})
+		}
+	}
+}
+
+// fetchFeed returns the identifiers listed by the feed, one per line
+func (c *crawler) fetchFeed(ctx context.Context, url string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, crawlFeedTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	var ids []string
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, crawlMaxIDs*128))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ids = append(ids, line)
+	}
+	return ids, scanner.Err()
+}
+
+// uniqueCrawlIDs returns the z-base-32 encoded IDs of the identifiers, without duplicates
+func uniqueCrawlIDs(identifiers []string) []string {
+	seen := make(map[string]struct{}, len(identifiers))
+	ids := make([]string, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		id := strings.TrimPrefix(strings.TrimSpace(identifier), did.Prefix+":")
+		if id == "" {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht/testnet"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestCrawl(t *testing.T) {
+	network := testnet.New(t, 3)
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "crawl.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, network.Node(0), cache.None{})
+	require.NoError(t, err)
+
+	// records only known to the DHT, one of which is listed by a feed
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var ids []string
+	for i := 0; i < 2; i++ {
+		pubKey, privKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		put := bep44.Put{V: []byte(fmt.Sprintf("hello crawl %d", i)), K: (*[32]byte)(pubKey), Seq: 1}
+		put.Sign(privKey)
+		_, err = network.Node(1).Put(ctx, put)
+		require.NoError(t, err)
+		ids = append(ids, util.Z32Encode(pubKey))
+	}
+	missing, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "# known identifiers\n\ndid:dht:%s\n%s\n", ids[1], util.Z32Encode(missing))
+	}))
+	defer feed.Close()
+
+	_, err = svc.Crawl(ctx, CrawlRequest{})
+	assert.ErrorIs(t, err, ErrNothingToCrawl)
+
+	status, err := svc.Crawl(ctx, CrawlRequest{IDs: []string{"did:dht:" + ids[0], ids[1], "malformed"}, FeedURL: feed.URL})
+	require.NoError(t, err)
+	assert.True(t, status.Running)
+	assert.Equal(t, 4, status.Total, "identifiers are deduplicated")
+
+	require.Eventually(t, func() bool {
+		return !svc.CrawlStatus().Running
+	}, 30*time.Second, 10*time.Millisecond)
+	final := svc.CrawlStatus()
+	assert.Equal(t, 4, final.Crawled)
+	assert.Equal(t, 2, final.Imported)
+	assert.Equal(t, 1, final.Unresolved)
+	assert.Equal(t, 1, final.Failed)
+	require.Len(t, final.Errors, 1)
+	assert.Equal(t, "malformed", final.Errors[0].ID)
+
+	for _, id := range ids {
+		key, err := saltedRecordKey(id, nil)
+		require.NoError(t, err)
+		record, err := db.ReadRecord(ctx, key)
+		require.NoError(t, err)
+		require.NotNil(t, record)
+		assert.Equal(t, int64(1), record.Seq)
+	}
+
+	t.Run("records already stored are unchanged", func(t *testing.T) {
+		_, err := svc.Crawl(ctx, CrawlRequest{IDs: ids})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			return !svc.CrawlStatus().Running
+		}, 30*time.Second, 10*time.Millisecond)
+		assert.Equal(t, 2, svc.CrawlStatus().Unchanged)
+	})
+}
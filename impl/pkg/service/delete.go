@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cdc"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+var errDeleteUnsupported = errors.New("storage does not support deleting records")
+
+// DeletePkarr deletes the stored record, with all of its versions, for the given z-base-32 encoded ID and optional
+// salt at the request of its owner, so the gateway stops republishing it and resolving it from storage. The record
+// remains on the DHT until it expires there. It returns false if no record was stored.
+func (s *PkarrService) DeletePkarr(ctx context.Context, id string, salt []byte) (bool, error) {
+	if !s.cfg.ServerConfig.Role.Publishes() {
+		return false, ErrReadOnly
+	}
+	deleted, err := s.purgeRecord(ctx, id, salt)
+	if err != nil || !deleted {
+		return false, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"audit":  "delete",
+		"action": "delete",
+		"id":     id,
+	}).Info("record deleted by its owner")
+	return true, nil
+}
+
+// deleteRecord deletes the stored record with all of its versions, taking it out of the running usage of the
+// retention budget, if any
+func (s *PkarrService) deleteRecord(ctx context.Context, quota storage.Quota, record pkarr.Record) error {
+	if s.retention != nil {
+		s.retention.mu.Lock()
+		defer s.retention.mu.Unlock()
+	}
+	if err := quota.DeleteRecord(ctx, record.Key()); err != nil {
+		return err
+	}
+	if s.retention != nil {
+		s.retention.deleted(record)
+	}
+	return nil
+}
+
+// purgeRecord deletes the stored record, with all of its versions, for the given z-base-32 encoded ID and optional
+// salt, removes it from the document index, and evicts it from the cache. It returns false if no record was stored.
+func (s *PkarrService) purgeRecord(ctx context.Context, id string, salt []byte) (bool, error) {
+	quota, ok := storage.As[storage.Quota](s.db)
+	if !ok {
+		return false, errDeleteUnsupported
+	}
+	key, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return false, err
+	}
+	record, err := s.db.ReadRecord(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if record == nil {
+		return false, nil
+	}
+	if err = s.deleteRecord(ctx, quota, *record); err != nil {
+		return false, err
+	}
+	s.releaseTenantRecord(ctx, key)
+	if s.index != nil && len(salt) == 0 {
+		s.unindexRecord(ctx, id)
+	}
+	if err = s.cache.Delete(ctx, cacheKey(id, salt)); err != nil {
+		logrus.WithError(err).Warnf("failed to evict record[%s] from cache", id)
+	}
+	s.emitChange(cdc.OpDelete, id, record)
+	return true, nil
+}
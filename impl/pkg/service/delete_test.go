@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func TestDeletePkarr(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	put := bep44.Put{V: []byte("hello delete"), K: (*[32]byte)(pubKey), Seq: 1}
+	put.Sign(privKey)
+	request := PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "delete.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, staticDHT{}, cache.None{})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, svc.storePkarr(ctx, id, request))
+	deleted, err := svc.DeletePkarr(ctx, id, nil)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	key, err := saltedRecordKey(id, nil)
+	require.NoError(t, err)
+	record, err := db.ReadRecord(ctx, key)
+	require.NoError(t, err)
+	assert.Nil(t, record)
+
+	deleted, err = svc.DeletePkarr(ctx, id, nil)
+	require.NoError(t, err)
+	assert.False(t, deleted, "nothing is left to delete")
+
+	t.Run("deleted records leave the index", func(t *testing.T) {
+		indexCfg := cfg
+		indexCfg.IndexConfig.Enabled = true
+		indexed, err := NewPkarrServiceWith(&indexCfg, db, staticDHT{}, cache.None{})
+		require.NoError(t, err)
+		sk, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{})
+		require.NoError(t, err)
+		docID, err := did.DHT(doc.ID).Suffix()
+		require.NoError(t, err)
+		packet, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+		require.NoError(t, err)
+		put, err := dht.CreatePKARRPublishRequest(sk, *packet)
+		require.NoError(t, err)
+		require.NoError(t, indexed.storePkarr(ctx, docID, PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}))
+
+		query := pkarr.DocumentQuery{Field: pkarr.VerificationMethodTypeField, Value: "JsonWebKey"}
+		result, err := indexed.QueryDocuments(ctx, query)
+		require.NoError(t, err)
+		assert.Contains(t, result.DIDs, doc.ID)
+
+		deleted, err := indexed.DeletePkarr(ctx, docID, nil)
+		require.NoError(t, err)
+		require.True(t, deleted)
+		result, err = indexed.QueryDocuments(ctx, query)
+		require.NoError(t, err)
+		assert.NotContains(t, result.DIDs, doc.ID)
+	})
+
+	t.Run("resolvers are read-only", func(t *testing.T) {
+		resolverCfg := cfg
+		resolverCfg.ServerConfig.Role = config.RoleResolver
+		resolver, err := NewPkarrServiceWith(&resolverCfg, db, staticDHT{}, cache.None{})
+		require.NoError(t, err)
+		_, err = resolver.DeletePkarr(ctx, id, nil)
+		assert.ErrorIs(t, err, ErrReadOnly)
+	})
+}
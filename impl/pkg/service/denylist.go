@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cdc"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+var errDenylistUnsupported = errors.New("storage does not support a denylist")
+
+// denylist is an in-memory copy of the stored denylist, which is checked on every publish, resolution, and republish
+type denylist struct {
+	db storage.Denylist
+
+	mu  sync.RWMutex
+	ids map[string]struct{}
+}
+
+func newDenylist(ctx context.Context, db storage.Denylist) (*denylist, error) {
+	entries, err := db.ListDenylistEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		ids[entry.ID] = struct{}{}
+	}
+	return &denylist{db: db, ids: ids}, nil
+}
+
+// isDenied returns true if the given z-base-32 encoded ID is on the denylist
+func (s *PkarrService) isDenied(id string) bool {
+	if s.denylist == nil {
+		return false
+	}
+	s.denylist.mu.RLock()
+	defer s.denylist.mu.RUnlock()
+	_, ok := s.denylist.ids[id]
+	return ok
+}
+
+// DenyKey adds the z-base-32 encoded ID to the denylist, taking down its record
+func (s *PkarrService) DenyKey(ctx context.Context, id, reason string) error {
+	if !s.cfg.ServerConfig.Role.Publishes() {
+		return ErrReadOnly
+	}
+	if s.denylist == nil {
+		return errDenylistUnsupported
+	}
+	entry := pkarr.DenylistEntry{ID: id, Reason: reason, Timestamp: time.Now().Unix()}
+	if err := s.denylist.db.WriteDenylistEntry(ctx, entry); err != nil {
+		return err
+	}
+	s.denylist.mu.Lock()
+	s.denylist.ids[id] = struct{}{}
+	s.denylist.mu.Unlock()
+
+	if err := s.cache.Delete(ctx, id); err != nil {
+		logrus.WithError(err).Warnf("failed to evict record[%s] from cache", id)
+	}
+	s.emitChange(cdc.OpDelete, id, nil)
+	logrus.WithFields(logrus.Fields{
+		"audit":  "denylist",
+		"action": "deny",
+		"id":     id,
+		"reason": reason,
+	}).Info("key added to denylist")
+	return nil
+}
+
+// AllowKey removes the z-base-32 encoded ID from the denylist
+func (s *PkarrService) AllowKey(ctx context.Context, id string) error {
+	if !s.cfg.ServerConfig.Role.Publishes() {
+		return ErrReadOnly
+	}
+	if s.denylist == nil {
+		return errDenylistUnsupported
+	}
+	if err := s.denylist.db.DeleteDenylistEntry(ctx, id); err != nil {
+		return err
+	}
+	s.denylist.mu.Lock()
+	delete(s.denylist.ids, id)
+	s.denylist.mu.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"audit":  "denylist",
+		"action": "allow",
+		"id":     id,
+	}).Info("key removed from denylist")
+	return nil
+}
+
+// ListDeniedKeys returns all entries of the denylist, ordered by ID
+func (s *PkarrService) ListDeniedKeys(ctx context.Context) ([]pkarr.DenylistEntry, error) {
+	if s.denylist == nil {
+		return nil, errDenylistUnsupported
+	}
+	return s.denylist.db.ListDenylistEntries(ctx)
+}
+
+// interceptDenied rejects records for keys on the denylist
+func (s *PkarrService) interceptDenied(_ context.Context, id string, _ PublishPkarrRequest) error {
+	if s.isDenied(id) {
+		return &PublishRejectedError{ID: id, Reason: "key is on the denylist"}
+	}
+	return nil
+}
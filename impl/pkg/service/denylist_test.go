@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func TestDeniedKeyReads(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.IndexConfig.Enabled = true
+	cfg.FeedConfig.Enabled = true
+	cfg.HistoryConfig.Enabled = true
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "denied.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, staticDHT{}, cache.None{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// two versions of the DID Document of a key, which is then denied
+	sk, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{})
+	require.NoError(t, err)
+	id, err := did.DHT(doc.ID).Suffix()
+	require.NoError(t, err)
+	for seq := int64(1); seq <= 2; seq++ {
+		packet, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+		require.NoError(t, err)
+		put, err := dht.CreatePKARRPublishRequest(sk, *packet)
+		require.NoError(t, err)
+		put.Seq = seq
+		put.Sign(sk)
+		require.NoError(t, svc.storePkarr(ctx, id, PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}))
+	}
+	query := pkarr.DocumentQuery{Field: pkarr.VerificationMethodTypeField, Value: "JsonWebKey"}
+
+	diff, err := svc.DiffPkarr(ctx, id, 1, 2)
+	require.NoError(t, err)
+	require.NotNil(t, diff)
+	result, err := svc.QueryDocuments(ctx, query)
+	require.NoError(t, err)
+	assert.Equal(t, []string{doc.ID}, result.DIDs)
+
+	require.NoError(t, svc.DenyKey(ctx, id, "spam"))
+
+	t.Run("diff", func(t *testing.T) {
+		diff, err := svc.DiffPkarr(ctx, id, 1, 2)
+		assert.NoError(t, err)
+		assert.Nil(t, diff)
+	})
+
+	t.Run("query", func(t *testing.T) {
+		result, err := svc.QueryDocuments(ctx, query)
+		require.NoError(t, err)
+		assert.Empty(t, result.DIDs)
+	})
+
+	t.Run("feed", func(t *testing.T) {
+		page, err := svc.ListFeed(ctx, 0, 0)
+		require.NoError(t, err)
+		assert.Empty(t, page.Entries)
+		// tailing still moves past the entries
+		assert.NotZero(t, page.Cursor)
+	})
+
+	t.Run("history", func(t *testing.T) {
+		entries, err := svc.ListHistory(ctx, 0, 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		for _, entry := range entries {
+			assert.True(t, entry.Redacted)
+			assert.Empty(t, entry.ID)
+			assert.Empty(t, entry.Salt)
+			assert.Empty(t, entry.RecordHash)
+			assert.NotEmpty(t, entry.Hash)
+		}
+		assert.Equal(t, entries[0].Hash, entries[1].PrevHash)
+	})
+
+	require.NoError(t, svc.AllowKey(ctx, id))
+	diff, err = svc.DiffPkarr(ctx, id, 1, 2)
+	require.NoError(t, err)
+	assert.NotNil(t, diff)
+}
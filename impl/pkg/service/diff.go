@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/goccy/go-json"
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeChanged ChangeType = "changed"
+)
+
+// Change is a single difference between two versions of a record
+type Change struct {
+	Type ChangeType `json:"type"`
+	// Path identifies what changed, such as a resource record name and type, or a DID Document property
+	Path string `json:"path"`
+	From any    `json:"from,omitempty"`
+	To   any    `json:"to,omitempty"`
+}
+
+// PkarrRecordDiff describes the differences between two versions of a Pkarr record
+type PkarrRecordDiff struct {
+	ID   string `json:"id"`
+	From int64  `json:"from"`
+	To   int64  `json:"to"`
+	// Records are the changes to the DNS resource records in the packet
+	Records []Change `json:"records"`
+	// Document are the changes to the DID Document the packet represents
+	Document []Change `json:"document"`
+}
+
+// DiffPkarr returns the differences between two stored versions of the record for the given z-base-32 encoded ID.
+// A nil diff is returned if either version is not known, or the key is on the denylist.
+func (s *PkarrService) DiffPkarr(ctx context.Context, id string, from, to int64) (*PkarrRecordDiff, error) {
+	if s.isDenied(id) {
+		logrus.Debugf("refusing to diff denied pkarr record[%s]", id)
+		return nil, nil
+	}
+	key, err := recordKey(id)
+	if err != nil {
+		return nil, err
+	}
+	fromRecord, err := s.db.ReadRecordVersion(ctx, key, from)
+	if err != nil || fromRecord == nil {
+		return nil, err
+	}
+	toRecord, err := s.db.ReadRecordVersion(ctx, key, to)
+	if err != nil || toRecord == nil {
+		return nil, err
+	}
+
+	fromMsg, err := recordToDNSMsg(*fromRecord)
+	if err != nil {
+		return nil, err
+	}
+	toMsg, err := recordToDNSMsg(*toRecord)
+	if err != nil {
+		return nil, err
+	}
+	documentChanges, err := diffDocuments(did.DHT(did.Prefix+":"+id), fromMsg, toMsg)
+	if err != nil {
+		return nil, err
+	}
+	return &PkarrRecordDiff{
+		ID:       id,
+		From:     from,
+		To:       to,
+		Records:  diffResourceRecords(fromMsg, toMsg),
+		Document: documentChanges,
+	}, nil
+}
+
+// recordToDNSMsg decodes the DNS packet stored in the value of a record
+func recordToDNSMsg(record pkarr.Record) (*dns.Msg, error) {
+	vBytes, err := base64.RawURLEncoding.DecodeString(record.V)
+	if err != nil {
+		return nil, err
+	}
+	msg := new(dns.Msg)
+	if err = msg.Unpack(vBytes); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// diffResourceRecords compares resource records grouped by name and type
+func diffResourceRecords(from, to *dns.Msg) []Change {
+	group := func(msg *dns.Msg) map[string][]string {
+		grouped := make(map[string][]string)
+		for _, rr := range msg.Answer {
+			path := fmt.Sprintf("%s %s", rr.Header().Name, dns.TypeToString[rr.Header().Rrtype])
+			grouped[path] = append(grouped[path], rr.String())
+		}
+		for _, rrs := range grouped {
+			sort.Strings(rrs)
+		}
+		return grouped
+	}
+	fromRRs, toRRs := group(from), group(to)
+
+	changes := make([]Change, 0)
+	for _, path := range unionKeys(fromRRs, toRRs) {
+		fromRR, inFrom := fromRRs[path]
+		toRR, inTo := toRRs[path]
+		switch {
+		case !inFrom:
+			changes = append(changes, Change{Type: ChangeAdded, Path: path, To: toRR})
+		case !inTo:
+			changes = append(changes, Change{Type: ChangeRemoved, Path: path, From: fromRR})
+		case !slices.Equal(fromRR, toRR):
+			changes = append(changes, Change{Type: ChangeChanged, Path: path, From: fromRR, To: toRR})
+		}
+	}
+	return changes
+}
+
+// diffDocuments compares the DID Documents represented by two packets property by property. Properties holding
+// a list of objects with an id, such as verification methods and services, are compared entry by entry.
+func diffDocuments(d did.DHT, from, to *dns.Msg) ([]Change, error) {
+	fromDoc, _, err := d.FromDNSPacket(from)
+	if err != nil {
+		return nil, err
+	}
+	toDoc, _, err := d.FromDNSPacket(to)
+	if err != nil {
+		return nil, err
+	}
+	fromProps, err := documentToMap(*fromDoc)
+	if err != nil {
+		return nil, err
+	}
+	toProps, err := documentToMap(*toDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]Change, 0)
+	for _, prop := range unionKeys(fromProps, toProps) {
+		fromEntries, fromOK := entriesByID(fromProps[prop])
+		toEntries, toOK := entriesByID(toProps[prop])
+		if fromOK && toOK {
+			for _, entryID := range unionKeys(fromEntries, toEntries) {
+				changes = appendChange(changes, prop+"/"+entryID, fromEntries[entryID], toEntries[entryID])
+			}
+			continue
+		}
+		changes = appendChange(changes, prop, fromProps[prop], toProps[prop])
+	}
+	return changes, nil
+}
+
+func appendChange(changes []Change, path string, from, to any) []Change {
+	switch {
+	case from == nil && to != nil:
+		return append(changes, Change{Type: ChangeAdded, Path: path, To: to})
+	case from != nil && to == nil:
+		return append(changes, Change{Type: ChangeRemoved, Path: path, From: from})
+	case !reflect.DeepEqual(from, to):
+		return append(changes, Change{Type: ChangeChanged, Path: path, From: from, To: to})
+	}
+	return changes
+}
+
+func documentToMap(doc didsdk.Document) (map[string]any, error) {
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var props map[string]any
+	if err = json.Unmarshal(docBytes, &props); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+// entriesByID indexes a list of objects by their id property, returning false if the value is not such a list.
+// A missing value is treated as an empty list.
+func entriesByID(value any) (map[string]any, bool) {
+	entries := make(map[string]any)
+	if value == nil {
+		return entries, true
+	}
+	list, ok := value.([]any)
+	if !ok {
+		return nil, false
+	}
+	for _, item := range list {
+		object, ok := item.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		id, ok := object["id"].(string)
+		if !ok {
+			return nil, false
+		}
+		entries[id[strings.LastIndex(id, "#")+1:]] = object
+	}
+	return entries, true
+}
+
+func unionKeys[T any](a, b map[string]T) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+func TestDiff(t *testing.T) {
+	sk, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{})
+	require.NoError(t, err)
+	d := did.DHT(doc.ID)
+
+	fromMsg, err := d.ToDNSPacket(*doc, nil)
+	require.NoError(t, err)
+
+	updatedDoc, err := did.CreateDIDDHTDID(sk.Public().(ed25519.PublicKey), did.CreateDIDDHTOpts{
+		Services: []didsdk.Service{
+			{
+				ID:              "dwn",
+				Type:            "DecentralizedWebNode",
+				ServiceEndpoint: "https://example.com/dwn",
+			},
+		},
+	})
+	require.NoError(t, err)
+	toMsg, err := d.ToDNSPacket(*updatedDoc, nil)
+	require.NoError(t, err)
+
+	t.Run("no changes", func(t *testing.T) {
+		assert.Empty(t, diffResourceRecords(fromMsg, fromMsg))
+
+		changes, err := diffDocuments(d, fromMsg, fromMsg)
+		assert.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("added service", func(t *testing.T) {
+		recordChanges := diffResourceRecords(fromMsg, toMsg)
+		require.Len(t, recordChanges, 2)
+		assert.Equal(t, ChangeChanged, recordChanges[0].Type)
+		assert.Equal(t, "_did. TXT", recordChanges[0].Path)
+		assert.Equal(t, ChangeAdded, recordChanges[1].Type)
+		assert.Equal(t, "_s0._did. TXT", recordChanges[1].Path)
+
+		documentChanges, err := diffDocuments(d, fromMsg, toMsg)
+		assert.NoError(t, err)
+		require.Len(t, documentChanges, 1)
+		assert.Equal(t, ChangeAdded, documentChanges[0].Type)
+		assert.Equal(t, "service/dwn", documentChanges[0].Path)
+	})
+
+	t.Run("removed service", func(t *testing.T) {
+		documentChanges, err := diffDocuments(d, toMsg, fromMsg)
+		assert.NoError(t, err)
+		require.Len(t, documentChanges, 1)
+		assert.Equal(t, ChangeRemoved, documentChanges[0].Type)
+		assert.Equal(t, "service/dwn", documentChanges[0].Path)
+	})
+}
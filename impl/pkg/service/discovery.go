@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+// registrySizeLimit bounds the size of the registry of gateways read
+const registrySizeLimit = 1 << 20
+
+// Sources of the gateways of the fallback pool
+const (
+	FallbackSourceConfig   = "config"
+	FallbackSourceRegistry = "registry"
+	FallbackSourceDHT      = "dht"
+)
+
+var errFallbackUnsupported = errors.New("no fallback gateways are configured or discovered")
+
+// FallbackGateway is a gateway records may be resolved from, and the outcome of its last health check
+type FallbackGateway struct {
+	// URL is the base URL of the gateway
+	URL string `json:"url"`
+	// Source is where the gateway was found: config, registry, or dht
+	Source  string `json:"source"`
+	Healthy bool   `json:"healthy"`
+	// LatencyMillis is how long the health check took
+	LatencyMillis int64 `json:"latencyMillis"`
+	// Error is why the health check failed, if it did
+	Error string `json:"error,omitempty"`
+	// CheckedAt is the unix time in seconds the gateway was health-checked at
+	CheckedAt int64 `json:"checkedAt"`
+	// Resolutions is the number of records resolved from the gateway since startup, of which Errors failed.
+	// ResolveLatencyMillis and ErrorRate are the moving averages of their latency and error rate, which the
+	// gateways are ranked by.
+	Resolutions          int64   `json:"resolutions"`
+	Errors               int64   `json:"errors"`
+	ResolveLatencyMillis int64   `json:"resolveLatencyMillis"`
+	ErrorRate            float64 `json:"errorRate"`
+}
+
+// gatewayRegistry is the registry of gateways read from the configured registry URL
+type gatewayRegistry struct {
+	Gateways []struct {
+		URL string `json:"url"`
+	} `json:"gateways"`
+}
+
+// GetFallbackGateways returns the gateways found by the last discovery and the outcome of their health checks, or
+// the configured gateways if none has run
+func (s *PkarrService) GetFallbackGateways() ([]FallbackGateway, error) {
+	if s.fallback == nil {
+		return nil, errFallbackUnsupported
+	}
+	s.fallback.mu.RLock()
+	defer s.fallback.mu.RUnlock()
+	var gateways []FallbackGateway
+	if s.fallback.checked != nil {
+		gateways = slices.Clone(s.fallback.checked)
+	} else {
+		for _, gateway := range s.fallback.gateways {
+			gateways = append(gateways, FallbackGateway{URL: gateway, Source: FallbackSourceConfig, Healthy: true})
+		}
+	}
+	for i := range gateways {
+		if stats, ok := s.fallback.stats[gateways[i].URL]; ok {
+			gateways[i].Resolutions = stats.resolutions
+			gateways[i].Errors = stats.errors
+			gateways[i].ResolveLatencyMillis = stats.latency.Milliseconds()
+			gateways[i].ErrorRate = stats.errorRate
+		}
+	}
+	return gateways, nil
+}
+
+// DiscoverGateways finds the gateways of the config, the registry, and the DIDs of gateways announcing themselves on
+// the DHT, health-checks them, and replaces the fallback pool with the healthy ones: the configured gateways first,
+// in order, then the others by latency. The configured gateways are kept if none is healthy.
+func (s *PkarrService) DiscoverGateways(ctx context.Context) ([]FallbackGateway, error) {
+	if s.fallback == nil {
+		return nil, errFallbackUnsupported
+	}
+	candidates := s.gatewayCandidates(ctx)
+	checked := make([]FallbackGateway, len(candidates))
+	var wg sync.WaitGroup
+	for i, candidate := range candidates {
+		wg.Add(1)
+		go func(i int, candidate FallbackGateway) {
+			defer wg.Done()
+			checked[i] = s.fallback.check(ctx, candidate)
+		}(i, candidate)
+	}
+	wg.Wait()
+
+	var healthy []FallbackGateway
+	for _, gateway := range checked {
+		if gateway.Healthy {
+			healthy = append(healthy, gateway)
+		}
+	}
+	slices.SortStableFunc(healthy, func(a, b FallbackGateway) int {
+		aConfigured, bConfigured := a.Source == FallbackSourceConfig, b.Source == FallbackSourceConfig
+		switch {
+		case aConfigured && bConfigured:
+			return 0
+		case aConfigured:
+			return -1
+		case bConfigured:
+			return 1
+		}
+		return int(a.LatencyMillis - b.LatencyMillis)
+	})
+	pool := make([]string, 0, len(healthy))
+	for _, gateway := range healthy {
+		pool = append(pool, gateway.URL)
+	}
+	if len(pool) == 0 {
+		pool = s.fallback.configured
+	}
+
+	s.fallback.mu.Lock()
+	s.fallback.gateways = pool
+	s.fallback.checked = checked
+	s.fallback.mu.Unlock()
+	logrus.WithFields(logrus.Fields{
+		"found":   len(candidates),
+		"healthy": len(healthy),
+		"pool":    pool,
+	}).Info("discovered fallback gateways")
+	return s.GetFallbackGateways()
+}
+
+// discoverGateways discovers the fallback gateways, on a schedule
+func (s *PkarrService) discoverGateways() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if _, err := s.DiscoverGateways(ctx); err != nil {
+		logrus.WithError(err).Error("failed to discover fallback gateways")
+	}
+}
+
+// gatewayCandidates returns the gateways of the config, the registry, and the DHT, without duplicates or the gateway
+// itself. Sources which fail are logged and skipped.
+func (s *PkarrService) gatewayCandidates(ctx context.Context) []FallbackGateway {
+	var candidates []FallbackGateway
+	seen := map[string]struct{}{strings.TrimSuffix(s.cfg.ServerConfig.BaseURL, "/"): {}}
+	add := func(gateway, source string) {
+		gateway = strings.TrimSuffix(gateway, "/")
+		if !strings.HasPrefix(gateway, "https://") && !strings.HasPrefix(gateway, "http://") {
+			logrus.Debugf("skipping fallback gateway[%s] from %s without an http(s) url", gateway, source)
+			return
+		}
+		if _, ok := seen[gateway]; ok {
+			return
+		}
+		seen[gateway] = struct{}{}
+		candidates = append(candidates, FallbackGateway{URL: gateway, Source: source})
+	}
+
+	for _, gateway := range s.fallback.configured {
+		add(gateway, FallbackSourceConfig)
+	}
+	if registryURL := s.cfg.PkarrConfig.FallbackRegistryURL; registryURL != "" {
+		gateways, err := s.fallback.fetchRegistry(ctx, registryURL)
+		if err != nil {
+			logrus.WithError(err).Warnf("failed to fetch registry of gateways[%s]", registryURL)
+		}
+		for _, gateway := range gateways {
+			add(gateway, FallbackSourceRegistry)
+		}
+	}
+	for _, gatewayDID := range s.cfg.PkarrConfig.FallbackGatewayDIDs {
+		endpoints, err := s.resolveGatewayEndpoints(ctx, gatewayDID)
+		if err != nil {
+			logrus.WithError(err).Warnf("failed to resolve endpoints of gateway[%s]", gatewayDID)
+			continue
+		}
+		for _, endpoint := range endpoints {
+			add(endpoint, FallbackSourceDHT)
+		}
+	}
+	return candidates
+}
+
+// resolveGatewayEndpoints resolves the DID Document a gateway announces itself with from the DHT, returning the
+// endpoints of its gateway services
+func (s *PkarrService) resolveGatewayEndpoints(ctx context.Context, gatewayDID string) ([]string, error) {
+	id, err := did.DHT(gatewayDID).Suffix()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.getFromDHT(ctx, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	var msg dns.Msg
+	if err = msg.Unpack(resp.V); err != nil {
+		return nil, err
+	}
+	doc, _, err := did.DHT(gatewayDID).FromDNSPacket(&msg)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []string
+	for _, service := range doc.Services {
+		if service.Type != GatewayServiceType {
+			continue
+		}
+		switch endpoint := service.ServiceEndpoint.(type) {
+		case string:
+			endpoints = append(endpoints, endpoint)
+		case []string:
+			endpoints = append(endpoints, endpoint...)
+		}
+	}
+	return endpoints, nil
+}
+
+// fetchRegistry returns the base URLs of the gateways of the registry
+func (f *fallback) fetchRegistry(ctx context.Context, registryURL string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, registryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var registry gatewayRegistry
+	if err = json.NewDecoder(io.LimitReader(resp.Body, registrySizeLimit)).Decode(&registry); err != nil {
+		return nil, err
+	}
+	gateways := make([]string, 0, len(registry.Gateways))
+	for _, gateway := range registry.Gateways {
+		gateways = append(gateways, gateway.URL)
+	}
+	return gateways, nil
+}
+
+// check health-checks the gateway, which is healthy if its health endpoint responds OK within the timeout
+func (f *fallback) check(ctx context.Context, gateway FallbackGateway) FallbackGateway {
+	start := time.Now()
+	err := f.ping(ctx, gateway.URL)
+	gateway.LatencyMillis = time.Since(start).Milliseconds()
+	gateway.CheckedAt = start.Unix()
+	if err != nil {
+		gateway.Error = err.Error()
+		return gateway
+	}
+	gateway.Healthy = true
+	return gateway
+}
+
+// ping gets the health endpoint of the gateway, returning an error unless it responds OK
+func (f *fallback) ping(ctx context.Context, gateway string) error {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gateway+"/health", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	intutil "github.com/TBD54566975/did-dht-method/impl/internal/util"
+)
+
+// ResolveDNS answers a DNS query with the resource records of the packet stored for the identifier in the queried
+// name. Record names are relative to the identifier, e.g. a query for `_did.<z32>.did.` returns the packet's `_did.`
+// records, and a query for the identifier itself, e.g. `<z32>.did.`, returns all of the packet's records.
+func (s *PkarrService) ResolveDNS(ctx context.Context, query *dns.Msg) (*dns.Msg, error) {
+	resp := new(dns.Msg)
+	resp.SetReply(query)
+	resp.Authoritative = true
+	if len(query.Question) != 1 {
+		resp.Rcode = dns.RcodeFormatError
+		return resp, nil
+	}
+
+	question := query.Question[0]
+	id, origin, ok := splitDNSName(question.Name)
+	if !ok {
+		resp.Rcode = dns.RcodeNameError
+		return resp, nil
+	}
+	record, err := s.GetPkarr(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		resp.Rcode = dns.RcodeNameError
+		return resp, nil
+	}
+	packet := new(dns.Msg)
+	if err = packet.Unpack(record.V); err != nil {
+		return nil, err
+	}
+
+	answers, found := answerDNSQuestion(question, origin, packet)
+	if !found {
+		resp.Rcode = dns.RcodeNameError
+		return resp, nil
+	}
+	resp.Answer = answers
+	return resp, nil
+}
+
+// splitDNSName finds the z-base-32 encoded identifier in a DNS name, returning the identifier and the origin the
+// packet's records are relative to, which is the name from the identifier onwards
+func splitDNSName(name string) (id string, origin string, ok bool) {
+	labels := dns.SplitDomainName(strings.ToLower(name))
+	for i, label := range labels {
+		if len(label) != 52 {
+			continue
+		}
+		if key, err := intutil.Z32Decode(label); err != nil || len(key) != 32 {
+			continue
+		}
+		return label, dns.Fqdn(strings.Join(labels[i:], ".")), true
+	}
+	return "", "", false
+}
+
+// answerDNSQuestion returns the records of the packet that answer the question, with names made absolute under the
+// origin. The returned bool is false if the packet has no records for the queried name.
+func answerDNSQuestion(question dns.Question, origin string, packet *dns.Msg) ([]dns.RR, bool) {
+	name := strings.ToLower(dns.Fqdn(question.Name))
+	// the name relative to the origin, or empty for the origin itself, which matches all records
+	relative := strings.TrimSuffix(name, origin)
+
+	var answers []dns.RR
+	found := relative == ""
+	for _, rr := range packet.Answer {
+		rrName := strings.ToLower(rr.Header().Name)
+		if relative != "" && rrName != relative {
+			continue
+		}
+		found = true
+		if question.Qtype != dns.TypeANY && rr.Header().Rrtype != question.Qtype {
+			continue
+		}
+		answer := dns.Copy(rr)
+		answer.Header().Name = rrName + origin
+		answers = append(answers, answer)
+	}
+	return answers, found
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+func TestAnswerDNSQuestion(t *testing.T) {
+	_, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{})
+	require.NoError(t, err)
+	d := did.DHT(doc.ID)
+	packet, err := d.ToDNSPacket(*doc, nil)
+	require.NoError(t, err)
+
+	suffix, err := d.Suffix()
+	require.NoError(t, err)
+
+	t.Run("split name", func(t *testing.T) {
+		id, origin, ok := splitDNSName("_did." + suffix + ".did.")
+		assert.True(t, ok)
+		assert.Equal(t, suffix, id)
+		assert.Equal(t, suffix+".did.", origin)
+
+		_, _, ok = splitDNSName("_did.example.com.")
+		assert.False(t, ok)
+	})
+
+	t.Run("origin returns all records", func(t *testing.T) {
+		answers, found := answerDNSQuestion(dns.Question{Name: suffix + ".did.", Qtype: dns.TypeANY}, suffix+".did.", packet)
+		assert.True(t, found)
+		assert.Len(t, answers, len(packet.Answer))
+	})
+
+	t.Run("records by name", func(t *testing.T) {
+		answers, found := answerDNSQuestion(dns.Question{Name: "_did." + suffix + ".", Qtype: dns.TypeTXT}, suffix+".", packet)
+		assert.True(t, found)
+		require.Len(t, answers, 1)
+		assert.Equal(t, "_did."+suffix+".", answers[0].Header().Name)
+
+		// the original packet is left untouched
+		for _, rr := range packet.Answer {
+			assert.NotContains(t, rr.Header().Name, suffix)
+		}
+	})
+
+	t.Run("no records of type", func(t *testing.T) {
+		answers, found := answerDNSQuestion(dns.Question{Name: "_did." + suffix + ".", Qtype: dns.TypeA}, suffix+".", packet)
+		assert.True(t, found)
+		assert.Empty(t, answers)
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		answers, found := answerDNSQuestion(dns.Question{Name: "_x." + suffix + ".", Qtype: dns.TypeTXT}, suffix+".", packet)
+		assert.False(t, found)
+		assert.Empty(t, answers)
+	})
+}
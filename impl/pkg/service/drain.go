@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrDraining is returned for work which isn't started while the gateway drains
+var ErrDraining = errors.New("gateway is draining")
+
+// DrainStatus is the progress of draining the gateway
+type DrainStatus struct {
+	// Draining is true once the gateway stopped accepting new publishes
+	Draining bool `json:"draining"`
+	// InFlight is the number of DHT puts and republish runs still in progress
+	InFlight int `json:"inFlight"`
+	// Drained is true once the gateway is draining with no work in flight, and is safe to terminate
+	Drained bool `json:"drained"`
+}
+
+// drain tracks in-flight work, so the gateway can stop taking on new work and wait for the rest to finish
+type drain struct {
+	mu       sync.Mutex
+	draining bool
+	inFlight int
+	// stop is closed once the gateway starts draining, waking up work waiting to continue
+	stop chan struct{}
+}
+
+// begin registers a unit of work, returning false if the gateway is draining and the work shouldn't start
+func (d *drain) begin() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.inFlight++
+	return true
+}
+
+// done unregisters a unit of work registered with begin
+func (d *drain) done() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight--
+	if d.draining && d.inFlight == 0 {
+		logrus.Info("Drained all in-flight work, safe to terminate")
+	}
+}
+
+// stopping returns a channel closed once the gateway starts draining
+func (d *drain) stopping() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop == nil {
+		d.stop = make(chan struct{})
+		if d.draining {
+			close(d.stop)
+		}
+	}
+	return d.stop
+}
+
+// wait waits until the given time, returning false if the gateway starts draining first
+func (d *drain) wait(until time.Time) bool {
+	delay := time.Until(until)
+	if delay <= 0 {
+		return !d.status().Draining
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-d.stopping():
+		return false
+	}
+}
+
+func (d *drain) status() DrainStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return DrainStatus{
+		Draining: d.draining,
+		InFlight: d.inFlight,
+		Drained:  d.draining && d.inFlight == 0,
+	}
+}
+
+// Drain stops the gateway from accepting new publishes and starting republish runs, while in-flight DHT puts and
+// republish runs finish. Runs waiting out their jitter, or smeared across a window, stop early. It is idempotent, and
+// DrainStatus reports when the gateway is safe to terminate.
+func (s *PkarrService) Drain() DrainStatus {
+	s.drain.mu.Lock()
+	if !s.drain.draining {
+		s.drain.draining = true
+		if s.drain.stop != nil {
+			close(s.drain.stop)
+		}
+		logrus.Infof("Draining with %d unit(s) of work in flight", s.drain.inFlight)
+	}
+	s.drain.mu.Unlock()
+	return s.drain.status()
+}
+
+// DrainStatus returns the progress of draining the gateway
+func (s *PkarrService) DrainStatus() DrainStatus {
+	return s.drain.status()
+}
+
+// Ready returns whether the gateway is ready to take traffic, which it isn't once it starts draining
+func (s *PkarrService) Ready() bool {
+	return !s.drain.status().Draining
+}
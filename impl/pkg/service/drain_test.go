@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrain(t *testing.T) {
+	svc := PkarrService{drain: new(drain)}
+	assert.True(t, svc.Ready())
+	assert.Equal(t, DrainStatus{}, svc.DrainStatus())
+
+	require.True(t, svc.drain.begin())
+
+	status := svc.Drain()
+	assert.False(t, svc.Ready())
+	assert.Equal(t, DrainStatus{Draining: true, InFlight: 1}, status)
+
+	// no new work is started while draining
+	assert.False(t, svc.drain.begin())
+
+	svc.drain.done()
+	assert.Equal(t, DrainStatus{Draining: true, Drained: true}, svc.DrainStatus())
+
+	// draining again is a no-op
+	assert.Equal(t, DrainStatus{Draining: true, Drained: true}, svc.Drain())
+}
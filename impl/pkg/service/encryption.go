@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// reencryptStored re-encrypts the stored records and versions not encrypted with the current key in the background,
+// stopping early if the gateway drains
+func (s *PkarrService) reencryptStored(encrypted *storage.Encrypted) {
+	if !s.drain.begin() {
+		return
+	}
+	defer s.drain.done()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.drain.stopping():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	n, err := storage.ReencryptRecords(ctx, encrypted)
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to re-encrypt stored pkarr records after re-encrypting %d", n)
+		return
+	}
+	if n > 0 {
+		logrus.Infof("re-encrypted %d stored pkarr record value(s) with the current key", n)
+	}
+}
+
+// GetEncryptionKeyUsage returns the number of stored values of records and their versions encrypted with each key,
+// or nil if storage isn't encrypted
+func (s *PkarrService) GetEncryptionKeyUsage(ctx context.Context) (*pkarr.EncryptionKeyUsage, error) {
+	encrypted, ok := storage.As[*storage.Encrypted](s.db)
+	if !ok {
+		return nil, nil
+	}
+	usage, err := encrypted.KeyUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+// defaultEndpointSchemes are the schemes service endpoints may have if none are configured
+var defaultEndpointSchemes = []string{"https"}
+
+// ServiceEndpointValidator returns an interceptor which validates that the service endpoints of published DID
+// Documents, such as those of DWNs or credential status services, are absolute URIs of one of the given schemes,
+// https if none. Under EndpointPolicyWarn invalid endpoints are logged and the record accepted, and under
+// EndpointPolicyReject the record is rejected. Records which are not DID Documents are accepted.
+func ServiceEndpointValidator(policy config.EndpointPolicy, schemes []string) PublishInterceptor {
+	if len(schemes) == 0 {
+		schemes = defaultEndpointSchemes
+	}
+	return PublishInterceptorFunc(func(_ context.Context, id string, request PublishPkarrRequest) error {
+		if policy == "" || policy == config.EndpointPolicyOff {
+			return nil
+		}
+		msg := new(dns.Msg)
+		if err := msg.Unpack(request.V); err != nil {
+			return nil
+		}
+		doc, _, err := did.DHT(did.Prefix + ":" + id).FromDNSPacket(msg)
+		if err != nil {
+			return nil
+		}
+		if err = did.ValidateServiceEndpoints(*doc, schemes); err != nil {
+			if policy == config.EndpointPolicyReject {
+				return &PublishRejectedError{ID: id, Reason: strings.ReplaceAll(err.Error(), "\n", "; ")}
+			}
+			logrus.WithError(err).Warnf("record[%s] has invalid service endpoints", id)
+		}
+		return nil
+	})
+}
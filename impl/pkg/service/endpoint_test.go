@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+func TestServiceEndpointValidator(t *testing.T) {
+	ctx := context.Background()
+	publishRequest := func(endpoint string) (string, PublishPkarrRequest) {
+		_, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{
+			Services: []didsdk.Service{{ID: "dwn", Type: "DecentralizedWebNode", ServiceEndpoint: endpoint}},
+		})
+		require.NoError(t, err)
+		msg, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+		require.NoError(t, err)
+		v, err := msg.Pack()
+		require.NoError(t, err)
+		id, err := did.DHT(doc.ID).Suffix()
+		require.NoError(t, err)
+		return id, PublishPkarrRequest{V: v}
+	}
+
+	t.Run("valid endpoint", func(t *testing.T) {
+		id, request := publishRequest("https://example.com/dwn")
+		interceptor := ServiceEndpointValidator(config.EndpointPolicyReject, nil)
+		assert.NoError(t, interceptor.InterceptPublish(ctx, id, request))
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		id, request := publishRequest("http://example.com/dwn")
+		interceptor := ServiceEndpointValidator(config.EndpointPolicyReject, nil)
+		var rejected *PublishRejectedError
+		require.ErrorAs(t, interceptor.InterceptPublish(ctx, id, request), &rejected)
+		assert.Equal(t, id, rejected.ID)
+
+		// allowed by the configured schemes
+		interceptor = ServiceEndpointValidator(config.EndpointPolicyReject, []string{"http", "https"})
+		assert.NoError(t, interceptor.InterceptPublish(ctx, id, request))
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		id, request := publishRequest("not a uri")
+		interceptor := ServiceEndpointValidator(config.EndpointPolicyWarn, nil)
+		assert.NoError(t, interceptor.InterceptPublish(ctx, id, request))
+	})
+
+	t.Run("not a did document", func(t *testing.T) {
+		interceptor := ServiceEndpointValidator(config.EndpointPolicyReject, nil)
+		assert.NoError(t, interceptor.InterceptPublish(ctx, "alice", PublishPkarrRequest{V: []byte("not a dns packet")}))
+	})
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// equivocationWebhookTimeout bounds how long delivering evidence to the webhook may take
+const equivocationWebhookTimeout = 10 * time.Second
+
+// EquivocationReport is the evidence of keys found signing different records with the same seq
+type EquivocationReport struct {
+	// Detected is the number of equivocations detected since startup
+	Detected int64 `json:"detected"`
+	// Equivocations is the stored evidence, empty if the storage doesn't keep it
+	Equivocations []pkarr.Equivocation `json:"equivocations"`
+}
+
+// equivocations detects keys signing different records with the same seq, such as by a client bug or an attempt to
+// fork a DID Document, which resolvers would otherwise silently pick one of. Each new equivocation is stored as
+// evidence, if the storage supports it, and alerted on.
+type equivocations struct {
+	// db stores the evidence, if the storage supports it
+	db         storage.EquivocationLog
+	webhookURL string
+	client     *http.Client
+	// events is where new equivocations are emitted, to which the webhook subscribes
+	events *Events
+
+	mu sync.Mutex
+	// seen are the fingerprints of the equivocations already alerted on
+	seen     map[string]struct{}
+	detected atomic.Int64
+}
+
+func newEquivocations(ctx context.Context, db storage.EquivocationLog, webhookURL string, events *Events) (*equivocations, error) {
+	e := &equivocations{db: db, webhookURL: webhookURL, client: http.DefaultClient, events: events, seen: make(map[string]struct{})}
+	if db != nil {
+		stored, err := db.ListEquivocations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, equivocation := range stored {
+			e.seen[equivocation.Fingerprint()] = struct{}{}
+		}
+	}
+	if webhookURL != "" {
+		events.OnEquivocation(e.notify)
+	}
+	return e, nil
+}
+
+// checkEquivocation reports an equivocation if the observed record for the given z-base-32 encoded ID has the same
+// seq as the stored record but a different value
+func (s *PkarrService) checkEquivocation(ctx context.Context, id string, stored *pkarr.Record, observed pkarr.Record, source string) {
+	if s.equivocations == nil || stored == nil || stored.Seq != observed.Seq {
+		return
+	}
+	if stored.V == observed.V && stored.Sig == observed.Sig {
+		return
+	}
+	s.equivocations.report(ctx, pkarr.Equivocation{
+		ID:        id,
+		Seq:       observed.Seq,
+		Stored:    *stored,
+		Observed:  observed,
+		Source:    source,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// checkResolvedEquivocation checks the record resolved from the DHT for the given z-base-32 encoded ID and salt
+// against the stored record
+func (s *PkarrService) checkResolvedEquivocation(ctx context.Context, id string, salt []byte, resp GetPkarrResponse) {
+	if s.equivocations == nil {
+		return
+	}
+	k, err := recordKey(id)
+	if err != nil {
+		return
+	}
+	storageKey, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return
+	}
+	stored, err := s.db.ReadRecord(ctx, storageKey)
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to read pkarr record[%s] to check for equivocation", id)
+		return
+	}
+	encoding := base64.RawURLEncoding
+	observed := pkarr.Record{
+		V:    encoding.EncodeToString(resp.V),
+		K:    k,
+		Sig:  encoding.EncodeToString(resp.Sig[:]),
+		Seq:  resp.Seq,
+		Salt: encoding.EncodeToString(salt),
+	}
+	s.checkEquivocation(ctx, id, stored, observed, pkarr.EquivocationSourceDHT)
+}
+
+// report stores and alerts on the equivocation, unless it was already reported
+func (e *equivocations) report(ctx context.Context, equivocation pkarr.Equivocation) {
+	fingerprint := equivocation.Fingerprint()
+	e.mu.Lock()
+	_, seen := e.seen[fingerprint]
+	e.seen[fingerprint] = struct{}{}
+	e.mu.Unlock()
+	if seen {
+		return
+	}
+
+	e.detected.Add(1)
+	logrus.WithFields(logrus.Fields{
+		"alert":       "equivocation",
+		"id":          equivocation.ID,
+		"seq":         equivocation.Seq,
+		"source":      equivocation.Source,
+		"fingerprint": fingerprint,
+	}).Warn("key signed different records with the same seq")
+	if e.db != nil {
+		if err := e.db.WriteEquivocation(ctx, equivocation); err != nil {
+			logrus.WithError(err).Errorf("failed to store evidence of equivocation of pkarr record[%s]", equivocation.ID)
+		}
+	}
+	e.events.equivocation.emit(equivocation)
+}
+
+// notify posts the evidence of the equivocation to the webhook
+func (e *equivocations) notify(equivocation pkarr.Equivocation) {
+	if err := e.post(equivocation); err != nil {
+		logrus.WithError(err).Errorf("failed to notify webhook of equivocation of pkarr record[%s]", equivocation.ID)
+	}
+}
+
+func (e *equivocations) post(equivocation pkarr.Equivocation) error {
+	body, err := json.Marshal(equivocation)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), equivocationWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ListEquivocations returns the number of equivocations detected since startup, and the stored evidence
+func (s *PkarrService) ListEquivocations(ctx context.Context) (*EquivocationReport, error) {
+	report := EquivocationReport{Detected: s.equivocations.detected.Load(), Equivocations: []pkarr.Equivocation{}}
+	if s.equivocations.db == nil {
+		return &report, nil
+	}
+	stored, err := s.equivocations.db.ListEquivocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		report.Equivocations = stored
+	}
+	return &report, nil
+}
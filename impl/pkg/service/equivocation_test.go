@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func TestEquivocations(t *testing.T) {
+	alerts := make(chan pkarr.Equivocation, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		var equivocation pkarr.Equivocation
+		assert.NoError(t, json.Unmarshal(body, &equivocation))
+		alerts <- equivocation
+	}))
+	defer webhook.Close()
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	request := func(v string, seq int64) PublishPkarrRequest {
+		put := bep44.Put{V: []byte(v), K: (*[32]byte)(pubKey), Seq: seq}
+		put.Sign(privKey)
+		return PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+	}
+
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.EquivocationConfig.WebhookURL = webhook.URL
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "equivocation.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	forked := request("forked", 2)
+	v, err := bencode.Marshal(forked.V)
+	require.NoError(t, err)
+	svc, err := NewPkarrServiceWith(&cfg, db, staticDHT{result: dht.FullGetResult{Seq: forked.Seq, V: v, Sig: forked.Sig}}, cache.None{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("updates are not equivocations", func(t *testing.T) {
+		require.NoError(t, svc.storePkarr(ctx, id, request("first", 1)))
+		require.NoError(t, svc.storePkarr(ctx, id, request("first", 1)))
+		require.NoError(t, svc.storePkarr(ctx, id, request("second", 2)))
+		report, err := svc.ListEquivocations(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, report.Detected)
+		assert.Empty(t, report.Equivocations)
+	})
+
+	t.Run("a different record resolved with the same seq", func(t *testing.T) {
+		got, err := svc.GetPkarr(ctx, id)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+
+		select {
+		case alert := <-alerts:
+			assert.Equal(t, id, alert.ID)
+			assert.Equal(t, int64(2), alert.Seq)
+			assert.Equal(t, pkarr.EquivocationSourceDHT, alert.Source)
+		case <-time.After(5 * time.Second):
+			t.Fatal("webhook was not notified")
+		}
+
+		// resolving the same fork again isn't a new equivocation
+		_, err = svc.GetPkarr(ctx, id)
+		require.NoError(t, err)
+		report, err := svc.ListEquivocations(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), report.Detected)
+		require.Len(t, report.Equivocations, 1)
+		assert.Equal(t, forked.toRecord(), report.Equivocations[0].Observed)
+	})
+
+	t.Run("a different record published with the same seq", func(t *testing.T) {
+		require.NoError(t, svc.storePkarr(ctx, id, request("third", 3)))
+		require.NoError(t, svc.storePkarr(ctx, id, request("fork of third", 3)))
+		select {
+		case alert := <-alerts:
+			assert.Equal(t, int64(3), alert.Seq)
+			assert.Equal(t, pkarr.EquivocationSourcePublish, alert.Source)
+		case <-time.After(5 * time.Second):
+			t.Fatal("webhook was not notified")
+		}
+		report, err := svc.ListEquivocations(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), report.Detected)
+		assert.Len(t, report.Equivocations, 2)
+	})
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cdc"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// eventQueueSize bounds the events waiting to be delivered to a subscriber, beyond which its events are dropped
+// rather than holding up the service while the subscriber is slow
+const eventQueueSize = 1000
+
+// Sources a record is resolved from
+const (
+	ResolveSourceCache    = "cache"
+	ResolveSourceDHT      = "dht"
+	ResolveSourceStorage  = "storage"
+	ResolveSourceFallback = "fallback"
+	ResolveSourcePeer     = "peer"
+)
+
+// PublishEvent is a record accepted for publishing, once it is stored
+type PublishEvent struct {
+	// ID is the z-base-32 encoded ID of the record
+	ID     string
+	Salt   []byte
+	Record pkarr.Record
+	// Changed is whether the record is new or replaced an older record, rather than republishing the stored record
+	Changed   bool
+	Timestamp time.Time
+}
+
+// ResolveEvent is a record resolved for a client
+type ResolveEvent struct {
+	// ID is the z-base-32 encoded ID of the record. It and the salt are empty in privacy mode, so subscribers only
+	// see aggregates.
+	ID   string
+	Salt []byte
+	Seq  int64
+	// Source is where the record was resolved from, one of the ResolveSource constants
+	Source    string
+	Timestamp time.Time
+}
+
+// RepublishCompleteEvent is the outcome of republishing the stored records to the DHT
+type RepublishCompleteEvent struct {
+	// Records is the number of records stored, of which Republished were put to the DHT, Failed weren't,
+	// UnderReplicated were stored by fewer nodes than targeted, Skipped were recently confirmed on the DHT, and Denied
+	// are of denylisted keys, which aren't republished
+	Records         int
+	Republished     int
+	Failed          int
+	UnderReplicated int
+	Skipped         int
+	Denied          int
+	Started         time.Time
+	Finished        time.Time
+}
+
+// Events is the bus of the service's events, which embedders subscribe to for in-process reactions to records being
+// published and resolved, such as metering or invalidating their own caches. Each subscriber is called with its
+// events in order on a goroutine of its own, so a slow subscriber doesn't hold up the service or other subscribers;
+// events beyond eventQueueSize waiting for a subscriber are dropped. Each subscription returns a function which
+// unsubscribes.
+type Events struct {
+	publish      topic[PublishEvent]
+	resolve      topic[ResolveEvent]
+	republish    topic[RepublishCompleteEvent]
+	change       topic[cdc.Event]
+	equivocation topic[pkarr.Equivocation]
+}
+
+// Events returns the bus of the service's events
+func (s *PkarrService) Events() *Events {
+	return &s.events
+}
+
+// OnPublish calls handler with each record accepted for publishing
+func (e *Events) OnPublish(handler func(PublishEvent)) (unsubscribe func()) {
+	return subscribeQueued(&e.publish, "publish", handler)
+}
+
+// OnResolve calls handler with each record resolved for a client
+func (e *Events) OnResolve(handler func(ResolveEvent)) (unsubscribe func()) {
+	return subscribeQueued(&e.resolve, "resolve", handler)
+}
+
+// OnRepublishComplete calls handler each time republishing the stored records completes
+func (e *Events) OnRepublishComplete(handler func(RepublishCompleteEvent)) (unsubscribe func()) {
+	return subscribeQueued(&e.republish, "republish", handler)
+}
+
+// OnChange calls handler with each change to a stored record, the events of change data capture
+func (e *Events) OnChange(handler func(cdc.Event)) (unsubscribe func()) {
+	return subscribeQueued(&e.change, "change", handler)
+}
+
+// OnEquivocation calls handler with each new equivocation detected, the evidence sent to the equivocation webhook
+func (e *Events) OnEquivocation(handler func(pkarr.Equivocation)) (unsubscribe func()) {
+	return subscribeQueued(&e.equivocation, "equivocation", handler)
+}
+
+// topic delivers events of one type to its subscribers
+type topic[E any] struct {
+	mu          sync.RWMutex
+	next        int
+	subscribers map[int]func(E)
+}
+
+// subscribe calls handler with each event emitted, on the goroutine emitting it
+func (t *topic[E]) subscribe(handler func(E)) (unsubscribe func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.subscribers == nil {
+		t.subscribers = make(map[int]func(E))
+	}
+	id := t.next
+	t.next++
+	t.subscribers[id] = handler
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.subscribers, id)
+	}
+}
+
+// emit delivers the event to each subscriber
+func (t *topic[E]) emit(event E) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, handler := range t.subscribers {
+		handler(event)
+	}
+}
+
+// subscribeQueued calls handler with the events of the topic on a goroutine of its own, in the order they were
+// emitted, dropping them while eventQueueSize events are waiting for it
+func subscribeQueued[E any](t *topic[E], name string, handler func(E)) (unsubscribe func()) {
+	queue := make(chan E, eventQueueSize)
+	var dropped atomic.Int64
+	unsubscribeTopic := t.subscribe(func(event E) {
+		select {
+		case queue <- event:
+		default:
+			logrus.Warnf("%s event queue is full, dropped event (%d dropped since subscribing)", name, dropped.Add(1))
+		}
+	})
+	go func() {
+		for event := range queue {
+			handler(event)
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			// no event is sent on the queue once the subscriber is removed, so it is safe to close
+			unsubscribeTopic()
+			close(queue)
+		})
+	}
+}
+
+// emitPublish emits the record stored for the given z-base-32 encoded ID and salt
+func (s *PkarrService) emitPublish(id string, salt []byte, record pkarr.Record, changed bool) {
+	s.events.publish.emit(PublishEvent{ID: id, Salt: salt, Record: record, Changed: changed, Timestamp: time.Now()})
+}
+
+// emitResolve emits the record resolved for the given z-base-32 encoded ID and salt from the source, without the
+// ID and salt in privacy mode
+func (s *PkarrService) emitResolve(id string, salt []byte, resp *GetPkarrResponse, source string) {
+	if resp == nil {
+		return
+	}
+	if s.cfg.PrivacyConfig.Enabled {
+		id, salt = "", nil
+	}
+	s.events.resolve.emit(ResolveEvent{ID: id, Salt: salt, Seq: resp.Seq, Source: source, Timestamp: time.Now()})
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cdc"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestEvents(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	put := bep44.Put{V: []byte("hello pkarr"), K: (*[32]byte)(pubKey), Seq: 1}
+	put.Sign(privKey)
+	v, err := bencode.Marshal(put.V)
+	require.NoError(t, err)
+
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "events.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	d := staticDHT{result: dht.FullGetResult{Seq: put.Seq, V: v, Sig: put.Sig, Mutable: true}}
+	svc, err := NewPkarrServiceWith(&cfg, db, d, cache.None{})
+	require.NoError(t, err)
+
+	publishes := make(chan PublishEvent, 10)
+	resolves := make(chan ResolveEvent, 10)
+	republishes := make(chan RepublishCompleteEvent, 10)
+	changes := make(chan cdc.Event, 10)
+	events := svc.Events()
+	unsubscribe := events.OnPublish(func(e PublishEvent) { publishes <- e })
+	events.OnResolve(func(e ResolveEvent) { resolves <- e })
+	events.OnRepublishComplete(func(e RepublishCompleteEvent) { republishes <- e })
+	events.OnChange(func(e cdc.Event) { changes <- e })
+
+	ctx := context.Background()
+	request := PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+	require.NoError(t, svc.storePkarr(ctx, id, request))
+	// republishing the same record is a publish, but not a change
+	require.NoError(t, svc.storePkarr(ctx, id, request))
+	for _, changed := range []bool{true, false} {
+		select {
+		case e := <-publishes:
+			assert.Equal(t, id, e.ID)
+			assert.Equal(t, put.Seq, e.Record.Seq)
+			assert.Equal(t, changed, e.Changed)
+		case <-time.After(time.Second):
+			t.Fatal("publish was not emitted")
+		}
+	}
+	select {
+	case e := <-changes:
+		assert.Equal(t, cdc.OpCreate, e.Op)
+		assert.Equal(t, id, e.ID)
+	case <-time.After(time.Second):
+		t.Fatal("change was not emitted")
+	}
+
+	resp, err := svc.GetPkarr(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	select {
+	case e := <-resolves:
+		assert.Equal(t, id, e.ID)
+		assert.Equal(t, put.Seq, e.Seq)
+		assert.Equal(t, ResolveSourceDHT, e.Source)
+	case <-time.After(time.Second):
+		t.Fatal("resolve was not emitted")
+	}
+
+	svc.republish()
+	select {
+	case e := <-republishes:
+		assert.Equal(t, 1, e.Records)
+		assert.Equal(t, e.Records, e.Republished+e.Failed)
+		assert.False(t, e.Finished.Before(e.Started))
+	case <-time.After(time.Second):
+		t.Fatal("republish completion was not emitted")
+	}
+
+	t.Run("unsubscribed handlers aren't called", func(t *testing.T) {
+		unsubscribe()
+		// unsubscribing again is a no-op
+		unsubscribe()
+		require.NoError(t, svc.storePkarr(ctx, id, request))
+		select {
+		case <-publishes:
+			t.Fatal("publish was delivered after unsubscribing")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
+func TestEventsPrivacyMode(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	put := bep44.Put{V: []byte("hello pkarr"), K: (*[32]byte)(pubKey), Seq: 1}
+	put.Sign(privKey)
+
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.PrivacyConfig.Enabled = true
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "privacy.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("is incompatible with lru eviction", func(t *testing.T) {
+		cfg := cfg
+		cfg.RetentionConfig.MaxRecords = 10
+		cfg.RetentionConfig.Eviction = config.EvictionLRU
+		_, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+		assert.Error(t, err)
+	})
+
+	t.Run("is incompatible with adopting resolved records", func(t *testing.T) {
+		cfg := cfg
+		cfg.PkarrConfig.AdoptOnResolve = true
+		_, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+		assert.Error(t, err)
+	})
+
+	svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+	require.NoError(t, err)
+	resolves := make(chan ResolveEvent, 1)
+	svc.Events().OnResolve(func(e ResolveEvent) { resolves <- e })
+	ctx := context.Background()
+	require.NoError(t, svc.storePkarr(ctx, id, PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}))
+	resp, err := svc.GetPkarr(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	select {
+	case e := <-resolves:
+		assert.Empty(t, e.ID)
+		assert.Equal(t, put.Seq, e.Seq)
+		assert.Equal(t, ResolveSourceStorage, e.Source)
+	case <-time.After(time.Second):
+		t.Fatal("resolve was not emitted")
+	}
+}
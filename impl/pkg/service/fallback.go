@@ -0,0 +1,217 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// fallbackWeight is the weight of the latest resolution in the moving averages of the latency and error rate of a
+	// gateway
+	fallbackWeight = 0.2
+	// fallbackErrorPenalty scales the latency of a gateway by its error rate when ranking gateways, so a fast gateway
+	// which often fails ranks below a slower reliable one
+	fallbackErrorPenalty = 10
+)
+
+// fallback resolves records from other gateways, for records this gateway never saw which have dropped off the DHT
+type fallback struct {
+	// configured are the gateways of the config, which the pool falls back to if no gateway is healthy
+	configured []string
+	timeout    time.Duration
+	// hedge is how long a resolution waits for a gateway before also trying the next, or zero to try them in turn
+	hedge  time.Duration
+	client *http.Client
+
+	mu sync.RWMutex
+	// gateways is the pool of gateways records are resolved from
+	gateways []string
+	// checked are the gateways found by the last discovery, healthy or not
+	checked []FallbackGateway
+	// stats are the latency and error rate of resolving records from each gateway
+	stats map[string]*gatewayStats
+}
+
+// gatewayStats are the moving averages of the latency and error rate of resolving records from a gateway
+type gatewayStats struct {
+	resolutions int64
+	errors      int64
+	latency     time.Duration
+	errorRate   float64
+}
+
+// score ranks the gateway, lower being better. Gateways not yet resolved from score zero, so they are tried.
+func (g *gatewayStats) score() float64 {
+	if g == nil {
+		return 0
+	}
+	return float64(g.latency) * (1 + fallbackErrorPenalty*g.errorRate)
+}
+
+func newFallback(gateways []string, timeout, hedge time.Duration) *fallback {
+	trimmed := make([]string, 0, len(gateways))
+	for _, gateway := range gateways {
+		trimmed = append(trimmed, strings.TrimSuffix(gateway, "/"))
+	}
+	return &fallback{
+		configured: trimmed,
+		gateways:   trimmed,
+		timeout:    timeout,
+		hedge:      hedge,
+		client:     http.DefaultClient,
+		stats:      make(map[string]*gatewayStats),
+	}
+}
+
+// pool returns the gateways records are resolved from, best first: by latency scaled by error rate, then in the
+// order of the pool
+func (f *fallback) pool() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	ranked := slices.Clone(f.gateways)
+	slices.SortStableFunc(ranked, func(a, b string) int {
+		return cmp.Compare(f.stats[a].score(), f.stats[b].score())
+	})
+	return ranked
+}
+
+// record updates the moving averages of the gateway with a resolution which took the given latency and failed or not
+func (f *fallback) record(gateway string, latency time.Duration, failed bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var failure float64
+	if failed {
+		failure = 1
+	}
+	stats, ok := f.stats[gateway]
+	if !ok {
+		stats = &gatewayStats{latency: latency, errorRate: failure}
+		f.stats[gateway] = stats
+	}
+	stats.resolutions++
+	if failed {
+		stats.errors++
+	}
+	stats.latency += time.Duration(fallbackWeight * float64(latency-stats.latency))
+	stats.errorRate += fallbackWeight * (failure - stats.errorRate)
+}
+
+// resolve returns the record for the given z-base-32 encoded ID from the best gateway which has a record signed by
+// its key, or nil if none do. Gateways are tried best first; if hedging, once a gateway has taken longer than the
+// hedge delay the next is tried too, and the first record found wins.
+func (f *fallback) resolve(ctx context.Context, id string) *GetPkarrResponse {
+	gateways := f.pool()
+	if len(gateways) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan *GetPkarrResponse, len(gateways))
+	next, inflight := 0, 0
+	try := func() {
+		gateway := gateways[next]
+		next++
+		inflight++
+		go func() {
+			results <- f.try(ctx, gateway, id)
+		}()
+	}
+
+	try()
+	for inflight > 0 {
+		var hedge <-chan time.Time
+		var timer *time.Timer
+		if f.hedge > 0 && next < len(gateways) {
+			timer = time.NewTimer(f.hedge)
+			hedge = timer.C
+		}
+		select {
+		case resp := <-results:
+			inflight--
+			if timer != nil {
+				timer.Stop()
+			}
+			if resp != nil {
+				return resp
+			}
+			if next < len(gateways) {
+				try()
+			}
+		case <-hedge:
+			logrus.Debugf("hedging resolution of pkarr record[%s] with gateway[%s]", id, gateways[next])
+			try()
+		}
+	}
+	return nil
+}
+
+// try gets the record for the given z-base-32 encoded ID from the gateway, returning it if it is signed by its key,
+// and records how long the gateway took and whether it failed
+func (f *fallback) try(ctx context.Context, gateway, id string) *GetPkarrResponse {
+	start := time.Now()
+	resp, err := f.get(ctx, gateway, id)
+	if err == nil && resp != nil {
+		if err = resp.verify(id, nil); err != nil {
+			logrus.WithError(err).Warnf("gateway[%s] returned an invalid pkarr record[%s]", gateway, id)
+		}
+	}
+	// resolutions cut short by another gateway resolving the record first don't count against this one
+	if ctx.Err() != nil && err != nil {
+		return nil
+	}
+	f.record(gateway, time.Since(start), err != nil)
+	if err != nil {
+		logrus.WithError(err).Debugf("failed to resolve pkarr record[%s] from gateway[%s]", id, gateway)
+		return nil
+	}
+	if resp != nil {
+		logrus.Debugf("resolved pkarr record[%s] from gateway[%s]", id, gateway)
+	}
+	return resp
+}
+
+// get gets the record from the relay API of the gateway, returning nil if the gateway doesn't have it
+func (f *fallback) get(ctx context.Context, gateway, id string) (*GetPkarrResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gateway+"/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	// the body is 64 bytes sig, 8 bytes u64 big-endian seq, and up to 1000 bytes of v
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 72+recordSizeLimit+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) <= 72 || len(body) > 72+recordSizeLimit {
+		return nil, fmt.Errorf("invalid response length: %d", len(body))
+	}
+	return &GetPkarrResponse{
+		V:   body[72:],
+		Seq: int64(binary.BigEndian.Uint64(body[64:72])),
+		Sig: [64]byte(body[:64]),
+	}, nil
+}
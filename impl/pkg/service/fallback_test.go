@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestFallback(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+
+	put := bep44.Put{V: []byte("hello pkarr"), K: (*[32]byte)(pubKey), Seq: 1}
+	put.Sign(privKey)
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], uint64(put.Seq))
+	body := append(append(put.Sig[:], seq[:]...), put.V.([]byte)...)
+
+	forged := append([]byte{}, body...)
+	forged[0] ^= 0xff
+
+	gateway := func(body []byte) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if body == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if r.URL.Path != "/"+id {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write(body)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(slow.Close)
+
+	t.Run("resolves from the first gateway with a valid record", func(t *testing.T) {
+		f := newFallback([]string{gateway(nil).URL, slow.URL, gateway(forged).URL, gateway(body).URL + "/"}, 100*time.Millisecond, 0)
+		got := f.resolve(context.Background(), id)
+		require.NotNil(t, got)
+		assert.Equal(t, put.V, got.V)
+		assert.Equal(t, put.Seq, got.Seq)
+		assert.Equal(t, put.Sig, got.Sig)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		f := newFallback([]string{gateway(nil).URL, gateway(forged).URL}, 100*time.Millisecond, 0)
+		assert.Nil(t, f.resolve(context.Background(), id))
+	})
+
+	t.Run("hedges slow gateways", func(t *testing.T) {
+		f := newFallback([]string{slow.URL, gateway(body).URL}, 5*time.Second, 20*time.Millisecond)
+		start := time.Now()
+		got := f.resolve(context.Background(), id)
+		require.NotNil(t, got)
+		assert.Equal(t, put.Seq, got.Seq)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("ranks gateways by latency and error rate", func(t *testing.T) {
+		failing, fast, slower := gateway(forged).URL, gateway(body).URL, gateway(body).URL
+		f := newFallback([]string{failing, slower, fast}, time.Second, 0)
+		f.record(failing, 5*time.Millisecond, true)
+		f.record(slower, 50*time.Millisecond, false)
+		f.record(fast, 10*time.Millisecond, false)
+		assert.Equal(t, []string{fast, slower, failing}, f.pool())
+
+		// the failing gateway moves up as it recovers
+		for i := 0; i < 5; i++ {
+			f.record(failing, 5*time.Millisecond, false)
+		}
+		assert.Equal(t, []string{fast, failing, slower}, f.pool())
+
+		got := f.resolve(context.Background(), id)
+		require.NotNil(t, got)
+		assert.Equal(t, int64(2), f.stats[fast].resolutions)
+	})
+}
+
+func TestDiscoverGateways(t *testing.T) {
+	gateway := func(status int) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+	configured, registered, unhealthy, announced := gateway(http.StatusOK), gateway(http.StatusOK), gateway(http.StatusServiceUnavailable), gateway(http.StatusOK)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"gateways": [{"url": "` + registered + `/"}, {"url": "` + unhealthy + `"}, {"url": "` + configured + `"}]}`))
+	}))
+	t.Cleanup(registry.Close)
+
+	// another gateway announces itself on the dht
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	doc, err := did.CreateDIDDHTDID(pubKey, did.CreateDIDDHTOpts{
+		Services: []didsdk.Service{{ID: GatewayServiceID, Type: GatewayServiceType, ServiceEndpoint: announced}},
+	})
+	require.NoError(t, err)
+	msg, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+	require.NoError(t, err)
+	put, err := dht.CreatePKARRPublishRequest(privKey, *msg)
+	require.NoError(t, err)
+	v, err := bencode.Marshal(put.V)
+	require.NoError(t, err)
+
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.PkarrConfig.FallbackDiscoveryCRON = ""
+	cfg.PkarrConfig.FallbackGateways = []string{configured}
+	cfg.PkarrConfig.FallbackRegistryURL = registry.URL
+	cfg.PkarrConfig.FallbackGatewayDIDs = []string{doc.ID}
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "discovery.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	d := staticDHT{result: dht.FullGetResult{Seq: put.Seq, V: v, Sig: put.Sig, Mutable: true}}
+	svc, err := NewPkarrServiceWith(&cfg, db, d, cache.None{})
+	require.NoError(t, err)
+
+	gateways, err := svc.GetFallbackGateways()
+	require.NoError(t, err)
+	require.Len(t, gateways, 1)
+	assert.Equal(t, configured, gateways[0].URL)
+
+	gateways, err = svc.DiscoverGateways(context.Background())
+	require.NoError(t, err)
+	sources := make(map[string]string)
+	for _, gateway := range gateways {
+		sources[gateway.URL] = gateway.Source
+		assert.Equal(t, gateway.URL != unhealthy, gateway.Healthy, gateway.URL)
+	}
+	assert.Equal(t, map[string]string{
+		configured: FallbackSourceConfig,
+		registered: FallbackSourceRegistry,
+		unhealthy:  FallbackSourceRegistry,
+		announced:  FallbackSourceDHT,
+	}, sources)
+
+	pool := svc.fallback.pool()
+	require.Len(t, pool, 3)
+	assert.Equal(t, configured, pool[0], "configured gateways come first")
+	assert.ElementsMatch(t, []string{registered, announced}, pool[1:])
+	assert.NotContains(t, pool, unhealthy)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	defaultFeedLimit = 100
+	maxFeedLimit     = 1000
+)
+
+// FeedPage is a page of the change feed, with the cursor to request the next page after
+type FeedPage struct {
+	Entries []pkarr.FeedEntry `json:"entries"`
+	// Cursor is the cursor of the last entry of the page, or the requested cursor if the page is empty, to pass as
+	// since to continue tailing the feed
+	Cursor int64 `json:"cursor"`
+}
+
+// appendFeed appends an entry for the record first seen or updated by the gateway to the change feed
+func (s *PkarrService) appendFeed(ctx context.Context, id string, request PublishPkarrRequest) {
+	entry := pkarr.FeedEntry{
+		ID:        id,
+		Salt:      base64.RawURLEncoding.EncodeToString(request.Salt),
+		Seq:       request.Seq,
+		Timestamp: time.Now().Unix(),
+	}
+	if _, err := s.feed.AppendFeedEntry(ctx, entry); err != nil {
+		logrus.WithError(err).Errorf("failed to append feed entry for record[%s]", id)
+	}
+}
+
+// ListFeed returns up to limit entries of the change feed after the given cursor, in the order the gateway saw them.
+// Entries of keys on the denylist are left out, though the cursor still moves past them.
+func (s *PkarrService) ListFeed(ctx context.Context, since int64, limit int) (*FeedPage, error) {
+	if limit <= 0 {
+		limit = defaultFeedLimit
+	}
+	limit = min(limit, maxFeedLimit)
+	entries, err := s.feed.ListFeedEntries(ctx, max(since, 0), limit)
+	if err != nil {
+		return nil, err
+	}
+	page := FeedPage{Entries: make([]pkarr.FeedEntry, 0, len(entries)), Cursor: since}
+	for _, entry := range entries {
+		if !s.isDenied(entry.ID) {
+			page.Entries = append(page.Entries, entry)
+		}
+	}
+	if len(entries) > 0 {
+		page.Cursor = entries[len(entries)-1].Cursor
+	}
+	return &page, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestFeed(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	request := func(v string, seq int64) PublishPkarrRequest {
+		put := bep44.Put{V: []byte(v), K: (*[32]byte)(pubKey), Seq: seq}
+		put.Sign(privKey)
+		return PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+	}
+
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.FeedConfig.Enabled = true
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "feed.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, staticDHT{}, cache.None{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	page, err := svc.ListFeed(ctx, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, page.Entries)
+	assert.Zero(t, page.Cursor)
+
+	require.NoError(t, svc.storePkarr(ctx, id, request("first", 1)))
+	// republishing the same seq isn't a change
+	require.NoError(t, svc.storePkarr(ctx, id, request("first", 1)))
+	require.NoError(t, svc.storePkarr(ctx, id, request("second", 2)))
+	assert.ErrorIs(t, svc.storePkarr(ctx, id, request("stale", 1)), ErrStaleSeq)
+
+	page, err = svc.ListFeed(ctx, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 2)
+	assert.Equal(t, id, page.Entries[0].ID)
+	assert.Equal(t, int64(1), page.Entries[0].Seq)
+	assert.Equal(t, int64(2), page.Entries[1].Seq)
+	assert.Less(t, page.Entries[0].Cursor, page.Entries[1].Cursor)
+	assert.Equal(t, page.Entries[1].Cursor, page.Cursor)
+
+	t.Run("tailing from a cursor", func(t *testing.T) {
+		first, err := svc.ListFeed(ctx, 0, 1)
+		require.NoError(t, err)
+		require.Len(t, first.Entries, 1)
+		next, err := svc.ListFeed(ctx, first.Cursor, 1)
+		require.NoError(t, err)
+		require.Len(t, next.Entries, 1)
+		assert.Equal(t, int64(2), next.Entries[0].Seq)
+
+		// the end of the feed keeps the cursor to poll from
+		end, err := svc.ListFeed(ctx, next.Cursor, 0)
+		require.NoError(t, err)
+		assert.Empty(t, end.Entries)
+		assert.Equal(t, next.Cursor, end.Cursor)
+	})
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// HistoryCheckpoint is a signed Merkle root over the first Size entries of the history log, computed according to
+// https://www.rfc-editor.org/rfc/rfc6962#section-2.1 with the entry hashes as leaves. The signature is over the
+// checkpoint message, see checkpointMessage.
+type HistoryCheckpoint struct {
+	Size      int64  `json:"size"`
+	RootHash  string `json:"rootHash"`
+	Timestamp int64  `json:"timestamp"`
+	// PublicKey is the base64url encoded ed25519 public key of the gateway
+	PublicKey string `json:"publicKey"`
+	// Signature is the base64url encoded ed25519 signature of the checkpoint message
+	Signature string `json:"signature"`
+}
+
+// history appends record updates to the log and checkpoints it
+type history struct {
+	db storage.HistoryLog
+
+	// mu serializes appends, which each depend on the previous entry
+	mu         sync.Mutex
+	checkpoint atomic.Pointer[HistoryCheckpoint]
+}
+
+// appendHistory appends an entry for the published record to the history log
+func (s *PkarrService) appendHistory(ctx context.Context, id string, request PublishPkarrRequest) {
+	s.history.mu.Lock()
+	defer s.history.mu.Unlock()
+
+	latest, err := s.history.db.ReadLatestHistoryEntry(ctx)
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to read latest history entry for record[%s]", id)
+		return
+	}
+	entry := newHistoryEntry(latest, id, request, time.Now().Unix())
+	if err = s.history.db.AppendHistoryEntry(ctx, entry); err != nil {
+		logrus.WithError(err).Errorf("failed to append history entry for record[%s]", id)
+	}
+}
+
+// ListHistory returns a page of the history log starting at the given index. Entries of keys on the denylist are
+// redacted, without their ID, salt and record, rather than left out, keeping their hashes so the chain and checkpoints
+// can still be verified.
+func (s *PkarrService) ListHistory(ctx context.Context, from int64, limit int) ([]pkarr.HistoryEntry, error) {
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	limit = min(limit, maxHistoryLimit)
+	entries, err := s.history.db.ListHistoryEntries(ctx, from, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if s.isDenied(entry.ID) {
+			entries[i] = pkarr.HistoryEntry{
+				Index:     entry.Index,
+				Timestamp: entry.Timestamp,
+				PrevHash:  entry.PrevHash,
+				Hash:      entry.Hash,
+				Redacted:  true,
+			}
+		}
+	}
+	return entries, nil
+}
+
+// GetHistoryCheckpoint returns the latest checkpoint of the history log, or nil if none has been made yet
+func (s *PkarrService) GetHistoryCheckpoint() *HistoryCheckpoint {
+	return s.history.checkpoint.Load()
+}
+
+// checkpointHistory computes and signs the Merkle root of the history log
+func (s *PkarrService) checkpointHistory() {
+	ctx := context.Background()
+	var leaves [][]byte
+	for {
+		entries, err := s.history.db.ListHistoryEntries(ctx, int64(len(leaves)), maxHistoryLimit)
+		if err != nil {
+			logrus.WithError(err).Error("failed to list history entries for checkpoint")
+			return
+		}
+		for _, entry := range entries {
+			leaf, err := hex.DecodeString(entry.Hash)
+			if err != nil {
+				logrus.WithError(err).Errorf("failed to decode hash of history entry[%d]", entry.Index)
+				return
+			}
+			leaves = append(leaves, leaf)
+		}
+		if len(entries) < maxHistoryLimit {
+			break
+		}
+	}
+
+	checkpoint := HistoryCheckpoint{
+		Size:      int64(len(leaves)),
+		RootHash:  hex.EncodeToString(merkleRoot(leaves)),
+		Timestamp: time.Now().Unix(),
+		PublicKey: base64.RawURLEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey)),
+	}
+	signature := ed25519.Sign(s.key, checkpointMessage(checkpoint))
+	checkpoint.Signature = base64.RawURLEncoding.EncodeToString(signature)
+	s.history.checkpoint.Store(&checkpoint)
+	logrus.Infof("Checkpointed history of %d entries with root[%s]", checkpoint.Size, checkpoint.RootHash)
+}
+
+// newHistoryEntry builds the entry following prev, which is nil for the first entry of the log
+func newHistoryEntry(prev *pkarr.HistoryEntry, id string, request PublishPkarrRequest, timestamp int64) pkarr.HistoryEntry {
+	entry := pkarr.HistoryEntry{
+		ID:         id,
+		Salt:       base64.RawURLEncoding.EncodeToString(request.Salt),
+		Seq:        request.Seq,
+		RecordHash: hex.EncodeToString(hashPayload(request.Sig, request.Seq, request.V)),
+		Timestamp:  timestamp,
+	}
+	if prev != nil {
+		entry.Index = prev.Index + 1
+		entry.PrevHash = prev.Hash
+	}
+	entry.Hash = hashHistoryEntry(entry)
+	return entry
+}
+
+// hashHistoryEntry returns the hex encoded SHA-256 hash of the newline separated fields of the entry. The salt is
+// only hashed if set, so entries of unsalted records, and those appended before salts were logged, hash as before.
+func hashHistoryEntry(entry pkarr.HistoryEntry) string {
+	data := fmt.Sprintf("%d\n%s\n%d\n%s\n%d\n%s", entry.Index, entry.ID, entry.Seq, entry.RecordHash, entry.Timestamp, entry.PrevHash)
+	if entry.Salt != "" {
+		data += "\n" + entry.Salt
+	}
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
+
+// checkpointMessage returns the message signed for a checkpoint
+func checkpointMessage(checkpoint HistoryCheckpoint) []byte {
+	return []byte(fmt.Sprintf("did-dht-history\n%d\n%s\n%d", checkpoint.Size, checkpoint.RootHash, checkpoint.Timestamp))
+}
+
+// merkleRoot computes the Merkle Tree Hash of the leaves according to https://www.rfc-editor.org/rfc/rfc6962#section-2.1
+func merkleRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		hash := sha256.Sum256(nil)
+		return hash[:]
+	case 1:
+		hash := sha256.Sum256(append([]byte{0x00}, leaves[0]...))
+		return hash[:]
+	}
+	// split at the largest power of two smaller than the number of leaves
+	split := 1
+	for split*2 < len(leaves) {
+		split *= 2
+	}
+	node := append([]byte{0x01}, merkleRoot(leaves[:split])...)
+	node = append(node, merkleRoot(leaves[split:])...)
+	hash := sha256.Sum256(node)
+	return hash[:]
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHistoryEntry(t *testing.T) {
+	first := newHistoryEntry(nil, "alice", PublishPkarrRequest{V: []byte("v1"), Seq: 1}, 100)
+	assert.Equal(t, int64(0), first.Index)
+	assert.Empty(t, first.PrevHash)
+	assert.Equal(t, hashHistoryEntry(first), first.Hash)
+
+	second := newHistoryEntry(&first, "alice", PublishPkarrRequest{V: []byte("v2"), Seq: 2}, 200)
+	assert.Equal(t, int64(1), second.Index)
+	assert.Equal(t, first.Hash, second.PrevHash)
+	assert.NotEqual(t, first.RecordHash, second.RecordHash)
+
+	// rewriting an earlier entry changes its hash, breaking the chain
+	rewritten := first
+	rewritten.Seq = 3
+	assert.NotEqual(t, second.PrevHash, hashHistoryEntry(rewritten))
+
+	// updates of salted records are told apart from those of the unsalted record of the same key
+	salted := newHistoryEntry(&first, "alice", PublishPkarrRequest{V: []byte("v2"), Seq: 2, Salt: []byte("salt")}, 200)
+	assert.Equal(t, "c2FsdA", salted.Salt)
+	assert.Empty(t, second.Salt)
+	assert.NotEqual(t, second.Hash, salted.Hash)
+	unsalted := salted
+	unsalted.Salt = ""
+	assert.NotEqual(t, salted.Hash, hashHistoryEntry(unsalted))
+}
+
+func TestMerkleRoot(t *testing.T) {
+	hash := func(data ...[]byte) []byte {
+		h := sha256.New()
+		for _, d := range data {
+			h.Write(d)
+		}
+		return h.Sum(nil)
+	}
+	leaf := func(d []byte) []byte { return hash([]byte{0x00}, d) }
+	node := func(l, r []byte) []byte { return hash([]byte{0x01}, l, r) }
+	a, b, c := []byte("a"), []byte("b"), []byte("c")
+
+	// the empty tree is the hash of the empty string
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", hex.EncodeToString(merkleRoot(nil)))
+	assert.Equal(t, leaf(a), merkleRoot([][]byte{a}))
+	assert.Equal(t, node(leaf(a), leaf(b)), merkleRoot([][]byte{a, b}))
+	assert.Equal(t, node(node(leaf(a), leaf(b)), leaf(c)), merkleRoot([][]byte{a, b, c}))
+}
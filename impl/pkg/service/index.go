@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+
+	intutil "github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+// QueryDocumentsResult is a page of DIDs matching a document query
+type QueryDocumentsResult struct {
+	DIDs []string
+	// Next is the cursor to pass as the After value of the query for the next page, empty if there are no more results
+	Next string
+}
+
+// QueryDocuments returns a page of DIDs of the indexed documents matching the query. Documents of keys on the
+// denylist stay indexed but are left out of the page, which may then hold fewer DIDs than the limit.
+func (s *PkarrService) QueryDocuments(ctx context.Context, query pkarr.DocumentQuery) (*QueryDocumentsResult, error) {
+	if query.Limit <= 0 {
+		query.Limit = defaultQueryLimit
+	}
+	if query.Limit > maxQueryLimit {
+		query.Limit = maxQueryLimit
+	}
+	if query.Field == pkarr.ServiceDomainField {
+		query.Value = strings.ToLower(query.Value)
+	}
+	ids, err := s.index.QueryDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	result := QueryDocumentsResult{DIDs: make([]string, 0, len(ids))}
+	for _, id := range ids {
+		if s.isDenied(id) {
+			continue
+		}
+		result.DIDs = append(result.DIDs, did.Prefix+":"+id)
+	}
+	if len(ids) == query.Limit {
+		result.Next = ids[len(ids)-1]
+	}
+	return &result, nil
+}
+
+// indexRecord decodes the DID Document represented by the given packet and adds it to the index. Records which are
+// not DID Documents are skipped.
+func (s *PkarrService) indexRecord(ctx context.Context, id string, v []byte) {
+	msg := new(dns.Msg)
+	if err := msg.Unpack(v); err != nil {
+		logrus.WithError(err).Debugf("skipping indexing of record[%s], not a dns packet", id)
+		return
+	}
+	doc, types, err := did.DHT(did.Prefix + ":" + id).FromDNSPacket(msg)
+	if err != nil {
+		logrus.WithError(err).Debugf("skipping indexing of record[%s], not a did document", id)
+		return
+	}
+	if err = s.index.IndexDocument(ctx, toIndexedDocument(id, *doc, types)); err != nil {
+		logrus.WithError(err).Errorf("failed to index record[%s]", id)
+	}
+}
+
+// unindexRecord removes the DID Document of the deleted record for the given ID from the index
+func (s *PkarrService) unindexRecord(ctx context.Context, id string) {
+	if err := s.index.DeleteDocument(ctx, id); err != nil {
+		logrus.WithError(err).Errorf("failed to remove record[%s] from the index", id)
+	}
+}
+
+// reindex adds the documents of all stored records to the index
+func (s *PkarrService) reindex() {
+	ctx := context.Background()
+	records, err := s.db.ListRecords(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("failed to list record(s) for indexing")
+		return
+	}
+	for _, record := range records {
+		kBytes, err := base64.RawURLEncoding.DecodeString(record.K)
+		if err != nil {
+			logrus.WithError(err).Error("failed to decode record key for indexing")
+			continue
+		}
+		vBytes, err := base64.RawURLEncoding.DecodeString(record.V)
+		if err != nil {
+			logrus.WithError(err).Error("failed to decode record value for indexing")
+			continue
+		}
+		s.indexRecord(ctx, intutil.Z32Encode(kBytes), vBytes)
+	}
+	logrus.Infof("Indexed [%d] record(s)", len(records))
+}
+
+func toIndexedDocument(id string, doc didsdk.Document, types []did.TypeIndex) pkarr.Document {
+	indexed := pkarr.Document{ID: id}
+	for _, service := range doc.Services {
+		indexed.ServiceTypes = appendUnique(indexed.ServiceTypes, service.Type)
+		var endpoints []string
+		switch endpoint := service.ServiceEndpoint.(type) {
+		case string:
+			endpoints = []string{endpoint}
+		case []string:
+			endpoints = endpoint
+		}
+		for _, endpoint := range endpoints {
+			indexed.ServiceEndpoints = appendUnique(indexed.ServiceEndpoints, endpoint)
+			if u, err := url.Parse(endpoint); err == nil && u.Hostname() != "" {
+				indexed.ServiceDomains = appendUnique(indexed.ServiceDomains, strings.ToLower(u.Hostname()))
+			}
+		}
+	}
+	for _, vm := range doc.VerificationMethod {
+		indexed.VerificationMethodTypes = appendUnique(indexed.VerificationMethodTypes, string(vm.Type))
+	}
+	for _, t := range types {
+		indexed.Types = appendUnique(indexed.Types, strconv.Itoa(int(t)))
+	}
+	return indexed
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func TestToIndexedDocument(t *testing.T) {
+	_, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{
+		Services: []didsdk.Service{
+			{
+				ID:              "dwn",
+				Type:            "DecentralizedWebNode",
+				ServiceEndpoint: []string{"https://example.com/dwn", "https://example.org/dwn"},
+			},
+			{
+				ID:              "dwn2",
+				Type:            "DecentralizedWebNode",
+				ServiceEndpoint: "https://example.com/dwn",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	id, err := did.DHT(doc.ID).Suffix()
+	require.NoError(t, err)
+
+	indexed := toIndexedDocument(id, *doc, []did.TypeIndex{did.Organization, did.WebApplication})
+	assert.Equal(t, pkarr.Document{
+		ID:                      id,
+		ServiceTypes:            []string{"DecentralizedWebNode"},
+		ServiceEndpoints:        []string{"https://example.com/dwn", "https://example.org/dwn"},
+		ServiceDomains:          []string{"example.com", "example.org"},
+		VerificationMethodTypes: []string{"JsonWebKey"},
+		Types:                   []string{"1", "6"},
+	}, indexed)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+)
+
+// PublishInterceptor enforces a policy on records before they are accepted for publishing, such as an allowlist,
+// business rules, or content scanning. Returning an error rejects the record; returning a PublishRejectedError
+// reports the rejection to the publisher as a policy violation rather than a failure of the gateway.
+type PublishInterceptor interface {
+	InterceptPublish(ctx context.Context, id string, request PublishPkarrRequest) error
+}
+
+// PublishInterceptorFunc adapts a function to a PublishInterceptor
+type PublishInterceptorFunc func(ctx context.Context, id string, request PublishPkarrRequest) error
+
+// InterceptPublish calls f(ctx, id, request)
+func (f PublishInterceptorFunc) InterceptPublish(ctx context.Context, id string, request PublishPkarrRequest) error {
+	return f(ctx, id, request)
+}
+
+// PublishRejectedError is returned when a PublishInterceptor rejects a record
+type PublishRejectedError struct {
+	ID     string
+	Reason string
+}
+
+func (e *PublishRejectedError) Error() string {
+	return fmt.Sprintf("record[%s] rejected: %s", e.ID, e.Reason)
+}
+
+// RegisterPublishInterceptor adds an interceptor, which runs after the record's signature is verified and after all
+// previously registered interceptors. Interceptors must be registered before the service starts serving requests.
+func (s *PkarrService) RegisterPublishInterceptor(interceptor PublishInterceptor) {
+	s.interceptors = append(s.interceptors, interceptor)
+}
+
+// interceptPublish runs the registered interceptors in order, returning the first rejection
+func (s *PkarrService) interceptPublish(ctx context.Context, id string, request PublishPkarrRequest) error {
+	for _, interceptor := range s.interceptors {
+		if err := interceptor.InterceptPublish(ctx, id, request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KeyAllowList returns an interceptor which rejects records for any z-base-32 encoded ID not in the given list
+func KeyAllowList(ids []string) PublishInterceptor {
+	allowed := toSet(ids)
+	return PublishInterceptorFunc(func(_ context.Context, id string, _ PublishPkarrRequest) error {
+		if _, ok := allowed[id]; !ok {
+			return &PublishRejectedError{ID: id, Reason: "key is not allowed"}
+		}
+		return nil
+	})
+}
+
+// KeyDenyList returns an interceptor which rejects records for any z-base-32 encoded ID in the given list
+func KeyDenyList(ids []string) PublishInterceptor {
+	denied := toSet(ids)
+	return PublishInterceptorFunc(func(_ context.Context, id string, _ PublishPkarrRequest) error {
+		if _, ok := denied[id]; ok {
+			return &PublishRejectedError{ID: id, Reason: "key is denied"}
+		}
+		return nil
+	})
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
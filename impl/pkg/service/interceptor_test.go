@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishInterceptors(t *testing.T) {
+	ctx := context.Background()
+	request := PublishPkarrRequest{}
+
+	t.Run("no interceptors", func(t *testing.T) {
+		var svc PkarrService
+		assert.NoError(t, svc.interceptPublish(ctx, "alice", request))
+	})
+
+	t.Run("allow list", func(t *testing.T) {
+		var svc PkarrService
+		svc.RegisterPublishInterceptor(KeyAllowList([]string{"alice"}))
+		assert.NoError(t, svc.interceptPublish(ctx, "alice", request))
+
+		err := svc.interceptPublish(ctx, "bob", request)
+		var rejected *PublishRejectedError
+		assert.ErrorAs(t, err, &rejected)
+		assert.Equal(t, "bob", rejected.ID)
+	})
+
+	t.Run("deny list", func(t *testing.T) {
+		var svc PkarrService
+		svc.RegisterPublishInterceptor(KeyDenyList([]string{"alice"}))
+		assert.NoError(t, svc.interceptPublish(ctx, "bob", request))
+
+		var rejected *PublishRejectedError
+		assert.ErrorAs(t, svc.interceptPublish(ctx, "alice", request), &rejected)
+	})
+
+	t.Run("runs in order", func(t *testing.T) {
+		var svc PkarrService
+		var calls []string
+		svc.RegisterPublishInterceptor(PublishInterceptorFunc(func(context.Context, string, PublishPkarrRequest) error {
+			calls = append(calls, "first")
+			return errors.New("failed")
+		}))
+		svc.RegisterPublishInterceptor(PublishInterceptorFunc(func(context.Context, string, PublishPkarrRequest) error {
+			calls = append(calls, "second")
+			return nil
+		}))
+		assert.EqualError(t, svc.interceptPublish(ctx, "alice", request), "failed")
+		assert.Equal(t, []string{"first"}, calls)
+	})
+}
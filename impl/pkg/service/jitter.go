@@ -0,0 +1,39 @@
+package service
+
+import (
+	"math/rand"
+	"time"
+)
+
+// republishPacing spreads republish runs over time, so gateways on the same schedule holding the same records don't
+// put them to the same DHT nodes in synchronized bursts
+type republishPacing struct {
+	// jitter is the most the start of a run is delayed by, at random
+	jitter time.Duration
+	// smear is the window the puts of a run are spread evenly across
+	smear time.Duration
+}
+
+// delay returns the random delay before a run starts, up to the jitter
+func (p republishPacing) delay() time.Duration {
+	if p.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(p.jitter)))
+}
+
+// due returns when the i-th of n records of a run whose puts started at the given time is due to be put
+func (p republishPacing) due(start time.Time, i, n int) time.Time {
+	if p.smear <= 0 || n == 0 {
+		return start
+	}
+	return start.Add(time.Duration(int64(p.smear) * int64(i) / int64(n)))
+}
+
+// order shuffles the records of a run being smeared, so gateways holding the same records don't put them in the same
+// order at the same offsets
+func (p republishPacing) order(n int, swap func(i, j int)) {
+	if p.smear > 0 {
+		rand.Shuffle(n, swap)
+	}
+}
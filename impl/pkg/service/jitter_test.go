@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestRepublishPacing(t *testing.T) {
+	start := time.Now()
+	none := republishPacing{}
+	assert.Zero(t, none.delay())
+	assert.Equal(t, start, none.due(start, 3, 4))
+
+	pacing := republishPacing{jitter: time.Minute, smear: time.Hour}
+	for i := 0; i < 10; i++ {
+		delay := pacing.delay()
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.Less(t, delay, time.Minute)
+	}
+	assert.Equal(t, start, pacing.due(start, 0, 4))
+	assert.Equal(t, start.Add(45*time.Minute), pacing.due(start, 3, 4))
+}
+
+func TestSmearedRepublishStopsWhileDraining(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "jitter.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	d := new(flakyDHT)
+	svc, err := NewPkarrServiceWith(&cfg, db, d, cache.None{})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		pubKey, privKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		put := bep44.Put{V: []byte("hello smearing"), K: (*[32]byte)(pubKey), Seq: 1}
+		put.Sign(privKey)
+		request := PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+		require.NoError(t, svc.storePkarr(ctx, util.Z32Encode(pubKey), request))
+	}
+
+	completed := make(chan RepublishCompleteEvent, 1)
+	svc.events.OnRepublishComplete(func(event RepublishCompleteEvent) { completed <- event })
+	svc.republishPacing = republishPacing{smear: time.Hour}
+	go svc.republish()
+
+	// the first record is put at once, and the others 20 and 40 minutes into the run
+	require.Eventually(t, func() bool { return d.puts.Load() == 1 }, time.Second, 10*time.Millisecond)
+	svc.Drain()
+	select {
+	case event := <-completed:
+		assert.Equal(t, 3, event.Records)
+		assert.Equal(t, 1, event.Republished)
+	case <-time.After(time.Second):
+		t.Fatal("republishing didn't stop while draining")
+	}
+	assert.True(t, svc.DrainStatus().Drained)
+}
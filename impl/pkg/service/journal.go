@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// journalPut writes the intent to put the published record for the given z-base-32 encoded ID and salt to the DHT, if
+// the publish journal is enabled, before the record is stored
+func (s *PkarrService) journalPut(ctx context.Context, id string, salt []byte, seq int64) error {
+	if s.journal == nil {
+		return nil
+	}
+	key, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return err
+	}
+	return s.journal.WriteJournalEntry(ctx, pkarr.JournalEntry{Key: key, Seq: seq, Timestamp: time.Now().Unix()})
+}
+
+// ackPut deletes the journal entry of the put of the record for the given z-base-32 encoded ID and salt once the put
+// completed, or the record failed to store, unless a newer publish replaced it
+func (s *PkarrService) ackPut(id string, salt []byte, seq int64) {
+	if s.journal == nil {
+		return
+	}
+	key, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return
+	}
+	if err = s.journal.DeleteJournalEntry(context.Background(), key, seq); err != nil {
+		logrus.WithError(err).Warnf("failed to acknowledge put of pkarr record[%s] in the journal", id)
+	}
+}
+
+// ReplayJournal puts the stored records whose puts were journaled but never completed, such as when the gateway
+// crashed between storing a record and putting it, to the DHT. Entries of records since deleted or denied are dropped,
+// and entries whose put fails again are kept for the next replay. It returns the number of records put.
+func (s *PkarrService) ReplayJournal(ctx context.Context) (int, error) {
+	if s.journal == nil {
+		return 0, nil
+	}
+	entries, err := s.journal.ListJournalEntries(ctx)
+	if err != nil {
+		return 0, err
+	}
+	replayed := 0
+	for _, entry := range entries {
+		record, err := s.db.ReadRecord(ctx, entry.Key)
+		if err != nil {
+			logrus.WithError(err).Warnf("failed to read journaled pkarr record[%s]", entry.Key)
+			continue
+		}
+		dropped := record == nil
+		if record != nil {
+			if id, err := recordID(record.K); err == nil && s.isDenied(id) {
+				dropped = true
+			}
+		}
+		if dropped {
+			if err = s.journal.DeleteJournalEntry(ctx, entry.Key, entry.Seq); err != nil {
+				logrus.WithError(err).Warnf("failed to drop journal entry of pkarr record[%s]", entry.Key)
+			}
+			continue
+		}
+		put, err := recordToBEP44Put(*record)
+		if err != nil {
+			logrus.WithError(err).Errorf("failed to convert journaled pkarr record[%s] to bep44 put", entry.Key)
+			continue
+		}
+		result, err := dht.PutReplicated(ctx, s.dht, *put)
+		if err != nil {
+			logrus.WithError(err).Errorf("failed to replay put of pkarr record[%s]", entry.Key)
+			continue
+		}
+		logReplication(entry.Key, result)
+		if err = s.journal.DeleteJournalEntry(ctx, entry.Key, entry.Seq); err != nil {
+			logrus.WithError(err).Warnf("failed to acknowledge put of pkarr record[%s] in the journal", entry.Key)
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+// replayJournal replays the journal on startup, logging whether it failed
+func (s *PkarrService) replayJournal() {
+	if !s.drain.begin() {
+		return
+	}
+	defer s.drain.done()
+	replayed, err := s.ReplayJournal(context.Background())
+	if err != nil {
+		logrus.WithError(err).Error("failed to replay the publish journal")
+		return
+	}
+	if replayed > 0 {
+		logrus.Infof("replayed %d journaled put(s) of pkarr record(s) interrupted before completing", replayed)
+	}
+}
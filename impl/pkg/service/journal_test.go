@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+// flakyDHT has no records, and fails puts while failing is set, counting them
+type flakyDHT struct {
+	failing atomic.Bool
+	puts    atomic.Int32
+}
+
+func (d *flakyDHT) Put(context.Context, bep44.Put) (string, error) {
+	d.puts.Add(1)
+	if d.failing.Load() {
+		return "", errors.New("unreachable")
+	}
+	return "", nil
+}
+
+func (d *flakyDHT) GetFull(context.Context, string, []byte) (*dht.FullGetResult, error) {
+	return nil, errors.New("not found")
+}
+
+func TestPublishJournal(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	publish := func(seq int64) PublishPkarrRequest {
+		put := bep44.Put{V: []byte("hello pkarr"), K: (*[32]byte)(pubKey), Seq: seq}
+		put.Sign(privKey)
+		return PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+	}
+
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.PkarrConfig.PublishJournal = true
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "journal.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	d := new(flakyDHT)
+	svc, err := NewPkarrServiceWith(&cfg, db, d, cache.None{})
+	require.NoError(t, err)
+	ctx := context.Background()
+	journal, ok := storage.As[storage.PutJournal](db)
+	require.True(t, ok)
+
+	// a put which fails, as if the gateway crashed before it, stays journaled
+	d.failing.Store(true)
+	require.NoError(t, svc.PublishPkarr(ctx, id, publish(1)))
+	require.Eventually(t, func() bool { return d.puts.Load() > 0 }, time.Second, 10*time.Millisecond)
+	entries, err := journal.ListJournalEntries(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(1), entries[0].Seq)
+
+	d.failing.Store(false)
+	replayed, err := svc.ReplayJournal(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	entries, err = journal.ListJournalEntries(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	t.Run("completed puts are acknowledged", func(t *testing.T) {
+		require.NoError(t, svc.PublishPkarr(ctx, id, publish(2)))
+		assert.Eventually(t, func() bool {
+			entries, err := journal.ListJournalEntries(ctx)
+			return err == nil && len(entries) == 0
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("puts of records which fail to store are dropped", func(t *testing.T) {
+		err := svc.PublishPkarr(ctx, id, publish(1))
+		assert.ErrorIs(t, err, ErrStaleSeq)
+		entries, err := journal.ListJournalEntries(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
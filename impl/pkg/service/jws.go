@@ -0,0 +1,89 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// jwsHeader is the protected header of a JWS signed by the gateway, or by the key of an ID, with the EdDSA algorithm
+// of RFC 8037
+type jwsHeader struct {
+	Alg string `json:"alg"`
+	// Cty is the content type of the payload
+	Cty string `json:"cty,omitempty"`
+	// JWK is the public key of the signer
+	JWK jwsKey `json:"jwk"`
+}
+
+// jwsKey is an ed25519 public key as an OKP JWK
+type jwsKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+// SignJWS returns the compact JWS (RFC 7515) of the payload signed with the gateway's key, whose protected header has
+// the given content type (cty) and the gateway's public key as a JWK
+func (s *PkarrService) SignJWS(payload []byte, contentType string) (string, error) {
+	return signJWS(s.key, payload, contentType)
+}
+
+// signJWS returns the compact JWS of the payload signed with the given key, whose protected header has the given
+// content type (cty) and the public key as a JWK
+func signJWS(key ed25519.PrivateKey, payload []byte, contentType string) (string, error) {
+	header, err := json.Marshal(jwsHeader{
+		Alg: "EdDSA",
+		Cty: contentType,
+		JWK: jwsKey{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(key, []byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyJWS verifies the signature of a compact JWS against the public key of its signer, such as a gateway or the
+// owner of an ID, returning the payload. The key is pinned by the caller; the JWK in the header is only checked to
+// match it. The content type (cty) of the header must be the given one, which binds the JWS to its purpose.
+func VerifyJWS(compact string, publicKey ed25519.PublicKey, contentType string) ([]byte, error) {
+	parts := strings.Split(compact, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("jws is not in compact form")
+	}
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	var header jwsHeader
+	if err = json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "EdDSA" {
+		return nil, errors.New("jws algorithm is not EdDSA")
+	}
+	if header.Cty != contentType {
+		return nil, fmt.Errorf("jws content type is %q, not %q", header.Cty, contentType)
+	}
+	if header.JWK.X != base64.RawURLEncoding.EncodeToString(publicKey) {
+		return nil, errors.New("jws is signed by another key")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(publicKey, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, errors.New("jws signature is invalid")
+	}
+	return base64.RawURLEncoding.DecodeString(parts[1])
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignJWS(t *testing.T) {
+	key, err := signingKey("")
+	require.NoError(t, err)
+	svc := PkarrService{key: key}
+	publicKey := key.Public().(ed25519.PublicKey)
+
+	compact, err := svc.SignJWS([]byte(`{"status":200}`), "json")
+	require.NoError(t, err)
+
+	t.Run("verifies", func(t *testing.T) {
+		payload, err := VerifyJWS(compact, publicKey, "json")
+		require.NoError(t, err)
+		assert.Equal(t, `{"status":200}`, string(payload))
+	})
+
+	t.Run("other gateway", func(t *testing.T) {
+		otherKey, err := signingKey("")
+		require.NoError(t, err)
+		_, err = VerifyJWS(compact, otherKey.Public().(ed25519.PublicKey), "json")
+		assert.Error(t, err)
+	})
+
+	t.Run("other content type", func(t *testing.T) {
+		_, err := VerifyJWS(compact, publicKey, SeqResetConsentContentType)
+		assert.ErrorContains(t, err, "content type")
+	})
+
+	t.Run("tampered payload", func(t *testing.T) {
+		other, err := svc.SignJWS([]byte(`{"status":404}`), "json")
+		require.NoError(t, err)
+		parts, otherParts := strings.Split(compact, "."), strings.Split(other, ".")
+		_, err = VerifyJWS(parts[0]+"."+otherParts[1]+"."+parts[2], publicKey, "json")
+		assert.ErrorContains(t, err, "signature is invalid")
+	})
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// maxLabels is the maximum number of labels of a record
+const maxLabels = 32
+
+var (
+	// ErrInvalidLabel is returned for labels which aren't 1 to 64 letters, digits, and separators, or for more than
+	// maxLabels labels
+	ErrInvalidLabel      = errors.New("invalid label")
+	errLabelsUnsupported = errors.New("storage does not support labels")
+
+	// labelPattern matches labels of letters, digits, and the separators ':', '.', '_', '/', and '-', which start with
+	// a letter or digit, such as "customer:acme"
+	labelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9:._/-]{0,63}$`)
+)
+
+// labels is an in-memory copy of the stored labels, which are checked when evicting records
+type labels struct {
+	db storage.LabelStore
+
+	mu    sync.RWMutex
+	byKey map[string][]string
+}
+
+func newLabels(ctx context.Context, db storage.LabelStore) (*labels, error) {
+	labeled, err := db.ListRecordLabels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string][]string, len(labeled))
+	for _, recordLabels := range labeled {
+		byKey[recordLabels.Key] = recordLabels.Labels
+	}
+	return &labels{db: db, byKey: byKey}, nil
+}
+
+// LabeledRecord is the record of an ID and salt with the labels an operator attached to it
+type LabeledRecord struct {
+	// ID is the z-base-32 encoded ID of the record
+	ID string `json:"id"`
+	// Salt is the base64url encoded salt of the record, if any
+	Salt   string   `json:"salt,omitempty"`
+	Labels []string `json:"labels"`
+	// Timestamp is the unix time in seconds the labels were set at
+	Timestamp int64 `json:"timestamp"`
+}
+
+// LabelRecord replaces the labels of the record for the given z-base-32 encoded ID and optional salt, removing them
+// if none are given. The record needn't be stored, and its labels are kept if it is deleted.
+func (s *PkarrService) LabelRecord(ctx context.Context, id string, salt []byte, recordLabels []string) error {
+	if !s.cfg.ServerConfig.Role.Publishes() {
+		return ErrReadOnly
+	}
+	if s.labels == nil {
+		return errLabelsUnsupported
+	}
+	key, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return err
+	}
+	if recordLabels, err = normalizeLabels(recordLabels); err != nil {
+		return err
+	}
+	if len(recordLabels) == 0 {
+		err = s.labels.db.DeleteRecordLabels(ctx, key)
+	} else {
+		err = s.labels.db.WriteRecordLabels(ctx, pkarr.RecordLabels{Key: key, Labels: recordLabels, Timestamp: time.Now().Unix()})
+	}
+	if err != nil {
+		return err
+	}
+	s.labels.mu.Lock()
+	if len(recordLabels) == 0 {
+		delete(s.labels.byKey, key)
+	} else {
+		s.labels.byKey[key] = recordLabels
+	}
+	s.labels.mu.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"audit":  "labels",
+		"action": "label",
+		"id":     id,
+		"labels": recordLabels,
+	}).Info("record labeled")
+	return nil
+}
+
+// GetRecordLabels returns the labels of the record for the given z-base-32 encoded ID and optional salt, or nil if
+// it has none
+func (s *PkarrService) GetRecordLabels(id string, salt []byte) ([]string, error) {
+	if s.labels == nil {
+		return nil, errLabelsUnsupported
+	}
+	key, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return nil, err
+	}
+	s.labels.mu.RLock()
+	defer s.labels.mu.RUnlock()
+	return s.labels.byKey[key], nil
+}
+
+// ListLabeledRecords returns the labeled records, ordered by the key they are stored under, or only those with the
+// given label if not empty
+func (s *PkarrService) ListLabeledRecords(ctx context.Context, label string) ([]LabeledRecord, error) {
+	if s.labels == nil {
+		return nil, errLabelsUnsupported
+	}
+	labeled, err := s.labels.db.ListRecordLabels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]LabeledRecord, 0, len(labeled))
+	for _, recordLabels := range labeled {
+		if label != "" && !slices.Contains(recordLabels.Labels, label) {
+			continue
+		}
+		k, salt, _ := strings.Cut(recordLabels.Key, ".")
+		id, err := recordID(k)
+		if err != nil {
+			logrus.WithError(err).Warnf("skipping labels of malformed record key[%s]", recordLabels.Key)
+			continue
+		}
+		records = append(records, LabeledRecord{
+			ID:        id,
+			Salt:      salt,
+			Labels:    recordLabels.Labels,
+			Timestamp: recordLabels.Timestamp,
+		})
+	}
+	return records, nil
+}
+
+// isKept returns whether the record stored under the given key has any of the labels exempting records from eviction
+func (s *PkarrService) isKept(key string) bool {
+	if s.labels == nil || len(s.cfg.RetentionConfig.KeepLabels) == 0 {
+		return false
+	}
+	s.labels.mu.RLock()
+	defer s.labels.mu.RUnlock()
+	return slices.ContainsFunc(s.labels.byKey[key], func(label string) bool {
+		return slices.Contains(s.cfg.RetentionConfig.KeepLabels, label)
+	})
+}
+
+// normalizeLabels returns the labels sorted and without duplicates, or ErrInvalidLabel if any is invalid
+func normalizeLabels(recordLabels []string) ([]string, error) {
+	for _, label := range recordLabels {
+		if !labelPattern.MatchString(label) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidLabel, label)
+		}
+	}
+	normalized := slices.Clone(recordLabels)
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) > maxLabels {
+		return nil, fmt.Errorf("%w: more than %d labels", ErrInvalidLabel, maxLabels)
+	}
+	return normalized, nil
+}
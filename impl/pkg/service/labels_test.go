@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestLabels(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "labels.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	acme := util.Z32Encode(make([]byte, 32))
+	other := util.Z32Encode(append(make([]byte, 31), 1))
+
+	require.NoError(t, svc.LabelRecord(ctx, acme, nil, []string{"pinned", "customer:acme", "pinned"}))
+	require.NoError(t, svc.LabelRecord(ctx, other, []byte("salt"), []string{"customer:other"}))
+
+	labels, err := svc.GetRecordLabels(acme, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"customer:acme", "pinned"}, labels)
+	labels, err = svc.GetRecordLabels(other, nil)
+	require.NoError(t, err)
+	assert.Empty(t, labels)
+
+	records, err := svc.ListLabeledRecords(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	records, err = svc.ListLabeledRecords(ctx, "customer:other")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, other, records[0].ID)
+	assert.Equal(t, "c2FsdA", records[0].Salt)
+	assert.Equal(t, []string{"customer:other"}, records[0].Labels)
+
+	t.Run("invalid labels", func(t *testing.T) {
+		for _, label := range []string{"", " pinned", "-pinned", "customer acme", strings.Repeat("a", 65)} {
+			assert.ErrorIs(t, svc.LabelRecord(ctx, acme, nil, []string{label}), ErrInvalidLabel, label)
+		}
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		require.NoError(t, svc.LabelRecord(ctx, acme, nil, nil))
+		labels, err := svc.GetRecordLabels(acme, nil)
+		require.NoError(t, err)
+		assert.Empty(t, labels)
+		records, err := svc.ListLabeledRecords(ctx, "pinned")
+		require.NoError(t, err)
+		assert.Empty(t, records)
+	})
+
+	t.Run("loaded on startup", func(t *testing.T) {
+		restarted, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+		require.NoError(t, err)
+		labels, err := restarted.GetRecordLabels(other, []byte("salt"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"customer:other"}, labels)
+	})
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+// LintPkarr returns the lint warnings of the DID Document of a published record's v for the given z-base-32 encoded
+// ID, or nil if linting publishes is disabled or the record isn't a DID Document
+func (s *PkarrService) LintPkarr(id string, v []byte) []did.LintWarning {
+	if !s.cfg.PkarrConfig.LintPublishes {
+		return nil
+	}
+	return lintPkarr(id, v)
+}
+
+// ValidatePkarr validates the request like a published record, without storing or publishing it, and returns the
+// lint warnings of its DID Document, which don't make it invalid. Records which aren't DID Documents have none.
+func (s *PkarrService) ValidatePkarr(id string, request PublishPkarrRequest) ([]did.LintWarning, error) {
+	if err := request.isValid(); err != nil {
+		return nil, err
+	}
+	if err := request.matchesID(id); err != nil {
+		return nil, err
+	}
+	if err := s.checkSeq(request.Seq); err != nil {
+		return nil, err
+	}
+	warnings := lintPkarr(id, request.V)
+	if warnings == nil {
+		warnings = []did.LintWarning{}
+	}
+	return warnings, nil
+}
+
+func lintPkarr(id string, v []byte) []did.LintWarning {
+	warnings, err := did.DHT(did.Prefix + ":" + id).LintDNSPacket(v)
+	if err != nil {
+		logrus.WithError(err).Debugf("record[%s] is not a did document, not linting it", id)
+		return nil
+	}
+	return warnings
+}
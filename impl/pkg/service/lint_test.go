@@ -0,0 +1,65 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestValidatePkarr(t *testing.T) {
+	privKey, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{})
+	require.NoError(t, err)
+	unused := doc.VerificationMethod[0]
+	unused.ID = doc.ID + "#unused"
+	doc.VerificationMethod = append(doc.VerificationMethod, unused)
+	msg, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+	require.NoError(t, err)
+	msg.Compress = true
+	v, err := msg.Pack()
+	require.NoError(t, err)
+	id, err := did.DHT(doc.ID).Suffix()
+	require.NoError(t, err)
+	pubKey := privKey.Public().(ed25519.PublicKey)
+	sign := func(v []byte) PublishPkarrRequest {
+		put := bep44.Put{V: v, K: (*[32]byte)(pubKey), Seq: time.Now().Unix()}
+		put.Sign(privKey)
+		return PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+	}
+
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "lint.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+	require.NoError(t, err)
+
+	warnings, err := svc.ValidatePkarr(id, sign(v))
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, did.LintMissingKeyPurpose, warnings[0].Code)
+	assert.Equal(t, unused.ID, warnings[0].Path)
+	assert.Equal(t, warnings, svc.LintPkarr(id, v))
+
+	warnings, err = svc.ValidatePkarr(id, sign([]byte("not a dns packet")))
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	forged := sign(v)
+	forged.Seq++
+	_, err = svc.ValidatePkarr(id, forged)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	svc.cfg.PkarrConfig.LintPublishes = false
+	assert.Nil(t, svc.LintPkarr(id, v))
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// ErrMaintenanceUnsupported is returned for maintaining storage which needs no maintenance
+var ErrMaintenanceUnsupported = errors.New("storage does not support maintenance")
+
+// MaintenanceStats counts the storage maintenance run since the gateway started
+type MaintenanceStats struct {
+	Runs     int64 `json:"runs"`
+	Failures int64 `json:"failures"`
+	// BytesReclaimed is the total size the database file shrank by when compacted, and RowsDeleted the total rows
+	// left behind by deleted records which were deleted
+	BytesReclaimed int64 `json:"bytesReclaimed"`
+	RowsDeleted    int64 `json:"rowsDeleted"`
+	// Last is the outcome of the last maintenance which succeeded, if any
+	Last *pkarr.Maintenance `json:"last,omitempty"`
+	// LastError is the error of the last maintenance, if it failed
+	LastError string `json:"lastError,omitempty"`
+}
+
+// maintenance serializes the maintenance of storage, counting its runs
+type maintenance struct {
+	mu    sync.Mutex
+	stats MaintenanceStats
+}
+
+// MaintainStorage reclaims the space left behind by deleted records now: bolt is compacted into a fresh file, pausing
+// reads and writes while it is, and postgres has the rows of deleted records deleted in batches
+func (s *PkarrService) MaintainStorage(ctx context.Context) (*pkarr.Maintenance, error) {
+	maintainer, ok := storage.As[storage.Maintainer](s.db)
+	if !ok {
+		return nil, ErrMaintenanceUnsupported
+	}
+	s.maintenance.mu.Lock()
+	defer s.maintenance.mu.Unlock()
+
+	stats := &s.maintenance.stats
+	stats.Runs++
+	m, err := maintainer.Maintain(ctx)
+	if err != nil {
+		stats.Failures++
+		stats.LastError = err.Error()
+		return nil, err
+	}
+	stats.Last, stats.LastError = &m, ""
+	stats.BytesReclaimed += max(m.BytesBefore-m.BytesAfter, 0)
+	stats.RowsDeleted += m.Deleted
+	logrus.WithFields(logrus.Fields{
+		"bytesBefore": m.BytesBefore,
+		"bytesAfter":  m.BytesAfter,
+		"deleted":     m.Deleted,
+		"seconds":     m.Finished - m.Started,
+	}).Info("maintained storage")
+	return &m, nil
+}
+
+// GetMaintenanceStats returns the counts of the storage maintenance run since the gateway started
+func (s *PkarrService) GetMaintenanceStats() (*MaintenanceStats, error) {
+	if _, ok := storage.As[storage.Maintainer](s.db); !ok {
+		return nil, ErrMaintenanceUnsupported
+	}
+	s.maintenance.mu.Lock()
+	defer s.maintenance.mu.Unlock()
+	stats := s.maintenance.stats
+	return &stats, nil
+}
+
+// maintainStorage runs scheduled maintenance of storage
+func (s *PkarrService) maintainStorage() {
+	if _, err := s.MaintainStorage(context.Background()); err != nil {
+		logrus.WithError(err).Error("failed to maintain storage")
+	}
+}
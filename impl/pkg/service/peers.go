@@ -0,0 +1,51 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+)
+
+var errPeerReputationUnsupported = errors.New("dht does not keep the reputation of peers")
+
+// GetPeerReputation returns the outcomes of DHT queries and the bans of misbehaving peers since startup
+func (s *PkarrService) GetPeerReputation() (*dht.ReputationStats, error) {
+	if s.reputation == nil {
+		return nil, errPeerReputationUnsupported
+	}
+	stats := s.reputation.Stats()
+	return &stats, nil
+}
+
+// BanPeer bans the DHT peers at the given IP address for the given duration, the configured ban duration if zero
+func (s *PkarrService) BanPeer(ip net.IP, duration time.Duration) error {
+	if s.reputation == nil {
+		return errPeerReputationUnsupported
+	}
+	s.reputation.Ban(ip, duration)
+	logrus.WithFields(logrus.Fields{
+		"audit":    "peers",
+		"action":   "ban",
+		"addr":     ip.String(),
+		"duration": duration.String(),
+	}).Info("dht peer banned")
+	return nil
+}
+
+// UnbanPeer lifts the ban of the DHT peers at the given IP address
+func (s *PkarrService) UnbanPeer(ip net.IP) error {
+	if s.reputation == nil {
+		return errPeerReputationUnsupported
+	}
+	s.reputation.Unban(ip)
+	logrus.WithFields(logrus.Fields{
+		"audit":  "peers",
+		"action": "unban",
+		"addr":   ip.String(),
+	}).Info("dht peer unbanned")
+	return nil
+}
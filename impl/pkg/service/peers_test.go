@@ -0,0 +1,56 @@
+package service
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht/testnet"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestPeerBans(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "peers.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("dht without reputation", func(t *testing.T) {
+		svc, err := NewPkarrServiceWith(&cfg, db, staticDHT{}, cache.None{})
+		require.NoError(t, err)
+		_, err = svc.GetPeerReputation()
+		assert.ErrorIs(t, err, errPeerReputationUnsupported)
+		assert.ErrorIs(t, svc.BanPeer(net.ParseIP("10.0.0.1"), 0), errPeerReputationUnsupported)
+	})
+
+	t.Run("ban and unban", func(t *testing.T) {
+		network := testnet.New(t, 1)
+		svc, err := NewPkarrServiceWith(&cfg, db, network.Node(0), cache.None{})
+		require.NoError(t, err)
+
+		// the testnet only allows loopback addresses, of which its nodes use another
+		ip := net.ParseIP("127.0.0.2")
+		require.NoError(t, svc.BanPeer(ip, time.Hour))
+		stats, err := svc.GetPeerReputation()
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.Banned)
+		require.Len(t, stats.Peers, 1)
+		assert.Equal(t, "127.0.0.2", stats.Peers[0].Addr)
+		_, blocked := network.Node(0).IPBlocklist().Lookup(ip)
+		assert.True(t, blocked, "banned peers are blocked by the dht server")
+
+		require.NoError(t, svc.UnbanPeer(ip))
+		stats, err = svc.GetPeerReputation()
+		require.NoError(t, err)
+		assert.Zero(t, stats.Banned)
+		_, blocked = network.Node(0).IPBlocklist().Lookup(ip)
+		assert.False(t, blocked)
+	})
+}
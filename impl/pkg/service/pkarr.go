@@ -1,35 +1,118 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
-	"github.com/goccy/go-json"
-
 	"github.com/TBD54566975/ssi-sdk/util"
-	"github.com/allegro/bigcache/v3"
 	"github.com/anacrolix/dht/v2/bep44"
 	"github.com/anacrolix/torrent/bencode"
 	"github.com/sirupsen/logrus"
 
-	"github.com/TBD54566975/did-dht-method/config"
-	dhtint "github.com/TBD54566975/did-dht-method/internal/dht"
-	"github.com/TBD54566975/did-dht-method/pkg/dht"
-	"github.com/TBD54566975/did-dht-method/pkg/storage"
-	"github.com/TBD54566975/did-dht-method/pkg/storage/pkarr"
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	intutil "github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cdc"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/ipfs"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
 )
 
 const recordSizeLimit = 1000
 
+var (
+	// ErrReadOnly is returned for writes to a gateway running in the resolver role
+	ErrReadOnly = errors.New("gateway is read-only")
+	// ErrInvalidSignature is returned for records whose signature doesn't verify
+	ErrInvalidSignature = errors.New("signature is invalid")
+	// ErrStaleSeq is returned for records older than the one already stored for the same key
+	ErrStaleSeq = errors.New("seq is older than the stored record's")
+	// ErrSeqInFuture is returned for records whose seq is further in the future than the configured tolerance
+	ErrSeqInFuture = errors.New("seq is too far in the future")
+	// ErrPacketTooLarge is returned for records whose v exceeds the BEP44 limit of 1000 bytes
+	ErrPacketTooLarge = fmt.Errorf("v exceeds %d bytes", recordSizeLimit)
+)
+
+// KeyMismatchError is returned for records published under an ID which isn't the z-base-32 encoding of their key
+type KeyMismatchError struct {
+	ID string
+	K  [32]byte
+}
+
+func (e *KeyMismatchError) Error() string {
+	return fmt.Sprintf("id[%s] does not match key[%s]", e.ID, intutil.Z32Encode(e.K[:]))
+}
+
 // PkarrService is the Pkarr service responsible for managing the Pkarr DHT and reading/writing records
 type PkarrService struct {
-	cfg       *config.Config
-	db        storage.Storage
-	dht       *dht.DHT
-	cache     *bigcache.BigCache
-	scheduler *dhtint.Scheduler
+	cfg *config.Config
+	db  storage.Storage
+	dht dht.Client
+	// paced paces the outbound operations of dht, which it wraps
+	paced *dht.Paced
+	cache cache.Cache
+	// lifecycle tracks the scheduled jobs and background goroutines of the service, until closed
+	lifecycle *lifecycle
+	index     storage.DocumentIndex
+	ipfs      *ipfs.Client
+	history   *history
+	// key is the gateway's own signing key
+	key          ed25519.PrivateKey
+	interceptors []PublishInterceptor
+	denylist     *denylist
+	drain        *drain
+	fallback     *fallback
+	waiters      *waiters
+	adopter      *adopter
+	retention    *retention
+	// journal journals the puts of published records to the DHT until they complete, if enabled
+	journal storage.PutJournal
+	// tenants accounts the records published by each tenant against its quotas, if tenants are configured
+	tenants *tenants
+	// versionPruning keeps the version history within its limits, if any are configured
+	versionPruning *versionPruning
+	adaptiveTTL    *adaptiveTTL
+	// recordTTL caches records for the TTLs of their DNS records, if enabled
+	recordTTL *recordTTL
+	// equivocations detects keys signing different records with the same seq
+	equivocations *equivocations
+	// reputation scores the DHT peers queried, if the DHT keeps it
+	reputation *dht.Reputation
+	// crawler imports the records of known identifiers from the DHT
+	crawler *crawler
+	// feed is the change feed of the records first seen or updated, if enabled
+	feed storage.ChangeFeed
+	// changes publishes the changes to stored records to a message broker, if enabled
+	changes *changes
+	// cacheChecks checks the cached records against storage
+	cacheChecks *cacheChecks
+	// reconciliations reconciles the primary and secondary storage, if there is a secondary
+	reconciliations *reconciliations
+	// maintenance reclaims the space left behind by deleted records, if storage supports it
+	maintenance *maintenance
+	// labels are the private labels operators attach to records, if storage supports them
+	labels *labels
+	// sla aggregates the outcomes and latency of resolutions and publishes for SLA reports
+	sla *sla
+	// confirmations tracks when records were last confirmed on the DHT, if republishing skips confirmed records
+	confirmations *confirmations
+	// republishPacing delays and spreads out republish runs, if jitter or smearing is configured
+	republishPacing republishPacing
+	// coldStart reads records missing from storage through from peer gateways after startup, if configured
+	coldStart *coldStart
+	// canary publishes and resolves a synthetic record to verify the publish pipeline end to end, if scheduled
+	canary *canary
+	// seqResets are the emergency seq resets of records, if the role publishes
+	seqResets *seqResets
+	// events is the bus of the service's events, which change data capture and embedders subscribe to
+	events Events
 }
 
 // NewPkarrService returns a new instance of the Pkarr service
@@ -37,42 +120,390 @@ func NewPkarrService(cfg *config.Config, db storage.Storage) (*PkarrService, err
 	if cfg == nil {
 		return nil, util.LoggingNewError("config is required")
 	}
+	if !cfg.ServerConfig.Role.IsValid() {
+		return nil, util.LoggingNewErrorf("unknown role: %s", cfg.ServerConfig.Role)
+	}
 
-	d, err := dht.NewDHT(cfg.DHTConfig.BootstrapPeers)
+	d, err := dht.NewDHTFromConfig(cfg.DHTConfig)
 	if err != nil {
 		return nil, util.LoggingErrorMsg(err, "failed to instantiate dht")
 	}
-
-	// create and start cache and scheduler
-	cacheTTL := time.Duration(cfg.PkarrConfig.CacheTTLSeconds) * time.Second
-	cacheConfig := bigcache.DefaultConfig(cacheTTL)
-	cacheConfig.MaxEntrySize = recordSizeLimit
-	cacheConfig.HardMaxCacheSize = cfg.PkarrConfig.CacheSizeLimitMB
-	cacheConfig.CleanWindow = cacheTTL / 2
-	cache, err := bigcache.New(context.Background(), cacheConfig)
+	recordCache, err := NewRecordCache(cfg)
 	if err != nil {
+		d.Close()
 		return nil, util.LoggingErrorMsg(err, "failed to instantiate cache")
 	}
-	scheduler := dhtint.NewScheduler()
+	service, err := NewPkarrServiceWith(cfg, db, d, recordCache)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	return service, nil
+}
+
+// NewRecordCache returns the record cache described by the config
+func NewRecordCache(cfg *config.Config) (cache.Cache, error) {
+	memory := cache.MemoryConfig{
+		SizeLimitMB:        cfg.PkarrConfig.CacheSizeLimitMB,
+		Shards:             cfg.PkarrConfig.CacheShards,
+		MaxEntrySize:       cfg.PkarrConfig.CacheMaxEntrySize,
+		MaxEntriesInWindow: cfg.PkarrConfig.CacheMaxEntriesInWindow,
+		CleanWindow:        time.Duration(cfg.PkarrConfig.CacheCleanWindowSeconds) * time.Second,
+	}
+	if memory.MaxEntrySize == 0 {
+		memory.MaxEntrySize = recordSizeLimit
+	}
+	return cache.NewCache(cfg.PkarrConfig.CacheURI, cacheTTL(cfg.PkarrConfig), memory)
+}
+
+// NewPkarrServiceWith returns a new instance of the Pkarr service using the given DHT and record cache instead of
+// creating them from the config, such as when the gateway is embedded in another process
+func NewPkarrServiceWith(cfg *config.Config, db storage.Storage, d dht.Client, recordCache cache.Cache) (*PkarrService, error) {
+	if cfg == nil {
+		return nil, util.LoggingNewError("config is required")
+	}
+	if db == nil || d == nil || recordCache == nil {
+		return nil, util.LoggingNewError("storage, dht, and cache are required")
+	}
+	if !cfg.ServerConfig.Role.IsValid() {
+		return nil, util.LoggingNewErrorf("unknown role: %s", cfg.ServerConfig.Role)
+	}
+	if !cfg.PkarrConfig.SeqUnit.IsValid() {
+		return nil, util.LoggingNewErrorf("unknown seq unit: %s", cfg.PkarrConfig.SeqUnit)
+	}
+	keepByKey, err := checkHashKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// create and start scheduler
+	key, err := signingKey(cfg.ServerConfig.SigningKey)
+	if err != nil {
+		return nil, util.LoggingErrorMsg(err, "failed to load signing key")
+	}
+	// requests to other gateways go through the outbound proxy, if one is configured, while the DHT stays direct
+	gatewayClient, err := intutil.NewProxiedHTTPClient(cfg.PkarrConfig.OutboundProxyURL)
+	if err != nil {
+		return nil, util.LoggingErrorMsg(err, "invalid outbound proxy")
+	}
+	dhtCfg := cfg.DHTConfig
+	paced := dht.NewPaced(d,
+		dht.Budget{PerSecond: dhtCfg.MaxPutsPerSecond, Burst: dhtCfg.PutBurst},
+		dht.Budget{PerSecond: dhtCfg.MaxGetsPerSecond, Burst: dhtCfg.GetBurst})
 	service := PkarrService{
-		cfg:       cfg,
-		db:        db,
-		dht:       d,
-		cache:     cache,
-		scheduler: &scheduler,
+		cfg:             cfg,
+		db:              db,
+		dht:             paced,
+		paced:           paced,
+		cache:           recordCache,
+		lifecycle:       new(lifecycle),
+		key:             key,
+		drain:           new(drain),
+		waiters:         newWaiters(),
+		crawler:         &crawler{client: gatewayClient},
+		cacheChecks:     new(cacheChecks),
+		reconciliations: new(reconciliations),
+		maintenance:     new(maintenance),
+		sla:             newSLA(cfg.SLAConfig, cfg.ServerConfig.BaseURL),
+	}
+	if scorer, ok := d.(dht.PeerScorer); ok {
+		service.reputation = scorer.Reputation()
+	}
+	if denylistDB, ok := storage.As[storage.Denylist](db); ok {
+		if service.denylist, err = newDenylist(context.Background(), denylistDB); err != nil {
+			return nil, util.LoggingErrorMsg(err, "failed to load denylist")
+		}
+		service.RegisterPublishInterceptor(PublishInterceptorFunc(service.interceptDenied))
+	}
+	if labelStore, ok := storage.As[storage.LabelStore](db); ok && keepByKey {
+		if service.labels, err = newLabels(context.Background(), labelStore); err != nil {
+			return nil, util.LoggingErrorMsg(err, "failed to load labels")
+		}
+	}
+	if len(cfg.PkarrConfig.AllowedKeys) > 0 {
+		service.RegisterPublishInterceptor(KeyAllowList(cfg.PkarrConfig.AllowedKeys))
+	}
+	if len(cfg.PkarrConfig.DeniedKeys) > 0 {
+		service.RegisterPublishInterceptor(KeyDenyList(cfg.PkarrConfig.DeniedKeys))
+	}
+	if cfg.PkarrConfig.BurstMaxUpdates > 0 {
+		window := time.Duration(cfg.PkarrConfig.BurstWindowSeconds) * time.Second
+		cooldown := time.Duration(cfg.PkarrConfig.BurstCooldownSeconds) * time.Second
+		service.RegisterPublishInterceptor(newBursts(cfg.PkarrConfig.BurstMaxUpdates, window, cooldown))
+	}
+	if policy := cfg.PkarrConfig.ServiceEndpointPolicy; policy != "" && policy != config.EndpointPolicyOff {
+		if !policy.IsValid() {
+			return nil, util.LoggingNewErrorf("unknown service endpoint policy: %s", policy)
+		}
+		service.RegisterPublishInterceptor(ServiceEndpointValidator(policy, cfg.PkarrConfig.ServiceEndpointSchemes))
+	}
+	if cfg.IndexConfig.Enabled {
+		index, ok := storage.As[storage.DocumentIndex](db)
+		if !ok {
+			return nil, util.LoggingNewError("storage does not support indexing documents")
+		}
+		service.index = index
+		if cfg.ServerConfig.Role.Publishes() {
+			service.goBackground(service.reindex)
+		}
+	}
+	// an empty schedule leaves republishing to another instance, such as a dedicated worker deployment
+	if cfg.PkarrConfig.RepublishCRON != "" && cfg.ServerConfig.Role.Publishes() {
+		service.republishPacing = republishPacing{
+			jitter: time.Duration(cfg.PkarrConfig.RepublishJitterSeconds) * time.Second,
+			smear:  time.Duration(cfg.PkarrConfig.RepublishSmearSeconds) * time.Second,
+		}
+		if err = service.schedule(cfg.PkarrConfig.RepublishCRON, service.republish); err != nil {
+			return nil, util.LoggingErrorMsg(err, "failed to start republisher")
+		}
+	} else {
+		logrus.Info("republishing is disabled on this instance")
+	}
+	if skip := cfg.PkarrConfig.RepublishSkipConfirmedSeconds; skip > 0 {
+		service.confirmations = newConfirmations(time.Duration(skip) * time.Second)
+	}
+	if cfg.PkarrConfig.PublishJournal && cfg.ServerConfig.Role.Publishes() {
+		journal, ok := storage.As[storage.PutJournal](db)
+		if !ok {
+			return nil, util.LoggingNewError("storage does not support a publish journal")
+		}
+		service.journal = journal
+		service.goBackground(service.replayJournal)
+	}
+	if len(cfg.TenantConfig.Tenants) > 0 && cfg.ServerConfig.Role.Publishes() {
+		ledger, ok := storage.As[storage.TenantLedger](db)
+		if !ok {
+			return nil, util.LoggingNewError("storage does not support tenants")
+		}
+		if service.tenants, err = newTenants(cfg.TenantConfig, ledger); err != nil {
+			return nil, util.LoggingErrorMsg(err, "invalid tenants")
+		}
+	}
+	if compressed, ok := storage.As[*storage.Compressed](db); ok && compressed.Enabled() && cfg.ServerConfig.Role.Publishes() {
+		service.goBackground(func() { service.compressStored(compressed) })
+	}
+	if encrypted, ok := storage.As[*storage.Encrypted](db); ok && cfg.ServerConfig.Role.Publishes() {
+		service.goBackground(func() { service.reencryptStored(encrypted) })
+	}
+	if cfg.ServerConfig.Announce && cfg.ServerConfig.Role.Publishes() {
+		if cfg.ServerConfig.SigningKey == "" {
+			return nil, util.LoggingNewError("announcing the gateway requires a signing key")
+		}
+		service.goBackground(service.announceGateway)
+	}
+	if cfg.ServerConfig.Role.Publishes() {
+		service.seqResets = newSeqResets()
+		service.RegisterPublishInterceptor(PublishInterceptorFunc(service.interceptReset))
+	}
+	if cfg.ServerConfig.CanaryCRON != "" && cfg.ServerConfig.Role.Publishes() {
+		service.canary = newCanary()
+		if err = service.schedule(cfg.ServerConfig.CanaryCRON, service.runCanary); err != nil {
+			return nil, util.LoggingErrorMsg(err, "failed to start canary")
+		}
+	}
+	// adopting stores the records the gateway's users resolve, which privacy mode doesn't keep
+	if cfg.PrivacyConfig.Enabled && cfg.PkarrConfig.AdoptOnResolve {
+		return nil, util.LoggingNewError("privacy mode is incompatible with adopting resolved records")
+	}
+	if cfg.PkarrConfig.AdoptOnResolve && cfg.ServerConfig.Role.Publishes() {
+		service.adopter = &adopter{maxRecords: int64(cfg.PkarrConfig.AdoptMaxRecords)}
+		if service.adopter.maxRecords > 0 {
+			counter, ok := storage.As[storage.RecordCounter](db)
+			if !ok {
+				return nil, util.LoggingNewError("storage does not support counting records to limit adoption")
+			}
+			service.adopter.counter = counter
+		}
+	}
+	if !cfg.RetentionConfig.Eviction.IsValid() {
+		return nil, util.LoggingNewErrorf("unknown eviction policy: %s", cfg.RetentionConfig.Eviction)
+	}
+	// lru eviction keeps when each record was last resolved, which privacy mode doesn't
+	if cfg.PrivacyConfig.Enabled && cfg.RetentionConfig.Eviction == config.EvictionLRU {
+		return nil, util.LoggingNewError("privacy mode is incompatible with lru eviction")
+	}
+	if (cfg.RetentionConfig.MaxRecords > 0 || cfg.RetentionConfig.MaxBytes > 0) && cfg.ServerConfig.Role.Publishes() {
+		quota, ok := storage.As[storage.Quota](db)
+		if !ok {
+			return nil, util.LoggingNewError("storage does not support a retention budget")
+		}
+		service.retention = newRetention(cfg.RetentionConfig, quota)
+		if err = service.retention.recount(context.Background()); err != nil {
+			return nil, util.LoggingErrorMsg(err, "failed to count storage usage")
+		}
+	}
+	retentionCfg := cfg.RetentionConfig
+	if (retentionCfg.MaxVersionsPerRecord > 0 || retentionCfg.MaxVersions > 0) && cfg.ServerConfig.Role.Publishes() {
+		pruner, ok := storage.As[storage.VersionPruner](db)
+		if !ok {
+			return nil, util.LoggingNewError("storage does not support pruning record versions")
+		}
+		service.versionPruning = &versionPruning{
+			db:        pruner,
+			perRecord: retentionCfg.MaxVersionsPerRecord,
+			total:     retentionCfg.MaxVersions,
+		}
+		if retentionCfg.PruneVersionsCRON != "" {
+			if err = service.schedule(retentionCfg.PruneVersionsCRON, service.pruneVersions); err != nil {
+				return nil, util.LoggingErrorMsg(err, "failed to start record version pruning")
+			}
+		}
+	}
+	if cfg.PkarrConfig.AdaptiveCacheTTL {
+		service.adaptiveTTL = newAdaptiveTTL(cfg.PkarrConfig)
+	}
+	if cfg.PkarrConfig.RecordCacheTTL {
+		service.recordTTL = newRecordTTL(cfg.PkarrConfig)
+	}
+	var equivocationLog storage.EquivocationLog
+	if evidence, ok := storage.As[storage.EquivocationLog](db); ok && keepByKey {
+		equivocationLog = evidence
+	}
+	if service.equivocations, err = newEquivocations(context.Background(), equivocationLog, cfg.EquivocationConfig.WebhookURL, &service.events); err != nil {
+		return nil, util.LoggingErrorMsg(err, "failed to load equivocations")
+	}
+	pkarrCfg := cfg.PkarrConfig
+	if len(pkarrCfg.FallbackGateways) > 0 || pkarrCfg.FallbackRegistryURL != "" || len(pkarrCfg.FallbackGatewayDIDs) > 0 {
+		timeout := time.Duration(pkarrCfg.FallbackTimeoutSeconds) * time.Second
+		hedge := time.Duration(pkarrCfg.FallbackHedgeMillis) * time.Millisecond
+		service.fallback = newFallback(pkarrCfg.FallbackGateways, timeout, hedge)
+		service.fallback.client = gatewayClient
+		if pkarrCfg.FallbackDiscoveryCRON != "" {
+			if err = service.schedule(pkarrCfg.FallbackDiscoveryCRON, service.discoverGateways); err != nil {
+				return nil, util.LoggingErrorMsg(err, "failed to start fallback gateway discovery")
+			}
+			service.goBackground(service.discoverGateways)
+		}
+	}
+	if len(pkarrCfg.ColdStartGateways) > 0 && pkarrCfg.ColdStartHours > 0 && cfg.ServerConfig.Role.Publishes() {
+		timeout := time.Duration(pkarrCfg.FallbackTimeoutSeconds) * time.Second
+		hedge := time.Duration(pkarrCfg.FallbackHedgeMillis) * time.Millisecond
+		service.coldStart = &coldStart{
+			peers: newFallback(pkarrCfg.ColdStartGateways, timeout, hedge),
+			until: time.Now().Add(time.Duration(pkarrCfg.ColdStartHours) * time.Hour),
+		}
+		service.coldStart.peers.client = gatewayClient
+	}
+	if cfg.FeedConfig.Enabled {
+		feed, ok := storage.As[storage.ChangeFeed](db)
+		if !ok {
+			return nil, util.LoggingNewError("storage does not support a change feed")
+		}
+		service.feed = feed
 	}
-	if err = scheduler.Schedule(cfg.PkarrConfig.RepublishCRON, service.republish); err != nil {
-		return nil, util.LoggingErrorMsg(err, "failed to start republisher")
+	if service.changes, err = newChanges(cfg.CDCConfig); err != nil {
+		return nil, util.LoggingErrorMsg(err, "failed to start change data capture")
+	}
+	service.lifecycle.unsubscribe = append(service.lifecycle.unsubscribe, service.events.change.subscribe(service.queueChange))
+	if cfg.HistoryConfig.Enabled {
+		historyLog, ok := storage.As[storage.HistoryLog](db)
+		if !ok {
+			return nil, util.LoggingNewError("storage does not support a history log")
+		}
+		service.history = &history{db: historyLog}
+		if err = service.schedule(cfg.HistoryConfig.CheckpointCRON, service.checkpointHistory); err != nil {
+			return nil, util.LoggingErrorMsg(err, "failed to start history checkpointer")
+		}
+		service.goBackground(service.checkpointHistory)
+	}
+	_, hasSecondary := storage.As[*storage.Failover](db)
+	if hasSecondary && cfg.ServerConfig.ReconcileCRON != "" && cfg.ServerConfig.Role.Publishes() {
+		if err = service.schedule(cfg.ServerConfig.ReconcileCRON, service.reconcileStorage); err != nil {
+			return nil, util.LoggingErrorMsg(err, "failed to start storage reconciler")
+		}
+	}
+	if _, ok := storage.As[storage.Maintainer](db); ok && cfg.ServerConfig.MaintenanceCRON != "" {
+		if err = service.schedule(cfg.ServerConfig.MaintenanceCRON, service.maintainStorage); err != nil {
+			return nil, util.LoggingErrorMsg(err, "failed to start storage maintenance")
+		}
+	}
+	if _, ok := recordCache.(cache.Inspector); ok && cfg.PkarrConfig.CacheCheckCRON != "" {
+		if err = service.schedule(cfg.PkarrConfig.CacheCheckCRON, service.checkCache); err != nil {
+			return nil, util.LoggingErrorMsg(err, "failed to start cache checker")
+		}
+	}
+	if slaCfg := cfg.SLAConfig; slaCfg.WebhookURL != "" || (slaCfg.SMTPURL != "" && len(slaCfg.EmailTo) > 0) {
+		for period, cron := range map[string]string{SLAPeriodDaily: slaCfg.DailyCRON, SLAPeriodWeekly: slaCfg.WeeklyCRON} {
+			if cron == "" {
+				continue
+			}
+			period := period
+			if err = service.schedule(cron, func() { service.sendSLASummary(period) }); err != nil {
+				return nil, util.LoggingErrorMsgf(err, "failed to start %s sla summaries", period)
+			}
+		}
+	}
+	if cfg.ArchiveConfig.Enabled {
+		service.ipfs = ipfs.NewClient(cfg.ArchiveConfig.IPFSAPIURL)
+		if err = service.schedule(cfg.ArchiveConfig.ArchiveCRON, service.archive); err != nil {
+			return nil, util.LoggingErrorMsg(err, "failed to start archiver")
+		}
 	}
 	return &service, nil
 }
 
+// checkHashKeys checks the config against hashing storage keys, which hides the keys of stored records, and so which
+// DIDs the gateway holds records for. Nothing else may be kept by key or ID alongside it: the features which must be
+// enabled to keep data by key are rejected, and those kept whenever storage supports them, labels and equivocation
+// evidence, aren't kept. It returns whether data may be kept by key.
+func checkHashKeys(cfg *config.Config) (bool, error) {
+	if !cfg.EncryptionConfig.HashKeys {
+		return true, nil
+	}
+	var conflicts []string
+	if cfg.IndexConfig.Enabled {
+		conflicts = append(conflicts, "the document index")
+	}
+	if cfg.HistoryConfig.Enabled {
+		conflicts = append(conflicts, "the history log")
+	}
+	if cfg.FeedConfig.Enabled {
+		conflicts = append(conflicts, "the change feed")
+	}
+	if cfg.PkarrConfig.PublishJournal {
+		conflicts = append(conflicts, "the publish journal")
+	}
+	if len(cfg.TenantConfig.Tenants) > 0 {
+		conflicts = append(conflicts, "tenants")
+	}
+	if len(conflicts) > 0 {
+		return false, util.LoggingNewErrorf("hashing storage keys is incompatible with %s", strings.Join(conflicts, ", "))
+	}
+	logrus.Info("labels and equivocation evidence aren't kept while hashing storage keys")
+	return false, nil
+}
+
+// GetDHTStats returns the utilization of the outbound DHT budgets
+func (s *PkarrService) GetDHTStats() dht.PaceStats {
+	return s.paced.Stats()
+}
+
+// signingKey decodes the base64url encoded ed25519 seed, generating a new key if the seed is empty
+func signingKey(seed string) (ed25519.PrivateKey, error) {
+	if seed == "" {
+		logrus.Warn("no signing key configured, generating a new one; signatures will not verify against it after a restart")
+		_, key, err := ed25519.GenerateKey(nil)
+		return key, err
+	}
+	seedBytes, err := base64.RawURLEncoding.DecodeString(seed)
+	if err != nil {
+		return nil, err
+	}
+	if len(seedBytes) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key must be a %d byte seed", ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seedBytes), nil
+}
+
 // PublishPkarrRequest is the request to publish a Pkarr record
 type PublishPkarrRequest struct {
 	V   []byte   `validate:"required"`
 	K   [32]byte `validate:"required"`
 	Sig [64]byte `validate:"required"`
 	Seq int64    `validate:"required"`
+	// Salt, if set, distinguishes one of multiple mutable records of the same key, according to
+	// https://www.bittorrent.org/beps/bep_0044.html
+	Salt []byte `validate:"max=64"`
 }
 
 // isValid returns an error if the request is invalid; also validates the signature
@@ -80,13 +511,16 @@ func (p PublishPkarrRequest) isValid() error {
 	if err := util.IsValidStruct(p); err != nil {
 		return err
 	}
+	if len(p.V) > recordSizeLimit {
+		return ErrPacketTooLarge
+	}
 	// validate the signature
 	bv, err := bencode.Marshal(p.V)
 	if err != nil {
 		return err
 	}
-	if !bep44.Verify(p.K[:], nil, p.Seq, bv, p.Sig[:]) {
-		return errors.New("signature is invalid")
+	if !bep44.Verify(p.K[:], p.Salt, p.Seq, bv, p.Sig[:]) {
+		return ErrInvalidSignature
 	}
 	return nil
 }
@@ -94,59 +528,272 @@ func (p PublishPkarrRequest) isValid() error {
 func (p PublishPkarrRequest) toRecord() pkarr.Record {
 	encoding := base64.RawURLEncoding
 	return pkarr.Record{
-		V:   encoding.EncodeToString(p.V),
-		K:   encoding.EncodeToString(p.K[:]),
-		Sig: encoding.EncodeToString(p.Sig[:]),
-		Seq: p.Seq,
+		V:    encoding.EncodeToString(p.V),
+		K:    encoding.EncodeToString(p.K[:]),
+		Sig:  encoding.EncodeToString(p.Sig[:]),
+		Seq:  p.Seq,
+		Salt: encoding.EncodeToString(p.Salt),
+	}
+}
+
+// recordKey converts a z-base-32 encoded ID to the base64url encoded key records are stored under
+func recordKey(id string) (string, error) {
+	key, err := intutil.Z32Decode(id)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+// saltedRecordKey converts a z-base-32 encoded ID and optional salt to the key records are stored under
+func saltedRecordKey(id string, salt []byte) (string, error) {
+	key, err := recordKey(id)
+	if err != nil {
+		return "", err
 	}
+	return pkarr.RecordKey(key, base64.RawURLEncoding.EncodeToString(salt)), nil
+}
+
+// cacheKey returns the key the record for the given z-base-32 encoded ID and optional salt is cached under
+func cacheKey(id string, salt []byte) string {
+	if len(salt) == 0 {
+		return id
+	}
+	return id + "." + base64.RawURLEncoding.EncodeToString(salt)
+}
+
+// recordID converts the base64url encoded key a record is stored under to its z-base-32 encoded ID
+func recordID(key string) (string, error) {
+	kBytes, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil {
+		return "", err
+	}
+	return intutil.Z32Encode(kBytes), nil
 }
 
 // PublishPkarr stores the record in the db, publishes the given Pkarr record to the DHT, and returns the z-base-32 encoded ID
 func (s *PkarrService) PublishPkarr(ctx context.Context, id string, request PublishPkarrRequest) error {
+	start := time.Now()
+	err := s.publishPkarr(ctx, id, request)
+	s.sla.observePublish(start, err)
+	return err
+}
+
+func (s *PkarrService) publishPkarr(ctx context.Context, id string, request PublishPkarrRequest) error {
+	if !s.cfg.ServerConfig.Role.Publishes() {
+		return ErrReadOnly
+	}
 	if err := request.isValid(); err != nil {
 		return err
 	}
-
-	// write to db and cache
-	record := request.toRecord()
-	if err := s.db.WriteRecord(ctx, record); err != nil {
+	if err := request.matchesID(id); err != nil {
 		return err
 	}
-	recordBytes, err := json.Marshal(GetPkarrResponse{
-		V:   request.V,
-		Seq: request.Seq,
-		Sig: request.Sig,
-	})
-	if err != nil {
+	if err := s.checkSeq(request.Seq); err != nil {
 		return err
 	}
-
-	if err = s.cache.Set(id, recordBytes); err != nil {
+	if !s.drain.begin() {
+		return ErrDraining
+	}
+	// the put is journaled before the record is stored, so it is replayed on startup if the gateway crashes before it
+	if err := s.journalPut(ctx, id, request.Salt, request.Seq); err != nil {
+		s.drain.done()
+		return err
+	}
+	if err := s.storePkarr(ctx, id, request); err != nil {
+		// there is no record to put
+		s.ackPut(id, request.Salt, request.Seq)
+		s.drain.done()
 		return err
 	}
 
-	// return here and put it in the DHT asynchronously
+	// return here and put it in the DHT asynchronously, outliving the request
 	// TODO(gabe): consider a background process to monitor failures
+	ctx = context.WithoutCancel(ctx)
 	go func() {
-		_, err := s.dht.Put(ctx, bep44.Put{
-			V:   request.V,
-			K:   &request.K,
-			Sig: request.Sig,
-			Seq: request.Seq,
+		defer s.drain.done()
+		result, err := dht.PutReplicated(ctx, s.dht, bep44.Put{
+			V:    request.V,
+			K:    &request.K,
+			Salt: request.Salt,
+			Sig:  request.Sig,
+			Seq:  request.Seq,
 		})
 		if err != nil {
 			logrus.WithError(err).Error("error from dht.Put")
+			return
 		}
+		logReplication(id, result)
+		if result.Replicated() {
+			s.confirmOnDHT(id, request.Salt, request.Seq)
+		}
+		s.ackPut(id, request.Salt, request.Seq)
 	}()
 
 	return nil
 }
 
+// checkSeq returns ErrSeqInFuture if the seq, as a unix timestamp in the configured unit, is further in the future
+// than the configured tolerance
+func (s *PkarrService) checkSeq(seq int64) error {
+	maxFuture := s.cfg.PkarrConfig.MaxFutureSeqSeconds
+	if maxFuture <= 0 {
+		return nil
+	}
+	perSecond := s.cfg.PkarrConfig.SeqUnit.PerSecond()
+	if latest := (time.Now().Unix() + int64(maxFuture)) * perSecond; seq > latest {
+		return fmt.Errorf("%w: %d is later than %d, %ds ahead of the gateway's clock", ErrSeqInFuture, seq, latest,
+			maxFuture)
+	}
+	return nil
+}
+
+// NextSeq returns the seq the next record for the given z-base-32 encoded ID should use, newer than the stored record's
+func (s *PkarrService) NextSeq(ctx context.Context, id string) (int64, error) {
+	key, err := recordKey(id)
+	if err != nil {
+		return 0, err
+	}
+	current, err := s.db.ReadRecord(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	var prev int64
+	if current != nil {
+		prev = current.Seq
+	}
+	return dht.NextSeq(prev), nil
+}
+
+// storePkarr writes the record to the db and cache, once it passes the publish interceptors
+func (s *PkarrService) storePkarr(ctx context.Context, id string, request PublishPkarrRequest) error {
+	if err := s.interceptPublish(ctx, id, request); err != nil {
+		return err
+	}
+
+	// write to db and cache
+	record := request.toRecord()
+	current, err := s.db.ReadRecord(ctx, record.Key())
+	if err != nil {
+		return err
+	}
+	if current != nil && current.Seq > record.Seq {
+		return ErrStaleSeq
+	}
+	s.checkEquivocation(ctx, id, current, record, pkarr.EquivocationSourcePublish)
+	if s.retention != nil {
+		s.retention.mu.Lock()
+		defer s.retention.mu.Unlock()
+		if err = s.admit(ctx, record.Key(), current == nil); err != nil {
+			return err
+		}
+	}
+	witnessed := false
+	if s.history != nil {
+		existing, err := s.db.ReadRecordVersion(ctx, record.Key(), record.Seq)
+		if err != nil {
+			return err
+		}
+		witnessed = existing != nil
+	}
+	// the record is checked against the stored record again as it is written, as a concurrent publish of the same
+	// key may have stored a newer record since it was read
+	current, written, err := storage.WriteRecordIfNewer(ctx, s.db, record)
+	if err != nil {
+		return err
+	}
+	if !written {
+		return ErrStaleSeq
+	}
+	if s.retention != nil {
+		s.retention.stored(current, record)
+	}
+	if s.history != nil && !witnessed {
+		s.appendHistory(ctx, id, request)
+	}
+	changed := current == nil || current.Seq < record.Seq
+	if changed {
+		if s.feed != nil {
+			s.appendFeed(ctx, id, request)
+		}
+		op := cdc.OpUpdate
+		if current == nil {
+			op = cdc.OpCreate
+		}
+		s.emitChange(op, id, &record)
+	}
+	s.markResolved(id, request.Salt)
+	key := cacheKey(id, request.Salt)
+	var prev *cachedRecord
+	if s.adaptiveTTL != nil {
+		if prev, err = s.getCachedRecord(ctx, key); err != nil {
+			logrus.WithError(err).Warnf("failed to get pkarr record[%s] from cache", key)
+		}
+	}
+	resp := GetPkarrResponse{V: request.V, Seq: request.Seq, Sig: request.Sig}
+	if _, err = s.cacheRecord(ctx, key, resp, prev); err != nil {
+		return err
+	}
+	s.waiters.notify(key)
+
+	// salted records are not the DID Document of their key
+	if s.index != nil && len(request.Salt) == 0 {
+		s.indexRecord(ctx, id, request.V)
+	}
+	s.emitPublish(id, request.Salt, record, changed)
+	return nil
+}
+
+// matchesID returns a KeyMismatchError unless the given ID is the z-base-32 encoding of the request's key
+func (p PublishPkarrRequest) matchesID(id string) error {
+	k, err := intutil.Z32Decode(id)
+	if err != nil || !bytes.Equal(k, p.K[:]) {
+		return &KeyMismatchError{ID: id, K: p.K}
+	}
+	return nil
+}
+
 // GetPkarrResponse is the response to a get Pkarr request
 type GetPkarrResponse struct {
 	V   []byte   `validate:"required"`
 	Seq int64    `validate:"required"`
 	Sig [64]byte `validate:"required"`
+	// Metadata describes the resolution of the record from the DHT, if it searched for the highest seq or was cut short
+	Metadata *ResolutionMetadata `json:",omitempty"`
+	// Freshness is how long the record may be served from HTTP caches, if it was resolved as the latest record
+	Freshness *Freshness `json:"-"`
+}
+
+// ResolutionMetadata describes the records of different nodes a record was resolved from
+type ResolutionMetadata struct {
+	// Conflict is whether nodes held records of different seqs, such as while a new record propagates, or if someone
+	// is replaying older records
+	Conflict bool `json:"conflict"`
+	// Seqs are the distinct seqs of the records nodes held, in ascending order
+	Seqs []int64 `json:"seqs"`
+	// Partial is whether the DHT traversal was cut short by its budget or the request's deadline, so the record is the
+	// best found so far, and nodes not yet queried may hold a newer seq
+	Partial bool `json:"partial,omitempty"`
+}
+
+// verify returns ErrInvalidSignature unless the record is signed by the key of the given z-base-32 encoded ID, with
+// the given salt, if any
+func (r GetPkarrResponse) verify(id string, salt []byte) error {
+	k, err := intutil.Z32Decode(id)
+	if err != nil {
+		return err
+	}
+	if len(k) != ed25519.PublicKeySize {
+		return fmt.Errorf("id must be a %d byte key", ed25519.PublicKeySize)
+	}
+	bv, err := bencode.Marshal(r.V)
+	if err != nil {
+		return err
+	}
+	if !bep44.Verify(k, salt, r.Seq, bv, r.Sig[:]) {
+		return ErrInvalidSignature
+	}
+	return nil
 }
 
 func fromPkarrRecord(record pkarr.Record) (*GetPkarrResponse, error) {
@@ -168,36 +815,103 @@ func fromPkarrRecord(record pkarr.Record) (*GetPkarrResponse, error) {
 
 // GetPkarr returns the full Pkarr record (including sig data) for the given z-base-32 encoded ID
 func (s *PkarrService) GetPkarr(ctx context.Context, id string) (*GetPkarrResponse, error) {
+	return s.GetSaltedPkarr(ctx, id, nil)
+}
+
+// GetSaltedPkarr returns the full Pkarr record (including sig data) for the given z-base-32 encoded ID and salt,
+// one of multiple mutable records of the same key. It is GetPkarr if the salt is empty.
+func (s *PkarrService) GetSaltedPkarr(ctx context.Context, id string, salt []byte) (*GetPkarrResponse, error) {
+	start := time.Now()
+	resp, err := s.getSaltedPkarr(ctx, id, salt)
+	s.sla.observeResolution(start, resp, err)
+	return resp, err
+}
+
+func (s *PkarrService) getSaltedPkarr(ctx context.Context, id string, salt []byte) (*GetPkarrResponse, error) {
+	if s.isDenied(id) {
+		logrus.Debugf("refusing to resolve denied pkarr record[%s]", id)
+		return nil, nil
+	}
+
 	// first do a cache lookup
-	if got, err := s.cache.Get(id); err == nil {
-		var resp GetPkarrResponse
-		if err = json.Unmarshal(got, &resp); err != nil {
-			return nil, err
-		}
-		logrus.Debugf("resolved pkarr record[%s] from cache", id)
+	key := cacheKey(id, salt)
+	cached, err := s.getCachedRecord(ctx, key)
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to get pkarr record[%s] from cache", key)
+	} else if cached != nil && ((s.adaptiveTTL == nil && s.recordTTL == nil) || !cached.expired(time.Now())) {
+		logrus.Debugf("resolved pkarr record[%s] from cache", key)
+		s.markResolved(id, salt)
+		resp := cached.GetPkarrResponse
+		resp.Freshness = s.freshness(*cached, time.Now())
+		s.emitResolve(id, salt, &resp, ResolveSourceCache)
 		return &resp, nil
 	}
 
-	// next do a dht lookup
-	got, err := s.dht.GetFull(ctx, id)
+	// next do a dht lookup, only trusting records signed by the requested key
+	resp, err := s.getFromDHT(ctx, id, salt)
 	if err != nil {
 		// try to resolve from storage before returning and error
-		logrus.WithError(err).Warnf("failed to get pkarr record[%s] from dht, attempting to resolve from storage", id)
-		record, err := s.db.ReadRecord(ctx, id)
+		logrus.WithError(err).Warnf("failed to get pkarr record[%s] from dht, attempting to resolve from storage", key)
+		storageKey, err := saltedRecordKey(id, salt)
+		if err != nil {
+			logrus.WithError(err).Debugf("pkarr record[%s] has an invalid id", key)
+			return nil, nil
+		}
+		record, err := s.db.ReadRecord(ctx, storageKey)
+		if err == nil && record == nil && len(salt) == 0 {
+			if resp := s.readThrough(ctx, id); resp != nil {
+				s.emitResolve(id, salt, resp, ResolveSourcePeer)
+				return resp, nil
+			}
+		}
+		if err == nil && record == nil && s.fallback != nil && len(salt) == 0 {
+			resp := s.resolveFromFallback(ctx, id)
+			s.emitResolve(id, salt, resp, ResolveSourceFallback)
+			return resp, nil
+		}
 		if err != nil || record == nil {
-			logrus.WithError(err).Errorf("failed to resolve pkarr record[%s] from storage", id)
+			logrus.WithError(err).Errorf("failed to resolve pkarr record[%s] from storage", key)
 			return nil, err
 		}
-		logrus.Debugf("resolved pkarr record[%s] from storage", id)
-		resp, err := fromPkarrRecord(*record)
+		logrus.Debugf("resolved pkarr record[%s] from storage", key)
+		s.markResolved(id, salt)
+		resp, err = fromPkarrRecord(*record)
 		if err == nil {
-			if err = s.addRecordToCache(id, *resp); err != nil {
-				logrus.WithError(err).Errorf("failed to set pkarr record[%s] in cache", id)
+			entry, err := s.cacheRecord(ctx, key, *resp, cached)
+			if err != nil {
+				logrus.WithError(err).Errorf("failed to set pkarr record[%s] in cache", key)
 			}
+			resp.Freshness = s.freshness(entry, time.Now())
+			s.emitResolve(id, salt, resp, ResolveSourceStorage)
 		}
 		return resp, err
 	}
 
+	// add the record to cache, do it here to avoid duplicate calculations. Partial records may be stale, so they
+	// aren't cached, and are resolved again on the next request.
+	if resp.Metadata == nil || !resp.Metadata.Partial {
+		entry, err := s.cacheRecord(ctx, key, *resp, cached)
+		if err != nil {
+			logrus.WithError(err).Errorf("failed to set pkarr record[%s] in cache", key)
+		}
+		resp.Freshness = s.freshness(entry, time.Now())
+	}
+	s.checkResolvedEquivocation(ctx, id, salt, *resp)
+	s.adopt(ctx, id, salt, *resp)
+	s.markResolved(id, salt)
+	s.emitResolve(id, salt, resp, ResolveSourceDHT)
+
+	return resp, nil
+}
+
+// getFromDHT gets the record for the given z-base-32 encoded ID and optional salt from the DHT, returning
+// ErrInvalidSignature if the record returned isn't signed by the key of the ID, e.g. if a node spoofed or corrupted it
+func (s *PkarrService) getFromDHT(ctx context.Context, id string, salt []byte) (*GetPkarrResponse, error) {
+	got, err := s.dht.GetFull(ctx, id, salt)
+	if err != nil {
+		return nil, err
+	}
+
 	// prepare the record for return
 	bBytes, err := got.V.MarshalBencode()
 	if err != nil {
@@ -212,28 +926,62 @@ func (s *PkarrService) GetPkarr(ctx context.Context, id string) (*GetPkarrRespon
 		Seq: got.Seq,
 		Sig: got.Sig,
 	}
-
-	// add the record to cache, do it here to avoid duplicate calculations
-	if err = s.addRecordToCache(id, resp); err != nil {
-		logrus.WithError(err).Errorf("failed to set pkarr record[%s] in cache", id)
+	if err = resp.verify(id, salt); err != nil {
+		return nil, err
+	}
+	// a reset record lingers on the DHT until it expires there, while the owner's new records are resolved from storage
+	if s.seqSuppressed(id, salt, resp.Seq) {
+		return nil, errSeqSuppressed
+	}
+	if len(got.Seqs) > 0 || got.Partial {
+		resp.Metadata = &ResolutionMetadata{Conflict: len(got.Seqs) > 1, Seqs: got.Seqs, Partial: got.Partial}
+		if resp.Metadata.Conflict {
+			logrus.Infof("resolved pkarr record[%s] with conflicting seqs %v from dht", id, got.Seqs)
+		}
+	}
+	// privacy mode keeps no per-DID resolution data, so only puts confirm records
+	if (resp.Metadata == nil || !(resp.Metadata.Conflict || resp.Metadata.Partial)) && !s.cfg.PrivacyConfig.Enabled {
+		s.confirmOnDHT(id, salt, resp.Seq)
 	}
-
 	return &resp, nil
 }
 
-func (s *PkarrService) addRecordToCache(id string, resp GetPkarrResponse) error {
-	recordBytes, err := json.Marshal(resp)
-	if err != nil {
-		return err
+// resolveFromFallback resolves the record from the fallback gateways, caching it if found
+func (s *PkarrService) resolveFromFallback(ctx context.Context, id string) *GetPkarrResponse {
+	resp := s.fallback.resolve(ctx, id)
+	if resp == nil {
+		logrus.Debugf("pkarr record[%s] not found on any fallback gateway", id)
+		return nil
 	}
-	if err = s.cache.Set(id, recordBytes); err != nil {
-		return err
+	if s.seqSuppressed(id, nil, resp.Seq) {
+		logrus.Debugf("pkarr record[%s] from fallback gateways has a reset seq", id)
+		return nil
 	}
-	return nil
+	s.adopt(ctx, id, nil, *resp)
+	if _, err := s.cacheRecord(ctx, id, *resp, nil); err != nil {
+		logrus.WithError(err).Errorf("failed to set pkarr record[%s] in cache", id)
+	}
+	return resp
 }
 
 // TODO(gabe) make this more efficient. create a publish schedule based on each individual record, not all records
 func (s *PkarrService) republish() {
+	if !s.drain.begin() {
+		logrus.Info("Skipping republishing while draining")
+		return
+	}
+	defer s.drain.done()
+
+	// a random delay keeps gateways on the same schedule from starting their runs at once
+	if delay := s.republishPacing.delay(); delay > 0 {
+		logrus.Infof("Delaying republishing by %s", delay.Round(time.Second))
+		if !s.drain.wait(time.Now().Add(delay)) {
+			logrus.Info("Skipping republishing while draining")
+			return
+		}
+	}
+
+	started := time.Now()
 	allRecords, err := s.db.ListRecords(context.Background())
 	if err != nil {
 		logrus.WithError(err).Error("failed to list record(s) for republishing")
@@ -241,24 +989,71 @@ func (s *PkarrService) republish() {
 	}
 	if len(allRecords) == 0 {
 		logrus.Info("No records to republish")
+		s.events.republish.emit(RepublishCompleteEvent{Started: started, Finished: time.Now()})
 		return
 	}
 	logrus.Infof("Republishing [%d] record(s)", len(allRecords))
-	errCnt := 0
-	for _, record := range allRecords {
+	s.confirmations.prune(started)
+	errCnt, underReplicated, skipped, denied, stopped := 0, 0, 0, 0, 0
+	s.republishPacing.order(len(allRecords), func(i, j int) { allRecords[i], allRecords[j] = allRecords[j], allRecords[i] })
+	for i, record := range allRecords {
+		// the puts are spread across the smearing window, and the rest of a run being smeared is left to the next once
+		// draining, rather than holding up termination until the window ends
+		if s.republishPacing.smear > 0 && !s.drain.wait(s.republishPacing.due(started, i, len(allRecords))) {
+			stopped = len(allRecords) - i
+			logrus.Infof("Stopping republishing while draining, %d record(s) left", stopped)
+			break
+		}
+		if id, err := recordID(record.K); err == nil && s.isDenied(id) {
+			logrus.Debugf("skipping republishing denied record[%s]", id)
+			denied++
+			continue
+		}
+		// records unchanged since they were recently confirmed on the DHT don't need putting again yet
+		key := pkarr.RecordKey(record.K, record.Salt)
+		if s.confirmations.recent(key, record.Seq, time.Now()) {
+			skipped++
+			continue
+		}
 		put, err := recordToBEP44Put(record)
 		if err != nil {
 			logrus.WithError(err).Error("failed to convert record to bep44 put")
 			errCnt++
 			continue
 		}
-		if _, err = s.dht.Put(context.Background(), *put); err != nil {
+		result, err := dht.PutReplicated(context.Background(), s.dht, *put)
+		if err != nil {
 			logrus.WithError(err).Error("failed to republish record")
 			errCnt++
 			continue
 		}
+		if !result.Replicated() {
+			underReplicated++
+			continue
+		}
+		s.confirmations.confirm(key, record.Seq, time.Now())
 	}
-	logrus.Infof("Republishing complete. Successfully republished %d out of %d record(s)", len(allRecords)-errCnt, len(allRecords))
+	republished := len(allRecords) - errCnt - skipped - denied - stopped
+	logrus.Infof("Republishing complete. Successfully republished %d out of %d record(s), %d below the replication factor, %d skipped as recently confirmed, %d skipped as denied", republished, len(allRecords), underReplicated, skipped, denied)
+	s.events.republish.emit(RepublishCompleteEvent{
+		Records:         len(allRecords),
+		Republished:     republished,
+		Failed:          errCnt,
+		UnderReplicated: underReplicated,
+		Skipped:         skipped,
+		Denied:          denied,
+		Started:         started,
+		Finished:        time.Now(),
+	})
+}
+
+// logReplication warns if fewer nodes stored a published record than targeted
+func logReplication(id string, result *dht.PutResult) {
+	if result.Replicated() {
+		logrus.Debugf("published pkarr record[%s] to %d node(s)", id, result.Stored)
+		return
+	}
+	logrus.Warnf("pkarr record[%s] stored by %d of %d targeted node(s) after putting to %d", id, result.Stored, result.Replication, result.Attempted)
 }
 
 func recordToBEP44Put(record pkarr.Record) (*bep44.Put, error) {
@@ -275,10 +1070,15 @@ func recordToBEP44Put(record pkarr.Record) (*bep44.Put, error) {
 	if err != nil {
 		return nil, err
 	}
+	saltBytes, err := encoding.DecodeString(record.Salt)
+	if err != nil {
+		return nil, err
+	}
 	return &bep44.Put{
-		V:   vBytes,
-		K:   (*[32]byte)(kBytes),
-		Sig: [64]byte(sigBytes),
-		Seq: record.Seq,
+		V:    vBytes,
+		K:    (*[32]byte)(kBytes),
+		Salt: saltBytes,
+		Sig:  [64]byte(sigBytes),
+		Seq:  record.Seq,
 	}, nil
 }
@@ -2,15 +2,23 @@ package service
 
 import (
 	"context"
+	"crypto/ed25519"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/torrent/bencode"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/TBD54566975/did-dht-method/config"
-	"github.com/TBD54566975/did-dht-method/internal/did"
-	"github.com/TBD54566975/did-dht-method/pkg/dht"
-	"github.com/TBD54566975/did-dht-method/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht/testnet"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
 )
 
 func TestPKARRService(t *testing.T) {
@@ -62,8 +70,75 @@ func TestPKARRService(t *testing.T) {
 			Sig: putMsg.Sig,
 			Seq: putMsg.Seq,
 		})
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "signature is invalid")
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("test record with a stale seq", func(t *testing.T) {
+		sk, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{})
+		require.NoError(t, err)
+
+		d := did.DHT(doc.ID)
+		packet, err := d.ToDNSPacket(*doc, nil)
+		require.NoError(t, err)
+
+		putMsg, err := dht.CreatePKARRPublishRequest(sk, *packet)
+		require.NoError(t, err)
+
+		suffix, err := d.Suffix()
+		require.NoError(t, err)
+		err = svc.PublishPkarr(context.Background(), suffix, PublishPkarrRequest{
+			V:   putMsg.V.([]byte),
+			K:   *putMsg.K,
+			Sig: putMsg.Sig,
+			Seq: putMsg.Seq,
+		})
+		assert.NoError(t, err)
+
+		// re-sign the record with an older seq
+		putMsg.Seq--
+		putMsg.Sign(sk)
+		err = svc.PublishPkarr(context.Background(), suffix, PublishPkarrRequest{
+			V:   putMsg.V.([]byte),
+			K:   *putMsg.K,
+			Sig: putMsg.Sig,
+			Seq: putMsg.Seq,
+		})
+		assert.ErrorIs(t, err, ErrStaleSeq)
+	})
+
+	t.Run("test record published under another id", func(t *testing.T) {
+		sk, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{})
+		require.NoError(t, err)
+
+		packet, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+		require.NoError(t, err)
+
+		putMsg, err := dht.CreatePKARRPublishRequest(sk, *packet)
+		require.NoError(t, err)
+
+		otherPubKey, _, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		for _, id := range []string{util.Z32Encode(otherPubKey), "not-z32", ""} {
+			err = svc.PublishPkarr(context.Background(), id, PublishPkarrRequest{
+				V:   putMsg.V.([]byte),
+				K:   *putMsg.K,
+				Sig: putMsg.Sig,
+				Seq: putMsg.Seq,
+			})
+			var mismatch *KeyMismatchError
+			require.ErrorAs(t, err, &mismatch)
+			assert.Equal(t, id, mismatch.ID)
+		}
+	})
+
+	t.Run("test record too large", func(t *testing.T) {
+		err := svc.PublishPkarr(context.Background(), "", PublishPkarrRequest{
+			V:   make([]byte, 1001),
+			K:   [32]byte{1},
+			Sig: [64]byte{1},
+			Seq: 1,
+		})
+		assert.ErrorIs(t, err, ErrPacketTooLarge)
 	})
 
 	t.Run("test put and get record", func(t *testing.T) {
@@ -98,9 +173,235 @@ func TestPKARRService(t *testing.T) {
 		assert.Equal(t, putMsg.Sig, got.Sig)
 		assert.Equal(t, putMsg.Seq, got.Seq)
 	})
+
+	t.Run("test put and get salted record", func(t *testing.T) {
+		sk, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{})
+		require.NoError(t, err)
+
+		d := did.DHT(doc.ID)
+		packet, err := d.ToDNSPacket(*doc, nil)
+		require.NoError(t, err)
+
+		putMsg, err := dht.CreatePKARRPublishRequest(sk, *packet)
+		require.NoError(t, err)
+		putMsg.Salt = []byte("salt")
+		putMsg.Sign(sk)
+
+		suffix, err := d.Suffix()
+		require.NoError(t, err)
+		request := PublishPkarrRequest{
+			V:    putMsg.V.([]byte),
+			K:    *putMsg.K,
+			Sig:  putMsg.Sig,
+			Seq:  putMsg.Seq,
+			Salt: putMsg.Salt,
+		}
+		err = svc.PublishPkarr(context.Background(), suffix, request)
+		assert.NoError(t, err)
+
+		got, err := svc.GetSaltedPkarr(context.Background(), suffix, putMsg.Salt)
+		assert.NoError(t, err)
+		require.NotEmpty(t, got)
+		assert.Equal(t, putMsg.Sig, got.Sig)
+
+		// the signature covers the salt
+		request.Salt = []byte("other salt")
+		err = svc.PublishPkarr(context.Background(), suffix, request)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+}
+
+func TestPKARRServiceResolverRole(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.ServerConfig.Role = config.RoleResolver
+	svc := PkarrService{cfg: &cfg}
+
+	err := svc.PublishPkarr(context.Background(), "", PublishPkarrRequest{})
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	err = svc.DenyKey(context.Background(), "", "abuse")
+	assert.ErrorIs(t, err, ErrReadOnly)
+}
+
+func TestPKARRServiceSeqInFuture(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	svc := PkarrService{cfg: &cfg}
+
+	future := time.Now().Add(time.Hour).Unix()
+	assert.NoError(t, svc.checkSeq(future))
+
+	cfg.PkarrConfig.MaxFutureSeqSeconds = 300
+	assert.NoError(t, svc.checkSeq(time.Now().Unix()+60))
+	assert.ErrorIs(t, svc.checkSeq(future), ErrSeqInFuture)
+
+	// pkarr clients use timestamps in microseconds as seqs
+	cfg.PkarrConfig.SeqUnit = config.SeqUnitMicroseconds
+	assert.NoError(t, svc.checkSeq(time.Now().UnixMicro()))
+	assert.NoError(t, svc.checkSeq(time.Now().Add(time.Minute).UnixMicro()))
+	assert.ErrorIs(t, svc.checkSeq(time.Now().Add(time.Hour).UnixMicro()), ErrSeqInFuture)
+	// a timestamp in seconds is far in the past in microseconds
+	assert.NoError(t, svc.checkSeq(future))
+
+	t.Run("unknown seq units are rejected", func(t *testing.T) {
+		cfg := config.GetDefaultConfig()
+		cfg.PkarrConfig.SeqUnit = "minutes"
+		db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "sequnit.db"))
+		require.NoError(t, err)
+		defer db.Close()
+		_, err = NewPkarrServiceWith(&cfg, db, staticDHT{}, cache.None{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown seq unit")
+	})
+}
+
+func TestPKARRServiceHashKeys(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.EncryptionConfig.HashKeys = true
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "hashkeys.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	svc, err := NewPkarrServiceWith(&cfg, db, staticDHT{}, cache.None{})
+	require.NoError(t, err)
+	assert.Nil(t, svc.labels, "labels aren't kept by key")
+	assert.Nil(t, svc.equivocations.db, "equivocation evidence isn't kept by key")
+
+	conflicting := cfg
+	conflicting.IndexConfig.Enabled = true
+	conflicting.PkarrConfig.PublishJournal = true
+	conflicting.TenantConfig.Tenants = []config.Tenant{{Name: "acme", APIKey: "acme-key"}}
+	_, err = NewPkarrServiceWith(&conflicting, db, staticDHT{}, cache.None{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the document index, the publish journal, tenants")
+}
+
+// staticDHT returns the same record for every key
+type staticDHT struct {
+	result dht.FullGetResult
+}
+
+func (d staticDHT) Put(context.Context, bep44.Put) (string, error) {
+	return "", nil
+}
+
+func (d staticDHT) GetFull(context.Context, string, []byte) (*dht.FullGetResult, error) {
+	return &d.result, nil
+}
+
+func TestPKARRServiceVerifiesDHTRecords(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+
+	put := bep44.Put{V: []byte("hello pkarr"), K: (*[32]byte)(pubKey), Seq: 1}
+	put.Sign(privKey)
+	v, err := bencode.Marshal(put.V)
+	require.NoError(t, err)
+
+	cfg := config.GetDefaultConfig()
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "verify.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	recordCache, err := NewRecordCache(&cfg)
+	require.NoError(t, err)
+
+	t.Run("valid record", func(t *testing.T) {
+		svc := PkarrService{
+			cfg:   &cfg,
+			db:    db,
+			dht:   staticDHT{result: dht.FullGetResult{Seq: put.Seq, V: v, Sig: put.Sig, Mutable: true}},
+			cache: recordCache,
+		}
+		got, err := svc.GetPkarr(context.Background(), id)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, put.V, got.V)
+	})
+
+	t.Run("spoofed record", func(t *testing.T) {
+		_, otherKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		spoofed := bep44.Put{V: []byte("spoofed"), K: put.K, Seq: 2}
+		spoofed.Sign(otherKey)
+		spoofedV, err := bencode.Marshal(spoofed.V)
+		require.NoError(t, err)
+
+		svc := PkarrService{
+			cfg:   &cfg,
+			db:    db,
+			dht:   staticDHT{result: dht.FullGetResult{Seq: spoofed.Seq, V: spoofedV, Sig: spoofed.Sig, Mutable: true}},
+			cache: cache.None{},
+		}
+		got, err := svc.GetPkarr(context.Background(), id)
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestPKARRServiceResolutionMetadata(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	put := bep44.Put{V: []byte("hello pkarr"), K: (*[32]byte)(pubKey), Seq: 2}
+	put.Sign(privKey)
+	v, err := bencode.Marshal(put.V)
+	require.NoError(t, err)
+
+	cfg := config.GetDefaultConfig()
+	svc := PkarrService{cfg: &cfg, cache: cache.None{}}
+
+	svc.dht = staticDHT{result: dht.FullGetResult{Seq: put.Seq, V: v, Sig: put.Sig}}
+	got, err := svc.getFromDHT(context.Background(), id, nil)
+	require.NoError(t, err)
+	assert.Nil(t, got.Metadata, "gets which don't search for the highest seq have no metadata")
+
+	svc.dht = staticDHT{result: dht.FullGetResult{Seq: put.Seq, V: v, Sig: put.Sig, Seqs: []int64{2}}}
+	got, err = svc.getFromDHT(context.Background(), id, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &ResolutionMetadata{Seqs: []int64{2}}, got.Metadata)
+
+	svc.dht = staticDHT{result: dht.FullGetResult{Seq: put.Seq, V: v, Sig: put.Sig, Seqs: []int64{1, 2}}}
+	got, err = svc.getFromDHT(context.Background(), id, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &ResolutionMetadata{Conflict: true, Seqs: []int64{1, 2}}, got.Metadata)
+
+	svc.dht = staticDHT{result: dht.FullGetResult{Seq: put.Seq, V: v, Sig: put.Sig, Partial: true}}
+	got, err = svc.getFromDHT(context.Background(), id, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &ResolutionMetadata{Partial: true}, got.Metadata, "gets cut short are flagged")
+}
+
+func TestPKARRServiceRepublishes(t *testing.T) {
+	network := testnet.New(t, 3)
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "republish.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, network.Node(0), cache.None{})
+	require.NoError(t, err)
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	put := bep44.Put{V: []byte("hello republish"), K: (*[32]byte)(pubKey), Seq: 1}
+	put.Sign(privKey)
+
+	// the record is only stored, so other nodes only have it once it is republished
+	request := PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+	require.NoError(t, svc.storePkarr(context.Background(), id, request))
+	svc.republish()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	got, err := network.Node(2).GetFull(ctx, id, nil)
+	require.NoError(t, err)
+	assert.Equal(t, put.Seq, got.Seq)
+	assert.Equal(t, put.Sig, got.Sig)
 }
 
-func newPKARRService(t *testing.T) PkarrService {
+func newPKARRService(t *testing.T) *PkarrService {
 	defaultConfig := config.GetDefaultConfig()
 	db, err := storage.NewStorage(defaultConfig.ServerConfig.StorageURI)
 	require.NoError(t, err)
@@ -108,5 +409,5 @@ func newPKARRService(t *testing.T) PkarrService {
 	pkarrService, err := NewPkarrService(&defaultConfig, db)
 	require.NoError(t, err)
 	require.NotEmpty(t, pkarrService)
-	return *pkarrService
+	return pkarrService
 }
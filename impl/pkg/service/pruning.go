@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+// errPruningDisabled is returned for pruning the version history while no limits are configured
+var errPruningDisabled = errors.New("no record version limits are configured")
+
+// versionPruning keeps the version history within the configured maximum of versions per record and in total
+type versionPruning struct {
+	db        storage.VersionPruner
+	perRecord int
+	total     int64
+}
+
+// VersionPruning is the outcome of pruning the version history
+type VersionPruning struct {
+	// Records is the number of records with versions, and Versions the number of their versions before pruning, of
+	// which Pruned were deleted
+	Records  int   `json:"records"`
+	Versions int64 `json:"versions"`
+	Pruned   int64 `json:"pruned"`
+}
+
+// prunedVersion is a version which may be pruned to keep within the maximum of versions in total
+type prunedVersion struct {
+	key string
+	seq int64
+}
+
+// PruneVersions deletes the versions of records beyond the configured maximum per record, then those beyond the
+// maximum in total, the versions with the lowest seqs first. Seqs being unix timestamps, as the spec recommends,
+// those are the oldest versions. The current version of a record, the one with its highest seq, is never pruned.
+func (s *PkarrService) PruneVersions(ctx context.Context) (*VersionPruning, error) {
+	p := s.versionPruning
+	if p == nil {
+		return nil, errPruningDisabled
+	}
+	seqs, err := p.db.ListVersionSeqs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := VersionPruning{Records: len(seqs)}
+	// pruned is the number of versions pruned of each record, those with the lowest seqs
+	pruned := make(map[string]int)
+	var kept int64
+	for key, recordSeqs := range seqs {
+		result.Versions += int64(len(recordSeqs))
+		if p.perRecord > 0 && len(recordSeqs) > p.perRecord {
+			pruned[key] = len(recordSeqs) - p.perRecord
+		}
+		kept += int64(len(recordSeqs) - pruned[key])
+	}
+	if p.total > 0 && kept > p.total {
+		var candidates []prunedVersion
+		for key, recordSeqs := range seqs {
+			for _, seq := range recordSeqs[pruned[key] : len(recordSeqs)-1] {
+				candidates = append(candidates, prunedVersion{key: key, seq: seq})
+			}
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].seq != candidates[j].seq {
+				return candidates[i].seq < candidates[j].seq
+			}
+			return candidates[i].key < candidates[j].key
+		})
+		// the versions of each record are candidates in order of seq, so pruning a prefix of the candidates prunes
+		// the lowest seqs of each record
+		for _, candidate := range candidates[:min(kept-p.total, int64(len(candidates)))] {
+			pruned[candidate.key]++
+		}
+	}
+
+	for key, n := range pruned {
+		deleted, err := p.db.DeleteRecordVersionsBefore(ctx, key, seqs[key][n])
+		if err != nil {
+			return &result, err
+		}
+		result.Pruned += deleted
+	}
+	logrus.WithFields(logrus.Fields{
+		"records":  result.Records,
+		"versions": result.Versions,
+		"pruned":   result.Pruned,
+	}).Info("pruned record versions")
+	return &result, nil
+}
+
+// pruneVersions prunes the version history, on a schedule
+func (s *PkarrService) pruneVersions() {
+	if _, err := s.PruneVersions(context.Background()); err != nil {
+		logrus.WithError(err).Error("failed to prune record versions")
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func TestPruneVersions(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "pruning.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	t.Run("requires a limit", func(t *testing.T) {
+		svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+		require.NoError(t, err)
+		_, err = svc.PruneVersions(ctx)
+		assert.Error(t, err)
+	})
+
+	old := pkarr.Record{K: "old", V: "value", Sig: "sig"}
+	for _, seq := range []int64{1, 2, 3, 4, 5} {
+		old.Seq = seq
+		require.NoError(t, db.WriteRecord(ctx, old))
+	}
+	recent := pkarr.Record{K: "recent", V: "value", Sig: "sig"}
+	for _, seq := range []int64{10, 11, 12} {
+		recent.Seq = seq
+		require.NoError(t, db.WriteRecord(ctx, recent))
+	}
+
+	cfg.RetentionConfig.MaxVersionsPerRecord = 3
+	cfg.RetentionConfig.MaxVersions = 5
+	cfg.RetentionConfig.PruneVersionsCRON = ""
+	svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+	require.NoError(t, err)
+	result, err := svc.PruneVersions(ctx)
+	require.NoError(t, err)
+	// seqs 1 and 2 exceed the limit per record, and seq 3, the lowest left, the limit in total
+	assert.Equal(t, VersionPruning{Records: 2, Versions: 8, Pruned: 3}, *result)
+
+	versionSeqs := func(record pkarr.Record) []int64 {
+		versions, err := db.ListRecordVersions(ctx, record.Key())
+		require.NoError(t, err)
+		var seqs []int64
+		for _, version := range versions {
+			seqs = append(seqs, version.Seq)
+		}
+		return seqs
+	}
+	assert.Equal(t, []int64{4, 5}, versionSeqs(old))
+	assert.Equal(t, []int64{10, 11, 12}, versionSeqs(recent))
+
+	t.Run("current versions are never pruned", func(t *testing.T) {
+		svc.versionPruning.perRecord = 1
+		svc.versionPruning.total = 1
+		result, err := svc.PruneVersions(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), result.Pruned)
+		assert.Equal(t, []int64{5}, versionSeqs(old))
+		assert.Equal(t, []int64{12}, versionSeqs(recent))
+	})
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+// ErrNoSecondaryStorage is returned for reconciling storage without a secondary storage
+var ErrNoSecondaryStorage = errors.New("storage has no secondary storage to reconcile")
+
+// reconciliations serializes reconciliations of the primary and secondary storage, keeping the outcome of the last one
+type reconciliations struct {
+	mu   sync.Mutex
+	last *storage.Reconciliation
+}
+
+// ReconcileStorage compares the records of the primary and secondary storage now, repairing their drift, see
+// storage.Failover.Reconcile
+func (s *PkarrService) ReconcileStorage(ctx context.Context) (*storage.Reconciliation, error) {
+	if !s.cfg.ServerConfig.Role.Publishes() {
+		return nil, ErrReadOnly
+	}
+	failover, ok := storage.As[*storage.Failover](s.db)
+	if !ok {
+		return nil, ErrNoSecondaryStorage
+	}
+	s.reconciliations.mu.Lock()
+	defer s.reconciliations.mu.Unlock()
+
+	reconciliation, err := failover.Reconcile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.reconciliations.last = reconciliation
+	logrus.Infof("reconciled %d primary and %d secondary record(s): %d drifted, %d repaired, %d unrepaired",
+		reconciliation.PrimaryRecords, reconciliation.SecondaryRecords, reconciliation.Drift,
+		reconciliation.Repaired, reconciliation.Unrepaired)
+	return reconciliation, nil
+}
+
+// GetLastReconciliation returns the outcome of the last reconciliation of the primary and secondary storage, or nil
+// if none has run since the gateway started
+func (s *PkarrService) GetLastReconciliation() (*storage.Reconciliation, error) {
+	if _, ok := storage.As[*storage.Failover](s.db); !ok {
+		return nil, ErrNoSecondaryStorage
+	}
+	s.reconciliations.mu.Lock()
+	defer s.reconciliations.mu.Unlock()
+	return s.reconciliations.last, nil
+}
+
+// reconcileStorage runs a scheduled reconciliation of the primary and secondary storage
+func (s *PkarrService) reconcileStorage() {
+	if _, err := s.ReconcileStorage(context.Background()); err != nil {
+		logrus.WithError(err).Error("failed to reconcile storage")
+	}
+}
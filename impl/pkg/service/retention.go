@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cdc"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	// defaultEvictToPercent is the percentage of the limits evicting reduces storage to, if not configured
+	defaultEvictToPercent = 90
+	// resolvedMarkInterval is how often a record's last resolution is written to storage, at most
+	resolvedMarkInterval = time.Hour
+	// maxMarked is the number of recently marked keys remembered before forgetting them all
+	maxMarked = 100_000
+	// usageRecountInterval is how often the running usage is recounted from storage, at most
+	usageRecountInterval = 5 * time.Minute
+)
+
+var (
+	// ErrStorageFull is returned for new records once storage holds the configured maximum of records or bytes,
+	// and stored records can't be evicted to make room for them
+	ErrStorageFull = errors.New("storage is full")
+	// errQuotaUnsupported is returned for storage stats from storage which can't report its usage
+	errQuotaUnsupported = errors.New("storage does not support reporting its usage")
+)
+
+// retention keeps storage within the configured maximum of records and bytes
+type retention struct {
+	db         storage.Quota
+	maxRecords int64
+	maxBytes   int64
+	policy     config.EvictionPolicy
+	evictTo    int64
+
+	// mu serializes quota checks with the writes they admit, so concurrent writes can't exceed the limits together
+	mu sync.Mutex
+	// usage is a running count of the stored records and bytes, so admitting a record doesn't scan storage. Writes and
+	// deletes adjust it by an estimate of their size, and it is recounted from storage every usageRecountInterval, and
+	// after evicting, to correct the estimates and count the writes of other instances. It is guarded by mu.
+	usage     pkarr.Usage
+	countedAt time.Time
+
+	markedMu sync.Mutex
+	// marked are the keys marked resolved since markedSince, which aren't marked again until the interval passes
+	marked      map[string]struct{}
+	markedSince time.Time
+}
+
+func newRetention(cfg config.RetentionConfig, db storage.Quota) *retention {
+	evictTo := int64(cfg.EvictToPercent)
+	if evictTo <= 0 || evictTo > 100 {
+		evictTo = defaultEvictToPercent
+	}
+	return &retention{
+		db:          db,
+		maxRecords:  cfg.MaxRecords,
+		maxBytes:    cfg.MaxBytes,
+		policy:      cfg.Eviction,
+		evictTo:     evictTo,
+		marked:      make(map[string]struct{}),
+		markedSince: time.Now(),
+	}
+}
+
+// recount counts the usage from storage. It must be called holding the lock.
+func (r *retention) recount(ctx context.Context) error {
+	usage, err := r.db.Usage(ctx)
+	if err != nil {
+		return err
+	}
+	r.usage, r.countedAt = usage, time.Now()
+	return nil
+}
+
+// currentUsage returns the running usage, recounting it first if it is due. It must be called holding the lock.
+func (r *retention) currentUsage(ctx context.Context) (pkarr.Usage, error) {
+	if time.Since(r.countedAt) >= usageRecountInterval {
+		if err := r.recount(ctx); err != nil {
+			return pkarr.Usage{}, err
+		}
+	}
+	return r.usage, nil
+}
+
+// stored adds the record written in place of current, nil if the record is new, to the running usage. Each seq is
+// also kept as a version. It must be called holding the lock.
+func (r *retention) stored(current *pkarr.Record, record pkarr.Record) {
+	size := recordSize(record)
+	switch {
+	case current == nil:
+		r.usage.Records++
+		r.usage.Bytes += 2 * size
+	case current.Seq == record.Seq:
+		r.usage.Bytes += size - recordSize(*current)
+	default:
+		r.usage.Bytes += 2*size - recordSize(*current)
+	}
+}
+
+// deleted removes the deleted record from the running usage. Its versions are counted until the next recount, which
+// overestimates the usage rather than exceeding the limits. It must be called holding the lock.
+func (r *retention) deleted(record pkarr.Record) {
+	r.usage.Records--
+	r.usage.Bytes -= recordSize(record)
+}
+
+// recordSize estimates the bytes a record uses in storage by the size of its fields
+func recordSize(record pkarr.Record) int64 {
+	return int64(len(record.Key()) + len(record.V) + len(record.Sig) + len(record.Salt) + 8)
+}
+
+// full returns whether storing another record, new unless it replaces a stored one, would exceed the limits
+func (r *retention) full(usage pkarr.Usage, isNew bool) bool {
+	if isNew && r.maxRecords > 0 && usage.Records >= r.maxRecords {
+		return true
+	}
+	return r.maxBytes > 0 && usage.Bytes >= r.maxBytes
+}
+
+// StorageStats is the occupancy of storage relative to the retention budget
+type StorageStats struct {
+	pkarr.Usage
+	// MaxRecords is the maximum number of stored records, unlimited if zero
+	MaxRecords int64 `json:"maxRecords"`
+	// MaxBytes is the maximum approximate size of the stored records and their versions, unlimited if zero
+	MaxBytes int64 `json:"maxBytes"`
+	// Eviction is the policy applied once storage is full
+	Eviction config.EvictionPolicy `json:"eviction"`
+}
+
+// GetStorageStats returns the occupancy of storage relative to the retention budget
+func (s *PkarrService) GetStorageStats(ctx context.Context) (*StorageStats, error) {
+	quota, ok := storage.As[storage.Quota](s.db)
+	if !ok {
+		return nil, errQuotaUnsupported
+	}
+	usage, err := quota.Usage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	retentionCfg := s.cfg.RetentionConfig
+	eviction := retentionCfg.Eviction
+	if eviction == "" {
+		eviction = config.EvictionNone
+	}
+	return &StorageStats{
+		Usage:      usage,
+		MaxRecords: retentionCfg.MaxRecords,
+		MaxBytes:   retentionCfg.MaxBytes,
+		Eviction:   eviction,
+	}, nil
+}
+
+// admit returns ErrStorageFull if the record can't be stored within the limits, evicting stored records to make
+// room for it first if configured to. It must be called holding the retention lock.
+func (s *PkarrService) admit(ctx context.Context, key string, isNew bool) error {
+	usage, err := s.retention.currentUsage(ctx)
+	if err != nil {
+		return err
+	}
+	if !s.retention.full(usage, isNew) {
+		return nil
+	}
+	if s.retention.policy != config.EvictionLRU {
+		return ErrStorageFull
+	}
+	if err = s.evict(ctx, usage, key); err != nil {
+		return err
+	}
+	if err = s.retention.recount(ctx); err != nil {
+		return err
+	}
+	if s.retention.full(s.retention.usage, isNew) {
+		return ErrStorageFull
+	}
+	return nil
+}
+
+// evict deletes the least recently resolved records, other than the one stored under the given key and those labeled
+// to be kept, until storage is reduced to the configured percentage of the limits
+func (s *PkarrService) evict(ctx context.Context, usage pkarr.Usage, keep string) error {
+	r := s.retention
+	var excess int64
+	if r.maxRecords > 0 {
+		excess = usage.Records - r.maxRecords*r.evictTo/100
+	}
+	// records are assumed to be of the average size to estimate how many to evict to free enough bytes
+	if r.maxBytes > 0 && usage.Records > 0 {
+		recordBytes := usage.Bytes / usage.Records
+		if recordBytes > 0 {
+			excessBytes := usage.Bytes - r.maxBytes*r.evictTo/100
+			excess = max(excess, (excessBytes+recordBytes-1)/recordBytes)
+		}
+	}
+	if excess <= 0 {
+		return nil
+	}
+
+	records, err := s.db.ListRecords(ctx)
+	if err != nil {
+		return err
+	}
+	resolved, err := r.db.ListResolved(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(records, func(i, j int) bool {
+		ki, kj := records[i].Key(), records[j].Key()
+		if resolved[ki] != resolved[kj] {
+			return resolved[ki] < resolved[kj]
+		}
+		return ki < kj
+	})
+
+	var evicted int64
+	for _, record := range records {
+		if evicted >= excess {
+			break
+		}
+		if record.Key() == keep || s.isKept(record.Key()) {
+			continue
+		}
+		if err = r.db.DeleteRecord(ctx, record.Key()); err != nil {
+			return err
+		}
+		s.releaseTenantRecord(ctx, record.Key())
+		evicted++
+		if id, err := recordID(record.K); err == nil {
+			deleted := record
+			s.emitChange(cdc.OpDelete, id, &deleted)
+			salt, _ := base64.RawURLEncoding.DecodeString(record.Salt)
+			if s.index != nil && len(salt) == 0 {
+				s.unindexRecord(ctx, id)
+			}
+			if err = s.cache.Delete(ctx, cacheKey(id, salt)); err != nil {
+				logrus.WithError(err).Warnf("failed to evict record[%s] from cache", id)
+			}
+		}
+	}
+	logrus.Infof("evicted %d least recently resolved record(s) to stay within the storage quota", evicted)
+	return nil
+}
+
+// markResolved records that the record for the given z-base-32 encoded ID and salt was resolved, for evicting the
+// least recently resolved records first. To limit writes, each record is marked at most once per interval.
+func (s *PkarrService) markResolved(id string, salt []byte) {
+	if s.retention == nil || s.retention.policy != config.EvictionLRU {
+		return
+	}
+	key, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return
+	}
+	r := s.retention
+	r.markedMu.Lock()
+	now := time.Now()
+	if now.Sub(r.markedSince) > resolvedMarkInterval || len(r.marked) >= maxMarked {
+		r.marked = make(map[string]struct{})
+		r.markedSince = now
+	}
+	_, ok := r.marked[key]
+	r.marked[key] = struct{}{}
+	r.markedMu.Unlock()
+	if ok {
+		return
+	}
+	s.goBackground(func() {
+		if err := r.db.MarkResolved(context.Background(), key, now); err != nil {
+			logrus.WithError(err).Warnf("failed to mark pkarr record[%s] resolved", id)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func TestRetention(t *testing.T) {
+	newService := func(t *testing.T, retentionCfg config.RetentionConfig) (*PkarrService, storage.Quota) {
+		cfg := config.GetDefaultConfig()
+		cfg.PkarrConfig.RepublishCRON = ""
+		cfg.RetentionConfig = retentionCfg
+		db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "retention.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+		require.NoError(t, err)
+		quota, ok := storage.As[storage.Quota](db)
+		require.True(t, ok)
+		return svc, quota
+	}
+	newRecord := func(t *testing.T) (string, PublishPkarrRequest) {
+		pubKey, privKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		put := bep44.Put{V: []byte("hello pkarr"), K: (*[32]byte)(pubKey), Seq: 1}
+		put.Sign(privKey)
+		return util.Z32Encode(pubKey), PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+	}
+	ctx := context.Background()
+
+	t.Run("unknown eviction policy", func(t *testing.T) {
+		cfg := config.GetDefaultConfig()
+		cfg.RetentionConfig.Eviction = "fifo"
+		db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "retention.db"))
+		require.NoError(t, err)
+		defer db.Close()
+		_, err = NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects new records once full", func(t *testing.T) {
+		svc, _ := newService(t, config.RetentionConfig{MaxRecords: 1, Eviction: config.EvictionNone})
+		id, request := newRecord(t)
+		require.NoError(t, svc.storePkarr(ctx, id, request))
+
+		otherID, otherRequest := newRecord(t)
+		assert.ErrorIs(t, svc.storePkarr(ctx, otherID, otherRequest), ErrStorageFull)
+
+		// stored records are still updated
+		assert.NoError(t, svc.storePkarr(ctx, id, request))
+
+		stats, err := svc.GetStorageStats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), stats.Records)
+		assert.Greater(t, stats.Bytes, int64(0))
+		assert.Equal(t, int64(1), stats.MaxRecords)
+		assert.Equal(t, config.EvictionNone, stats.Eviction)
+	})
+
+	t.Run("evicts least recently resolved records", func(t *testing.T) {
+		svc, quota := newService(t, config.RetentionConfig{MaxRecords: 2, Eviction: config.EvictionLRU, EvictToPercent: 50})
+		firstID, first := newRecord(t)
+		secondID, second := newRecord(t)
+		require.NoError(t, svc.storePkarr(ctx, firstID, first))
+		require.NoError(t, svc.storePkarr(ctx, secondID, second))
+
+		// wait for the records to be marked on publish, then mark the first as more recently resolved
+		firstKey, err := recordKey(firstID)
+		require.NoError(t, err)
+		secondKey, err := recordKey(secondID)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			resolved, err := quota.ListResolved(ctx)
+			return err == nil && len(resolved) == 2
+		}, 5*time.Second, 10*time.Millisecond)
+		now := time.Now()
+		require.NoError(t, quota.MarkResolved(ctx, firstKey, now))
+		require.NoError(t, quota.MarkResolved(ctx, secondKey, now.Add(-time.Hour)))
+
+		thirdID, third := newRecord(t)
+		require.NoError(t, svc.storePkarr(ctx, thirdID, third))
+
+		usage, err := quota.Usage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), usage.Records)
+		evicted, err := svc.db.ReadRecord(ctx, secondKey)
+		require.NoError(t, err)
+		assert.Nil(t, evicted)
+		kept, err := svc.db.ReadRecord(ctx, firstKey)
+		require.NoError(t, err)
+		assert.NotNil(t, kept)
+	})
+	t.Run("keeps labeled records", func(t *testing.T) {
+		svc, quota := newService(t, config.RetentionConfig{MaxRecords: 2, Eviction: config.EvictionLRU, EvictToPercent: 50, KeepLabels: []string{"pinned"}})
+		pinnedID, pinned := newRecord(t)
+		otherID, other := newRecord(t)
+		require.NoError(t, svc.storePkarr(ctx, pinnedID, pinned))
+		require.NoError(t, svc.storePkarr(ctx, otherID, other))
+		require.NoError(t, svc.LabelRecord(ctx, pinnedID, nil, []string{"pinned", "customer:acme"}))
+
+		// the pinned record is the least recently resolved
+		pinnedKey, err := recordKey(pinnedID)
+		require.NoError(t, err)
+		otherKey, err := recordKey(otherID)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			resolved, err := quota.ListResolved(ctx)
+			return err == nil && len(resolved) == 2
+		}, 5*time.Second, 10*time.Millisecond)
+		now := time.Now()
+		require.NoError(t, quota.MarkResolved(ctx, pinnedKey, now.Add(-time.Hour)))
+		require.NoError(t, quota.MarkResolved(ctx, otherKey, now))
+
+		thirdID, third := newRecord(t)
+		require.NoError(t, svc.storePkarr(ctx, thirdID, third))
+
+		kept, err := svc.db.ReadRecord(ctx, pinnedKey)
+		require.NoError(t, err)
+		assert.NotNil(t, kept)
+		evicted, err := svc.db.ReadRecord(ctx, otherKey)
+		require.NoError(t, err)
+		assert.Nil(t, evicted)
+	})
+
+	t.Run("keeps a running usage", func(t *testing.T) {
+		svc, quota := newService(t, config.RetentionConfig{MaxRecords: 2, Eviction: config.EvictionNone})
+		counting := &usageCountingQuota{Quota: quota}
+		svc.retention.db = counting
+		firstID, first := newRecord(t)
+		secondID, second := newRecord(t)
+		require.NoError(t, svc.storePkarr(ctx, firstID, first))
+		require.NoError(t, svc.storePkarr(ctx, secondID, second))
+		thirdID, third := newRecord(t)
+		assert.ErrorIs(t, svc.storePkarr(ctx, thirdID, third), ErrStorageFull)
+		assert.Zero(t, counting.counts, "usage is counted at startup, not on each publish")
+
+		// deleting makes room again
+		deleted, err := svc.DeletePkarr(ctx, firstID, nil)
+		require.NoError(t, err)
+		require.True(t, deleted)
+		require.NoError(t, svc.storePkarr(ctx, thirdID, third))
+
+		// the running usage stays close to the usage in storage, correcting it once due
+		stored, err := quota.Usage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, stored.Records, svc.retention.usage.Records)
+		assert.InEpsilon(t, stored.Bytes, svc.retention.usage.Bytes, 0.5)
+		svc.retention.countedAt = time.Now().Add(-usageRecountInterval)
+		require.NoError(t, svc.storePkarr(ctx, thirdID, third))
+		assert.Equal(t, 1, counting.counts)
+		assert.Equal(t, stored, svc.retention.usage)
+	})
+}
+
+// usageCountingQuota counts how often the usage of storage is counted
+type usageCountingQuota struct {
+	storage.Quota
+	counts int
+}
+
+func (q *usageCountingQuota) Usage(ctx context.Context) (pkarr.Usage, error) {
+	q.counts++
+	return q.Quota.Usage(ctx)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	intutil "github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// seedLineLimit bounds the length of a line of seed data, comfortably above the size of an encoded record
+const seedLineLimit = 64 * 1024
+
+// SeedResult is the outcome of loading seed records
+type SeedResult struct {
+	// Loaded is the number of records stored
+	Loaded int `json:"loaded"`
+	// Skipped is the number of records not stored because a newer record of their key was already stored
+	Skipped int `json:"skipped"`
+	// Errors are the records which failed validation or could not be stored
+	Errors []SeedError `json:"errors,omitempty"`
+}
+
+// SeedError is a record of seed data which could not be loaded
+type SeedError struct {
+	// Line is the 1-indexed line of the record in the seed data
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// SeedFile loads the seed records of the JSONL file at the given path, see Seed
+func (s *PkarrService) SeedFile(ctx context.Context, path string) (*SeedResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return s.Seed(ctx, f)
+}
+
+// Seed loads pre-signed records into storage from JSONL data, one record per line in the format records are stored
+// in, with base64url encoded v, k, sig, and optional salt. Each record is validated like a published record and
+// rejected records are reported without failing the others, so the same data can be loaded repeatedly. Records
+// are not put to the DHT, leaving that to the republisher.
+func (s *PkarrService) Seed(ctx context.Context, r io.Reader) (*SeedResult, error) {
+	if !s.cfg.ServerConfig.Role.Publishes() {
+		return nil, ErrReadOnly
+	}
+	result := SeedResult{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), seedLineLimit)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		err := s.seedRecord(ctx, scanner.Bytes())
+		switch {
+		case err == nil:
+			result.Loaded++
+		case errors.Is(err, ErrStaleSeq):
+			result.Skipped++
+		default:
+			result.Errors = append(result.Errors, SeedError{Line: line, Error: err.Error()})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	logrus.Infof("seeded %d record(s), skipped %d, with %d error(s)", result.Loaded, result.Skipped, len(result.Errors))
+	return &result, nil
+}
+
+// seedRecord validates and stores a single JSON encoded record
+func (s *PkarrService) seedRecord(ctx context.Context, data []byte) error {
+	var record pkarr.Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+	request, err := recordToPublishRequest(record)
+	if err != nil {
+		return err
+	}
+	if err = request.isValid(); err != nil {
+		return err
+	}
+	if err = s.checkSeq(request.Seq); err != nil {
+		return err
+	}
+	return s.storePkarr(ctx, intutil.Z32Encode(request.K[:]), *request)
+}
+
+// recordToPublishRequest decodes a record, checking the lengths of its fields
+func recordToPublishRequest(record pkarr.Record) (*PublishPkarrRequest, error) {
+	encoding := base64.RawURLEncoding
+	v, err := encoding.DecodeString(record.V)
+	if err != nil {
+		return nil, fmt.Errorf("invalid v: %w", err)
+	}
+	k, err := encoding.DecodeString(record.K)
+	if err != nil {
+		return nil, fmt.Errorf("invalid k: %w", err)
+	}
+	if len(k) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("k must be %d bytes", ed25519.PublicKeySize)
+	}
+	sig, err := encoding.DecodeString(record.Sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sig: %w", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("sig must be %d bytes", ed25519.SignatureSize)
+	}
+	salt, err := encoding.DecodeString(record.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	return &PublishPkarrRequest{
+		V:    v,
+		K:    [32]byte(k),
+		Sig:  [64]byte(sig),
+		Seq:  record.Seq,
+		Salt: salt,
+	}, nil
+}
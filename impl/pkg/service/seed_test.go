@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func TestSeed(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "seed.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, staticDHT{}, cache.None{})
+	require.NoError(t, err)
+
+	seedRecord := func(privKey ed25519.PrivateKey, v string, seq int64) pkarr.Record {
+		pubKey := privKey.Public().(ed25519.PublicKey)
+		put := bep44.Put{V: []byte(v), K: (*[32]byte)(pubKey), Seq: seq}
+		put.Sign(privKey)
+		encoding := base64.RawURLEncoding
+		return pkarr.Record{
+			V:   encoding.EncodeToString(put.V.([]byte)),
+			K:   encoding.EncodeToString(pubKey),
+			Sig: encoding.EncodeToString(put.Sig[:]),
+			Seq: put.Seq,
+		}
+	}
+	line := func(record pkarr.Record) string {
+		recordBytes, err := json.Marshal(record)
+		require.NoError(t, err)
+		return string(recordBytes)
+	}
+
+	alicePub, aliceKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, bobKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	aliceID := util.Z32Encode(alicePub)
+	alice := seedRecord(aliceKey, "alice", 2)
+	bob := seedRecord(bobKey, "bob", 1)
+	forged := bob
+	forged.Seq = 2
+	truncated := bob
+	truncated.K = truncated.K[:10]
+
+	data := strings.Join([]string{line(alice), "", line(bob), line(forged), "not json", line(truncated)}, "\n")
+	result, err := svc.Seed(context.Background(), strings.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Loaded)
+	assert.Equal(t, 0, result.Skipped)
+	require.Len(t, result.Errors, 3)
+	assert.Equal(t, []int{4, 5, 6}, []int{result.Errors[0].Line, result.Errors[1].Line, result.Errors[2].Line})
+
+	got, err := svc.GetPkarr(context.Background(), aliceID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, []byte("alice"), got.V)
+
+	t.Run("stale records are skipped", func(t *testing.T) {
+		stale := seedRecord(aliceKey, "older", 1)
+		result, err := svc.Seed(context.Background(), strings.NewReader(line(alice)+"\n"+line(stale)))
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Loaded)
+		assert.Equal(t, 1, result.Skipped)
+		assert.Empty(t, result.Errors)
+	})
+
+	t.Run("resolver role", func(t *testing.T) {
+		resolverCfg := cfg
+		resolverCfg.ServerConfig.Role = config.RoleResolver
+		resolver := PkarrService{cfg: &resolverCfg}
+		_, err := resolver.Seed(context.Background(), strings.NewReader(line(alice)))
+		assert.ErrorIs(t, err, ErrReadOnly)
+	})
+}
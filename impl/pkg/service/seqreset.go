@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
+
+	intutil "github.com/TBD54566975/did-dht-method/impl/internal/util"
+)
+
+const (
+	// SeqResetConsentContentType is the content type (cty) of the JWS by which the owner of a key consents to resetting
+	// the seq of its record
+	SeqResetConsentContentType = "did-dht-seq-reset+json"
+
+	// seqResetTimeout is how long a seq reset may await consent and execution before it expires
+	seqResetTimeout = time.Hour
+	// seqResetSuppression is how long the reset seq, and any newer, stay suppressed once a reset is executed, long
+	// enough for DHT nodes to drop the record once nobody republishes it
+	seqResetSuppression = 24 * time.Hour
+)
+
+var (
+	// ErrSeqResetNotFound is returned for seq resets which were never opened, or have expired
+	ErrSeqResetNotFound = errors.New("seq reset not found")
+	// ErrSeqResetState is returned for steps of a seq reset taken out of order
+	ErrSeqResetState = errors.New("seq reset is not awaiting this step")
+	// ErrInvalidConsent is returned for consents which aren't signed by the key of the ID, or are for another reset
+	ErrInvalidConsent = errors.New("invalid seq reset consent")
+
+	// errSeqSuppressed is returned for records resolved from the DHT whose seq was reset
+	errSeqSuppressed = errors.New("seq was reset by the key's owner")
+)
+
+// SeqResetState is the step an emergency seq reset is at
+type SeqResetState string
+
+const (
+	// SeqResetAwaitingConsent is a reset awaiting the signed consent of the key's owner
+	SeqResetAwaitingConsent SeqResetState = "awaiting_consent"
+	// SeqResetConsented is a reset the key's owner consented to, awaiting execution by an admin
+	SeqResetConsented SeqResetState = "consented"
+	// SeqResetExecuted is a reset whose record was purged, and whose seq is suppressed
+	SeqResetExecuted SeqResetState = "executed"
+)
+
+// SeqReset is an emergency reset of a record whose seq was accidentally set astronomically high, which no record can
+// supersede. An admin opens it, the key's owner consents to it by signing its Consent as a JWS, and an admin executes
+// it, purging the record from storage and suppressing its seq so the owner can resume publishing with a lower one.
+type SeqReset struct {
+	// Consent is the payload the key's owner signs to consent to the reset
+	Consent SeqResetConsent `json:"consent"`
+	State   SeqResetState   `json:"state"`
+	Reason  string          `json:"reason"`
+	// Opened is the unix time in seconds the reset was opened at
+	Opened int64 `json:"opened"`
+	// Expires is the unix time in seconds the reset expires at, if not executed by then, or its seq stops being
+	// suppressed at, once executed
+	Expires int64 `json:"expires"`
+	// Executed is the unix time in seconds the reset was executed at, if it was
+	Executed int64 `json:"executed,omitempty"`
+}
+
+// SeqResetConsent identifies the record a seq reset purges, which the key's owner signs as a JWS with the
+// SeqResetConsentContentType to consent to it
+type SeqResetConsent struct {
+	// ID is the z-base-32 encoded ID of the record
+	ID string `json:"id"`
+	// Salt is the base64url encoded salt of the record, if any
+	Salt string `json:"salt,omitempty"`
+	// Seq is the seq of the stored record, which is purged and suppressed along with any newer
+	Seq int64 `json:"seq"`
+	// Nonce is a random nonce binding the consent to this reset
+	Nonce string `json:"nonce"`
+}
+
+// SignSeqResetConsent returns the compact JWS of the consent signed with the private key of its ID, by which the
+// key's owner consents to resetting the seq of its record
+func SignSeqResetConsent(key ed25519.PrivateKey, consent SeqResetConsent) (string, error) {
+	payload, err := json.Marshal(consent)
+	if err != nil {
+		return "", err
+	}
+	return signJWS(key, payload, SeqResetConsentContentType)
+}
+
+// seqResets are the emergency seq resets in progress, and the executed resets whose seq is still suppressed, by the
+// storage key of their record. They are kept in memory, so they are lost on restart.
+type seqResets struct {
+	mu     sync.Mutex
+	resets map[string]*SeqReset
+}
+
+func newSeqResets() *seqResets {
+	return &seqResets{resets: make(map[string]*SeqReset)}
+}
+
+// get returns the unexpired reset of the record stored under the given key, if any
+func (r *seqResets) get(key string, now time.Time) *SeqReset {
+	reset, ok := r.resets[key]
+	if !ok {
+		return nil
+	}
+	if now.Unix() >= reset.Expires {
+		delete(r.resets, key)
+		return nil
+	}
+	return reset
+}
+
+// suppressed returns whether a reset of the record stored under the given key was executed, suppressing the seq
+func (r *seqResets) suppressed(key string, seq int64, now time.Time) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reset := r.get(key, now)
+	return reset != nil && reset.State == SeqResetExecuted && seq >= reset.Consent.Seq
+}
+
+// OpenSeqReset opens an emergency reset of the stored record for the given z-base-32 encoded ID and optional salt,
+// replacing any reset of it in progress. It returns nil if no record is stored.
+func (s *PkarrService) OpenSeqReset(ctx context.Context, id string, salt []byte, reason string) (*SeqReset, error) {
+	if s.seqResets == nil {
+		return nil, ErrReadOnly
+	}
+	key, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return nil, err
+	}
+	record, err := s.db.ReadRecord(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, nil
+	}
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	reset := SeqReset{
+		Consent: SeqResetConsent{
+			ID:    id,
+			Salt:  base64.RawURLEncoding.EncodeToString(salt),
+			Seq:   record.Seq,
+			Nonce: base64.RawURLEncoding.EncodeToString(nonce),
+		},
+		State:   SeqResetAwaitingConsent,
+		Reason:  reason,
+		Opened:  now.Unix(),
+		Expires: now.Add(seqResetTimeout).Unix(),
+	}
+	s.seqResets.mu.Lock()
+	s.seqResets.resets[key] = &reset
+	s.seqResets.mu.Unlock()
+	logSeqReset(reset, "open")
+	result := reset
+	return &result, nil
+}
+
+// ConsentSeqReset records the consent of the key's owner to the reset of the record for the given z-base-32 encoded
+// ID and optional salt, a compact JWS of the reset's Consent signed with the key of the ID
+func (s *PkarrService) ConsentSeqReset(id string, salt []byte, consent string) (*SeqReset, error) {
+	if s.seqResets == nil {
+		return nil, ErrReadOnly
+	}
+	key, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := intutil.Z32Decode(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(pubKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("id must be a %d byte key", ed25519.PublicKeySize)
+	}
+	payload, err := VerifyJWS(consent, pubKey, SeqResetConsentContentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConsent, err)
+	}
+	var signed SeqResetConsent
+	if err = json.Unmarshal(payload, &signed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConsent, err)
+	}
+
+	s.seqResets.mu.Lock()
+	defer s.seqResets.mu.Unlock()
+	reset := s.seqResets.get(key, time.Now())
+	if reset == nil {
+		return nil, ErrSeqResetNotFound
+	}
+	if reset.State != SeqResetAwaitingConsent {
+		return nil, ErrSeqResetState
+	}
+	if signed != reset.Consent {
+		return nil, fmt.Errorf("%w: signed for another reset", ErrInvalidConsent)
+	}
+	reset.State = SeqResetConsented
+	logSeqReset(*reset, "consent")
+	result := *reset
+	return &result, nil
+}
+
+// ExecuteSeqReset executes the consented reset of the record for the given z-base-32 encoded ID and optional salt:
+// the record is purged from storage, with all of its versions, and from the cache, and its seq, and any newer, is
+// suppressed, so it is neither stored, republished, nor resolved from the DHT while it lingers there. The owner may
+// then publish a record with a lower seq.
+func (s *PkarrService) ExecuteSeqReset(ctx context.Context, id string, salt []byte) (*SeqReset, error) {
+	if s.seqResets == nil {
+		return nil, ErrReadOnly
+	}
+	key, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.seqResets.mu.Lock()
+	defer s.seqResets.mu.Unlock()
+	now := time.Now()
+	reset := s.seqResets.get(key, now)
+	if reset == nil {
+		return nil, ErrSeqResetNotFound
+	}
+	if reset.State != SeqResetConsented {
+		return nil, ErrSeqResetState
+	}
+	// the seq is suppressed before the record is purged, so a concurrent adoption or import can't store it again
+	reset.State = SeqResetExecuted
+	reset.Executed = now.Unix()
+	reset.Expires = now.Add(seqResetSuppression).Unix()
+	if _, err = s.purgeRecord(ctx, id, salt); err != nil {
+		reset.State = SeqResetConsented
+		reset.Executed = 0
+		reset.Expires = now.Add(seqResetTimeout).Unix()
+		return nil, err
+	}
+	s.confirmations.forget(key)
+	logSeqReset(*reset, "execute")
+	result := *reset
+	return &result, nil
+}
+
+// ListSeqResets returns the seq resets in progress, and the executed resets whose seq is still suppressed, oldest
+// first
+func (s *PkarrService) ListSeqResets() ([]SeqReset, error) {
+	if s.seqResets == nil {
+		return nil, ErrReadOnly
+	}
+	s.seqResets.mu.Lock()
+	defer s.seqResets.mu.Unlock()
+	now := time.Now()
+	resets := make([]SeqReset, 0, len(s.seqResets.resets))
+	for key := range s.seqResets.resets {
+		if reset := s.seqResets.get(key, now); reset != nil {
+			resets = append(resets, *reset)
+		}
+	}
+	sort.Slice(resets, func(i, j int) bool { return resets[i].Opened < resets[j].Opened })
+	return resets, nil
+}
+
+// interceptReset rejects the records whose seq an executed reset suppresses, such as the reset record replayed from
+// the DHT, another gateway, or an import
+func (s *PkarrService) interceptReset(_ context.Context, id string, request PublishPkarrRequest) error {
+	key, err := saltedRecordKey(id, request.Salt)
+	if err != nil {
+		return nil
+	}
+	if s.seqResets.suppressed(key, request.Seq, time.Now()) {
+		return &PublishRejectedError{ID: id, Reason: "seq was reset by the key's owner, publish a lower seq"}
+	}
+	return nil
+}
+
+// seqSuppressed returns whether the seq of the record for the given z-base-32 encoded ID and salt was reset
+func (s *PkarrService) seqSuppressed(id string, salt []byte, seq int64) bool {
+	if s.seqResets == nil {
+		return false
+	}
+	key, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return false
+	}
+	return s.seqResets.suppressed(key, seq, time.Now())
+}
+
+func logSeqReset(reset SeqReset, action string) {
+	logrus.WithFields(logrus.Fields{
+		"audit":  "seq_reset",
+		"action": action,
+		"id":     reset.Consent.ID,
+		"seq":    reset.Consent.Seq,
+		"reason": reset.Reason,
+	}).Info("emergency seq reset")
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestSeqReset(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	sign := func(seq int64) PublishPkarrRequest {
+		put := bep44.Put{V: []byte("hello reset"), K: (*[32]byte)(pubKey), Seq: seq}
+		put.Sign(privKey)
+		return PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+	}
+	bad := sign(math.MaxInt64 - 1)
+	v, err := bencode.Marshal(bad.V)
+	require.NoError(t, err)
+	// the bad record lingers on the DHT
+	d := staticDHT{result: dht.FullGetResult{Seq: bad.Seq, V: v, Sig: bad.Sig, Mutable: true}}
+
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "reset.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, d, cache.None{})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, svc.storePkarr(ctx, id, bad))
+	assert.ErrorIs(t, svc.storePkarr(ctx, id, sign(1)), ErrStaleSeq)
+
+	reset, err := svc.OpenSeqReset(ctx, id, nil, "clock bug")
+	require.NoError(t, err)
+	require.NotNil(t, reset)
+	assert.Equal(t, SeqResetAwaitingConsent, reset.State)
+	assert.Equal(t, bad.Seq, reset.Consent.Seq)
+
+	_, err = svc.ExecuteSeqReset(ctx, id, nil)
+	assert.ErrorIs(t, err, ErrSeqResetState, "the owner hasn't consented")
+
+	t.Run("consent must be signed by the key for this reset", func(t *testing.T) {
+		_, otherKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		forged, err := SignSeqResetConsent(otherKey, reset.Consent)
+		require.NoError(t, err)
+		_, err = svc.ConsentSeqReset(id, nil, forged)
+		assert.ErrorIs(t, err, ErrInvalidConsent)
+
+		other := reset.Consent
+		other.Nonce = "replayed"
+		replayed, err := SignSeqResetConsent(privKey, other)
+		require.NoError(t, err)
+		_, err = svc.ConsentSeqReset(id, nil, replayed)
+		assert.ErrorIs(t, err, ErrInvalidConsent)
+
+		// a JWS of the owner's for another purpose isn't consent
+		payload, err := json.Marshal(reset.Consent)
+		require.NoError(t, err)
+		transfer, err := signJWS(privKey, payload, TransferBundleContentType)
+		require.NoError(t, err)
+		_, err = svc.ConsentSeqReset(id, nil, transfer)
+		assert.ErrorIs(t, err, ErrInvalidConsent)
+	})
+
+	consent, err := SignSeqResetConsent(privKey, reset.Consent)
+	require.NoError(t, err)
+	reset, err = svc.ConsentSeqReset(id, nil, consent)
+	require.NoError(t, err)
+	assert.Equal(t, SeqResetConsented, reset.State)
+
+	reset, err = svc.ExecuteSeqReset(ctx, id, nil)
+	require.NoError(t, err)
+	assert.Equal(t, SeqResetExecuted, reset.State)
+	key, err := saltedRecordKey(id, nil)
+	require.NoError(t, err)
+	stored, err := db.ReadRecord(ctx, key)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "the record is purged")
+
+	// the reset record is neither stored again nor resolved from the DHT, while the owner publishes a lower seq
+	var rejected *PublishRejectedError
+	assert.ErrorAs(t, svc.storePkarr(ctx, id, bad), &rejected)
+	require.NoError(t, svc.storePkarr(ctx, id, sign(1)))
+	got, err := svc.GetPkarr(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, int64(1), got.Seq)
+
+	resets, err := svc.ListSeqResets()
+	require.NoError(t, err)
+	assert.Len(t, resets, 1)
+
+	_, err = svc.ConsentSeqReset(util.Z32Encode(make([]byte, 32)), nil, consent)
+	assert.Error(t, err)
+}
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+const (
+	// SLAPeriodDaily and SLAPeriodWeekly are the periods reports cover: a UTC day, or a week starting on Monday
+	SLAPeriodDaily  = "daily"
+	SLAPeriodWeekly = "weekly"
+
+	// slaRetentionDays is the number of days the stats reports are generated from are kept, enough for the report of
+	// the previous week
+	slaRetentionDays = 14
+	// slaSummaryTimeout bounds how long delivering a summary to the webhook or mail server may take
+	slaSummaryTimeout = 30 * time.Second
+)
+
+// slaLatencyBuckets are the upper bounds of the buckets of the latency histograms percentiles are estimated from
+var slaLatencyBuckets = [...]time.Duration{
+	5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond, time.Second,
+	2500 * time.Millisecond, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+}
+
+// ErrUnknownSLAPeriod is returned for reports of periods other than SLAPeriodDaily and SLAPeriodWeekly
+var ErrUnknownSLAPeriod = errors.New("period must be daily or weekly")
+
+// SLAReport is the success rate and latency of the resolutions and publishes of a period
+type SLAReport struct {
+	Period string    `json:"period"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	// Complete is whether the period has ended and the gateway ran all of it, since the stats aren't kept across
+	// restarts
+	Complete    bool               `json:"complete"`
+	Resolutions SLAOperationReport `json:"resolutions"`
+	Publishes   SLAOperationReport `json:"publishes"`
+}
+
+// SLAOperationReport is the success rate and latency of one kind of operation
+type SLAOperationReport struct {
+	// Requests is the number of operations, of which Succeeded succeeded, NotFound resolved no record, Rejected were
+	// publishes rejected as invalid or by policy, and Failed failed with an error of the gateway
+	Requests  int64 `json:"requests"`
+	Succeeded int64 `json:"succeeded"`
+	NotFound  int64 `json:"notFound,omitempty"`
+	Rejected  int64 `json:"rejected,omitempty"`
+	Failed    int64 `json:"failed"`
+	// Availability is the fraction of the requests which didn't fail, 1 if there were none
+	Availability float64 `json:"availability"`
+	// The latency percentiles are estimated as the upper bound of the histogram bucket they fall in, capped by the
+	// highest latency observed
+	P50Millis int64 `json:"p50Millis"`
+	P95Millis int64 `json:"p95Millis"`
+	P99Millis int64 `json:"p99Millis"`
+	MaxMillis int64 `json:"maxMillis"`
+}
+
+// slaOutcome is how an operation ended
+type slaOutcome int
+
+const (
+	slaSucceeded slaOutcome = iota
+	slaNotFound
+	slaRejected
+	slaFailed
+)
+
+// slaStats are the outcomes and latency histogram of one kind of operation
+type slaStats struct {
+	outcomes [slaFailed + 1]int64
+	// buckets counts the latencies up to each of slaLatencyBuckets, the last counting those beyond them
+	buckets [len(slaLatencyBuckets) + 1]int64
+	max     time.Duration
+}
+
+func (s *slaStats) observe(latency time.Duration, outcome slaOutcome) {
+	s.outcomes[outcome]++
+	bucket := len(slaLatencyBuckets)
+	for i, bound := range slaLatencyBuckets {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+	s.buckets[bucket]++
+	s.max = max(s.max, latency)
+}
+
+func (s *slaStats) merge(other slaStats) {
+	for i := range s.outcomes {
+		s.outcomes[i] += other.outcomes[i]
+	}
+	for i := range s.buckets {
+		s.buckets[i] += other.buckets[i]
+	}
+	s.max = max(s.max, other.max)
+}
+
+// percentile estimates the latency the given fraction of the operations took at most
+func (s *slaStats) percentile(p float64) time.Duration {
+	var total int64
+	for _, count := range s.buckets {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := int64(p * float64(total))
+	if float64(rank) < p*float64(total) {
+		rank++
+	}
+	var seen int64
+	for i, count := range s.buckets {
+		seen += count
+		if seen >= rank && i < len(slaLatencyBuckets) {
+			return min(slaLatencyBuckets[i], s.max)
+		}
+	}
+	return s.max
+}
+
+func (s *slaStats) report() SLAOperationReport {
+	report := SLAOperationReport{
+		Succeeded:    s.outcomes[slaSucceeded],
+		NotFound:     s.outcomes[slaNotFound],
+		Rejected:     s.outcomes[slaRejected],
+		Failed:       s.outcomes[slaFailed],
+		Availability: 1,
+		P50Millis:    s.percentile(0.5).Milliseconds(),
+		P95Millis:    s.percentile(0.95).Milliseconds(),
+		P99Millis:    s.percentile(0.99).Milliseconds(),
+		MaxMillis:    s.max.Milliseconds(),
+	}
+	report.Requests = report.Succeeded + report.NotFound + report.Rejected + report.Failed
+	if report.Requests > 0 {
+		report.Availability = 1 - float64(report.Failed)/float64(report.Requests)
+	}
+	return report
+}
+
+// slaDay is the stats of a UTC day
+type slaDay struct {
+	resolutions slaStats
+	publishes   slaStats
+}
+
+// sla aggregates the outcomes and latency of resolutions and publishes by day, for the reports of operators
+// publishing SLAs for their gateway, and sends summaries of them on a schedule
+type sla struct {
+	started time.Time
+	cfg     config.SLAConfig
+	client  *http.Client
+	// baseURL identifies the gateway in summaries
+	baseURL string
+
+	mu sync.Mutex
+	// days are the stats of the latest slaRetentionDays days, keyed by their start
+	days map[time.Time]*slaDay
+}
+
+func newSLA(cfg config.SLAConfig, baseURL string) *sla {
+	return &sla{
+		started: time.Now(),
+		cfg:     cfg,
+		client:  http.DefaultClient,
+		baseURL: baseURL,
+		days:    make(map[time.Time]*slaDay),
+	}
+}
+
+// day returns the stats of the day of the time, dropping those of days past retention; the lock must be held
+func (a *sla) day(at time.Time) *slaDay {
+	start := startOfDay(at)
+	if day, ok := a.days[start]; ok {
+		return day
+	}
+	for dayStart := range a.days {
+		if start.Sub(dayStart) >= slaRetentionDays*24*time.Hour {
+			delete(a.days, dayStart)
+		}
+	}
+	day := new(slaDay)
+	a.days[start] = day
+	return day
+}
+
+// observeResolution records a resolution which started at the given time and resolved the response, or failed
+func (a *sla) observeResolution(start time.Time, resp *GetPkarrResponse, err error) {
+	if a == nil {
+		return
+	}
+	outcome := slaSucceeded
+	switch {
+	case err != nil:
+		outcome = slaFailed
+	case resp == nil:
+		outcome = slaNotFound
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.day(start).resolutions.observe(time.Since(start), outcome)
+}
+
+// observePublish records a publish which started at the given time and ended with the error, if any
+func (a *sla) observePublish(start time.Time, err error) {
+	if a == nil {
+		return
+	}
+	outcome := slaSucceeded
+	switch {
+	case isPublishRejection(err):
+		outcome = slaRejected
+	case err != nil:
+		outcome = slaFailed
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.day(start).publishes.observe(time.Since(start), outcome)
+}
+
+// isPublishRejection returns whether the publish failed for a reason of the client's, such as an invalid record or
+// a policy, rather than an error of the gateway's
+func isPublishRejection(err error) bool {
+	if err == nil {
+		return false
+	}
+	var (
+		validationErrs validator.ValidationErrors
+		keyMismatch    *KeyMismatchError
+		coolDown       *CoolDownError
+		rejected       *PublishRejectedError
+	)
+	return errors.Is(err, ErrReadOnly) || errors.Is(err, ErrInvalidSignature) || errors.Is(err, ErrStaleSeq) ||
+		errors.Is(err, ErrSeqInFuture) || errors.Is(err, ErrPacketTooLarge) || errors.As(err, &validationErrs) ||
+		errors.As(err, &keyMismatch) || errors.As(err, &coolDown) || errors.As(err, &rejected)
+}
+
+// report returns the report of the period containing the given time
+func (a *sla) report(period string, at time.Time) (*SLAReport, error) {
+	start := startOfDay(at)
+	days := 1
+	switch period {
+	case SLAPeriodDaily:
+	case SLAPeriodWeekly:
+		// weeks start on Monday
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		days = 7
+	default:
+		return nil, ErrUnknownSLAPeriod
+	}
+	end := start.AddDate(0, 0, days)
+
+	var resolutions, publishes slaStats
+	a.mu.Lock()
+	for dayStart, day := range a.days {
+		if !dayStart.Before(start) && dayStart.Before(end) {
+			resolutions.merge(day.resolutions)
+			publishes.merge(day.publishes)
+		}
+	}
+	a.mu.Unlock()
+	return &SLAReport{
+		Period:      period,
+		Start:       start,
+		End:         end,
+		Complete:    !a.started.After(start) && !time.Now().Before(end),
+		Resolutions: resolutions.report(),
+		Publishes:   publishes.report(),
+	}, nil
+}
+
+// startOfDay returns the start of the UTC day of the time
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// GetSLAReport returns the success rate and latency of the resolutions and publishes of the daily or weekly period
+// containing the given time
+func (s *PkarrService) GetSLAReport(period string, at time.Time) (*SLAReport, error) {
+	return s.sla.report(period, at)
+}
+
+// sendSLASummary sends the report of the period before the current one to the webhook and email recipients
+func (s *PkarrService) sendSLASummary(period string) {
+	previous := time.Now().AddDate(0, 0, -1)
+	if period == SLAPeriodWeekly {
+		previous = time.Now().AddDate(0, 0, -7)
+	}
+	report, err := s.sla.report(period, previous)
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to generate %s sla report", period)
+		return
+	}
+	if s.sla.cfg.WebhookURL != "" {
+		if err = s.sla.post(*report); err != nil {
+			logrus.WithError(err).Errorf("failed to send %s sla report to webhook", period)
+		}
+	}
+	if s.sla.cfg.SMTPURL != "" && len(s.sla.cfg.EmailTo) > 0 {
+		if err = s.sla.email(*report); err != nil {
+			logrus.WithError(err).Errorf("failed to email %s sla report", period)
+		}
+	}
+}
+
+// post posts the report to the webhook
+func (a *sla) post(report SLAReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), slaSummaryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// email emails the summary of the report through the SMTP server, authenticating with the credentials of its URL,
+// if any
+func (a *sla) email(report SLAReport) error {
+	server, err := url.Parse(a.cfg.SMTPURL)
+	if err != nil {
+		return err
+	}
+	if server.Scheme != "smtp" {
+		return fmt.Errorf("unsupported smtp url scheme: %s", server.Scheme)
+	}
+	var auth smtp.Auth
+	if server.User != nil {
+		password, _ := server.User.Password()
+		auth = smtp.PlainAuth("", server.User.Username(), password, server.Hostname())
+	}
+
+	// smtp.SendMail doesn't take a context, so the connection is dialed with the timeout as its deadline
+	conn, err := net.DialTimeout("tcp", server.Host, slaSummaryTimeout)
+	if err != nil {
+		return err
+	}
+	if err = conn.SetDeadline(time.Now().Add(slaSummaryTimeout)); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	client, err := smtp.NewClient(conn, server.Hostname())
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(nil); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(a.cfg.EmailFrom); err != nil {
+		return err
+	}
+	for _, to := range a.cfg.EmailTo {
+		if err = client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(a.summary(report)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// summary returns the email message summarizing the report
+func (a *sla) summary(report SLAReport) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", a.cfg.EmailFrom)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(a.cfg.EmailTo, ", "))
+	fmt.Fprintf(&b, "Subject: %s SLA report of %s for %s\r\n", report.Period, report.Start.Format(time.DateOnly), a.baseURL)
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&b, "%s to %s\r\n", report.Start.Format(time.RFC3339), report.End.Format(time.RFC3339))
+	if !report.Complete {
+		b.WriteString("The gateway restarted during the period, so the report is incomplete.\r\n")
+	}
+	for _, op := range []struct {
+		name   string
+		report SLAOperationReport
+	}{{"Resolutions", report.Resolutions}, {"Publishes", report.Publishes}} {
+		fmt.Fprintf(&b, "\r\n%s: %d requests, %.3f%% available (%d failed)\r\n", op.name, op.report.Requests,
+			op.report.Availability*100, op.report.Failed)
+		fmt.Fprintf(&b, "Latency: p50 %dms, p95 %dms, p99 %dms, max %dms\r\n", op.report.P50Millis,
+			op.report.P95Millis, op.report.P99Millis, op.report.MaxMillis)
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestSLAReport(t *testing.T) {
+	a := newSLA(config.SLAConfig{}, "")
+	// a wednesday
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	observe := func(at time.Time, latency time.Duration, outcome slaOutcome) {
+		a.day(at).resolutions.observe(latency, outcome)
+	}
+	for i := 0; i < 90; i++ {
+		observe(now, 20*time.Millisecond, slaSucceeded)
+	}
+	for i := 0; i < 8; i++ {
+		observe(now, 400*time.Millisecond, slaNotFound)
+	}
+	observe(now, 3*time.Second, slaFailed)
+	observe(now, 45*time.Second, slaFailed)
+	// the monday of the same week, and the sunday of the week before
+	observe(now.AddDate(0, 0, -2), time.Millisecond, slaSucceeded)
+	observe(now.AddDate(0, 0, -3), time.Millisecond, slaFailed)
+
+	daily, err := a.report(SLAPeriodDaily, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), daily.Start)
+	assert.Equal(t, time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), daily.End)
+	assert.False(t, daily.Complete, "the gateway didn't run all of the day")
+	resolutions := daily.Resolutions
+	assert.Equal(t, int64(100), resolutions.Requests)
+	assert.Equal(t, int64(90), resolutions.Succeeded)
+	assert.Equal(t, int64(8), resolutions.NotFound)
+	assert.Equal(t, int64(2), resolutions.Failed)
+	assert.InDelta(t, 0.98, resolutions.Availability, 1e-9)
+	assert.Equal(t, int64(25), resolutions.P50Millis)
+	assert.Equal(t, int64(500), resolutions.P95Millis)
+	assert.Equal(t, int64(5000), resolutions.P99Millis)
+	assert.Equal(t, int64(45000), resolutions.MaxMillis)
+	assert.Equal(t, int64(0), daily.Publishes.Requests)
+	assert.Equal(t, float64(1), daily.Publishes.Availability)
+
+	weekly, err := a.report(SLAPeriodWeekly, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), weekly.Start)
+	assert.Equal(t, int64(101), weekly.Resolutions.Requests)
+	assert.Equal(t, int64(2), weekly.Resolutions.Failed)
+
+	_, err = a.report("hourly", now)
+	assert.ErrorIs(t, err, ErrUnknownSLAPeriod)
+
+	t.Run("days past retention are dropped", func(t *testing.T) {
+		a.day(now.AddDate(0, 0, slaRetentionDays))
+		report, err := a.report(SLAPeriodDaily, now)
+		require.NoError(t, err)
+		assert.Zero(t, report.Resolutions.Requests)
+	})
+
+	t.Run("services without an sla observe nothing", func(t *testing.T) {
+		var unset *sla
+		assert.NotPanics(t, func() {
+			unset.observeResolution(now, nil, nil)
+			unset.observePublish(now, nil)
+		})
+	})
+}
+
+func TestSLA(t *testing.T) {
+	reports := make(chan SLAReport, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report SLAReport
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&report))
+		reports <- report
+	}))
+	defer webhook.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.SLAConfig.WebhookURL = webhook.URL
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "sla.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+	require.NoError(t, err)
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	put := bep44.Put{V: []byte("hello sla"), K: (*[32]byte)(pubKey), Seq: 2}
+	put.Sign(privKey)
+	ctx := context.Background()
+	require.NoError(t, svc.PublishPkarr(ctx, id, PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}))
+	// an older seq is the client's mistake, which doesn't count against availability
+	stale := bep44.Put{V: []byte("hello sla"), K: (*[32]byte)(pubKey), Seq: 1}
+	stale.Sign(privKey)
+	err = svc.PublishPkarr(ctx, id, PublishPkarrRequest{V: stale.V.([]byte), K: *stale.K, Sig: stale.Sig, Seq: stale.Seq})
+	require.ErrorIs(t, err, ErrStaleSeq)
+	resp, err := svc.GetPkarr(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	report, err := svc.GetSLAReport(SLAPeriodDaily, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), report.Publishes.Requests)
+	assert.Equal(t, int64(1), report.Publishes.Succeeded)
+	assert.Equal(t, int64(1), report.Publishes.Rejected)
+	assert.Equal(t, float64(1), report.Publishes.Availability)
+	assert.Equal(t, int64(1), report.Resolutions.Succeeded)
+
+	t.Run("summaries are sent to the webhook", func(t *testing.T) {
+		svc.sendSLASummary(SLAPeriodDaily)
+		select {
+		case summary := <-reports:
+			assert.Equal(t, SLAPeriodDaily, summary.Period)
+			assert.Equal(t, startOfDay(time.Now().AddDate(0, 0, -1)), summary.Start.UTC())
+			assert.Zero(t, summary.Publishes.Requests, "the summary is of the previous day")
+		case <-time.After(time.Second):
+			t.Fatal("summary was not sent")
+		}
+	})
+
+	t.Run("rejections", func(t *testing.T) {
+		assert.False(t, isPublishRejection(nil))
+		assert.False(t, isPublishRejection(errors.New("storage is down")))
+		assert.False(t, isPublishRejection(ErrStorageFull))
+		assert.True(t, isPublishRejection(&CoolDownError{}))
+		assert.True(t, isPublishRejection(&KeyMismatchError{ID: id}))
+	})
+}
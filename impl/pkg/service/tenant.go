@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	// QuotaRecords and QuotaBytes are the quotas of a tenant, of the number of records it stores and the size of
+	// their DNS packets
+	QuotaRecords = "records"
+	QuotaBytes   = "bytes"
+)
+
+// ErrQuotaExceeded is returned for publishes which would take a tenant beyond a hard quota
+var ErrQuotaExceeded = errors.New("tenant quota exceeded")
+
+// tenants accounts the records stored by each tenant against its quotas
+type tenants struct {
+	ledger storage.TenantLedger
+	// byKey are the tenants by the sha256 hash of their API key, so looking one up takes no longer for a key sharing
+	// a prefix with that of a tenant
+	byKey  map[[32]byte]config.Tenant
+	byName map[string]config.Tenant
+	cfg    config.TenantConfig
+
+	// mu serializes quota checks with the charges they admit, so concurrent publishes can't exceed a quota together
+	mu sync.Mutex
+	// usage is the usage of each tenant, loaded from the ledger on first use
+	usage map[string]*pkarr.Usage
+	// pending are the charges of records being published, by the key they are stored under, so a failed publish
+	// only reverts its charge if no later publish of the record was charged relative to it
+	pending map[string]*pkarr.TenantRecord
+}
+
+func newTenants(cfg config.TenantConfig, ledger storage.TenantLedger) (*tenants, error) {
+	t := tenants{
+		ledger:  ledger,
+		byKey:   make(map[[32]byte]config.Tenant, len(cfg.Tenants)),
+		byName:  make(map[string]config.Tenant, len(cfg.Tenants)),
+		cfg:     cfg,
+		pending: make(map[string]*pkarr.TenantRecord),
+	}
+	for _, tenant := range cfg.Tenants {
+		if tenant.Name == "" || len(tenant.Name) > 64 {
+			return nil, errors.New("tenant names must be 1 to 64 characters")
+		}
+		if tenant.APIKey == "" {
+			return nil, fmt.Errorf("tenant %s has no api key", tenant.Name)
+		}
+		hash := sha256.Sum256([]byte(tenant.APIKey))
+		if _, ok := t.byKey[hash]; ok {
+			return nil, fmt.Errorf("tenant %s shares its api key with another tenant", tenant.Name)
+		}
+		if _, ok := t.byName[tenant.Name]; ok {
+			return nil, fmt.Errorf("tenant %s is configured more than once", tenant.Name)
+		}
+		t.byKey[hash] = tenant
+		t.byName[tenant.Name] = tenant
+	}
+	return &t, nil
+}
+
+// limits returns the tenant with the limits of the config in place of those it doesn't set
+func (t *tenants) limits(name string) config.Tenant {
+	tenant := t.byName[name]
+	if tenant.SoftMaxRecords == 0 {
+		tenant.SoftMaxRecords = t.cfg.SoftMaxRecords
+	}
+	if tenant.HardMaxRecords == 0 {
+		tenant.HardMaxRecords = t.cfg.HardMaxRecords
+	}
+	if tenant.SoftMaxBytes == 0 {
+		tenant.SoftMaxBytes = t.cfg.SoftMaxBytes
+	}
+	if tenant.HardMaxBytes == 0 {
+		tenant.HardMaxBytes = t.cfg.HardMaxBytes
+	}
+	return tenant
+}
+
+// load sums the usage of each tenant from the ledger, if not yet loaded. It must be called holding the lock.
+func (t *tenants) load(ctx context.Context) error {
+	if t.usage != nil {
+		return nil
+	}
+	records, err := t.ledger.ListTenantRecords(ctx)
+	if err != nil {
+		return err
+	}
+	usage := make(map[string]*pkarr.Usage)
+	for _, record := range records {
+		tenantUsage, ok := usage[record.Tenant]
+		if !ok {
+			tenantUsage = new(pkarr.Usage)
+			usage[record.Tenant] = tenantUsage
+		}
+		tenantUsage.Records++
+		tenantUsage.Bytes += record.Bytes
+	}
+	t.usage = usage
+	return nil
+}
+
+// of returns the usage of the tenant. It must be called holding the lock, once loaded.
+func (t *tenants) of(name string) *pkarr.Usage {
+	usage, ok := t.usage[name]
+	if !ok {
+		usage = new(pkarr.Usage)
+		t.usage[name] = usage
+	}
+	return usage
+}
+
+// charge counts the record stored under the given key, with a DNS packet of the given size, against the usage of the
+// tenant publishing it, unless it takes the tenant beyond a hard quota. The record no longer counts against the tenant
+// which published it before, if another. The record is attributed to the tenant in the ledger along with the charge,
+// so a concurrent publish of the same record is charged relative to it rather than counted twice. It returns the soft
+// quotas the tenant is beyond, and a func settling the charge once the publish succeeded or failed, which reverts it
+// if the publish failed.
+func (t *tenants) charge(ctx context.Context, name, key string, bytes int64) ([]string, func(published bool), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(ctx); err != nil {
+		return nil, nil, err
+	}
+	prev, err := t.ledger.ReadTenantRecord(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	delta := pkarr.Usage{Records: 1, Bytes: bytes}
+	if prev != nil && prev.Tenant == name {
+		delta = pkarr.Usage{Bytes: bytes - prev.Bytes}
+	}
+	usage, limits := t.of(name), t.limits(name)
+	if delta.Records > 0 && limits.HardMaxRecords > 0 && usage.Records+delta.Records > limits.HardMaxRecords {
+		return nil, nil, ErrQuotaExceeded
+	}
+	if delta.Bytes > 0 && limits.HardMaxBytes > 0 && usage.Bytes+delta.Bytes > limits.HardMaxBytes {
+		return nil, nil, ErrQuotaExceeded
+	}
+	record := &pkarr.TenantRecord{Key: key, Tenant: name, Bytes: bytes, Timestamp: time.Now().Unix()}
+	if err = t.ledger.WriteTenantRecord(ctx, *record); err != nil {
+		return nil, nil, err
+	}
+	t.apply(name, delta, prev)
+	t.pending[key] = record
+	settle := func(published bool) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		// a later charge of the record was made relative to this one, which it settles instead
+		if t.pending[key] != record {
+			return
+		}
+		delete(t.pending, key)
+		if published {
+			return
+		}
+		t.apply(name, pkarr.Usage{Records: -delta.Records, Bytes: -delta.Bytes}, nil)
+		if prev != nil && prev.Tenant != name {
+			t.apply(prev.Tenant, pkarr.Usage{Records: 1, Bytes: prev.Bytes}, nil)
+		}
+		var err error
+		if prev != nil {
+			err = t.ledger.WriteTenantRecord(context.Background(), *prev)
+		} else {
+			err = t.ledger.DeleteTenantRecord(context.Background(), key)
+		}
+		if err != nil {
+			// the usage is reverted all the same, and loaded from the ledger as it is when the gateway restarts
+			logrus.WithError(err).Errorf("failed to revert the attribution of pkarr record[%s] to tenant[%s]", key, name)
+		}
+	}
+	return exceeded(*usage, limits), settle, nil
+}
+
+// apply adds the delta to the usage of the tenant, and takes the record attributed to prev from its tenant, if
+// another. It must be called holding the lock, once loaded.
+func (t *tenants) apply(name string, delta pkarr.Usage, prev *pkarr.TenantRecord) {
+	usage := t.of(name)
+	usage.Records += delta.Records
+	usage.Bytes += delta.Bytes
+	if prev != nil && prev.Tenant != name {
+		prevUsage := t.of(prev.Tenant)
+		prevUsage.Records--
+		prevUsage.Bytes -= prev.Bytes
+	}
+}
+
+// release stops counting the record stored under the given key against the tenant which published it, if any
+func (t *tenants) release(ctx context.Context, key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(ctx); err != nil {
+		return err
+	}
+	prev, err := t.ledger.ReadTenantRecord(ctx, key)
+	if err != nil || prev == nil {
+		return err
+	}
+	if err = t.ledger.DeleteTenantRecord(ctx, key); err != nil {
+		return err
+	}
+	t.apply(prev.Tenant, pkarr.Usage{Records: -1, Bytes: -prev.Bytes}, nil)
+	return nil
+}
+
+// exceeded returns the soft quotas the usage is beyond
+func exceeded(usage pkarr.Usage, limits config.Tenant) []string {
+	var quotas []string
+	if limits.SoftMaxRecords > 0 && usage.Records > limits.SoftMaxRecords {
+		quotas = append(quotas, QuotaRecords)
+	}
+	if limits.SoftMaxBytes > 0 && usage.Bytes > limits.SoftMaxBytes {
+		quotas = append(quotas, QuotaBytes)
+	}
+	return quotas
+}
+
+// Tenant returns the name of the tenant with the given API key, or false if no tenant has it
+func (s *PkarrService) Tenant(apiKey string) (string, bool) {
+	if s.tenants == nil {
+		return "", false
+	}
+	tenant, ok := s.tenants.byKey[sha256.Sum256([]byte(apiKey))]
+	return tenant.Name, ok
+}
+
+// PublishTenantPkarr publishes the record like PublishPkarr, counting it against the quotas of the given tenant, if
+// any. It returns ErrQuotaExceeded if the record would take the tenant beyond a hard quota, and the soft quotas the
+// tenant is beyond otherwise.
+func (s *PkarrService) PublishTenantPkarr(ctx context.Context, tenant, id string, request PublishPkarrRequest) ([]string, error) {
+	return s.publishAsTenant(ctx, tenant, request, func() error {
+		return s.PublishPkarr(ctx, id, request)
+	})
+}
+
+// publishAsTenant runs publish, which publishes the record of the request, counting the record against the quotas of
+// the given tenant, if any. The record is charged and attributed to the tenant before it is published, and the charge
+// reverted if publish fails.
+func (s *PkarrService) publishAsTenant(ctx context.Context, tenant string, request PublishPkarrRequest, publish func() error) ([]string, error) {
+	if s.tenants == nil || tenant == "" {
+		return nil, publish()
+	}
+	warnings, settle, err := s.tenants.charge(ctx, tenant, request.toRecord().Key(), int64(len(request.V)))
+	if err != nil {
+		return nil, err
+	}
+	err = publish()
+	settle(err == nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(warnings) > 0 {
+		logrus.WithField("tenant", tenant).Warnf("tenant is beyond its soft quota of %v", warnings)
+	}
+	return warnings, nil
+}
+
+// releaseTenantRecord stops counting the deleted record stored under the given key against its tenant
+func (s *PkarrService) releaseTenantRecord(ctx context.Context, key string) {
+	if s.tenants == nil {
+		return
+	}
+	if err := s.tenants.release(ctx, key); err != nil {
+		logrus.WithError(err).Warnf("failed to release pkarr record[%s] from its tenant", key)
+	}
+}
+
+// TenantUsage is the storage used by a tenant, relative to its quotas
+type TenantUsage struct {
+	Tenant string `json:"tenant"`
+	pkarr.Usage
+	// SoftMaxRecords, HardMaxRecords, SoftMaxBytes, and HardMaxBytes are the quotas of the tenant, unlimited if zero
+	SoftMaxRecords int64 `json:"softMaxRecords"`
+	HardMaxRecords int64 `json:"hardMaxRecords"`
+	SoftMaxBytes   int64 `json:"softMaxBytes"`
+	HardMaxBytes   int64 `json:"hardMaxBytes"`
+	// Exceeded are the soft quotas the tenant is beyond, records or bytes
+	Exceeded []string `json:"exceeded,omitempty"`
+}
+
+// GetTenantUsage returns the storage used by the given tenant, relative to its quotas
+func (s *PkarrService) GetTenantUsage(ctx context.Context, tenant string) (*TenantUsage, error) {
+	if s.tenants == nil {
+		return nil, errors.New("tenants are not configured")
+	}
+	s.tenants.mu.Lock()
+	defer s.tenants.mu.Unlock()
+	if err := s.tenants.load(ctx); err != nil {
+		return nil, err
+	}
+	usage, limits := *s.tenants.of(tenant), s.tenants.limits(tenant)
+	return &TenantUsage{
+		Tenant:         tenant,
+		Usage:          usage,
+		SoftMaxRecords: limits.SoftMaxRecords,
+		HardMaxRecords: limits.HardMaxRecords,
+		SoftMaxBytes:   limits.SoftMaxBytes,
+		HardMaxBytes:   limits.HardMaxBytes,
+		Exceeded:       exceeded(usage, limits),
+	}, nil
+}
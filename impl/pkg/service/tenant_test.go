@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestTenantQuotas(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.TenantConfig = config.TenantConfig{
+		Tenants: []config.Tenant{
+			{Name: "acme", APIKey: "acme-key", SoftMaxRecords: 1},
+			{Name: "globex", APIKey: "globex-key", HardMaxBytes: 1000},
+		},
+		HardMaxRecords: 2,
+	}
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "tenants.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+	require.NoError(t, err)
+
+	tenant, ok := svc.Tenant("acme-key")
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenant)
+	_, ok = svc.Tenant("acme")
+	assert.False(t, ok)
+
+	type key struct {
+		id   string
+		sign func(v string, seq int64) PublishPkarrRequest
+	}
+	newKey := func() key {
+		pubKey, privKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		return key{id: util.Z32Encode(pubKey), sign: func(v string, seq int64) PublishPkarrRequest {
+			put := bep44.Put{V: []byte(v), K: (*[32]byte)(pubKey), Seq: seq}
+			put.Sign(privKey)
+			return PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+		}}
+	}
+	ctx := context.Background()
+	seq := time.Now().Unix()
+	alice, bob, carol := newKey(), newKey(), newKey()
+
+	warnings, err := svc.PublishTenantPkarr(ctx, "acme", alice.id, alice.sign("hello", seq))
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	// republishing a record doesn't count it again
+	warnings, err = svc.PublishTenantPkarr(ctx, "acme", alice.id, alice.sign("hello tenants", seq+1))
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	warnings, err = svc.PublishTenantPkarr(ctx, "acme", bob.id, bob.sign("hello", seq))
+	require.NoError(t, err)
+	assert.Equal(t, []string{QuotaRecords}, warnings, "beyond the soft quota")
+	_, err = svc.PublishTenantPkarr(ctx, "acme", carol.id, carol.sign("hello", seq))
+	assert.ErrorIs(t, err, ErrQuotaExceeded, "beyond the default hard quota")
+	stored, err := svc.GetPkarr(ctx, carol.id)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	usage, err := svc.GetTenantUsage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Records)
+	assert.Equal(t, int64(len("hello tenants")+len("hello")), usage.Bytes)
+	assert.Equal(t, int64(1), usage.SoftMaxRecords)
+	assert.Equal(t, int64(2), usage.HardMaxRecords)
+	assert.Equal(t, []string{QuotaRecords}, usage.Exceeded)
+
+	t.Run("failed publishes aren't counted", func(t *testing.T) {
+		_, err := svc.PublishTenantPkarr(ctx, "globex", bob.id, bob.sign("stale", seq-1))
+		assert.ErrorIs(t, err, ErrStaleSeq)
+		usage, err := svc.GetTenantUsage(ctx, "globex")
+		require.NoError(t, err)
+		assert.Zero(t, usage.Records)
+		usage, err = svc.GetTenantUsage(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), usage.Records)
+	})
+
+	t.Run("records move to the tenant publishing them", func(t *testing.T) {
+		_, err := svc.PublishTenantPkarr(ctx, "globex", bob.id, bob.sign("hello globex", seq+1))
+		require.NoError(t, err)
+		usage, err := svc.GetTenantUsage(ctx, "globex")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), usage.Records)
+		assert.Equal(t, int64(len("hello globex")), usage.Bytes)
+		usage, err = svc.GetTenantUsage(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), usage.Records)
+		assert.Empty(t, usage.Exceeded)
+	})
+
+	t.Run("deleted records are released", func(t *testing.T) {
+		deleted, err := svc.DeletePkarr(ctx, alice.id, nil)
+		require.NoError(t, err)
+		assert.True(t, deleted)
+		usage, err := svc.GetTenantUsage(ctx, "acme")
+		require.NoError(t, err)
+		assert.Zero(t, usage.Records)
+		assert.Zero(t, usage.Bytes)
+	})
+
+	t.Run("usage is loaded from storage", func(t *testing.T) {
+		restarted, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+		require.NoError(t, err)
+		usage, err := restarted.GetTenantUsage(ctx, "globex")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), usage.Records)
+		assert.Equal(t, int64(len("hello globex")), usage.Bytes)
+	})
+
+	t.Run("concurrent first publishes are counted once", func(t *testing.T) {
+		svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+		require.NoError(t, err)
+		// every publish is held before it is stored until all of them are charged, or those rejected are given up on
+		const publishes = 8
+		var charged sync.WaitGroup
+		charged.Add(publishes)
+		allCharged := make(chan struct{})
+		go func() {
+			charged.Wait()
+			close(allCharged)
+		}()
+		svc.RegisterPublishInterceptor(PublishInterceptorFunc(func(context.Context, string, PublishPkarrRequest) error {
+			charged.Done()
+			select {
+			case <-allCharged:
+			case <-time.After(time.Second):
+			}
+			return nil
+		}))
+		dave := newKey()
+		var wg sync.WaitGroup
+		for i := 0; i < publishes; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = svc.PublishTenantPkarr(ctx, "acme", dave.id, dave.sign("hello dave", seq))
+			}()
+		}
+		wg.Wait()
+		usage, err := svc.GetTenantUsage(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), usage.Records)
+		assert.Equal(t, int64(len("hello dave")), usage.Bytes)
+
+		restarted, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+		require.NoError(t, err)
+		usage, err = restarted.GetTenantUsage(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), usage.Records)
+	})
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
+
+	intutil "github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	// TransferBundleContentType is the content type (cty) of the JWS by which the owner of a key signs a transfer
+	// bundle of its record
+	TransferBundleContentType = "did-dht-transfer+json"
+
+	// transferBundleValidity is how long after it was exported a transfer bundle may be imported, so a bundle can't be
+	// replayed to restore a record its owner later deleted
+	transferBundleValidity = 24 * time.Hour
+)
+
+var (
+	// ErrInvalidTransfer is returned for transfer bundles which aren't signed by the key of their ID, have expired, or
+	// hold records of another key or salt
+	ErrInvalidTransfer = errors.New("invalid transfer bundle")
+)
+
+// TransferBundle is a record with the versions of it a gateway retains, exported so its owner can move it to another
+// gateway. The owner signs it as a JWS with the TransferBundleContentType, by which the other gateway imports it.
+type TransferBundle struct {
+	// ID is the z-base-32 encoded ID of the record
+	ID string `json:"id"`
+	// Salt is the base64url encoded salt of the record, if any
+	Salt string `json:"salt,omitempty"`
+	// Record is the current record
+	Record pkarr.Record `json:"record"`
+	// Versions are the retained versions of the record, in ascending order of seq, if the gateway keeps them
+	Versions []pkarr.Record `json:"versions,omitempty"`
+	// Source is the base URL of the gateway the record was exported from
+	Source string `json:"source,omitempty"`
+	// Exported is the unix time in seconds the record was exported at
+	Exported int64 `json:"exported"`
+}
+
+// TransferResult is the outcome of importing a transfer bundle
+type TransferResult struct {
+	// ID is the z-base-32 encoded ID of the record
+	ID string `json:"id"`
+	// Seq is the seq of the current record
+	Seq int64 `json:"seq"`
+	// Versions is the number of versions of the record stored
+	Versions int `json:"versions"`
+	// Skipped is the number of versions not stored because the gateway already stored a newer record
+	Skipped int `json:"skipped"`
+}
+
+// SignTransferBundle returns the compact JWS of the bundle signed with the private key of its ID, by which the key's
+// owner moves its record to another gateway
+func SignTransferBundle(key ed25519.PrivateKey, bundle TransferBundle) (string, error) {
+	payload, err := json.Marshal(bundle)
+	if err != nil {
+		return "", err
+	}
+	return signJWS(key, payload, TransferBundleContentType)
+}
+
+// ExportTransfer returns the transfer bundle of the stored record for the given z-base-32 encoded ID and optional
+// salt, with its retained versions, for its owner to sign and import to another gateway. It returns nil if no record
+// is stored.
+func (s *PkarrService) ExportTransfer(ctx context.Context, id string, salt []byte) (*TransferBundle, error) {
+	key, err := saltedRecordKey(id, salt)
+	if err != nil {
+		return nil, err
+	}
+	record, err := s.db.ReadRecord(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, nil
+	}
+	versions, err := s.db.ListRecordVersions(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Seq < versions[j].Seq })
+	return &TransferBundle{
+		ID:       id,
+		Salt:     base64.RawURLEncoding.EncodeToString(salt),
+		Record:   *record,
+		Versions: versions,
+		Source:   s.cfg.ServerConfig.BaseURL,
+		Exported: time.Now().Unix(),
+	}, nil
+}
+
+// ImportTransfer imports the record and versions of a transfer bundle signed by the key of its ID as a compact JWS,
+// on behalf of the given tenant, if any. The versions are stored in ascending order of seq, each validated like a
+// published record, so the gateway retains the record's history. The current record is then published like
+// PublishTenantPkarr does, counted against the tenant's quotas, journaled, and put to the DHT, and the gateway
+// republishes it from then on. Versions older than a record the gateway already stores are skipped. It returns the
+// soft quotas the tenant is beyond.
+func (s *PkarrService) ImportTransfer(ctx context.Context, tenant, signed string) (*TransferResult, []string, error) {
+	if !s.cfg.ServerConfig.Role.Publishes() {
+		return nil, nil, ErrReadOnly
+	}
+	bundle, err := verifyTransferBundle(signed, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the versions include the current record, if the gateway keeps them, which is stored last
+	var records []pkarr.Record
+	for _, version := range bundle.Versions {
+		if version.Seq < bundle.Record.Seq {
+			records = append(records, version)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Seq < records[j].Seq })
+	records = append(records, bundle.Record)
+	requests := make([]PublishPkarrRequest, 0, len(records))
+	for _, record := range records {
+		request, err := recordToPublishRequest(record)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidTransfer, err)
+		}
+		if err = request.matchesID(bundle.ID); err != nil || record.Salt != bundle.Salt {
+			return nil, nil, fmt.Errorf("%w: holds a record of another key or salt", ErrInvalidTransfer)
+		}
+		if err = request.isValid(); err != nil {
+			return nil, nil, err
+		}
+		requests = append(requests, *request)
+	}
+
+	// the current record is checked like a published record before any version is stored, as versions may be of any age
+	current := requests[len(requests)-1]
+	if err = s.checkSeq(current.Seq); err != nil {
+		return nil, nil, err
+	}
+	result := TransferResult{ID: bundle.ID, Seq: bundle.Record.Seq}
+	warnings, err := s.publishAsTenant(ctx, tenant, current, func() error {
+		return s.storeTransfer(ctx, bundle.ID, requests, &result)
+	})
+	if errors.Is(err, ErrStaleSeq) {
+		result.Skipped++
+	} else if err != nil {
+		return nil, nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"audit":    "transfer",
+		"action":   "import",
+		"id":       bundle.ID,
+		"source":   bundle.Source,
+		"versions": result.Versions,
+	}).Info("record transferred from another gateway by its owner")
+	return &result, warnings, nil
+}
+
+// storeTransfer stores the versions of a transfer bundle, then publishes its current record, the last of the requests.
+// It returns ErrStaleSeq if the gateway already stores a newer record than the current record.
+func (s *PkarrService) storeTransfer(ctx context.Context, id string, requests []PublishPkarrRequest, result *TransferResult) error {
+	if !s.drain.begin() {
+		return ErrDraining
+	}
+	for _, request := range requests[:len(requests)-1] {
+		err := s.storePkarr(ctx, id, request)
+		switch {
+		case err == nil:
+			result.Versions++
+		case errors.Is(err, ErrStaleSeq):
+			result.Skipped++
+		default:
+			s.drain.done()
+			return err
+		}
+	}
+	s.drain.done()
+
+	if err := s.PublishPkarr(ctx, id, requests[len(requests)-1]); err != nil {
+		return err
+	}
+	result.Versions++
+	return nil
+}
+
+// verifyTransferBundle returns the transfer bundle of the compact JWS if it is signed by the key of the bundle's ID,
+// and was exported within the validity of bundles before the given time
+func verifyTransferBundle(signed string, now time.Time) (*TransferBundle, error) {
+	parts := strings.Split(signed, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: jws is not in compact form", ErrInvalidTransfer)
+	}
+	// the bundle names the key it must be signed by, so it is read before verifying its signature
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransfer, err)
+	}
+	var bundle TransferBundle
+	if err = json.Unmarshal(payload, &bundle); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransfer, err)
+	}
+	key, err := intutil.Z32Decode(bundle.ID)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: id is not a z-base-32 encoded ed25519 public key", ErrInvalidTransfer)
+	}
+	if _, err = VerifyJWS(signed, key, TransferBundleContentType); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransfer, err)
+	}
+	exported := time.Unix(bundle.Exported, 0)
+	if now.Sub(exported) > transferBundleValidity || exported.After(now.Add(time.Minute)) {
+		return nil, fmt.Errorf("%w: exported at %s, outside the %s it may be imported within", ErrInvalidTransfer,
+			exported.UTC().Format(time.RFC3339), transferBundleValidity)
+	}
+	return &bundle, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestTransfer(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	sign := func(seq int64) PublishPkarrRequest {
+		put := bep44.Put{V: []byte("hello transfer"), K: (*[32]byte)(pubKey), Seq: seq}
+		put.Sign(privKey)
+		return PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+	}
+	newService := func(name string, tenants config.TenantConfig) (*PkarrService, storage.Storage, *flakyDHT) {
+		cfg := config.GetDefaultConfig()
+		cfg.PkarrConfig.RepublishCRON = ""
+		cfg.TenantConfig = tenants
+		db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), name))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		d := new(flakyDHT)
+		svc, err := NewPkarrServiceWith(&cfg, db, d, cache.None{})
+		require.NoError(t, err)
+		return svc, db, d
+	}
+
+	ctx := context.Background()
+	source, _, _ := newService("source.db", config.TenantConfig{})
+	now := time.Now().Unix()
+	for _, seq := range []int64{now - 20, now - 10, now} {
+		require.NoError(t, source.storePkarr(ctx, id, sign(seq)))
+	}
+	missing, err := source.ExportTransfer(ctx, util.Z32Encode(make([]byte, 32)), nil)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+	bundle, err := source.ExportTransfer(ctx, id, nil)
+	require.NoError(t, err)
+	require.NotNil(t, bundle)
+	assert.Equal(t, now, bundle.Record.Seq)
+	assert.Len(t, bundle.Versions, 3)
+
+	t.Run("bundle must be signed by the key of its id", func(t *testing.T) {
+		target, _, _ := newService("forged.db", config.TenantConfig{})
+		_, otherKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		forged, err := SignTransferBundle(otherKey, *bundle)
+		require.NoError(t, err)
+		_, _, err = target.ImportTransfer(ctx, "", forged)
+		assert.ErrorIs(t, err, ErrInvalidTransfer)
+
+		// a JWS of the owner's for another purpose isn't a bundle
+		payload, err := json.Marshal(bundle)
+		require.NoError(t, err)
+		consent, err := signJWS(privKey, payload, SeqResetConsentContentType)
+		require.NoError(t, err)
+		_, _, err = target.ImportTransfer(ctx, "", consent)
+		assert.ErrorIs(t, err, ErrInvalidTransfer)
+	})
+
+	t.Run("bundle expires", func(t *testing.T) {
+		target, _, _ := newService("expired.db", config.TenantConfig{})
+		expired := *bundle
+		expired.Exported = time.Now().Add(-2 * transferBundleValidity).Unix()
+		signed, err := SignTransferBundle(privKey, expired)
+		require.NoError(t, err)
+		_, _, err = target.ImportTransfer(ctx, "", signed)
+		assert.ErrorIs(t, err, ErrInvalidTransfer)
+	})
+
+	t.Run("bundle must hold records of its id", func(t *testing.T) {
+		target, _, _ := newService("other.db", config.TenantConfig{})
+		otherPub, otherPriv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		other := *bundle
+		other.ID = util.Z32Encode(otherPub)
+		signed, err := SignTransferBundle(otherPriv, other)
+		require.NoError(t, err)
+		_, _, err = target.ImportTransfer(ctx, "", signed)
+		assert.ErrorIs(t, err, ErrInvalidTransfer)
+	})
+
+	signed, err := SignTransferBundle(privKey, *bundle)
+	require.NoError(t, err)
+
+	t.Run("draining gateways don't import", func(t *testing.T) {
+		target, db, _ := newService("draining.db", config.TenantConfig{})
+		target.Drain()
+		_, _, err := target.ImportTransfer(ctx, "", signed)
+		assert.ErrorIs(t, err, ErrDraining)
+		record, err := db.ReadRecord(ctx, bundle.Record.Key())
+		require.NoError(t, err)
+		assert.Nil(t, record)
+	})
+
+	t.Run("imports count against the tenant", func(t *testing.T) {
+		target, db, _ := newService("tenant.db", config.TenantConfig{
+			Tenants: []config.Tenant{{Name: "acme", APIKey: "acme-key", HardMaxRecords: 1}},
+		})
+		otherPub, otherPriv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		put := bep44.Put{V: []byte("hello tenant"), K: (*[32]byte)(otherPub), Seq: now}
+		put.Sign(otherPriv)
+		otherID := util.Z32Encode(otherPub)
+		_, err = target.PublishTenantPkarr(ctx, "acme", otherID,
+			PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq})
+		require.NoError(t, err)
+
+		// none of the versions are stored beyond the hard quota
+		_, _, err = target.ImportTransfer(ctx, "acme", signed)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		record, err := db.ReadRecord(ctx, bundle.Record.Key())
+		require.NoError(t, err)
+		assert.Nil(t, record)
+
+		_, err = target.DeletePkarr(ctx, otherID, nil)
+		require.NoError(t, err)
+		result, _, err := target.ImportTransfer(ctx, "acme", signed)
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Versions)
+		usage, err := target.GetTenantUsage(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), usage.Records)
+	})
+
+	target, db, d := newService("target.db", config.TenantConfig{})
+	require.NoError(t, target.storePkarr(ctx, id, sign(now-15)))
+	result, _, err := target.ImportTransfer(ctx, "", signed)
+	require.NoError(t, err)
+	// the oldest version is older than the record the target already stored
+	assert.Equal(t, TransferResult{ID: id, Seq: now, Versions: 2, Skipped: 1}, *result)
+	// only the current record is put to the DHT
+	assert.Eventually(t, func() bool { return d.puts.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	got, err := target.GetPkarr(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, now, got.Seq)
+	key, err := saltedRecordKey(id, nil)
+	require.NoError(t, err)
+	versions, err := db.ListRecordVersions(ctx, key)
+	require.NoError(t, err)
+	assert.Len(t, versions, 3)
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+const (
+	// defaultCacheMinTTL is the shortest adaptive TTL, if not configured
+	defaultCacheMinTTL = time.Minute
+	// defaultCacheMaxTTL is the longest adaptive TTL, if not configured
+	defaultCacheMaxTTL = 6 * time.Hour
+)
+
+// cachedRecord is a record as cached, with the statistics of how often it changes adaptive TTLs are derived from.
+// It is cached as the JSON of its GetPkarrResponse with extra fields, so entries remain readable either way.
+type cachedRecord struct {
+	GetPkarrResponse
+	// Expires is the unix time the record is resolved again after, if adaptive or record TTLs are enabled
+	Expires int64 `json:"expires,omitempty"`
+	// Changed is the unix time the record was last observed with a new seq
+	Changed int64 `json:"changed,omitempty"`
+	// Interval is the moving average of the seconds between observed changes, zero until a change is observed
+	Interval int64 `json:"interval,omitempty"`
+	// Cached is the unix time the record was cached
+	Cached int64 `json:"cached,omitempty"`
+}
+
+// Freshness is how long a resolved record may be served from HTTP caches in front of the gateway: for its TTL after
+// the gateway cached it, so they don't serve it for longer than the gateway itself would
+type Freshness struct {
+	// Cached is the time the gateway resolved the record from the DHT or storage and cached it
+	Cached time.Time
+	TTL    time.Duration
+}
+
+// adaptiveTTL derives the TTL of each cached record from how often it is observed to change, so records which change
+// often are resolved again sooner, and stable records are resolved from the DHT less often
+type adaptiveTTL struct {
+	min time.Duration
+	max time.Duration
+	// initial is the TTL of records not yet observed to change or stay unchanged
+	initial time.Duration
+}
+
+func newAdaptiveTTL(cfg config.PKARRServiceConfig) *adaptiveTTL {
+	initial := time.Duration(cfg.CacheTTLSeconds) * time.Second
+	minTTL := time.Duration(cfg.CacheMinTTLSeconds) * time.Second
+	if minTTL <= 0 {
+		minTTL = defaultCacheMinTTL
+	}
+	maxTTL := time.Duration(cfg.CacheMaxTTLSeconds) * time.Second
+	if maxTTL <= 0 {
+		maxTTL = defaultCacheMaxTTL
+	}
+	return &adaptiveTTL{min: min(minTTL, initial), max: max(maxTTL, initial), initial: initial}
+}
+
+// recordTTL caches each record for the smallest TTL of the DNS records in its packet, as its publisher intends,
+// bounded by the minimum and maximum TTLs
+type recordTTL struct {
+	min time.Duration
+	max time.Duration
+	// initial is the TTL of records whose packets have no DNS records
+	initial time.Duration
+}
+
+func newRecordTTL(cfg config.PKARRServiceConfig) *recordTTL {
+	bounds := newAdaptiveTTL(cfg)
+	return &recordTTL{min: bounds.min, max: bounds.max, initial: bounds.initial}
+}
+
+// ttl returns the smallest TTL of the DNS records in the packet, within the bounds, and false if the packet has no
+// records or can't be parsed
+func (r *recordTTL) ttl(v []byte) (time.Duration, bool) {
+	var msg dns.Msg
+	if err := msg.Unpack(v); err != nil {
+		return 0, false
+	}
+	var smallest uint32
+	found := false
+	for _, section := range [][]dns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+			if ttl := rr.Header().Ttl; !found || ttl < smallest {
+				smallest, found = ttl, true
+			}
+		}
+	}
+	if !found {
+		return 0, false
+	}
+	return min(max(time.Duration(smallest)*time.Second, r.min), r.max), true
+}
+
+// expires returns the unix time the entry caching the record at the given time expires: its record TTL, or its
+// adaptive TTL if sooner, or the configured TTL if the packet has no records and adaptive TTLs are disabled
+func (r *recordTTL) expires(entry cachedRecord, now time.Time) int64 {
+	ttl, ok := r.ttl(entry.V)
+	if !ok {
+		if entry.Expires > 0 {
+			return entry.Expires
+		}
+		return now.Add(r.initial).Unix()
+	}
+	expires := now.Add(ttl).Unix()
+	if entry.Expires > 0 {
+		return min(entry.Expires, expires)
+	}
+	return expires
+}
+
+// cacheTTL returns how long the cache should keep entries: the longest adaptive or record TTL if enabled, so entries
+// aren't evicted before they expire, or the configured TTL otherwise
+func cacheTTL(cfg config.PKARRServiceConfig) time.Duration {
+	if cfg.AdaptiveCacheTTL || cfg.RecordCacheTTL {
+		return newAdaptiveTTL(cfg).max
+	}
+	return time.Duration(cfg.CacheTTLSeconds) * time.Second
+}
+
+// next returns the entry caching the resolved record at the given time, given the previously cached entry for it, if
+// any. A record is expected to stay unchanged for its average interval between changes, or for as long as it has
+// already been unchanged if longer, so the TTL grows while a record stays unchanged.
+func (a *adaptiveTTL) next(prev *cachedRecord, resp GetPkarrResponse, now time.Time) cachedRecord {
+	entry := cachedRecord{GetPkarrResponse: resp, Changed: now.Unix()}
+	if prev != nil && prev.Changed > 0 {
+		entry.Interval = prev.Interval
+		if prev.Seq == resp.Seq {
+			entry.Changed = prev.Changed
+		} else if observed := now.Unix() - prev.Changed; entry.Interval == 0 {
+			entry.Interval = observed
+		} else {
+			entry.Interval = (entry.Interval + observed) / 2
+		}
+	}
+	expected := a.initial
+	if entry.Interval > 0 {
+		expected = time.Duration(entry.Interval) * time.Second
+	}
+	ttl := max(expected, now.Sub(time.Unix(entry.Changed, 0)))
+	ttl = min(max(ttl, a.min), a.max)
+	entry.Expires = now.Add(ttl).Unix()
+	return entry
+}
+
+// expired returns whether the cached entry should be resolved again
+func (a *adaptiveTTL) expired(entry cachedRecord, now time.Time) bool {
+	return entry.expired(now)
+}
+
+// expired returns whether the entry is past its adaptive or record TTL, if it has one, and should be resolved again
+func (c cachedRecord) expired(now time.Time) bool {
+	return c.Expires > 0 && now.Unix() >= c.Expires
+}
+
+// getCachedRecord returns the cached entry for the given cache key, or nil if it isn't cached. Corrupted entries,
+// which can't be decoded or hold no signed record, are evicted and counted, and reported as not cached, so the record
+// is resolved from the DHT or storage instead.
+func (s *PkarrService) getCachedRecord(ctx context.Context, key string) (*cachedRecord, error) {
+	got, err := s.cache.Get(ctx, key)
+	if err != nil || got == nil {
+		return nil, err
+	}
+	var entry cachedRecord
+	if err = json.Unmarshal(got, &entry); err == nil && entry.Sig == ([64]byte{}) {
+		err = errUnsignedCacheEntry
+	}
+	if err != nil {
+		s.cacheChecks.corrupted.Add(1)
+		logrus.WithError(err).Warnf("evicting corrupted pkarr record[%s] from cache", key)
+		if err = s.cache.Delete(ctx, key); err != nil {
+			logrus.WithError(err).Errorf("failed to evict corrupted pkarr record[%s] from cache", key)
+		}
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// cacheRecord caches the record under the given cache key, with an adaptive TTL derived from prev, the previously
+// cached entry for the record, and the TTL of its DNS records, if enabled, returning the entry cached
+func (s *PkarrService) cacheRecord(ctx context.Context, key string, resp GetPkarrResponse, prev *cachedRecord) (cachedRecord, error) {
+	now := time.Now()
+	entry := cachedRecord{GetPkarrResponse: resp}
+	if s.adaptiveTTL != nil {
+		entry = s.adaptiveTTL.next(prev, resp, now)
+	}
+	if s.recordTTL != nil {
+		entry.Expires = s.recordTTL.expires(entry, now)
+	}
+	entry.Cached = now.Unix()
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return entry, err
+	}
+	return entry, s.cache.Set(ctx, key, entryBytes)
+}
+
+// freshness returns how long the cached entry may be served from HTTP caches: from when it was cached, for its
+// adaptive or record TTL if enabled, or the configured TTL otherwise. Entries cached before the time was kept count as cached
+// now.
+func (s *PkarrService) freshness(entry cachedRecord, now time.Time) *Freshness {
+	cached := now
+	if entry.Cached > 0 {
+		cached = time.Unix(entry.Cached, 0)
+	}
+	ttl := time.Duration(s.cfg.PkarrConfig.CacheTTLSeconds) * time.Second
+	if entry.Expires > 0 {
+		ttl = time.Unix(entry.Expires, 0).Sub(cached)
+	}
+	return &Freshness{Cached: cached, TTL: max(ttl, 0)}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+)
+
+func TestAdaptiveTTL(t *testing.T) {
+	cfg := config.GetDefaultConfig().PkarrConfig
+	ttl := newAdaptiveTTL(cfg)
+	assert.Equal(t, time.Minute, ttl.min)
+	assert.Equal(t, 6*time.Hour, ttl.max)
+	assert.Equal(t, 6*time.Hour, cacheTTL(config.PKARRServiceConfig{AdaptiveCacheTTL: true, CacheTTLSeconds: 600}))
+	assert.Equal(t, 10*time.Minute, cacheTTL(cfg))
+
+	start := time.Unix(1_700_000_000, 0)
+	resp := func(seq int64) GetPkarrResponse {
+		return GetPkarrResponse{V: []byte("hello pkarr"), Seq: seq}
+	}
+	expiresIn := func(entry cachedRecord, now time.Time) time.Duration {
+		return time.Unix(entry.Expires, 0).Sub(now)
+	}
+
+	t.Run("new records use the configured ttl", func(t *testing.T) {
+		entry := ttl.next(nil, resp(1), start)
+		assert.Equal(t, 10*time.Minute, expiresIn(entry, start))
+		assert.Equal(t, start.Unix(), entry.Changed)
+		assert.False(t, ttl.expired(entry, start.Add(time.Minute)))
+		assert.True(t, ttl.expired(entry, start.Add(10*time.Minute)))
+	})
+
+	t.Run("unchanged records grow their ttl", func(t *testing.T) {
+		entry := ttl.next(nil, resp(1), start)
+		now := start
+		for i := 0; i < 10; i++ {
+			previousTTL := expiresIn(entry, now)
+			now = time.Unix(entry.Expires, 0)
+			entry = ttl.next(&entry, resp(1), now)
+			assert.GreaterOrEqual(t, expiresIn(entry, now), previousTTL)
+		}
+		assert.Equal(t, 6*time.Hour, expiresIn(entry, now))
+		assert.Equal(t, start.Unix(), entry.Changed)
+		assert.Zero(t, entry.Interval)
+	})
+
+	t.Run("changing records shorten their ttl", func(t *testing.T) {
+		entry := ttl.next(nil, resp(1), start)
+		now := start
+		for seq := int64(2); seq < 10; seq++ {
+			now = now.Add(2 * time.Minute)
+			entry = ttl.next(&entry, resp(seq), now)
+		}
+		assert.Equal(t, int64(120), entry.Interval)
+		assert.Equal(t, 2*time.Minute, expiresIn(entry, now))
+
+		// the ttl never drops below the minimum
+		now = now.Add(time.Second)
+		entry = ttl.next(&entry, resp(10), now)
+		entry = ttl.next(&entry, resp(11), now)
+		assert.Equal(t, time.Minute, expiresIn(entry, now))
+	})
+
+	t.Run("entries without an expiry don't expire", func(t *testing.T) {
+		assert.False(t, ttl.expired(cachedRecord{GetPkarrResponse: resp(1)}, start))
+	})
+}
+
+func TestRecordTTL(t *testing.T) {
+	cfg := config.GetDefaultConfig().PkarrConfig
+	cfg.RecordCacheTTL = true
+	assert.Equal(t, 6*time.Hour, cacheTTL(cfg))
+	ttl := newRecordTTL(cfg)
+	now := time.Unix(1_700_000_000, 0)
+
+	packet := func(ttls ...uint32) []byte {
+		msg := dns.Msg{MsgHdr: dns.MsgHdr{Response: true, Authoritative: true}}
+		for _, ttl := range ttls {
+			msg.Answer = append(msg.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: "_k0._did.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl},
+				Txt: []string{"id=0;t=0;k=key"},
+			})
+		}
+		v, err := msg.Pack()
+		require.NoError(t, err)
+		return v
+	}
+	expiresIn := func(entry cachedRecord) time.Duration {
+		return time.Unix(ttl.expires(entry, now), 0).Sub(now)
+	}
+	entry := func(v []byte) cachedRecord {
+		return cachedRecord{GetPkarrResponse: GetPkarrResponse{V: v, Seq: 1}}
+	}
+
+	t.Run("the smallest record ttl", func(t *testing.T) {
+		assert.Equal(t, 30*time.Minute, expiresIn(entry(packet(7200, 1800, 3600))))
+	})
+
+	t.Run("bounded by the configured ttls", func(t *testing.T) {
+		assert.Equal(t, time.Minute, expiresIn(entry(packet(5))))
+		assert.Equal(t, 6*time.Hour, expiresIn(entry(packet(86400))))
+	})
+
+	t.Run("packets without records use the configured ttl", func(t *testing.T) {
+		assert.Equal(t, 10*time.Minute, expiresIn(entry(packet())))
+		assert.Equal(t, 10*time.Minute, expiresIn(entry([]byte("not a dns packet"))))
+	})
+
+	t.Run("the shorter of the adaptive and record ttl", func(t *testing.T) {
+		adaptive := entry(packet(1800))
+		adaptive.Expires = now.Add(5 * time.Minute).Unix()
+		assert.Equal(t, 5*time.Minute, expiresIn(adaptive))
+		adaptive.Expires = now.Add(time.Hour).Unix()
+		assert.Equal(t, 30*time.Minute, expiresIn(adaptive))
+	})
+}
+
+func TestFreshness(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	svc := PkarrService{cfg: &cfg}
+	now := time.Unix(1_700_000_000, 0)
+
+	t.Run("configured ttl", func(t *testing.T) {
+		freshness := svc.freshness(cachedRecord{Cached: now.Unix() - 60}, now)
+		assert.Equal(t, now.Add(-time.Minute), freshness.Cached)
+		assert.Equal(t, 10*time.Minute, freshness.TTL)
+	})
+
+	t.Run("adaptive ttl", func(t *testing.T) {
+		freshness := svc.freshness(cachedRecord{Cached: now.Unix() - 60, Expires: now.Unix() + 3600}, now)
+		assert.Equal(t, 61*time.Minute, freshness.TTL)
+	})
+
+	t.Run("entries cached before their time was kept", func(t *testing.T) {
+		freshness := svc.freshness(cachedRecord{}, now)
+		assert.Equal(t, now, freshness.Cached)
+	})
+}
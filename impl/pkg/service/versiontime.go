@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GetSaltedPkarrAt resolves the version of the record for the given z-base-32 encoded ID and optional salt which was
+// current at the given time, with the semantics of the versionTime DID resolution option: the stored version with the
+// highest seq not after that time, seqs being unix timestamps in seconds as the spec recommends. Only versions stored
+// by the gateway are considered, and nil is returned if the record had no stored version yet at that time.
+func (s *PkarrService) GetSaltedPkarrAt(ctx context.Context, id string, salt []byte, at time.Time) (*GetPkarrResponse, error) {
+	if s.isDenied(id) {
+		logrus.Debugf("refusing to resolve denied pkarr record[%s]", id)
+		return nil, nil
+	}
+	storageKey, err := saltedRecordKey(id, salt)
+	if err != nil {
+		logrus.WithError(err).Debugf("pkarr record[%s] has an invalid id", cacheKey(id, salt))
+		return nil, nil
+	}
+	versions, err := s.db.ListRecordVersions(ctx, storageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// versions are ordered by seq
+	found := -1
+	for i, version := range versions {
+		if version.Seq > at.Unix() {
+			break
+		}
+		found = i
+	}
+	if found < 0 {
+		return nil, nil
+	}
+	return fromPkarrRecord(versions[found])
+}
+
+// GetSaltedPkarrVersion returns the stored version of the record for the given z-base-32 encoded ID and optional salt
+// with the given seq, or nil if the gateway doesn't store that version. A version is signed over its seq and value, so
+// it doesn't change once stored unless its key equivocates, signing another value with the same seq.
+func (s *PkarrService) GetSaltedPkarrVersion(ctx context.Context, id string, salt []byte, seq int64) (*GetPkarrResponse, error) {
+	if s.isDenied(id) {
+		logrus.Debugf("refusing to resolve denied pkarr record[%s]", id)
+		return nil, nil
+	}
+	storageKey, err := saltedRecordKey(id, salt)
+	if err != nil {
+		logrus.WithError(err).Debugf("pkarr record[%s] has an invalid id", cacheKey(id, salt))
+		return nil, nil
+	}
+	record, err := s.db.ReadRecordVersion(ctx, storageKey, seq)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	return fromPkarrRecord(*record)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestGetSaltedPkarrAt(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "versiontime.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, staticDHT{}, cache.None{})
+	require.NoError(t, err)
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	first := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+	for _, at := range []time.Time{first, second} {
+		put := bep44.Put{V: []byte("hello " + at.Format(time.DateOnly)), K: (*[32]byte)(pubKey), Seq: at.Unix()}
+		put.Sign(privKey)
+		request := PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+		require.NoError(t, svc.storePkarr(context.Background(), id, request))
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		seq  int64
+	}{
+		{name: "before the first version", at: first.Add(-time.Second)},
+		{name: "at the first version", at: first, seq: first.Unix()},
+		{name: "between versions", at: first.Add(time.Hour), seq: first.Unix()},
+		{name: "after the last version", at: second.Add(time.Hour), seq: second.Unix()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := svc.GetSaltedPkarrAt(context.Background(), id, nil, test.at)
+			require.NoError(t, err)
+			if test.seq == 0 {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, test.seq, got.Seq)
+		})
+	}
+
+	t.Run("version", func(t *testing.T) {
+		got, err := svc.GetSaltedPkarrVersion(context.Background(), id, nil, first.Unix())
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, []byte("hello 2024-05-01"), got.V)
+
+		got, err = svc.GetSaltedPkarrVersion(context.Background(), id, nil, first.Unix()+1)
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+}
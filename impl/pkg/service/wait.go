@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// waiters notifies requests waiting for records to be published to the gateway
+type waiters struct {
+	mu sync.Mutex
+	// chans are closed once a record is stored under their cache key
+	chans map[string][]chan struct{}
+}
+
+func newWaiters() *waiters {
+	return &waiters{chans: make(map[string][]chan struct{})}
+}
+
+// subscribe returns a channel which is closed once a record is stored under the given cache key, and a function to
+// call once no longer waiting
+func (w *waiters) subscribe(key string) (<-chan struct{}, func()) {
+	ch := make(chan struct{})
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.chans[key] = append(w.chans[key], ch)
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		chans := w.chans[key]
+		for i, c := range chans {
+			if c == ch {
+				chans = append(chans[:i], chans[i+1:]...)
+				break
+			}
+		}
+		if len(chans) == 0 {
+			delete(w.chans, key)
+		} else {
+			w.chans[key] = chans
+		}
+	}
+}
+
+// notify wakes the requests waiting for a record under the given cache key
+func (w *waiters) notify(key string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, ch := range w.chans[key] {
+		close(ch)
+	}
+	delete(w.chans, key)
+}
+
+// WaitForSaltedPkarr is GetSaltedPkarr, waiting up to the given duration, capped by the configured maximum, for a
+// record which isn't found yet to be published. It returns as soon as the record is published to this gateway, and
+// rechecks the DHT and storage periodically for records published elsewhere, returning nil if the wait times out.
+func (s *PkarrService) WaitForSaltedPkarr(ctx context.Context, id string, salt []byte, wait time.Duration) (*GetPkarrResponse, error) {
+	if maxWait := time.Duration(s.cfg.PkarrConfig.MaxWaitSeconds) * time.Second; wait > maxWait {
+		wait = maxWait
+	}
+	if wait <= 0 || s.waiters == nil {
+		return s.GetSaltedPkarr(ctx, id, salt)
+	}
+
+	// subscribe before the first lookup so a record published in between isn't missed
+	published, unsubscribe := s.waiters.subscribe(cacheKey(id, salt))
+	defer unsubscribe()
+	resp, err := s.GetSaltedPkarr(ctx, id, salt)
+	if err != nil || resp != nil {
+		return resp, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	interval := time.Duration(s.cfg.PkarrConfig.WaitRecheckSeconds) * time.Second
+	if interval <= 0 {
+		interval = wait
+	}
+	recheck := time.NewTicker(interval)
+	defer recheck.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-published:
+			return s.GetSaltedPkarr(ctx, id, salt)
+		case <-recheck.C:
+			resp, err = s.GetSaltedPkarr(ctx, id, salt)
+			if ctx.Err() != nil {
+				return nil, nil
+			}
+			if err != nil || resp != nil {
+				return resp, err
+			}
+		}
+	}
+}
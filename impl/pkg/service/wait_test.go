@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/cache"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+func TestWaitForSaltedPkarr(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.PkarrConfig.MaxWaitSeconds = 5
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "wait.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	svc, err := NewPkarrServiceWith(&cfg, db, new(countingDHT), cache.None{})
+	require.NoError(t, err)
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := util.Z32Encode(pubKey)
+	put := bep44.Put{V: []byte("hello pkarr"), K: (*[32]byte)(pubKey), Seq: 1}
+	put.Sign(privKey)
+
+	t.Run("times out", func(t *testing.T) {
+		start := time.Now()
+		got, err := svc.WaitForSaltedPkarr(context.Background(), id, nil, 100*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("returns once published", func(t *testing.T) {
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			request := PublishPkarrRequest{V: put.V.([]byte), K: *put.K, Sig: put.Sig, Seq: put.Seq}
+			assert.NoError(t, svc.storePkarr(context.Background(), id, request))
+		}()
+		start := time.Now()
+		got, err := svc.WaitForSaltedPkarr(context.Background(), id, nil, time.Minute)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, put.V, got.V)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("waiters are removed", func(t *testing.T) {
+		assert.Empty(t, svc.waiters.chans)
+	})
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/goccy/go-json"
+	"github.com/miekg/dns"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+// GetDIDWebDocument resolves the did:dht record mapped to the given domain and returns its DID Document
+// as a did:web document for the domain, with only the given fields if any. A nil document is returned if the domain
+// or record is unknown.
+func (s *PkarrService) GetDIDWebDocument(ctx context.Context, domain string, fields ...did.DocumentField) (*didsdk.Document, error) {
+	id, ok := s.cfg.DIDWebConfig.Domains[domain]
+	if !ok {
+		return nil, nil
+	}
+	record, err := s.GetPkarr(ctx, id)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	msg := new(dns.Msg)
+	if err = msg.Unpack(record.V); err != nil {
+		return nil, err
+	}
+	doc, _, err := did.DHT(did.Prefix+":"+id).FromDNSPacketFields(msg, fields...)
+	if err != nil {
+		return nil, err
+	}
+	webDoc, err := toDIDWebDocument(*doc, domain)
+	if err != nil {
+		return nil, err
+	}
+	// the did:dht identifier is only listed as an alternative identifier if requested
+	if len(fields) > 0 && !slices.Contains(fields, did.FieldAlsoKnownAs) {
+		webDoc.AlsoKnownAs = nil
+	}
+	return webDoc, nil
+}
+
+// toDIDWebDocument rewrites a did:dht document as a did:web document for the given domain, keeping the did:dht
+// identifier as an alternative identifier so the same identity can be resolved via both methods
+func toDIDWebDocument(doc didsdk.Document, domain string) (*didsdk.Document, error) {
+	dhtID := doc.ID
+	webID := "did:web:" + strings.ReplaceAll(domain, ":", "%3A")
+
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	// the did:dht identifier only appears in the document as a complete DID or DID URL, so it can be safely replaced
+	docBytes = []byte(strings.ReplaceAll(string(docBytes), `"`+dhtID, `"`+webID))
+	var webDoc didsdk.Document
+	if err = json.Unmarshal(docBytes, &webDoc); err != nil {
+		return nil, err
+	}
+
+	aka := []string{dhtID}
+	switch a := doc.AlsoKnownAs.(type) {
+	case string:
+		aka = append(aka, a)
+	case []string:
+		aka = append(aka, a...)
+	}
+	webDoc.AlsoKnownAs = aka
+	return &webDoc, nil
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+func TestToDIDWebDocument(t *testing.T) {
+	_, doc, err := did.GenerateDIDDHT(did.CreateDIDDHTOpts{AlsoKnownAs: []string{"did:example:123"}})
+	require.NoError(t, err)
+
+	webDoc, err := toDIDWebDocument(*doc, "example.com:8305")
+	require.NoError(t, err)
+
+	webID := "did:web:example.com%3A8305"
+	assert.Equal(t, webID, webDoc.ID)
+	assert.Equal(t, []string{doc.ID, "did:example:123"}, webDoc.AlsoKnownAs)
+	require.Len(t, webDoc.VerificationMethod, 1)
+	assert.Equal(t, webID+"#0", webDoc.VerificationMethod[0].ID)
+	assert.Equal(t, webID, webDoc.VerificationMethod[0].Controller)
+	assert.Equal(t, doc.VerificationMethod[0].PublicKeyJWK.X, webDoc.VerificationMethod[0].PublicKeyJWK.X)
+	assert.EqualValues(t, webID+"#0", webDoc.Authentication[0])
+}
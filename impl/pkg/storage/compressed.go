@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/DataDog/zstd"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// compressedPrefix starts compressed values, and can't start an uncompressed value since ':' isn't in the base64URL
+// alphabet, so each record is flagged as compressed or not by its own value
+const compressedPrefix = "zstd:"
+
+// Compressed is a Storage which compresses the values of records at rest with zstd, if enabled, and decompresses
+// compressed values on read either way, so records written while compression was enabled are still readable once it
+// isn't. Values which don't shrink are stored uncompressed.
+type Compressed struct {
+	Storage
+	enabled bool
+}
+
+// WithCompression wraps the storage to compress record values if enabled. It must wrap any Encrypted storage, as
+// encrypted values don't compress.
+func WithCompression(db Storage, enabled bool) Storage {
+	return NewCompressed(db, enabled)
+}
+
+// NewCompressed wraps the storage to decompress record values, and compress those it writes if enabled
+func NewCompressed(db Storage, enabled bool) *Compressed {
+	return &Compressed{Storage: db, enabled: enabled}
+}
+
+// Unwrap returns the underlying storage
+func (c *Compressed) Unwrap() Storage {
+	return c.Storage
+}
+
+// Enabled returns whether the values of written records are compressed
+func (c *Compressed) Enabled() bool {
+	return c.enabled
+}
+
+func (c *Compressed) WriteRecord(ctx context.Context, record pkarr.Record) error {
+	v, err := c.compress(record.V)
+	if err != nil {
+		return err
+	}
+	record.V = v
+	return c.Storage.WriteRecord(ctx, record)
+}
+
+func (c *Compressed) WriteRecordIfNewer(ctx context.Context, record pkarr.Record) (*pkarr.Record, bool, error) {
+	v, err := c.compress(record.V)
+	if err != nil {
+		return nil, false, err
+	}
+	record.V = v
+	current, written, err := WriteRecordIfNewer(ctx, c.Storage, record)
+	if err != nil || current == nil {
+		return current, written, err
+	}
+	return current, written, decompressRecord(current)
+}
+
+func (c *Compressed) ReadRecord(ctx context.Context, id string) (*pkarr.Record, error) {
+	record, err := c.Storage.ReadRecord(ctx, id)
+	if err != nil || record == nil {
+		return record, err
+	}
+	return record, decompressRecord(record)
+}
+
+func (c *Compressed) ListRecords(ctx context.Context) ([]pkarr.Record, error) {
+	records, err := c.Storage.ListRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return records, decompressRecords(records)
+}
+
+func (c *Compressed) ReadRecordVersion(ctx context.Context, id string, seq int64) (*pkarr.Record, error) {
+	record, err := c.Storage.ReadRecordVersion(ctx, id, seq)
+	if err != nil || record == nil {
+		return record, err
+	}
+	return record, decompressRecord(record)
+}
+
+func (c *Compressed) ListRecordVersions(ctx context.Context, id string) ([]pkarr.Record, error) {
+	records, err := c.Storage.ListRecordVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return records, decompressRecords(records)
+}
+
+func decompressRecords(records []pkarr.Record) error {
+	for i := range records {
+		if err := decompressRecord(&records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decompressRecord(record *pkarr.Record) error {
+	v, err := decompress(record.V)
+	if err != nil {
+		return fmt.Errorf("failed to decompress record[%s]: %w", record.Key(), err)
+	}
+	record.V = v
+	return nil
+}
+
+// compress returns the value as zstd:<base64URL encoded compressed value> if compression is enabled and it is shorter
+// than the value, or the value itself otherwise
+func (c *Compressed) compress(v string) (string, error) {
+	if !c.enabled {
+		return v, nil
+	}
+	compressed, err := zstd.Compress(nil, []byte(v))
+	if err != nil {
+		return "", err
+	}
+	if len(compressedPrefix)+base64.RawURLEncoding.EncodedLen(len(compressed)) >= len(v) {
+		return v, nil
+	}
+	return compressedPrefix + base64.RawURLEncoding.EncodeToString(compressed), nil
+}
+
+// decompress returns the value a compressed value was compressed from, or the value itself if it isn't compressed
+func decompress(v string) (string, error) {
+	if !strings.HasPrefix(v, compressedPrefix) {
+		return v, nil
+	}
+	compressed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(v, compressedPrefix))
+	if err != nil {
+		return "", err
+	}
+	decompressed, err := zstd.Decompress(nil, compressed)
+	if err != nil {
+		return "", err
+	}
+	return string(decompressed), nil
+}
+
+// CompressRecords compresses the values of the stored records which aren't, such as those written before compression
+// was enabled, returning the number of records compressed. Each record is rewritten unless a newer one was written
+// meanwhile, which is compressed already, so it is safe to run while records are written. Only the current version of
+// each record is compressed; earlier versions are kept as they were written.
+func CompressRecords(ctx context.Context, c *Compressed) (int, error) {
+	if !c.enabled {
+		return 0, nil
+	}
+	records, err := c.Storage.ListRecords(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var compressed int
+	for _, record := range records {
+		if err = ctx.Err(); err != nil {
+			return compressed, err
+		}
+		if strings.HasPrefix(record.V, compressedPrefix) {
+			continue
+		}
+		v, err := c.compress(record.V)
+		if err != nil {
+			return compressed, fmt.Errorf("failed to compress record[%s]: %w", record.Key(), err)
+		}
+		if v == record.V {
+			// it doesn't shrink
+			continue
+		}
+		record.V = v
+		if _, written, err := WriteRecordIfNewer(ctx, c.Storage, record); err != nil {
+			return compressed, fmt.Errorf("failed to write compressed record[%s]: %w", record.Key(), err)
+		} else if written {
+			compressed++
+		}
+	}
+	return compressed, nil
+}
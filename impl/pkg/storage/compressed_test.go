@@ -0,0 +1,92 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func TestCompressedStorage(t *testing.T) {
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "compressed.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	encrypted, err := storage.NewEncrypted(db, [][]byte{bytes.Repeat([]byte{1}, 32)})
+	require.NoError(t, err)
+	compressed := storage.NewCompressed(encrypted, true)
+
+	ctx := context.Background()
+	encoding := base64.RawURLEncoding
+	repetitive := encoding.EncodeToString(bytes.Repeat([]byte("_did.example. TXT id=0;t=0;k=abc "), 20))
+	old := pkarr.Record{V: repetitive, K: encoding.EncodeToString(bytes.Repeat([]byte{2}, 32)), Sig: "sig", Seq: 1}
+	large := pkarr.Record{V: repetitive, K: encoding.EncodeToString(bytes.Repeat([]byte{3}, 32)), Sig: "sig", Seq: 1}
+	small := pkarr.Record{V: encoding.EncodeToString([]byte("x")), K: encoding.EncodeToString(bytes.Repeat([]byte{4}, 32)), Sig: "sig", Seq: 1}
+
+	// records written before compression was enabled remain readable
+	require.NoError(t, encrypted.WriteRecord(ctx, old))
+	require.NoError(t, compressed.WriteRecord(ctx, large))
+	require.NoError(t, compressed.WriteRecord(ctx, small))
+
+	// compression happens before encryption
+	stored, err := encrypted.ReadRecord(ctx, large.K)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.V, "zstd:"))
+	assert.Less(t, len(stored.V), len(large.V))
+	stored, err = encrypted.ReadRecord(ctx, small.K)
+	require.NoError(t, err)
+	assert.Equal(t, small.V, stored.V, "values which don't shrink are stored uncompressed")
+
+	records, err := compressed.ListRecords(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []pkarr.Record{old, large, small}, records)
+	got, err := compressed.ReadRecordVersion(ctx, large.K, 1)
+	require.NoError(t, err)
+	assert.Equal(t, large, *got)
+
+	t.Run("existing records are compressed", func(t *testing.T) {
+		n, err := storage.CompressRecords(ctx, compressed)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		stored, err := encrypted.ReadRecord(ctx, old.K)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(stored.V, "zstd:"))
+		got, err := compressed.ReadRecord(ctx, old.K)
+		require.NoError(t, err)
+		assert.Equal(t, old, *got)
+
+		n, err = storage.CompressRecords(ctx, compressed)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("compressed records are read once compression is disabled", func(t *testing.T) {
+		disabled := storage.NewCompressed(encrypted, false)
+		updated := large
+		updated.Seq = 2
+		require.NoError(t, disabled.WriteRecord(ctx, updated))
+		stored, err := encrypted.ReadRecord(ctx, large.K)
+		require.NoError(t, err)
+		assert.Equal(t, updated.V, stored.V)
+
+		got, err := disabled.ReadRecordVersion(ctx, large.K, 1)
+		require.NoError(t, err)
+		assert.Equal(t, large, *got)
+		got, err = disabled.ReadRecord(ctx, old.K)
+		require.NoError(t, err)
+		assert.Equal(t, old, *got)
+	})
+
+	t.Run("encryption is found through compression", func(t *testing.T) {
+		_, ok := storage.As[*storage.Encrypted](compressed)
+		assert.True(t, ok)
+	})
+}
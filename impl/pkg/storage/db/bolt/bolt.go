@@ -3,20 +3,24 @@ package bolt
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
-	"github.com/TBD54566975/did-dht-method/pkg/storage/pkarr"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	bolt "go.etcd.io/bbolt"
 )
 
 const (
-	pkarrNamespace = "pkarr"
+	// pkarrNamespace is the single records bucket of earlier versions, migrated into shards on start
+	pkarrNamespace         = "pkarr"
+	pkarrVersionsNamespace = "pkarr_versions"
 )
 
 type boltdb struct {
-	db *bolt.DB
+	db *swappableDB
 }
 
 // NewBolt creates a BoltDB-based implementation of storage.Storage
@@ -29,22 +33,124 @@ func NewBolt(path string) (*boltdb, error) {
 		return nil, err
 	}
 
-	return &boltdb{db: db}, nil
+	s := &boltdb{db: &swappableDB{DB: db}}
+	if err = s.migrateShards(); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "migrating records into shards")
+	}
+	return s, nil
 }
 
-// WriteRecord writes the given record to the storage
-// TODO: don't overwrite existing records, store unique seq numbers
+// WriteRecord writes the given record to the storage, keeping a copy of each unique seq in the version history, in one
+// transaction so the record is never stored without its version
 func (s *boltdb) WriteRecord(_ context.Context, record pkarr.Record) error {
 	recordBytes, err := json.Marshal(record)
 	if err != nil {
 		return err
 	}
-	return s.write(pkarrNamespace, record.K, recordBytes)
+	return s.db.Update(func(tx *bolt.Tx) error {
+		shard, err := createRecordShard(tx, record.Key())
+		if err != nil {
+			return err
+		}
+		versions, err := tx.CreateBucketIfNotExists([]byte(pkarrVersionsNamespace))
+		if err != nil {
+			return err
+		}
+		if err = versions.Put([]byte(versionKey(record.Key(), record.Seq)), recordBytes); err != nil {
+			return err
+		}
+		return shard.Put([]byte(record.Key()), recordBytes)
+	})
+}
+
+// WriteRecordIfNewer writes the given record unless the stored record has a higher seq, in one transaction, which bolt
+// runs one at a time
+func (s *boltdb) WriteRecordIfNewer(_ context.Context, record pkarr.Record) (*pkarr.Record, bool, error) {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+	var current *pkarr.Record
+	written := false
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		shard, err := createRecordShard(tx, record.Key())
+		if err != nil {
+			return err
+		}
+		if currentBytes := shard.Get([]byte(record.Key())); len(currentBytes) > 0 {
+			current = new(pkarr.Record)
+			if err = json.Unmarshal(currentBytes, current); err != nil {
+				return err
+			}
+			if current.Seq > record.Seq {
+				return nil
+			}
+		}
+		versions, err := tx.CreateBucketIfNotExists([]byte(pkarrVersionsNamespace))
+		if err != nil {
+			return err
+		}
+		if err = versions.Put([]byte(versionKey(record.Key(), record.Seq)), recordBytes); err != nil {
+			return err
+		}
+		written = true
+		return shard.Put([]byte(record.Key()), recordBytes)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return current, written, nil
 }
 
 // ReadRecord reads the record with the given id from the storage
 func (s *boltdb) ReadRecord(_ context.Context, id string) (*pkarr.Record, error) {
-	recordBytes, err := s.read(pkarrNamespace, id)
+	var recordBytes []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if shard := recordShard(tx, id); shard != nil {
+			recordBytes = shard.Get([]byte(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(recordBytes) == 0 {
+		return nil, nil
+	}
+	var record pkarr.Record
+	if err = json.Unmarshal(recordBytes, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ListRecords lists all records in the storage, scanning its shards in parallel
+func (s *boltdb) ListRecords(ctx context.Context) ([]pkarr.Record, error) {
+	var records []pkarr.Record
+	err := s.scanRecords(ctx, func(record pkarr.Record) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// CountRecords returns the number of stored records
+func (s *boltdb) CountRecords(_ context.Context) (int64, error) {
+	var count int64
+	err := s.forEachShard(func(shard *bolt.Bucket) error {
+		count += int64(shard.Stats().KeyN)
+		return nil
+	})
+	return count, err
+}
+
+// ReadRecordVersion reads the version of the record with the given id and seq from the storage
+func (s *boltdb) ReadRecordVersion(_ context.Context, id string, seq int64) (*pkarr.Record, error) {
+	recordBytes, err := s.read(pkarrVersionsNamespace, versionKey(id, seq))
 	if err != nil {
 		return nil, err
 	}
@@ -58,14 +164,14 @@ func (s *boltdb) ReadRecord(_ context.Context, id string) (*pkarr.Record, error)
 	return &record, nil
 }
 
-// ListRecords lists all records in the storage
-func (s *boltdb) ListRecords(_ context.Context) ([]pkarr.Record, error) {
-	recordsMap, err := s.readAll(pkarrNamespace)
+// ListRecordVersions lists all stored versions of the record with the given id, ordered by seq
+func (s *boltdb) ListRecordVersions(_ context.Context, id string) ([]pkarr.Record, error) {
+	versions, err := s.readPrefix(pkarrVersionsNamespace, id+":")
 	if err != nil {
 		return nil, err
 	}
 	var records []pkarr.Record
-	for _, recordBytes := range recordsMap {
+	for _, recordBytes := range versions {
 		var record pkarr.Record
 		if err = json.Unmarshal(recordBytes, &record); err != nil {
 			return nil, err
@@ -106,6 +212,24 @@ func (s *boltdb) read(namespace, key string) ([]byte, error) {
 	return result, err
 }
 
+// readPrefix returns the values of all keys in the namespace starting with the given prefix, in key order
+func (s *boltdb) readPrefix(namespace, prefix string) ([][]byte, error) {
+	var result [][]byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(namespace))
+		if bucket == nil {
+			logrus.Infof("namespace[%s] does not exist", namespace)
+			return nil
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = cursor.Next() {
+			result = append(result, append([]byte(nil), v...))
+		}
+		return nil
+	})
+	return result, err
+}
+
 func (s *boltdb) readAll(namespace string) (map[string][]byte, error) {
 	result := make(map[string][]byte)
 	err := s.db.View(func(tx *bolt.Tx) error {
@@ -122,3 +246,8 @@ func (s *boltdb) readAll(namespace string) (map[string][]byte, error) {
 	})
 	return result, err
 }
+
+// versionKey builds a key for a record version which sorts lexicographically by seq
+func versionKey(id string, seq int64) string {
+	return fmt.Sprintf("%s:%020d", id, seq)
+}
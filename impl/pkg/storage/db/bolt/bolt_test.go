@@ -3,12 +3,15 @@ package bolt
 import (
 	"context"
 	"encoding/base64"
+	"fmt"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/TBD54566975/did-dht-method/internal/did"
-	"github.com/TBD54566975/did-dht-method/pkg/dht"
-	"github.com/TBD54566975/did-dht-method/pkg/storage/pkarr"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
 	"github.com/goccy/go-json"
 
 	"github.com/stretchr/testify/assert"
@@ -145,4 +148,533 @@ func TestPKARRStorage(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, records)
 	assert.Equal(t, record, records[0])
+
+	// write a newer version and confirm both versions are kept
+	newerRecord := record
+	newerRecord.Seq++
+	err = db.WriteRecord(ctx, newerRecord)
+	assert.NoError(t, err)
+
+	readRecord, err = db.ReadRecord(ctx, record.K)
+	assert.NoError(t, err)
+	assert.Equal(t, newerRecord, *readRecord)
+
+	readVersion, err := db.ReadRecordVersion(ctx, record.K, record.Seq)
+	assert.NoError(t, err)
+	assert.Equal(t, record, *readVersion)
+
+	missingVersion, err := db.ReadRecordVersion(ctx, record.K, record.Seq+2)
+	assert.NoError(t, err)
+	assert.Nil(t, missingVersion)
+
+	versions, err := db.ListRecordVersions(ctx, record.K)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.Record{record, newerRecord}, versions)
+
+	// a salted record of the same key is stored separately
+	saltedRecord := record
+	saltedRecord.Salt = encoding.EncodeToString([]byte("salt"))
+	err = db.WriteRecord(ctx, saltedRecord)
+	assert.NoError(t, err)
+
+	readRecord, err = db.ReadRecord(ctx, saltedRecord.Key())
+	assert.NoError(t, err)
+	assert.Equal(t, saltedRecord, *readRecord)
+
+	readRecord, err = db.ReadRecord(ctx, record.K)
+	assert.NoError(t, err)
+	assert.Equal(t, newerRecord, *readRecord)
+
+	versions, err = db.ListRecordVersions(ctx, record.K)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.Record{record, newerRecord}, versions)
+}
+
+func TestBoltDB_IndexDocuments(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	err := db.IndexDocument(ctx, pkarr.Document{
+		ID:           "alice",
+		ServiceTypes: []string{"DecentralizedWebNode"},
+		Types:        []string{"1"},
+	})
+	assert.NoError(t, err)
+	err = db.IndexDocument(ctx, pkarr.Document{
+		ID:           "bob",
+		ServiceTypes: []string{"DecentralizedWebNode", "LinkedDomains"},
+	})
+	assert.NoError(t, err)
+
+	ids, err := db.QueryDocuments(ctx, pkarr.DocumentQuery{Field: pkarr.ServiceTypeField, Value: "DecentralizedWebNode"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, ids)
+
+	ids, err = db.QueryDocuments(ctx, pkarr.DocumentQuery{Field: pkarr.TypeField, Value: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, ids)
+
+	// values of another field don't match
+	ids, err = db.QueryDocuments(ctx, pkarr.DocumentQuery{Field: pkarr.TypeField, Value: "DecentralizedWebNode"})
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// re-indexing replaces previously indexed values
+	err = db.IndexDocument(ctx, pkarr.Document{
+		ID:           "bob",
+		ServiceTypes: []string{"LinkedDomains"},
+	})
+	assert.NoError(t, err)
+
+	ids, err = db.QueryDocuments(ctx, pkarr.DocumentQuery{Field: pkarr.ServiceTypeField, Value: "DecentralizedWebNode"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, ids)
+
+	ids, err = db.QueryDocuments(ctx, pkarr.DocumentQuery{Field: pkarr.ServiceTypeField, Value: "LinkedDomains"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, ids)
+
+	// deleting removes all indexed values
+	assert.NoError(t, db.DeleteDocument(ctx, "bob"))
+	assert.NoError(t, db.DeleteDocument(ctx, "bob"), "deleting an unindexed document is a no-op")
+	ids, err = db.QueryDocuments(ctx, pkarr.DocumentQuery{Field: pkarr.ServiceTypeField, Value: "LinkedDomains"})
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestBoltDB_QueryDocumentsByPrefix(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	for _, doc := range []pkarr.Document{
+		{ID: "a", ServiceEndpoints: []string{"https://example.com/dwn", "https://example.com/hub"}},
+		{ID: "b", ServiceEndpoints: []string{"https://example.com/dwn"}},
+		{ID: "c", ServiceEndpoints: []string{"https://example.org/dwn"}},
+		{ID: "d", ServiceEndpoints: []string{"https://example.community/dwn"}},
+	} {
+		err := db.IndexDocument(ctx, doc)
+		assert.NoError(t, err)
+	}
+
+	query := pkarr.DocumentQuery{Field: pkarr.ServiceEndpointField, Value: "https://example.com/", Prefix: true}
+	ids, err := db.QueryDocuments(ctx, query)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, ids)
+
+	// page through the results
+	query.Limit = 1
+	ids, err = db.QueryDocuments(ctx, query)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, ids)
+
+	query.After = ids[0]
+	ids, err = db.QueryDocuments(ctx, query)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, ids)
+
+	query.After = ids[0]
+	ids, err = db.QueryDocuments(ctx, query)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestBoltDB_HistoryLog(t *testing.T) {
+	db := setupBoltDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	latest, err := db.ReadLatestHistoryEntry(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+
+	for i := int64(0); i < 12; i++ {
+		require.NoError(t, db.AppendHistoryEntry(ctx, pkarr.HistoryEntry{Index: i, ID: "id", Seq: i, Hash: fmt.Sprintf("hash%d", i)}))
+	}
+
+	// entries can't be overwritten
+	assert.Error(t, db.AppendHistoryEntry(ctx, pkarr.HistoryEntry{Index: 3}))
+
+	latest, err = db.ReadLatestHistoryEntry(ctx)
+	assert.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, int64(11), latest.Index)
+
+	entries, err := db.ListHistoryEntries(ctx, 9, 2)
+	assert.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "hash9", entries[0].Hash)
+	assert.Equal(t, "hash10", entries[1].Hash)
+
+	entries, err = db.ListHistoryEntries(ctx, 10, 100)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestBoltDB_Denylist(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	entries, err := db.ListDenylistEntries(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// deleting a missing entry is a no-op
+	assert.NoError(t, db.DeleteDenylistEntry(ctx, "bob"))
+
+	assert.NoError(t, db.WriteDenylistEntry(ctx, pkarr.DenylistEntry{ID: "bob", Reason: "spam", Timestamp: 1}))
+	assert.NoError(t, db.WriteDenylistEntry(ctx, pkarr.DenylistEntry{ID: "alice", Reason: "abuse", Timestamp: 2}))
+	assert.NoError(t, db.WriteDenylistEntry(ctx, pkarr.DenylistEntry{ID: "bob", Reason: "phishing", Timestamp: 3}))
+
+	entries, err = db.ListDenylistEntries(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.DenylistEntry{
+		{ID: "alice", Reason: "abuse", Timestamp: 2},
+		{ID: "bob", Reason: "phishing", Timestamp: 3},
+	}, entries)
+
+	assert.NoError(t, db.DeleteDenylistEntry(ctx, "alice"))
+	entries, err = db.ListDenylistEntries(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.DenylistEntry{{ID: "bob", Reason: "phishing", Timestamp: 3}}, entries)
+}
+
+func TestBoltDB_Labels(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	labeled, err := db.ListRecordLabels(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, labeled)
+
+	// deleting missing labels is a no-op
+	assert.NoError(t, db.DeleteRecordLabels(ctx, "bob"))
+
+	assert.NoError(t, db.WriteRecordLabels(ctx, pkarr.RecordLabels{Key: "bob", Labels: []string{"pinned"}, Timestamp: 1}))
+	assert.NoError(t, db.WriteRecordLabels(ctx, pkarr.RecordLabels{Key: "alice", Labels: []string{"customer:acme"}, Timestamp: 2}))
+	assert.NoError(t, db.WriteRecordLabels(ctx, pkarr.RecordLabels{Key: "bob", Labels: []string{"customer:acme", "pinned"}, Timestamp: 3}))
+
+	labeled, err = db.ListRecordLabels(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.RecordLabels{
+		{Key: "alice", Labels: []string{"customer:acme"}, Timestamp: 2},
+		{Key: "bob", Labels: []string{"customer:acme", "pinned"}, Timestamp: 3},
+	}, labeled)
+
+	assert.NoError(t, db.DeleteRecordLabels(ctx, "alice"))
+	labeled, err = db.ListRecordLabels(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.RecordLabels{{Key: "bob", Labels: []string{"customer:acme", "pinned"}, Timestamp: 3}}, labeled)
+}
+
+func TestBoltDB_Equivocations(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	equivocations, err := db.ListEquivocations(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, equivocations)
+
+	equivocation := pkarr.Equivocation{
+		ID:        "bob",
+		Seq:       1,
+		Stored:    pkarr.Record{K: "k", V: "a", Sig: "sig-a", Seq: 1},
+		Observed:  pkarr.Record{K: "k", V: "b", Sig: "sig-b", Seq: 1},
+		Source:    pkarr.EquivocationSourcePublish,
+		Timestamp: 1,
+	}
+	assert.NoError(t, db.WriteEquivocation(ctx, equivocation))
+	// the same pair of records observed the other way around is the same equivocation, and the first evidence is kept
+	reversed := equivocation
+	reversed.Stored, reversed.Observed, reversed.Timestamp = equivocation.Observed, equivocation.Stored, 2
+	assert.NoError(t, db.WriteEquivocation(ctx, reversed))
+
+	equivocations, err = db.ListEquivocations(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.Equivocation{equivocation}, equivocations)
+}
+
+func TestBoltDB_Feed(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	entries, err := db.ListFeedEntries(ctx, 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	for seq := int64(1); seq <= 3; seq++ {
+		cursor, err := db.AppendFeedEntry(ctx, pkarr.FeedEntry{ID: "alice", Seq: seq, Timestamp: seq})
+		assert.NoError(t, err)
+		assert.Equal(t, seq, cursor)
+	}
+
+	entries, err = db.ListFeedEntries(ctx, 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.FeedEntry{
+		{Cursor: 1, ID: "alice", Seq: 1, Timestamp: 1},
+		{Cursor: 2, ID: "alice", Seq: 2, Timestamp: 2},
+	}, entries)
+
+	entries, err = db.ListFeedEntries(ctx, 2, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.FeedEntry{{Cursor: 3, ID: "alice", Seq: 3, Timestamp: 3}}, entries)
+}
+
+func TestBoltDB_WriteRecordIfNewer(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	// concurrent writes of the same key leave the highest seq stored, whatever order they are applied in
+	var wg sync.WaitGroup
+	for seq := int64(1); seq <= 100; seq++ {
+		wg.Add(1)
+		go func(seq int64) {
+			defer wg.Done()
+			current, written, err := db.WriteRecordIfNewer(ctx, pkarr.Record{K: "key", V: "value", Sig: "sig", Seq: seq})
+			assert.NoError(t, err)
+			if current != nil {
+				assert.Equal(t, current.Seq <= seq, written)
+			}
+		}(seq)
+	}
+	wg.Wait()
+
+	record, err := db.ReadRecord(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), record.Seq)
+
+	current, written, err := db.WriteRecordIfNewer(ctx, pkarr.Record{K: "key", V: "stale", Sig: "sig", Seq: 99})
+	assert.NoError(t, err)
+	assert.False(t, written)
+	assert.Equal(t, int64(100), current.Seq)
+	version, err := db.ReadRecordVersion(ctx, "key", 100)
+	require.NoError(t, err)
+	assert.NotNil(t, version)
+}
+
+func TestBoltDB_Quota(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	usage, err := db.Usage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, pkarr.Usage{}, usage)
+
+	record := pkarr.Record{K: "key", V: "value", Sig: "sig", Seq: 1}
+	newerRecord := record
+	newerRecord.Seq++
+	saltedRecord := record
+	saltedRecord.Salt = "salt"
+	for _, r := range []pkarr.Record{record, newerRecord, saltedRecord} {
+		require.NoError(t, db.WriteRecord(ctx, r))
+	}
+
+	usage, err = db.Usage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Records)
+	assert.Greater(t, usage.Bytes, int64(0))
+
+	// only stored records are marked resolved
+	at := time.Unix(1700000000, 0)
+	assert.NoError(t, db.MarkResolved(ctx, record.Key(), at))
+	assert.NoError(t, db.MarkResolved(ctx, "missing", at))
+	resolved, err := db.ListResolved(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{record.Key(): at.Unix()}, resolved)
+
+	// deleting a record deletes its versions, but not those of a salted record of the same key
+	assert.NoError(t, db.DeleteRecord(ctx, record.Key()))
+	readRecord, err := db.ReadRecord(ctx, record.Key())
+	assert.NoError(t, err)
+	assert.Nil(t, readRecord)
+	versions, err := db.ListRecordVersions(ctx, record.Key())
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+	versions, err = db.ListRecordVersions(ctx, saltedRecord.Key())
+	assert.NoError(t, err)
+	assert.Len(t, versions, 1)
+	resolved, err = db.ListResolved(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, resolved)
+
+	remaining, err := db.Usage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), remaining.Records)
+	assert.Less(t, remaining.Bytes, usage.Bytes)
+}
+
+func TestBoltDB_Shards(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	// records of earlier versions, stored in a single bucket, are migrated into shards
+	encoding := base64.RawURLEncoding
+	var legacy []pkarr.Record
+	for i := 0; i < 3; i++ {
+		record := pkarr.Record{K: encoding.EncodeToString([]byte(fmt.Sprintf("legacy key %d", i))), V: "v", Sig: "sig", Seq: 1}
+		recordBytes, err := json.Marshal(record)
+		require.NoError(t, err)
+		require.NoError(t, db.write(pkarrNamespace, record.Key(), recordBytes))
+		legacy = append(legacy, record)
+	}
+	require.NoError(t, db.migrateShards())
+	legacyBucket, err := db.readAll(pkarrNamespace)
+	assert.NoError(t, err)
+	assert.Empty(t, legacyBucket)
+	for _, record := range legacy {
+		readRecord, err := db.ReadRecord(ctx, record.Key())
+		assert.NoError(t, err)
+		assert.Equal(t, record, *readRecord)
+	}
+
+	// records are listed from all shards
+	for i := 0; i < 200; i++ {
+		// keys spread over the shards by their first byte
+		record := pkarr.Record{K: encoding.EncodeToString([]byte{byte(i), 'k'}), V: "v", Sig: "sig", Seq: 1}
+		require.NoError(t, db.WriteRecord(ctx, record))
+	}
+	names, err := db.shardNames()
+	assert.NoError(t, err)
+	assert.Greater(t, len(names), 1)
+
+	records, err := db.ListRecords(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, records, 203)
+	keys := make(map[string]struct{}, len(records))
+	for _, record := range records {
+		keys[record.Key()] = struct{}{}
+	}
+	assert.Len(t, keys, 203)
+
+	count, err := db.CountRecords(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(203), count)
+}
+
+func TestBoltDB_Maintain(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	encoding := base64.RawURLEncoding
+	var records []pkarr.Record
+	for i := 0; i < 2000; i++ {
+		record := pkarr.Record{K: encoding.EncodeToString([]byte(fmt.Sprintf("key %d", i))), V: "value", Sig: "sig", Seq: 1}
+		require.NoError(t, db.WriteRecord(ctx, record))
+		records = append(records, record)
+	}
+	kept := records[0]
+	for _, record := range records[1:] {
+		require.NoError(t, db.DeleteRecord(ctx, record.Key()))
+	}
+
+	maintenance, err := db.Maintain(ctx)
+	require.NoError(t, err)
+	assert.Less(t, maintenance.BytesAfter, maintenance.BytesBefore)
+	assert.NotZero(t, maintenance.Finished)
+
+	// the compacted database is swapped in, keeping the records and taking writes
+	got, err := db.ReadRecord(ctx, kept.Key())
+	require.NoError(t, err)
+	assert.Equal(t, kept, *got)
+	newer := kept
+	newer.Seq++
+	require.NoError(t, db.WriteRecord(ctx, newer))
+	versions, err := db.ListRecordVersions(ctx, kept.Key())
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+}
+
+func TestBoltDB_PruneVersions(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	record := pkarr.Record{K: "key", V: "value", Sig: "sig"}
+	saltedRecord := record
+	saltedRecord.Salt = "salt"
+	for seq := int64(1); seq <= 3; seq++ {
+		record.Seq, saltedRecord.Seq = seq, seq
+		require.NoError(t, db.WriteRecord(ctx, record))
+		require.NoError(t, db.WriteRecord(ctx, saltedRecord))
+	}
+
+	seqs, err := db.ListVersionSeqs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]int64{record.Key(): {1, 2, 3}, saltedRecord.Key(): {1, 2, 3}}, seqs)
+
+	// only the versions of the record itself below the seq are deleted
+	deleted, err := db.DeleteRecordVersionsBefore(ctx, record.Key(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	seqs, err = db.ListVersionSeqs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]int64{record.Key(): {3}, saltedRecord.Key(): {1, 2, 3}}, seqs)
+
+	deleted, err = db.DeleteRecordVersionsBefore(ctx, record.Key(), 3)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	current, err := db.ReadRecord(ctx, record.Key())
+	require.NoError(t, err)
+	assert.Equal(t, &record, current)
+}
+
+func TestBoltDB_PutJournal(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	entries, err := db.ListJournalEntries(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	entry := pkarr.JournalEntry{Key: "key", Seq: 1, Timestamp: 1700000000}
+	require.NoError(t, db.WriteJournalEntry(ctx, entry))
+	replaced := entry
+	replaced.Seq = 2
+	require.NoError(t, db.WriteJournalEntry(ctx, replaced))
+	entries, err = db.ListJournalEntries(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []pkarr.JournalEntry{replaced}, entries)
+
+	// acknowledging the replaced put leaves the entry of the newer put
+	require.NoError(t, db.DeleteJournalEntry(ctx, entry.Key, entry.Seq))
+	entries, err = db.ListJournalEntries(ctx)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	require.NoError(t, db.DeleteJournalEntry(ctx, replaced.Key, replaced.Seq))
+	entries, err = db.ListJournalEntries(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestBoltDB_TenantLedger(t *testing.T) {
+	db := setupBoltDB(t)
+	ctx := context.Background()
+
+	records, err := db.ListTenantRecords(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+	record, err := db.ReadTenantRecord(ctx, "bob")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+
+	// deleting a missing attribution is a no-op
+	assert.NoError(t, db.DeleteTenantRecord(ctx, "bob"))
+
+	require.NoError(t, db.WriteTenantRecord(ctx, pkarr.TenantRecord{Key: "bob", Tenant: "acme", Bytes: 100, Timestamp: 1}))
+	require.NoError(t, db.WriteTenantRecord(ctx, pkarr.TenantRecord{Key: "alice", Tenant: "acme", Bytes: 200, Timestamp: 2}))
+	require.NoError(t, db.WriteTenantRecord(ctx, pkarr.TenantRecord{Key: "bob", Tenant: "globex", Bytes: 150, Timestamp: 3}))
+
+	record, err = db.ReadTenantRecord(ctx, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, &pkarr.TenantRecord{Key: "bob", Tenant: "globex", Bytes: 150, Timestamp: 3}, record)
+	records, err = db.ListTenantRecords(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []pkarr.TenantRecord{
+		{Key: "alice", Tenant: "acme", Bytes: 200, Timestamp: 2},
+		{Key: "bob", Tenant: "globex", Bytes: 150, Timestamp: 3},
+	}, records)
+
+	require.NoError(t, db.DeleteTenantRecord(ctx, "alice"))
+	records, err = db.ListTenantRecords(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
 }
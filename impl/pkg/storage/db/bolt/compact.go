@@ -0,0 +1,102 @@
+package bolt
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// compactTxMaxSize is the most bytes copied per transaction while compacting
+const compactTxMaxSize = 64 << 20
+
+// swappableDB is the bolt database, which compaction replaces with a compacted copy. Transactions hold a read lock,
+// so the database isn't swapped out from under them.
+type swappableDB struct {
+	mu sync.RWMutex
+	*bolt.DB
+}
+
+func (d *swappableDB) View(fn func(*bolt.Tx) error) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.DB.View(fn)
+}
+
+func (d *swappableDB) Update(fn func(*bolt.Tx) error) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.DB.Update(fn)
+}
+
+func (d *swappableDB) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.DB.Close()
+}
+
+// Maintain compacts the database into a fresh file, which then atomically replaces the database file, reclaiming the
+// pages freed by deleted records, which bolt keeps for reuse rather than shrinking the file. Reads and writes wait
+// until it is done.
+func (s *boltdb) Maintain(_ context.Context) (pkarr.Maintenance, error) {
+	m := pkarr.Maintenance{Started: time.Now().Unix()}
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	path := s.db.Path()
+	info, err := os.Stat(path)
+	if err != nil {
+		return m, err
+	}
+	m.BytesBefore = info.Size()
+
+	compactPath := path + ".compact"
+	if err = os.Remove(compactPath); err != nil && !os.IsNotExist(err) {
+		return m, err
+	}
+	compacted, err := bolt.Open(compactPath, 0600, &bolt.Options{Timeout: 3 * time.Second})
+	if err != nil {
+		return m, err
+	}
+	if err = bolt.Compact(compacted, s.db.DB, compactTxMaxSize); err != nil {
+		_ = compacted.Close()
+		_ = os.Remove(compactPath)
+		return m, errors.Wrap(err, "compacting database")
+	}
+	if err = compacted.Close(); err != nil {
+		_ = os.Remove(compactPath)
+		return m, err
+	}
+
+	// swap the compacted file in, reopening the database whether or not it was swapped
+	if err = s.db.DB.Close(); err != nil {
+		_ = os.Remove(compactPath)
+		return m, err
+	}
+	swapErr := os.Rename(compactPath, path)
+	if swapErr != nil {
+		_ = os.Remove(compactPath)
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 3 * time.Second})
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to reopen bolt database[%s] after compacting", path)
+		return m, errors.Wrap(err, "reopening database")
+	}
+	s.db.DB = db
+	if swapErr != nil {
+		return m, errors.Wrap(swapErr, "replacing database with compacted copy")
+	}
+
+	if info, err = os.Stat(path); err != nil {
+		return m, err
+	}
+	m.BytesAfter = info.Size()
+	m.Finished = time.Now().Unix()
+	return m, nil
+}
@@ -0,0 +1,49 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const denylistNamespace = "denylist"
+
+// WriteDenylistEntry adds the entry to the denylist, replacing any existing entry for its ID
+func (s *boltdb) WriteDenylistEntry(_ context.Context, entry pkarr.DenylistEntry) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.write(denylistNamespace, entry.ID, entryBytes)
+}
+
+// DeleteDenylistEntry removes the entry for the given ID from the denylist, if any
+func (s *boltdb) DeleteDenylistEntry(_ context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(denylistNamespace))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// ListDenylistEntries returns all entries of the denylist, ordered by ID
+func (s *boltdb) ListDenylistEntries(_ context.Context) ([]pkarr.DenylistEntry, error) {
+	values, err := s.readPrefix(denylistNamespace, "")
+	if err != nil {
+		return nil, err
+	}
+	var entries []pkarr.DenylistEntry
+	for _, entryBytes := range values {
+		var entry pkarr.DenylistEntry
+		if err = json.Unmarshal(entryBytes, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,48 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const equivocationNamespace = "equivocations"
+
+// WriteEquivocation stores the evidence, unless evidence with the same fingerprint is already stored
+func (s *boltdb) WriteEquivocation(_ context.Context, equivocation pkarr.Equivocation) error {
+	equivocationBytes, err := json.Marshal(equivocation)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(equivocationNamespace))
+		if err != nil {
+			return err
+		}
+		key := []byte(equivocation.Fingerprint())
+		if bucket.Get(key) != nil {
+			return nil
+		}
+		return bucket.Put(key, equivocationBytes)
+	})
+}
+
+// ListEquivocations returns all stored evidence, ordered by fingerprint
+func (s *boltdb) ListEquivocations(_ context.Context) ([]pkarr.Equivocation, error) {
+	values, err := s.readPrefix(equivocationNamespace, "")
+	if err != nil {
+		return nil, err
+	}
+	var equivocations []pkarr.Equivocation
+	for _, equivocationBytes := range values {
+		var equivocation pkarr.Equivocation
+		if err = json.Unmarshal(equivocationBytes, &equivocation); err != nil {
+			return nil, err
+		}
+		equivocations = append(equivocations, equivocation)
+	}
+	return equivocations, nil
+}
@@ -0,0 +1,55 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const feedNamespace = "feed"
+
+// AppendFeedEntry appends the entry to the feed, returning the cursor assigned to it
+func (s *boltdb) AppendFeedEntry(_ context.Context, entry pkarr.FeedEntry) (int64, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(feedNamespace))
+		if err != nil {
+			return err
+		}
+		// the bucket's sequence starts at 1, so the cursor 0 is before all entries
+		sequence, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		entry.Cursor = int64(sequence)
+		entryBytes, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(historyKey(entry.Cursor), entryBytes)
+	})
+	return entry.Cursor, err
+}
+
+// ListFeedEntries returns up to limit entries with a cursor after since, ordered by cursor
+func (s *boltdb) ListFeedEntries(_ context.Context, since int64, limit int) ([]pkarr.FeedEntry, error) {
+	var entries []pkarr.FeedEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(feedNamespace))
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek(historyKey(since + 1)); k != nil && len(entries) < limit; k, v = cursor.Next() {
+			var entry pkarr.FeedEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
@@ -0,0 +1,77 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const historyNamespace = "history"
+
+// AppendHistoryEntry appends the entry to the log, failing if an entry already exists at its index
+func (s *boltdb) AppendHistoryEntry(_ context.Context, entry pkarr.HistoryEntry) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(historyNamespace))
+		if err != nil {
+			return err
+		}
+		key := historyKey(entry.Index)
+		if bucket.Get(key) != nil {
+			return errors.Errorf("history entry %d already exists", entry.Index)
+		}
+		return bucket.Put(key, entryBytes)
+	})
+}
+
+// ReadLatestHistoryEntry returns the last entry of the log, or nil if the log is empty
+func (s *boltdb) ReadLatestHistoryEntry(_ context.Context) (*pkarr.HistoryEntry, error) {
+	var entry *pkarr.HistoryEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(historyNamespace))
+		if bucket == nil {
+			return nil
+		}
+		_, v := bucket.Cursor().Last()
+		if v == nil {
+			return nil
+		}
+		entry = new(pkarr.HistoryEntry)
+		return json.Unmarshal(v, entry)
+	})
+	return entry, err
+}
+
+// ListHistoryEntries returns up to limit entries starting at the given index, ordered by index
+func (s *boltdb) ListHistoryEntries(_ context.Context, from int64, limit int) ([]pkarr.HistoryEntry, error) {
+	var entries []pkarr.HistoryEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(historyNamespace))
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek(historyKey(from)); k != nil && len(entries) < limit; k, v = cursor.Next() {
+			var entry pkarr.HistoryEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// historyKey builds a key for a history entry which sorts lexicographically by index
+func historyKey(index int64) []byte {
+	return []byte(fmt.Sprintf("%020d", index))
+}
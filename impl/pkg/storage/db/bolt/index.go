@@ -0,0 +1,132 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	documentsNamespace = "documents"
+	indexNamespace     = "documents_index"
+
+	// termSeparator separates the field and value of an index term from the ID it points to
+	termSeparator = "\x00"
+)
+
+// IndexDocument adds the document to an inverted index of field values to IDs, replacing the terms of any
+// previously indexed version of the document
+func (s *boltdb) IndexDocument(_ context.Context, doc pkarr.Document) error {
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		documents, err := tx.CreateBucketIfNotExists([]byte(documentsNamespace))
+		if err != nil {
+			return err
+		}
+		index, err := tx.CreateBucketIfNotExists([]byte(indexNamespace))
+		if err != nil {
+			return err
+		}
+
+		if err = deleteIndexTerms(documents, index, doc.ID); err != nil {
+			return err
+		}
+		for _, term := range indexTerms(doc) {
+			if err = index.Put([]byte(term), nil); err != nil {
+				return err
+			}
+		}
+		return documents.Put([]byte(doc.ID), docBytes)
+	})
+}
+
+// DeleteDocument removes the document with the given ID, and its terms, from the index, if indexed
+func (s *boltdb) DeleteDocument(_ context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		documents, index := tx.Bucket([]byte(documentsNamespace)), tx.Bucket([]byte(indexNamespace))
+		if documents == nil || index == nil {
+			return nil
+		}
+		if err := deleteIndexTerms(documents, index, id); err != nil {
+			return err
+		}
+		return documents.Delete([]byte(id))
+	})
+}
+
+// deleteIndexTerms deletes the terms of the indexed document with the given ID, if any, from the index
+func deleteIndexTerms(documents, index *bolt.Bucket, id string) error {
+	previousBytes := documents.Get([]byte(id))
+	if previousBytes == nil {
+		return nil
+	}
+	var previous pkarr.Document
+	if err := json.Unmarshal(previousBytes, &previous); err != nil {
+		return err
+	}
+	for _, term := range indexTerms(previous) {
+		if err := index.Delete([]byte(term)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryDocuments returns the IDs of the documents matching the query, ordered by ID
+func (s *boltdb) QueryDocuments(_ context.Context, query pkarr.DocumentQuery) ([]string, error) {
+	prefix := indexTermPrefix(query.Field, query.Value)
+	if query.Prefix {
+		prefix = strings.TrimSuffix(prefix, termSeparator)
+	}
+	matches := make(map[string]bool)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		index := tx.Bucket([]byte(indexNamespace))
+		if index == nil {
+			return nil
+		}
+		cursor := index.Cursor()
+		for k, _ := cursor.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, _ = cursor.Next() {
+			id := string(k[strings.LastIndex(string(k), termSeparator)+1:])
+			if id > query.After {
+				matches[id] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// a prefix may match several values of the same document, so results are only ordered by ID once collected
+	ids := make([]string, 0, len(matches))
+	for id := range matches {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if query.Limit > 0 && len(ids) > query.Limit {
+		ids = ids[:query.Limit]
+	}
+	return ids, nil
+}
+
+func indexTerms(doc pkarr.Document) []string {
+	var terms []string
+	for _, field := range pkarr.IndexFields {
+		for _, value := range doc.Values(field) {
+			terms = append(terms, indexTermPrefix(field, value)+doc.ID)
+		}
+	}
+	return terms
+}
+
+func indexTermPrefix(field pkarr.IndexField, value string) string {
+	return string(field) + "=" + value + termSeparator
+}
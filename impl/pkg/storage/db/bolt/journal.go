@@ -0,0 +1,60 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const journalNamespace = "put_journal"
+
+// WriteJournalEntry writes the entry, replacing any entry for the same key
+func (s *boltdb) WriteJournalEntry(_ context.Context, entry pkarr.JournalEntry) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.write(journalNamespace, entry.Key, entryBytes)
+}
+
+// DeleteJournalEntry deletes the entry for the given key, unless it was replaced by an entry with another seq
+func (s *boltdb) DeleteJournalEntry(_ context.Context, key string, seq int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(journalNamespace))
+		if bucket == nil {
+			return nil
+		}
+		entryBytes := bucket.Get([]byte(key))
+		if entryBytes == nil {
+			return nil
+		}
+		var entry pkarr.JournalEntry
+		if err := json.Unmarshal(entryBytes, &entry); err != nil {
+			return err
+		}
+		if entry.Seq != seq {
+			return nil
+		}
+		return bucket.Delete([]byte(key))
+	})
+}
+
+// ListJournalEntries returns all entries, ordered by key
+func (s *boltdb) ListJournalEntries(_ context.Context) ([]pkarr.JournalEntry, error) {
+	values, err := s.readPrefix(journalNamespace, "")
+	if err != nil {
+		return nil, err
+	}
+	var entries []pkarr.JournalEntry
+	for _, entryBytes := range values {
+		var entry pkarr.JournalEntry
+		if err = json.Unmarshal(entryBytes, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,49 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const labelsNamespace = "labels"
+
+// WriteRecordLabels replaces the labels of the record stored under the key of the given labels
+func (s *boltdb) WriteRecordLabels(_ context.Context, labels pkarr.RecordLabels) error {
+	labelsBytes, err := json.Marshal(labels)
+	if err != nil {
+		return err
+	}
+	return s.write(labelsNamespace, labels.Key, labelsBytes)
+}
+
+// DeleteRecordLabels removes the labels of the record stored under the given key, if any
+func (s *boltdb) DeleteRecordLabels(_ context.Context, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(labelsNamespace))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(key))
+	})
+}
+
+// ListRecordLabels returns the labels of all labeled records, ordered by key
+func (s *boltdb) ListRecordLabels(_ context.Context) ([]pkarr.RecordLabels, error) {
+	values, err := s.readPrefix(labelsNamespace, "")
+	if err != nil {
+		return nil, err
+	}
+	var labeled []pkarr.RecordLabels
+	for _, labelsBytes := range values {
+		var labels pkarr.RecordLabels
+		if err = json.Unmarshal(labelsBytes, &labels); err != nil {
+			return nil, err
+		}
+		labeled = append(labeled, labels)
+	}
+	return labeled, nil
+}
@@ -0,0 +1,104 @@
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const resolvedNamespace = "pkarr_resolved"
+
+// Usage returns the number of stored records and the bytes used by them and their versions, counting the size of
+// their keys and values
+func (s *boltdb) Usage(_ context.Context) (pkarr.Usage, error) {
+	var usage pkarr.Usage
+	err := s.forEachShard(func(shard *bolt.Bucket) error {
+		return shard.ForEach(func(k, v []byte) error {
+			usage.Records++
+			usage.Bytes += int64(len(k) + len(v))
+			return nil
+		})
+	})
+	if err != nil {
+		return usage, err
+	}
+	err = s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(pkarrVersionsNamespace))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			usage.Bytes += int64(len(k) + len(v))
+			return nil
+		})
+	})
+	return usage, err
+}
+
+// DeleteRecord deletes the record stored under the given key, with all of its versions
+func (s *boltdb) DeleteRecord(_ context.Context, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range []*bolt.Bucket{recordShard(tx, key), tx.Bucket([]byte(resolvedNamespace))} {
+			if bucket != nil {
+				if err := bucket.Delete([]byte(key)); err != nil {
+					return err
+				}
+			}
+		}
+		bucket := tx.Bucket([]byte(pkarrVersionsNamespace))
+		if bucket == nil {
+			return nil
+		}
+		// collect the versions first, as deleting keys while iterating a cursor skips keys
+		prefix := key + ":"
+		var versions [][]byte
+		cursor := bucket.Cursor()
+		for k, _ := cursor.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, _ = cursor.Next() {
+			versions = append(versions, append([]byte(nil), k...))
+		}
+		for _, k := range versions {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MarkResolved sets the time the record stored under the given key was last resolved, if it is stored
+func (s *boltdb) MarkResolved(_ context.Context, key string, at time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		shard := recordShard(tx, key)
+		if shard == nil || shard.Get([]byte(key)) == nil {
+			return nil
+		}
+		bucket, err := tx.CreateBucketIfNotExists([]byte(resolvedNamespace))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), binary.BigEndian.AppendUint64(nil, uint64(at.Unix())))
+	})
+}
+
+// ListResolved returns the unix time each record was last resolved at, by the key it is stored under
+func (s *boltdb) ListResolved(_ context.Context) (map[string]int64, error) {
+	resolved := make(map[string]int64)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(resolvedNamespace))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if len(v) == 8 {
+				resolved[string(k)] = int64(binary.BigEndian.Uint64(v))
+			}
+			return nil
+		})
+	})
+	return resolved, err
+}
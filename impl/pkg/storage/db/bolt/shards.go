@@ -0,0 +1,189 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	// pkarrShardsNamespace holds the records in sub-buckets, shards, by the first character of their key. Keys are
+	// base64url encoded public keys, so records are spread evenly over up to 64 shards, which are scanned in parallel.
+	pkarrShardsNamespace = "pkarr_shards"
+	// migrationBatchSize is the number of records moved into shards per transaction while migrating
+	migrationBatchSize = 10_000
+)
+
+// shardName returns the name of the shard the record stored under the given key belongs to
+func shardName(key string) []byte {
+	if key == "" {
+		return []byte{0}
+	}
+	return []byte(key[:1])
+}
+
+// recordShard returns the shard the record stored under the given key belongs to, or nil if it doesn't exist
+func recordShard(tx *bolt.Tx, key string) *bolt.Bucket {
+	shards := tx.Bucket([]byte(pkarrShardsNamespace))
+	if shards == nil {
+		return nil
+	}
+	return shards.Bucket(shardName(key))
+}
+
+// createRecordShard returns the shard the record stored under the given key belongs to, creating it if needed
+func createRecordShard(tx *bolt.Tx, key string) (*bolt.Bucket, error) {
+	shards, err := tx.CreateBucketIfNotExists([]byte(pkarrShardsNamespace))
+	if err != nil {
+		return nil, err
+	}
+	return shards.CreateBucketIfNotExists(shardName(key))
+}
+
+// forEachShard calls fn with each shard in a read transaction
+func (s *boltdb) forEachShard(fn func(shard *bolt.Bucket) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		shards := tx.Bucket([]byte(pkarrShardsNamespace))
+		if shards == nil {
+			return nil
+		}
+		return shards.ForEachBucket(func(name []byte) error {
+			return fn(shards.Bucket(name))
+		})
+	})
+}
+
+// shardNames returns the names of all shards
+func (s *boltdb) shardNames() ([][]byte, error) {
+	var names [][]byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		shards := tx.Bucket([]byte(pkarrShardsNamespace))
+		if shards == nil {
+			return nil
+		}
+		return shards.ForEachBucket(func(name []byte) error {
+			names = append(names, append([]byte(nil), name...))
+			return nil
+		})
+	})
+	return names, err
+}
+
+// scanRecords calls fn with every stored record. Shards are read and decoded in parallel, each in its own read
+// transaction, so the scan isn't a consistent snapshot of all shards; fn is called from one goroutine at a time.
+func (s *boltdb) scanRecords(ctx context.Context, fn func(record pkarr.Record) error) error {
+	names, err := s.shardNames()
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	setErr := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for _, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(name []byte) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			var records []pkarr.Record
+			err := s.db.View(func(tx *bolt.Tx) error {
+				shard := tx.Bucket([]byte(pkarrShardsNamespace)).Bucket(name)
+				if shard == nil {
+					return nil
+				}
+				return shard.ForEach(func(_, v []byte) error {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+					var record pkarr.Record
+					if err := json.Unmarshal(v, &record); err != nil {
+						return err
+					}
+					records = append(records, record)
+					return nil
+				})
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil || firstErr != nil {
+				setErr(err)
+				return
+			}
+			for _, record := range records {
+				if err = fn(record); err != nil {
+					setErr(err)
+					return
+				}
+			}
+		}(name)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// migrateShards moves the records of the single records bucket of earlier versions into shards, in batches so
+// large databases aren't migrated in one transaction. An interrupted migration resumes on the next start.
+func (s *boltdb) migrateShards() error {
+	var moved int
+	for {
+		var done bool
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			legacy := tx.Bucket([]byte(pkarrNamespace))
+			if legacy == nil {
+				done = true
+				return nil
+			}
+			var keys [][]byte
+			cursor := legacy.Cursor()
+			for k, v := cursor.First(); k != nil && len(keys) < migrationBatchSize; k, v = cursor.Next() {
+				shard, err := createRecordShard(tx, string(k))
+				if err != nil {
+					return err
+				}
+				if err = shard.Put(k, v); err != nil {
+					return err
+				}
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			for _, k := range keys {
+				if err := legacy.Delete(k); err != nil {
+					return err
+				}
+			}
+			moved += len(keys)
+			if len(keys) < migrationBatchSize {
+				done = true
+				return tx.DeleteBucket([]byte(pkarrNamespace))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+	}
+	if moved > 0 {
+		logrus.Infof("migrated %d record(s) into sharded buckets", moved)
+	}
+	return nil
+}
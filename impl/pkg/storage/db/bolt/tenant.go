@@ -0,0 +1,70 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const tenantNamespace = "tenants"
+
+// WriteTenantRecord replaces the attribution of the record stored under the key of the given attribution
+func (s *boltdb) WriteTenantRecord(_ context.Context, record pkarr.TenantRecord) error {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.write(tenantNamespace, record.Key, recordBytes)
+}
+
+// ReadTenantRecord returns the attribution of the record stored under the given key, or nil if it has none
+func (s *boltdb) ReadTenantRecord(_ context.Context, key string) (*pkarr.TenantRecord, error) {
+	var record *pkarr.TenantRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(tenantNamespace))
+		if bucket == nil {
+			return nil
+		}
+		recordBytes := bucket.Get([]byte(key))
+		if len(recordBytes) == 0 {
+			return nil
+		}
+		record = new(pkarr.TenantRecord)
+		return json.Unmarshal(recordBytes, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// DeleteTenantRecord removes the attribution of the record stored under the given key, if any
+func (s *boltdb) DeleteTenantRecord(_ context.Context, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(tenantNamespace))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(key))
+	})
+}
+
+// ListTenantRecords returns the attributions of all attributed records, ordered by key
+func (s *boltdb) ListTenantRecords(_ context.Context) ([]pkarr.TenantRecord, error) {
+	values, err := s.readPrefix(tenantNamespace, "")
+	if err != nil {
+		return nil, err
+	}
+	var records []pkarr.TenantRecord
+	for _, recordBytes := range values {
+		var record pkarr.TenantRecord
+		if err = json.Unmarshal(recordBytes, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,93 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// ListVersionSeqs returns the seqs of the stored versions of each record, ordered by seq, by the key it is stored under
+func (s *boltdb) ListVersionSeqs(_ context.Context) (map[string][]int64, error) {
+	seqs := make(map[string][]int64)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(pkarrVersionsNamespace))
+		if bucket == nil {
+			return nil
+		}
+		// versions are iterated in key order, so by record, then by seq
+		return bucket.ForEach(func(k, _ []byte) error {
+			key, seq, err := parseVersionKey(string(k))
+			if err != nil {
+				return err
+			}
+			seqs[key] = append(seqs[key], seq)
+			return nil
+		})
+	})
+	return seqs, err
+}
+
+// DeleteRecordVersionsBefore deletes the versions of the record stored under the given key with a seq lower than the
+// given seq, returning how many it deleted
+func (s *boltdb) DeleteRecordVersionsBefore(_ context.Context, key string, seq int64) (int64, error) {
+	var deleted int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(pkarrVersionsNamespace))
+		if bucket == nil {
+			return nil
+		}
+		// collect the versions first, as deleting keys while iterating a cursor skips keys
+		prefix, end := key+":", versionKey(key, seq)
+		var versions [][]byte
+		cursor := bucket.Cursor()
+		for k, _ := cursor.Seek([]byte(prefix)); k != nil && string(k) < end; k, _ = cursor.Next() {
+			versions = append(versions, append([]byte(nil), k...))
+		}
+		for _, k := range versions {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		deleted = int64(len(versions))
+		return nil
+	})
+	return deleted, err
+}
+
+// parseVersionKey returns the key of the record and the seq of a key built by versionKey
+func parseVersionKey(k string) (string, int64, error) {
+	i := strings.LastIndexByte(k, ':')
+	if i < 0 {
+		return "", 0, fmt.Errorf("malformed version key: %s", k)
+	}
+	seq, err := strconv.ParseInt(k[i+1:], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("malformed version key: %s", k)
+	}
+	return k[:i], seq, nil
+}
+
+// ReplaceRecordVersion replaces the stored version of the record with its seq, if stored
+func (s *boltdb) ReplaceRecordVersion(_ context.Context, record pkarr.Record) error {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(pkarrVersionsNamespace))
+		if bucket == nil {
+			return nil
+		}
+		key := []byte(versionKey(record.Key(), record.Seq))
+		if bucket.Get(key) == nil {
+			return nil
+		}
+		return bucket.Put(key, recordBytes)
+	})
+}
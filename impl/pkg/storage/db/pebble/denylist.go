@@ -0,0 +1,40 @@
+package pebble
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// WriteDenylistEntry adds the entry to the denylist, replacing any existing entry for its ID
+func (s *pebbledb) WriteDenylistEntry(_ context.Context, entry pkarr.DenylistEntry) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.apply(op{key: denylistKey(entry.ID), value: entryBytes})
+}
+
+// DeleteDenylistEntry removes the entry for the given ID from the denylist, if any
+func (s *pebbledb) DeleteDenylistEntry(_ context.Context, id string) error {
+	return s.apply(op{key: denylistKey(id), delete: true})
+}
+
+// ListDenylistEntries returns all entries of the denylist, ordered by ID
+func (s *pebbledb) ListDenylistEntries(_ context.Context) ([]pkarr.DenylistEntry, error) {
+	var entries []pkarr.DenylistEntry
+	err := s.scan([]byte(denylistPrefix), func(_, value []byte) error {
+		var entry pkarr.DenylistEntry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func denylistKey(id string) []byte {
+	return []byte(denylistPrefix + id)
+}
@@ -0,0 +1,40 @@
+package pebble
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// WriteEquivocation stores the evidence, unless evidence with the same fingerprint is already stored
+func (s *pebbledb) WriteEquivocation(_ context.Context, equivocation pkarr.Equivocation) error {
+	key := equivocationKey(equivocation.Fingerprint())
+	existing, err := s.get(key)
+	if err != nil || existing != nil {
+		return err
+	}
+	equivocationBytes, err := json.Marshal(equivocation)
+	if err != nil {
+		return err
+	}
+	return s.apply(op{key: key, value: equivocationBytes})
+}
+
+// ListEquivocations returns all stored evidence, ordered by fingerprint
+func (s *pebbledb) ListEquivocations(_ context.Context) ([]pkarr.Equivocation, error) {
+	var equivocations []pkarr.Equivocation
+	err := s.scan([]byte(equivocationPrefix), func(_, value []byte) error {
+		var equivocation pkarr.Equivocation
+		if err := json.Unmarshal(value, &equivocation); err != nil {
+			return err
+		}
+		equivocations = append(equivocations, equivocation)
+		return nil
+	})
+	return equivocations, err
+}
+
+func equivocationKey(fingerprint string) []byte {
+	return []byte(equivocationPrefix + fingerprint)
+}
@@ -0,0 +1,73 @@
+package pebble
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/cockroachdb/pebble"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// AppendFeedEntry appends the entry to the feed, returning the cursor assigned to it
+func (s *pebbledb) AppendFeedEntry(_ context.Context, entry pkarr.FeedEntry) (int64, error) {
+	// appends are serialized, as each cursor follows the last
+	s.feedMu.Lock()
+	defer s.feedMu.Unlock()
+	if s.feedCursor == 0 {
+		last, err := s.lastFeedCursor()
+		if err != nil {
+			return 0, err
+		}
+		s.feedCursor = last
+	}
+	entry.Cursor = s.feedCursor + 1
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return 0, err
+	}
+	if err = s.apply(op{key: feedKey(entry.Cursor), value: entryBytes}); err != nil {
+		return 0, err
+	}
+	s.feedCursor = entry.Cursor
+	return entry.Cursor, nil
+}
+
+// ListFeedEntries returns up to limit entries with a cursor after since, ordered by cursor
+func (s *pebbledb) ListFeedEntries(_ context.Context, since int64, limit int) ([]pkarr.FeedEntry, error) {
+	prefix := []byte(feedPrefix)
+	iter, err := s.db.NewIter(&pebble.IterOptions{LowerBound: feedKey(since + 1), UpperBound: prefixEnd(prefix)})
+	if err != nil {
+		return nil, err
+	}
+	var entries []pkarr.FeedEntry
+	for iter.First(); iter.Valid() && len(entries) < limit; iter.Next() {
+		var entry pkarr.FeedEntry
+		if err = json.Unmarshal(iter.Value(), &entry); err != nil {
+			_ = iter.Close()
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, iter.Close()
+}
+
+// lastFeedCursor returns the cursor of the last entry of the feed, 0 if it is empty
+func (s *pebbledb) lastFeedCursor() (int64, error) {
+	prefix := []byte(feedPrefix)
+	iter, err := s.db.NewIter(&pebble.IterOptions{LowerBound: prefix, UpperBound: prefixEnd(prefix)})
+	if err != nil {
+		return 0, err
+	}
+	var cursor int64
+	if iter.Last() {
+		cursor = int64(binary.BigEndian.Uint64(iter.Key()[len(prefix):]))
+	}
+	return cursor, iter.Close()
+}
+
+// feedKey builds a key for a feed entry which sorts by cursor
+func feedKey(cursor int64) []byte {
+	return binary.BigEndian.AppendUint64([]byte(feedPrefix), uint64(cursor))
+}
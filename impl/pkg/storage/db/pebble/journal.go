@@ -0,0 +1,59 @@
+package pebble
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// WriteJournalEntry writes the entry, replacing any entry for the same key
+func (s *pebbledb) WriteJournalEntry(_ context.Context, entry pkarr.JournalEntry) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	lock := s.keyLock(string(journalKey(entry.Key)))
+	lock.Lock()
+	defer lock.Unlock()
+	return s.apply(op{key: journalKey(entry.Key), value: entryBytes})
+}
+
+// DeleteJournalEntry deletes the entry for the given key, unless it was replaced by an entry with another seq, holding
+// the lock of its key from reading the entry until the delete is committed
+func (s *pebbledb) DeleteJournalEntry(_ context.Context, key string, seq int64) error {
+	lock := s.keyLock(string(journalKey(key)))
+	lock.Lock()
+	defer lock.Unlock()
+
+	entryBytes, err := s.get(journalKey(key))
+	if err != nil || entryBytes == nil {
+		return err
+	}
+	var entry pkarr.JournalEntry
+	if err = json.Unmarshal(entryBytes, &entry); err != nil {
+		return err
+	}
+	if entry.Seq != seq {
+		return nil
+	}
+	return s.apply(op{key: journalKey(key), delete: true})
+}
+
+// ListJournalEntries returns all entries, ordered by key
+func (s *pebbledb) ListJournalEntries(_ context.Context) ([]pkarr.JournalEntry, error) {
+	var entries []pkarr.JournalEntry
+	err := s.scan([]byte(journalPrefix), func(_, value []byte) error {
+		var entry pkarr.JournalEntry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func journalKey(key string) []byte {
+	return []byte(journalPrefix + key)
+}
@@ -0,0 +1,40 @@
+package pebble
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// WriteRecordLabels replaces the labels of the record stored under the key of the given labels
+func (s *pebbledb) WriteRecordLabels(_ context.Context, labels pkarr.RecordLabels) error {
+	labelsBytes, err := json.Marshal(labels)
+	if err != nil {
+		return err
+	}
+	return s.apply(op{key: labelsKey(labels.Key), value: labelsBytes})
+}
+
+// DeleteRecordLabels removes the labels of the record stored under the given key, if any
+func (s *pebbledb) DeleteRecordLabels(_ context.Context, key string) error {
+	return s.apply(op{key: labelsKey(key), delete: true})
+}
+
+// ListRecordLabels returns the labels of all labeled records, ordered by key
+func (s *pebbledb) ListRecordLabels(_ context.Context) ([]pkarr.RecordLabels, error) {
+	var labeled []pkarr.RecordLabels
+	err := s.scan([]byte(labelsPrefix), func(_, value []byte) error {
+		var labels pkarr.RecordLabels
+		if err := json.Unmarshal(value, &labels); err != nil {
+			return err
+		}
+		labeled = append(labeled, labels)
+		return nil
+	})
+	return labeled, err
+}
+
+func labelsKey(key string) []byte {
+	return []byte(labelsPrefix + key)
+}
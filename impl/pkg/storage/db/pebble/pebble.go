@@ -0,0 +1,326 @@
+package pebble
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/pkg/errors"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	// keys are namespaced by a prefix, as pebble has no buckets
+	recordPrefix   = "r/"
+	versionPrefix  = "v/"
+	resolvedPrefix = "t/"
+	denylistPrefix = "d/"
+	// equivocationPrefix namespaces the evidence of equivocating keys
+	equivocationPrefix = "e/"
+	// feedPrefix namespaces the entries of the change feed
+	feedPrefix = "f/"
+	// labelsPrefix namespaces the labels operators attach to records
+	labelsPrefix = "l/"
+	// journalPrefix namespaces the journal of puts of published records to the DHT
+	journalPrefix = "j/"
+	// tenantPrefix namespaces the attributions of records to the tenants which published them
+	tenantPrefix = "n/"
+
+	// maxBatchWrites is the maximum number of concurrent writes committed together in one batch
+	maxBatchWrites = 256
+	// keyLockStripes is the number of locks conditional writes are serialized by, by the hash of their key
+	keyLockStripes = 64
+)
+
+var errClosed = errors.New("pebble storage is closed")
+
+// op is a single set or delete of a write
+type op struct {
+	key   []byte
+	value []byte
+	// end, if set, deletes the range of keys from key up to end instead
+	end    []byte
+	delete bool
+}
+
+// write is a set of ops applied atomically, waiting for the batch it is committed in
+type write struct {
+	ops  []op
+	done chan error
+}
+
+type pebbledb struct {
+	db *pebble.DB
+
+	// writes are committed by a single goroutine, which groups concurrent writes into one synced batch
+	writes  chan write
+	stopped chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+
+	// feedMu serializes appends to the change feed, whose last cursor is loaded on the first append
+	feedMu     sync.Mutex
+	feedCursor int64
+
+	// keyLocks serialize conditional writes of the same key, while those of other keys are still batched together
+	keyLocks [keyLockStripes]sync.Mutex
+}
+
+// NewPebble creates a Pebble-based implementation of storage.Storage, for gateways accepting high publish rates.
+// Concurrent writes are committed together in batches, and scans iterate a consistent snapshot.
+func NewPebble(path string) (*pebbledb, error) {
+	if path == "" {
+		return nil, errors.New("path is required")
+	}
+	db, err := pebble.Open(path, &pebble.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &pebbledb{
+		db:      db,
+		writes:  make(chan write, maxBatchWrites),
+		stopped: make(chan struct{}),
+	}
+	go s.commitWrites()
+	return s, nil
+}
+
+// WriteRecord writes the given record to the storage, keeping a copy of each unique seq in the version history
+func (s *pebbledb) WriteRecord(_ context.Context, record pkarr.Record) error {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.apply(
+		op{key: versionKey(record.Key(), record.Seq), value: recordBytes},
+		op{key: recordKey(record.Key()), value: recordBytes},
+	)
+}
+
+// WriteRecordIfNewer writes the given record unless the stored record has a higher seq, holding the lock of its key
+// from reading the stored record until the write is committed
+func (s *pebbledb) WriteRecordIfNewer(_ context.Context, record pkarr.Record) (*pkarr.Record, bool, error) {
+	lock := s.keyLock(record.Key())
+	lock.Lock()
+	defer lock.Unlock()
+
+	current, err := s.readRecord(recordKey(record.Key()))
+	if err != nil {
+		return nil, false, err
+	}
+	if current != nil && current.Seq > record.Seq {
+		return current, false, nil
+	}
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+	err = s.apply(
+		op{key: versionKey(record.Key(), record.Seq), value: recordBytes},
+		op{key: recordKey(record.Key()), value: recordBytes},
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	return current, true, nil
+}
+
+// keyLock returns the lock conditional writes of the given key are serialized by
+func (s *pebbledb) keyLock(key string) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &s.keyLocks[h.Sum32()%keyLockStripes]
+}
+
+// ReadRecord reads the record with the given id from the storage
+func (s *pebbledb) ReadRecord(_ context.Context, id string) (*pkarr.Record, error) {
+	return s.readRecord(recordKey(id))
+}
+
+// ListRecords lists all records in the storage, from a snapshot of the storage
+func (s *pebbledb) ListRecords(_ context.Context) ([]pkarr.Record, error) {
+	return s.listRecords([]byte(recordPrefix))
+}
+
+// CountRecords returns the number of stored records
+func (s *pebbledb) CountRecords(_ context.Context) (int64, error) {
+	var count int64
+	err := s.scan([]byte(recordPrefix), func(_, _ []byte) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// ReadRecordVersion reads the version of the record with the given id and seq from the storage
+func (s *pebbledb) ReadRecordVersion(_ context.Context, id string, seq int64) (*pkarr.Record, error) {
+	return s.readRecord(versionKey(id, seq))
+}
+
+// ListRecordVersions lists all stored versions of the record with the given id, ordered by seq
+func (s *pebbledb) ListRecordVersions(_ context.Context, id string) ([]pkarr.Record, error) {
+	return s.listRecords(versionsPrefix(id))
+}
+
+// Close stops accepting writes, waits for pending writes to be committed, and closes the database
+func (s *pebbledb) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.writes)
+	s.mu.Unlock()
+
+	<-s.stopped
+	return s.db.Close()
+}
+
+// apply queues the ops to be committed atomically in the next batch, and waits for the batch to be committed
+func (s *pebbledb) apply(ops ...op) error {
+	done := make(chan error, 1)
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return errClosed
+	}
+	s.writes <- write{ops: ops, done: done}
+	s.mu.RUnlock()
+	return <-done
+}
+
+// commitWrites commits queued writes until the queue is closed, grouping the writes queued while the previous batch
+// was committed into one batch, so concurrent writers share a single sync
+func (s *pebbledb) commitWrites() {
+	defer close(s.stopped)
+	for first := range s.writes {
+		pending := []write{first}
+	collect:
+		for len(pending) < maxBatchWrites {
+			select {
+			case w, ok := <-s.writes:
+				if !ok {
+					break collect
+				}
+				pending = append(pending, w)
+			default:
+				break collect
+			}
+		}
+		err := s.commit(pending)
+		for _, w := range pending {
+			w.done <- err
+		}
+	}
+}
+
+// commit applies the writes in one synced batch
+func (s *pebbledb) commit(writes []write) error {
+	batch := s.db.NewBatch()
+	defer batch.Close()
+	for _, w := range writes {
+		for _, o := range w.ops {
+			var err error
+			switch {
+			case o.end != nil:
+				err = batch.DeleteRange(o.key, o.end, nil)
+			case o.delete:
+				err = batch.Delete(o.key, nil)
+			default:
+				err = batch.Set(o.key, o.value, nil)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return batch.Commit(pebble.Sync)
+}
+
+// get returns a copy of the value of the given key, or nil if it isn't set
+func (s *pebbledb) get(key []byte) ([]byte, error) {
+	value, closer, err := s.db.Get(key)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	return append([]byte(nil), value...), nil
+}
+
+// scan calls fn with the key and value of each key starting with the given prefix, in key order, from a snapshot of
+// the storage. The key and value are only valid until fn returns.
+func (s *pebbledb) scan(prefix []byte, fn func(key, value []byte) error) error {
+	snapshot := s.db.NewSnapshot()
+	defer snapshot.Close()
+	iter, err := snapshot.NewIter(&pebble.IterOptions{LowerBound: prefix, UpperBound: prefixEnd(prefix)})
+	if err != nil {
+		return err
+	}
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err = fn(iter.Key(), iter.Value()); err != nil {
+			_ = iter.Close()
+			return err
+		}
+	}
+	return iter.Close()
+}
+
+func (s *pebbledb) readRecord(key []byte) (*pkarr.Record, error) {
+	recordBytes, err := s.get(key)
+	if err != nil || recordBytes == nil {
+		return nil, err
+	}
+	var record pkarr.Record
+	if err = json.Unmarshal(recordBytes, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *pebbledb) listRecords(prefix []byte) ([]pkarr.Record, error) {
+	var records []pkarr.Record
+	err := s.scan(prefix, func(_, value []byte) error {
+		var record pkarr.Record
+		if err := json.Unmarshal(value, &record); err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	return records, err
+}
+
+func recordKey(key string) []byte {
+	return []byte(recordPrefix + key)
+}
+
+// versionKey builds a key for a record version which sorts lexicographically by seq
+func versionKey(key string, seq int64) []byte {
+	return []byte(fmt.Sprintf("%s%s:%020d", versionPrefix, key, seq))
+}
+
+// versionsPrefix is the prefix of the keys of all versions of the record stored under the given key
+func versionsPrefix(key string) []byte {
+	return []byte(versionPrefix + key + ":")
+}
+
+// prefixEnd returns the first key after all keys starting with the given prefix
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func setupPebbleDB(t *testing.T) *pebbledb {
+	db, err := NewPebble(filepath.Join(t.TempDir(), "pebble"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestPebble_Records(t *testing.T) {
+	db := setupPebbleDB(t)
+	ctx := context.Background()
+
+	record := pkarr.Record{K: "key", V: "value", Sig: "sig", Seq: 1}
+	require.NoError(t, db.WriteRecord(ctx, record))
+
+	readRecord, err := db.ReadRecord(ctx, record.Key())
+	assert.NoError(t, err)
+	assert.Equal(t, record, *readRecord)
+
+	missing, err := db.ReadRecord(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	// write a newer version and confirm both versions are kept
+	newerRecord := record
+	newerRecord.Seq++
+	require.NoError(t, db.WriteRecord(ctx, newerRecord))
+
+	readRecord, err = db.ReadRecord(ctx, record.Key())
+	assert.NoError(t, err)
+	assert.Equal(t, newerRecord, *readRecord)
+
+	readVersion, err := db.ReadRecordVersion(ctx, record.Key(), record.Seq)
+	assert.NoError(t, err)
+	assert.Equal(t, record, *readVersion)
+
+	// a salted record of the same key is stored separately
+	saltedRecord := record
+	saltedRecord.Salt = "salt"
+	require.NoError(t, db.WriteRecord(ctx, saltedRecord))
+
+	versions, err := db.ListRecordVersions(ctx, record.Key())
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.Record{record, newerRecord}, versions)
+
+	records, err := db.ListRecords(ctx)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []pkarr.Record{newerRecord, saltedRecord}, records)
+
+	count, err := db.CountRecords(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestPebble_ConcurrentWrites(t *testing.T) {
+	db := setupPebbleDB(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 500; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			record := pkarr.Record{K: fmt.Sprintf("key%d", i), V: "value", Sig: "sig", Seq: 1}
+			assert.NoError(t, db.WriteRecord(ctx, record))
+		}(i)
+	}
+	wg.Wait()
+
+	count, err := db.CountRecords(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), count)
+
+	// writes after closing fail rather than being lost
+	require.NoError(t, db.Close())
+	assert.ErrorIs(t, db.WriteRecord(ctx, pkarr.Record{K: "late", V: "value", Sig: "sig", Seq: 1}), errClosed)
+}
+
+func TestPebble_WriteRecordIfNewer(t *testing.T) {
+	db := setupPebbleDB(t)
+	ctx := context.Background()
+
+	// concurrent writes of the same key leave the highest seq stored, whatever order they are applied in
+	var wg sync.WaitGroup
+	for seq := int64(1); seq <= 100; seq++ {
+		wg.Add(1)
+		go func(seq int64) {
+			defer wg.Done()
+			current, written, err := db.WriteRecordIfNewer(ctx, pkarr.Record{K: "key", V: "value", Sig: "sig", Seq: seq})
+			assert.NoError(t, err)
+			if current != nil {
+				assert.Equal(t, current.Seq <= seq, written)
+			}
+		}(seq)
+	}
+	wg.Wait()
+
+	record, err := db.ReadRecord(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), record.Seq)
+
+	current, written, err := db.WriteRecordIfNewer(ctx, pkarr.Record{K: "key", V: "stale", Sig: "sig", Seq: 99})
+	assert.NoError(t, err)
+	assert.False(t, written)
+	assert.Equal(t, int64(100), current.Seq)
+	version, err := db.ReadRecordVersion(ctx, "key", 100)
+	require.NoError(t, err)
+	assert.NotNil(t, version)
+}
+
+func TestPebble_Quota(t *testing.T) {
+	db := setupPebbleDB(t)
+	ctx := context.Background()
+
+	record := pkarr.Record{K: "key", V: "value", Sig: "sig", Seq: 1}
+	newerRecord := record
+	newerRecord.Seq++
+	saltedRecord := record
+	saltedRecord.Salt = "salt"
+	for _, r := range []pkarr.Record{record, newerRecord, saltedRecord} {
+		require.NoError(t, db.WriteRecord(ctx, r))
+	}
+
+	usage, err := db.Usage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Records)
+	assert.Greater(t, usage.Bytes, int64(0))
+
+	// only stored records are marked resolved
+	at := time.Unix(1700000000, 0)
+	assert.NoError(t, db.MarkResolved(ctx, record.Key(), at))
+	assert.NoError(t, db.MarkResolved(ctx, "missing", at))
+	resolved, err := db.ListResolved(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{record.Key(): at.Unix()}, resolved)
+
+	// deleting a record deletes its versions, but not those of a salted record of the same key
+	assert.NoError(t, db.DeleteRecord(ctx, record.Key()))
+	readRecord, err := db.ReadRecord(ctx, record.Key())
+	assert.NoError(t, err)
+	assert.Nil(t, readRecord)
+	versions, err := db.ListRecordVersions(ctx, record.Key())
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+	versions, err = db.ListRecordVersions(ctx, saltedRecord.Key())
+	assert.NoError(t, err)
+	assert.Len(t, versions, 1)
+	resolved, err = db.ListResolved(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, resolved)
+}
+
+func TestPebble_Denylist(t *testing.T) {
+	db := setupPebbleDB(t)
+	ctx := context.Background()
+
+	assert.NoError(t, db.WriteDenylistEntry(ctx, pkarr.DenylistEntry{ID: "bob", Reason: "spam", Timestamp: 1}))
+	assert.NoError(t, db.WriteDenylistEntry(ctx, pkarr.DenylistEntry{ID: "alice", Reason: "abuse", Timestamp: 2}))
+	assert.NoError(t, db.WriteDenylistEntry(ctx, pkarr.DenylistEntry{ID: "bob", Reason: "phishing", Timestamp: 3}))
+
+	entries, err := db.ListDenylistEntries(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.DenylistEntry{
+		{ID: "alice", Reason: "abuse", Timestamp: 2},
+		{ID: "bob", Reason: "phishing", Timestamp: 3},
+	}, entries)
+
+	assert.NoError(t, db.DeleteDenylistEntry(ctx, "alice"))
+	entries, err = db.ListDenylistEntries(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.DenylistEntry{{ID: "bob", Reason: "phishing", Timestamp: 3}}, entries)
+}
+
+func TestPebble_Labels(t *testing.T) {
+	db := setupPebbleDB(t)
+	ctx := context.Background()
+
+	labeled, err := db.ListRecordLabels(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, labeled)
+
+	// deleting missing labels is a no-op
+	assert.NoError(t, db.DeleteRecordLabels(ctx, "bob"))
+
+	assert.NoError(t, db.WriteRecordLabels(ctx, pkarr.RecordLabels{Key: "bob", Labels: []string{"pinned"}, Timestamp: 1}))
+	assert.NoError(t, db.WriteRecordLabels(ctx, pkarr.RecordLabels{Key: "alice", Labels: []string{"customer:acme"}, Timestamp: 2}))
+	assert.NoError(t, db.WriteRecordLabels(ctx, pkarr.RecordLabels{Key: "bob", Labels: []string{"customer:acme", "pinned"}, Timestamp: 3}))
+
+	labeled, err = db.ListRecordLabels(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.RecordLabels{
+		{Key: "alice", Labels: []string{"customer:acme"}, Timestamp: 2},
+		{Key: "bob", Labels: []string{"customer:acme", "pinned"}, Timestamp: 3},
+	}, labeled)
+
+	assert.NoError(t, db.DeleteRecordLabels(ctx, "alice"))
+	labeled, err = db.ListRecordLabels(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.RecordLabels{{Key: "bob", Labels: []string{"customer:acme", "pinned"}, Timestamp: 3}}, labeled)
+}
+
+func TestPebble_Equivocations(t *testing.T) {
+	db := setupPebbleDB(t)
+	ctx := context.Background()
+
+	equivocation := pkarr.Equivocation{
+		ID:        "bob",
+		Seq:       1,
+		Stored:    pkarr.Record{K: "k", V: "a", Sig: "sig-a", Seq: 1},
+		Observed:  pkarr.Record{K: "k", V: "b", Sig: "sig-b", Seq: 1},
+		Source:    pkarr.EquivocationSourceDHT,
+		Timestamp: 1,
+	}
+	assert.NoError(t, db.WriteEquivocation(ctx, equivocation))
+	reversed := equivocation
+	reversed.Stored, reversed.Observed, reversed.Timestamp = equivocation.Observed, equivocation.Stored, 2
+	assert.NoError(t, db.WriteEquivocation(ctx, reversed))
+
+	equivocations, err := db.ListEquivocations(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.Equivocation{equivocation}, equivocations)
+}
+
+func TestPebble_Feed(t *testing.T) {
+	db := setupPebbleDB(t)
+	ctx := context.Background()
+
+	for seq := int64(1); seq <= 3; seq++ {
+		cursor, err := db.AppendFeedEntry(ctx, pkarr.FeedEntry{ID: "alice", Seq: seq, Timestamp: seq})
+		assert.NoError(t, err)
+		assert.Equal(t, seq, cursor)
+	}
+
+	entries, err := db.ListFeedEntries(ctx, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkarr.FeedEntry{
+		{Cursor: 2, ID: "alice", Seq: 2, Timestamp: 2},
+		{Cursor: 3, ID: "alice", Seq: 3, Timestamp: 3},
+	}, entries)
+}
+
+func TestPrefixEnd(t *testing.T) {
+	assert.Equal(t, []byte("v/key;"), prefixEnd([]byte("v/key:")))
+	assert.Equal(t, []byte("b"), prefixEnd([]byte{'a', 0xff}))
+	assert.Nil(t, prefixEnd([]byte{0xff}))
+}
+
+func TestPebble_PruneVersions(t *testing.T) {
+	db := setupPebbleDB(t)
+	ctx := context.Background()
+
+	record := pkarr.Record{K: "key", V: "value", Sig: "sig"}
+	saltedRecord := record
+	saltedRecord.Salt = "salt"
+	for seq := int64(1); seq <= 3; seq++ {
+		record.Seq, saltedRecord.Seq = seq, seq
+		require.NoError(t, db.WriteRecord(ctx, record))
+		require.NoError(t, db.WriteRecord(ctx, saltedRecord))
+	}
+
+	seqs, err := db.ListVersionSeqs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]int64{record.Key(): {1, 2, 3}, saltedRecord.Key(): {1, 2, 3}}, seqs)
+
+	// only the versions of the record itself below the seq are deleted
+	deleted, err := db.DeleteRecordVersionsBefore(ctx, record.Key(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	seqs, err = db.ListVersionSeqs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]int64{record.Key(): {3}, saltedRecord.Key(): {1, 2, 3}}, seqs)
+
+	deleted, err = db.DeleteRecordVersionsBefore(ctx, record.Key(), 3)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	current, err := db.ReadRecord(ctx, record.Key())
+	require.NoError(t, err)
+	assert.Equal(t, &record, current)
+}
+
+func TestPebble_PutJournal(t *testing.T) {
+	db := setupPebbleDB(t)
+	ctx := context.Background()
+
+	entries, err := db.ListJournalEntries(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	entry := pkarr.JournalEntry{Key: "key", Seq: 1, Timestamp: 1700000000}
+	require.NoError(t, db.WriteJournalEntry(ctx, entry))
+	replaced := entry
+	replaced.Seq = 2
+	require.NoError(t, db.WriteJournalEntry(ctx, replaced))
+	entries, err = db.ListJournalEntries(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []pkarr.JournalEntry{replaced}, entries)
+
+	// acknowledging the replaced put leaves the entry of the newer put
+	require.NoError(t, db.DeleteJournalEntry(ctx, entry.Key, entry.Seq))
+	entries, err = db.ListJournalEntries(ctx)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	require.NoError(t, db.DeleteJournalEntry(ctx, replaced.Key, replaced.Seq))
+	entries, err = db.ListJournalEntries(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,63 @@
+package pebble
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// Usage returns the number of stored records and the bytes used by them and their versions, counting the size of
+// their keys and values
+func (s *pebbledb) Usage(_ context.Context) (pkarr.Usage, error) {
+	var usage pkarr.Usage
+	err := s.scan([]byte(recordPrefix), func(key, value []byte) error {
+		usage.Records++
+		usage.Bytes += int64(len(key) + len(value))
+		return nil
+	})
+	if err != nil {
+		return usage, err
+	}
+	err = s.scan([]byte(versionPrefix), func(key, value []byte) error {
+		usage.Bytes += int64(len(key) + len(value))
+		return nil
+	})
+	return usage, err
+}
+
+// DeleteRecord deletes the record stored under the given key, with all of its versions
+func (s *pebbledb) DeleteRecord(_ context.Context, key string) error {
+	versions := versionsPrefix(key)
+	return s.apply(
+		op{key: recordKey(key), delete: true},
+		op{key: versions, end: prefixEnd(versions)},
+		op{key: resolvedKey(key), delete: true},
+	)
+}
+
+// MarkResolved sets the time the record stored under the given key was last resolved, if it is stored
+func (s *pebbledb) MarkResolved(_ context.Context, key string, at time.Time) error {
+	stored, err := s.get(recordKey(key))
+	if err != nil || stored == nil {
+		return err
+	}
+	return s.apply(op{key: resolvedKey(key), value: binary.BigEndian.AppendUint64(nil, uint64(at.Unix()))})
+}
+
+// ListResolved returns the unix time each record was last resolved at, by the key it is stored under
+func (s *pebbledb) ListResolved(_ context.Context) (map[string]int64, error) {
+	resolved := make(map[string]int64)
+	err := s.scan([]byte(resolvedPrefix), func(key, value []byte) error {
+		if len(value) == 8 {
+			resolved[string(key[len(resolvedPrefix):])] = int64(binary.BigEndian.Uint64(value))
+		}
+		return nil
+	})
+	return resolved, err
+}
+
+func resolvedKey(key string) []byte {
+	return []byte(resolvedPrefix + key)
+}
@@ -0,0 +1,53 @@
+package pebble
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// WriteTenantRecord replaces the attribution of the record stored under the key of the given attribution
+func (s *pebbledb) WriteTenantRecord(_ context.Context, record pkarr.TenantRecord) error {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.apply(op{key: tenantKey(record.Key), value: recordBytes})
+}
+
+// ReadTenantRecord returns the attribution of the record stored under the given key, or nil if it has none
+func (s *pebbledb) ReadTenantRecord(_ context.Context, key string) (*pkarr.TenantRecord, error) {
+	recordBytes, err := s.get(tenantKey(key))
+	if err != nil || recordBytes == nil {
+		return nil, err
+	}
+	var record pkarr.TenantRecord
+	if err = json.Unmarshal(recordBytes, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// DeleteTenantRecord removes the attribution of the record stored under the given key, if any
+func (s *pebbledb) DeleteTenantRecord(_ context.Context, key string) error {
+	return s.apply(op{key: tenantKey(key), delete: true})
+}
+
+// ListTenantRecords returns the attributions of all attributed records, ordered by key
+func (s *pebbledb) ListTenantRecords(_ context.Context) ([]pkarr.TenantRecord, error) {
+	var records []pkarr.TenantRecord
+	err := s.scan([]byte(tenantPrefix), func(_, value []byte) error {
+		var record pkarr.TenantRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	return records, err
+}
+
+func tenantKey(key string) []byte {
+	return []byte(tenantPrefix + key)
+}
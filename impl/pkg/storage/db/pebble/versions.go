@@ -0,0 +1,70 @@
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// ListVersionSeqs returns the seqs of the stored versions of each record, ordered by seq, by the key it is stored under
+func (s *pebbledb) ListVersionSeqs(_ context.Context) (map[string][]int64, error) {
+	seqs := make(map[string][]int64)
+	// versions are scanned in key order, so by record, then by seq
+	err := s.scan([]byte(versionPrefix), func(key, _ []byte) error {
+		i := bytes.LastIndexByte(key, ':')
+		if i < len(versionPrefix) {
+			return fmt.Errorf("malformed version key: %s", key)
+		}
+		seq, err := strconv.ParseInt(string(key[i+1:]), 10, 64)
+		if err != nil {
+			return fmt.Errorf("malformed version key: %s", key)
+		}
+		recordKey := string(key[len(versionPrefix):i])
+		seqs[recordKey] = append(seqs[recordKey], seq)
+		return nil
+	})
+	return seqs, err
+}
+
+// DeleteRecordVersionsBefore deletes the versions of the record stored under the given key with a seq lower than the
+// given seq, returning how many it deleted
+func (s *pebbledb) DeleteRecordVersionsBefore(_ context.Context, key string, seq int64) (int64, error) {
+	end := versionKey(key, seq)
+	var deleted int64
+	err := s.scan(versionsPrefix(key), func(k, _ []byte) error {
+		if bytes.Compare(k, end) < 0 {
+			deleted++
+		}
+		return nil
+	})
+	if err != nil || deleted == 0 {
+		return 0, err
+	}
+	if err = s.apply(op{key: versionsPrefix(key), end: end}); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// ReplaceRecordVersion replaces the stored version of the record with its seq, if stored, holding the lock of its key
+// from checking the version until the write is committed
+func (s *pebbledb) ReplaceRecordVersion(_ context.Context, record pkarr.Record) error {
+	lock := s.keyLock(record.Key())
+	lock.Lock()
+	defer lock.Unlock()
+
+	key := versionKey(record.Key(), record.Seq)
+	stored, err := s.get(key)
+	if err != nil || stored == nil {
+		return err
+	}
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.apply(op{key: key, value: recordBytes})
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// WriteDenylistEntry adds the entry to the denylist, replacing any existing entry for its ID
+func (p postgres) WriteDenylistEntry(ctx context.Context, entry pkarr.DenylistEntry) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	return queries.WriteDenylistEntry(ctx, WriteDenylistEntryParams{
+		ID:        entry.ID,
+		Reason:    entry.Reason,
+		Timestamp: entry.Timestamp,
+	})
+}
+
+// DeleteDenylistEntry removes the entry for the given ID from the denylist, if any
+func (p postgres) DeleteDenylistEntry(ctx context.Context, id string) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	return queries.DeleteDenylistEntry(ctx, id)
+}
+
+// ListDenylistEntries returns all entries of the denylist, ordered by ID
+func (p postgres) ListDenylistEntries(ctx context.Context) ([]pkarr.DenylistEntry, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	rows, err := queries.ListDenylistEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var entries []pkarr.DenylistEntry
+	for _, row := range rows {
+		entries = append(entries, pkarr.DenylistEntry{
+			ID:        row.ID,
+			Reason:    row.Reason,
+			Timestamp: row.Timestamp,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// WriteEquivocation stores the evidence, unless evidence with the same fingerprint is already stored
+func (p postgres) WriteEquivocation(ctx context.Context, equivocation pkarr.Equivocation) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	return queries.WriteEquivocation(ctx, WriteEquivocationParams{
+		Fingerprint:   equivocation.Fingerprint(),
+		ID:            equivocation.ID,
+		Seq:           equivocation.Seq,
+		K:             equivocation.Stored.K,
+		Salt:          equivocation.Stored.Salt,
+		StoredValue:   equivocation.Stored.V,
+		StoredSig:     equivocation.Stored.Sig,
+		ObservedValue: equivocation.Observed.V,
+		ObservedSig:   equivocation.Observed.Sig,
+		Source:        equivocation.Source,
+		Timestamp:     equivocation.Timestamp,
+	})
+}
+
+// ListEquivocations returns all stored evidence, ordered by fingerprint
+func (p postgres) ListEquivocations(ctx context.Context) ([]pkarr.Equivocation, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	rows, err := queries.ListEquivocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var equivocations []pkarr.Equivocation
+	for _, row := range rows {
+		equivocations = append(equivocations, pkarr.Equivocation{
+			ID:        row.ID,
+			Seq:       row.Seq,
+			Stored:    pkarr.Record{K: row.K, V: row.StoredValue, Sig: row.StoredSig, Seq: row.Seq, Salt: row.Salt},
+			Observed:  pkarr.Record{K: row.K, V: row.ObservedValue, Sig: row.ObservedSig, Seq: row.Seq, Salt: row.Salt},
+			Source:    row.Source,
+			Timestamp: row.Timestamp,
+		})
+	}
+	return equivocations, nil
+}
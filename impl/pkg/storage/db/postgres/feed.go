@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"math"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// AppendFeedEntry appends the entry to the feed, returning the cursor assigned to it
+func (p postgres) AppendFeedEntry(ctx context.Context, entry pkarr.FeedEntry) (int64, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close(ctx)
+
+	return queries.AppendFeedEntry(ctx, AppendFeedEntryParams{
+		ID:        entry.ID,
+		Salt:      entry.Salt,
+		Seq:       entry.Seq,
+		Timestamp: entry.Timestamp,
+	})
+}
+
+// ListFeedEntries returns up to limit entries with a cursor after since, ordered by cursor
+func (p postgres) ListFeedEntries(ctx context.Context, since int64, limit int) ([]pkarr.FeedEntry, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	maxResults := int32(math.MaxInt32)
+	if limit < math.MaxInt32 {
+		maxResults = int32(limit)
+	}
+	rows, err := queries.ListFeedEntries(ctx, ListFeedEntriesParams{Since: since, MaxResults: maxResults})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]pkarr.FeedEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, pkarr.FeedEntry{
+			Cursor:    row.Cursor,
+			ID:        row.ID,
+			Salt:      row.Salt,
+			Seq:       row.Seq,
+			Timestamp: row.Timestamp,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	pgx "github.com/jackc/pgx/v5"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// AppendHistoryEntry appends the entry to the log, failing if an entry already exists at its index
+func (p postgres) AppendHistoryEntry(ctx context.Context, entry pkarr.HistoryEntry) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	return queries.WriteHistoryEntry(ctx, WriteHistoryEntryParams{
+		Idx:        entry.Index,
+		ID:         entry.ID,
+		Seq:        entry.Seq,
+		RecordHash: entry.RecordHash,
+		Timestamp:  entry.Timestamp,
+		PrevHash:   entry.PrevHash,
+		Hash:       entry.Hash,
+		Salt:       entry.Salt,
+	})
+}
+
+// ReadLatestHistoryEntry returns the last entry of the log, or nil if the log is empty
+func (p postgres) ReadLatestHistoryEntry(ctx context.Context) (*pkarr.HistoryEntry, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	row, err := queries.ReadLatestHistoryEntry(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry := fromHistoryEntryRow(row)
+	return &entry, nil
+}
+
+// ListHistoryEntries returns up to limit entries starting at the given index, ordered by index
+func (p postgres) ListHistoryEntries(ctx context.Context, from int64, limit int) ([]pkarr.HistoryEntry, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	maxResults := int32(math.MaxInt32)
+	if limit < math.MaxInt32 {
+		maxResults = int32(limit)
+	}
+	rows, err := queries.ListHistoryEntries(ctx, ListHistoryEntriesParams{FromIdx: from, MaxResults: maxResults})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]pkarr.HistoryEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, fromHistoryEntryRow(row))
+	}
+	return entries, nil
+}
+
+func fromHistoryEntryRow(row HistoryEntry) pkarr.HistoryEntry {
+	return pkarr.HistoryEntry{
+		Index:      row.Idx,
+		ID:         row.ID,
+		Salt:       row.Salt,
+		Seq:        row.Seq,
+		RecordHash: row.RecordHash,
+		Timestamp:  row.Timestamp,
+		PrevHash:   row.PrevHash,
+		Hash:       row.Hash,
+	}
+}
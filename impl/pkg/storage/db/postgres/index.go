@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"math"
+
+	"github.com/goccy/go-json"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// IndexDocument stores the document as JSONB, replacing any previously indexed version of the document
+func (p postgres) IndexDocument(ctx context.Context, doc pkarr.Document) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return queries.WriteDocument(ctx, WriteDocumentParams{
+		ID:       doc.ID,
+		Document: docBytes,
+	})
+}
+
+// DeleteDocument removes the document with the given ID from the index, if indexed
+func (p postgres) DeleteDocument(ctx context.Context, id string) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	return queries.DeleteDocument(ctx, id)
+}
+
+// QueryDocuments returns the IDs of the documents matching the query, ordered by ID
+func (p postgres) QueryDocuments(ctx context.Context, query pkarr.DocumentQuery) ([]string, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	maxResults := int32(math.MaxInt32)
+	if query.Limit > 0 && query.Limit < math.MaxInt32 {
+		maxResults = int32(query.Limit)
+	}
+	if query.Prefix {
+		return queries.QueryDocumentsByPrefix(ctx, QueryDocumentsByPrefixParams{
+			Field:      string(query.Field),
+			Prefix:     query.Value,
+			After:      query.After,
+			MaxResults: maxResults,
+		})
+	}
+	return queries.QueryDocuments(ctx, QueryDocumentsParams{
+		Field:      string(query.Field),
+		Value:      query.Value,
+		After:      query.After,
+		MaxResults: maxResults,
+	})
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// WriteJournalEntry writes the entry, replacing any entry for the same key
+func (p postgres) WriteJournalEntry(ctx context.Context, entry pkarr.JournalEntry) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	return queries.WriteJournalEntry(ctx, WriteJournalEntryParams{
+		Key:       entry.Key,
+		Seq:       entry.Seq,
+		Timestamp: entry.Timestamp,
+	})
+}
+
+// DeleteJournalEntry deletes the entry for the given key, unless it was replaced by an entry with another seq
+func (p postgres) DeleteJournalEntry(ctx context.Context, key string, seq int64) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	return queries.DeleteJournalEntry(ctx, DeleteJournalEntryParams{Key: key, Seq: seq})
+}
+
+// ListJournalEntries returns all entries, ordered by key
+func (p postgres) ListJournalEntries(ctx context.Context) ([]pkarr.JournalEntry, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	rows, err := queries.ListJournalEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]pkarr.JournalEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, pkarr.JournalEntry{Key: row.Key, Seq: row.Seq, Timestamp: row.Timestamp})
+	}
+	return entries, nil
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// WriteRecordLabels replaces the labels of the record stored under the key of the given labels
+func (p postgres) WriteRecordLabels(ctx context.Context, labels pkarr.RecordLabels) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	queries = queries.WithTx(tx)
+	if err = queries.DeleteRecordLabels(ctx, labels.Key); err != nil {
+		return err
+	}
+	for _, label := range labels.Labels {
+		err = queries.WriteRecordLabel(ctx, WriteRecordLabelParams{
+			Key:       labels.Key,
+			Label:     label,
+			Timestamp: labels.Timestamp,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteRecordLabels removes the labels of the record stored under the given key, if any
+func (p postgres) DeleteRecordLabels(ctx context.Context, key string) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	return queries.DeleteRecordLabels(ctx, key)
+}
+
+// ListRecordLabels returns the labels of all labeled records, ordered by key
+func (p postgres) ListRecordLabels(ctx context.Context) ([]pkarr.RecordLabels, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	rows, err := queries.ListRecordLabels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// rows are ordered by key, so the labels of a record are adjacent
+	var labeled []pkarr.RecordLabels
+	for _, row := range rows {
+		if n := len(labeled); n > 0 && labeled[n-1].Key == row.Key {
+			labeled[n-1].Labels = append(labeled[n-1].Labels, row.Label)
+			continue
+		}
+		labeled = append(labeled, pkarr.RecordLabels{
+			Key:       row.Key,
+			Labels:    []string{row.Label},
+			Timestamp: row.Timestamp,
+		})
+	}
+	return labeled, nil
+}
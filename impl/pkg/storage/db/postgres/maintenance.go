@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	// maintenanceBatchSize is the number of rows deleted per statement, each in its own transaction, so deleting many
+	// rows neither holds locks for long nor leaves autovacuum one huge batch of dead rows to catch up on
+	maintenanceBatchSize = 1000
+	// maintenanceBatchPause is the pause between batches, leaving room for autovacuum and other queries
+	maintenanceBatchPause = 100 * time.Millisecond
+)
+
+// Maintain deletes the versions and resolutions left behind by records deleted without them, such as by hand, in
+// batches. The space of the deleted rows is reclaimed by autovacuum.
+func (p postgres) Maintain(ctx context.Context) (pkarr.Maintenance, error) {
+	m := pkarr.Maintenance{Started: time.Now().Unix()}
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return m, err
+	}
+	defer db.Close(ctx)
+
+	for _, deleteBatch := range []func(context.Context, int32) (int64, error){
+		queries.DeleteOrphanedRecordVersions,
+		queries.DeleteOrphanedRecordResolutions,
+	} {
+		for {
+			deleted, err := deleteBatch(ctx, maintenanceBatchSize)
+			if err != nil {
+				return m, err
+			}
+			m.Deleted += deleted
+			m.Batches++
+			if deleted < maintenanceBatchSize {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return m, ctx.Err()
+			case <-time.After(maintenanceBatchPause):
+			}
+		}
+	}
+	m.Finished = time.Now().Unix()
+	return m, nil
+}
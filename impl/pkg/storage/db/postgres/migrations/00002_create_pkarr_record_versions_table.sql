@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE pkarr_record_versions (
+    key VARCHAR(43) NOT NULL, -- VARCHAR(43) holds 32 bytes base64-encoded
+    value VARCHAR(1334) NOT NULL, -- VARCHAR(1334) holds 1000 bytes base64-encoded
+    sig VARCHAR(86) NOT NULL, -- VARCHAR(86) holds 64 bytes base64-encoded
+    seq BIGINT NOT NULL,
+    PRIMARY KEY (key, seq)
+);
+
+-- +goose Down
+DROP TABLE pkarr_record_versions;
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE documents (
+    id VARCHAR(52) PRIMARY KEY NOT NULL, -- VARCHAR(52) holds 32 bytes z-base-32-encoded
+    document JSONB NOT NULL
+);
+CREATE INDEX documents_document_idx ON documents USING GIN (document);
+
+-- +goose Down
+DROP TABLE documents;
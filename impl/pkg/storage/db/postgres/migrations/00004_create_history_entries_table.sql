@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE history_entries (
+    idx BIGINT PRIMARY KEY NOT NULL,
+    id VARCHAR(52) NOT NULL, -- VARCHAR(52) holds 32 bytes z-base-32-encoded
+    seq BIGINT NOT NULL,
+    record_hash VARCHAR(64) NOT NULL, -- VARCHAR(64) holds a hex-encoded SHA-256 hash
+    timestamp BIGINT NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL,
+    hash VARCHAR(64) NOT NULL
+);
+
+-- +goose Down
+DROP TABLE history_entries;
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE denylist (
+    id VARCHAR(52) PRIMARY KEY NOT NULL, -- VARCHAR(52) holds 32 bytes z-base-32-encoded
+    reason TEXT NOT NULL,
+    timestamp BIGINT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE denylist;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- salted records are stored under their key and salt joined by a '.', VARCHAR(130) holds 32 bytes and 64 bytes base64-encoded
+ALTER TABLE pkarr_records ALTER COLUMN key TYPE VARCHAR(130);
+ALTER TABLE pkarr_records ADD COLUMN salt VARCHAR(86) NOT NULL DEFAULT ''; -- VARCHAR(86) holds 64 bytes base64-encoded
+ALTER TABLE pkarr_record_versions ALTER COLUMN key TYPE VARCHAR(130);
+ALTER TABLE pkarr_record_versions ADD COLUMN salt VARCHAR(86) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE pkarr_record_versions DROP COLUMN salt;
+ALTER TABLE pkarr_record_versions ALTER COLUMN key TYPE VARCHAR(43);
+ALTER TABLE pkarr_records DROP COLUMN salt;
+ALTER TABLE pkarr_records ALTER COLUMN key TYPE VARCHAR(43);
@@ -0,0 +1,9 @@
+-- +goose Up
+-- values encrypted at rest are prefixed with enc:<key id>: and carry a nonce and tag, VARCHAR(1384) holds 1000 bytes
+-- encrypted with AES-GCM and base64-encoded
+ALTER TABLE pkarr_records ALTER COLUMN value TYPE VARCHAR(1384);
+ALTER TABLE pkarr_record_versions ALTER COLUMN value TYPE VARCHAR(1384);
+
+-- +goose Down
+ALTER TABLE pkarr_record_versions ALTER COLUMN value TYPE VARCHAR(1334);
+ALTER TABLE pkarr_records ALTER COLUMN value TYPE VARCHAR(1334);
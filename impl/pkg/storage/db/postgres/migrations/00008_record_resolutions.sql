@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE TABLE record_resolutions (
+    key VARCHAR(130) PRIMARY KEY NOT NULL, -- VARCHAR(130) holds the key a record is stored under
+    resolved_at BIGINT NOT NULL
+);
+-- +goose Down
+DROP TABLE record_resolutions;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- records stored under hashed keys keep their key of up to 130 characters in their value, prefixed with key: and
+-- followed by a ':', VARCHAR(2009) holds such a value of 1000 bytes encrypted with AES-GCM and base64-encoded
+ALTER TABLE pkarr_records ALTER COLUMN value TYPE VARCHAR(2009);
+ALTER TABLE pkarr_record_versions ALTER COLUMN value TYPE VARCHAR(2009);
+
+-- +goose Down
+ALTER TABLE pkarr_record_versions ALTER COLUMN value TYPE VARCHAR(1384);
+ALTER TABLE pkarr_records ALTER COLUMN value TYPE VARCHAR(1384);
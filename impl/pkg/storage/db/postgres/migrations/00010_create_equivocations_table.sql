@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE equivocations (
+    fingerprint VARCHAR(64) PRIMARY KEY NOT NULL, -- VARCHAR(64) holds a hex encoded SHA-256 hash
+    id VARCHAR(52) NOT NULL, -- VARCHAR(52) holds 32 bytes z-base-32-encoded
+    seq BIGINT NOT NULL,
+    k VARCHAR(43) NOT NULL, -- VARCHAR(43) holds 32 bytes base64-encoded
+    salt VARCHAR(86) NOT NULL DEFAULT '', -- VARCHAR(86) holds 64 bytes base64-encoded
+    stored_value VARCHAR(1334) NOT NULL, -- VARCHAR(1334) holds 1000 bytes base64-encoded
+    stored_sig VARCHAR(86) NOT NULL,
+    observed_value VARCHAR(1334) NOT NULL,
+    observed_sig VARCHAR(86) NOT NULL,
+    source TEXT NOT NULL,
+    timestamp BIGINT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE equivocations;
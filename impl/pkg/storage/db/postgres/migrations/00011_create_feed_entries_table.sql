@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE feed_entries (
+    cursor BIGSERIAL PRIMARY KEY NOT NULL,
+    id VARCHAR(52) NOT NULL, -- VARCHAR(52) holds 32 bytes z-base-32-encoded
+    salt VARCHAR(86) NOT NULL DEFAULT '', -- VARCHAR(86) holds 64 bytes base64-encoded
+    seq BIGINT NOT NULL,
+    timestamp BIGINT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE feed_entries;
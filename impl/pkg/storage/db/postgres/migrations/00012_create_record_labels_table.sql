@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE record_labels (
+    key VARCHAR(130) NOT NULL, -- VARCHAR(130) holds the key a record is stored under
+    label VARCHAR(64) NOT NULL,
+    timestamp BIGINT NOT NULL,
+    PRIMARY KEY (key, label)
+);
+
+-- +goose Down
+DROP TABLE record_labels;
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE put_journal (
+    key VARCHAR(130) PRIMARY KEY NOT NULL, -- VARCHAR(130) holds the key a record is stored under
+    seq BIGINT NOT NULL,
+    timestamp BIGINT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE put_journal;
@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE tenant_records (
+    key VARCHAR(130) PRIMARY KEY NOT NULL, -- VARCHAR(130) holds the key a record is stored under
+    tenant VARCHAR(64) NOT NULL,
+    bytes BIGINT NOT NULL,
+    timestamp BIGINT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE tenant_records;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE history_entries ADD COLUMN salt VARCHAR(86) NOT NULL DEFAULT ''; -- VARCHAR(86) holds 64 bytes base64-encoded
+
+-- +goose Down
+ALTER TABLE history_entries DROP COLUMN salt;
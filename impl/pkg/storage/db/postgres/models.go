@@ -6,9 +6,86 @@ package postgres
 
 import ()
 
+type Denylist struct {
+	ID        string
+	Reason    string
+	Timestamp int64
+}
+
+type Document struct {
+	ID       string
+	Document []byte
+}
+
+type Equivocation struct {
+	Fingerprint   string
+	ID            string
+	Seq           int64
+	K             string
+	Salt          string
+	StoredValue   string
+	StoredSig     string
+	ObservedValue string
+	ObservedSig   string
+	Source        string
+	Timestamp     int64
+}
+
+type FeedEntry struct {
+	Cursor    int64
+	ID        string
+	Salt      string
+	Seq       int64
+	Timestamp int64
+}
+
+type HistoryEntry struct {
+	Idx        int64
+	ID         string
+	Seq        int64
+	RecordHash string
+	Timestamp  int64
+	PrevHash   string
+	Hash       string
+	Salt       string
+}
+
 type PkarrRecord struct {
 	Key   string
 	Value string
 	Sig   string
 	Seq   int64
+	Salt  string
+}
+
+type PkarrRecordVersion struct {
+	Key   string
+	Value string
+	Sig   string
+	Seq   int64
+	Salt  string
+}
+
+type PutJournal struct {
+	Key       string
+	Seq       int64
+	Timestamp int64
+}
+
+type RecordLabel struct {
+	Key       string
+	Label     string
+	Timestamp int64
+}
+
+type RecordResolution struct {
+	Key        string
+	ResolvedAt int64
+}
+
+type TenantRecord struct {
+	Key       string
+	Tenant    string
+	Bytes     int64
+	Timestamp int64
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
+	"strings"
 
-	"github.com/TBD54566975/did-dht-method/pkg/storage/pkarr"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
 	pgx "github.com/jackc/pgx/v5"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	goose "github.com/pressly/goose/v3"
@@ -62,17 +64,93 @@ func (p postgres) WriteRecord(ctx context.Context, record pkarr.Record) error {
 	}
 	defer db.Close(ctx)
 
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	queries = queries.WithTx(tx)
 	err = queries.WriteRecord(ctx, WriteRecordParams{
-		Key:   record.K,
+		Key:   record.Key(),
 		Value: record.V,
 		Sig:   record.Sig,
 		Seq:   record.Seq,
+		Salt:  record.Salt,
 	})
 	if err != nil {
 		return err
 	}
 
-	return nil
+	err = queries.WriteRecordVersion(ctx, WriteRecordVersionParams{
+		Key:   record.Key(),
+		Value: record.V,
+		Sig:   record.Sig,
+		Seq:   record.Seq,
+		Salt:  record.Salt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// WriteRecordIfNewer writes the given record unless the stored record has a higher seq, locking the stored record's
+// row until the transaction commits. Concurrent first writes of a key have no row to lock, so the write itself only
+// replaces a record with a seq no higher than its own.
+func (p postgres) WriteRecordIfNewer(ctx context.Context, record pkarr.Record) (*pkarr.Record, bool, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer db.Close(ctx)
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries = queries.WithTx(tx)
+	var current *pkarr.Record
+	row, err := queries.ReadRecordForUpdate(ctx, record.Key())
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, err
+	}
+	if err == nil {
+		current = &pkarr.Record{K: recordK(row.Key, row.Salt), V: row.Value, Sig: row.Sig, Seq: row.Seq, Salt: row.Salt}
+		if current.Seq > record.Seq {
+			return current, false, nil
+		}
+	}
+	written, err := queries.WriteRecordIfNewer(ctx, WriteRecordIfNewerParams{
+		Key:   record.Key(),
+		Value: record.V,
+		Sig:   record.Sig,
+		Seq:   record.Seq,
+		Salt:  record.Salt,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if written == 0 {
+		return current, false, nil
+	}
+	err = queries.WriteRecordVersion(ctx, WriteRecordVersionParams{
+		Key:   record.Key(),
+		Value: record.V,
+		Sig:   record.Sig,
+		Seq:   record.Seq,
+		Salt:  record.Salt,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+	return current, true, nil
 }
 
 func (p postgres) ReadRecord(ctx context.Context, id string) (*pkarr.Record, error) {
@@ -83,15 +161,19 @@ func (p postgres) ReadRecord(ctx context.Context, id string) (*pkarr.Record, err
 	defer db.Close(ctx)
 
 	record, err := queries.ReadRecord(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	return &pkarr.Record{
-		K:   record.Key,
-		V:   record.Value,
-		Sig: record.Sig,
-		Seq: record.Seq,
+		K:    recordK(record.Key, record.Salt),
+		V:    record.Value,
+		Sig:  record.Sig,
+		Seq:  record.Seq,
+		Salt: record.Salt,
 	}, nil
 }
 
@@ -110,10 +192,72 @@ func (p postgres) ListRecords(ctx context.Context) ([]pkarr.Record, error) {
 	var records []pkarr.Record
 	for _, row := range rows {
 		records = append(records, pkarr.Record{
-			K:   row.Key,
-			V:   row.Value,
-			Sig: row.Sig,
-			Seq: row.Seq,
+			K:    recordK(row.Key, row.Salt),
+			V:    row.Value,
+			Sig:  row.Sig,
+			Seq:  row.Seq,
+			Salt: row.Salt,
+		})
+	}
+
+	return records, nil
+}
+
+// CountRecords returns the number of stored records
+func (p postgres) CountRecords(ctx context.Context) (int64, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close(ctx)
+
+	return queries.CountRecords(ctx)
+}
+
+func (p postgres) ReadRecordVersion(ctx context.Context, id string, seq int64) (*pkarr.Record, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	record, err := queries.ReadRecordVersion(ctx, ReadRecordVersionParams{Key: id, Seq: seq})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &pkarr.Record{
+		K:    recordK(record.Key, record.Salt),
+		V:    record.Value,
+		Sig:  record.Sig,
+		Seq:  record.Seq,
+		Salt: record.Salt,
+	}, nil
+}
+
+func (p postgres) ListRecordVersions(ctx context.Context, id string) ([]pkarr.Record, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	rows, err := queries.ListRecordVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []pkarr.Record
+	for _, row := range rows {
+		records = append(records, pkarr.Record{
+			K:    recordK(row.Key, row.Salt),
+			V:    row.Value,
+			Sig:  row.Sig,
+			Seq:  row.Seq,
+			Salt: row.Salt,
 		})
 	}
 
@@ -124,3 +268,12 @@ func (p postgres) Close() error {
 	// no-op, postgres connection is closed after each request
 	return nil
 }
+
+// recordK returns the base64URL encoded key of the record stored under the given key, which includes the salt of
+// salted records
+func recordK(key, salt string) string {
+	if salt == "" {
+		return key
+	}
+	return strings.TrimSuffix(key, "."+salt)
+}
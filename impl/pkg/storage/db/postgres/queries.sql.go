@@ -9,8 +9,407 @@ import (
 	"context"
 )
 
+const appendFeedEntry = `-- name: AppendFeedEntry :one
+INSERT INTO feed_entries(id, salt, seq, timestamp) VALUES($1, $2, $3, $4) RETURNING cursor
+`
+
+type AppendFeedEntryParams struct {
+	ID        string
+	Salt      string
+	Seq       int64
+	Timestamp int64
+}
+
+func (q *Queries) AppendFeedEntry(ctx context.Context, arg AppendFeedEntryParams) (int64, error) {
+	row := q.db.QueryRow(ctx, appendFeedEntry,
+		arg.ID,
+		arg.Salt,
+		arg.Seq,
+		arg.Timestamp,
+	)
+	var cursor int64
+	err := row.Scan(&cursor)
+	return cursor, err
+}
+
+const countRecords = `-- name: CountRecords :one
+SELECT COUNT(*) FROM pkarr_records
+`
+
+func (q *Queries) CountRecords(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countRecords)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteDenylistEntry = `-- name: DeleteDenylistEntry :exec
+DELETE FROM denylist WHERE id = $1
+`
+
+func (q *Queries) DeleteDenylistEntry(ctx context.Context, id string) error {
+	_, err := q.db.Exec(ctx, deleteDenylistEntry, id)
+	return err
+}
+
+const deleteDocument = `-- name: DeleteDocument :exec
+DELETE FROM documents WHERE id = $1
+`
+
+func (q *Queries) DeleteDocument(ctx context.Context, id string) error {
+	_, err := q.db.Exec(ctx, deleteDocument, id)
+	return err
+}
+
+const deleteJournalEntry = `-- name: DeleteJournalEntry :exec
+DELETE FROM put_journal WHERE key = $1 AND seq = $2
+`
+
+type DeleteJournalEntryParams struct {
+	Key string
+	Seq int64
+}
+
+func (q *Queries) DeleteJournalEntry(ctx context.Context, arg DeleteJournalEntryParams) error {
+	_, err := q.db.Exec(ctx, deleteJournalEntry, arg.Key, arg.Seq)
+	return err
+}
+
+const deleteOrphanedRecordResolutions = `-- name: DeleteOrphanedRecordResolutions :execrows
+DELETE FROM record_resolutions WHERE key IN (
+    SELECT res.key FROM record_resolutions res
+    WHERE NOT EXISTS (SELECT 1 FROM pkarr_records r WHERE r.key = res.key)
+    LIMIT $1
+)
+`
+
+func (q *Queries) DeleteOrphanedRecordResolutions(ctx context.Context, limit int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrphanedRecordResolutions, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOrphanedRecordVersions = `-- name: DeleteOrphanedRecordVersions :execrows
+DELETE FROM pkarr_record_versions WHERE ctid IN (
+    SELECT v.ctid FROM pkarr_record_versions v
+    WHERE NOT EXISTS (SELECT 1 FROM pkarr_records r WHERE r.key = v.key)
+    LIMIT $1
+)
+`
+
+func (q *Queries) DeleteOrphanedRecordVersions(ctx context.Context, limit int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrphanedRecordVersions, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRecord = `-- name: DeleteRecord :exec
+DELETE FROM pkarr_records WHERE key = $1
+`
+
+func (q *Queries) DeleteRecord(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, deleteRecord, key)
+	return err
+}
+
+const deleteRecordLabels = `-- name: DeleteRecordLabels :exec
+DELETE FROM record_labels WHERE key = $1
+`
+
+func (q *Queries) DeleteRecordLabels(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, deleteRecordLabels, key)
+	return err
+}
+
+const deleteRecordResolution = `-- name: DeleteRecordResolution :exec
+DELETE FROM record_resolutions WHERE key = $1
+`
+
+func (q *Queries) DeleteRecordResolution(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, deleteRecordResolution, key)
+	return err
+}
+
+const deleteRecordVersions = `-- name: DeleteRecordVersions :exec
+DELETE FROM pkarr_record_versions WHERE key = $1
+`
+
+func (q *Queries) DeleteRecordVersions(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, deleteRecordVersions, key)
+	return err
+}
+
+const deleteRecordVersionsBefore = `-- name: DeleteRecordVersionsBefore :execrows
+DELETE FROM pkarr_record_versions WHERE key = $1 AND seq < $2
+`
+
+type DeleteRecordVersionsBeforeParams struct {
+	Key string
+	Seq int64
+}
+
+func (q *Queries) DeleteRecordVersionsBefore(ctx context.Context, arg DeleteRecordVersionsBeforeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRecordVersionsBefore, arg.Key, arg.Seq)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteTenantRecord = `-- name: DeleteTenantRecord :exec
+DELETE FROM tenant_records WHERE key = $1
+`
+
+func (q *Queries) DeleteTenantRecord(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, deleteTenantRecord, key)
+	return err
+}
+
+const listDenylistEntries = `-- name: ListDenylistEntries :many
+SELECT id, reason, timestamp FROM denylist ORDER BY id
+`
+
+func (q *Queries) ListDenylistEntries(ctx context.Context) ([]Denylist, error) {
+	rows, err := q.db.Query(ctx, listDenylistEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Denylist
+	for rows.Next() {
+		var i Denylist
+		if err := rows.Scan(
+			&i.ID,
+			&i.Reason,
+			&i.Timestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEquivocations = `-- name: ListEquivocations :many
+SELECT fingerprint, id, seq, k, salt, stored_value, stored_sig, observed_value, observed_sig, source, timestamp FROM equivocations ORDER BY fingerprint
+`
+
+func (q *Queries) ListEquivocations(ctx context.Context) ([]Equivocation, error) {
+	rows, err := q.db.Query(ctx, listEquivocations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Equivocation
+	for rows.Next() {
+		var i Equivocation
+		if err := rows.Scan(
+			&i.Fingerprint,
+			&i.ID,
+			&i.Seq,
+			&i.K,
+			&i.Salt,
+			&i.StoredValue,
+			&i.StoredSig,
+			&i.ObservedValue,
+			&i.ObservedSig,
+			&i.Source,
+			&i.Timestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeedEntries = `-- name: ListFeedEntries :many
+SELECT cursor, id, salt, seq, timestamp FROM feed_entries WHERE cursor > $1 ORDER BY cursor LIMIT $2
+`
+
+type ListFeedEntriesParams struct {
+	Since      int64
+	MaxResults int32
+}
+
+func (q *Queries) ListFeedEntries(ctx context.Context, arg ListFeedEntriesParams) ([]FeedEntry, error) {
+	rows, err := q.db.Query(ctx, listFeedEntries, arg.Since, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedEntry
+	for rows.Next() {
+		var i FeedEntry
+		if err := rows.Scan(
+			&i.Cursor,
+			&i.ID,
+			&i.Salt,
+			&i.Seq,
+			&i.Timestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listHistoryEntries = `-- name: ListHistoryEntries :many
+SELECT idx, id, seq, record_hash, timestamp, prev_hash, hash, salt FROM history_entries WHERE idx >= $1 ORDER BY idx LIMIT $2
+`
+
+type ListHistoryEntriesParams struct {
+	FromIdx    int64
+	MaxResults int32
+}
+
+func (q *Queries) ListHistoryEntries(ctx context.Context, arg ListHistoryEntriesParams) ([]HistoryEntry, error) {
+	rows, err := q.db.Query(ctx, listHistoryEntries, arg.FromIdx, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []HistoryEntry
+	for rows.Next() {
+		var i HistoryEntry
+		if err := rows.Scan(
+			&i.Idx,
+			&i.ID,
+			&i.Seq,
+			&i.RecordHash,
+			&i.Timestamp,
+			&i.PrevHash,
+			&i.Hash,
+			&i.Salt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listJournalEntries = `-- name: ListJournalEntries :many
+SELECT key, seq, timestamp FROM put_journal ORDER BY key
+`
+
+func (q *Queries) ListJournalEntries(ctx context.Context) ([]PutJournal, error) {
+	rows, err := q.db.Query(ctx, listJournalEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PutJournal
+	for rows.Next() {
+		var i PutJournal
+		if err := rows.Scan(&i.Key, &i.Seq, &i.Timestamp); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecordLabels = `-- name: ListRecordLabels :many
+SELECT key, label, timestamp FROM record_labels ORDER BY key, label
+`
+
+func (q *Queries) ListRecordLabels(ctx context.Context) ([]RecordLabel, error) {
+	rows, err := q.db.Query(ctx, listRecordLabels)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RecordLabel
+	for rows.Next() {
+		var i RecordLabel
+		if err := rows.Scan(&i.Key, &i.Label, &i.Timestamp); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecordResolutions = `-- name: ListRecordResolutions :many
+SELECT key, resolved_at FROM record_resolutions
+`
+
+func (q *Queries) ListRecordResolutions(ctx context.Context) ([]RecordResolution, error) {
+	rows, err := q.db.Query(ctx, listRecordResolutions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RecordResolution
+	for rows.Next() {
+		var i RecordResolution
+		if err := rows.Scan(&i.Key, &i.ResolvedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecordVersions = `-- name: ListRecordVersions :many
+SELECT key, value, sig, seq, salt FROM pkarr_record_versions WHERE key = $1 ORDER BY seq
+`
+
+func (q *Queries) ListRecordVersions(ctx context.Context, key string) ([]PkarrRecordVersion, error) {
+	rows, err := q.db.Query(ctx, listRecordVersions, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PkarrRecordVersion
+	for rows.Next() {
+		var i PkarrRecordVersion
+		if err := rows.Scan(
+			&i.Key,
+			&i.Value,
+			&i.Sig,
+			&i.Seq,
+			&i.Salt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listRecords = `-- name: ListRecords :many
-SELECT key, value, sig, seq FROM pkarr_records
+SELECT key, value, sig, seq, salt FROM pkarr_records
 `
 
 func (q *Queries) ListRecords(ctx context.Context) ([]PkarrRecord, error) {
@@ -27,6 +426,7 @@ func (q *Queries) ListRecords(ctx context.Context) ([]PkarrRecord, error) {
 			&i.Value,
 			&i.Sig,
 			&i.Seq,
+			&i.Salt,
 		); err != nil {
 			return nil, err
 		}
@@ -38,8 +438,179 @@ func (q *Queries) ListRecords(ctx context.Context) ([]PkarrRecord, error) {
 	return items, nil
 }
 
+const listTenantRecords = `-- name: ListTenantRecords :many
+SELECT key, tenant, bytes, timestamp FROM tenant_records ORDER BY key
+`
+
+func (q *Queries) ListTenantRecords(ctx context.Context) ([]TenantRecord, error) {
+	rows, err := q.db.Query(ctx, listTenantRecords)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TenantRecord
+	for rows.Next() {
+		var i TenantRecord
+		if err := rows.Scan(
+			&i.Key,
+			&i.Tenant,
+			&i.Bytes,
+			&i.Timestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVersionSeqs = `-- name: ListVersionSeqs :many
+SELECT key, seq FROM pkarr_record_versions ORDER BY key, seq
+`
+
+type ListVersionSeqsRow struct {
+	Key string
+	Seq int64
+}
+
+func (q *Queries) ListVersionSeqs(ctx context.Context) ([]ListVersionSeqsRow, error) {
+	rows, err := q.db.Query(ctx, listVersionSeqs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListVersionSeqsRow
+	for rows.Next() {
+		var i ListVersionSeqsRow
+		if err := rows.Scan(&i.Key, &i.Seq); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markRecordResolved = `-- name: MarkRecordResolved :exec
+INSERT INTO record_resolutions(key, resolved_at) SELECT key, $1::BIGINT FROM pkarr_records WHERE key = $2
+ON CONFLICT (key) DO UPDATE SET resolved_at = EXCLUDED.resolved_at
+`
+
+type MarkRecordResolvedParams struct {
+	ResolvedAt int64
+	Key        string
+}
+
+func (q *Queries) MarkRecordResolved(ctx context.Context, arg MarkRecordResolvedParams) error {
+	_, err := q.db.Exec(ctx, markRecordResolved, arg.ResolvedAt, arg.Key)
+	return err
+}
+
+const queryDocuments = `-- name: QueryDocuments :many
+SELECT id FROM documents
+WHERE document -> $1::text @> to_jsonb($2::text) AND id > $3::text
+ORDER BY id LIMIT $4
+`
+
+type QueryDocumentsParams struct {
+	Field      string
+	Value      string
+	After      string
+	MaxResults int32
+}
+
+func (q *Queries) QueryDocuments(ctx context.Context, arg QueryDocumentsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, queryDocuments,
+		arg.Field,
+		arg.Value,
+		arg.After,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const queryDocumentsByPrefix = `-- name: QueryDocumentsByPrefix :many
+SELECT id FROM documents
+WHERE EXISTS (
+    SELECT 1 FROM jsonb_array_elements_text(document -> $1::text) AS v WHERE starts_with(v, $2::text)
+) AND id > $3::text
+ORDER BY id LIMIT $4
+`
+
+type QueryDocumentsByPrefixParams struct {
+	Field      string
+	Prefix     string
+	After      string
+	MaxResults int32
+}
+
+func (q *Queries) QueryDocumentsByPrefix(ctx context.Context, arg QueryDocumentsByPrefixParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, queryDocumentsByPrefix,
+		arg.Field,
+		arg.Prefix,
+		arg.After,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const readLatestHistoryEntry = `-- name: ReadLatestHistoryEntry :one
+SELECT idx, id, seq, record_hash, timestamp, prev_hash, hash, salt FROM history_entries ORDER BY idx DESC LIMIT 1
+`
+
+func (q *Queries) ReadLatestHistoryEntry(ctx context.Context) (HistoryEntry, error) {
+	row := q.db.QueryRow(ctx, readLatestHistoryEntry)
+	var i HistoryEntry
+	err := row.Scan(
+		&i.Idx,
+		&i.ID,
+		&i.Seq,
+		&i.RecordHash,
+		&i.Timestamp,
+		&i.PrevHash,
+		&i.Hash,
+		&i.Salt,
+	)
+	return i, err
+}
+
 const readRecord = `-- name: ReadRecord :one
-SELECT key, value, sig, seq FROM pkarr_records WHERE key = $1 LIMIT 1
+SELECT key, value, sig, seq, salt FROM pkarr_records WHERE key = $1 LIMIT 1
 `
 
 func (q *Queries) ReadRecord(ctx context.Context, key string) (PkarrRecord, error) {
@@ -50,12 +621,224 @@ func (q *Queries) ReadRecord(ctx context.Context, key string) (PkarrRecord, erro
 		&i.Value,
 		&i.Sig,
 		&i.Seq,
+		&i.Salt,
 	)
 	return i, err
 }
 
+const readRecordForUpdate = `-- name: ReadRecordForUpdate :one
+SELECT key, value, sig, seq, salt FROM pkarr_records WHERE key = $1 LIMIT 1 FOR UPDATE
+`
+
+func (q *Queries) ReadRecordForUpdate(ctx context.Context, key string) (PkarrRecord, error) {
+	row := q.db.QueryRow(ctx, readRecordForUpdate, key)
+	var i PkarrRecord
+	err := row.Scan(
+		&i.Key,
+		&i.Value,
+		&i.Sig,
+		&i.Seq,
+		&i.Salt,
+	)
+	return i, err
+}
+
+const readRecordVersion = `-- name: ReadRecordVersion :one
+SELECT key, value, sig, seq, salt FROM pkarr_record_versions WHERE key = $1 AND seq = $2 LIMIT 1
+`
+
+type ReadRecordVersionParams struct {
+	Key string
+	Seq int64
+}
+
+func (q *Queries) ReadRecordVersion(ctx context.Context, arg ReadRecordVersionParams) (PkarrRecordVersion, error) {
+	row := q.db.QueryRow(ctx, readRecordVersion, arg.Key, arg.Seq)
+	var i PkarrRecordVersion
+	err := row.Scan(
+		&i.Key,
+		&i.Value,
+		&i.Sig,
+		&i.Seq,
+		&i.Salt,
+	)
+	return i, err
+}
+
+const readTenantRecord = `-- name: ReadTenantRecord :one
+SELECT key, tenant, bytes, timestamp FROM tenant_records WHERE key = $1 LIMIT 1
+`
+
+func (q *Queries) ReadTenantRecord(ctx context.Context, key string) (TenantRecord, error) {
+	row := q.db.QueryRow(ctx, readTenantRecord, key)
+	var i TenantRecord
+	err := row.Scan(
+		&i.Key,
+		&i.Tenant,
+		&i.Bytes,
+		&i.Timestamp,
+	)
+	return i, err
+}
+
+const recordUsage = `-- name: RecordUsage :one
+SELECT
+    (SELECT COUNT(*) FROM pkarr_records)::BIGINT AS records,
+    ((SELECT COALESCE(SUM(LENGTH(key) + LENGTH(value) + LENGTH(sig) + LENGTH(salt) + 8), 0) FROM pkarr_records) +
+     (SELECT COALESCE(SUM(LENGTH(key) + LENGTH(value) + LENGTH(sig) + LENGTH(salt) + 8), 0) FROM pkarr_record_versions))::BIGINT AS bytes
+`
+
+type RecordUsageRow struct {
+	Records int64
+	Bytes   int64
+}
+
+func (q *Queries) RecordUsage(ctx context.Context) (RecordUsageRow, error) {
+	row := q.db.QueryRow(ctx, recordUsage)
+	var i RecordUsageRow
+	err := row.Scan(&i.Records, &i.Bytes)
+	return i, err
+}
+
+const replaceRecordVersion = `-- name: ReplaceRecordVersion :exec
+UPDATE pkarr_record_versions SET value = $3, sig = $4 WHERE key = $1 AND seq = $2
+`
+
+type ReplaceRecordVersionParams struct {
+	Key   string
+	Seq   int64
+	Value string
+	Sig   string
+}
+
+func (q *Queries) ReplaceRecordVersion(ctx context.Context, arg ReplaceRecordVersionParams) error {
+	_, err := q.db.Exec(ctx, replaceRecordVersion,
+		arg.Key,
+		arg.Seq,
+		arg.Value,
+		arg.Sig,
+	)
+	return err
+}
+
+const writeDenylistEntry = `-- name: WriteDenylistEntry :exec
+INSERT INTO denylist(id, reason, timestamp) VALUES($1, $2, $3)
+ON CONFLICT (id) DO UPDATE SET reason = EXCLUDED.reason, timestamp = EXCLUDED.timestamp
+`
+
+type WriteDenylistEntryParams struct {
+	ID        string
+	Reason    string
+	Timestamp int64
+}
+
+func (q *Queries) WriteDenylistEntry(ctx context.Context, arg WriteDenylistEntryParams) error {
+	_, err := q.db.Exec(ctx, writeDenylistEntry,
+		arg.ID,
+		arg.Reason,
+		arg.Timestamp,
+	)
+	return err
+}
+
+const writeDocument = `-- name: WriteDocument :exec
+INSERT INTO documents(id, document) VALUES($1, $2) ON CONFLICT (id) DO UPDATE SET document = EXCLUDED.document
+`
+
+type WriteDocumentParams struct {
+	ID       string
+	Document []byte
+}
+
+func (q *Queries) WriteDocument(ctx context.Context, arg WriteDocumentParams) error {
+	_, err := q.db.Exec(ctx, writeDocument, arg.ID, arg.Document)
+	return err
+}
+
+const writeEquivocation = `-- name: WriteEquivocation :exec
+INSERT INTO equivocations(fingerprint, id, seq, k, salt, stored_value, stored_sig, observed_value, observed_sig, source, timestamp)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (fingerprint) DO NOTHING
+`
+
+type WriteEquivocationParams struct {
+	Fingerprint   string
+	ID            string
+	Seq           int64
+	K             string
+	Salt          string
+	StoredValue   string
+	StoredSig     string
+	ObservedValue string
+	ObservedSig   string
+	Source        string
+	Timestamp     int64
+}
+
+func (q *Queries) WriteEquivocation(ctx context.Context, arg WriteEquivocationParams) error {
+	_, err := q.db.Exec(ctx, writeEquivocation,
+		arg.Fingerprint,
+		arg.ID,
+		arg.Seq,
+		arg.K,
+		arg.Salt,
+		arg.StoredValue,
+		arg.StoredSig,
+		arg.ObservedValue,
+		arg.ObservedSig,
+		arg.Source,
+		arg.Timestamp,
+	)
+	return err
+}
+
+const writeHistoryEntry = `-- name: WriteHistoryEntry :exec
+INSERT INTO history_entries(idx, id, seq, record_hash, timestamp, prev_hash, hash, salt) VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+`
+
+type WriteHistoryEntryParams struct {
+	Idx        int64
+	ID         string
+	Seq        int64
+	RecordHash string
+	Timestamp  int64
+	PrevHash   string
+	Hash       string
+	Salt       string
+}
+
+func (q *Queries) WriteHistoryEntry(ctx context.Context, arg WriteHistoryEntryParams) error {
+	_, err := q.db.Exec(ctx, writeHistoryEntry,
+		arg.Idx,
+		arg.ID,
+		arg.Seq,
+		arg.RecordHash,
+		arg.Timestamp,
+		arg.PrevHash,
+		arg.Hash,
+		arg.Salt,
+	)
+	return err
+}
+
+const writeJournalEntry = `-- name: WriteJournalEntry :exec
+INSERT INTO put_journal(key, seq, timestamp) VALUES($1, $2, $3)
+ON CONFLICT (key) DO UPDATE SET seq = EXCLUDED.seq, timestamp = EXCLUDED.timestamp
+`
+
+type WriteJournalEntryParams struct {
+	Key       string
+	Seq       int64
+	Timestamp int64
+}
+
+func (q *Queries) WriteJournalEntry(ctx context.Context, arg WriteJournalEntryParams) error {
+	_, err := q.db.Exec(ctx, writeJournalEntry, arg.Key, arg.Seq, arg.Timestamp)
+	return err
+}
+
 const writeRecord = `-- name: WriteRecord :exec
-INSERT INTO pkarr_records(key, value, sig, seq) VALUES($1, $2, $3, $4)
+INSERT INTO pkarr_records(key, value, sig, seq, salt) VALUES($1, $2, $3, $4, $5)
 `
 
 type WriteRecordParams struct {
@@ -63,6 +846,7 @@ type WriteRecordParams struct {
 	Value string
 	Sig   string
 	Seq   int64
+	Salt  string
 }
 
 func (q *Queries) WriteRecord(ctx context.Context, arg WriteRecordParams) error {
@@ -71,6 +855,95 @@ func (q *Queries) WriteRecord(ctx context.Context, arg WriteRecordParams) error
 		arg.Value,
 		arg.Sig,
 		arg.Seq,
+		arg.Salt,
+	)
+	return err
+}
+
+const writeRecordIfNewer = `-- name: WriteRecordIfNewer :execrows
+INSERT INTO pkarr_records(key, value, sig, seq, salt) VALUES($1, $2, $3, $4, $5)
+ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, sig = EXCLUDED.sig, seq = EXCLUDED.seq, salt = EXCLUDED.salt
+WHERE pkarr_records.seq <= EXCLUDED.seq
+`
+
+type WriteRecordIfNewerParams struct {
+	Key   string
+	Value string
+	Sig   string
+	Seq   int64
+	Salt  string
+}
+
+func (q *Queries) WriteRecordIfNewer(ctx context.Context, arg WriteRecordIfNewerParams) (int64, error) {
+	result, err := q.db.Exec(ctx, writeRecordIfNewer,
+		arg.Key,
+		arg.Value,
+		arg.Sig,
+		arg.Seq,
+		arg.Salt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const writeRecordLabel = `-- name: WriteRecordLabel :exec
+INSERT INTO record_labels(key, label, timestamp) VALUES($1, $2, $3)
+`
+
+type WriteRecordLabelParams struct {
+	Key       string
+	Label     string
+	Timestamp int64
+}
+
+func (q *Queries) WriteRecordLabel(ctx context.Context, arg WriteRecordLabelParams) error {
+	_, err := q.db.Exec(ctx, writeRecordLabel, arg.Key, arg.Label, arg.Timestamp)
+	return err
+}
+
+const writeRecordVersion = `-- name: WriteRecordVersion :exec
+INSERT INTO pkarr_record_versions(key, value, sig, seq, salt) VALUES($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING
+`
+
+type WriteRecordVersionParams struct {
+	Key   string
+	Value string
+	Sig   string
+	Seq   int64
+	Salt  string
+}
+
+func (q *Queries) WriteRecordVersion(ctx context.Context, arg WriteRecordVersionParams) error {
+	_, err := q.db.Exec(ctx, writeRecordVersion,
+		arg.Key,
+		arg.Value,
+		arg.Sig,
+		arg.Seq,
+		arg.Salt,
+	)
+	return err
+}
+
+const writeTenantRecord = `-- name: WriteTenantRecord :exec
+INSERT INTO tenant_records(key, tenant, bytes, timestamp) VALUES($1, $2, $3, $4)
+ON CONFLICT (key) DO UPDATE SET tenant = EXCLUDED.tenant, bytes = EXCLUDED.bytes, timestamp = EXCLUDED.timestamp
+`
+
+type WriteTenantRecordParams struct {
+	Key       string
+	Tenant    string
+	Bytes     int64
+	Timestamp int64
+}
+
+func (q *Queries) WriteTenantRecord(ctx context.Context, arg WriteTenantRecordParams) error {
+	_, err := q.db.Exec(ctx, writeTenantRecord,
+		arg.Key,
+		arg.Tenant,
+		arg.Bytes,
+		arg.Timestamp,
 	)
 	return err
 }
@@ -1,8 +1,156 @@
 -- name: WriteRecord :exec
-INSERT INTO pkarr_records(key, value, sig, seq) VALUES($1, $2, $3, $4);
+INSERT INTO pkarr_records(key, value, sig, seq, salt) VALUES($1, $2, $3, $4, $5);
 
 -- name: ReadRecord :one
 SELECT * FROM pkarr_records WHERE key = $1 LIMIT 1;
 
+-- name: ReadRecordForUpdate :one
+SELECT * FROM pkarr_records WHERE key = $1 LIMIT 1 FOR UPDATE;
+
+-- name: WriteRecordIfNewer :execrows
+INSERT INTO pkarr_records(key, value, sig, seq, salt) VALUES($1, $2, $3, $4, $5)
+ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, sig = EXCLUDED.sig, seq = EXCLUDED.seq, salt = EXCLUDED.salt
+WHERE pkarr_records.seq <= EXCLUDED.seq;
+
 -- name: ListRecords :many
-SELECT * FROM pkarr_records;
\ No newline at end of file
+SELECT * FROM pkarr_records;
+
+-- name: CountRecords :one
+SELECT COUNT(*) FROM pkarr_records;
+
+-- name: RecordUsage :one
+SELECT
+    (SELECT COUNT(*) FROM pkarr_records)::BIGINT AS records,
+    ((SELECT COALESCE(SUM(LENGTH(key) + LENGTH(value) + LENGTH(sig) + LENGTH(salt) + 8), 0) FROM pkarr_records) +
+     (SELECT COALESCE(SUM(LENGTH(key) + LENGTH(value) + LENGTH(sig) + LENGTH(salt) + 8), 0) FROM pkarr_record_versions))::BIGINT AS bytes;
+
+-- name: DeleteRecord :exec
+DELETE FROM pkarr_records WHERE key = $1;
+
+-- name: DeleteRecordVersions :exec
+DELETE FROM pkarr_record_versions WHERE key = $1;
+
+-- name: DeleteRecordResolution :exec
+DELETE FROM record_resolutions WHERE key = $1;
+
+-- name: DeleteOrphanedRecordVersions :execrows
+DELETE FROM pkarr_record_versions WHERE ctid IN (
+    SELECT v.ctid FROM pkarr_record_versions v
+    WHERE NOT EXISTS (SELECT 1 FROM pkarr_records r WHERE r.key = v.key)
+    LIMIT $1
+);
+
+-- name: DeleteOrphanedRecordResolutions :execrows
+DELETE FROM record_resolutions WHERE key IN (
+    SELECT res.key FROM record_resolutions res
+    WHERE NOT EXISTS (SELECT 1 FROM pkarr_records r WHERE r.key = res.key)
+    LIMIT $1
+);
+
+-- name: MarkRecordResolved :exec
+INSERT INTO record_resolutions(key, resolved_at) SELECT key, sqlc.arg(resolved_at)::BIGINT FROM pkarr_records WHERE key = sqlc.arg(key)
+ON CONFLICT (key) DO UPDATE SET resolved_at = EXCLUDED.resolved_at;
+
+-- name: ListRecordResolutions :many
+SELECT * FROM record_resolutions;
+
+-- name: WriteRecordVersion :exec
+INSERT INTO pkarr_record_versions(key, value, sig, seq, salt) VALUES($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING;
+
+-- name: ReplaceRecordVersion :exec
+UPDATE pkarr_record_versions SET value = $3, sig = $4 WHERE key = $1 AND seq = $2;
+
+-- name: ReadRecordVersion :one
+SELECT * FROM pkarr_record_versions WHERE key = $1 AND seq = $2 LIMIT 1;
+
+-- name: ListRecordVersions :many
+SELECT * FROM pkarr_record_versions WHERE key = $1 ORDER BY seq;
+
+-- name: ListVersionSeqs :many
+SELECT key, seq FROM pkarr_record_versions ORDER BY key, seq;
+
+-- name: DeleteRecordVersionsBefore :execrows
+DELETE FROM pkarr_record_versions WHERE key = $1 AND seq < $2;
+
+-- name: WriteDocument :exec
+INSERT INTO documents(id, document) VALUES($1, $2) ON CONFLICT (id) DO UPDATE SET document = EXCLUDED.document;
+
+-- name: DeleteDocument :exec
+DELETE FROM documents WHERE id = $1;
+
+-- name: QueryDocuments :many
+SELECT id FROM documents
+WHERE document -> sqlc.arg(field)::text @> to_jsonb(sqlc.arg(value)::text) AND id > sqlc.arg(after)::text
+ORDER BY id LIMIT sqlc.arg(max_results);
+
+-- name: QueryDocumentsByPrefix :many
+SELECT id FROM documents
+WHERE EXISTS (
+    SELECT 1 FROM jsonb_array_elements_text(document -> sqlc.arg(field)::text) AS v WHERE starts_with(v, sqlc.arg(prefix)::text)
+) AND id > sqlc.arg(after)::text
+ORDER BY id LIMIT sqlc.arg(max_results);
+
+-- name: WriteHistoryEntry :exec
+INSERT INTO history_entries(idx, id, seq, record_hash, timestamp, prev_hash, hash, salt) VALUES($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ReadLatestHistoryEntry :one
+SELECT * FROM history_entries ORDER BY idx DESC LIMIT 1;
+
+-- name: ListHistoryEntries :many
+SELECT * FROM history_entries WHERE idx >= sqlc.arg(from_idx) ORDER BY idx LIMIT sqlc.arg(max_results);
+
+-- name: WriteDenylistEntry :exec
+INSERT INTO denylist(id, reason, timestamp) VALUES($1, $2, $3)
+ON CONFLICT (id) DO UPDATE SET reason = EXCLUDED.reason, timestamp = EXCLUDED.timestamp;
+
+-- name: DeleteDenylistEntry :exec
+DELETE FROM denylist WHERE id = $1;
+
+-- name: ListDenylistEntries :many
+SELECT * FROM denylist ORDER BY id;
+
+-- name: WriteEquivocation :exec
+INSERT INTO equivocations(fingerprint, id, seq, k, salt, stored_value, stored_sig, observed_value, observed_sig, source, timestamp)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (fingerprint) DO NOTHING;
+
+-- name: ListEquivocations :many
+SELECT * FROM equivocations ORDER BY fingerprint;
+
+-- name: AppendFeedEntry :one
+INSERT INTO feed_entries(id, salt, seq, timestamp) VALUES($1, $2, $3, $4) RETURNING cursor;
+
+-- name: ListFeedEntries :many
+SELECT * FROM feed_entries WHERE cursor > sqlc.arg(since) ORDER BY cursor LIMIT sqlc.arg(max_results);
+
+-- name: WriteRecordLabel :exec
+INSERT INTO record_labels(key, label, timestamp) VALUES($1, $2, $3);
+
+-- name: DeleteRecordLabels :exec
+DELETE FROM record_labels WHERE key = $1;
+
+-- name: ListRecordLabels :many
+SELECT * FROM record_labels ORDER BY key, label;
+
+-- name: WriteJournalEntry :exec
+INSERT INTO put_journal(key, seq, timestamp) VALUES($1, $2, $3)
+ON CONFLICT (key) DO UPDATE SET seq = EXCLUDED.seq, timestamp = EXCLUDED.timestamp;
+
+-- name: DeleteJournalEntry :exec
+DELETE FROM put_journal WHERE key = $1 AND seq = $2;
+
+-- name: ListJournalEntries :many
+SELECT * FROM put_journal ORDER BY key;
+
+-- name: WriteTenantRecord :exec
+INSERT INTO tenant_records(key, tenant, bytes, timestamp) VALUES($1, $2, $3, $4)
+ON CONFLICT (key) DO UPDATE SET tenant = EXCLUDED.tenant, bytes = EXCLUDED.bytes, timestamp = EXCLUDED.timestamp;
+
+-- name: ReadTenantRecord :one
+SELECT * FROM tenant_records WHERE key = $1 LIMIT 1;
+
+-- name: DeleteTenantRecord :exec
+DELETE FROM tenant_records WHERE key = $1;
+
+-- name: ListTenantRecords :many
+SELECT * FROM tenant_records ORDER BY key;
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// Usage returns the number of stored records and the bytes used by them and their versions, counting the size of
+// their columns
+func (p postgres) Usage(ctx context.Context) (pkarr.Usage, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return pkarr.Usage{}, err
+	}
+	defer db.Close(ctx)
+
+	row, err := queries.RecordUsage(ctx)
+	if err != nil {
+		return pkarr.Usage{}, err
+	}
+	return pkarr.Usage{Records: row.Records, Bytes: row.Bytes}, nil
+}
+
+// DeleteRecord deletes the record stored under the given key, with all of its versions
+func (p postgres) DeleteRecord(ctx context.Context, key string) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	queries = queries.WithTx(tx)
+	if err = queries.DeleteRecord(ctx, key); err != nil {
+		return err
+	}
+	if err = queries.DeleteRecordVersions(ctx, key); err != nil {
+		return err
+	}
+	if err = queries.DeleteRecordResolution(ctx, key); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// MarkResolved sets the time the record stored under the given key was last resolved, if it is stored
+func (p postgres) MarkResolved(ctx context.Context, key string, at time.Time) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	return queries.MarkRecordResolved(ctx, MarkRecordResolvedParams{ResolvedAt: at.Unix(), Key: key})
+}
+
+// ListResolved returns the unix time each record was last resolved at, by the key it is stored under
+func (p postgres) ListResolved(ctx context.Context) (map[string]int64, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	rows, err := queries.ListRecordResolutions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolved := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		resolved[row.Key] = row.ResolvedAt
+	}
+	return resolved, nil
+}
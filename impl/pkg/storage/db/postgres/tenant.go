@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// WriteTenantRecord replaces the attribution of the record stored under the key of the given attribution
+func (p postgres) WriteTenantRecord(ctx context.Context, record pkarr.TenantRecord) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	return queries.WriteTenantRecord(ctx, WriteTenantRecordParams{
+		Key:       record.Key,
+		Tenant:    record.Tenant,
+		Bytes:     record.Bytes,
+		Timestamp: record.Timestamp,
+	})
+}
+
+// ReadTenantRecord returns the attribution of the record stored under the given key, or nil if it has none
+func (p postgres) ReadTenantRecord(ctx context.Context, key string) (*pkarr.TenantRecord, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	row, err := queries.ReadTenantRecord(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pkarr.TenantRecord{Key: row.Key, Tenant: row.Tenant, Bytes: row.Bytes, Timestamp: row.Timestamp}, nil
+}
+
+// DeleteTenantRecord removes the attribution of the record stored under the given key, if any
+func (p postgres) DeleteTenantRecord(ctx context.Context, key string) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	return queries.DeleteTenantRecord(ctx, key)
+}
+
+// ListTenantRecords returns the attributions of all attributed records, ordered by key
+func (p postgres) ListTenantRecords(ctx context.Context) ([]pkarr.TenantRecord, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	rows, err := queries.ListTenantRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]pkarr.TenantRecord, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, pkarr.TenantRecord{Key: row.Key, Tenant: row.Tenant, Bytes: row.Bytes, Timestamp: row.Timestamp})
+	}
+	return records, nil
+}
@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// ListVersionSeqs returns the seqs of the stored versions of each record, ordered by seq, by the key it is stored under
+func (p postgres) ListVersionSeqs(ctx context.Context) (map[string][]int64, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(ctx)
+
+	rows, err := queries.ListVersionSeqs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seqs := make(map[string][]int64)
+	for _, row := range rows {
+		seqs[row.Key] = append(seqs[row.Key], row.Seq)
+	}
+	return seqs, nil
+}
+
+// DeleteRecordVersionsBefore deletes the versions of the record stored under the given key with a seq lower than the
+// given seq, returning how many it deleted
+func (p postgres) DeleteRecordVersionsBefore(ctx context.Context, key string, seq int64) (int64, error) {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close(ctx)
+
+	return queries.DeleteRecordVersionsBefore(ctx, DeleteRecordVersionsBeforeParams{Key: key, Seq: seq})
+}
+
+// ReplaceRecordVersion replaces the stored version of the record with its seq, if stored
+func (p postgres) ReplaceRecordVersion(ctx context.Context, record pkarr.Record) error {
+	queries, db, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close(ctx)
+
+	return queries.ReplaceRecordVersion(ctx, ReplaceRecordVersionParams{
+		Key:   record.Key(),
+		Seq:   record.Seq,
+		Value: record.V,
+		Sig:   record.Sig,
+	})
+}
@@ -0,0 +1,331 @@
+package storage
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	// encryptedPrefix starts encrypted values, and can't start a plaintext value since ':' isn't in the base64URL
+	// alphabet, so records written before encryption was enabled are still readable
+	encryptedPrefix = "enc:"
+	// keyIDLength is the length of the base64URL encoded sha256 prefix identifying the key a value is encrypted with
+	keyIDLength = 8
+)
+
+// Encrypted is a Storage which encrypts the values of records at rest with AES-256-GCM. Values are encrypted with the
+// first key, and decrypted with whichever key they were encrypted with, so keys can be rotated by adding a new key
+// first, re-encrypting the stored records with ReencryptRecords, and removing the old one once KeyUsage reports no
+// stored values are encrypted with it.
+type Encrypted struct {
+	Storage
+	keyID string
+	keys  map[string]cipher.AEAD
+}
+
+// WithEncryption wraps the storage to encrypt record values with the keys of the config, or returns it unchanged if
+// none are configured
+func WithEncryption(db Storage, cfg config.EncryptionConfig) (Storage, error) {
+	encodedKeys := cfg.Keys
+	if cfg.KeysFile != "" {
+		contents, err := os.ReadFile(cfg.KeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption keys file: %w", err)
+		}
+		encodedKeys = append(strings.Fields(string(contents)), encodedKeys...)
+	}
+	if len(encodedKeys) == 0 {
+		return db, nil
+	}
+	keys := make([][]byte, 0, len(encodedKeys))
+	for _, encoded := range encodedKeys {
+		key, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode encryption key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return NewEncrypted(db, keys)
+}
+
+// NewEncrypted wraps the storage to encrypt record values with the first of the given 32 byte keys, and decrypt them
+// with any of them
+func NewEncrypted(db Storage, keys [][]byte) (*Encrypted, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one encryption key is required")
+	}
+	e := Encrypted{Storage: db, keys: make(map[string]cipher.AEAD, len(keys))}
+	for i, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %d must be 32 bytes, got %d", i, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		id := encryptionKeyID(key)
+		if i == 0 {
+			e.keyID = id
+		}
+		e.keys[id] = aead
+	}
+	return &e, nil
+}
+
+// encryptionKeyID identifies a key without revealing it
+func encryptionKeyID(key []byte) string {
+	hash := sha256.Sum256(key)
+	return base64.RawURLEncoding.EncodeToString(hash[:])[:keyIDLength]
+}
+
+// Unwrap returns the underlying storage
+func (e *Encrypted) Unwrap() Storage {
+	return e.Storage
+}
+
+func (e *Encrypted) WriteRecord(ctx context.Context, record pkarr.Record) error {
+	v, err := e.encrypt(record.V, record.Key())
+	if err != nil {
+		return err
+	}
+	record.V = v
+	return e.Storage.WriteRecord(ctx, record)
+}
+
+func (e *Encrypted) WriteRecordIfNewer(ctx context.Context, record pkarr.Record) (*pkarr.Record, bool, error) {
+	v, err := e.encrypt(record.V, record.Key())
+	if err != nil {
+		return nil, false, err
+	}
+	record.V = v
+	current, written, err := WriteRecordIfNewer(ctx, e.Storage, record)
+	if err != nil || current == nil {
+		return current, written, err
+	}
+	return current, written, e.decryptRecord(current)
+}
+
+func (e *Encrypted) ReadRecord(ctx context.Context, id string) (*pkarr.Record, error) {
+	record, err := e.Storage.ReadRecord(ctx, id)
+	if err != nil || record == nil {
+		return record, err
+	}
+	return record, e.decryptRecord(record)
+}
+
+func (e *Encrypted) ListRecords(ctx context.Context) ([]pkarr.Record, error) {
+	records, err := e.Storage.ListRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return records, e.decryptRecords(records)
+}
+
+func (e *Encrypted) ReadRecordVersion(ctx context.Context, id string, seq int64) (*pkarr.Record, error) {
+	record, err := e.Storage.ReadRecordVersion(ctx, id, seq)
+	if err != nil || record == nil {
+		return record, err
+	}
+	return record, e.decryptRecord(record)
+}
+
+func (e *Encrypted) ListRecordVersions(ctx context.Context, id string) ([]pkarr.Record, error) {
+	records, err := e.Storage.ListRecordVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return records, e.decryptRecords(records)
+}
+
+func (e *Encrypted) decryptRecords(records []pkarr.Record) error {
+	for i := range records {
+		if err := e.decryptRecord(&records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encrypted) decryptRecord(record *pkarr.Record) error {
+	v, err := e.decrypt(record.V, record.Key())
+	if err != nil {
+		return fmt.Errorf("failed to decrypt record[%s]: %w", record.Key(), err)
+	}
+	record.V = v
+	return nil
+}
+
+// encrypt returns the value as enc:<key id>:<base64URL encoded nonce and ciphertext>, authenticating the key it is
+// stored under so values can't be swapped between records
+func (e *Encrypted) encrypt(v, key string) (string, error) {
+	aead := e.keys[e.keyID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(v), []byte(key))
+	return encryptedPrefix + e.keyID + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt returns the plaintext of an encrypted value, or the value itself if it isn't encrypted
+func (e *Encrypted) decrypt(v, key string) (string, error) {
+	if !strings.HasPrefix(v, encryptedPrefix) {
+		return v, nil
+	}
+	keyID, encoded, ok := strings.Cut(strings.TrimPrefix(v, encryptedPrefix), ":")
+	if !ok {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	aead, ok := e.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("value is encrypted with unknown key[%s]", keyID)
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(key))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// KeyUsage counts the stored values of records and their versions by the key they are encrypted with
+func (e *Encrypted) KeyUsage(ctx context.Context) (pkarr.EncryptionKeyUsage, error) {
+	usage := pkarr.EncryptionKeyUsage{CurrentKey: e.keyID, Keys: make(map[string]int64)}
+	count := func(records []pkarr.Record) {
+		for _, record := range records {
+			if keyID, ok := encryptionKeyOf(record.V); ok {
+				usage.Keys[keyID]++
+			} else {
+				usage.Plaintext++
+			}
+		}
+	}
+	records, err := e.Storage.ListRecords(ctx)
+	if err != nil {
+		return usage, err
+	}
+	count(records)
+	for _, record := range records {
+		versions, err := e.Storage.ListRecordVersions(ctx, record.Key())
+		if err != nil {
+			return usage, err
+		}
+		count(versions)
+	}
+	return usage, nil
+}
+
+// ReencryptRecords encrypts the values of the stored records and their versions which aren't encrypted with the
+// current key, such as those written before a key was rotated or encryption was enabled, returning the number of
+// values re-encrypted. Each record is rewritten unless a newer one was written meanwhile, which is encrypted with the
+// current key already, so it is safe to run while records are written. Versions are only re-encrypted by storage
+// which is a VersionWriter.
+func ReencryptRecords(ctx context.Context, e *Encrypted) (int, error) {
+	records, err := e.Storage.ListRecords(ctx)
+	if err != nil {
+		return 0, err
+	}
+	versionWriter, rewritesVersions := e.Storage.(VersionWriter)
+	var reencrypted int
+	for _, record := range records {
+		if err = ctx.Err(); err != nil {
+			return reencrypted, err
+		}
+		if e.isStale(record.V) {
+			if err = e.reencryptRecord(&record); err != nil {
+				return reencrypted, err
+			}
+			if _, written, err := WriteRecordIfNewer(ctx, e.Storage, record); err != nil {
+				return reencrypted, fmt.Errorf("failed to write re-encrypted record[%s]: %w", record.Key(), err)
+			} else if written {
+				reencrypted++
+			}
+		}
+		if !rewritesVersions {
+			continue
+		}
+		versions, err := e.Storage.ListRecordVersions(ctx, record.Key())
+		if err != nil {
+			return reencrypted, err
+		}
+		for _, version := range versions {
+			if !e.isStale(version.V) {
+				continue
+			}
+			if err = e.reencryptRecord(&version); err != nil {
+				return reencrypted, err
+			}
+			if err = versionWriter.ReplaceRecordVersion(ctx, version); err != nil {
+				return reencrypted, fmt.Errorf("failed to write re-encrypted version %d of record[%s]: %w",
+					version.Seq, version.Key(), err)
+			}
+			reencrypted++
+		}
+	}
+	return reencrypted, nil
+}
+
+// isStale returns whether the stored value isn't encrypted with the current key
+func (e *Encrypted) isStale(v string) bool {
+	keyID, ok := encryptionKeyOf(v)
+	return !ok || keyID != e.keyID
+}
+
+// reencryptRecord replaces the stored value of the record with its value encrypted with the current key
+func (e *Encrypted) reencryptRecord(record *pkarr.Record) error {
+	if err := e.decryptRecord(record); err != nil {
+		return err
+	}
+	v, err := e.encrypt(record.V, record.Key())
+	if err != nil {
+		return err
+	}
+	record.V = v
+	return nil
+}
+
+// encryptionKeyOf returns the ID of the key the stored value is encrypted with, or false if it isn't encrypted
+func encryptionKeyOf(v string) (string, bool) {
+	if !strings.HasPrefix(v, encryptedPrefix) {
+		return "", false
+	}
+	keyID, _, ok := strings.Cut(strings.TrimPrefix(v, encryptedPrefix), ":")
+	return keyID, ok
+}
+
+// As returns the storage as T, looking through wrappers such as Encrypted, so optional capabilities of the underlying
+// storage like DocumentIndex remain available
+func As[T any](db Storage) (T, bool) {
+	for db != nil {
+		if t, ok := db.(T); ok {
+			return t, true
+		}
+		wrapper, ok := db.(interface{ Unwrap() Storage })
+		if !ok {
+			break
+		}
+		db = wrapper.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,178 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func TestEncryptedStorage(t *testing.T) {
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "encrypted.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	encrypted, err := storage.NewEncrypted(db, [][]byte{oldKey})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	encoding := base64.RawURLEncoding
+	plain := pkarr.Record{V: encoding.EncodeToString([]byte("plain")), K: encoding.EncodeToString(bytes.Repeat([]byte{3}, 32)), Sig: "sig", Seq: 1}
+	secret := pkarr.Record{V: encoding.EncodeToString([]byte("secret")), K: encoding.EncodeToString(bytes.Repeat([]byte{4}, 32)), Sig: "sig", Seq: 1}
+
+	// records written before encryption was enabled remain readable
+	require.NoError(t, db.WriteRecord(ctx, plain))
+	require.NoError(t, encrypted.WriteRecord(ctx, secret))
+
+	stored, err := db.ReadRecord(ctx, secret.K)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.V, "enc:"))
+	assert.NotContains(t, stored.V, secret.V)
+
+	got, err := encrypted.ReadRecord(ctx, secret.K)
+	require.NoError(t, err)
+	assert.Equal(t, secret, *got)
+	got, err = encrypted.ReadRecord(ctx, plain.K)
+	require.NoError(t, err)
+	assert.Equal(t, plain, *got)
+
+	t.Run("rotation", func(t *testing.T) {
+		rotated, err := storage.NewEncrypted(db, [][]byte{newKey, oldKey})
+		require.NoError(t, err)
+
+		records, err := rotated.ListRecords(ctx)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []pkarr.Record{plain, secret}, records)
+
+		versions, err := rotated.ListRecordVersions(ctx, secret.K)
+		require.NoError(t, err)
+		assert.Equal(t, []pkarr.Record{secret}, versions)
+
+		// the old key can't decrypt records written with the new one
+		updated := secret
+		updated.Seq = 2
+		require.NoError(t, rotated.WriteRecord(ctx, updated))
+		_, err = encrypted.ReadRecord(ctx, secret.K)
+		assert.Error(t, err)
+		got, err := rotated.ReadRecordVersion(ctx, secret.K, 1)
+		require.NoError(t, err)
+		assert.Equal(t, secret, *got)
+	})
+
+	t.Run("swapped values don't decrypt", func(t *testing.T) {
+		swapped := plain
+		swapped.V = stored.V
+		require.NoError(t, db.WriteRecord(ctx, swapped))
+		_, err = encrypted.ReadRecord(ctx, plain.K)
+		assert.Error(t, err)
+	})
+
+	t.Run("conditional writes are encrypted", func(t *testing.T) {
+		newer := pkarr.Record{V: encoding.EncodeToString([]byte("newer")), K: encoding.EncodeToString(bytes.Repeat([]byte{5}, 32)), Sig: "sig", Seq: 2}
+		current, written, err := storage.WriteRecordIfNewer(ctx, encrypted, newer)
+		require.NoError(t, err)
+		assert.True(t, written)
+		assert.Nil(t, current)
+		stored, err := db.ReadRecord(ctx, newer.K)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(stored.V, "enc:"))
+
+		older := newer
+		older.Seq = 1
+		current, written, err = storage.WriteRecordIfNewer(ctx, encrypted, older)
+		require.NoError(t, err)
+		assert.False(t, written)
+		assert.Equal(t, newer, *current)
+	})
+
+	t.Run("capabilities of the underlying storage are kept", func(t *testing.T) {
+		_, ok := storage.As[storage.DocumentIndex](encrypted)
+		assert.True(t, ok)
+	})
+
+	t.Run("config", func(t *testing.T) {
+		unencrypted, err := storage.WithEncryption(db, config.EncryptionConfig{})
+		require.NoError(t, err)
+		assert.Equal(t, db, unencrypted)
+
+		_, err = storage.WithEncryption(db, config.EncryptionConfig{Keys: []string{encoding.EncodeToString([]byte("short"))}})
+		assert.Error(t, err)
+
+		fromConfig, err := storage.WithEncryption(db, config.EncryptionConfig{Keys: []string{encoding.EncodeToString(newKey)}})
+		require.NoError(t, err)
+		got, err := fromConfig.ReadRecord(ctx, secret.K)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), got.Seq)
+	})
+}
+
+func TestReencryptRecords(t *testing.T) {
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "reencrypt.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	encrypted, err := storage.NewEncrypted(db, [][]byte{oldKey})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	encoding := base64.RawURLEncoding
+	plain := pkarr.Record{V: encoding.EncodeToString([]byte("plain")), K: encoding.EncodeToString(bytes.Repeat([]byte{3}, 32)), Sig: "sig", Seq: 1}
+	require.NoError(t, db.WriteRecord(ctx, plain))
+	secret := pkarr.Record{V: encoding.EncodeToString([]byte("secret")), K: encoding.EncodeToString(bytes.Repeat([]byte{4}, 32)), Sig: "sig", Seq: 1}
+	require.NoError(t, encrypted.WriteRecord(ctx, secret))
+	updated := secret
+	updated.V = encoding.EncodeToString([]byte("updated"))
+	updated.Seq = 2
+	require.NoError(t, encrypted.WriteRecord(ctx, updated))
+
+	rotated, err := storage.NewEncrypted(db, [][]byte{newKey, oldKey})
+	require.NoError(t, err)
+	before, err := rotated.KeyUsage(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, before.Keys[before.CurrentKey])
+	assert.NotZero(t, before.Plaintext)
+
+	n, err := storage.ReencryptRecords(ctx, rotated)
+	require.NoError(t, err)
+	assert.NotZero(t, n)
+
+	after, err := rotated.KeyUsage(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, after.Plaintext)
+	total := before.Plaintext
+	for _, count := range before.Keys {
+		total += count
+	}
+	assert.Equal(t, map[string]int64{after.CurrentKey: total}, after.Keys)
+
+	// the old key is no longer needed to read the records or their versions
+	newOnly, err := storage.NewEncrypted(db, [][]byte{newKey})
+	require.NoError(t, err)
+	got, err := newOnly.ReadRecord(ctx, plain.K)
+	require.NoError(t, err)
+	assert.Equal(t, plain, *got)
+	got, err = newOnly.ReadRecord(ctx, secret.K)
+	require.NoError(t, err)
+	assert.Equal(t, updated, *got)
+	got, err = newOnly.ReadRecordVersion(ctx, secret.K, 1)
+	require.NoError(t, err)
+	assert.Equal(t, secret, *got)
+
+	// re-encrypting again has nothing left to do
+	n, err = storage.ReencryptRecords(ctx, rotated)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// mirrorQueueSize is the number of writes waiting to be mirrored to the secondary storage, beyond which writes are
+// dropped from mirroring rather than slowing down writes to the primary
+const mirrorQueueSize = 10_000
+
+// Failover is a Storage which writes records to a primary storage and mirrors them asynchronously to a secondary
+// storage, reading from the secondary when the primary fails, so records stay resolvable during an incident of the
+// primary's database. Writes fail with the primary, and writes mirrored while the secondary is unavailable are lost
+// to it, so the secondary may lag behind. The other capabilities of the storage, such as the document index and
+// retention quota, are those of the primary.
+type Failover struct {
+	Storage
+	secondary Storage
+
+	mirror chan pkarr.Record
+	done   sync.WaitGroup
+	close  sync.Once
+}
+
+// WithFailover wraps the storage to mirror records to the storage at the given URI, and read from it when the storage
+// fails, or returns the storage unchanged if the URI is empty
+func WithFailover(db Storage, secondaryURI string) (Storage, error) {
+	if secondaryURI == "" {
+		return db, nil
+	}
+	secondary, err := NewStorage(secondaryURI)
+	if err != nil {
+		return nil, err
+	}
+	return NewFailover(db, secondary), nil
+}
+
+// NewFailover wraps the primary storage to mirror records to the secondary storage, and read from it when the primary
+// fails. Closing it closes both.
+func NewFailover(primary, secondary Storage) *Failover {
+	f := Failover{Storage: primary, secondary: secondary, mirror: make(chan pkarr.Record, mirrorQueueSize)}
+	f.done.Add(1)
+	go f.mirrorRecords()
+	return &f
+}
+
+// Unwrap returns the primary storage
+func (f *Failover) Unwrap() Storage {
+	return f.Storage
+}
+
+// Secondary returns the secondary storage
+func (f *Failover) Secondary() Storage {
+	return f.secondary
+}
+
+func (f *Failover) WriteRecord(ctx context.Context, record pkarr.Record) error {
+	if err := f.Storage.WriteRecord(ctx, record); err != nil {
+		return err
+	}
+	f.enqueue(record)
+	return nil
+}
+
+func (f *Failover) WriteRecordIfNewer(ctx context.Context, record pkarr.Record) (*pkarr.Record, bool, error) {
+	current, written, err := WriteRecordIfNewer(ctx, f.Storage, record)
+	if err == nil && written {
+		f.enqueue(record)
+	}
+	return current, written, err
+}
+
+// ReplaceRecordVersion replaces the stored version of the record with its seq in the primary storage, and in the
+// secondary if it can, so both keep the same values
+func (f *Failover) ReplaceRecordVersion(ctx context.Context, record pkarr.Record) error {
+	writer, ok := f.Storage.(VersionWriter)
+	if !ok {
+		return errors.New("primary storage does not support replacing record versions")
+	}
+	if err := writer.ReplaceRecordVersion(ctx, record); err != nil {
+		return err
+	}
+	if writer, ok = f.secondary.(VersionWriter); ok {
+		if err := writer.ReplaceRecordVersion(ctx, record); err != nil {
+			logrus.WithError(err).Warnf("failed to replace version of record[%s] in secondary storage", record.Key())
+		}
+	}
+	return nil
+}
+
+func (f *Failover) ReadRecord(ctx context.Context, id string) (*pkarr.Record, error) {
+	record, err := f.Storage.ReadRecord(ctx, id)
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to read record[%s] from primary storage, reading from secondary", id)
+		return f.secondary.ReadRecord(ctx, id)
+	}
+	return record, nil
+}
+
+func (f *Failover) ListRecords(ctx context.Context) ([]pkarr.Record, error) {
+	records, err := f.Storage.ListRecords(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to list records from primary storage, listing from secondary")
+		return f.secondary.ListRecords(ctx)
+	}
+	return records, nil
+}
+
+func (f *Failover) ReadRecordVersion(ctx context.Context, id string, seq int64) (*pkarr.Record, error) {
+	record, err := f.Storage.ReadRecordVersion(ctx, id, seq)
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to read record[%s] version[%d] from primary storage, reading from secondary", id, seq)
+		return f.secondary.ReadRecordVersion(ctx, id, seq)
+	}
+	return record, nil
+}
+
+func (f *Failover) ListRecordVersions(ctx context.Context, id string) ([]pkarr.Record, error) {
+	versions, err := f.Storage.ListRecordVersions(ctx, id)
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to list record[%s] versions from primary storage, listing from secondary", id)
+		return f.secondary.ListRecordVersions(ctx, id)
+	}
+	return versions, nil
+}
+
+// Close stops mirroring once the writes waiting to be mirrored are, and closes both storages
+func (f *Failover) Close() error {
+	f.close.Do(func() {
+		close(f.mirror)
+	})
+	f.done.Wait()
+	return errors.Join(f.Storage.Close(), f.secondary.Close())
+}
+
+// enqueue queues the record to be mirrored, dropping it if the queue is full
+func (f *Failover) enqueue(record pkarr.Record) {
+	select {
+	case f.mirror <- record:
+	default:
+		logrus.Warnf("mirror queue is full, not mirroring record[%s] to secondary storage", record.Key())
+	}
+}
+
+// mirrorRecords writes the queued records to the secondary storage, never replacing a record with an older one, as
+// records may be queued out of order by concurrent writes
+func (f *Failover) mirrorRecords() {
+	defer f.done.Done()
+	for record := range f.mirror {
+		if _, _, err := WriteRecordIfNewer(context.Background(), f.secondary, record); err != nil {
+			logrus.WithError(err).Warnf("failed to mirror record[%s] to secondary storage", record.Key())
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// unavailableStorage fails reads while down, like a database during an incident
+type unavailableStorage struct {
+	storage.Storage
+	down bool
+}
+
+func (u *unavailableStorage) ReadRecord(ctx context.Context, id string) (*pkarr.Record, error) {
+	if u.down {
+		return nil, errors.New("storage is down")
+	}
+	return u.Storage.ReadRecord(ctx, id)
+}
+
+func (u *unavailableStorage) ListRecords(ctx context.Context) ([]pkarr.Record, error) {
+	if u.down {
+		return nil, errors.New("storage is down")
+	}
+	return u.Storage.ListRecords(ctx)
+}
+
+func TestFailoverStorage(t *testing.T) {
+	dir := t.TempDir()
+	primaryDB, err := storage.NewStorage("bolt://" + filepath.Join(dir, "primary.db"))
+	require.NoError(t, err)
+	secondaryURI := "bolt://" + filepath.Join(dir, "secondary.db")
+	secondary, err := storage.NewStorage(secondaryURI)
+	require.NoError(t, err)
+	primary := &unavailableStorage{Storage: primaryDB}
+	failover := storage.NewFailover(primary, secondary)
+
+	ctx := context.Background()
+	encoding := base64.RawURLEncoding
+	key := encoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	older := pkarr.Record{V: encoding.EncodeToString([]byte("older")), K: key, Sig: "sig", Seq: 1}
+	newer := pkarr.Record{V: encoding.EncodeToString([]byte("newer")), K: key, Sig: "sig", Seq: 2}
+
+	_, written, err := failover.WriteRecordIfNewer(ctx, newer)
+	require.NoError(t, err)
+	assert.True(t, written)
+	_, written, err = failover.WriteRecordIfNewer(ctx, older)
+	require.NoError(t, err)
+	assert.False(t, written)
+
+	wrapped, ok := storage.As[*storage.Failover](failover)
+	require.True(t, ok)
+	assert.Equal(t, storage.Storage(primary), wrapped.Unwrap())
+
+	t.Run("reads from the primary", func(t *testing.T) {
+		got, err := failover.ReadRecord(ctx, key)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, newer, *got)
+	})
+
+	// closing waits for the queued records to be mirrored
+	require.NoError(t, failover.Close())
+
+	t.Run("falls back to the secondary", func(t *testing.T) {
+		primaryDB, err := storage.NewStorage("bolt://" + filepath.Join(dir, "primary.db"))
+		require.NoError(t, err)
+		secondary, err := storage.NewStorage(secondaryURI)
+		require.NoError(t, err)
+		primary := &unavailableStorage{Storage: primaryDB, down: true}
+		failover := storage.NewFailover(primary, secondary)
+		defer failover.Close()
+
+		got, err := failover.ReadRecord(ctx, key)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, newer, *got)
+
+		records, err := failover.ListRecords(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []pkarr.Record{newer}, records)
+	})
+}
+
+func TestReconcileFailoverStorage(t *testing.T) {
+	primary, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "primary.db"))
+	require.NoError(t, err)
+	secondary, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "secondary.db"))
+	require.NoError(t, err)
+	failover := storage.NewFailover(primary, secondary)
+	defer failover.Close()
+
+	ctx := context.Background()
+	encoding := base64.RawURLEncoding
+	record := func(b byte, seq int64) pkarr.Record {
+		return pkarr.Record{V: encoding.EncodeToString([]byte{b}), K: encoding.EncodeToString(bytes.Repeat([]byte{b}, 32)), Sig: "sig", Seq: seq}
+	}
+	inSync := record(1, 1)
+	onlyInPrimary := record(2, 1)
+	behindInSecondary := record(3, 2)
+	behindInPrimary := record(4, 2)
+	onlyInSecondary := record(5, 1)
+
+	// write around the failover, as if mirroring had failed
+	for _, r := range []pkarr.Record{inSync, onlyInPrimary, behindInSecondary, record(4, 1)} {
+		require.NoError(t, primary.WriteRecord(ctx, r))
+	}
+	for _, r := range []pkarr.Record{inSync, record(3, 1), behindInPrimary, onlyInSecondary} {
+		require.NoError(t, secondary.WriteRecord(ctx, r))
+	}
+
+	reconciliation, err := failover.Reconcile(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, reconciliation.PrimaryRecords)
+	assert.Equal(t, 4, reconciliation.SecondaryRecords)
+	assert.Equal(t, 4, reconciliation.Drift)
+	assert.Equal(t, 1, reconciliation.MissingFromSecondary)
+	assert.Equal(t, 1, reconciliation.BehindInSecondary)
+	assert.Equal(t, 1, reconciliation.BehindInPrimary)
+	assert.Equal(t, 1, reconciliation.OnlyInSecondary)
+	assert.Equal(t, 4, reconciliation.Repaired)
+	assert.Zero(t, reconciliation.Unrepaired)
+
+	want := []pkarr.Record{inSync, onlyInPrimary, behindInSecondary, behindInPrimary}
+	records, err := primary.ListRecords(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, want, records)
+	records, err = secondary.ListRecords(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, want, records)
+
+	reconciliation, err = failover.Reconcile(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, reconciliation.Drift)
+}
@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+const (
+	// hashedPrefix starts the values of records stored under a hashed key, followed by the key itself and a ':'.
+	// Neither character is in the base64URL alphabet, so records stored under their key are told apart.
+	hashedPrefix = "key:"
+	// minKeyHashSecretLength is the minimum length of the secret keys are hashed with
+	minKeyHashSecretLength = 32
+)
+
+var errQuotaUnsupported = errors.New("storage does not support quotas")
+
+// HashedKeys is a Storage which stores records under an HMAC of the key they would be stored under, keeping the key in
+// the value, which the underlying storage must encrypt. Records still stored under their key, such as those written
+// before hashing was enabled, are read until migrated with MigrateKeys.
+type HashedKeys struct {
+	Storage
+	secret []byte
+}
+
+// WithKeyHashing wraps the storage to hash the keys records are stored under if the config enables it, or returns it
+// unchanged otherwise. The storage must be Encrypted, as keys are kept in record values.
+func WithKeyHashing(db Storage, cfg config.EncryptionConfig) (Storage, error) {
+	if !cfg.HashKeys {
+		return db, nil
+	}
+	if _, ok := As[*Encrypted](db); !ok {
+		return nil, errors.New("hashing keys requires encryption keys, as keys are kept in encrypted record values")
+	}
+	secret, err := KeyHashSecret(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewHashedKeys(db, secret)
+}
+
+// KeyHashSecret decodes the secret of the config keys are hashed with
+func KeyHashSecret(cfg config.EncryptionConfig) ([]byte, error) {
+	if cfg.KeyHashSecret == "" {
+		return nil, errors.New("a key hash secret is required to hash keys")
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(cfg.KeyHashSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key hash secret: %w", err)
+	}
+	return secret, nil
+}
+
+// NewHashedKeys wraps the storage to store records under an HMAC of their key with the given secret
+func NewHashedKeys(db Storage, secret []byte) (*HashedKeys, error) {
+	if len(secret) < minKeyHashSecretLength {
+		return nil, fmt.Errorf("key hash secret must be at least %d bytes, got %d", minKeyHashSecretLength, len(secret))
+	}
+	return &HashedKeys{Storage: db, secret: secret}, nil
+}
+
+// Unwrap returns the underlying storage
+func (h *HashedKeys) Unwrap() Storage {
+	return h.Storage
+}
+
+// hash returns the key a record which would be stored under the given key is stored under instead
+func (h *HashedKeys) hash(key string) string {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(key))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// hashRecord returns the record as stored under its hashed key
+func (h *HashedKeys) hashRecord(record pkarr.Record) pkarr.Record {
+	return pkarr.Record{
+		V:   hashedPrefix + record.Key() + ":" + record.V,
+		K:   h.hash(record.Key()),
+		Sig: record.Sig,
+		Seq: record.Seq,
+	}
+}
+
+// unhashRecord returns the record stored under a hashed key as it would be stored under its key, and whether it was
+// stored under a hashed key
+func unhashRecord(record pkarr.Record) (pkarr.Record, bool, error) {
+	if !strings.HasPrefix(record.V, hashedPrefix) {
+		return record, false, nil
+	}
+	key, v, ok := strings.Cut(strings.TrimPrefix(record.V, hashedPrefix), ":")
+	if !ok {
+		return record, false, fmt.Errorf("malformed value of record[%s] stored under a hashed key", record.K)
+	}
+	k, salt, _ := strings.Cut(key, ".")
+	return pkarr.Record{V: v, K: k, Sig: record.Sig, Seq: record.Seq, Salt: salt}, true, nil
+}
+
+func unhashRecords(records []pkarr.Record) ([]pkarr.Record, error) {
+	for i := range records {
+		record, _, err := unhashRecord(records[i])
+		if err != nil {
+			return nil, err
+		}
+		records[i] = record
+	}
+	return records, nil
+}
+
+func (h *HashedKeys) WriteRecord(ctx context.Context, record pkarr.Record) error {
+	return h.Storage.WriteRecord(ctx, h.hashRecord(record))
+}
+
+// WriteRecordIfNewer writes the record under its hashed key unless the record stored under it has a higher seq. The
+// seq of a record still stored under its key, until migrated, is checked too, though not atomically with the write.
+func (h *HashedKeys) WriteRecordIfNewer(ctx context.Context, record pkarr.Record) (*pkarr.Record, bool, error) {
+	current, err := h.Storage.ReadRecord(ctx, record.Key())
+	if err != nil {
+		return nil, false, err
+	}
+	if current != nil && current.Seq > record.Seq {
+		return current, false, nil
+	}
+	hashed, written, err := WriteRecordIfNewer(ctx, h.Storage, h.hashRecord(record))
+	if err != nil {
+		return nil, false, err
+	}
+	if hashed != nil {
+		unhashed, _, err := unhashRecord(*hashed)
+		return &unhashed, written, err
+	}
+	return current, written, nil
+}
+
+func (h *HashedKeys) ReadRecord(ctx context.Context, id string) (*pkarr.Record, error) {
+	record, err := h.Storage.ReadRecord(ctx, h.hash(id))
+	if err == nil && record == nil {
+		record, err = h.Storage.ReadRecord(ctx, id)
+	}
+	if err != nil || record == nil {
+		return record, err
+	}
+	unhashed, _, err := unhashRecord(*record)
+	return &unhashed, err
+}
+
+// ListRecords lists all records, with those stored under both their key and their hashed key, such as while being
+// migrated, listed once at their latest seq
+func (h *HashedKeys) ListRecords(ctx context.Context) ([]pkarr.Record, error) {
+	records, err := h.Storage.ListRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if records, err = unhashRecords(records); err != nil {
+		return nil, err
+	}
+	latest := make(map[string]int, len(records))
+	deduped := records[:0]
+	for _, record := range records {
+		i, ok := latest[record.Key()]
+		if !ok {
+			latest[record.Key()] = len(deduped)
+			deduped = append(deduped, record)
+			continue
+		}
+		if record.Seq > deduped[i].Seq {
+			deduped[i] = record
+		}
+	}
+	return deduped, nil
+}
+
+func (h *HashedKeys) ReadRecordVersion(ctx context.Context, id string, seq int64) (*pkarr.Record, error) {
+	record, err := h.Storage.ReadRecordVersion(ctx, h.hash(id), seq)
+	if err == nil && record == nil {
+		record, err = h.Storage.ReadRecordVersion(ctx, id, seq)
+	}
+	if err != nil || record == nil {
+		return record, err
+	}
+	unhashed, _, err := unhashRecord(*record)
+	return &unhashed, err
+}
+
+func (h *HashedKeys) ListRecordVersions(ctx context.Context, id string) ([]pkarr.Record, error) {
+	records, err := h.Storage.ListRecordVersions(ctx, h.hash(id))
+	if err == nil && len(records) == 0 {
+		records, err = h.Storage.ListRecordVersions(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return unhashRecords(records)
+}
+
+// CountRecords returns the number of stored records, if the underlying storage supports counting them
+func (h *HashedKeys) CountRecords(ctx context.Context) (int64, error) {
+	counter, ok := As[RecordCounter](h.Storage)
+	if !ok {
+		return 0, errors.New("storage does not support counting records")
+	}
+	return counter.CountRecords(ctx)
+}
+
+// Usage returns the usage of the underlying storage
+func (h *HashedKeys) Usage(ctx context.Context) (pkarr.Usage, error) {
+	quota, ok := As[Quota](h.Storage)
+	if !ok {
+		return pkarr.Usage{}, errQuotaUnsupported
+	}
+	return quota.Usage(ctx)
+}
+
+// DeleteRecord deletes the record which would be stored under the given key, whether stored under it or its hash
+func (h *HashedKeys) DeleteRecord(ctx context.Context, key string) error {
+	quota, ok := As[Quota](h.Storage)
+	if !ok {
+		return errQuotaUnsupported
+	}
+	if err := quota.DeleteRecord(ctx, h.hash(key)); err != nil {
+		return err
+	}
+	return quota.DeleteRecord(ctx, key)
+}
+
+// MarkResolved sets the time the record which would be stored under the given key was last resolved. Records not yet
+// migrated to their hashed key are not marked, so are evicted first.
+func (h *HashedKeys) MarkResolved(ctx context.Context, key string, at time.Time) error {
+	quota, ok := As[Quota](h.Storage)
+	if !ok {
+		return errQuotaUnsupported
+	}
+	return quota.MarkResolved(ctx, h.hash(key), at)
+}
+
+// ListResolved returns the unix time each record was last resolved at, by the key it would be stored under. As
+// hashes can't be reversed, the stored records are listed to map hashed keys back to their keys.
+func (h *HashedKeys) ListResolved(ctx context.Context) (map[string]int64, error) {
+	quota, ok := As[Quota](h.Storage)
+	if !ok {
+		return nil, errQuotaUnsupported
+	}
+	resolved, err := quota.ListResolved(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records, err := h.ListRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if at, ok := resolved[h.hash(record.Key())]; ok {
+			delete(resolved, h.hash(record.Key()))
+			resolved[record.Key()] = max(at, resolved[record.Key()])
+		}
+	}
+	return resolved, nil
+}
+
+// MigrateKeys moves the records stored under their key to their hashed key, with all of their versions, or back to
+// their key if unhash is set, returning the number of records moved. Records are written before being deleted from
+// their previous key, so an interrupted migration is resumed by migrating again.
+func MigrateKeys(ctx context.Context, h *HashedKeys, unhash bool) (int, error) {
+	quota, ok := As[Quota](h.Storage)
+	if !ok {
+		return 0, errors.New("storage does not support deleting records to migrate them")
+	}
+	records, err := h.Storage.ListRecords(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var moved int
+	for _, stored := range records {
+		record, hashed, err := unhashRecord(stored)
+		if err != nil {
+			return moved, err
+		}
+		if hashed != unhash {
+			continue
+		}
+		from, to := record.Key(), h.hash(record.Key())
+		write := h.WriteRecord
+		if unhash {
+			from, to = to, from
+			write = h.Storage.WriteRecord
+		}
+		versions, err := h.Storage.ListRecordVersions(ctx, from)
+		if err != nil {
+			return moved, err
+		}
+		if versions, err = unhashRecords(versions); err != nil {
+			return moved, err
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Seq < versions[j].Seq })
+		// the record is written last so it remains the current version
+		for _, version := range append(versions, record) {
+			if err = write(ctx, version); err != nil {
+				return moved, fmt.Errorf("failed to migrate record[%s] to key[%s]: %w", from, to, err)
+			}
+		}
+		if err = quota.DeleteRecord(ctx, from); err != nil {
+			return moved, fmt.Errorf("failed to delete migrated record[%s]: %w", from, err)
+		}
+		moved++
+	}
+	return moved, nil
+}
@@ -0,0 +1,99 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+func TestHashedKeys(t *testing.T) {
+	db, err := storage.NewStorage("bolt://" + filepath.Join(t.TempDir(), "hashed.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	encrypted, err := storage.NewEncrypted(db, [][]byte{bytes.Repeat([]byte{1}, 32)})
+	require.NoError(t, err)
+	hashed, err := storage.NewHashedKeys(encrypted, bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	encoding := base64.RawURLEncoding
+	plain := pkarr.Record{V: encoding.EncodeToString([]byte("plain")), K: encoding.EncodeToString(bytes.Repeat([]byte{3}, 32)), Sig: "sig", Seq: 1}
+	secret := pkarr.Record{V: encoding.EncodeToString([]byte("secret")), K: encoding.EncodeToString(bytes.Repeat([]byte{4}, 32)), Sig: "sig", Seq: 1, Salt: encoding.EncodeToString([]byte("salt"))}
+
+	// records written before hashing was enabled remain readable
+	require.NoError(t, encrypted.WriteRecord(ctx, plain))
+	require.NoError(t, hashed.WriteRecord(ctx, secret))
+
+	stored, err := db.ReadRecord(ctx, secret.Key())
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+	stored, err = db.ReadRecord(ctx, secret.K)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	for _, record := range []pkarr.Record{plain, secret} {
+		got, err := hashed.ReadRecord(ctx, record.Key())
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, record, *got)
+	}
+	records, err := hashed.ListRecords(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []pkarr.Record{plain, secret}, records)
+
+	t.Run("migrate", func(t *testing.T) {
+		updated := secret
+		updated.Seq = 2
+		require.NoError(t, hashed.WriteRecord(ctx, updated))
+
+		moved, err := storage.MigrateKeys(ctx, hashed, false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, moved)
+		got, err := encrypted.ReadRecord(ctx, plain.Key())
+		require.NoError(t, err)
+		assert.Nil(t, got)
+		got, err = hashed.ReadRecord(ctx, plain.Key())
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, plain, *got)
+
+		// migrating back restores every version under its key
+		moved, err = storage.MigrateKeys(ctx, hashed, true)
+		require.NoError(t, err)
+		assert.Equal(t, 2, moved)
+		versions, err := encrypted.ListRecordVersions(ctx, secret.Key())
+		require.NoError(t, err)
+		assert.Equal(t, []pkarr.Record{secret, updated}, versions)
+		got, err = encrypted.ReadRecord(ctx, secret.Key())
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, updated, *got)
+		records, err := encrypted.ListRecords(ctx)
+		require.NoError(t, err)
+		assert.Len(t, records, 2)
+	})
+
+	t.Run("config", func(t *testing.T) {
+		cfg := config.EncryptionConfig{HashKeys: true, KeyHashSecret: encoding.EncodeToString(bytes.Repeat([]byte{2}, 32))}
+		_, err := storage.WithKeyHashing(db, cfg)
+		assert.Error(t, err, "hashing keys requires encryption")
+		wrapped, err := storage.WithKeyHashing(encrypted, cfg)
+		require.NoError(t, err)
+		_, ok := storage.As[*storage.HashedKeys](wrapped)
+		assert.True(t, ok)
+
+		cfg.KeyHashSecret = encoding.EncodeToString([]byte("short"))
+		_, err = storage.WithKeyHashing(encrypted, cfg)
+		assert.Error(t, err)
+	})
+}
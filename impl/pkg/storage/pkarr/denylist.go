@@ -0,0 +1,9 @@
+package pkarr
+
+// DenylistEntry is a key the gateway refuses to store, serve, or republish records for
+type DenylistEntry struct {
+	// ID is the z-base-32 encoded ID of the denied key
+	ID        string `json:"id"`
+	Reason    string `json:"reason"`
+	Timestamp int64  `json:"timestamp"`
+}
@@ -0,0 +1,57 @@
+package pkarr
+
+type IndexField string
+
+const (
+	ServiceTypeField            IndexField = "serviceTypes"
+	ServiceEndpointField        IndexField = "serviceEndpoints"
+	ServiceDomainField          IndexField = "serviceDomains"
+	VerificationMethodTypeField IndexField = "verificationMethodTypes"
+	TypeField                   IndexField = "types"
+)
+
+// IndexFields are all fields of a Document which can be queried
+var IndexFields = []IndexField{ServiceTypeField, ServiceEndpointField, ServiceDomainField, VerificationMethodTypeField, TypeField}
+
+// Document is the searchable summary of the DID Document represented by a record
+type Document struct {
+	// z-base-32 encoded ID of the record
+	ID               string   `json:"id" validate:"required"`
+	ServiceTypes     []string `json:"serviceTypes,omitempty"`
+	ServiceEndpoints []string `json:"serviceEndpoints,omitempty"`
+	// ServiceDomains are the lowercase hosts of all service endpoints which are URLs
+	ServiceDomains          []string `json:"serviceDomains,omitempty"`
+	VerificationMethodTypes []string `json:"verificationMethodTypes,omitempty"`
+	// Types are the type indexes of the DID, as decimal strings
+	Types []string `json:"types,omitempty"`
+}
+
+// Values returns the indexed values of the document for the given field
+func (d Document) Values(field IndexField) []string {
+	switch field {
+	case ServiceTypeField:
+		return d.ServiceTypes
+	case ServiceEndpointField:
+		return d.ServiceEndpoints
+	case ServiceDomainField:
+		return d.ServiceDomains
+	case VerificationMethodTypeField:
+		return d.VerificationMethodTypes
+	case TypeField:
+		return d.Types
+	default:
+		return nil
+	}
+}
+
+// DocumentQuery selects indexed documents by the value of one of their fields
+type DocumentQuery struct {
+	Field IndexField
+	Value string
+	// Prefix matches all values starting with Value rather than only Value itself
+	Prefix bool
+	// After is the ID of the last document of the previous page of results, if any
+	After string
+	// Limit is the maximum number of IDs to return, zero for no limit
+	Limit int
+}
@@ -0,0 +1,38 @@
+package pkarr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+const (
+	// EquivocationSourcePublish is the source of an equivocation observed as a record published to the gateway
+	EquivocationSourcePublish = "publish"
+	// EquivocationSourceDHT is the source of an equivocation observed as a record resolved from the DHT
+	EquivocationSourceDHT = "dht"
+)
+
+// Equivocation is evidence of a key signing two different records with the same seq, such as by a client bug or an
+// attempt to fork the key's DID Document. Both records are kept so anyone can verify their signatures.
+type Equivocation struct {
+	// ID is the z-base-32 encoded ID of the key which signed both records
+	ID  string `json:"id"`
+	Seq int64  `json:"seq"`
+	// Stored is the record the gateway held, and Observed the conflicting record it then came across
+	Stored   Record `json:"stored"`
+	Observed Record `json:"observed"`
+	// Source is where the observed record came from, one of EquivocationSourcePublish and EquivocationSourceDHT
+	Source    string `json:"source"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Fingerprint identifies the pair of conflicting records, regardless of which of them was observed first
+func (e Equivocation) Fingerprint() string {
+	first, second := e.Stored.Sig, e.Observed.Sig
+	if second < first {
+		first, second = second, first
+	}
+	hash := sha256.Sum256([]byte(e.Stored.Key() + ":" + strconv.FormatInt(e.Seq, 10) + ":" + first + ":" + second))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,15 @@
+package pkarr
+
+// FeedEntry is an entry in the append-only feed of records first seen or updated by the gateway, which indexers tail
+// by cursor
+type FeedEntry struct {
+	// Cursor is the position of the entry in the feed, greater than the cursors of all earlier entries
+	Cursor int64 `json:"cursor"`
+	// ID is the z-base-32 encoded ID of the record
+	ID string `json:"id"`
+	// Salt is the base64url encoded salt of the record, if any
+	Salt string `json:"salt,omitempty"`
+	Seq  int64  `json:"seq"`
+	// Timestamp is the unix timestamp in seconds the gateway saw the record at
+	Timestamp int64 `json:"timestamp"`
+}
@@ -0,0 +1,23 @@
+package pkarr
+
+// HistoryEntry is an entry in the append-only log of record updates witnessed by the gateway. Each entry is
+// chained to the previous one by including its hash, so history cannot be rewritten without changing every later hash.
+type HistoryEntry struct {
+	// Index is the position of the entry in the log, starting at 0
+	Index int64 `json:"index"`
+	// ID is the z-base-32 encoded ID of the updated record
+	ID string `json:"id"`
+	// Salt is the base64url encoded salt of the updated record, if any
+	Salt string `json:"salt,omitempty"`
+	Seq  int64  `json:"seq"`
+	// RecordHash is the hex encoded SHA-256 hash of the record's signature, seq, and value
+	RecordHash string `json:"recordHash"`
+	Timestamp  int64  `json:"timestamp"`
+	// PrevHash is the hash of the previous entry, empty for the first entry
+	PrevHash string `json:"prevHash"`
+	// Hash is the hex encoded SHA-256 hash of all other fields of the entry
+	Hash string `json:"hash"`
+	// Redacted is set on entries listed without their record, whose key is on the denylist. Their hash can't be
+	// recomputed, but still chains them to the other entries. It isn't stored or hashed.
+	Redacted bool `json:"redacted,omitempty"`
+}
@@ -0,0 +1,11 @@
+package pkarr
+
+// JournalEntry is the intent to put the record stored under Key, published with Seq, to the DHT. It is written before
+// the publish is acknowledged and deleted once the put completes, so puts interrupted by a crash are replayed.
+type JournalEntry struct {
+	// Key is the key the published record is stored under, see RecordKey
+	Key string `json:"key"`
+	Seq int64  `json:"seq"`
+	// Timestamp is the unix time in seconds the record was published at
+	Timestamp int64 `json:"timestamp"`
+}
@@ -0,0 +1,10 @@
+package pkarr
+
+// RecordLabels are the private labels an operator attached to the record stored under Key, such as "customer:acme" or
+// "pinned". They are kept apart from the record, and never published or served with it.
+type RecordLabels struct {
+	// Key is the key the labeled record is stored under, see RecordKey
+	Key       string   `json:"key"`
+	Labels    []string `json:"labels"`
+	Timestamp int64    `json:"timestamp"`
+}
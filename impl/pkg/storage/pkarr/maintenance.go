@@ -0,0 +1,14 @@
+package pkarr
+
+// Maintenance is the outcome of a run of storage maintenance, which reclaims the space left behind by deleted records
+type Maintenance struct {
+	// Started and Finished are the unix timestamps in seconds the maintenance started and finished at
+	Started  int64 `json:"started"`
+	Finished int64 `json:"finished"`
+	// BytesBefore and BytesAfter are the size of the database file before and after compacting it, for bolt
+	BytesBefore int64 `json:"bytesBefore,omitempty"`
+	BytesAfter  int64 `json:"bytesAfter,omitempty"`
+	// Deleted is the number of rows left behind by deleted records which were deleted, in Batches, for postgres
+	Deleted int64 `json:"deleted,omitempty"`
+	Batches int   `json:"batches,omitempty"`
+}
@@ -8,4 +8,20 @@ type Record struct {
 	// 64 byte base64URL encoded string
 	Sig string `json:"sig" validate:"required"`
 	Seq int64  `json:"seq" validate:"required"`
+	// Up to a 64 byte base64URL encoded string, distinguishing one of multiple mutable records of the same key
+	Salt string `json:"salt,omitempty"`
+}
+
+// Key returns the key the record is stored under, see RecordKey
+func (r Record) Key() string {
+	return RecordKey(r.K, r.Salt)
+}
+
+// RecordKey returns the key a record with the given base64URL encoded key and salt is stored under: the key itself
+// for unsalted records, and the key and salt joined by a '.', which isn't in the base64URL alphabet, otherwise
+func RecordKey(k, salt string) string {
+	if salt == "" {
+		return k
+	}
+	return k + "." + salt
 }
@@ -0,0 +1,13 @@
+package pkarr
+
+// TenantRecord attributes the record stored under Key to the tenant which last published it with its API key, for
+// accounting the storage each tenant uses. It is kept apart from the record.
+type TenantRecord struct {
+	// Key is the key the attributed record is stored under, see RecordKey
+	Key    string `json:"key"`
+	Tenant string `json:"tenant"`
+	// Bytes is the size of the record's DNS packet
+	Bytes int64 `json:"bytes"`
+	// Timestamp is the unix time in seconds the tenant published the record at
+	Timestamp int64 `json:"timestamp"`
+}
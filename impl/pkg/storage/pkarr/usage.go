@@ -0,0 +1,19 @@
+package pkarr
+
+// Usage is the storage used by records
+type Usage struct {
+	// Records is the number of stored records, not counting their versions
+	Records int64 `json:"records"`
+	// Bytes is the approximate size of the stored records and their versions
+	Bytes int64 `json:"bytes"`
+}
+
+// EncryptionKeyUsage counts the stored values of records and their versions by the key they are encrypted with
+type EncryptionKeyUsage struct {
+	// CurrentKey is the ID of the key values are encrypted with
+	CurrentKey string `json:"currentKey"`
+	// Keys are the numbers of values encrypted with each key, by its ID, including keys no longer configured
+	Keys map[string]int64 `json:"keys"`
+	// Plaintext is the number of values stored before encryption was enabled
+	Plaintext int64 `json:"plaintext"`
+}
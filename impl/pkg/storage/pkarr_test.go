@@ -1,18 +1,21 @@
 package storage_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/TBD54566975/did-dht-method/internal/did"
-	"github.com/TBD54566975/did-dht-method/pkg/dht"
-	"github.com/TBD54566975/did-dht-method/pkg/storage"
-	"github.com/TBD54566975/did-dht-method/pkg/storage/pkarr"
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
 )
 
 func TestPKARRStorage(t *testing.T) {
@@ -66,3 +69,40 @@ func TestPKARRStorage(t *testing.T) {
 	assert.NotEmpty(t, records)
 	assert.Equal(t, record, records[0])
 }
+
+func TestNewFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	encoding := base64.RawURLEncoding
+	cfg := config.GetDefaultConfig()
+	cfg.ServerConfig.StorageURI = "bolt://" + filepath.Join(dir, "primary.db")
+	cfg.ServerConfig.SecondaryStorageURI = "bolt://" + filepath.Join(dir, "secondary.db")
+	cfg.ServerConfig.CompressRecords = true
+	cfg.EncryptionConfig = config.EncryptionConfig{
+		Keys:          []string{encoding.EncodeToString(bytes.Repeat([]byte{1}, 32))},
+		HashKeys:      true,
+		KeyHashSecret: encoding.EncodeToString(bytes.Repeat([]byte{2}, 32)),
+	}
+
+	db, err := storage.NewFromConfig(&cfg)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// the wrappers are in the order they must be, outermost first
+	hashed, ok := db.(*storage.HashedKeys)
+	require.True(t, ok)
+	compressed, ok := hashed.Unwrap().(*storage.Compressed)
+	require.True(t, ok)
+	encrypted, ok := compressed.Unwrap().(*storage.Encrypted)
+	require.True(t, ok)
+	_, ok = encrypted.Unwrap().(*storage.Failover)
+	assert.True(t, ok)
+
+	t.Run("hashing keys requires encryption", func(t *testing.T) {
+		cfg := cfg
+		cfg.ServerConfig.StorageURI = "bolt://" + filepath.Join(dir, "unencrypted.db")
+		cfg.ServerConfig.SecondaryStorageURI = ""
+		cfg.EncryptionConfig.Keys = nil
+		_, err := storage.NewFromConfig(&cfg)
+		assert.Error(t, err)
+	})
+}
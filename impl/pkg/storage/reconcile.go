@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
+)
+
+// Reconciliation is the drift found between the primary and secondary storage of a Failover, comparing the seq of the
+// latest record stored under each key, and its repair. Versions of records aren't compared.
+type Reconciliation struct {
+	// Started and Finished are the unix timestamps in seconds the reconciliation started and finished at
+	Started  int64 `json:"started"`
+	Finished int64 `json:"finished"`
+	// PrimaryRecords and SecondaryRecords are the number of records stored by each
+	PrimaryRecords   int `json:"primaryRecords"`
+	SecondaryRecords int `json:"secondaryRecords"`
+	// Drift is the number of keys whose records differ between the storages, the sum of the counts below
+	Drift int `json:"drift"`
+	// MissingFromSecondary and BehindInSecondary count the records the secondary doesn't have, or has at a lower seq
+	// than the primary, such as those written while it was unavailable
+	MissingFromSecondary int `json:"missingFromSecondary"`
+	BehindInSecondary    int `json:"behindInSecondary"`
+	// BehindInPrimary counts the records the primary has at a lower seq than the secondary, such as after restoring it
+	// from a backup
+	BehindInPrimary int `json:"behindInPrimary"`
+	// OnlyInSecondary counts the records the primary doesn't have, such as those deleted from it
+	OnlyInSecondary int `json:"onlyInSecondary"`
+	// Repaired and Unrepaired count the drifted records which were, and weren't, repaired
+	Repaired   int `json:"repaired"`
+	Unrepaired int `json:"unrepaired"`
+}
+
+// Reconcile compares the records of the primary and secondary storage, and repairs their drift: records missing from
+// or behind in either are copied from the other, never replacing a record with an older one, and records the primary
+// doesn't have are deleted from the secondary if it supports deleting records, as the primary is authoritative for
+// deletions. Records written while reconciling may be counted as drifted, but aren't lost.
+func (f *Failover) Reconcile(ctx context.Context) (*Reconciliation, error) {
+	r := Reconciliation{Started: time.Now().Unix()}
+	primaryRecords, err := f.Storage.ListRecords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing records of primary storage: %w", err)
+	}
+	secondaryRecords, err := f.secondary.ListRecords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing records of secondary storage: %w", err)
+	}
+	r.PrimaryRecords, r.SecondaryRecords = len(primaryRecords), len(secondaryRecords)
+
+	primarySeqs := seqsByKey(primaryRecords)
+	secondarySeqs := seqsByKey(secondaryRecords)
+	for _, record := range primaryRecords {
+		seq, ok := secondarySeqs[record.Key()]
+		switch {
+		case !ok:
+			r.MissingFromSecondary++
+		case seq < record.Seq:
+			r.BehindInSecondary++
+		default:
+			continue
+		}
+		_, _, err = WriteRecordIfNewer(ctx, f.secondary, record)
+		r.repaired(err, "copy record[%s] to secondary storage", record.Key())
+	}
+	deleter, canDelete := As[Quota](f.secondary)
+	for _, record := range secondaryRecords {
+		seq, ok := primarySeqs[record.Key()]
+		switch {
+		case ok && seq < record.Seq:
+			r.BehindInPrimary++
+			_, _, err = WriteRecordIfNewer(ctx, f.Storage, record)
+			r.repaired(err, "copy record[%s] to primary storage", record.Key())
+		case !ok:
+			// the record may have been written to the primary since it was listed
+			current, err := f.Storage.ReadRecord(ctx, record.Key())
+			if err != nil {
+				logrus.WithError(err).Warnf("failed to read record[%s] from primary storage", record.Key())
+				continue
+			}
+			if current != nil {
+				continue
+			}
+			r.OnlyInSecondary++
+			if !canDelete {
+				r.Unrepaired++
+				continue
+			}
+			r.repaired(deleter.DeleteRecord(ctx, record.Key()), "delete record[%s] from secondary storage", record.Key())
+		}
+	}
+	r.Drift = r.MissingFromSecondary + r.BehindInSecondary + r.BehindInPrimary + r.OnlyInSecondary
+	r.Finished = time.Now().Unix()
+	return &r, nil
+}
+
+// repaired counts the repair of a drifted record, logging why it failed if it did
+func (r *Reconciliation) repaired(err error, action string, args ...any) {
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to "+action, args...)
+		r.Unrepaired++
+		return
+	}
+	r.Repaired++
+}
+
+// seqsByKey returns the seq of each record by the key it is stored under
+func seqsByKey(records []pkarr.Record) map[string]int64 {
+	seqs := make(map[string]int64, len(records))
+	for _, record := range records {
+		seqs[record.Key()] = record.Seq
+	}
+	return seqs
+}
@@ -4,19 +4,169 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"time"
 
-	"github.com/TBD54566975/did-dht-method/pkg/storage/db/bolt"
-	"github.com/TBD54566975/did-dht-method/pkg/storage/db/postgres"
-	"github.com/TBD54566975/did-dht-method/pkg/storage/pkarr"
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/db/bolt"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/db/pebble"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/db/postgres"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage/pkarr"
 )
 
 type Storage interface {
 	WriteRecord(ctx context.Context, record pkarr.Record) error
 	ReadRecord(ctx context.Context, id string) (*pkarr.Record, error)
 	ListRecords(ctx context.Context) ([]pkarr.Record, error)
+	ReadRecordVersion(ctx context.Context, id string, seq int64) (*pkarr.Record, error)
+	ListRecordVersions(ctx context.Context, id string) ([]pkarr.Record, error)
 	Close() error
 }
 
+// SeqWriter writes records atomically with checking the seq of the record stored under the same key, so concurrent
+// writes of a key are linearizable and a record is never replaced by one with a lower seq
+type SeqWriter interface {
+	// WriteRecordIfNewer writes the record, keeping a copy of its seq in the version history, unless the record stored
+	// under its key has a higher seq. It returns the record stored before, or nil if none, and whether it wrote.
+	WriteRecordIfNewer(ctx context.Context, record pkarr.Record) (*pkarr.Record, bool, error)
+}
+
+// WriteRecordIfNewer writes the record with the storage's SeqWriter, or for storage which isn't one, reads the stored
+// record to check its seq first, which concurrent writes of the same key may interleave with. Unlike As, it doesn't
+// look through wrappers, which transform the records they write.
+func WriteRecordIfNewer(ctx context.Context, db Storage, record pkarr.Record) (*pkarr.Record, bool, error) {
+	if writer, ok := db.(SeqWriter); ok {
+		return writer.WriteRecordIfNewer(ctx, record)
+	}
+	current, err := db.ReadRecord(ctx, record.Key())
+	if err != nil {
+		return nil, false, err
+	}
+	if current != nil && current.Seq > record.Seq {
+		return current, false, nil
+	}
+	return current, true, db.WriteRecord(ctx, record)
+}
+
+// RecordCounter counts the stored records, such as to limit how many records the gateway adopts
+type RecordCounter interface {
+	// CountRecords returns the number of stored records, not counting their versions
+	CountRecords(ctx context.Context) (int64, error)
+}
+
+// Quota reports and reclaims the storage used by records, to keep storage within a retention budget
+type Quota interface {
+	// Usage returns the number of stored records and the bytes used by them and their versions
+	Usage(ctx context.Context) (pkarr.Usage, error)
+	// DeleteRecord deletes the record stored under the given key, with all of its versions
+	DeleteRecord(ctx context.Context, key string) error
+	// MarkResolved sets the time the record stored under the given key was last resolved, if it is stored
+	MarkResolved(ctx context.Context, key string, at time.Time) error
+	// ListResolved returns the unix time each record was last resolved at, by the key it is stored under
+	ListResolved(ctx context.Context) (map[string]int64, error)
+}
+
+// Maintainer reclaims the space left behind by deleted records, such as by compacting or cleaning up the database
+type Maintainer interface {
+	// Maintain reclaims the space left behind by deleted records, reporting what it did
+	Maintain(ctx context.Context) (pkarr.Maintenance, error)
+}
+
+// VersionPruner deletes old versions of records from their version history, to keep it from growing without bound
+type VersionPruner interface {
+	// ListVersionSeqs returns the seqs of the stored versions of each record, ordered by seq, by the key it is stored
+	// under
+	ListVersionSeqs(ctx context.Context) (map[string][]int64, error)
+	// DeleteRecordVersionsBefore deletes the versions of the record stored under the given key with a seq lower than
+	// the given seq, returning how many it deleted
+	DeleteRecordVersionsBefore(ctx context.Context, key string, seq int64) (int64, error)
+}
+
+// VersionWriter rewrites stored versions of records, such as to re-encrypt their values under another key
+type VersionWriter interface {
+	// ReplaceRecordVersion replaces the stored version of the record with its seq, if stored, leaving the record
+	// stored under its key as it is
+	ReplaceRecordVersion(ctx context.Context, record pkarr.Record) error
+}
+
+// DocumentIndex indexes the DID Documents represented by records so they can be queried by their contents
+type DocumentIndex interface {
+	// IndexDocument adds the document to the index, replacing any previously indexed values for its ID
+	IndexDocument(ctx context.Context, doc pkarr.Document) error
+	// DeleteDocument removes the document with the given ID from the index, if indexed
+	DeleteDocument(ctx context.Context, id string) error
+	// QueryDocuments returns the IDs of the documents matching the query, ordered by ID
+	QueryDocuments(ctx context.Context, query pkarr.DocumentQuery) ([]string, error)
+}
+
+// HistoryLog is an append-only log of the record updates witnessed by the gateway
+type HistoryLog interface {
+	// AppendHistoryEntry appends the entry to the log, failing if an entry already exists at its index
+	AppendHistoryEntry(ctx context.Context, entry pkarr.HistoryEntry) error
+	// ReadLatestHistoryEntry returns the last entry of the log, or nil if the log is empty
+	ReadLatestHistoryEntry(ctx context.Context) (*pkarr.HistoryEntry, error)
+	// ListHistoryEntries returns up to limit entries starting at the given index, ordered by index
+	ListHistoryEntries(ctx context.Context, from int64, limit int) ([]pkarr.HistoryEntry, error)
+}
+
+// Denylist stores the keys the gateway refuses to store, serve, or republish records for
+type Denylist interface {
+	// WriteDenylistEntry adds the entry to the denylist, replacing any existing entry for its ID
+	WriteDenylistEntry(ctx context.Context, entry pkarr.DenylistEntry) error
+	// DeleteDenylistEntry removes the entry for the given ID from the denylist, if any
+	DeleteDenylistEntry(ctx context.Context, id string) error
+	// ListDenylistEntries returns all entries of the denylist, ordered by ID
+	ListDenylistEntries(ctx context.Context) ([]pkarr.DenylistEntry, error)
+}
+
+// LabelStore stores the private labels operators attach to records, apart from the records
+type LabelStore interface {
+	// WriteRecordLabels replaces the labels of the record stored under the key of the given labels
+	WriteRecordLabels(ctx context.Context, labels pkarr.RecordLabels) error
+	// DeleteRecordLabels removes the labels of the record stored under the given key, if any
+	DeleteRecordLabels(ctx context.Context, key string) error
+	// ListRecordLabels returns the labels of all labeled records, ordered by key
+	ListRecordLabels(ctx context.Context) ([]pkarr.RecordLabels, error)
+}
+
+// TenantLedger stores which tenant each record was published by, apart from the records
+type TenantLedger interface {
+	// WriteTenantRecord replaces the attribution of the record stored under the key of the given attribution
+	WriteTenantRecord(ctx context.Context, record pkarr.TenantRecord) error
+	// ReadTenantRecord returns the attribution of the record stored under the given key, or nil if it has none
+	ReadTenantRecord(ctx context.Context, key string) (*pkarr.TenantRecord, error)
+	// DeleteTenantRecord removes the attribution of the record stored under the given key, if any
+	DeleteTenantRecord(ctx context.Context, key string) error
+	// ListTenantRecords returns the attributions of all attributed records, ordered by key
+	ListTenantRecords(ctx context.Context) ([]pkarr.TenantRecord, error)
+}
+
+// EquivocationLog stores the evidence of keys signing different records with the same seq
+type EquivocationLog interface {
+	// WriteEquivocation stores the evidence, unless evidence with the same fingerprint is already stored
+	WriteEquivocation(ctx context.Context, equivocation pkarr.Equivocation) error
+	// ListEquivocations returns all stored evidence, ordered by fingerprint
+	ListEquivocations(ctx context.Context) ([]pkarr.Equivocation, error)
+}
+
+// PutJournal is a write-ahead journal of the puts of published records to the DHT, so a crash between storing a record
+// and putting it doesn't leave it off the DHT until it is next republished
+type PutJournal interface {
+	// WriteJournalEntry writes the entry, replacing any entry for the same key
+	WriteJournalEntry(ctx context.Context, entry pkarr.JournalEntry) error
+	// DeleteJournalEntry deletes the entry for the given key, unless it was replaced by an entry with another seq
+	DeleteJournalEntry(ctx context.Context, key string, seq int64) error
+	// ListJournalEntries returns all entries, ordered by key
+	ListJournalEntries(ctx context.Context) ([]pkarr.JournalEntry, error)
+}
+
+// ChangeFeed is an append-only feed of the records first seen or updated by the gateway, so indexers can tail it
+type ChangeFeed interface {
+	// AppendFeedEntry appends the entry to the feed, returning the cursor assigned to it
+	AppendFeedEntry(ctx context.Context, entry pkarr.FeedEntry) (int64, error)
+	// ListFeedEntries returns up to limit entries with a cursor after since, ordered by cursor
+	ListFeedEntries(ctx context.Context, since int64, limit int) ([]pkarr.FeedEntry, error)
+}
+
 func NewStorage(uri string) (Storage, error) {
 	u, err := url.Parse(uri)
 	if err != nil {
@@ -29,9 +179,42 @@ func NewStorage(uri string) (Storage, error) {
 			filename = fmt.Sprintf("%s/%s", filename, u.Path)
 		}
 		return bolt.NewBolt(filename)
+	case "pebble":
+		dirname := u.Host
+		if u.Path != "" {
+			dirname = fmt.Sprintf("%s/%s", dirname, u.Path)
+		}
+		return pebble.NewPebble(dirname)
 	case "postgres":
 		return postgres.NewPostgres(uri)
 	default:
 		return nil, fmt.Errorf("unsupported db type %s (from uri %s)", u.Scheme, uri)
 	}
 }
+
+// NewFromConfig instantiates the storage at the URI of the config, wrapped as the config sets: mirrored to the
+// secondary storage, encrypting record values, compressing them, and hashing the keys records are stored under.
+// Compression wraps encryption, as encrypted values don't compress, and key hashing wraps both, as it keeps keys in
+// the record values.
+func NewFromConfig(cfg *config.Config) (Storage, error) {
+	db, err := NewStorage(cfg.ServerConfig.StorageURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate storage: %w", err)
+	}
+	wrapped, err := WithFailover(db, cfg.ServerConfig.SecondaryStorageURI)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to instantiate secondary storage: %w", err)
+	}
+	db = wrapped
+	if wrapped, err = WithEncryption(db, cfg.EncryptionConfig); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to set up storage encryption: %w", err)
+	}
+	db = WithCompression(wrapped, cfg.ServerConfig.CompressRecords)
+	if wrapped, err = WithKeyHashing(db, cfg.EncryptionConfig); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to set up storage key hashing: %w", err)
+	}
+	return wrapped, nil
+}
@@ -0,0 +1,85 @@
+package testutil
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"sync"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/anacrolix/torrent/bencode"
+
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/dht"
+)
+
+// DHT is an in-memory fake of the Mainline DHT. Like DHT nodes, it only accepts puts with a valid signature and
+// keeps the value with the highest seq for each key and salt.
+type DHT struct {
+	mu sync.RWMutex
+	// records are keyed by their BEP-44 target, the hash of their key and salt
+	records map[bep44.Target]dht.FullGetResult
+}
+
+var _ dht.Client = (*DHT)(nil)
+
+// NewDHT returns a new, empty, in-memory DHT
+func NewDHT() *DHT {
+	return &DHT{records: make(map[bep44.Target]dht.FullGetResult)}
+}
+
+// Put stores the given BEP-44 value and returns its z32-encoded key
+func (d *DHT) Put(_ context.Context, request bep44.Put) (string, error) {
+	if request.K == nil {
+		return "", fmt.Errorf("put is not mutable")
+	}
+	v, err := bencode.Marshal(request.V)
+	if err != nil {
+		return "", err
+	}
+	if !bep44.Verify(request.K[:], request.Salt, request.Seq, v, request.Sig[:]) {
+		return "", fmt.Errorf("invalid signature")
+	}
+
+	key := util.Z32Encode(request.K[:])
+	target := request.Target()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if existing, ok := d.records[target]; ok && existing.Seq > request.Seq {
+		return "", fmt.Errorf("seq %d is older than the stored value's seq %d", request.Seq, existing.Seq)
+	}
+	d.records[target] = dht.FullGetResult{
+		Seq:     request.Seq,
+		V:       v,
+		Sig:     request.Sig,
+		Mutable: true,
+	}
+	return key, nil
+}
+
+// GetFull returns the value stored for the given z32-encoded key and optional salt, failing like the DHT if there
+// is none
+func (d *DHT) GetFull(_ context.Context, key string, salt []byte) (*dht.FullGetResult, error) {
+	k, err := util.Z32Decode(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(k) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("key must be %d bytes", ed25519.PublicKeySize)
+	}
+	target := bep44.MakeMutableTarget([32]byte(k), salt)
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	record, ok := d.records[target]
+	if !ok {
+		return nil, fmt.Errorf("failed to get key[%s] from dht", key)
+	}
+	return &record, nil
+}
+
+// Len returns the number of keys stored in the DHT
+func (d *DHT) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.records)
+}
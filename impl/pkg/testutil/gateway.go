@@ -0,0 +1,60 @@
+package testutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/config"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/gateway"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/storage"
+)
+
+// Gateway is a did:dht gateway served by an httptest server, backed by an in-memory DHT
+type Gateway struct {
+	*httptest.Server
+	DHT     *DHT
+	Gateway *gateway.Gateway
+}
+
+// NewGateway starts a gateway with the test environment's default config, storage in a temporary directory, an
+// in-memory cache, and an in-memory DHT. The options are applied after these defaults, so they can override them.
+// The gateway is stopped when the test completes.
+func NewGateway(t testing.TB, opts ...gateway.Option) *Gateway {
+	cfg := config.GetDefaultConfig()
+	cfg.ServerConfig.Environment = config.EnvironmentTest
+	cfg.ServerConfig.StorageURI = "bolt://" + filepath.Join(t.TempDir(), "gateway.db")
+	cfg.PkarrConfig.CacheURI = "memory://"
+	cfg.PkarrConfig.RepublishCRON = ""
+	cfg.DNSConfig.Enabled = false
+
+	db, err := storage.NewStorage(cfg.ServerConfig.StorageURI)
+	require.NoError(t, err)
+	fakeDHT := NewDHT()
+	mux := http.NewServeMux()
+	gw := gateway.New(append([]gateway.Option{
+		gateway.WithConfig(&cfg),
+		gateway.WithStorage(db),
+		gateway.WithDHT(fakeDHT),
+		gateway.WithMux(mux, ""),
+	}, opts...)...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, gw.Start(ctx))
+	server := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		server.Close()
+		cancel()
+		_ = gw.Wait()
+		_ = db.Close()
+	})
+	return &Gateway{
+		Server:  server,
+		DHT:     fakeDHT,
+		Gateway: gw,
+	}
+}
@@ -0,0 +1,26 @@
+package testutil
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+// Key returns an ed25519 private key derived from the given seed, so tests get the same key, and so the same ID,
+// on every run
+func Key(seed string) ed25519.PrivateKey {
+	hashed := sha256.Sum256([]byte(seed))
+	return ed25519.NewKeyFromSeed(hashed[:])
+}
+
+// ID returns the z-base-32 encoded ID of the records published with the given key
+func ID(key ed25519.PrivateKey) string {
+	return util.Z32Encode(key.Public().(ed25519.PublicKey))
+}
+
+// DID returns the did:dht identifier of the given key
+func DID(key ed25519.PrivateKey) string {
+	return did.GetDIDDHTIdentifier(key.Public().(ed25519.PublicKey))
+}
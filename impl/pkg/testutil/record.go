@@ -0,0 +1,106 @@
+package testutil
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"testing"
+
+	"github.com/anacrolix/dht/v2/bep44"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/service"
+)
+
+// Record builds a signed Pkarr record for a key
+type Record struct {
+	key ed25519.PrivateKey
+	seq int64
+	msg dns.Msg
+}
+
+// NewRecord returns a builder for a record of the given key, with seq 1 and no resource records
+func NewRecord(key ed25519.PrivateKey) *Record {
+	return &Record{
+		key: key,
+		seq: 1,
+		msg: dns.Msg{
+			MsgHdr: dns.MsgHdr{
+				Response:      true,
+				Authoritative: true,
+			},
+		},
+	}
+}
+
+// NewDIDRecord returns a builder for the record of a DID Document created for the given key with the given options
+func NewDIDRecord(t testing.TB, key ed25519.PrivateKey, opts did.CreateDIDDHTOpts) *Record {
+	doc, err := did.CreateDIDDHTDID(key.Public().(ed25519.PublicKey), opts)
+	require.NoError(t, err)
+	msg, err := did.DHT(doc.ID).ToDNSPacket(*doc, nil)
+	require.NoError(t, err)
+
+	record := NewRecord(key)
+	record.msg = *msg
+	return record
+}
+
+// WithSeq sets the seq of the record, which must increase with each update of a key's record
+func (r *Record) WithSeq(seq int64) *Record {
+	r.seq = seq
+	return r
+}
+
+// WithTXT adds a TXT resource record with the given name and values, e.g. "_did."
+func (r *Record) WithTXT(name string, values ...string) *Record {
+	r.msg.Answer = append(r.msg.Answer, &dns.TXT{
+		Hdr: dns.RR_Header{
+			Name:   name,
+			Rrtype: dns.TypeTXT,
+			Class:  dns.ClassINET,
+			Ttl:    7200,
+		},
+		Txt: values,
+	})
+	return r
+}
+
+// Msg returns the DNS packet of the record
+func (r *Record) Msg() *dns.Msg {
+	return r.msg.Copy()
+}
+
+// Put returns the record as a signed BEP-44 put
+func (r *Record) Put(t testing.TB) *bep44.Put {
+	packed, err := r.msg.Pack()
+	require.NoError(t, err)
+	put := &bep44.Put{
+		V:   packed,
+		K:   (*[32]byte)(r.key.Public().(ed25519.PublicKey)),
+		Seq: r.seq,
+	}
+	put.Sign(r.key)
+	return put
+}
+
+// Request returns the record as a request to the Pkarr service
+func (r *Record) Request(t testing.TB) service.PublishPkarrRequest {
+	put := r.Put(t)
+	return service.PublishPkarrRequest{
+		V:   put.V.([]byte),
+		K:   *put.K,
+		Sig: put.Sig,
+		Seq: put.Seq,
+	}
+}
+
+// Body returns the record as the body of a request to the relay API: 64 bytes sig, 8 bytes u64 big-endian seq,
+// and the v
+func (r *Record) Body(t testing.TB) []byte {
+	put := r.Put(t)
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], uint64(put.Seq))
+	body := append(put.Sig[:], seq[:]...)
+	return append(body, put.V.([]byte)...)
+}
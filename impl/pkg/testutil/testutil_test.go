@@ -0,0 +1,92 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, Key("alice"), Key("alice"))
+	assert.NotEqual(t, Key("alice"), Key("bob"))
+	assert.Equal(t, "did:dht:"+ID(Key("alice")), DID(Key("alice")))
+}
+
+func TestDHT(t *testing.T) {
+	d := NewDHT()
+	key := Key("alice")
+
+	_, err := d.GetFull(context.Background(), ID(key), nil)
+	assert.Error(t, err)
+
+	put := NewRecord(key).WithSeq(2).WithTXT("_test.", "hello").Put(t)
+	id, err := d.Put(context.Background(), *put)
+	require.NoError(t, err)
+	assert.Equal(t, ID(key), id)
+
+	got, err := d.GetFull(context.Background(), id, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), got.Seq)
+	assert.Equal(t, put.Sig, got.Sig)
+
+	t.Run("salted", func(t *testing.T) {
+		salted := NewRecord(key).WithSeq(1).WithTXT("_test.", "salted").Put(t)
+		salted.Salt = []byte("salt")
+		salted.Sign(key)
+		_, err = d.Put(context.Background(), *salted)
+		require.NoError(t, err)
+
+		got, err := d.GetFull(context.Background(), id, salted.Salt)
+		require.NoError(t, err)
+		assert.Equal(t, salted.Sig, got.Sig)
+
+		got, err = d.GetFull(context.Background(), id, nil)
+		require.NoError(t, err)
+		assert.Equal(t, put.Sig, got.Sig)
+	})
+
+	t.Run("stale seq", func(t *testing.T) {
+		stale := NewRecord(key).WithSeq(1).Put(t)
+		_, err = d.Put(context.Background(), *stale)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		forged := NewRecord(key).WithSeq(3).Put(t)
+		forged.Sig[0] ^= 0xff
+		_, err = d.Put(context.Background(), *forged)
+		assert.Error(t, err)
+		assert.Equal(t, 2, d.Len())
+	})
+}
+
+func TestGateway(t *testing.T) {
+	gw := NewGateway(t)
+	key := Key("alice")
+	record := NewDIDRecord(t, key, did.CreateDIDDHTOpts{})
+
+	req, err := http.NewRequest(http.MethodPut, gw.URL+"/v1/"+ID(key), bytes.NewReader(record.Body(t)))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(gw.URL + "/v1/" + ID(key))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, record.Body(t), body)
+
+	assert.Eventually(t, func() bool { return gw.DHT.Len() == 1 }, time.Second, 10*time.Millisecond)
+}
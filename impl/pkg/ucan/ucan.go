@@ -0,0 +1,205 @@
+// Package ucan issues and validates UCAN-style capability tokens (https://github.com/ucan-wg/spec), through which the
+// controller of a DID delegates the right to act on its behalf to another principal, who may delegate it on in turn.
+// Tokens are JWTs signed with EdDSA whose principals are did:dht identifiers, their ed25519 keys encoded in the
+// identifier, and whose proofs are inlined as the encoded tokens they derive from.
+package ucan
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	intutil "github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+const (
+	// MaxChainLength bounds the number of tokens in a delegation chain
+	MaxChainLength = 8
+	// MaxTokenLength bounds the size of an encoded token, including its inlined proofs
+	MaxTokenLength = 16 * 1024
+
+	algorithm = "EdDSA"
+)
+
+var (
+	// ErrInvalidToken is returned for tokens which are malformed or whose signature doesn't verify
+	ErrInvalidToken = errors.New("invalid capability token")
+	// ErrUnauthorized is returned for tokens which don't grant the capability to the audience
+	ErrUnauthorized = errors.New("capability not delegated")
+)
+
+// Capability is the ability to act on a resource, such as {"with": "did:dht:<id>", "can": "pkarr/publish"}. A
+// capability of ability "*", or of a namespace such as "pkarr/*", grants all abilities, or all of the namespace.
+type Capability struct {
+	With string `json:"with"`
+	Can  string `json:"can"`
+}
+
+// Payload is the claims of a token
+type Payload struct {
+	// Issuer is the DID delegating the capabilities, and Audience the DID they are delegated to
+	Issuer   string `json:"iss"`
+	Audience string `json:"aud"`
+	// NotBefore and Expiry are the unix timestamps in seconds the token is valid from and until
+	NotBefore int64 `json:"nbf,omitempty"`
+	Expiry    int64 `json:"exp"`
+	// Nonce makes otherwise identical tokens distinct
+	Nonce string `json:"nnc,omitempty"`
+	// Attenuations are the capabilities delegated
+	Attenuations []Capability `json:"att"`
+	// Proofs are the encoded tokens delegating the capabilities to the issuer, if it doesn't own their resources
+	Proofs []string `json:"prf,omitempty"`
+}
+
+// Token is a decoded token whose signature, and those of its proofs, verified
+type Token struct {
+	Payload
+	proofs []*Token
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+}
+
+// Issue encodes the payload as a token signed by the issuer's key
+func Issue(key ed25519.PrivateKey, payload Payload) (string, error) {
+	issuerKey, err := PublicKey(payload.Issuer)
+	if err != nil {
+		return "", err
+	}
+	if !issuerKey.Equal(key.Public()) {
+		return "", errors.New("key is not the issuer's")
+	}
+	headerJSON, err := json.Marshal(header{Algorithm: algorithm, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(payloadJSON)
+	signature := ed25519.Sign(key, []byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Parse decodes the token and its proofs, verifying each is signed by its issuer. Their time bounds and the
+// capabilities they delegate are checked by Allows.
+func Parse(token string) (*Token, error) {
+	if len(token) > MaxTokenLength {
+		return nil, fmt.Errorf("%w: longer than %d bytes", ErrInvalidToken, MaxTokenLength)
+	}
+	return parse(token, 1)
+}
+
+func parse(token string, depth int) (*Token, error) {
+	if depth > MaxChainLength {
+		return nil, fmt.Errorf("%w: delegation chain longer than %d", ErrInvalidToken, MaxChainLength)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWT", ErrInvalidToken)
+	}
+	var h header
+	if err := decodePart(parts[0], &h); err != nil {
+		return nil, err
+	}
+	if h.Algorithm != algorithm {
+		return nil, fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidToken, h.Algorithm)
+	}
+	var t Token
+	if err := decodePart(parts[1], &t.Payload); err != nil {
+		return nil, err
+	}
+	issuerKey, err := PublicKey(t.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !ed25519.Verify(issuerKey, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, fmt.Errorf("%w: signature doesn't verify", ErrInvalidToken)
+	}
+	for _, proof := range t.Proofs {
+		p, err := parse(proof, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		t.proofs = append(t.proofs, p)
+	}
+	return &t, nil
+}
+
+func decodePart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+// Allows returns nil if the token delegates the capability to its audience at the given time: the token grants the
+// capability, and its issuer either owns the capability's resource, being the DID it names, or was delegated the
+// capability by one of its proofs, recursively. Every token of the chain must be valid at the given time.
+func (t *Token) Allows(capability Capability, now time.Time) error {
+	if !t.allows(capability, now) {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func (t *Token) allows(capability Capability, now time.Time) bool {
+	if !t.validAt(now) || !t.grants(capability) {
+		return false
+	}
+	if capability.With == t.Issuer {
+		return true
+	}
+	for _, proof := range t.proofs {
+		if proof.Audience == t.Issuer && proof.allows(capability, now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Token) validAt(now time.Time) bool {
+	return t.Expiry > 0 && now.Unix() < t.Expiry && now.Unix() >= t.NotBefore
+}
+
+func (t *Token) grants(capability Capability) bool {
+	for _, att := range t.Attenuations {
+		if att.With != capability.With {
+			continue
+		}
+		if att.Can == capability.Can || att.Can == "*" {
+			return true
+		}
+		if namespace, ok := strings.CutSuffix(att.Can, "/*"); ok && strings.HasPrefix(capability.Can, namespace+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// PublicKey returns the ed25519 key of a did:dht principal
+func PublicKey(principal string) (ed25519.PublicKey, error) {
+	id, ok := strings.CutPrefix(principal, did.Prefix+":")
+	if !ok {
+		return nil, fmt.Errorf("principal %q is not a %s identifier", principal, did.Prefix)
+	}
+	key, err := intutil.Z32Decode(id)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("principal %q is not a z-base-32 encoded ed25519 key", principal)
+	}
+	return key, nil
+}
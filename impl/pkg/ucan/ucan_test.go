@@ -0,0 +1,95 @@
+package ucan
+
+import (
+	"crypto/ed25519"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TBD54566975/did-dht-method/impl/internal/util"
+	"github.com/TBD54566975/did-dht-method/impl/pkg/did"
+)
+
+type principal struct {
+	did string
+	key ed25519.PrivateKey
+}
+
+func newPrincipal(t *testing.T) principal {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return principal{did: did.Prefix + ":" + util.Z32Encode(pubKey), key: privKey}
+}
+
+func TestDelegation(t *testing.T) {
+	controller, wallet, relay, stranger := newPrincipal(t), newPrincipal(t), newPrincipal(t), newPrincipal(t)
+	now := time.Now()
+	publish := Capability{With: controller.did, Can: "pkarr/publish"}
+
+	issue := func(issuer, audience principal, can string, proofs ...string) string {
+		token, err := Issue(issuer.key, Payload{
+			Issuer:       issuer.did,
+			Audience:     audience.did,
+			Expiry:       now.Add(time.Hour).Unix(),
+			Attenuations: []Capability{{With: controller.did, Can: can}},
+			Proofs:       proofs,
+		})
+		require.NoError(t, err)
+		return token
+	}
+
+	t.Run("delegated by the controller", func(t *testing.T) {
+		token, err := Parse(issue(controller, wallet, "pkarr/publish"))
+		require.NoError(t, err)
+		assert.Equal(t, wallet.did, token.Audience)
+		assert.NoError(t, token.Allows(publish, now))
+		assert.ErrorIs(t, token.Allows(Capability{With: controller.did, Can: "pkarr/delete"}, now), ErrUnauthorized)
+		assert.ErrorIs(t, token.Allows(publish, now.Add(2*time.Hour)), ErrUnauthorized, "expired")
+	})
+
+	t.Run("delegated on", func(t *testing.T) {
+		root := issue(controller, wallet, "pkarr/*")
+		token, err := Parse(issue(wallet, relay, "pkarr/publish", root))
+		require.NoError(t, err)
+		assert.NoError(t, token.Allows(publish, now))
+
+		// a proof delegated to someone else doesn't authorize the issuer
+		token, err = Parse(issue(stranger, relay, "pkarr/publish", root))
+		require.NoError(t, err)
+		assert.ErrorIs(t, token.Allows(publish, now), ErrUnauthorized)
+
+		// capabilities can't be broadened
+		token, err = Parse(issue(relay, stranger, "*", issue(wallet, relay, "pkarr/delete", root)))
+		require.NoError(t, err)
+		assert.ErrorIs(t, token.Allows(publish, now), ErrUnauthorized)
+	})
+
+	t.Run("not delegated by the controller", func(t *testing.T) {
+		token, err := Parse(issue(stranger, wallet, "pkarr/publish"))
+		require.NoError(t, err)
+		assert.ErrorIs(t, token.Allows(publish, now), ErrUnauthorized)
+	})
+
+	t.Run("invalid tokens", func(t *testing.T) {
+		_, err := Issue(stranger.key, Payload{Issuer: controller.did})
+		assert.Error(t, err)
+
+		token := issue(controller, wallet, "pkarr/publish")
+		parts := strings.Split(token, ".")
+		forged := issue(controller, stranger, "pkarr/publish")
+		_, err = Parse(parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2])
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		_, err = Parse("not.a.token")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+
+		chain := issue(controller, wallet, "pkarr/publish")
+		for i := 0; i < MaxChainLength; i++ {
+			chain = issue(wallet, wallet, "pkarr/publish", chain)
+		}
+		_, err = Parse(chain)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}